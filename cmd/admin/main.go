@@ -0,0 +1,122 @@
+// Command admin runs maintenance tasks against the configured MongoDB
+//
+//	go run ./cmd/admin migrate status
+//	go run ./cmd/admin migrate up [version]
+//	go run ./cmd/admin migrate down [steps]
+//	go run ./cmd/admin totals <lineID|all>
+//	go run ./cmd/admin transfers <lineID|all> [repair]
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/satisatang/backend/config"
+	"github.com/satisatang/backend/migrations"
+	"github.com/satisatang/backend/services"
+)
+
+func main() {
+	if len(os.Args) < 3 || (os.Args[1] != "migrate" && os.Args[1] != "totals" && os.Args[1] != "transfers") {
+		usage()
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	mongoService, err := services.NewMongoDBService(cfg.MongoDBURI, cfg.MongoDBName)
+	if err != nil {
+		log.Fatalf("Failed to connect MongoDB: %v", err)
+	}
+	defer mongoService.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	if os.Args[1] == "totals" {
+		// Rebuild totalIncome/totalExpense of daily records from their transactions
+		lineID := os.Args[2]
+		if lineID == "all" {
+			lineID = ""
+		}
+		checked, fixed, err := mongoService.RecalculateAllTotals(ctx, lineID)
+		fmt.Printf("Checked %d daily record(s), fixed %d\n", checked, fixed)
+		if err != nil {
+			log.Fatalf("Recalculation failed: %v", err)
+		}
+		return
+	}
+
+	if os.Args[1] == "transfers" {
+		// Check transfer records against the transactions linked by transfer_id
+		lineID := os.Args[2]
+		if lineID == "all" {
+			lineID = ""
+		}
+		issues, err := mongoService.CheckTransferIntegrity(ctx, lineID)
+		if err != nil {
+			log.Fatalf("Transfer check failed: %v", err)
+		}
+		for _, issue := range issues {
+			fmt.Printf("%s %s %s: %s\n", issue.Date, issue.LineID, issue.TransferID, issue.Problem)
+		}
+		fmt.Printf("Found %d transfer issue(s)\n", len(issues))
+		if len(os.Args) > 3 && os.Args[3] == "repair" {
+			repaired, err := mongoService.RepairTransfers(ctx, issues)
+			fmt.Printf("Rebuilt %d missing transfer record(s)\n", repaired)
+			if err != nil {
+				log.Fatalf("Repair failed: %v", err)
+			}
+		}
+		return
+	}
+
+	runner := migrations.NewRunner(mongoService.Database())
+
+	arg := 0
+	if len(os.Args) > 3 {
+		if arg, err = strconv.Atoi(os.Args[3]); err != nil {
+			usage()
+		}
+	}
+
+	switch os.Args[2] {
+	case "status":
+		lines, err := runner.Status(ctx)
+		if err != nil {
+			log.Fatalf("Failed to read migrations: %v", err)
+		}
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+	case "up":
+		count, err := runner.Up(ctx, arg)
+		fmt.Printf("Applied %d migration(s)\n", count)
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+	case "down":
+		if arg <= 0 {
+			arg = 1
+		}
+		count, err := runner.Down(ctx, arg)
+		fmt.Printf("Rolled back %d migration(s)\n", count)
+		if err != nil {
+			log.Fatalf("Rollback failed: %v", err)
+		}
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Println("usage: admin migrate status | up [version] | down [steps]")
+	fmt.Println("       admin totals <lineID|all>")
+	fmt.Println("       admin transfers <lineID|all> [repair]")
+	os.Exit(2)
+}
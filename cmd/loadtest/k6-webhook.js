@@ -0,0 +1,45 @@
+// k6 driver for the LINE webhook (same message mix and budgets as "go run ./cmd/loadtest run")
+//
+//   k6 run -e WEBHOOK_URL=http://localhost:8080/webhook/line -e LINE_CHANNEL_SECRET=... cmd/loadtest/k6-webhook.js
+import http from 'k6/http';
+import crypto from 'k6/crypto';
+import encoding from 'k6/encoding';
+import { check } from 'k6';
+
+const noAI = ['รายการล่าสุด', 'ยอด เงินสด', 'ยอด กสิกร', 'ร้านไหนเปลืองสุด', 'ดูงบ'];
+const ai = ['กาแฟ 65', 'สรุปรายจ่ายเดือนนี้', 'หาค่ากาแฟ', 'เดือนนี้ใช้อะไรเยอะสุด'];
+
+export const options = {
+  scenarios: {
+    noai: { executor: 'constant-vus', vus: 20, duration: '1m', env: { KIND: 'noai' }, tags: { kind: 'noai' } },
+    ai: { executor: 'constant-vus', vus: 5, duration: '1m', env: { KIND: 'ai' }, tags: { kind: 'ai' } },
+  },
+  thresholds: {
+    'http_req_duration{kind:noai}': ['p(95)<300'],
+    'http_req_duration{kind:ai}': ['p(95)<2000'],
+    http_req_failed: ['rate<0.01'],
+  },
+};
+
+export default function () {
+  const messages = __ENV.KIND === 'ai' ? ai : noAI;
+  const id = `${__VU}-${__ITER}`;
+  const body = JSON.stringify({
+    destination: 'loadtest',
+    events: [{
+      type: 'message',
+      mode: 'active',
+      timestamp: Date.now(),
+      webhookEventId: `loadtest-${id}`,
+      deliveryContext: { isRedelivery: false },
+      replyToken: `loadtest-${id}`,
+      source: { type: 'user', userId: `Uloadtest${String(__VU % 100 + 1).padStart(4, '0')}` },
+      message: { type: 'text', id: id, quoteToken: 'loadtest', text: messages[__ITER % messages.length] },
+    }],
+  });
+  const signature = encoding.b64encode(crypto.hmac('sha256', __ENV.LINE_CHANNEL_SECRET, body, 'binary'));
+  const res = http.post(__ENV.WEBHOOK_URL, body, {
+    headers: { 'Content-Type': 'application/json', 'X-Line-Signature': signature },
+  });
+  check(res, { 'status 200': (r) => r.status === 200 });
+}
@@ -0,0 +1,327 @@
+// Command loadtest seeds a load-test dataset and drives concurrent LINE webhook traffic against a server
+//
+//	go run ./cmd/loadtest seed [users] [transactions]       (default 100 users, 100000 transactions)
+//	go run ./cmd/loadtest run <webhook URL> [noai|ai|mixed] [users] [requests]
+//
+// seed writes into MONGODB_ATLAS_DBNAME and refuses unless the name contains "loadtest".
+// run signs events with LINE_CHANNEL_SECRET, reports p50/p95/p99 per message kind and exits 1 when a
+// p95 budget is exceeded (webhook with AI < 2s, without AI < 300ms).
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/satisatang/backend/config"
+	"github.com/satisatang/backend/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// p95 budgets of one webhook request
+const (
+	budgetAI   = 2 * time.Second
+	budgetNoAI = 300 * time.Millisecond
+)
+
+// Messages answered in Go (no AI) and through the AI
+var (
+	noAIMessages = []string{"รายการล่าสุด", "ยอด เงินสด", "ยอด กสิกร", "ร้านไหนเปลืองสุด", "ดูงบ"}
+	aiMessages   = []string{"กาแฟ 65", "สรุปรายจ่ายเดือนนี้", "หาค่ากาแฟ", "เดือนนี้ใช้อะไรเยอะสุด"}
+)
+
+// seedCategories are expense categories with typical amounts and merchants
+var seedCategories = []struct {
+	category  string
+	min, max  float64
+	merchants []string
+}{
+	{"อาหาร", 40, 350, []string{"7-Eleven", "MK", "KFC", "ร้านข้าวมันไก่", ""}},
+	{"เครื่องดื่ม", 35, 180, []string{"Starbucks", "Café Amazon", ""}},
+	{"เดินทาง", 15, 500, []string{"Grab", "PTT Station", ""}},
+	{"ช้อปปิ้ง", 100, 3000, []string{"Shopee", "Lazada", "Central", "Uniqlo"}},
+	{"ของใช้", 50, 900, []string{"Lotus's", "Big C", "Watsons", "Tops"}},
+	{"บันเทิง", 120, 800, []string{"Netflix", "Major Cineplex", ""}},
+}
+
+func main() {
+	if len(os.Args) < 2 || (os.Args[1] != "seed" && os.Args[1] != "run") || (os.Args[1] == "run" && len(os.Args) < 3) {
+		usage()
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	if os.Args[1] == "seed" {
+		users := argInt(2, 100)
+		transactions := argInt(3, 100000)
+		if !strings.Contains(cfg.MongoDBName, "loadtest") {
+			log.Fatalf("Refusing to seed %q: use a database whose name contains \"loadtest\"", cfg.MongoDBName)
+		}
+		mongoService, err := services.NewMongoDBService(cfg.MongoDBURI, cfg.MongoDBName)
+		if err != nil {
+			log.Fatalf("Failed to connect MongoDB: %v", err)
+		}
+		defer mongoService.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+		defer cancel()
+		records, err := seed(ctx, mongoService, users, transactions)
+		fmt.Printf("Seeded %d transaction(s) in %d daily record(s) for %d user(s)\n", transactions, records, users)
+		if err != nil {
+			log.Fatalf("Seeding failed: %v", err)
+		}
+		return
+	}
+
+	mix := "mixed"
+	if len(os.Args) > 3 {
+		mix = os.Args[3]
+	}
+	var kinds []string
+	switch mix {
+	case "noai":
+		kinds = []string{"noai"}
+	case "ai":
+		kinds = []string{"ai"}
+	case "mixed":
+		kinds = []string{"noai", "ai"}
+	default:
+		usage()
+	}
+	users := argInt(4, 20)
+	requests := argInt(5, 500)
+	latencies, failures := run(os.Args[2], cfg.LineChannelSecret, kinds, users, requests)
+
+	overBudget := false
+	for _, kind := range kinds {
+		budget := budgetNoAI
+		if kind == "ai" {
+			budget = budgetAI
+		}
+		l := latencies[kind]
+		p95 := percentile(l, 95)
+		status := "ok"
+		if p95 > budget {
+			status, overBudget = "OVER BUDGET", true
+		}
+		fmt.Printf("%-5s n=%-5d p50=%-8s p95=%-8s p99=%-8s max=%-8s budget p95<%s %s\n", kind, len(l),
+			percentile(l, 50).Round(time.Millisecond), p95.Round(time.Millisecond), percentile(l, 99).Round(time.Millisecond),
+			percentile(l, 100).Round(time.Millisecond), budget, status)
+	}
+	if failures > 0 {
+		fmt.Printf("%d request(s) failed (non-200 or network error)\n", failures)
+	}
+	if overBudget || failures > 0 {
+		os.Exit(1)
+	}
+}
+
+// seed inserts transactions spread over the last year for users "Uloadtest0001"..., one daily record per
+// user and day (replacing earlier seeded data of those users)
+func seed(ctx context.Context, mongoService *services.MongoDBService, users, transactions int) (int, error) {
+	rng := rand.New(rand.NewSource(1))
+	collection := mongoService.Database().Collection("daily_records")
+	lineIDs := make([]string, users)
+	for i := range lineIDs {
+		lineIDs[i] = loadTestUserID(i)
+	}
+	if _, err := collection.DeleteMany(ctx, bson.M{"lineid": bson.M{"$in": lineIDs}}); err != nil {
+		return 0, err
+	}
+
+	const days = 365
+	today := time.Now()
+	records := make(map[string]*services.DailyRecord)
+	for i := 0; i < transactions; i++ {
+		lineID := lineIDs[i%users]
+		date := today.AddDate(0, 0, -rng.Intn(days)).Format("2006-01-02")
+		key := lineID + date
+		record, ok := records[key]
+		if !ok {
+			record = &services.DailyRecord{LineID: lineID, Date: date, Time: "12:00", CreatedAt: today, UpdatedAt: today}
+			records[key] = record
+		}
+
+		tx := services.Transaction{ID: primitive.NewObjectID(), CreatedAt: today}
+		switch r := rng.Intn(100); {
+		case r < 3:
+			tx.Type, tx.Category, tx.Description, tx.Amount = 1, "เงินเดือน", "เงินเดือน", 30000
+			tx.UseType, tx.BankName = 2, "กสิกร"
+			record.Incomes = append(record.Incomes, tx)
+			record.TotalIncome += tx.Amount
+			continue
+		case r < 60:
+			tx.UseType = 0
+		case r < 85:
+			tx.UseType, tx.CreditCardName = 1, "KTC"
+		default:
+			tx.UseType, tx.BankName = 2, "กสิกร"
+		}
+		c := seedCategories[rng.Intn(len(seedCategories))]
+		tx.Type, tx.Category = -1, c.category
+		tx.Amount = float64(int(c.min + rng.Float64()*(c.max-c.min)))
+		tx.Description = c.category
+		if m := c.merchants[rng.Intn(len(c.merchants))]; m != "" {
+			tx.CustName, tx.Merchant, tx.Description = m, m, m
+		}
+		record.Expenses = append(record.Expenses, tx)
+		record.TotalExpense += tx.Amount
+	}
+
+	batch := make([]interface{}, 0, 1000)
+	inserted := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if _, err := collection.InsertMany(ctx, batch); err != nil {
+			return err
+		}
+		inserted += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+	for _, record := range records {
+		batch = append(batch, record)
+		if len(batch) == cap(batch) {
+			if err := flush(); err != nil {
+				return inserted, err
+			}
+		}
+	}
+	return inserted, flush()
+}
+
+// run sends requests signed webhook events from users concurrent users, alternating message kinds
+func run(url, secret string, kinds []string, users, requests int) (map[string][]time.Duration, int) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	latencies := make(map[string][]time.Duration)
+	failures := 0
+	var mu sync.Mutex
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for u := 0; u < users; u++ {
+		wg.Add(1)
+		go func(user int) {
+			defer wg.Done()
+			for n := range jobs {
+				kind := kinds[n%len(kinds)]
+				messages := noAIMessages
+				if kind == "ai" {
+					messages = aiMessages
+				}
+				body := webhookBody(loadTestUserID(user), messages[n%len(messages)], n)
+				elapsed, err := post(client, url, secret, body)
+
+				mu.Lock()
+				if err != nil {
+					failures++
+					log.Printf("Request %d failed: %v", n, err)
+				} else {
+					latencies[kind] = append(latencies[kind], elapsed)
+				}
+				mu.Unlock()
+			}
+		}(u)
+	}
+	for n := 0; n < requests; n++ {
+		jobs <- n
+	}
+	close(jobs)
+	wg.Wait()
+	return latencies, failures
+}
+
+// post sends one signed webhook body and returns how long the server took to answer 200
+func post(client *http.Client, url, secret string, body []byte) (time.Duration, error) {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Line-Signature", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return time.Since(start), nil
+}
+
+// webhookBody builds a LINE text message event (the reply token is fake, so the reply itself fails fast)
+func webhookBody(userID, text string, n int) []byte {
+	id := strconv.Itoa(n)
+	body, _ := json.Marshal(map[string]interface{}{
+		"destination": "loadtest",
+		"events": []interface{}{map[string]interface{}{
+			"type":            "message",
+			"mode":            "active",
+			"timestamp":       time.Now().UnixMilli(),
+			"webhookEventId":  "loadtest-" + id,
+			"deliveryContext": map[string]bool{"isRedelivery": false},
+			"replyToken":      "loadtest-" + id,
+			"source":          map[string]string{"type": "user", "userId": userID},
+			"message":         map[string]string{"type": "text", "id": id, "quoteToken": "loadtest", "text": text},
+		}},
+	})
+	return body
+}
+
+// percentile returns the p-th percentile (nearest rank) of durations
+func percentile(durations []time.Duration, p int) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// loadTestUserID is the LINE user ID of seeded user i
+func loadTestUserID(i int) string {
+	return fmt.Sprintf("Uloadtest%04d", i+1)
+}
+
+// argInt returns os.Args[i] as a positive number, def when missing
+func argInt(i, def int) int {
+	if len(os.Args) <= i {
+		return def
+	}
+	n, err := strconv.Atoi(os.Args[i])
+	if err != nil || n <= 0 {
+		usage()
+	}
+	return n
+}
+
+func usage() {
+	fmt.Println("usage: loadtest seed [users] [transactions]")
+	fmt.Println("       loadtest run <webhook URL> [noai|ai|mixed] [users] [requests]")
+	os.Exit(2)
+}
@@ -56,6 +56,9 @@ func main() {
 	// Create AI service
 	ai := services.NewAIService()
 	defer ai.Close()
+	if os.Getenv("AI_TWO_STAGE") == "true" {
+		ai.EnableTwoStage()
+	}
 
 	results := make([]TestResult, 0)
 	passed := 0
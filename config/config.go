@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -16,6 +18,17 @@ type Config struct {
 	LineChannelSecret      string
 	LineChannelAccessToken string
 
+	// LINE webhook routes: primary path and optional secondary path (canary or a second bot persona),
+	// each with its own comma-separated middleware chain, e.g. "body_limit,latency"
+	LineWebhookPath                string
+	LineWebhookMiddleware          string
+	LineWebhookSecondaryPath       string
+	LineWebhookSecondaryMiddleware string
+
+	// Channel of the second bot persona on the secondary path (optional, same channel when empty)
+	LineSecondaryChannelSecret      string
+	LineSecondaryChannelAccessToken string
+
 	// MongoDB Atlas
 	MongoDBURI  string
 	MongoDBName string
@@ -23,25 +36,147 @@ type Config struct {
 	// Firebase Cloud Storage (optional)
 	FirebaseCredentials   string // JSON string of service account credentials
 	FirebaseStorageBucket string
+
+	// Public URL of this service, used for short download links (optional)
+	PublicBaseURL string
+
+	// Google OAuth client for Sheets sync (optional, requires PublicBaseURL)
+	GoogleClientID     string
+	GoogleClientSecret string
+
+	// Chat retention: messages kept for AI context, and days kept in chat archive (0 = forever)
+	ChatHistoryLimit int
+	ChatArchiveDays  int
+
+	// LIFF app for amount number pad (optional, endpoint URL = PublicBaseURL + "/liff")
+	LIFFID string
+
+	// LINE Login channel ID of the mobile/web client using /api/v1/sync (optional, defaults to the LIFF channel)
+	SyncLoginChannelID string
+
+	// Allow push for replies longer than 5 messages (off by default: reply only, see markdown/rules.md)
+	LinePushEnabled bool
+
+	// Classify intent first, then extract with a narrow action prompt (prompts/intent.md, prompts/extract.md)
+	AITwoStage bool
+
+	// LINE user IDs allowed to use maintainer commands like "รายงานการใช้งาน" (comma separated)
+	AdminLineIDs []string
+
+	// Default rollout of feature flags, e.g. "two_stage_ai:20,draft_mode:0" (changed later with "ฟีเจอร์" command)
+	FeatureFlags string
+
+	// Bearer secret for POST /cron/backup scheduled backups (optional, requires Firebase)
+	BackupCronSecret string
+
+	// Bearer secret for POST /cron/pending-reminders (push before pending slips/edits expire, optional)
+	PendingReminderCronSecret string
+
+	// Bearer secret for POST /cron/archive and how many years of daily records stay hot (optional)
+	ArchiveCronSecret string
+	ArchiveAfterYears int
+
+	// Bearer secret for POST /admin/reload-prompts and how often prompt files are checked for changes
+	// (seconds, 0 = only reload through the endpoint)
+	PromptReloadSecret        string
+	PromptWatchIntervalSecond int
+
+	// Sentry error reporting (optional, errors are only logged when empty)
+	SentryDSN         string
+	SentryEnvironment string
+
+	// Receipt image limits before AI OCR and storage
+	ImageMaxDimension int // pixels, longest side
+	ImageMaxKB        int
+	ImageJPEGQuality  int
+
+	// Parse only the regular Thai font and reuse it for bold in PDFs (less memory per instance)
+	PDFFontLite bool
+
+	// Slip verification provider checked before a transfer slip is recorded (optional)
+	SlipVerifyURL    string
+	SlipVerifyAPIKey string
+
+	// Exchange rates to THB for travel mode (Frankfurter-compatible API, "off" disables)
+	FXRateURL string
+
+	// Server secret the transaction hash chain is keyed with (HMAC); without it anyone who can write
+	// to the database can rebuild a chain that still verifies
+	HashChainSecret string
+}
+
+// HasSecondaryPersona reports whether the secondary webhook path serves a different LINE channel
+func (c *Config) HasSecondaryPersona() bool {
+	return c.LineWebhookSecondaryPath != "" && c.LineSecondaryChannelSecret != ""
 }
 
 func (c *Config) HasFirebase() bool {
 	return c.FirebaseCredentials != "" && c.FirebaseStorageBucket != ""
 }
 
+// HasGoogleSheets reports whether Google Sheets sync can be enabled
+func (c *Config) HasGoogleSheets() bool {
+	return c.GoogleClientID != "" && c.GoogleClientSecret != "" && c.PublicBaseURL != ""
+}
+
+// SyncChannelID returns the LINE Login channel accepted by the sync API ("" disables it)
+// The LIFF ID starts with its channel ID ("1234567890-AbCdEfGh")
+func (c *Config) SyncChannelID() string {
+	if c.SyncLoginChannelID != "" {
+		return c.SyncLoginChannelID
+	}
+	if c.LIFFID == "" {
+		return ""
+	}
+	return strings.SplitN(c.LIFFID, "-", 2)[0]
+}
+
 func Load() (*Config, error) {
 	// Load .env file if exists
 	_ = godotenv.Load()
 
 	cfg := &Config{
-		Port:                   getEnv("PORT", "3000"),
-		GinMode:                getEnv("GIN_MODE", "debug"),
-		LineChannelSecret:      getEnv("LINE_CHANNEL_SECRET", ""),
-		LineChannelAccessToken: getEnv("LINE_CHANNEL_ACCESS_TOKEN", ""),
-		MongoDBURI:             getEnv("MONGODB_ATLAS_URI", ""),
-		MongoDBName:            getEnv("MONGODB_ATLAS_DBNAME", "satistang"),
-		FirebaseCredentials:    getEnv("FIREBASE_CREDENTIALS", ""),
-		FirebaseStorageBucket:  getEnv("FIREBASE_STORAGE_BUCKET", ""),
+		Port:                            getEnv("PORT", "3000"),
+		GinMode:                         getEnv("GIN_MODE", "debug"),
+		LineChannelSecret:               getEnv("LINE_CHANNEL_SECRET", ""),
+		LineChannelAccessToken:          getEnv("LINE_CHANNEL_ACCESS_TOKEN", ""),
+		LineWebhookPath:                 getEnv("LINE_WEBHOOK_PATH", "/webhook/line"),
+		LineWebhookMiddleware:           getEnv("LINE_WEBHOOK_MIDDLEWARE", ""),
+		LineWebhookSecondaryPath:        getEnv("LINE_WEBHOOK_SECONDARY_PATH", ""),
+		LineWebhookSecondaryMiddleware:  getEnv("LINE_WEBHOOK_SECONDARY_MIDDLEWARE", ""),
+		LineSecondaryChannelSecret:      getEnv("LINE_SECONDARY_CHANNEL_SECRET", ""),
+		LineSecondaryChannelAccessToken: getEnv("LINE_SECONDARY_CHANNEL_ACCESS_TOKEN", ""),
+		MongoDBURI:                      getEnv("MONGODB_ATLAS_URI", ""),
+		MongoDBName:                     getEnv("MONGODB_ATLAS_DBNAME", "satistang"),
+		FirebaseCredentials:             getEnv("FIREBASE_CREDENTIALS", ""),
+		FirebaseStorageBucket:           getEnv("FIREBASE_STORAGE_BUCKET", ""),
+		PublicBaseURL:                   getEnv("PUBLIC_BASE_URL", ""),
+		GoogleClientID:                  getEnv("GOOGLE_OAUTH_CLIENT_ID", ""),
+		GoogleClientSecret:              getEnv("GOOGLE_OAUTH_CLIENT_SECRET", ""),
+		LIFFID:                          getEnv("LINE_LIFF_ID", ""),
+		SyncLoginChannelID:              getEnv("SYNC_LOGIN_CHANNEL_ID", ""),
+		ChatHistoryLimit:                getEnvInt("CHAT_HISTORY_LIMIT", 20),
+		ChatArchiveDays:                 getEnvInt("CHAT_ARCHIVE_DAYS", 365),
+		LinePushEnabled:                 getEnv("LINE_PUSH_ENABLED", "") == "true",
+		AITwoStage:                      getEnv("AI_TWO_STAGE", "") == "true",
+		AdminLineIDs:                    strings.Split(getEnv("ADMIN_LINE_IDS", ""), ","),
+		SentryDSN:                       getEnv("SENTRY_DSN", ""),
+		FeatureFlags:                    getEnv("FEATURE_FLAGS", ""),
+		BackupCronSecret:                getEnv("BACKUP_CRON_SECRET", ""),
+		PendingReminderCronSecret:       getEnv("PENDING_REMINDER_CRON_SECRET", ""),
+		ArchiveCronSecret:               getEnv("ARCHIVE_CRON_SECRET", ""),
+		ArchiveAfterYears:               getEnvInt("ARCHIVE_AFTER_YEARS", 3),
+		PromptReloadSecret:              getEnv("PROMPT_RELOAD_SECRET", ""),
+		PromptWatchIntervalSecond:       getEnvInt("PROMPT_WATCH_INTERVAL", 0),
+		SentryEnvironment:               getEnv("SENTRY_ENVIRONMENT", "production"),
+		ImageMaxDimension:               getEnvInt("IMAGE_MAX_DIMENSION", 1600),
+		ImageMaxKB:                      getEnvInt("IMAGE_MAX_KB", 1024),
+		ImageJPEGQuality:                getEnvIntRange("IMAGE_JPEG_QUALITY", 80, 50, 100), // image compression never goes below 50
+		PDFFontLite:                     getEnv("PDF_FONT_LITE", "") == "true",
+		SlipVerifyURL:                   getEnv("SLIP_VERIFY_URL", ""),
+		SlipVerifyAPIKey:                getEnv("SLIP_VERIFY_API_KEY", ""),
+		FXRateURL:                       getEnv("FX_RATE_API_URL", "https://api.frankfurter.app"),
+		HashChainSecret:                 getEnv("HASH_CHAIN_SECRET", ""),
 	}
 
 	if err := cfg.Validate(); err != nil {
@@ -61,6 +196,20 @@ func (c *Config) Validate() error {
 	if c.MongoDBURI == "" {
 		return fmt.Errorf("MONGODB_ATLAS_URI is required")
 	}
+	if !strings.HasPrefix(c.LineWebhookPath, "/") {
+		return fmt.Errorf("LINE_WEBHOOK_PATH must start with /")
+	}
+	if c.LineWebhookSecondaryPath != "" {
+		if !strings.HasPrefix(c.LineWebhookSecondaryPath, "/") {
+			return fmt.Errorf("LINE_WEBHOOK_SECONDARY_PATH must start with /")
+		}
+		if c.LineWebhookSecondaryPath == c.LineWebhookPath {
+			return fmt.Errorf("LINE_WEBHOOK_SECONDARY_PATH must differ from LINE_WEBHOOK_PATH")
+		}
+	}
+	if (c.LineSecondaryChannelSecret == "") != (c.LineSecondaryChannelAccessToken == "") {
+		return fmt.Errorf("LINE_SECONDARY_CHANNEL_SECRET and LINE_SECONDARY_CHANNEL_ACCESS_TOKEN must be set together")
+	}
 	return nil
 }
 
@@ -70,3 +219,15 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value >= 0 {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvIntRange reads an int and clamps it to [lo, hi]
+func getEnvIntRange(key string, defaultValue, lo, hi int) int {
+	return min(max(getEnvInt(key, defaultValue), lo), hi)
+}
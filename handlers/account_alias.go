@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/satisatang/backend/services"
+)
+
+// accountAliasSetPattern matches "ตั้งชื่อบัญชี บัญชีเงินเดือน = กสิกร xxx-1234" (nickname first)
+var accountAliasSetPattern = regexp.MustCompile(`^(?:ตั้งชื่อเล่นบัญชี|ตั้งชื่อบัญชี|ชื่อเล่นบัญชี)\s*(.+?)\s*(?:=|คือ)\s*(.+)$`)
+
+// accountAliasNamePattern matches "ตั้งชื่อบัญชีกสิกร 1234 ว่าบัญชีเงินเดือน" / "ตั้งชื่อบัตร KTC ว่าบัตรเที่ยว" (account first)
+var accountAliasNamePattern = regexp.MustCompile(`^ตั้งชื่อ(?:เล่น)?\s*((?:บัญชี|บัตร|ธนาคาร).+?)\s*ว่า\s*(.+)$`)
+
+// accountAliasDeletePrefixes remove a nickname
+var accountAliasDeletePrefixes = []string{"ลบชื่อเล่นบัญชี", "ลบชื่อบัญชี", "ยกเลิกชื่อบัญชี", "ลบชื่อเล่น"}
+
+// accountAliasCommand is a parsed account nickname command
+type accountAliasCommand struct {
+	Action string // "set", "delete", "list"
+	Alias  string
+	Target string // raw account text, resolved with the user's names
+}
+
+// parseAccountAliasCommand parses account nickname commands (no AI)
+func parseAccountAliasCommand(text string) (accountAliasCommand, bool) {
+	text = strings.TrimSpace(text)
+	switch text {
+	case "ชื่อบัญชี", "ดูชื่อบัญชี", "ชื่อเล่นบัญชี", "ดูชื่อเล่นบัญชี":
+		return accountAliasCommand{Action: "list"}, true
+	}
+
+	for _, prefix := range accountAliasDeletePrefixes {
+		if strings.HasPrefix(text, prefix) {
+			if alias := strings.TrimSpace(strings.TrimPrefix(text, prefix)); alias != "" {
+				return accountAliasCommand{Action: "delete", Alias: alias}, true
+			}
+			return accountAliasCommand{}, false
+		}
+	}
+
+	if m := accountAliasSetPattern.FindStringSubmatch(text); m != nil {
+		return accountAliasCommand{Action: "set", Alias: m[1], Target: m[2]}, true
+	}
+	if m := accountAliasNamePattern.FindStringSubmatch(text); m != nil {
+		return accountAliasCommand{Action: "set", Alias: m[2], Target: m[1]}, true
+	}
+	return accountAliasCommand{}, false
+}
+
+// handleAccountAliasCommand creates, lists or removes account nicknames
+func (h *LineWebhookHandler) handleAccountAliasCommand(ctx context.Context, replyToken, userID string, cmd accountAliasCommand) {
+	switch cmd.Action {
+	case "set":
+		profile, err := h.mongo.GetUserProfile(ctx, userID)
+		if err != nil {
+			log.Printf("Failed to get user profile: %v", err)
+			h.replyText(replyToken, "ไม่สามารถตั้งชื่อบัญชีได้ กรุณาลองใหม่")
+			return
+		}
+		account, ok := services.ParsePaymentTarget(cmd.Target, profile.Banks, profile.CreditCards, profile.AccountAliases)
+		if !ok {
+			h.replyText(replyToken, "ไม่เข้าใจบัญชีค่ะ ตัวอย่าง: ตั้งชื่อบัญชี บัญชีเงินเดือน = กสิกร 1234")
+			return
+		}
+		if err := h.mongo.SetAccountAlias(ctx, userID, cmd.Alias, account); err != nil {
+			log.Printf("Failed to set account alias: %v", err)
+			h.replyText(replyToken, "ไม่สามารถตั้งชื่อบัญชีได้ กรุณาลองใหม่")
+			return
+		}
+		h.replyText(replyToken, fmt.Sprintf("ตั้งแล้วค่ะ \"%s\" = %s\nพิมพ์เช่น \"โอน 5000 จาก%sไปเงินสด\" ได้เลย\nลบ: พิมพ์ \"ลบชื่อบัญชี %s\"",
+			cmd.Alias, getPaymentName(account.UseType, account.BankName, account.CreditCardName), cmd.Alias, cmd.Alias))
+
+	case "delete":
+		deleted, err := h.mongo.DeleteAccountAlias(ctx, userID, cmd.Alias)
+		if err != nil {
+			log.Printf("Failed to delete account alias: %v", err)
+			h.replyText(replyToken, "ไม่สามารถลบชื่อบัญชีได้ กรุณาลองใหม่")
+			return
+		}
+		if !deleted {
+			h.replyText(replyToken, fmt.Sprintf("ไม่พบชื่อบัญชี \"%s\" ค่ะ", cmd.Alias))
+			return
+		}
+		h.replyText(replyToken, fmt.Sprintf("ลบชื่อบัญชี \"%s\" แล้วค่ะ", cmd.Alias))
+
+	default:
+		profile, err := h.mongo.GetUserProfile(ctx, userID)
+		if err != nil {
+			log.Printf("Failed to get user profile: %v", err)
+			h.replyText(replyToken, "ไม่สามารถดึงข้อมูลได้ กรุณาลองใหม่")
+			return
+		}
+		if len(profile.AccountAliases) == 0 {
+			h.replyText(replyToken, "ยังไม่มีชื่อบัญชีค่ะ\nตัวอย่าง: ตั้งชื่อบัญชี บัญชีเงินเดือน = กสิกร 1234")
+			return
+		}
+		lines := []string{"🏷️ ชื่อบัญชี"}
+		for _, a := range profile.AccountAliases {
+			lines = append(lines, fmt.Sprintf("• %s → %s", a.Alias, getPaymentName(a.UseType, a.BankName, a.CreditCardName)))
+		}
+		lines = append(lines, "", "ลบ: ลบชื่อบัญชี <ชื่อ>")
+		h.replyText(replyToken, strings.Join(lines, "\n"))
+	}
+}
+
+// handleAccountTransfer records "โอน 5000 จากบัญชีเงินเดือนไปบัญชีออม" without AI when both sides are
+// nicknames or known accounts; anything else is left to the AI
+func (h *LineWebhookHandler) handleAccountTransfer(ctx context.Context, replyToken, userID, text string) bool {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "โอน") || !strings.Contains(text, "จาก") {
+		return false
+	}
+	profile, err := h.mongo.GetUserProfile(ctx, userID)
+	if err != nil {
+		return false
+	}
+	transfer, ok := services.ParseAccountTransfer(text, profile.Banks, profile.CreditCards, profile.AccountAliases)
+	if !ok {
+		return false
+	}
+	transferID, _, err := h.mongo.SaveTransfer(ctx, userID, transfer)
+	if err != nil {
+		if msg, locked := periodLockedText(err); locked {
+			h.replyText(replyToken, msg)
+			return true
+		}
+		log.Printf("Failed to save transfer: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ บันทึกการโอนไม่สำเร็จ กรุณาลองใหม่อีกครั้ง")
+		return true
+	}
+	h.replyTransferFlex(replyToken, userID, transfer, transferID, "บันทึกการโอนแล้วค่ะ")
+	return true
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// accountBalanceRecentRows is how many latest entries the account balance card lists
+const accountBalanceRecentRows = 5
+
+// handleAccountBalanceQuery answers "ยอด กสิกร" with that account's balance, month-to-date in/out
+// and latest entries (no AI)
+func (h *LineWebhookHandler) handleAccountBalanceQuery(ctx context.Context, replyToken, userID, text string) bool {
+	if !strings.HasPrefix(strings.TrimSpace(text), "ยอด") {
+		return false
+	}
+	profile, err := h.mongo.GetUserProfile(ctx, userID)
+	if err != nil {
+		return false
+	}
+	account, ok := services.ParseAccountBalanceQuery(text, profile.Banks, profile.CreditCards, profile.AccountAliases)
+	if !ok {
+		return false
+	}
+
+	balances, err := h.mongo.GetBalanceByPaymentType(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to get account balance: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถดึงยอดคงเหลือได้")
+		return true
+	}
+	balance := services.FindPaymentBalance(balances, account)
+
+	now := time.Now()
+	today := now.Format("2006-01-02")
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).Format("2006-01-02")
+	monthly, err := h.mongo.SearchByPaymentRange(ctx, userID, account, monthStart, today, 0)
+	if err != nil {
+		log.Printf("Failed to search by payment: %v", err)
+	}
+	recent, err := h.mongo.SearchByPaymentRange(ctx, userID, account, "", today, accountBalanceRecentRows)
+	if err != nil {
+		log.Printf("Failed to search by payment: %v", err)
+	}
+	h.replyAccountBalance(replyToken, account, balance, monthly, recent)
+	return true
+}
+
+// replyAccountBalance renders one account: balance on top, this month's in/out, then latest entries
+func (h *LineWebhookHandler) replyAccountBalance(replyToken string, account services.AccountRef, balance services.PaymentBalance, monthly, recent []services.SearchResult) {
+	name := getPaymentName(account.UseType, account.BankName, account.CreditCardName)
+	headerColor := "#2C3E50"
+	balanceLabel := "คงเหลือ"
+	if account.UseType == 1 {
+		headerColor = "#8E44AD"
+		if balance.Balance < 0 {
+			balanceLabel = "ค้างจ่าย"
+		}
+	}
+
+	var spent, received float64
+	for _, r := range monthly {
+		if r.Transaction.Type == 1 {
+			received += r.Transaction.Amount
+		} else {
+			spent += r.Transaction.Amount
+		}
+	}
+	inLabel := "รับเข้า"
+	if account.UseType == 1 {
+		inLabel = "ชำระ/คืนเงิน"
+	}
+
+	body := []interface{}{
+		map[string]interface{}{"type": "text", "text": "เดือนนี้", "size": "xs", "color": "#888888"},
+		map[string]interface{}{
+			"type": "box", "layout": "horizontal", "margin": "sm",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": inLabel, "size": "sm", "color": "#888888"},
+				map[string]interface{}{"type": "text", "text": "+" + formatNumber(received), "size": "sm", "weight": "bold", "color": "#27AE60", "align": "end"},
+			},
+		},
+		map[string]interface{}{
+			"type": "box", "layout": "horizontal", "margin": "sm",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "ใช้ไป", "size": "sm", "color": "#888888"},
+				map[string]interface{}{"type": "text", "text": "-" + formatNumber(spent), "size": "sm", "weight": "bold", "color": "#E74C3C", "align": "end"},
+			},
+		},
+		map[string]interface{}{"type": "separator", "margin": "md"},
+		map[string]interface{}{"type": "text", "text": "รายการล่าสุด", "size": "xs", "color": "#888888", "margin": "md"},
+	}
+	if len(recent) == 0 {
+		body = append(body, map[string]interface{}{"type": "text", "text": "ยังไม่มีรายการ", "size": "sm", "color": "#888888", "align": "center", "margin": "md"})
+	}
+	for _, r := range recent {
+		tx := r.Transaction
+		amountColor, sign := "#E74C3C", "-"
+		if tx.Type == 1 {
+			amountColor, sign = "#27AE60", "+"
+		}
+		body = append(body, map[string]interface{}{
+			"type": "box", "layout": "horizontal", "margin": "sm",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": formatThaiShortDate(r.Date), "size": "xxs", "color": "#888888", "flex": 2},
+				map[string]interface{}{"type": "text", "text": orDefault(tx.Description, tx.Category), "size": "xs", "flex": 4, "wrap": true, "maxLines": 1},
+				map[string]interface{}{"type": "text", "text": sign + formatNumber(tx.Amount), "size": "xs", "color": amountColor, "align": "end", "flex": 3},
+			},
+		})
+	}
+
+	flex := map[string]interface{}{
+		"type": "bubble",
+		"header": map[string]interface{}{
+			"type":            "box",
+			"layout":          "vertical",
+			"backgroundColor": headerColor,
+			"paddingAll":      "md",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": name, "color": "#FFFFFF", "weight": "bold", "size": "md"},
+				map[string]interface{}{"type": "text", "text": balanceLabel, "color": "#FFFFFF", "size": "xs", "margin": "sm"},
+				map[string]interface{}{"type": "text", "text": formatBalanceText(balance.Balance), "color": "#FFFFFF", "weight": "bold", "size": "xl"},
+			},
+		},
+		"body": map[string]interface{}{
+			"type":       "box",
+			"layout":     "vertical",
+			"paddingAll": "md",
+			"contents":   body,
+		},
+	}
+	summary := fmt.Sprintf("%s %s %s\nเดือนนี้ +%s / -%s", name, balanceLabel, formatBalanceText(balance.Balance), formatNumber(received), formatNumber(spent))
+	if !h.replyFlexFromAI(replyToken, flex, summary) {
+		h.replyText(replyToken, summary)
+	}
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// pendingAmount is a transaction held until user picks the amount
+// PaidInBaht keeps whether the message said "บาท", so travel mode converts the chosen amount like text entry
+type pendingAmount struct {
+	services.TransactionData
+	PaidInBaht bool `json:"paid_in_baht,omitempty"`
+}
+
+// replyAmountConfirm asks user to confirm amount when AI's amount doesn't match the message
+// Transaction is kept in temp data until user chooses
+func (h *LineWebhookHandler) replyAmountConfirm(ctx context.Context, replyToken, userID string, tx *services.TransactionData, check services.AmountCheck, paidInBaht bool) bool {
+	txJSON, _ := json.Marshal(pendingAmount{TransactionData: *tx, PaidInBaht: paidInBaht})
+	key := fmt.Sprintf("amount_%s_%d", userID, time.Now().Unix())
+	if err := h.mongo.SaveTempData(ctx, key, string(txJSON), 10*time.Minute); err != nil {
+		log.Printf("Failed to save pending amount: %v", err)
+		return false
+	}
+
+	desc := orDefault(tx.Description, tx.Category)
+	var buttons []interface{}
+	if check.Suggested > 0 {
+		buttons = append(buttons, map[string]interface{}{
+			"type": "button", "style": "primary", "height": "sm", "color": "#27AE60",
+			"action": map[string]interface{}{
+				"type":        "postback",
+				"label":       truncateLabel("ใช้ "+formatNumber(check.Suggested)+" บาท", 20),
+				"data":        fmt.Sprintf("action=amount_confirm&key=%s&amount=%.2f", key, check.Suggested),
+				"displayText": "ใช้ " + formatNumber(check.Suggested) + " บาท",
+			},
+		})
+	}
+	buttons = append(buttons,
+		map[string]interface{}{
+			"type": "button", "style": "secondary", "height": "sm",
+			"action": map[string]interface{}{
+				"type":        "postback",
+				"label":       truncateLabel("ใช้ "+formatNumber(tx.Amount)+" บาท", 20),
+				"data":        fmt.Sprintf("action=amount_confirm&key=%s&amount=%.2f", key, tx.Amount),
+				"displayText": "ใช้ " + formatNumber(tx.Amount) + " บาท",
+			},
+		},
+		map[string]interface{}{
+			"type": "button", "style": "link", "height": "sm",
+			"action": map[string]interface{}{
+				"type":        "postback",
+				"label":       "ยกเลิก",
+				"data":        "action=amount_confirm&key=" + key + "&cancel=1",
+				"displayText": "ยกเลิก",
+			},
+		},
+	)
+
+	flex := map[string]interface{}{
+		"type": "bubble",
+		"size": "kilo",
+		"header": map[string]interface{}{
+			"type":            "box",
+			"layout":          "vertical",
+			"backgroundColor": "#F39C12",
+			"paddingAll":      "sm",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "⚠️ ยืนยันยอดเงิน", "color": "#FFFFFF", "weight": "bold", "size": "sm"},
+			},
+		},
+		"body": map[string]interface{}{
+			"type":       "box",
+			"layout":     "vertical",
+			"paddingAll": "md",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": desc, "size": "sm", "weight": "bold", "wrap": true},
+				map[string]interface{}{"type": "text", "text": "ยอดที่อ่านได้ไม่ตรงกับข้อความ กรุณาเลือกยอดที่ถูกต้อง", "size": "xs", "color": "#888888", "wrap": true, "margin": "sm"},
+			},
+		},
+		"footer": map[string]interface{}{
+			"type":     "box",
+			"layout":   "vertical",
+			"spacing":  "sm",
+			"contents": buttons,
+		},
+	}
+
+	if !h.replyFlexFromAI(replyToken, flex, "ยืนยันยอดเงิน") {
+		h.mongo.DeleteTempData(ctx, key)
+		return false
+	}
+	return true
+}
+
+// handleAmountConfirm saves pending transaction with the amount user chose
+func (h *LineWebhookHandler) handleAmountConfirm(ctx context.Context, replyToken, userID string, params map[string]string) {
+	key := params["key"]
+	txJSON, err := h.mongo.GetTempData(ctx, key)
+	if err != nil {
+		h.replyText(replyToken, pendingExpiredText)
+		return
+	}
+	h.mongo.DeleteTempData(ctx, key)
+
+	if params["cancel"] == "1" {
+		h.replyText(replyToken, "ยกเลิกรายการแล้วค่ะ")
+		return
+	}
+
+	var pending pendingAmount
+	if err := json.Unmarshal([]byte(txJSON), &pending); err != nil {
+		log.Printf("Failed to parse pending amount: %v", err)
+		h.replyText(replyToken, "เกิดข้อผิดพลาด กรุณาพิมพ์ใหม่อีกครั้ง")
+		return
+	}
+
+	var amount float64
+	if _, err := fmt.Sscanf(params["amount"], "%f", &amount); err != nil || amount <= 0 {
+		h.replyText(replyToken, "ยอดเงินไม่ถูกต้อง กรุณาพิมพ์ใหม่อีกครั้ง")
+		return
+	}
+	tx := pending.TransactionData
+	tx.Amount = amount
+
+	// Travel mode: the chosen amount is in the trip currency, converted before limits and saving
+	settings, _ := h.mongo.GetUserSettings(ctx, userID)
+	if settings != nil && settings.Trip != nil {
+		txs := []services.TransactionData{tx}
+		services.ApplyTripCurrency(settings.Trip, txs, pending.PaidInBaht)
+		tx = txs[0]
+	}
+
+	// Chosen amount may still exceed a per-transaction limit or a child account's cap
+	if h.replyGuardrailConfirm(ctx, replyToken, userID, []services.TransactionData{tx}, batchSave{}) {
+		return
+	}
+	if h.holdForParentApproval(ctx, replyToken, userID, settings, []services.TransactionData{tx}) {
+		return
+	}
+
+	// Save on the date resolved from the original message (same as text entry)
+	date := tx.Date
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
+	}
+	txID, err := h.mongo.SaveTransactionOnDate(ctx, userID, &tx, date)
+	if err != nil {
+		if msg, ok := h.spendingCapText(err); ok {
+			h.replyText(replyToken, msg)
+			return
+		}
+		log.Printf("Failed to save transaction: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถบันทึกข้อมูลได้")
+		return
+	}
+	if !h.replyTransactionsFlex(ctx, userID, replyToken, []services.TransactionData{tx}, "", txID, "") {
+		h.replyText(replyToken, fmt.Sprintf("บันทึก %s %s บาทแล้วค่ะ", orDefault(tx.Description, tx.Category), formatNumber(tx.Amount)))
+	}
+}
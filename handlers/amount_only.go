@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+	"github.com/satisatang/backend/services"
+)
+
+// amountOnlyCategories are suggested when the user has no history yet
+var amountOnlyCategories = []string{"อาหาร", "เครื่องดื่ม", "เดินทาง", "ช้อปปิ้ง", "ของใช้", "ค่าน้ำค่าไฟ"}
+
+// amountOnlyKey is the temp data key for a number waiting for its description
+func amountOnlyKey(userID string) string {
+	return "amount_only_" + userID
+}
+
+// handleAmountOnly asks "100 บาทนี่ค่าอะไรคะ?" when the message is just a number or math ("150*3") (no AI guess)
+func (h *LineWebhookHandler) handleAmountOnly(ctx context.Context, replyToken, userID, text string) bool {
+	amount, ok := services.ParseThaiAmount(text)
+	pending, question := strconv.FormatFloat(amount, 'f', -1, 64), formatNumber(amount)+" บาทนี่ค่าอะไรคะ?"
+	if calc, isMath := services.ParseArithmetic(text); !ok && isMath && calc.Label == "" && len(calc.Parts) == 1 {
+		// The expression is kept so the completed entry lists its components
+		amount, ok = calc.Parts[0].Value, true
+		pending, question = calc.Parts[0].Expr, fmt.Sprintf("%s = %s บาท นี่ค่าอะไรคะ?", calc.Parts[0].Expr, formatNumber(amount))
+	}
+	if !ok || amount <= 0 {
+		return false
+	}
+	if err := h.mongo.SaveTempData(ctx, amountOnlyKey(userID), pending, 10*time.Minute); err != nil {
+		log.Printf("Failed to save pending amount: %v", err)
+		return false
+	}
+
+	categories := amountOnlyCategories
+	if profile, err := h.mongo.GetUserProfile(ctx, userID); err == nil && len(profile.ExpenseCategories) > 0 {
+		categories = profile.ExpenseCategories
+	}
+	items := make([]messaging_api.QuickReplyItem, 0, 10)
+	for _, cat := range categories {
+		if len(items) == 8 {
+			break
+		}
+		items = append(items, messaging_api.QuickReplyItem{
+			Action: &messaging_api.MessageAction{Label: truncateLabel(cat, 20), Text: cat},
+		})
+	}
+	items = append(items,
+		messaging_api.QuickReplyItem{Action: &messaging_api.MessageAction{Label: "💰 รายรับ", Text: "รายรับ"}},
+		messaging_api.QuickReplyItem{Action: &messaging_api.MessageAction{Label: "❌ ยกเลิก", Text: "ยกเลิก"}},
+	)
+
+	flex := map[string]interface{}{
+		"type": "bubble",
+		"size": "kilo",
+		"body": map[string]interface{}{
+			"type":       "box",
+			"layout":     "vertical",
+			"paddingAll": "md",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": question, "weight": "bold", "size": "md", "wrap": true},
+				map[string]interface{}{"type": "text", "text": "เลือกหมวดด้านล่าง หรือพิมพ์ชื่อรายการ เช่น \"ข้าวมันไก่\"", "size": "xs", "color": "#888888", "wrap": true, "margin": "sm"},
+			},
+		},
+	}
+	if !h.replyFlexWithQuickReply(replyToken, flex, question, &messaging_api.QuickReply{Items: items}) {
+		h.mongo.DeleteTempData(ctx, amountOnlyKey(userID))
+		return false
+	}
+	return true
+}
+
+// completeAmountOnly joins a description typed after a bare number ("ข้าวมันไก่" -> "ข้าวมันไก่ 100");
+// "" when nothing is pending or the message has its own amount
+func (h *LineWebhookHandler) completeAmountOnly(ctx context.Context, userID, text string) string {
+	pending, err := h.mongo.GetTempData(ctx, amountOnlyKey(userID))
+	if err != nil || pending == "" {
+		return ""
+	}
+	h.mongo.DeleteTempData(ctx, amountOnlyKey(userID))
+
+	text = strings.TrimSpace(text)
+	// A message with its own amount is a new entry; the pending number is dropped
+	for _, r := range text {
+		if unicode.IsDigit(r) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("%s %s", text, pending)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// SetAdmins sets LINE user IDs allowed to use maintainer commands (e.g. usage report)
+func (h *LineWebhookHandler) SetAdmins(lineIDs []string) {
+	h.admins = make(map[string]bool)
+	for _, id := range lineIDs {
+		if id = strings.TrimSpace(id); id != "" {
+			h.admins[id] = true
+		}
+	}
+}
+
+// trackEvent records an anonymized analytics event without delaying the reply
+func (h *LineWebhookHandler) trackEvent(userID, action, source string, start time.Time, success, correction bool) {
+	event := services.AnalyticsEvent{
+		Action:     action,
+		Source:     source,
+		Success:    success,
+		LatencyMs:  time.Since(start).Milliseconds(),
+		Correction: correction,
+	}
+	services.GoSafe("analytics.track", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		h.mongo.LogAnalyticsEvent(ctx, userID, event)
+	})
+}
+
+// parseAnalyticsReportCommand matches "รายงานการใช้งาน" or "รายงานการใช้งาน 30" (days)
+func parseAnalyticsReportCommand(text string) (int, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(text), "รายงานการใช้งาน")
+	if !ok {
+		return 0, false
+	}
+	rest = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(rest), "วัน"))
+	if rest == "" {
+		return 7, true
+	}
+	days, err := strconv.Atoi(rest)
+	if err != nil || days <= 0 {
+		return 0, false
+	}
+	return days, true
+}
+
+// replyAnalyticsReport shows usage, failure and correction rates per action (admins only)
+func (h *LineWebhookHandler) replyAnalyticsReport(ctx context.Context, replyToken string, days int) {
+	stats, err := h.mongo.GetAnalyticsReport(ctx, days)
+	if err != nil {
+		log.Printf("Failed to get analytics report: %v", err)
+		h.replyText(replyToken, "ไม่สามารถดึงรายงานได้ค่ะ")
+		return
+	}
+	if len(stats) == 0 {
+		h.replyText(replyToken, fmt.Sprintf("ยังไม่มีข้อมูลการใช้งานใน %d วันค่ะ", days))
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📊 การใช้งาน %d วัน\naction: ครั้ง/ผู้ใช้ | พลาด | แก้ไข | ms\n", days))
+	for i, st := range stats {
+		if i >= 20 {
+			break
+		}
+		sb.WriteString(fmt.Sprintf("\n%s: %d/%d | %.0f%% | %.0f%% | %.0f",
+			st.Action, st.Count, st.Users, percentOf(st.Failures, st.Count), percentOf(st.Corrections, st.Count), st.AvgLatencyMs))
+	}
+	// Recording volume over all users (aggregate only, no user is identified)
+	if tx, err := h.mongo.GetTransactionStats(ctx, "", "month"); err == nil && tx.Count > 0 {
+		sb.WriteString(fmt.Sprintf("\n\n🧾 %s: %d รายการ จาก %d ผู้ใช้\nจ่ายเฉลี่ย %s บาท/รายการ | วันที่บันทึกมากสุด %s (%d)",
+			tx.Label, tx.Count, tx.Users, formatNumber(tx.AvgExpense), formatThaiShortDate(tx.BusiestDay), tx.BusiestCount))
+	}
+	h.replyText(replyToken, sb.String())
+}
+
+// percentOf returns part/total in percent (0 when total is 0)
+func percentOf(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total) * 100
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satisatang/backend/services"
+)
+
+// archivedTag marks a search result read from the archive
+func archivedTag(r services.SearchResult) string {
+	if r.Archived {
+		return " 🗄️"
+	}
+	return ""
+}
+
+// ArchiveCronHandler moves old daily records to the archive, called by an external scheduler
+type ArchiveCronHandler struct {
+	mongo  *services.MongoDBService
+	years  int
+	secret string
+}
+
+// NewArchiveCronHandler creates scheduler endpoint handler (records older than years are archived)
+func NewArchiveCronHandler(mongo *services.MongoDBService, years int, secret string) *ArchiveCronHandler {
+	return &ArchiveCronHandler{mongo: mongo, years: years, secret: secret}
+}
+
+// HandleArchive archives old records of every user (POST, Authorization: Bearer <secret>)
+func (h *ArchiveCronHandler) HandleArchive(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.secret)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Minute)
+	defer cancel()
+	result, err := h.mongo.ArchiveOldRecords(ctx, h.years)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+	"github.com/satisatang/backend/services"
+)
+
+// autocompleteLimit is max quick reply suggestions (LINE allows 13)
+const autocompleteLimit = 8
+
+// isAutocompleteCandidate matches a short word without amount, e.g. "กา" or "ค่ารถ"
+func isAutocompleteCandidate(text string) bool {
+	text = strings.TrimSpace(text)
+	n := utf8.RuneCountInString(text)
+	if n == 0 || n > 12 {
+		return false
+	}
+	for _, r := range text {
+		if unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// replyAutocomplete offers the user's frequent descriptions (with last amount) as quick replies
+// Returns false when nothing matches so the message goes to AI as usual
+func (h *LineWebhookHandler) replyAutocomplete(ctx context.Context, replyToken, userID, text string) bool {
+	if !isAutocompleteCandidate(text) {
+		return false
+	}
+	frequent, err := h.mongo.GetFrequentDescriptions(ctx, userID, 90)
+	if err != nil {
+		log.Printf("Failed to get frequent descriptions: %v", err)
+		return false
+	}
+	matches := services.MatchDescriptionPrefix(frequent, text, autocompleteLimit)
+	if len(matches) == 0 {
+		return false
+	}
+
+	items := make([]messaging_api.QuickReplyItem, 0, len(matches))
+	for _, fd := range matches {
+		suggestion := fmt.Sprintf("%s %s", fd.Description, formatNumber(fd.LastAmount))
+		items = append(items, messaging_api.QuickReplyItem{
+			Action: &messaging_api.MessageAction{Label: truncateLabel(suggestion, 20), Text: suggestion},
+		})
+	}
+
+	_, err = h.sendReply(&messaging_api.ReplyMessageRequest{
+		ReplyToken: replyToken,
+		Messages: []messaging_api.MessageInterface{
+			messaging_api.TextMessage{
+				Text:       "📝 เลือกรายการที่จดบ่อย หรือพิมพ์ยอดต่อท้ายได้เลยค่ะ เช่น \"" + matches[0].Description + " 50\"",
+				QuickReply: &messaging_api.QuickReply{Items: items},
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to send autocomplete: %v", err)
+	}
+	return true
+}
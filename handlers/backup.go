@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+	"github.com/satisatang/backend/services"
+)
+
+// SetBackup enables snapshot/restore commands (nil when storage is not configured)
+func (h *LineWebhookHandler) SetBackup(backup *services.BackupService) {
+	h.backup = backup
+}
+
+// backupBeforeDelete snapshots user data before bulk deletes (failure only logged)
+func (h *LineWebhookHandler) backupBeforeDelete(ctx context.Context, userID string) {
+	if h.backup == nil {
+		return
+	}
+	if _, err := h.backup.BackupUser(ctx, userID, "before delete"); err != nil {
+		log.Printf("Failed to back up before delete: %v", err)
+		services.ReportError("backup.before_delete", userID, err)
+	}
+}
+
+// handleBackupCommand handles "สำรองข้อมูล" and "กู้คืนข้อมูล" (returns false if text is not one)
+func (h *LineWebhookHandler) handleBackupCommand(ctx context.Context, replyToken, userID, text string) bool {
+	command := strings.ReplaceAll(strings.TrimSpace(text), " ", "")
+	if command != "สำรองข้อมูล" && command != "กู้คืนข้อมูล" {
+		return false
+	}
+	if h.backup == nil {
+		h.replyText(replyToken, "ยังไม่ได้เปิดใช้การสำรองข้อมูลค่ะ")
+		return true
+	}
+
+	if command == "สำรองข้อมูล" {
+		name, err := h.backup.BackupUser(ctx, userID, "manual")
+		if err != nil {
+			log.Printf("Failed to back up: %v", err)
+			h.replyText(replyToken, "ไม่สามารถสำรองข้อมูลได้ค่ะ")
+			return true
+		}
+		h.replyText(replyToken, fmt.Sprintf("💾 สำรองข้อมูลแล้วค่ะ (%s)\nพิมพ์ \"กู้คืนข้อมูล\" เพื่อย้อนกลับได้", backupLabel(name)))
+		return true
+	}
+
+	names, err := h.backup.ListBackups(ctx, userID)
+	if err != nil || len(names) == 0 {
+		h.replyText(replyToken, "ยังไม่มีข้อมูลสำรองค่ะ พิมพ์ \"สำรองข้อมูล\" เพื่อสร้าง")
+		return true
+	}
+	var items []messaging_api.QuickReplyItem
+	for i, name := range names {
+		if i >= 10 {
+			break
+		}
+		items = append(items, messaging_api.QuickReplyItem{
+			Action: &messaging_api.PostbackAction{
+				Label:       backupLabel(name),
+				Data:        "action=backup_restore&name=" + name,
+				DisplayText: "กู้คืน " + backupLabel(name),
+			},
+		})
+	}
+	_, err = h.sendReply(&messaging_api.ReplyMessageRequest{
+		ReplyToken: replyToken,
+		Messages: []messaging_api.MessageInterface{
+			messaging_api.TextMessage{
+				Text:       "♻️ เลือกข้อมูลสำรองที่จะกู้คืนค่ะ\n(ข้อมูลปัจจุบันจะถูกสำรองไว้ก่อนเสมอ)",
+				QuickReply: &messaging_api.QuickReply{Items: items},
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to send backup list: %v", err)
+	}
+	return true
+}
+
+// handleBackupRestore asks for confirmation, then restores the chosen snapshot
+func (h *LineWebhookHandler) handleBackupRestore(ctx context.Context, replyToken, userID string, params map[string]string) {
+	name := params["name"]
+	if h.backup == nil || name == "" {
+		h.replyText(replyToken, "ไม่พบข้อมูลสำรองค่ะ")
+		return
+	}
+
+	if params["confirm"] != "1" {
+		_, err := h.sendReply(&messaging_api.ReplyMessageRequest{
+			ReplyToken: replyToken,
+			Messages: []messaging_api.MessageInterface{
+				messaging_api.TextMessage{
+					Text: fmt.Sprintf("⚠️ ข้อมูลทั้งหมดจะย้อนกลับเป็น %s ยืนยันไหมคะ?", backupLabel(name)),
+					QuickReply: &messaging_api.QuickReply{Items: []messaging_api.QuickReplyItem{
+						{Action: &messaging_api.PostbackAction{Label: "✅ ยืนยันกู้คืน", Data: "action=backup_restore&confirm=1&name=" + name, DisplayText: "ยืนยันกู้คืน"}},
+						{Action: &messaging_api.MessageAction{Label: "❌ ยกเลิก", Text: "ยกเลิก"}},
+					}},
+				},
+			},
+		})
+		if err != nil {
+			log.Printf("Failed to send restore confirm: %v", err)
+		}
+		return
+	}
+
+	result, err := h.backup.Restore(ctx, userID, name)
+	if err != nil {
+		log.Printf("Failed to restore backup %s: %v", name, err)
+		services.ReportError("backup.restore", userID, err)
+		h.replyText(replyToken, "ไม่สามารถกู้คืนข้อมูลได้ค่ะ")
+		return
+	}
+	h.mongo.LogSecurityEvent(ctx, services.SecurityEvent{LineID: userID, Event: services.SecurityDataDeleted, Detail: "restored backup " + name})
+	h.replyText(replyToken, fmt.Sprintf("♻️ กู้คืนข้อมูล %s แล้วค่ะ (%d วันที่มีรายการ)\n\n%s",
+		backupLabel(name), result["daily_records"], h.getBalanceText(ctx, userID)))
+}
+
+// backupLabel formats "20261016-030000.json" as "16/10/2026 03:00"
+func backupLabel(name string) string {
+	t, err := time.ParseInLocation("20060102-150405", strings.TrimSuffix(name, ".json"), time.Local)
+	if err != nil {
+		return name
+	}
+	return t.Format("02/01/2006 15:04")
+}
+
+// BackupCronHandler runs scheduled backups, called by an external scheduler
+type BackupCronHandler struct {
+	backup *services.BackupService
+	secret string
+}
+
+// NewBackupCronHandler creates scheduler endpoint handler
+func NewBackupCronHandler(backup *services.BackupService, secret string) *BackupCronHandler {
+	return &BackupCronHandler{backup: backup, secret: secret}
+}
+
+// HandleBackup backs up users active in the last 35 days (POST, Authorization: Bearer <secret>)
+func (h *BackupCronHandler) HandleBackup(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.secret)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Minute)
+	defer cancel()
+	count, err := h.backup.BackupActiveUsers(ctx, 35)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"backed_up": count})
+}
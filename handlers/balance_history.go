@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// balanceAsOfMarkers separate account hint from date: "ยอดกสิกรเมื่อสิ้นเดือนที่แล้ว", "ยอดคงเหลือ ณ 30/9/2569"
+var balanceAsOfMarkers = []string{"เมื่อ", "ณ", "ตอน"}
+
+// parseBalanceAsOfCommand parses historical balance question, returns account hint ("" = all) and date
+func parseBalanceAsOfCommand(text string, now time.Time) (account, date string, ok bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "ยอด") {
+		return "", "", false
+	}
+	rest := strings.TrimPrefix(text, "ยอด")
+	for _, marker := range balanceAsOfMarkers {
+		idx := strings.Index(rest, marker)
+		if idx < 0 {
+			continue
+		}
+		r, found := services.ParseThaiDate(rest[idx:], now)
+		if !found {
+			return "", "", false
+		}
+		account = strings.TrimSpace(rest[:idx])
+		// Spending questions ("ยอดใช้จ่ายเมื่อวาน") belong to AI search, not balances
+		for _, word := range []string{"ใช้", "จ่าย", "รับ", "ซื้อ"} {
+			if strings.Contains(account, word) {
+				return "", "", false
+			}
+		}
+		for _, word := range []string{"เงินคงเหลือ", "คงเหลือ", "เงิน", "บัญชี"} {
+			account = strings.TrimSpace(strings.TrimPrefix(account, word))
+		}
+		return account, r.ToString(), true
+	}
+	return "", "", false
+}
+
+// isSnapshotBackfillCommand checks for "คำนวณยอดย้อนหลัง"
+func isSnapshotBackfillCommand(text string) bool {
+	text = strings.TrimSpace(text)
+	return text == "คำนวณยอดย้อนหลัง" || strings.EqualFold(text, "snapshot backfill")
+}
+
+// replyBalanceAsOf replies closing balances on a past date, false when account hint matches nothing (let AI answer)
+func (h *LineWebhookHandler) replyBalanceAsOf(ctx context.Context, replyToken, userID, account, date string) bool {
+	balances, err := h.mongo.GetBalanceAsOf(ctx, userID, date)
+	if err != nil {
+		log.Printf("Failed to get balance as of %s: %v", date, err)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถดึงยอดย้อนหลังได้")
+		return true
+	}
+
+	lines := []string{fmt.Sprintf("💰 ยอดคงเหลือ ณ สิ้นวัน %s", date)}
+	var total float64
+	matched := 0
+	for _, b := range balances {
+		name := getPaymentName(b.UseType, b.BankName, b.CreditCardName)
+		if account != "" && !strings.Contains(strings.ToLower(name), strings.ToLower(account)) {
+			continue
+		}
+		matched++
+		total += b.Balance
+		lines = append(lines, fmt.Sprintf("%s: %s บาท", name, formatNumber(b.Balance)))
+	}
+	if matched == 0 {
+		if account != "" {
+			return false
+		}
+		lines = append(lines, "ยังไม่มีรายการก่อนวันนี้ค่ะ")
+	} else if matched > 1 {
+		lines = append(lines, fmt.Sprintf("รวม: %s บาท", formatNumber(total)))
+	}
+	h.replyText(replyToken, strings.Join(lines, "\n"))
+	return true
+}
+
+// handleSnapshotBackfill rebuilds daily closing snapshots for the user
+func (h *LineWebhookHandler) handleSnapshotBackfill(ctx context.Context, replyToken, userID string) {
+	count, err := h.mongo.BackfillBalanceSnapshots(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to backfill balance snapshots: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ คำนวณยอดย้อนหลังไม่สำเร็จ กรุณาลองใหม่")
+		return
+	}
+	h.replyText(replyToken, fmt.Sprintf("คำนวณยอดปิดสิ้นวันย้อนหลังแล้ว %d วันค่ะ\nถามได้เลย เช่น \"ยอดกสิกรเมื่อสิ้นเดือนที่แล้ว\"", count))
+}
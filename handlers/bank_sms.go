@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// handleBankSMS parses a pasted bank SMS in Go and asks user to confirm before saving (no AI)
+// Returns false if text isn't a recognised bank notification
+func (h *LineWebhookHandler) handleBankSMS(ctx context.Context, replyToken, userID, text string) bool {
+	sms, ok := services.ParseBankSMS(text, time.Now())
+	if !ok {
+		return false
+	}
+
+	banks, cards, _ := h.mongo.GetDistinctPaymentMethods(ctx, userID)
+	tx := sms.ToTransaction(banks, cards)
+	tx.Source = services.TransactionSourceSMS
+
+	// Reuse amount confirm flow: pending tx in temp data, postback saves it
+	txJSON, _ := json.Marshal(tx)
+	key := fmt.Sprintf("amount_%s_%d", userID, time.Now().Unix())
+	if err := h.mongo.SaveTempData(ctx, key, string(txJSON), 10*time.Minute); err != nil {
+		log.Printf("Failed to save pending SMS transaction: %v", err)
+		return false
+	}
+
+	headerColor, typeText := "#E74C3C", "💸 รายจ่าย"
+	if tx.Type == "income" {
+		headerColor, typeText = "#27AE60", "💰 รายรับ"
+	}
+
+	row := func(label, value string) interface{} {
+		return map[string]interface{}{
+			"type": "box", "layout": "horizontal", "margin": "sm",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": label, "size": "xs", "color": "#888888", "flex": 2},
+				map[string]interface{}{"type": "text", "text": value, "size": "xs", "flex": 4, "wrap": true, "align": "end"},
+			},
+		}
+	}
+	rows := []interface{}{
+		map[string]interface{}{"type": "text", "text": formatNumber(tx.Amount) + " บาท", "size": "xl", "weight": "bold", "color": headerColor},
+		row("ประเภท", typeText),
+		row("ช่องทาง", getPaymentName(tx.UseType, tx.BankName, tx.CreditCardName)),
+		row("วันที่", tx.Date),
+	}
+	if sms.Account != "" {
+		rows = append(rows, row("เลขบัญชี/บัตร", sms.Account))
+	}
+	if sms.Merchant != "" {
+		rows = append(rows, row("ร้านค้า", sms.Merchant))
+	}
+	if sms.Balance > 0 {
+		rows = append(rows, row("คงเหลือตาม SMS", formatNumber(sms.Balance)+" บาท"))
+	}
+
+	flex := map[string]interface{}{
+		"type": "bubble",
+		"size": "kilo",
+		"header": map[string]interface{}{
+			"type":            "box",
+			"layout":          "vertical",
+			"backgroundColor": headerColor,
+			"paddingAll":      "sm",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "📩 SMS " + sms.Bank, "color": "#FFFFFF", "weight": "bold", "size": "sm"},
+			},
+		},
+		"body": map[string]interface{}{
+			"type":       "box",
+			"layout":     "vertical",
+			"paddingAll": "md",
+			"contents":   rows,
+		},
+		"footer": map[string]interface{}{
+			"type":    "box",
+			"layout":  "vertical",
+			"spacing": "sm",
+			"contents": []interface{}{
+				map[string]interface{}{
+					"type": "button", "style": "primary", "height": "sm", "color": "#27AE60",
+					"action": map[string]interface{}{
+						"type":        "postback",
+						"label":       truncateLabel("บันทึก "+formatNumber(tx.Amount)+" บาท", 20),
+						"data":        fmt.Sprintf("action=amount_confirm&key=%s&amount=%.2f", key, tx.Amount),
+						"displayText": "บันทึก " + formatNumber(tx.Amount) + " บาท",
+					},
+				},
+				map[string]interface{}{
+					"type": "button", "style": "link", "height": "sm",
+					"action": map[string]interface{}{
+						"type":        "postback",
+						"label":       "ยกเลิก",
+						"data":        "action=amount_confirm&key=" + key + "&cancel=1",
+						"displayText": "ยกเลิก",
+					},
+				},
+			},
+		},
+	}
+
+	if !h.replyFlexFromAI(replyToken, flex, fmt.Sprintf("SMS %s %s บาท", sms.Bank, formatNumber(tx.Amount))) {
+		h.mongo.DeleteTempData(ctx, key)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/satisatang/backend/services"
+)
+
+// batchSave is what a typed batch needs at save time; it travels with the batch when a guardrail
+// warning holds it so "บันทึกเลย" saves it the same way
+type batchSave struct {
+	PaymentSource  string               `json:"payment_source"`
+	WarrantyMonths int                  `json:"warranty_months,omitempty"` // "เก็บใบเสร็จประกัน 2 ปี"
+	NewAccount     *services.AccountRef `json:"new_account,omitempty"`     // bank/card never used before
+}
+
+// saveTransactionBatch saves a batch typed in one message and replies: an unknown bank/card is saved as
+// cash and asked about afterwards, the warranty goes on the biggest expense, and when some items fail the
+// reply names what was saved so a retry doesn't duplicate it. Returns false when no reply was sent.
+func (h *LineWebhookHandler) saveTransactionBatch(ctx context.Context, replyToken, userID string, txs []services.TransactionData, batch batchSave, msg string) bool {
+	if len(txs) == 0 {
+		return false
+	}
+	var held []int
+	if batch.NewAccount != nil {
+		held = holdUnknownAccount(txs, *batch.NewAccount)
+	}
+
+	txIDs := make([]string, len(txs))
+	var saved, failed []string
+	for i, tx := range txs {
+		if tx.Amount <= 0 {
+			continue
+		}
+		label := fmt.Sprintf("%s %s บาท", orDefault(tx.Description, tx.Category), formatNumber(tx.Amount))
+		txID, err := h.mongo.SaveTransactionOnDate(ctx, userID, &tx, tx.Date)
+		if err != nil {
+			if text, ok := h.spendingCapText(err); ok {
+				label = text
+			} else if text, ok := periodLockedText(err); ok {
+				label += " (" + text + ")"
+			}
+			log.Printf("Failed to save transaction: %v", err)
+			failed = append(failed, label)
+			continue
+		}
+		txIDs[i] = txID
+		saved = append(saved, label)
+	}
+
+	if batch.WarrantyMonths > 0 {
+		if w := h.saveWarrantyForBatch(ctx, userID, txs, txIDs, batch.WarrantyMonths); w != nil {
+			msg = strings.TrimSpace(msg + "\n" + warrantySavedText(w))
+		}
+	}
+	if len(failed) > 0 {
+		h.replyText(replyToken, partialSaveText(saved, failed))
+		return true
+	}
+	if batch.NewAccount != nil {
+		var refs []pendingTxRef
+		for _, i := range held {
+			if txIDs[i] != "" {
+				refs = append(refs, pendingTxRef{ID: txIDs[i], Date: txs[i].Date})
+			}
+		}
+		if len(refs) > 0 && h.askNewAccount(ctx, replyToken, userID, *batch.NewAccount, txs[held[0]], refs) {
+			return true
+		}
+	}
+	return h.replyTransactionsFlex(ctx, userID, replyToken, txs, msg, txIDs[0], batch.PaymentSource)
+}
+
+// partialSaveText tells which items of a batch were saved and which need to be typed again
+func partialSaveText(saved, failed []string) string {
+	if len(saved) == 0 {
+		return "ขออภัยค่ะ ไม่สามารถบันทึกข้อมูลได้"
+	}
+	lines := []string{fmt.Sprintf("✅ บันทึกแล้ว %d รายการ", len(saved))}
+	for _, label := range saved {
+		lines = append(lines, "• "+label)
+	}
+	lines = append(lines, "", fmt.Sprintf("⚠️ บันทึกไม่สำเร็จ %d รายการ", len(failed)))
+	for _, label := range failed {
+		lines = append(lines, "• "+label)
+	}
+	lines = append(lines, "", "พิมพ์ใหม่เฉพาะรายการที่ไม่สำเร็จนะคะ")
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+	"github.com/satisatang/backend/services"
+)
+
+// budgetOverviewLimit keeps the bubble under LINE's size limit
+const budgetOverviewLimit = 12
+
+// isBudgetOverviewCommand matches "ดูงบประมาณ"
+func isBudgetOverviewCommand(text string) bool {
+	switch strings.ReplaceAll(strings.TrimSpace(text), " ", "") {
+	case "ดูงบประมาณ", "ดูงบ", "งบประมาณ", "งบทั้งหมด", "ดูงบทั้งหมด":
+		return true
+	}
+	return false
+}
+
+// replyBudgetOverview lists every budget with a progress bar and edit/delete postbacks
+func (h *LineWebhookHandler) replyBudgetOverview(ctx context.Context, replyToken, userID string) {
+	statuses, err := h.mongo.ProjectedBudgetStatus(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to get budget status: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถดึงงบประมาณได้")
+		return
+	}
+	if len(statuses) == 0 {
+		h.replyText(replyToken, "ยังไม่ได้ตั้งงบประมาณค่ะ\nพิมพ์เช่น \"ตั้งงบอาหาร 5000\"")
+		return
+	}
+
+	// Highest usage first so over-budget categories are on top
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Percentage > statuses[j].Percentage
+	})
+
+	styles := h.mongo.GetCategoryStyles(ctx, userID)
+	var totalBudget, totalSpent float64
+	var contents []interface{}
+	for i, st := range statuses {
+		totalBudget += st.Budget
+		totalSpent += st.Spent
+		if i >= budgetOverviewLimit {
+			continue
+		}
+		if i > 0 {
+			contents = append(contents, map[string]interface{}{"type": "separator", "margin": "md"})
+		}
+		contents = append(contents, budgetOverviewRow(st, styles.Emoji(st.Category)))
+	}
+	if len(statuses) > budgetOverviewLimit {
+		contents = append(contents, map[string]interface{}{
+			"type": "text", "text": fmt.Sprintf("และอีก %d หมวด", len(statuses)-budgetOverviewLimit),
+			"size": "xs", "color": "#888888", "margin": "md",
+		})
+	}
+
+	totalColor := "#27AE60"
+	if totalSpent > totalBudget {
+		totalColor = "#E74C3C"
+	}
+
+	bubble := map[string]interface{}{
+		"type": "bubble",
+		"size": "mega",
+		"header": map[string]interface{}{
+			"type": "box", "layout": "vertical", "backgroundColor": "#9B59B6", "paddingAll": "15px",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "📋 งบประมาณเดือนนี้", "size": "sm", "color": "#FFFFFF"},
+				map[string]interface{}{"type": "text", "text": fmt.Sprintf("%s / %s บาท", formatNumber(totalSpent), formatNumber(totalBudget)), "weight": "bold", "size": "lg", "color": "#FFFFFF", "margin": "sm"},
+			},
+		},
+		"body": map[string]interface{}{
+			"type": "box", "layout": "vertical", "paddingAll": "15px", "contents": contents,
+		},
+		"footer": map[string]interface{}{
+			"type": "box", "layout": "horizontal", "paddingAll": "10px",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "คงเหลือรวม", "size": "sm", "color": "#888888"},
+				map[string]interface{}{"type": "text", "text": formatNumber(totalBudget-totalSpent) + " บาท", "size": "sm", "weight": "bold", "color": totalColor, "align": "end"},
+			},
+		},
+	}
+
+	if !h.replyFlexFromAI(replyToken, bubble, fmt.Sprintf("งบประมาณ %d หมวด", len(statuses))) {
+		h.replyText(replyToken, h.mongo.GetBudgetSummaryText(ctx, userID))
+	}
+}
+
+// budgetOverviewRow builds one budget with a filler progress bar (red when over budget)
+func budgetOverviewRow(st services.BudgetStatus, emoji string) map[string]interface{} {
+	color := budgetColor(st)
+
+	// Bar segments need a filler child because cleanFlexData strips empty contents
+	filled := int(st.Percentage)
+	if filled > 100 {
+		filled = 100
+	}
+	segments := []interface{}{}
+	if filled > 0 {
+		segments = append(segments, map[string]interface{}{
+			"type": "box", "layout": "vertical", "contents": []interface{}{map[string]interface{}{"type": "filler"}},
+			"backgroundColor": color, "flex": filled,
+		})
+	}
+	if filled < 100 {
+		segments = append(segments, map[string]interface{}{
+			"type": "box", "layout": "vertical", "contents": []interface{}{map[string]interface{}{"type": "filler"}},
+			"backgroundColor": "#EEEEEE", "flex": 100 - filled,
+		})
+	}
+
+	remaining := fmt.Sprintf("เหลือ %s", formatNumber(st.Remaining))
+	if st.IsOverBudget {
+		remaining = fmt.Sprintf("เกิน %s", formatNumber(-st.Remaining))
+	}
+	cat := url.QueryEscape(st.Category)
+
+	row := map[string]interface{}{
+		"type": "box", "layout": "vertical", "margin": "md", "spacing": "xs",
+		"contents": []interface{}{
+			map[string]interface{}{
+				"type": "box", "layout": "horizontal",
+				"contents": []interface{}{
+					map[string]interface{}{"type": "text", "text": emoji + " " + st.Category, "size": "sm", "weight": "bold", "flex": 3, "wrap": true},
+					map[string]interface{}{"type": "text", "text": fmt.Sprintf("%s/%s", formatNumber(st.Spent), formatNumber(st.Budget)), "size": "xs", "color": color, "align": "end", "flex": 3},
+				},
+			},
+			map[string]interface{}{
+				"type": "box", "layout": "horizontal", "height": "8px", "cornerRadius": "4px", "contents": segments,
+			},
+			map[string]interface{}{
+				"type": "box", "layout": "horizontal",
+				"contents": []interface{}{
+					map[string]interface{}{"type": "text", "text": fmt.Sprintf("%.0f%% · %s", st.Percentage, remaining), "size": "xxs", "color": color, "flex": 4},
+					map[string]interface{}{"type": "text", "text": "✏️ แก้", "size": "xxs", "color": "#1E88E5", "align": "end", "flex": 1,
+						"action": map[string]interface{}{"type": "postback", "label": "แก้งบ", "data": "action=budget_edit&cat=" + cat, "displayText": "แก้งบ " + st.Category}},
+					map[string]interface{}{"type": "text", "text": "🗑️ ลบ", "size": "xxs", "color": "#E74C3C", "align": "end", "flex": 1,
+						"action": map[string]interface{}{"type": "postback", "label": "ลบงบ", "data": "action=budget_delete&cat=" + cat, "displayText": "ลบงบ " + st.Category}},
+				},
+			},
+		},
+	}
+	if st.IsOverBudget {
+		row["backgroundColor"] = "#FDEDEC"
+		row["paddingAll"] = "sm"
+		row["cornerRadius"] = "md"
+	}
+	return row
+}
+
+// budgetEditKey is the temp data key of the budget chosen by ✏️ (used by the next number message)
+func budgetEditKey(userID string) string {
+	return "budget_edit_" + userID
+}
+
+// handleBudgetPostback handles ✏️/🗑️ taps from the budget overview
+func (h *LineWebhookHandler) handleBudgetPostback(ctx context.Context, replyToken, userID, action string, params map[string]string) {
+	category, err := url.QueryUnescape(params["cat"])
+	if err != nil || category == "" {
+		h.replyText(replyToken, "ไม่พบหมวดที่เลือกค่ะ")
+		return
+	}
+
+	if action == "budget_delete" {
+		if err := h.mongo.DeleteBudget(ctx, userID, category); err != nil {
+			log.Printf("Failed to delete budget: %v", err)
+			h.replyText(replyToken, "ไม่สามารถลบงบได้ค่ะ")
+			return
+		}
+		h.replyText(replyToken, fmt.Sprintf("🗑️ ลบงบหมวด %s แล้วค่ะ", category))
+		return
+	}
+
+	h.mongo.SaveTempData(ctx, budgetEditKey(userID), category, 10*time.Minute)
+	h.replyText(replyToken, fmt.Sprintf("✏️ พิมพ์งบใหม่ของหมวด %s ได้เลยค่ะ เช่น \"5000\"", category))
+}
+
+// handleBudgetEditAmount sets the chosen budget when user replies with only a number
+func (h *LineWebhookHandler) handleBudgetEditAmount(ctx context.Context, replyToken, userID, text string) bool {
+	category, err := h.mongo.GetTempData(ctx, budgetEditKey(userID))
+	if err != nil || category == "" {
+		return false
+	}
+	amount, ok := services.ParseThaiAmount(strings.TrimSpace(text))
+	if !ok || amount <= 0 {
+		return false
+	}
+	h.mongo.DeleteTempData(ctx, budgetEditKey(userID))
+
+	if err := h.mongo.SetBudget(ctx, userID, category, amount); err != nil {
+		log.Printf("Failed to set budget: %v", err)
+		h.replyText(replyToken, "ไม่สามารถแก้งบได้ค่ะ")
+		return true
+	}
+	h.replyBudgetFlex(replyToken, userID, category, amount, fmt.Sprintf("แก้งบหมวด %s เป็น %s บาทแล้วค่ะ", category, formatNumber(amount)))
+	return true
+}
+
+// budgetColor is green, orange from 80% and red when over budget
+func budgetColor(st services.BudgetStatus) string {
+	if st.IsOverBudget {
+		return "#E74C3C"
+	}
+	if st.Percentage >= 80 {
+		return "#F39C12"
+	}
+	return "#27AE60"
+}
+
+// savedBudgetStatuses returns this month's budget status of the expense categories just saved
+// (nil when none of them is budgeted, so users without budgets pay one small query)
+func (h *LineWebhookHandler) savedBudgetStatuses(ctx context.Context, userID string, txs []services.TransactionData) []services.BudgetStatus {
+	budgeted := false
+	for _, tx := range txs {
+		if tx.Type != "income" && tx.Type != "investment" && tx.Category != "" {
+			budgeted = true
+			break
+		}
+	}
+	if !budgeted {
+		return nil
+	}
+	statuses, err := h.mongo.GetBudgetStatus(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to get budget status: %v", err)
+		return nil
+	}
+	return statuses
+}
+
+// budgetProgressLine returns the "งบอาหารใช้ไป 62%" line of a saved expense (nil when its category has no budget)
+func budgetProgressLine(tx *services.TransactionData, statuses []services.BudgetStatus) *messaging_api.FlexText {
+	if tx.Type == "income" || tx.Type == "investment" {
+		return nil
+	}
+	st := services.FindBudgetStatus(statuses, tx.Category)
+	if st == nil {
+		return nil
+	}
+	return &messaging_api.FlexText{
+		Text:   st.ProgressText(),
+		Size:   "xs",
+		Color:  budgetColor(*st),
+		Weight: messaging_api.FlexTextWEIGHT_BOLD,
+		Margin: "sm",
+		Wrap:   true,
+	}
+}
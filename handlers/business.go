@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/satisatang/backend/services"
+)
+
+// businessCommands map normalized commands to business action
+var businessCommands = map[string]string{
+	"เปิดโหมดธุรกิจ": "on",
+	"ปิดโหมดธุรกิจ":  "off",
+	"สรุปโปรเจกต์":   "summary",
+	"สรุปโปรเจค":     "summary",
+	"สรุปลูกค้า":     "summary",
+	"exportโปรเจกต์": "export",
+	"exportโปรเจค":   "export",
+	"ส่งออกโปรเจกต์": "export",
+	"ส่งออกโปรเจค":   "export",
+	"เพิ่มโปรเจกต์":  "add",
+	"เพิ่มโปรเจค":    "add",
+	"เพิ่มลูกค้า":    "add",
+}
+
+// matchBusinessCommand returns business action and argument ("เพิ่มโปรเจกต์ งานบ้านคุณเอ"), "" if not a command
+func matchBusinessCommand(text string) (action, arg string) {
+	text = strings.TrimSpace(text)
+	normalized := strings.ToLower(strings.ReplaceAll(text, " ", ""))
+	for cmd, act := range businessCommands {
+		if act != "add" {
+			if normalized == cmd {
+				return act, ""
+			}
+			continue
+		}
+		if strings.HasPrefix(text, cmd) {
+			if arg = strings.TrimSpace(strings.TrimPrefix(text, cmd)); arg != "" {
+				return act, arg
+			}
+		}
+	}
+	return "", ""
+}
+
+// handleBusinessCommand toggles business mode, registers projects or replies project P&L/export
+func (h *LineWebhookHandler) handleBusinessCommand(ctx context.Context, replyToken, userID, action, arg string) {
+	switch action {
+	case "on", "off":
+		if err := h.mongo.SetBusinessMode(ctx, userID, action == "on"); err != nil {
+			log.Printf("Failed to set business mode: %v", err)
+			h.replyText(replyToken, "ไม่สามารถตั้งค่าโหมดธุรกิจได้ กรุณาลองใหม่")
+			return
+		}
+		if action == "on" {
+			h.replyText(replyToken, "💼 เปิดโหมดธุรกิจแล้วค่ะ\nระบุลูกค้า/โปรเจกต์ท้ายรายการได้เลย เช่น \"ค่าวัสดุ 3000 งานบ้านคุณเอ\"\nพิมพ์ \"สรุปโปรเจกต์\" เพื่อดูกำไรแต่ละงาน")
+			return
+		}
+		h.replyText(replyToken, "ปิดโหมดธุรกิจแล้วค่ะ (ข้อมูลโปรเจกต์เดิมยังอยู่ครบ)")
+
+	case "add":
+		if err := h.mongo.AddProject(ctx, userID, arg); err != nil {
+			log.Printf("Failed to add project: %v", err)
+			h.replyText(replyToken, "ไม่สามารถเพิ่มโปรเจกต์ได้ กรุณาลองใหม่")
+			return
+		}
+		h.replyText(replyToken, fmt.Sprintf("เพิ่มโปรเจกต์ \"%s\" แล้วค่ะ", arg))
+
+	case "summary":
+		summaries, err := h.mongo.GetProjectSummaries(ctx, userID, "", "")
+		if err != nil {
+			log.Printf("Failed to get project summaries: %v", err)
+			h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถดึงข้อมูลโปรเจกต์ได้")
+			return
+		}
+		if len(summaries) == 0 {
+			h.replyText(replyToken, "ยังไม่มีรายการที่ระบุโปรเจกต์ค่ะ\nพิมพ์ \"เปิดโหมดธุรกิจ\" แล้วบันทึกแบบ \"ค่าวัสดุ 3000 งานบ้านคุณเอ\"")
+			return
+		}
+		if !h.replyProjectSummaryFlex(replyToken, summaries) {
+			var lines []string
+			for _, p := range summaries {
+				lines = append(lines, fmt.Sprintf("• %s กำไร %s (รับ %s จ่าย %s)", p.Project, formatNumber(p.Profit), formatNumber(p.Income), formatNumber(p.Expense)))
+			}
+			h.replyText(replyToken, "💼 กำไร/ขาดทุนรายโปรเจกต์\n"+strings.Join(lines, "\n"))
+		}
+
+	case "export":
+		data, filename, err := h.export.ExportProjects(ctx, userID)
+		if err != nil {
+			log.Printf("Failed to export projects: %v", err)
+			h.replyText(replyToken, "ไม่สามารถสร้างไฟล์โปรเจกต์ได้ กรุณาลองใหม่")
+			return
+		}
+		h.replyAndSendFile(replyToken, userID, "💼 ไฟล์สรุปโปรเจกต์ (แยกชีตต่อโปรเจกต์)", data, filename, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	}
+}
+
+// buildBusinessSchema returns AI context for business mode ("โหมดธุรกิจ|โปรเจกต์:a,b"), "" if off
+func buildBusinessSchema(settings *services.UserSettings) string {
+	if settings == nil || !settings.BusinessMode {
+		return ""
+	}
+	if len(settings.Projects) == 0 {
+		return "โหมดธุรกิจ"
+	}
+	return "โหมดธุรกิจ|โปรเจกต์:" + strings.Join(settings.Projects, ",")
+}
+
+// replyProjectSummaryFlex sends profit/loss per project
+func (h *LineWebhookHandler) replyProjectSummaryFlex(replyToken string, summaries []services.ProjectSummary) bool {
+	var totalProfit float64
+	var contents []interface{}
+
+	for i, p := range summaries {
+		if i >= 10 {
+			break
+		}
+		totalProfit += p.Profit
+		profitColor := "#27AE60"
+		if p.Profit < 0 {
+			profitColor = "#E74C3C"
+		}
+		if i > 0 {
+			contents = append(contents, map[string]interface{}{"type": "separator", "margin": "md"})
+		}
+		contents = append(contents, map[string]interface{}{
+			"type": "box", "layout": "horizontal", "margin": "md",
+			"contents": []interface{}{
+				map[string]interface{}{
+					"type": "box", "layout": "vertical", "flex": 5,
+					"contents": []interface{}{
+						map[string]interface{}{"type": "text", "text": truncateLabel(p.Project, 22), "size": "sm", "weight": "bold", "color": "#333333"},
+						map[string]interface{}{"type": "text", "text": fmt.Sprintf("รับ %s · จ่าย %s", formatNumber(p.Income), formatNumber(p.Expense)), "size": "xxs", "color": "#888888"},
+						map[string]interface{}{"type": "text", "text": fmt.Sprintf("%d รายการ", p.TransactionCount), "size": "xxs", "color": "#AAAAAA"},
+					},
+				},
+				map[string]interface{}{"type": "text", "text": formatNumber(p.Profit), "size": "sm", "weight": "bold", "color": profitColor, "align": "end", "gravity": "center", "flex": 3},
+			},
+		})
+	}
+
+	flex := map[string]interface{}{
+		"type": "bubble",
+		"size": "mega",
+		"header": map[string]interface{}{
+			"type":            "box",
+			"layout":          "vertical",
+			"backgroundColor": "#2C3E50",
+			"paddingAll":      "md",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "💼 กำไร/ขาดทุนรายโปรเจกต์", "color": "#FFFFFF", "weight": "bold", "size": "md"},
+				map[string]interface{}{"type": "text", "text": fmt.Sprintf("กำไรรวม %s บาท", formatNumber(totalProfit)), "color": "#D5DBDB", "size": "xxs", "margin": "xs"},
+			},
+		},
+		"body": map[string]interface{}{
+			"type":       "box",
+			"layout":     "vertical",
+			"paddingAll": "md",
+			"contents":   contents,
+		},
+		"footer": map[string]interface{}{
+			"type":   "box",
+			"layout": "vertical",
+			"contents": []interface{}{
+				map[string]interface{}{
+					"type": "button", "style": "secondary", "height": "sm",
+					"action": map[string]interface{}{"type": "message", "label": "📊 ส่งออก Excel", "text": "ส่งออกโปรเจกต์"},
+				},
+			},
+		},
+	}
+
+	return h.replyFlexFromAI(replyToken, flex, fmt.Sprintf("กำไรรวมทุกโปรเจกต์ %s บาท", formatNumber(totalProfit)))
+}
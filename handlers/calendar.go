@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satisatang/backend/services"
+)
+
+// cardAccountPattern matches "ตั้งบัตร KTC ตัดรอบ 20 จ่าย 5" (ตัดรอบ is optional)
+var cardAccountPattern = regexp.MustCompile(`^ตั้งบัตร\s*(.+?)\s+(?:ตัดรอบ(?:วันที่)?\s*(\d{1,2})\s+)?(?:จ่าย|ครบกำหนด)(?:วันที่)?\s*(\d{1,2})$`)
+
+// CalendarHandler serves read-only iCal feeds (/cal/:token.ics)
+type CalendarHandler struct {
+	mongo *services.MongoDBService
+}
+
+// NewCalendarHandler creates a new calendar feed handler
+func NewCalendarHandler(mongo *services.MongoDBService) *CalendarHandler {
+	return &CalendarHandler{mongo: mongo}
+}
+
+// HandleFeed returns user's bills and card due dates as iCalendar
+func (h *CalendarHandler) HandleFeed(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+	lineID := h.mongo.GetLineIDByCalendarToken(ctx, token)
+	if lineID == "" {
+		h.mongo.LogSecurityEvent(ctx, services.SecurityEvent{Event: services.SecurityAccessDenied, Detail: "calendar token not found", ClientIP: c.ClientIP()})
+		c.String(http.StatusNotFound, "calendar not found")
+		return
+	}
+
+	subs, err := h.mongo.GetSubscriptions(ctx, lineID)
+	if err != nil {
+		log.Printf("Failed to get subscriptions for calendar: %v", err)
+	}
+	cards, err := h.mongo.GetCardAccounts(ctx, lineID)
+	if err != nil {
+		log.Printf("Failed to get card accounts for calendar: %v", err)
+	}
+
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(services.BuildICalFeed(subs, cards, time.Now())))
+}
+
+// parseCardAccountCommand parses card billing days, ok=false if not a card command
+func parseCardAccountCommand(text string) (cardName string, statementDay, dueDay int, ok bool) {
+	m := cardAccountPattern.FindStringSubmatch(strings.TrimSpace(text))
+	if m == nil {
+		return "", 0, 0, false
+	}
+	if m[2] != "" {
+		statementDay, _ = strconv.Atoi(m[2])
+	}
+	dueDay, _ = strconv.Atoi(m[3])
+	return strings.TrimSpace(m[1]), statementDay, dueDay, true
+}
+
+// isCalendarCommand checks if message asks for calendar feed, reset=true for new URL
+func isCalendarCommand(text string) (reset, ok bool) {
+	normalized := strings.ToLower(strings.ReplaceAll(text, " ", ""))
+	switch normalized {
+	case "ปฏิทิน", "ปฏิทินบิล", "calendar":
+		return false, true
+	case "ปฏิทินรีเซ็ต", "รีเซ็ตปฏิทิน", "calendarreset":
+		return true, true
+	}
+	return false, false
+}
+
+// handleCardAccountCommand saves card statement/due days
+func (h *LineWebhookHandler) handleCardAccountCommand(ctx context.Context, replyToken, userID, cardName string, statementDay, dueDay int) {
+	if err := h.mongo.SetCardAccount(ctx, userID, cardName, statementDay, dueDay); err != nil {
+		h.replyText(replyToken, "ตั้งค่าบัตรไม่สำเร็จ: "+err.Error())
+		return
+	}
+	msg := fmt.Sprintf("💳 บัตร %s ครบกำหนดจ่ายทุกวันที่ %d", cardName, dueDay)
+	if statementDay > 0 {
+		msg += fmt.Sprintf(" ตัดรอบวันที่ %d", statementDay)
+	}
+	h.replyText(replyToken, msg+"\nดูในปฏิทินมือถือได้ พิมพ์ \"ปฏิทิน\"")
+}
+
+// replyCalendarFeed replies with user's iCal subscription URL
+func (h *LineWebhookHandler) replyCalendarFeed(ctx context.Context, replyToken, userID string, reset bool) {
+	if h.publicBaseURL == "" {
+		h.replyText(replyToken, "ระบบยังไม่เปิดใช้ปฏิทินค่ะ")
+		return
+	}
+
+	var token string
+	var err error
+	if reset {
+		token, err = h.mongo.ResetCalendarToken(ctx, userID)
+		if err == nil {
+			h.mongo.LogSecurityEvent(ctx, services.SecurityEvent{LineID: userID, Event: services.SecurityTokenIssued, Detail: "calendar token reset"})
+		}
+	} else {
+		token, err = h.mongo.GetCalendarToken(ctx, userID)
+	}
+	if err != nil {
+		log.Printf("Failed to get calendar token: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ เกิดข้อผิดพลาด กรุณาลองใหม่")
+		return
+	}
+
+	feedURL := fmt.Sprintf("%s/cal/%s.ics", h.publicBaseURL, token)
+	webcalURL := "webcal://" + strings.TrimPrefix(strings.TrimPrefix(feedURL, "https://"), "http://")
+	msg := "📅 ปฏิทินบิลและวันจ่ายบัตร\nกดลิงก์เพื่อเพิ่มในปฏิทินมือถือ:\n" + webcalURL +
+		"\n\nGoogle Calendar: เพิ่มปฏิทิน > จาก URL แล้ววางลิงก์นี้\n" + feedURL +
+		"\n\nตั้งวันจ่ายบัตร: ตั้งบัตร KTC ตัดรอบ 20 จ่าย 5\n⚠️ อย่าแชร์ลิงก์นี้ (พิมพ์ \"ปฏิทิน รีเซ็ต\" เพื่อเปลี่ยนลิงก์)"
+	h.replyText(replyToken, msg)
+}
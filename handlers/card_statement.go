@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// handleCardPaid records the unpaid statement of a card as a transfer into the card ("✅ จ่ายแล้ว" on the
+// bill reminder); the amount is recomputed, so a second tap records nothing
+func (h *LineWebhookHandler) handleCardPaid(ctx context.Context, replyToken, userID, card string) {
+	st, err := h.mongo.GetCardStatement(ctx, userID, card, time.Now())
+	if err != nil {
+		log.Printf("Failed to get card statement: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถดึงยอดบัตรได้ กรุณาลองใหม่")
+		return
+	}
+	if st == nil || st.Outstanding < 1 {
+		h.replyText(replyToken, fmt.Sprintf("บัตร %s ไม่มียอดค้างจ่ายแล้วค่ะ", card))
+		return
+	}
+
+	source, _ := h.transferSource(ctx, userID, st.Outstanding, "")
+	source.Amount = st.Outstanding
+	transfer := &services.TransferData{
+		From:        []services.TransferEntry{source},
+		To:          []services.TransferEntry{{Amount: st.Outstanding, UseType: 1, CreditCardName: card}},
+		Description: "จ่ายบัตร " + card,
+	}
+	transferID, _, err := h.mongo.SaveTransfer(ctx, userID, transfer)
+	if err != nil {
+		if msg, locked := periodLockedText(err); locked {
+			h.replyText(replyToken, msg)
+			return
+		}
+		log.Printf("Failed to save card payment: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ บันทึกการจ่ายบัตรไม่สำเร็จ กรุณาลองใหม่อีกครั้ง")
+		return
+	}
+	h.replyTransferFlex(replyToken, userID, transfer, transferID,
+		fmt.Sprintf("บันทึกจ่ายบัตร %s ยอด %s บาทแล้วค่ะ (รอบตัด %s)", card, formatNumber(st.Outstanding), formatThaiShortDate(st.CloseDate)))
+}
+
+// handleCardSnooze hides a card's bill reminders until tomorrow ("⏰ เตือนพรุ่งนี้")
+func (h *LineWebhookHandler) handleCardSnooze(ctx context.Context, replyToken, userID, card string) {
+	if err := h.mongo.SnoozeCardReminder(ctx, userID, card); err != nil {
+		log.Printf("Failed to snooze card reminder: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ เลื่อนการเตือนไม่สำเร็จ กรุณาลองใหม่")
+		return
+	}
+	h.replyText(replyToken, fmt.Sprintf("⏰ เลื่อนเตือนจ่ายบัตร %s ไปพรุ่งนี้แล้วค่ะ", card))
+}
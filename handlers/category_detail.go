@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+
+	"github.com/satisatang/backend/services"
+)
+
+// categoryDetailAction makes a flex row tappable to list that category's transactions in the period
+func categoryDetailAction(category, from, to string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "postback",
+		"label":       truncateLabel(category, 20),
+		"data":        fmt.Sprintf("action=category_detail&cat=%s&from=%s&to=%s", url.QueryEscape(category), from, to),
+		"displayText": "ดูรายการ " + category,
+	}
+}
+
+// handleCategoryDetail shows transactions of the tapped category using the search result renderer
+func (h *LineWebhookHandler) handleCategoryDetail(ctx context.Context, replyToken, userID string, params map[string]string) {
+	category, err := url.QueryUnescape(params["cat"])
+	if err != nil || category == "" || params["from"] == "" || params["to"] == "" {
+		h.replyText(replyToken, "ไม่พบหมวดที่เลือกค่ะ")
+		return
+	}
+
+	results, err := h.mongo.SearchByCategoryRange(ctx, userID, category, params["from"], params["to"], 50)
+	if err != nil {
+		log.Printf("Failed to search category %s: %v", category, err)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถดึงรายการได้")
+		return
+	}
+	if len(results) == 0 {
+		h.replyText(replyToken, fmt.Sprintf("ไม่มีรายการหมวด %s ในช่วงนี้ค่ะ", category))
+		return
+	}
+
+	var total float64
+	for _, r := range results {
+		total += r.Transaction.Amount
+	}
+	msg := fmt.Sprintf("📂 %s %s - %s\n%d รายการ รวม %s บาท", category, formatThaiShortDate(params["from"]), formatThaiShortDate(params["to"]), len(results), formatNumber(total))
+	if !h.replyQueryResultsFlex(ctx, userID, replyToken, results, len(results), &services.QueryFilter{GroupBy: "none"}, msg) {
+		h.replyText(replyToken, msg)
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/satisatang/backend/services"
+)
+
+// categoryStylePrefixes map command prefixes to style field ("emoji" or "color")
+var categoryStylePrefixes = []struct{ prefix, field string }{
+	{"ตั้งอีโมจิ", "emoji"},
+	{"ตั้งไอคอน", "emoji"},
+	{"ตั้งสี", "color"},
+}
+
+// parseCategoryStyleCommand parses "ตั้งอีโมจิ <หมวด> <emoji>" or "ตั้งสี <หมวด> #RRGGBB"
+func parseCategoryStyleCommand(text string) (category, emoji, color string, ok bool) {
+	text = strings.TrimSpace(text)
+	for _, p := range categoryStylePrefixes {
+		if !strings.HasPrefix(text, p.prefix) {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(text, p.prefix))
+		if len(fields) < 2 {
+			return "", "", "", false
+		}
+		// Last field is emoji/color, the rest is category name (may contain spaces)
+		value := fields[len(fields)-1]
+		category = strings.Join(fields[:len(fields)-1], " ")
+		if strings.HasPrefix(category, "หมวด") && category != "หมวด" {
+			category = strings.TrimPrefix(category, "หมวด")
+		}
+		if p.field == "emoji" {
+			return category, value, "", true
+		}
+		return category, "", value, true
+	}
+	return "", "", "", false
+}
+
+// handleCategoryStyleCommand saves user's category emoji or color
+func (h *LineWebhookHandler) handleCategoryStyleCommand(ctx context.Context, replyToken, userID, category, emoji, color string) {
+	if emoji != "" {
+		if err := h.mongo.SetCategoryEmoji(ctx, userID, category, emoji); err != nil {
+			log.Printf("Failed to set category emoji: %v", err)
+			h.replyText(replyToken, "ไม่สามารถตั้งอีโมจิได้ กรุณาลองใหม่")
+			return
+		}
+		h.replyText(replyToken, fmt.Sprintf("ตั้งอีโมจิหมวด %s เป็น %s แล้วค่ะ", category, emoji))
+		return
+	}
+
+	if !services.IsValidHexColor(color) {
+		h.replyText(replyToken, "รูปแบบสีไม่ถูกต้องค่ะ ตัวอย่าง: ตั้งสี อาหาร #FF7675")
+		return
+	}
+	if err := h.mongo.SetCategoryColor(ctx, userID, category, color); err != nil {
+		log.Printf("Failed to set category color: %v", err)
+		h.replyText(replyToken, "ไม่สามารถตั้งสีได้ กรุณาลองใหม่")
+		return
+	}
+	h.replyText(replyToken, fmt.Sprintf("ตั้งสีหมวด %s เป็น %s แล้วค่ะ (ใช้ในกราฟและไฟล์ export)", category, strings.ToUpper(color)))
+}
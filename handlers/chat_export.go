@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// chatExportKeywords identify chat transcript requests ("ขอประวัติแชทเดือนนี้")
+var chatExportKeywords = []string{"ประวัติแชท", "ประวัติการแชท", "ประวัติการคุย", "ประวัติการสนทนา"}
+
+// parseChatExportCommand returns transcript date range (default this month) and format ("txt" or "pdf")
+func parseChatExportCommand(text string, now time.Time) (from, to, format string, ok bool) {
+	found := false
+	for _, keyword := range chatExportKeywords {
+		if strings.Contains(text, keyword) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return "", "", "", false
+	}
+
+	format = "txt"
+	if strings.Contains(strings.ToLower(text), "pdf") {
+		format = "pdf"
+	}
+	if r, parsed := services.ParseThaiDate(text, now); parsed {
+		return r.FromString(), r.ToString(), format, true
+	}
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	return monthStart.Format("2006-01-02"), now.Format("2006-01-02"), format, true
+}
+
+// replyChatTranscript exports chat history with the bot as a downloadable file
+func (h *LineWebhookHandler) replyChatTranscript(ctx context.Context, replyToken, userID, from, to, format string) {
+	var data []byte
+	var filename string
+	var err error
+	mimeType := "text/plain; charset=utf-8"
+	if format == "pdf" {
+		data, filename, err = h.export.ExportChatTranscriptPDF(ctx, userID, from, to)
+		mimeType = "application/pdf"
+	} else {
+		data, filename, err = h.export.ExportChatTranscriptText(ctx, userID, from, to)
+	}
+	if err != nil {
+		log.Printf("Failed to export chat transcript: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ "+err.Error())
+		return
+	}
+	h.replyAndSendFile(replyToken, userID, "💬 ประวัติแชท "+from+" ถึง "+to, data, filename, mimeType)
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satisatang/backend/services"
+)
+
+// CloseReportHandler serves the machine-readable monthly close report (/api/v1/close-report)
+type CloseReportHandler struct {
+	mongo *services.MongoDBService
+}
+
+// NewCloseReportHandler creates a new close report API handler
+func NewCloseReportHandler(mongo *services.MongoDBService) *CloseReportHandler {
+	return &CloseReportHandler{mongo: mongo}
+}
+
+// HandleCloseReport returns the JSON close report of ?month=YYYY-MM, last month by default
+// (GET, Authorization: Bearer <report token from the "API รายงาน" command>)
+func (h *CloseReportHandler) HandleCloseReport(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	c.Header("Cache-Control", "no-store")
+	lineID := h.mongo.GetLineIDByReportToken(ctx, strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+	if lineID == "" {
+		h.mongo.LogSecurityEvent(ctx, services.SecurityEvent{Event: services.SecurityAccessDenied, Detail: "report token rejected", ClientIP: c.ClientIP()})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	month := c.Query("month")
+	if month == "" {
+		month = time.Now().AddDate(0, 0, -time.Now().Day()).Format("2006-01")
+	}
+	if _, _, err := services.MonthRange(month); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.mongo.GetCloseReport(ctx, lineID, month)
+	if err != nil {
+		log.Printf("Failed to build close report: %v", err)
+		services.ReportError("close-report", lineID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "report failed"})
+		return
+	}
+	c.Header("X-Schema-Version", report.SchemaVersion)
+	c.JSON(http.StatusOK, report)
+}
+
+// isReportAPICommand checks if message asks for the close report API token, reset=true for a new one
+func isReportAPICommand(text string) (reset, ok bool) {
+	normalized := strings.ToLower(strings.ReplaceAll(text, " ", ""))
+	switch normalized {
+	case "apiรายงาน", "รายงานนักบัญชี", "ลิงก์นักบัญชี":
+		return false, true
+	case "apiรายงานรีเซ็ต", "รีเซ็ตapiรายงาน", "รีเซ็ตรายงานนักบัญชี":
+		return true, true
+	}
+	return false, false
+}
+
+// replyReportAPI replies with the close report URL and the user's report token
+func (h *LineWebhookHandler) replyReportAPI(ctx context.Context, replyToken, userID string, reset bool) {
+	if h.publicBaseURL == "" {
+		h.replyText(replyToken, "ระบบยังไม่เปิดใช้ API รายงานค่ะ")
+		return
+	}
+
+	var token string
+	var err error
+	if reset {
+		token, err = h.mongo.ResetReportToken(ctx, userID)
+		if err == nil {
+			h.mongo.LogSecurityEvent(ctx, services.SecurityEvent{LineID: userID, Event: services.SecurityTokenIssued, Detail: "report token reset"})
+		}
+	} else {
+		token, err = h.mongo.GetReportToken(ctx, userID)
+	}
+	if err != nil {
+		log.Printf("Failed to get report token: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ เกิดข้อผิดพลาด กรุณาลองใหม่")
+		return
+	}
+
+	lastMonth := time.Now().AddDate(0, 0, -time.Now().Day()).Format("2006-01")
+	msg := fmt.Sprintf("📊 API รายงานปิดเดือน (JSON) สำหรับนักบัญชี/โปรแกรมภายนอก\nGET %s/api/v1/close-report?month=%s\nAuthorization: Bearer %s", h.publicBaseURL, lastMonth, token) +
+		"\n\nมีรายรับ รายจ่าย ยอดตามหมวด ตามบัญชี และงบประมาณ คิดแบบเดียวกับรายงาน PDF" +
+		"\n⚠️ อย่าแชร์ token นี้ในที่สาธารณะ (พิมพ์ \"API รายงาน รีเซ็ต\" เพื่อเปลี่ยน)"
+	h.replyText(replyToken, msg)
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+	"github.com/satisatang/backend/services"
+)
+
+// handleDailyLimitCommand sets, turns off or shows the daily spending cap
+func (h *LineWebhookHandler) handleDailyLimitCommand(ctx context.Context, replyToken, userID string, cmd services.DailyLimitCommand) {
+	switch cmd.Action {
+	case "set":
+		if err := h.mongo.SetDailyLimit(ctx, userID, cmd.Limit); err != nil {
+			log.Printf("Failed to set daily limit: %v", err)
+			h.replyText(replyToken, "ไม่สามารถตั้งค่าได้ กรุณาลองใหม่")
+			return
+		}
+		text := fmt.Sprintf("📆 ตั้งวงเงินใช้จ่ายวันละ %s บาทแล้วค่ะ\nทุกครั้งที่บันทึกรายจ่ายจะแสดงยอดวันนี้เทียบกับวงเงิน\nปิด: ยกเลิกจำกัดวันละ", formatNumber(cmd.Limit))
+		if st, err := h.mongo.GetDailyLimitStatus(ctx, userID, time.Now().Format("2006-01-02")); err == nil && st != nil {
+			text += "\n\n" + st.ProgressText()
+		}
+		h.replyText(replyToken, text)
+
+	case "off":
+		if err := h.mongo.SetDailyLimit(ctx, userID, 0); err != nil {
+			log.Printf("Failed to turn off daily limit: %v", err)
+			h.replyText(replyToken, "ไม่สามารถตั้งค่าได้ กรุณาลองใหม่")
+			return
+		}
+		h.replyText(replyToken, "ปิดวงเงินรายวันแล้วค่ะ")
+
+	default:
+		st, err := h.mongo.GetDailyLimitStatus(ctx, userID, time.Now().Format("2006-01-02"))
+		if err != nil {
+			log.Printf("Failed to get daily limit: %v", err)
+			h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถดึงข้อมูลได้")
+			return
+		}
+		if st == nil {
+			h.replyText(replyToken, "ยังไม่ได้ตั้งวงเงินรายวันค่ะ ตัวอย่าง: จำกัดวันละ 500")
+			return
+		}
+		h.replyText(replyToken, st.ProgressText())
+	}
+}
+
+// savedDailyLimitStatus returns today's spending against the daily cap after expenses were saved today
+// (nil when none of txs is an expense of today or no cap is set)
+func (h *LineWebhookHandler) savedDailyLimitStatus(ctx context.Context, userID string, txs []services.TransactionData) *services.DailyLimitStatus {
+	today := time.Now().Format("2006-01-02")
+	spentToday := false
+	for _, tx := range txs {
+		if tx.Type != "income" && tx.Type != "investment" && (tx.Date == "" || tx.Date == today) {
+			spentToday = true
+			break
+		}
+	}
+	if !spentToday {
+		return nil
+	}
+	st, err := h.mongo.GetDailyLimitStatus(ctx, userID, today)
+	if err != nil {
+		log.Printf("Failed to get daily limit: %v", err)
+		return nil
+	}
+	return st
+}
+
+// dailyLimitColor is green, orange from 80% and red over the cap (same as budgets)
+func dailyLimitColor(st *services.DailyLimitStatus) string {
+	if st.Over {
+		return "#E74C3C"
+	}
+	if st.Percent >= 80 {
+		return "#F39C12"
+	}
+	return "#27AE60"
+}
+
+// dailyLimitLine returns the "วันนี้ใช้ไป 320/500 บาท" line of a saved expense (nil without a cap)
+func dailyLimitLine(tx *services.TransactionData, st *services.DailyLimitStatus) *messaging_api.FlexText {
+	if st == nil || tx.Type == "income" || tx.Type == "investment" {
+		return nil
+	}
+	return &messaging_api.FlexText{
+		Text:   st.ProgressText(),
+		Size:   "xs",
+		Color:  dailyLimitColor(st),
+		Weight: messaging_api.FlexTextWEIGHT_BOLD,
+		Margin: "sm",
+		Wrap:   true,
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// handleDeleteRequest resolves "ลบค่ากาแฟเมื่อวาน" in Go: one match is deleted (with undo), several are
+// shown as a "ลบอันไหน" carousel (no AI)
+func (h *LineWebhookHandler) handleDeleteRequest(ctx context.Context, replyToken, userID, text string) bool {
+	req, ok := services.ParseDeleteRequest(text, time.Now())
+	if !ok {
+		return false
+	}
+
+	var results []services.SearchResult
+	var err error
+	if req.From != "" {
+		results, err = h.mongo.SearchByDateRange(ctx, userID, req.From, req.To, 200)
+	} else {
+		results, err = h.mongo.SearchTransactions(ctx, userID, req.SearchKeyword(), 50)
+	}
+	if err != nil {
+		log.Printf("Failed to search delete candidates: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถค้นหารายการได้")
+		return true
+	}
+	candidates := services.FilterDeleteCandidates(req, results)
+
+	switch len(candidates) {
+	case 0:
+		where := ""
+		if req.DateExp != "" {
+			where = " " + req.DateExp
+		}
+		h.replyText(replyToken, fmt.Sprintf("ไม่พบรายการ \"%s\"%s ค่ะ\nดูรายการทั้งหมดได้ที่ \"ดูรายการล่าสุด\"", req.Keyword, where))
+	case 1:
+		c := candidates[0]
+		h.deleteWithUndo(ctx, replyToken, userID, c.Transaction.ID.Hex(), c.Date, services.TransactionKind(c.Transaction.Type))
+	default:
+		var bubbles []interface{}
+		for _, c := range candidates {
+			bubble := h.recentTransactionBubble(c)
+			bubble["footer"] = map[string]interface{}{
+				"type": "box", "layout": "vertical",
+				"contents": []interface{}{
+					map[string]interface{}{
+						"type": "button", "style": "primary", "color": "#E74C3C", "height": "sm",
+						"action": map[string]interface{}{
+							"type":        "postback",
+							"label":       "🗑️ ลบอันนี้",
+							"data":        fmt.Sprintf("action=delete_pick&txid=%s&date=%s&kind=%s", c.Transaction.ID.Hex(), c.Date, services.TransactionKind(c.Transaction.Type)),
+							"displayText": "ลบ " + orDefault(c.Transaction.Description, c.Transaction.Category),
+						},
+					},
+				},
+			}
+			bubbles = append(bubbles, bubble)
+		}
+		altText := fmt.Sprintf("พบ \"%s\" %d รายการ ลบอันไหนคะ?", req.Keyword, len(candidates))
+		if !h.replyFlexFromAI(replyToken, bubbles, altText) {
+			h.replyText(replyToken, altText)
+		}
+	}
+	return true
+}
+
+// deleteWithUndo deletes one transaction and replies with "↩️ ไม่ใช่อันนี้" that puts it back within UndoWindow
+func (h *LineWebhookHandler) deleteWithUndo(ctx context.Context, replyToken, userID, txID, date, kind string) {
+	before, err := h.mongo.GetTransactionOnDate(ctx, userID, txID, date)
+	if err != nil {
+		h.replyText(replyToken, "ไม่พบรายการนี้แล้วค่ะ (อาจถูกลบไปก่อนหน้า)")
+		return
+	}
+	if err := h.mongo.DeleteTransactionOfKind(ctx, userID, txID, date, kind); err != nil {
+		if msg, locked := periodLockedText(err); locked {
+			h.replyText(replyToken, msg)
+			return
+		}
+		log.Printf("Failed to delete transaction: %v", err)
+		h.replyText(replyToken, "ไม่สามารถลบรายการได้")
+		return
+	}
+
+	text := fmt.Sprintf("🗑️ ลบ %s %s บาท (%s) แล้วค่ะ\n\n%s",
+		orDefault(before.Description, before.Category), formatNumber(before.Amount), formatThaiShortDate(date), h.getBalanceText(ctx, userID))
+	token, err := h.mongo.RememberUndo(ctx, userID, services.UndoAction{Kind: services.UndoKindDelete, TxID: txID, Date: date, Deleted: before})
+	if err != nil {
+		log.Printf("Failed to remember undo: %v", err)
+		h.replyText(replyToken, text)
+		return
+	}
+	h.replyTextWithQuickReply(replyToken, text, undoQuickReply(token))
+}
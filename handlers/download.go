@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satisatang/backend/services"
+)
+
+// DownloadHandler serves short one-time download links (/d/:token)
+type DownloadHandler struct {
+	mongo    *services.MongoDBService
+	firebase *services.FirebaseService
+}
+
+// NewDownloadHandler creates a new download handler
+func NewDownloadHandler(mongo *services.MongoDBService, firebase *services.FirebaseService) *DownloadHandler {
+	return &DownloadHandler{mongo: mongo, firebase: firebase}
+}
+
+// HandleDownload streams the storage object of a short link, enforcing expiry and one-time download
+func (h *DownloadHandler) HandleDownload(c *gin.Context) {
+	token := c.Param("token")
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	event := services.DownloadEvent{
+		Token:     token,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+	defer func() {
+		if err := h.mongo.LogDownloadEvent(context.Background(), event); err != nil {
+			log.Printf("Failed to log download event: %v", err)
+		}
+	}()
+
+	link, err := h.mongo.ClaimDownloadLink(ctx, token)
+	if link != nil {
+		event.LineID = link.LineID
+	}
+	switch {
+	case errors.Is(err, services.ErrDownloadNotFound):
+		event.Status = "not_found"
+		h.mongo.LogSecurityEvent(ctx, services.SecurityEvent{Event: services.SecurityAccessDenied, Detail: "download token not found", ClientIP: c.ClientIP()})
+		c.String(http.StatusNotFound, "ไม่พบไฟล์ค่ะ")
+		return
+	case errors.Is(err, services.ErrDownloadUsed):
+		event.Status = "used"
+		c.String(http.StatusGone, "ลิงก์นี้ถูกใช้ดาวน์โหลดไปแล้วค่ะ กรุณาขอไฟล์ใหม่ใน LINE")
+		return
+	case errors.Is(err, services.ErrDownloadExpired):
+		event.Status = "expired"
+		c.String(http.StatusGone, "ลิงก์หมดอายุแล้วค่ะ กรุณาขอไฟล์ใหม่ใน LINE")
+		return
+	case err != nil:
+		event.Status = "error"
+		log.Printf("Failed to claim download link: %v", err)
+		c.String(http.StatusInternalServerError, "เกิดข้อผิดพลาด กรุณาลองใหม่")
+		return
+	}
+
+	reader, err := h.firebase.GetFileReader(ctx, link.ObjectPath)
+	if err != nil {
+		event.Status = "error"
+		log.Printf("Failed to open storage object %s: %v", link.ObjectPath, err)
+		// Let user retry since nothing was downloaded
+		if err := h.mongo.ReleaseDownloadLink(context.Background(), token); err != nil {
+			log.Printf("Failed to release download link: %v", err)
+		}
+		c.String(http.StatusBadGateway, "ไม่สามารถดาวน์โหลดไฟล์ได้ กรุณาลองใหม่")
+		return
+	}
+	defer reader.Close()
+
+	event.Status = "ok"
+	h.mongo.LogSecurityEvent(ctx, services.SecurityEvent{LineID: link.LineID, Event: services.SecurityDataExport, Detail: "downloaded " + link.Filename, ClientIP: c.ClientIP()})
+	c.DataFromReader(http.StatusOK, -1, link.ContentType, reader, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s"`, link.Filename),
+		"Cache-Control":       "no-store",
+	})
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/satisatang/backend/services"
+)
+
+// SetFeatureFlags enables gradual rollout checks (nil keeps all flagged features off)
+func (h *LineWebhookHandler) SetFeatureFlags(flags *services.FeatureFlagService) {
+	h.flags = flags
+}
+
+// featureEnabled reports whether flag is on for the user
+func (h *LineWebhookHandler) featureEnabled(ctx context.Context, flag, userID string) bool {
+	return h.flags != nil && h.flags.IsEnabled(ctx, flag, userID)
+}
+
+// handleFeatureFlagCommand handles admin commands (returns false if text is not one)
+//
+//	ฟีเจอร์                          list rollout of every flag
+//	ฟีเจอร์ two_stage_ai 20          roll out to 20% of users
+//	ฟีเจอร์ two_stage_ai เปิด [Uxxx]  force on for a user (default: yourself)
+//	ฟีเจอร์ two_stage_ai ปิด [Uxxx]   force off, "ล้าง" removes the override
+func (h *LineWebhookHandler) handleFeatureFlagCommand(ctx context.Context, replyToken, userID, text string) bool {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || fields[0] != "ฟีเจอร์" || !h.admins[userID] || h.flags == nil {
+		return false
+	}
+
+	if len(fields) == 1 {
+		var sb strings.Builder
+		sb.WriteString("🚩 Feature flags")
+		for _, name := range services.KnownFeatureFlags {
+			sb.WriteString(fmt.Sprintf("\n%s: %d%% (คุณ: %s)", name, h.flags.Percent(ctx, name), onOffText(h.flags.IsEnabled(ctx, name, userID))))
+		}
+		h.replyText(replyToken, sb.String())
+		return true
+	}
+
+	name := fields[1]
+	known := false
+	for _, flag := range services.KnownFeatureFlags {
+		known = known || flag == name
+	}
+	if !known || len(fields) < 3 {
+		h.replyText(replyToken, "ใช้: ฟีเจอร์ <ชื่อ> <0-100> หรือ ฟีเจอร์ <ชื่อ> เปิด|ปิด|ล้าง [userID]\nชื่อ: "+strings.Join(services.KnownFeatureFlags, ", "))
+		return true
+	}
+
+	target := userID
+	if len(fields) >= 4 {
+		target = fields[3]
+	}
+
+	var err error
+	var msg string
+	switch value := strings.TrimSuffix(fields[2], "%"); value {
+	case "เปิด", "on":
+		err = h.flags.SetOverride(ctx, name, target, true)
+		msg = fmt.Sprintf("เปิด %s ให้ %s แล้วค่ะ", name, target)
+	case "ปิด", "off":
+		err = h.flags.SetOverride(ctx, name, target, false)
+		msg = fmt.Sprintf("ปิด %s ให้ %s แล้วค่ะ", name, target)
+	case "ล้าง", "reset":
+		err = h.flags.ClearOverride(ctx, name, target)
+		msg = fmt.Sprintf("ล้างค่าเฉพาะ %s ของ %s แล้วค่ะ", name, target)
+	default:
+		percent, convErr := strconv.Atoi(value)
+		if convErr != nil || percent < 0 || percent > 100 {
+			h.replyText(replyToken, "เปอร์เซ็นต์ต้องอยู่ระหว่าง 0-100 ค่ะ")
+			return true
+		}
+		err = h.flags.SetPercent(ctx, name, percent)
+		msg = fmt.Sprintf("ตั้ง %s เป็น %d%% ของผู้ใช้แล้วค่ะ", name, percent)
+	}
+	if err != nil {
+		log.Printf("Failed to update feature flag %s: %v", name, err)
+		h.replyText(replyToken, "ไม่สามารถบันทึก feature flag ได้ค่ะ")
+		return true
+	}
+	h.replyText(replyToken, msg)
+	return true
+}
+
+// onOffText returns เปิด/ปิด
+func onOffText(on bool) string {
+	if on {
+		return "เปิด"
+	}
+	return "ปิด"
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// replyForecast answers "สิ้นเดือนจะเหลือเท่าไหร่" with the month-end forecast flex (no AI)
+func (h *LineWebhookHandler) replyForecast(ctx context.Context, replyToken, userID string) {
+	// Subscriptions are detected on demand, as in the subscription list
+	if err := h.mongo.DetectSubscriptions(ctx, userID); err != nil {
+		log.Printf("Failed to detect subscriptions: %v", err)
+	}
+	f, err := h.mongo.GetCashFlowForecast(ctx, userID, time.Now())
+	if err != nil {
+		log.Printf("Failed to build forecast: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถคาดการณ์ยอดสิ้นเดือนได้")
+		return
+	}
+	h.replyForecastFlex(replyToken, f)
+}
+
+// replyForecastFlex renders balance, committed vs discretionary split and the expected month-end balance
+func (h *LineWebhookHandler) replyForecastFlex(replyToken string, f *services.CashFlowForecast) {
+	row := func(label, value, color string) map[string]interface{} {
+		return map[string]interface{}{
+			"type": "box", "layout": "horizontal", "margin": "md",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": label, "size": "sm", "color": "#555555", "flex": 5},
+				map[string]interface{}{"type": "text", "text": value, "size": "sm", "weight": "bold", "color": color, "align": "end", "flex": 4},
+			},
+		}
+	}
+
+	expectedColor := "#27AE60"
+	if f.ExpectedBalance < 0 {
+		expectedColor = "#E74C3C"
+	}
+	body := []interface{}{
+		row("💰 คงเหลือตอนนี้", formatNumber(f.Balance), "#333333"),
+		row("📌 ผูกพันแล้ว (subscription)", "-"+formatNumber(f.Committed), "#8E44AD"),
+		row("🛒 ใช้จ่ายทั่วไป (คาด)", "-"+formatNumber(f.Discretionary), "#E67E22"),
+		map[string]interface{}{"type": "text", "text": fmt.Sprintf("เฉลี่ย %s บาท/วัน × %d วันที่เหลือ", formatNumber(f.DailyDiscretionary), f.DaysLeft), "size": "xxs", "color": "#888888", "align": "end"},
+	}
+
+	// Committed vs discretionary bar
+	if outflow := f.Committed + f.Discretionary; outflow > 0 {
+		committedWidth := int(f.Committed / outflow * 100)
+		bar := []interface{}{}
+		if committedWidth > 0 {
+			bar = append(bar, map[string]interface{}{"type": "box", "layout": "vertical", "backgroundColor": "#8E44AD", "height": "8px", "flex": committedWidth, "contents": []interface{}{map[string]interface{}{"type": "filler"}}})
+		}
+		if committedWidth < 100 {
+			bar = append(bar, map[string]interface{}{"type": "box", "layout": "vertical", "backgroundColor": "#E67E22", "height": "8px", "flex": 100 - committedWidth, "contents": []interface{}{map[string]interface{}{"type": "filler"}}})
+		}
+		body = append(body,
+			map[string]interface{}{"type": "box", "layout": "horizontal", "margin": "md", "cornerRadius": "4px", "contents": bar},
+			map[string]interface{}{"type": "text", "text": fmt.Sprintf("ผูกพัน %d%% · ใช้จ่ายทั่วไป %d%%", committedWidth, 100-committedWidth), "size": "xxs", "color": "#888888"},
+		)
+	}
+
+	body = append(body,
+		map[string]interface{}{"type": "separator", "margin": "md"},
+		row("🔮 คาดว่าเหลือสิ้นเดือน", formatNumber(f.ExpectedBalance), expectedColor),
+	)
+	for i, c := range f.Commitments {
+		if i >= 5 {
+			body = append(body, map[string]interface{}{"type": "text", "text": fmt.Sprintf("และอีก %d รายการ", len(f.Commitments)-5), "size": "xxs", "color": "#AAAAAA"})
+			break
+		}
+		if i == 0 {
+			body = append(body, map[string]interface{}{"type": "text", "text": "📅 รายการที่จะตัดเงิน", "size": "xs", "weight": "bold", "margin": "lg"})
+		}
+		body = append(body, map[string]interface{}{
+			"type": "box", "layout": "horizontal", "margin": "sm",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": truncateLabel(c.Name, 18), "size": "xs", "flex": 5},
+				map[string]interface{}{"type": "text", "text": formatRenewal(c.Date), "size": "xxs", "color": "#888888", "flex": 4},
+				map[string]interface{}{"type": "text", "text": formatNumber(c.Amount), "size": "xs", "align": "end", "flex": 3},
+			},
+		})
+	}
+
+	flex := map[string]interface{}{
+		"type": "bubble",
+		"header": map[string]interface{}{
+			"type":            "box",
+			"layout":          "vertical",
+			"backgroundColor": "#8E44AD",
+			"paddingAll":      "md",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "🔮 คาดการณ์สิ้นเดือน", "color": "#FFFFFF", "weight": "bold", "size": "md"},
+				map[string]interface{}{"type": "text", "text": fmt.Sprintf("ถึงสิ้นเดือน (อีก %d วัน)", f.DaysLeft), "color": "#FFFFFF", "size": "xs"},
+			},
+		},
+		"body": map[string]interface{}{
+			"type":       "box",
+			"layout":     "vertical",
+			"paddingAll": "md",
+			"contents":   body,
+		},
+	}
+	summary := fmt.Sprintf("คาดว่าสิ้นเดือนจะเหลือ %s บาท (ผูกพัน %s + ใช้จ่ายทั่วไป %s บาท)",
+		formatNumber(f.ExpectedBalance), formatNumber(f.Committed), formatNumber(f.Discretionary))
+	if !h.replyFlexFromAI(replyToken, flex, summary) {
+		h.replyText(replyToken, summary)
+	}
+}
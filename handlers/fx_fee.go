@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// replyFXFees answers "บัตรไหนชาร์จ FX แพง" with each card's markup over the mid-market rate (no AI)
+func (h *LineWebhookHandler) replyFXFees(ctx context.Context, replyToken, userID string) {
+	if h.fx == nil {
+		h.replyText(replyToken, "ยังไม่ได้เปิดใช้เรทแลกเปลี่ยนกลางค่ะ จึงเทียบค่าธรรมเนียม FX ไม่ได้")
+		return
+	}
+	fees, err := h.mongo.GetFXFees(ctx, userID, h.fx, time.Now())
+	if err != nil {
+		log.Printf("Failed to analyze FX fees: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถคำนวณค่าธรรมเนียม FX ได้")
+		return
+	}
+	if len(fees) == 0 {
+		h.replyText(replyToken, "ยังไม่มีรายการต่างประเทศที่รู้ยอดตัดบัตรจริงค่ะ\n"+
+			"ระหว่างเที่ยว ยอดบาทเป็นเรทประมาณการ พอยอดขึ้นในบัตรแล้วกด ✏️ แก้ไข ใส่ยอดบาทที่ถูกตัดจริง แล้วถามใหม่ได้เลย")
+		return
+	}
+
+	body := []interface{}{}
+	for i, fee := range fees {
+		color := "#27AE60"
+		switch {
+		case fee.Percent >= 2:
+			color = "#E74C3C"
+		case fee.Percent >= 1:
+			color = "#E67E22"
+		}
+		if i > 0 {
+			body = append(body, map[string]interface{}{"type": "separator", "margin": "md"})
+		}
+		body = append(body,
+			map[string]interface{}{
+				"type": "box", "layout": "horizontal", "margin": "md",
+				"contents": []interface{}{
+					map[string]interface{}{"type": "text", "text": "💳 " + truncateLabel(fee.Card, 18), "size": "sm", "weight": "bold", "flex": 6},
+					map[string]interface{}{"type": "text", "text": fmt.Sprintf("%.1f%%", fee.Percent), "size": "sm", "weight": "bold", "color": color, "align": "end", "flex": 3},
+				},
+			},
+			map[string]interface{}{"type": "text", "text": fmt.Sprintf("%d รายการ · ตัดจริง %s · เรทกลาง %s · ส่วนต่าง %s บาท",
+				fee.Count, formatNumber(fee.Charged), formatNumber(fee.MidMarket), formatNumber(fee.Fee)), "size": "xxs", "color": "#888888", "wrap": true},
+		)
+	}
+
+	var total float64
+	for _, fee := range fees {
+		total += fee.Fee
+	}
+	cheapest := fees[len(fees)-1]
+	tip := fmt.Sprintf("บัตร%s ชาร์จ FX เฉลี่ย %.1f%%", fees[0].Card, fees[0].Percent)
+	if len(fees) > 1 {
+		tip = fmt.Sprintf("ใช้ %s ต่างประเทศคุ้มสุด (%.1f%%)", cheapest.Card, cheapest.Percent)
+	}
+	body = append(body,
+		map[string]interface{}{"type": "separator", "margin": "md"},
+		map[string]interface{}{"type": "text", "text": fmt.Sprintf("รวมเสียค่า FX %s บาท", formatNumber(total)), "size": "sm", "weight": "bold", "margin": "md"},
+		map[string]interface{}{"type": "text", "text": "💡 " + tip, "size": "xs", "color": "#555555", "wrap": true},
+	)
+
+	flex := map[string]interface{}{
+		"type": "bubble",
+		"header": map[string]interface{}{
+			"type":            "box",
+			"layout":          "vertical",
+			"backgroundColor": "#0984E3",
+			"paddingAll":      "md",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "💱 ค่าธรรมเนียม FX ต่อบัตร", "color": "#FFFFFF", "weight": "bold", "size": "md"},
+				map[string]interface{}{"type": "text", "text": fmt.Sprintf("เทียบเรทกลางวันที่จ่าย (%d วันล่าสุด)", services.FXFeeLookbackDays), "color": "#FFFFFF", "size": "xs"},
+			},
+		},
+		"body": map[string]interface{}{
+			"type":       "box",
+			"layout":     "vertical",
+			"paddingAll": "md",
+			"contents":   body,
+		},
+	}
+
+	lines := make([]string, 0, len(fees))
+	for _, fee := range fees {
+		lines = append(lines, fmt.Sprintf("บัตร%s ชาร์จ FX เฉลี่ย %.1f%% (%s บาท)", fee.Card, fee.Percent, formatNumber(fee.Fee)))
+	}
+	text := strings.Join(lines, "\n")
+	if !h.replyFlexFromAI(replyToken, flex, text) {
+		h.replyText(replyToken, text)
+	}
+}
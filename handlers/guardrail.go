@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// guardrailSetPattern matches "เตือนถ้าค่าบันเทิงครั้งเดียวเกิน 1000" / "เตือนเมื่อช้อปปิ้งต่อครั้งเกิน 2,000 บาท"
+var guardrailSetPattern = regexp.MustCompile(`^เตือน(?:ถ้า|เมื่อ)\s*(?:หมวด)?\s*(.+?)\s*(?:ครั้งเดียว|ต่อครั้ง)\s*เกิน\s*([\d,]+(?:\.\d+)?)\s*(?:บาท)?$`)
+
+// guardrailDeletePrefixes remove a category's limit
+var guardrailDeletePrefixes = []string{"ยกเลิกเตือน", "ลบเตือน"}
+
+// guardrailCommand is a parsed per-transaction limit command
+type guardrailCommand struct {
+	Action   string // "set", "delete", "list"
+	Category string
+	Amount   float64
+}
+
+// parseGuardrailCommand parses per-transaction limit commands (no AI)
+func parseGuardrailCommand(text string) (guardrailCommand, bool) {
+	text = strings.TrimSpace(text)
+	switch text {
+	case "เตือนต่อครั้ง", "ดูเตือนต่อครั้ง", "ลิมิตต่อครั้ง":
+		return guardrailCommand{Action: "list"}, true
+	}
+
+	if m := guardrailSetPattern.FindStringSubmatch(text); m != nil {
+		var amount float64
+		if _, err := fmt.Sscanf(strings.ReplaceAll(m[2], ",", ""), "%f", &amount); err != nil || amount <= 0 {
+			return guardrailCommand{}, false
+		}
+		return guardrailCommand{Action: "set", Category: m[1], Amount: amount}, true
+	}
+
+	for _, prefix := range guardrailDeletePrefixes {
+		if !strings.HasPrefix(text, prefix) {
+			continue
+		}
+		category := strings.TrimSpace(strings.TrimPrefix(text, prefix))
+		category = strings.TrimSpace(strings.TrimPrefix(category, "หมวด"))
+		if category == "" {
+			return guardrailCommand{}, false
+		}
+		return guardrailCommand{Action: "delete", Category: category}, true
+	}
+	return guardrailCommand{}, false
+}
+
+// handleGuardrailCommand creates, lists or removes per-transaction limits
+func (h *LineWebhookHandler) handleGuardrailCommand(ctx context.Context, replyToken, userID string, cmd guardrailCommand) {
+	switch cmd.Action {
+	case "set":
+		if err := h.mongo.SetGuardrail(ctx, userID, cmd.Category, cmd.Amount); err != nil {
+			log.Printf("Failed to set guardrail: %v", err)
+			h.replyText(replyToken, "ไม่สามารถตั้งการเตือนได้ กรุณาลองใหม่")
+			return
+		}
+		h.replyText(replyToken, fmt.Sprintf("ตั้งแล้วค่ะ ถ้า%sครั้งเดียวเกิน %s บาท จะถามยืนยันก่อนบันทึก\nยกเลิก: พิมพ์ \"ยกเลิกเตือน %s\"", cmd.Category, formatNumber(cmd.Amount), cmd.Category))
+
+	case "delete":
+		deleted, err := h.mongo.DeleteGuardrail(ctx, userID, cmd.Category)
+		if err != nil {
+			log.Printf("Failed to delete guardrail: %v", err)
+			h.replyText(replyToken, "ไม่สามารถยกเลิกการเตือนได้ กรุณาลองใหม่")
+			return
+		}
+		if !deleted {
+			h.replyText(replyToken, fmt.Sprintf("ไม่พบการเตือนต่อครั้งของ %s ค่ะ", cmd.Category))
+			return
+		}
+		h.replyText(replyToken, fmt.Sprintf("ยกเลิกการเตือนต่อครั้งของ %s แล้วค่ะ", cmd.Category))
+
+	default:
+		rules, err := h.mongo.GetGuardrails(ctx, userID)
+		if err != nil {
+			log.Printf("Failed to get guardrails: %v", err)
+			h.replyText(replyToken, "ไม่สามารถดึงข้อมูลได้ กรุณาลองใหม่")
+			return
+		}
+		if len(rules) == 0 {
+			h.replyText(replyToken, "ยังไม่มีการเตือนต่อครั้งค่ะ\nตัวอย่าง: เตือนถ้าค่าบันเทิงครั้งเดียวเกิน 1000")
+			return
+		}
+		lines := []string{"🛡️ เตือนต่อครั้ง"}
+		for _, rule := range rules {
+			lines = append(lines, fmt.Sprintf("• %s เกิน %s บาท", rule.Category, formatNumber(rule.MaxAmount)))
+		}
+		lines = append(lines, "", "ยกเลิก: ยกเลิกเตือน <หมวด>")
+		h.replyText(replyToken, strings.Join(lines, "\n"))
+	}
+}
+
+// pendingGuardrail is a batch held until user confirms guardrail warning
+type pendingGuardrail struct {
+	Transactions []services.TransactionData `json:"transactions"`
+	batchSave
+}
+
+// replyGuardrailConfirm holds transactions that exceed a guardrail and asks user to confirm
+// Returns false (nothing held) when no guardrail is breached or reply failed
+func (h *LineWebhookHandler) replyGuardrailConfirm(ctx context.Context, replyToken, userID string, txs []services.TransactionData, batch batchSave) bool {
+	rules, err := h.mongo.GetGuardrails(ctx, userID)
+	if err != nil || len(rules) == 0 {
+		return false
+	}
+	breaches := services.FindGuardrailBreaches(rules, txs)
+	if len(breaches) == 0 {
+		return false
+	}
+
+	pendingJSON, _ := json.Marshal(pendingGuardrail{Transactions: txs, batchSave: batch})
+	key := fmt.Sprintf("guardrail_%s_%d", userID, time.Now().Unix())
+	if err := h.mongo.SaveTempData(ctx, key, string(pendingJSON), 10*time.Minute); err != nil {
+		log.Printf("Failed to save pending guardrail: %v", err)
+		return false
+	}
+
+	bodyContents := []interface{}{}
+	for _, b := range breaches {
+		tx := txs[b.Index]
+		bodyContents = append(bodyContents,
+			map[string]interface{}{"type": "text", "text": fmt.Sprintf("%s %s บาท", orDefault(tx.Description, tx.Category), formatNumber(tx.Amount)), "size": "sm", "weight": "bold", "wrap": true},
+			map[string]interface{}{"type": "text", "text": fmt.Sprintf("เกินที่ตั้งไว้ %s ครั้งละ %s บาท", b.Rule.Category, formatNumber(b.Rule.MaxAmount)), "size": "xs", "color": "#888888", "wrap": true},
+		)
+	}
+	bodyContents = append(bodyContents, map[string]interface{}{"type": "text", "text": "ยืนยันบันทึกรายการนี้ไหมคะ?", "size": "xs", "color": "#888888", "wrap": true, "margin": "md"})
+
+	flex := map[string]interface{}{
+		"type": "bubble",
+		"size": "kilo",
+		"header": map[string]interface{}{
+			"type":            "box",
+			"layout":          "vertical",
+			"backgroundColor": "#F39C12",
+			"paddingAll":      "sm",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "🛡️ เกินลิมิตต่อครั้ง", "color": "#FFFFFF", "weight": "bold", "size": "sm"},
+			},
+		},
+		"body": map[string]interface{}{
+			"type":       "box",
+			"layout":     "vertical",
+			"paddingAll": "md",
+			"spacing":    "xs",
+			"contents":   bodyContents,
+		},
+		"footer": map[string]interface{}{
+			"type":    "box",
+			"layout":  "vertical",
+			"spacing": "sm",
+			"contents": []interface{}{
+				map[string]interface{}{
+					"type": "button", "style": "primary", "height": "sm", "color": "#E67E22",
+					"action": map[string]interface{}{
+						"type":        "postback",
+						"label":       "บันทึกเลย",
+						"data":        "action=guardrail_confirm&key=" + key,
+						"displayText": "บันทึกเลย",
+					},
+				},
+				map[string]interface{}{
+					"type": "button", "style": "link", "height": "sm",
+					"action": map[string]interface{}{
+						"type":        "postback",
+						"label":       "ยกเลิก",
+						"data":        "action=guardrail_confirm&key=" + key + "&cancel=1",
+						"displayText": "ยกเลิก",
+					},
+				},
+			},
+		},
+	}
+
+	if !h.replyFlexFromAI(replyToken, flex, "เกินลิมิตต่อครั้ง รอยืนยัน") {
+		h.mongo.DeleteTempData(ctx, key)
+		return false
+	}
+	return true
+}
+
+// handleGuardrailConfirm saves (or drops) transactions held by a guardrail warning
+func (h *LineWebhookHandler) handleGuardrailConfirm(ctx context.Context, replyToken, userID string, params map[string]string) {
+	key := params["key"]
+	pendingJSON, err := h.mongo.GetTempData(ctx, key)
+	if err != nil {
+		h.replyText(replyToken, pendingExpiredText)
+		return
+	}
+	h.mongo.DeleteTempData(ctx, key)
+
+	if params["cancel"] == "1" {
+		h.replyText(replyToken, "ยกเลิกรายการแล้วค่ะ")
+		return
+	}
+
+	var pending pendingGuardrail
+	if err := json.Unmarshal([]byte(pendingJSON), &pending); err != nil || len(pending.Transactions) == 0 {
+		log.Printf("Failed to parse pending guardrail: %v", err)
+		h.replyText(replyToken, "เกิดข้อผิดพลาด กรุณาพิมพ์ใหม่อีกครั้ง")
+		return
+	}
+
+	// Confirming a guardrail warning doesn't lift a child account's cap
+	settings, _ := h.mongo.GetUserSettings(ctx, userID)
+	if h.holdForParentApproval(ctx, replyToken, userID, settings, pending.Transactions) {
+		return
+	}
+
+	if !h.saveTransactionBatch(ctx, replyToken, userID, pending.Transactions, pending.batchSave, "") {
+		h.replyText(replyToken, "บันทึกแล้วค่ะ")
+	}
+}
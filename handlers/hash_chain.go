@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// hashVerifyPattern matches "ตรวจแฮช" with an optional month ("ตรวจแฮช 2026-09")
+var hashVerifyPattern = regexp.MustCompile(`^ตรวจ\s*(?:แฮช|hash)\s*(\d{4}-\d{2})?$`)
+
+// hashChainCommand is a parsed transaction hashing command
+type hashChainCommand struct {
+	Action string // "on", "off", "verify"
+	Month  string // YYYY-MM for verify
+}
+
+// parseHashChainCommand parses "เปิดแฮชรายการ", "ปิดแฮชรายการ" and "ตรวจแฮช [YYYY-MM]"
+func parseHashChainCommand(text string, now time.Time) (hashChainCommand, bool) {
+	text = strings.TrimSpace(text)
+	switch strings.ReplaceAll(text, " ", "") {
+	case "เปิดแฮชรายการ", "เปิดแฮช":
+		return hashChainCommand{Action: "on"}, true
+	case "ปิดแฮชรายการ", "ปิดแฮช":
+		return hashChainCommand{Action: "off"}, true
+	}
+	if m := hashVerifyPattern.FindStringSubmatch(text); m != nil {
+		month := m[1]
+		if month == "" {
+			month = now.Format("2006-01")
+		}
+		return hashChainCommand{Action: "verify", Month: month}, true
+	}
+	return hashChainCommand{}, false
+}
+
+// hashMismatchReasons describe HashMismatch.Reason to the user
+var hashMismatchReasons = map[string]string{
+	"modified":   "ถูกแก้นอกแอป",
+	"missing":    "ถูกลบนอกแอป",
+	"unrecorded": "ไม่ได้บันทึกผ่านแอป",
+}
+
+// handleHashChainCommand turns transaction hashing on/off or verifies a month (no AI)
+func (h *LineWebhookHandler) handleHashChainCommand(ctx context.Context, replyToken, userID string, cmd hashChainCommand) {
+	switch cmd.Action {
+	case "on", "off":
+		if err := h.mongo.SetHashChain(ctx, userID, cmd.Action == "on"); err != nil {
+			log.Printf("Failed to set hash chain: %v", err)
+			h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถบันทึกการตั้งค่าได้")
+			return
+		}
+		if cmd.Action == "off" {
+			h.replyText(replyToken, "ปิดแฮชรายการแล้วค่ะ\n⚠️ รายการที่แก้ระหว่างปิดจะตรวจไม่ผ่านเมื่อเปิดใหม่")
+			return
+		}
+		h.replyText(replyToken, "🔐 เปิดแฮชรายการแล้วค่ะ\nทุกรายการที่บันทึก/แก้/ลบต่อจากนี้จะถูกเก็บลายนิ้วมือแบบต่อกันเป็นสายรายเดือน "+
+			"ใช้พิสูจน์ว่าไฟล์ที่ export ไม่ถูกแก้ภายหลัง (เช่น ยื่นเบิกหรือโต้แย้งยอด)\nตรวจ: ตรวจแฮช หรือ ตรวจแฮช 2026-09")
+		return
+	}
+
+	settings, err := h.mongo.GetUserSettings(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to get settings: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถดึงการตั้งค่าได้")
+		return
+	}
+	if settings.HashChainSince.IsZero() {
+		h.replyText(replyToken, "ยังไม่ได้เปิดแฮชรายการค่ะ พิมพ์ \"เปิดแฮชรายการ\" ก่อน")
+		return
+	}
+	report, err := h.mongo.VerifyMonthHashChain(ctx, userID, cmd.Month)
+	if err != nil {
+		log.Printf("Failed to verify hash chain: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถตรวจแฮชได้")
+		return
+	}
+	h.replyText(replyToken, hashChainReportText(report))
+}
+
+// hashChainReportText summarizes a verification: result, counts, problem entries and the head hash
+func hashChainReportText(r *services.HashChainReport) string {
+	var b strings.Builder
+	if r.OK() {
+		fmt.Fprintf(&b, "✅ เดือน %s ไม่ถูกแก้ไขตั้งแต่บันทึก\nตรงกับสายแฮช %d รายการ", r.Month, r.Verified)
+	} else {
+		fmt.Fprintf(&b, "❌ เดือน %s ตรวจไม่ผ่าน", r.Month)
+		if r.BrokenAt > 0 {
+			fmt.Fprintf(&b, "\nสายแฮชขาดที่ลำดับ %d (ข้อมูลแฮชถูกแก้)", r.BrokenAt)
+		}
+		for i, m := range r.Mismatches {
+			if i >= 10 {
+				fmt.Fprintf(&b, "\nและอีก %d รายการ", len(r.Mismatches)-10)
+				break
+			}
+			fmt.Fprintf(&b, "\n• %s %s %s (%s)", m.Date, orDefault(m.Description, m.TxID), formatNumber(m.Amount), hashMismatchReasons[m.Reason])
+		}
+		fmt.Fprintf(&b, "\nตรงกับสายแฮช %d รายการ", r.Verified)
+	}
+	if r.Unsealed > 0 {
+		fmt.Fprintf(&b, "\n(%d รายการบันทึกก่อนเปิดแฮช ไม่ได้ตรวจ)", r.Unsealed)
+	}
+	if r.Pending > 0 {
+		fmt.Fprintf(&b, "\n(%d รายการยังรอเข้าสายแฮช ตรวจอีกครั้งภายหลัง)", r.Pending)
+	}
+	if r.Head != "" {
+		fmt.Fprintf(&b, "\n\n🔐 แฮชเดือนนี้: %s\nเก็บรหัสนี้คู่กับไฟล์ export ไว้ใช้ยืนยันภายหลัง", r.Head)
+	}
+	return b.String()
+}
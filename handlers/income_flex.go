@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/satisatang/backend/services"
+)
+
+// incomeKeywords are phrases that indicate the user asks about income sources
+var incomeKeywords = []string{"รายได้", "income"}
+
+// needsIncomeContext checks if message asks about income
+func needsIncomeContext(text string) bool {
+	lower := strings.ToLower(text)
+	for _, kw := range incomeKeywords {
+		if strings.Contains(lower, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// stackColors are segment colors for stacked bars
+var stackColors = []string{"#27AE60", "#1E88E5", "#F39C12", "#8E44AD", "#16A085", "#95A5A6"}
+
+// buildStackedBar creates a horizontal stacked bar with legend (max 5 segments + others)
+// Segments use a filler child because cleanFlexData strips empty contents
+func buildStackedBar(sources []services.IncomeSource, total float64) []interface{} {
+	if total <= 0 {
+		return nil
+	}
+
+	var segments, legend []interface{}
+	others := 0.0
+	idx := 0
+	for _, src := range sources {
+		if src.Amount <= 0 {
+			continue
+		}
+		if idx >= len(stackColors)-1 {
+			others += src.Amount
+			continue
+		}
+		color := stackColors[idx]
+		percentage := (src.Amount / total) * 100
+		segments = append(segments, map[string]interface{}{
+			"type": "box", "layout": "vertical", "contents": []interface{}{map[string]interface{}{"type": "filler"}},
+			"backgroundColor": color, "flex": int(percentage) + 1,
+		})
+		legend = append(legend, map[string]interface{}{
+			"type": "box", "layout": "horizontal", "margin": "xs",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "■", "size": "xs", "color": color, "flex": 1},
+				map[string]interface{}{"type": "text", "text": truncateLabel(src.Name, 14), "size": "xs", "color": "#555555", "flex": 6},
+				map[string]interface{}{"type": "text", "text": fmt.Sprintf("%s (%.0f%%)", formatNumber(src.Amount), percentage), "size": "xs", "color": "#333333", "align": "end", "flex": 6},
+			},
+		})
+		idx++
+	}
+	if others > 0 {
+		color := stackColors[len(stackColors)-1]
+		percentage := (others / total) * 100
+		segments = append(segments, map[string]interface{}{
+			"type": "box", "layout": "vertical", "contents": []interface{}{map[string]interface{}{"type": "filler"}},
+			"backgroundColor": color, "flex": int(percentage) + 1,
+		})
+		legend = append(legend, map[string]interface{}{
+			"type": "box", "layout": "horizontal", "margin": "xs",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "■", "size": "xs", "color": color, "flex": 1},
+				map[string]interface{}{"type": "text", "text": "อื่นๆ", "size": "xs", "color": "#555555", "flex": 6},
+				map[string]interface{}{"type": "text", "text": fmt.Sprintf("%s (%.0f%%)", formatNumber(others), percentage), "size": "xs", "color": "#333333", "align": "end", "flex": 6},
+			},
+		})
+	}
+
+	bar := map[string]interface{}{
+		"type": "box", "layout": "horizontal", "height": "12px", "margin": "sm",
+		"cornerRadius": "6px", "contents": segments,
+	}
+	return append([]interface{}{bar}, legend...)
+}
+
+// formatIncomeDelta formats income change vs reference (more income = green)
+func formatIncomeDelta(current, reference float64) (string, string) {
+	diff := current - reference
+	text := formatBalanceText(diff)
+	if diff > 0 {
+		text = "+" + text
+	}
+	if reference > 0 {
+		text += fmt.Sprintf(" (%+.0f%%)", (diff/reference)*100)
+	}
+	switch {
+	case diff > 0:
+		return text, "#27AE60"
+	case diff < 0:
+		return text, "#E74C3C"
+	}
+	return text, "#888888"
+}
+
+// replyIncomeReportFlex sends income source report with stacked bars and MoM/YoY deltas
+func (h *LineWebhookHandler) replyIncomeReportFlex(replyToken string, report *services.IncomeReport, msg string) bool {
+	if report == nil {
+		return false
+	}
+
+	momText, momColor := formatIncomeDelta(report.Total, report.PreviousTotal)
+	contents := []interface{}{
+		map[string]interface{}{
+			"type": "box", "layout": "horizontal",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "💵 รายได้รวม", "size": "sm", "weight": "bold", "flex": 2},
+				map[string]interface{}{"type": "text", "text": formatNumber(report.Total), "size": "md", "weight": "bold", "color": "#27AE60", "align": "end", "flex": 3},
+			},
+		},
+		map[string]interface{}{
+			"type": "box", "layout": "horizontal", "margin": "sm",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "เทียบ" + report.PreviousLabel, "size": "xs", "color": "#888888", "flex": 2},
+				map[string]interface{}{"type": "text", "text": momText, "size": "xs", "color": momColor, "align": "end", "flex": 3},
+			},
+		},
+	}
+	if report.HasLastYear {
+		yoyText, yoyColor := formatIncomeDelta(report.Total, report.LastYearTotal)
+		contents = append(contents, map[string]interface{}{
+			"type": "box", "layout": "horizontal", "margin": "xs",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "เทียบปีที่แล้ว", "size": "xs", "color": "#888888", "flex": 2},
+				map[string]interface{}{"type": "text", "text": yoyText, "size": "xs", "color": yoyColor, "align": "end", "flex": 3},
+			},
+		})
+	}
+
+	if report.Total > 0 {
+		contents = append(contents,
+			map[string]interface{}{"type": "separator", "margin": "md"},
+			map[string]interface{}{"type": "text", "text": "🏷️ ตามหมวด", "size": "xs", "color": "#888888", "margin": "md"},
+		)
+		contents = append(contents, buildStackedBar(report.ByCategory, report.Total)...)
+		contents = append(contents,
+			map[string]interface{}{"type": "separator", "margin": "md"},
+			map[string]interface{}{"type": "text", "text": "🏦 เข้าบัญชี", "size": "xs", "color": "#888888", "margin": "md"},
+		)
+		contents = append(contents, buildStackedBar(report.ByDestination, report.Total)...)
+	} else {
+		contents = append(contents, map[string]interface{}{
+			"type": "text", "text": "ยังไม่มีรายได้ในช่วงนี้", "size": "sm", "color": "#888888", "margin": "md", "align": "center",
+		})
+	}
+
+	if msg != "" {
+		contents = append(contents,
+			map[string]interface{}{"type": "separator", "margin": "md"},
+			map[string]interface{}{"type": "text", "text": msg, "size": "sm", "color": "#666666", "wrap": true, "margin": "md"},
+		)
+	}
+
+	flex := map[string]interface{}{
+		"type": "bubble",
+		"size": "mega",
+		"header": map[string]interface{}{
+			"type":            "box",
+			"layout":          "vertical",
+			"backgroundColor": "#27AE60",
+			"paddingAll":      "md",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "💵 รายได้" + report.Label + "มาจากไหน", "color": "#FFFFFF", "weight": "bold", "size": "md"},
+				map[string]interface{}{"type": "text", "text": fmt.Sprintf("%s ถึง %s • %d รายการ", report.From, report.To, report.TransactionCount), "color": "#D5F5E3", "size": "xxs", "margin": "xs"},
+			},
+		},
+		"body": map[string]interface{}{
+			"type":       "box",
+			"layout":     "vertical",
+			"paddingAll": "md",
+			"contents":   contents,
+		},
+	}
+
+	altText := msg
+	if altText == "" {
+		altText = "รายได้" + report.Label
+	}
+	return h.replyFlexFromAI(replyToken, flex, altText)
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/satisatang/backend/services"
+)
+
+// replyInvestmentHoldings lists money put into each fund/stock (cost, not market value)
+func (h *LineWebhookHandler) replyInvestmentHoldings(ctx context.Context, replyToken, userID string) {
+	holdings, err := h.mongo.GetInvestmentHoldings(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to get investment holdings: %v", err)
+		h.replyText(replyToken, "ไม่สามารถดึงข้อมูลได้ กรุณาลองใหม่")
+		return
+	}
+	if len(holdings) == 0 {
+		h.replyText(replyToken, "ยังไม่มีรายการลงทุนค่ะ\nตัวอย่าง: ซื้อกองทุน SCBSET 5000 จากกสิกร")
+		return
+	}
+
+	lines := []string{fmt.Sprintf("📈 เงินลงทุนรวม %s บาท (ราคาทุน)", formatNumber(services.TotalInvested(holdings)))}
+	for _, holding := range holdings {
+		lines = append(lines, fmt.Sprintf("• %s %s บาท (ซื้อ %d ครั้ง ล่าสุด %s)",
+			holding.Asset, formatNumber(holding.Contributed), holding.Count, formatThaiShortDate(holding.LastDate)))
+	}
+	lines = append(lines, "", "ไม่นับเป็นรายจ่าย ไม่กระทบงบและสรุปค่าใช้จ่ายค่ะ")
+	h.replyText(replyToken, strings.Join(lines, "\n"))
+}
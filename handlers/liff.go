@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+	"github.com/satisatang/backend/services"
+)
+
+//go:embed liff_amount.html
+var liffAmountPage string
+
+// liffEditEchoText is sent by the LIFF page after saving so the bot can reply the updated flex
+const liffEditEchoText = "✏️ แก้ยอดเงินแล้ว"
+
+// LIFFHandler serves the amount number pad LIFF page and its API
+type LIFFHandler struct {
+	mongo     *services.MongoDBService
+	liffID    string
+	channelID string
+}
+
+// NewLIFFHandler creates a new LIFF handler
+func NewLIFFHandler(mongo *services.MongoDBService, liffID string) *LIFFHandler {
+	return &LIFFHandler{mongo: mongo, liffID: liffID, channelID: services.LIFFChannelID(liffID)}
+}
+
+// liffAmountURL returns LIFF URL of number pad for a transaction
+func liffAmountURL(liffID, txID, date string, amount float64) string {
+	return fmt.Sprintf("https://liff.line.me/%s/amount?txid=%s&date=%s&amount=%s",
+		liffID, url.QueryEscape(txID), url.QueryEscape(date), strings.TrimSuffix(fmt.Sprintf("%.2f", amount), ".00"))
+}
+
+// HandleAmountPage serves number pad HTML
+func (h *LIFFHandler) HandleAmountPage(c *gin.Context) {
+	page := strings.NewReplacer("{{LIFF_ID}}", h.liffID, "{{ECHO_TEXT}}", liffEditEchoText).Replace(liffAmountPage)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+}
+
+// liffAmountRequest is the number pad API body
+type liffAmountRequest struct {
+	TxID   string  `json:"txid"`
+	Date   string  `json:"date"`
+	Amount float64 `json:"amount"`
+}
+
+// HandleAmountUpdate updates transaction amount for the LIFF user
+func (h *LIFFHandler) HandleAmountUpdate(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	userID, err := services.VerifyLIFFAccessToken(ctx, strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "), h.channelID)
+	if err != nil {
+		log.Printf("LIFF token rejected: %v", err)
+		h.mongo.LogSecurityEvent(ctx, services.SecurityEvent{Event: services.SecurityAccessDenied, Detail: "liff token rejected", ClientIP: c.ClientIP()})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "กรุณาเปิดจาก LINE อีกครั้ง"})
+		return
+	}
+
+	var req liffAmountRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.TxID == "" || req.Amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	if _, err := time.Parse("2006-01-02", req.Date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "วันที่ไม่ถูกต้อง"})
+		return
+	}
+
+	if _, err := h.mongo.GetTransactionOnDate(ctx, userID, req.TxID, req.Date); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบรายการ"})
+		return
+	}
+	if err := h.mongo.UpdateTransactionAmountOnDate(ctx, userID, req.TxID, req.Date, req.Amount); err != nil {
+		if errors.Is(err, services.ErrPeriodLocked) {
+			c.JSON(http.StatusConflict, gin.H{"error": "รายการนี้อยู่ในงวดที่ปิดแล้ว"})
+			return
+		}
+		if errors.Is(err, services.ErrSpendingCap) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "ยอดเกินวงเงินต่อรายการของบัญชีเด็ก"})
+			return
+		}
+		log.Printf("Failed to update amount from LIFF: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "บันทึกไม่สำเร็จ"})
+		return
+	}
+
+	// Remember edited transaction so the echo message gets the updated flex as reply
+	h.mongo.SaveTempData(ctx, "liff_edit_"+userID, req.TxID+"|"+req.Date, 10*time.Minute)
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// handleLIFFEditEcho replies updated confirmation after number pad edit, false if nothing pending
+func (h *LineWebhookHandler) handleLIFFEditEcho(ctx context.Context, replyToken, userID, text string) bool {
+	if strings.TrimSpace(text) != liffEditEchoText {
+		return false
+	}
+	key := "liff_edit_" + userID
+	pending, err := h.mongo.GetTempData(ctx, key)
+	if err != nil || pending == "" {
+		return false
+	}
+	h.mongo.DeleteTempData(ctx, key)
+
+	parts := strings.SplitN(pending, "|", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	tx, err := h.mongo.GetTransactionOnDate(ctx, userID, parts[0], parts[1])
+	if err != nil {
+		h.replyText(replyToken, "แก้ยอดเงินแล้วค่ะ")
+		return true
+	}
+	h.replyUpdatedTransaction(replyToken, userID, tx, fmt.Sprintf("แก้ยอดเป็น %s บาทแล้วค่ะ", formatNumber(tx.Amount)), parts[0], parts[1])
+	return true
+}
+
+// editAmountButton returns "✏️ แก้ยอด" flex button opening number pad (nil when LIFF is off)
+func (h *LineWebhookHandler) editAmountButton(txID, date string, amount float64) map[string]interface{} {
+	if h.liffID == "" || txID == "" {
+		return nil
+	}
+	return map[string]interface{}{
+		"type": "button", "style": "secondary", "height": "sm",
+		"action": map[string]interface{}{"type": "uri", "label": "✏️ แก้ยอด", "uri": liffAmountURL(h.liffID, txID, date, amount)},
+	}
+}
+
+// editQuickReplyItem returns ✏️ quick reply: number pad when LIFF is on, otherwise edit guide postback
+func (h *LineWebhookHandler) editQuickReplyItem(label, txID, date string, amount float64) messaging_api.QuickReplyItem {
+	if h.liffID != "" && txID != "" {
+		return messaging_api.QuickReplyItem{
+			Action: &messaging_api.UriAction{Label: label, Uri: liffAmountURL(h.liffID, txID, date, amount)},
+		}
+	}
+	return messaging_api.QuickReplyItem{
+		Action: &messaging_api.PostbackAction{Label: label, Data: fmt.Sprintf("action=edit_request&txid=%s&date=%s", txID, date)},
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/webhook"
+	"github.com/satisatang/backend/services"
+)
+
+// fetchLineProfile gets display name/picture from the Messaging API and caches it on the profile
+func (h *LineWebhookHandler) fetchLineProfile(ctx context.Context, userID string) string {
+	if h.bot == nil || userID == "" {
+		return ""
+	}
+	resp, err := h.bot.GetProfile(userID)
+	if err != nil {
+		log.Printf("Failed to get LINE profile: %v", err)
+		return ""
+	}
+	if err := h.mongo.SetLineProfile(ctx, userID, resp.DisplayName, resp.PictureUrl); err != nil {
+		log.Printf("Failed to save LINE profile: %v", err)
+	}
+	return resp.DisplayName
+}
+
+// refreshLineProfileAsync refetches a stale display name without delaying the reply
+func (h *LineWebhookHandler) refreshLineProfileAsync(profile *services.UserProfile, userID string) {
+	if !profile.NeedsLineProfile(time.Now()) {
+		return
+	}
+	services.GoSafe("line.profile", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		h.fetchLineProfile(ctx, userID)
+	})
+}
+
+// handleFollow greets a new (or returning) friend by LINE display name
+func (h *LineWebhookHandler) handleFollow(ctx context.Context, event webhook.FollowEvent) {
+	userID := h.getUserID(event.Source)
+	greeting := "สวัสดีค่ะ"
+	if name := h.fetchLineProfile(ctx, userID); name != "" {
+		greeting += " คุณ" + name
+	}
+	h.replyText(event.ReplyToken, greeting+" 👋\nยินดีต้อนรับสู่สติสตางค์ ผู้ช่วยจดรายรับรายจ่าย\nพิมพ์ได้เลย เช่น \"กาแฟ 50\" หรือส่งรูปสลิปมาได้ค่ะ")
+}
+
+// slipAmountContents shows the slip amount with its verification badge, plus the fee and total when the slip
+// has a fee line (the fee is saved as its own expense when the slip is recorded as รายจ่าย)
+func slipAmountContents(slip *services.TransactionData) []interface{} {
+	contents := []interface{}{
+		map[string]interface{}{"type": "text", "text": formatNumber(slip.Amount) + " บาท", "size": "xl", "weight": "bold", "color": "#3498DB", "align": "center"},
+	}
+	switch slip.SlipStatus {
+	case services.SlipVerified:
+		contents = append(contents, map[string]interface{}{"type": "text", "text": "✅ ตรวจสอบกับธนาคารแล้ว", "size": "xxs", "color": "#27AE60", "align": "center"})
+	case services.SlipSuspicious:
+		contents = append(contents, map[string]interface{}{"type": "text", "text": "⚠️ สลิปน่าสงสัย: " + slip.SlipReason, "size": "xs", "color": "#E74C3C", "align": "center", "wrap": true, "weight": "bold"})
+	}
+	if slip.Fee <= 0 {
+		return contents
+	}
+	return append(contents,
+		map[string]interface{}{
+			"type": "box", "layout": "horizontal", "margin": "sm",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "🏧 ค่าธรรมเนียม", "size": "xxs", "color": "#888888"},
+				map[string]interface{}{"type": "text", "text": formatNumber(slip.Fee) + " บาท", "size": "xxs", "color": "#888888", "align": "end"},
+			},
+		},
+		map[string]interface{}{
+			"type": "box", "layout": "horizontal",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "รวมหักบัญชี", "size": "xxs", "color": "#666666", "weight": "bold"},
+				map[string]interface{}{"type": "text", "text": formatNumber(slip.Amount+slip.Fee) + " บาท", "size": "xxs", "color": "#666666", "weight": "bold", "align": "end"},
+			},
+		},
+	)
+}
+
+// slipSuggestion guesses direction by matching slip names against the user's display name
+func slipSuggestion(displayName string, slip *services.TransactionData) (string, string) {
+	switch {
+	case services.MatchSlipName(displayName, slip.FromName):
+		return "💡 คุณเป็นผู้โอน น่าจะเป็นรายจ่าย", "#E74C3C"
+	case services.MatchSlipName(displayName, slip.ToName):
+		return "💡 คุณเป็นผู้รับ น่าจะเป็นรายรับ", "#27AE60"
+	}
+	return "💡 เลือกว่าเป็นรายรับหรือรายจ่าย", "#666666"
+}
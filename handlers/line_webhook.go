@@ -10,14 +10,19 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
 	"github.com/line/line-bot-sdk-go/v8/linebot/webhook"
 	"github.com/satisatang/backend/services"
+	"golang.org/x/sync/errgroup"
 )
 
+// contextReadTimeout bounds the concurrent Mongo reads that build AI context
+const contextReadTimeout = 5 * time.Second
+
 type LineWebhookHandler struct {
 	channelSecret string
 	bot           *messaging_api.MessagingApiAPI
@@ -25,10 +30,25 @@ type LineWebhookHandler struct {
 	ai            services.AIChat
 	mongo         *services.MongoDBService
 	export        *services.ExportService
+	webhooks      *services.WebhookService
+	sheets        *services.SheetsService // nil when Google OAuth is not configured
+	notifications *services.NotificationService
 	firebase      *services.FirebaseService
+	publicBaseURL string // when set, files are served via short /d/:token links
+	liffID        string // when set, ✏️ opens LIFF number pad
+	push          pushQuota
+	metrics       replyMetrics
+	replyOwners   sync.Map // reply token -> *replyOwner, for push fallback
+	imageOpts     services.ImageCompressOptions
+	stitchMu      sync.Mutex // guards receipt photo buffers
+	admins        map[string]bool
+	flags         *services.FeatureFlagService // nil when feature flags are not configured
+	backup        *services.BackupService      // nil when storage is not configured
+	slipVerifier  *services.SlipVerifier       // nil when slip verification is not configured
+	fx            *services.FXRates            // nil when exchange rates are not configured (travel mode asks for the rate)
 }
 
-func NewLineWebhookHandler(channelSecret, channelToken string, ai services.AIChat, mongo *services.MongoDBService, firebase *services.FirebaseService) (*LineWebhookHandler, error) {
+func NewLineWebhookHandler(channelSecret, channelToken string, ai services.AIChat, mongo *services.MongoDBService, firebase *services.FirebaseService, sheets *services.SheetsService, publicBaseURL, liffID string) (*LineWebhookHandler, error) {
 	bot, err := messaging_api.NewMessagingApiAPI(channelToken)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Line bot: %w", err)
@@ -46,10 +66,65 @@ func NewLineWebhookHandler(channelSecret, channelToken string, ai services.AICha
 		ai:            ai,
 		mongo:         mongo,
 		export:        services.NewExportService(mongo),
+		webhooks:      services.NewWebhookService(mongo),
+		sheets:        sheets,
+		notifications: services.NewNotificationService(mongo),
 		firebase:      firebase,
+		publicBaseURL: strings.TrimRight(publicBaseURL, "/"),
+		liffID:        liffID,
+		imageOpts:     services.DefaultImageCompressOptions,
+	}, nil
+}
+
+// WithChannel returns a handler for another LINE channel (second bot persona) sharing services and settings
+// Services are shared so transaction hooks like outbound webhooks are not registered twice;
+// push quota (EnablePush) and reply tracking stay per channel
+func (h *LineWebhookHandler) WithChannel(channelSecret, channelToken string) (*LineWebhookHandler, error) {
+	bot, err := messaging_api.NewMessagingApiAPI(channelToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Line bot: %w", err)
+	}
+	blobAPI, err := messaging_api.NewMessagingApiBlobAPI(channelToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Line blob API: %w", err)
+	}
+	return &LineWebhookHandler{
+		channelSecret: channelSecret,
+		bot:           bot,
+		blobAPI:       blobAPI,
+		ai:            h.ai,
+		mongo:         h.mongo,
+		export:        h.export,
+		webhooks:      h.webhooks,
+		sheets:        h.sheets,
+		notifications: h.notifications,
+		firebase:      h.firebase,
+		publicBaseURL: h.publicBaseURL,
+		liffID:        h.liffID,
+		imageOpts:     h.imageOpts,
+		admins:        h.admins,
+		flags:         h.flags,
+		backup:        h.backup,
+		slipVerifier:  h.slipVerifier,
+		fx:            h.fx,
 	}, nil
 }
 
+// SetSlipVerifier enables checking transfer slips with the bank before they are recorded (nil disables)
+func (h *LineWebhookHandler) SetSlipVerifier(verifier *services.SlipVerifier) {
+	h.slipVerifier = verifier
+}
+
+// SetFXRates enables fetching exchange rates for travel mode (nil disables)
+func (h *LineWebhookHandler) SetFXRates(fx *services.FXRates) {
+	h.fx = fx
+}
+
+// SetImageCompression sets size limits for receipt images (0 dimension or size disables compression)
+func (h *LineWebhookHandler) SetImageCompression(opts services.ImageCompressOptions) {
+	h.imageOpts = opts
+}
+
 func (h *LineWebhookHandler) HandleWebhook(c *gin.Context) {
 	cb, err := webhook.ParseRequest(h.channelSecret, c.Request)
 	if err != nil {
@@ -63,19 +138,35 @@ func (h *LineWebhookHandler) HandleWebhook(c *gin.Context) {
 	}
 
 	for _, event := range cb.Events {
-		log.Printf("Got event: %v", event)
+		log.Printf("Got event: %T", event)
 
 		switch e := event.(type) {
 		case webhook.MessageEvent:
-			h.handleMessage(c.Request.Context(), e)
+			h.handleEvent(e.ReplyToken, h.getUserID(e.Source), time.UnixMilli(e.Timestamp), "webhook.message", func() {
+				h.handleMessage(c.Request.Context(), e)
+			})
 		case webhook.PostbackEvent:
-			h.handlePostback(c.Request.Context(), e)
+			h.handleEvent(e.ReplyToken, h.getUserID(e.Source), time.UnixMilli(e.Timestamp), "webhook.postback", func() {
+				h.handlePostback(c.Request.Context(), e)
+			})
+		case webhook.FollowEvent:
+			h.handleEvent(e.ReplyToken, h.getUserID(e.Source), time.UnixMilli(e.Timestamp), "webhook.follow", func() {
+				h.handleFollow(c.Request.Context(), e)
+			})
 		}
 	}
 
 	c.Status(http.StatusOK)
 }
 
+// handleEvent runs one event with reply token bookkeeping and panic recovery
+func (h *LineWebhookHandler) handleEvent(replyToken, userID string, receivedAt time.Time, action string, fn func()) {
+	h.rememberReplyToken(replyToken, userID, receivedAt)
+	defer h.forgetReplyToken(replyToken)
+	defer h.recoverEvent(action, replyToken, userID)
+	fn()
+}
+
 func (h *LineWebhookHandler) handleMessage(ctx context.Context, event webhook.MessageEvent) {
 	log.Printf("Message type: %T", event.Message)
 	replyToken := event.ReplyToken
@@ -85,10 +176,21 @@ func (h *LineWebhookHandler) handleMessage(ctx context.Context, event webhook.Me
 		log.Printf("Processing image message")
 		h.handleImageMessage(ctx, event.Source, message, replyToken)
 	case webhook.TextMessageContent:
-		log.Printf("Processing text message: %s", message.Text)
+		log.Printf("Processing text message: %s", services.RedactExportPassword(message.Text))
 		h.handleTextMessage(ctx, event.Source, message, replyToken)
+	case webhook.FileMessageContent:
+		log.Printf("Processing file message: %s", message.FileName)
+		h.handleFileMessage(ctx, event.Source, message, replyToken)
+	case webhook.VideoMessageContent:
+		log.Printf("Processing video message (%d ms)", message.Duration)
+		h.handleVideoMessage(ctx, event.Source, message, replyToken)
+	case webhook.AudioMessageContent:
+		log.Printf("Processing audio message (%d ms)", message.Duration)
+		h.handleAudioMessage(replyToken)
 	default:
+		// Stickers, locations etc.: a nudge instead of silence
 		log.Printf("Unknown message type: %T", event.Message)
+		h.replyText(replyToken, unsupportedMediaText)
 	}
 }
 
@@ -122,6 +224,23 @@ func (h *LineWebhookHandler) handleImageMessage(ctx context.Context, source webh
 		return
 	}
 
+	// Downscale large photos before OCR and storage (smaller payload, faster AI)
+	originalSize := len(imageBytes)
+	imageBytes, contentType = services.CompressImage(imageBytes, contentType, h.imageOpts)
+	if len(imageBytes) != originalSize {
+		log.Printf("Image compressed: %d -> %d bytes", originalSize, len(imageBytes))
+	}
+
+	// Long receipts sent as several photos are buffered and combined after user confirms
+	if h.bufferReceiptImage(ctx, replyToken, userID, message.ImageSet, imageBytes, contentType) {
+		return
+	}
+
+	h.processReceiptImage(ctx, replyToken, userID, imageBytes, contentType)
+}
+
+// processReceiptImage reads one image with AI and replies slip confirm or saved receipt
+func (h *LineWebhookHandler) processReceiptImage(ctx context.Context, replyToken, userID string, imageBytes []byte, contentType string) {
 	// Convert to base64 for storage
 	imageBase64 := base64.StdEncoding.EncodeToString(imageBytes)
 
@@ -139,12 +258,24 @@ func (h *LineWebhookHandler) handleImageMessage(ctx context.Context, source webh
 
 	// Check if it's a transfer slip - ask user if income or expense
 	if transactionData.ImageType == "slip" {
+		// Edited or fake slips are flagged before the user records them
+		if h.slipVerifier != nil {
+			check := h.slipVerifier.Verify(ctx, *transactionData)
+			transactionData.SlipStatus, transactionData.SlipReason = check.Status, check.Reason
+			if check.Status == services.SlipSuspicious {
+				h.mongo.LogSecurityEvent(ctx, services.SecurityEvent{LineID: userID, Event: services.SecuritySlipSuspicious, Detail: "ref " + transactionData.RefNo + ": " + check.Reason})
+			}
+		}
 		h.replySlipConfirmFlex(replyToken, userID, transactionData)
 		return
 	}
 
-	// Regular receipt - process directly
-	h.replyTransactionFlex(replyToken, userID, transactionData)
+	// Regular receipt - process directly, next photo within a short window may be its continuation
+	transactionData.Source = services.TransactionSourceImage
+	h.applyTripToReceipt(ctx, userID, transactionData)
+	if txID := h.replyTransactionFlex(replyToken, userID, transactionData); txID != "" {
+		h.rememberReceiptImages(ctx, userID, []services.ReceiptImage{{Data: imageBytes, MimeType: contentType}}, txID)
+	}
 }
 
 func (h *LineWebhookHandler) handleTextMessage(ctx context.Context, source webhook.SourceInterface, message webhook.TextMessageContent, replyToken string) {
@@ -158,6 +289,17 @@ func (h *LineWebhookHandler) handleTextMessage(ctx context.Context, source webho
 
 	bgCtx := context.Background()
 
+	// Password protection is detected in Go ("export excel ใส่รหัส 1234"); the typed password is
+	// masked right away so it isn't saved to chat history or sent to the AI
+	exportPassword, exportProtect := services.ParseExportPassword(message.Text)
+	message.Text = services.RedactExportPassword(message.Text)
+
+	// "ยกเลิก" drops any pending slip/edit/confirmation (no AI)
+	if isCancelCommand(message.Text) {
+		h.handleCancelCommand(bgCtx, replyToken, userID)
+		return
+	}
+
 	// Check if user has pending slip waiting for category
 	pendingKey := fmt.Sprintf("slip_pending_%s", userID)
 	if pendingJSON, err := h.mongo.GetTempData(bgCtx, pendingKey); err == nil && pendingJSON != "" {
@@ -166,46 +308,426 @@ func (h *LineWebhookHandler) handleTextMessage(ctx context.Context, source webho
 		return
 	}
 
-	// Get last transaction for update reference
-	lastTx, _, _ := h.mongo.GetLastTransaction(bgCtx, userID)
+	// "ใช่"/"ไม่ใช่" after being asked to add a new bank/card
+	if h.handleNewAccountAnswer(bgCtx, replyToken, userID, message.Text) {
+		return
+	}
 
-	// Get user's data structure for AI context (compact)
-	userBanks, userCards, _ := h.mongo.GetDistinctPaymentMethods(bgCtx, userID)
-	_, expenseCategories, _ := h.mongo.GetDistinctCategories(bgCtx, userID)
+	// Echo from LIFF number pad: reply updated transaction (no AI)
+	if h.handleLIFFEditEcho(bgCtx, replyToken, userID, message.Text) {
+		return
+	}
 
-	// Build compact schema for AI
-	schema := ""
-	if len(userBanks) > 0 {
-		schema += "ธนาคาร:" + strings.Join(userBanks, ",")
+	// Number typed after ✏️ on a specific transaction (carousel) updates that one
+	if h.handleEditTargetAmount(bgCtx, replyToken, userID, message.Text) {
+		h.trackEvent(userID, "edit_amount", services.AnalyticsSourcePostback, time.Now(), true, true)
+		return
 	}
-	if len(userCards) > 0 {
-		if schema != "" {
-			schema += "|"
-		}
-		schema += "บัตร:" + strings.Join(userCards, ",")
+
+	// Number typed after ✏️ on a budget (budget overview) sets that budget
+	if h.handleBudgetEditAmount(bgCtx, replyToken, userID, message.Text) {
+		return
+	}
+
+	// Bare number ("100"): ask what it was for instead of letting AI guess
+	if h.handleAmountOnly(bgCtx, replyToken, userID, message.Text) {
+		return
+	}
+
+	// Backup/restore of the user's own data (no AI)
+	if h.handleBackupCommand(bgCtx, replyToken, userID, message.Text) {
+		return
+	}
+
+	// Maintainer feature flag commands (admins only)
+	if h.handleFeatureFlagCommand(bgCtx, replyToken, userID, message.Text) {
+		return
+	}
+
+	// Maintainer usage report (admins only, anonymized)
+	if days, ok := parseAnalyticsReportCommand(message.Text); ok && h.admins[userID] {
+		h.replyAnalyticsReport(bgCtx, replyToken, days)
+		return
+	}
+
+	// Category emoji/color settings are parsed in Go (no AI)
+	if category, emoji, color, ok := parseCategoryStyleCommand(message.Text); ok {
+		h.handleCategoryStyleCommand(bgCtx, replyToken, userID, category, emoji, color)
+		return
+	}
+
+	// VAT report export (no AI)
+	if isVATReportCommand(message.Text) {
+		h.replyVATReport(bgCtx, replyToken, userID, message.Text)
+		return
+	}
+
+	// Subscription list is computed in Go (no AI)
+	if isSubscriptionCommand(message.Text) {
+		h.replySubscriptions(bgCtx, replyToken, userID)
+		return
+	}
+
+	// Month-end forecast with committed subscriptions is computed in Go (no AI)
+	if services.IsForecastQuery(message.Text) {
+		h.replyForecast(bgCtx, replyToken, userID)
+		return
+	}
+
+	// Outbound webhook management (no AI)
+	if action, arg, ok := parseWebhookCommand(message.Text); ok {
+		h.handleWebhookCommand(bgCtx, replyToken, userID, action, arg)
+		return
+	}
+
+	// Google Sheets sync management (no AI)
+	if action, arg, ok := parseSheetsCommand(message.Text); ok {
+		h.handleSheetsCommand(bgCtx, replyToken, userID, action, arg)
+		return
+	}
+
+	// Card billing days and calendar feed (no AI)
+	if cardName, statementDay, dueDay, ok := parseCardAccountCommand(message.Text); ok {
+		h.handleCardAccountCommand(bgCtx, replyToken, userID, cardName, statementDay, dueDay)
+		return
+	}
+	if reset, ok := isCalendarCommand(message.Text); ok {
+		h.replyCalendarFeed(bgCtx, replyToken, userID, reset)
+		return
+	}
+	if reset, ok := isReportAPICommand(message.Text); ok {
+		h.replyReportAPI(bgCtx, replyToken, userID, reset)
+		return
+	}
+
+	// Tamper evidence: "เปิดแฮชรายการ", "ตรวจแฮช 2026-09" (no AI)
+	if cmd, ok := parseHashChainCommand(message.Text, time.Now()); ok {
+		h.handleHashChainCommand(bgCtx, replyToken, userID, cmd)
+		return
+	}
+
+	// Notification preferences (no AI)
+	if cmd, ok := parseNotificationCommand(message.Text); ok {
+		h.handleNotificationCommand(bgCtx, replyToken, userID, cmd)
+		return
+	}
+
+	// Per-transaction category limits (no AI)
+	if cmd, ok := parseGuardrailCommand(message.Text); ok {
+		h.handleGuardrailCommand(bgCtx, replyToken, userID, cmd)
+		return
+	}
+
+	// Child accounts with spending cap and parent approvals (no AI)
+	if cmd, ok := parseChildCommand(message.Text); ok {
+		h.handleChildCommand(bgCtx, replyToken, userID, cmd)
+		return
+	}
+
+	// Daily spending cap: "จำกัดวันละ 500", "ยกเลิกจำกัดวันละ" (no AI)
+	if cmd, ok := services.ParseDailyLimitCommand(message.Text); ok {
+		h.handleDailyLimitCommand(bgCtx, replyToken, userID, cmd)
+		return
+	}
+
+	// Default payment method per category (no AI)
+	if cmd, ok := parsePaymentRuleCommand(message.Text); ok {
+		h.handlePaymentRuleCommand(bgCtx, replyToken, userID, cmd)
+		return
+	}
+
+	// Account nicknames: "ตั้งชื่อบัญชี บัญชีเงินเดือน = กสิกร 1234", "ชื่อบัญชี", "ลบชื่อบัญชี ..." (no AI)
+	if cmd, ok := parseAccountAliasCommand(message.Text); ok {
+		h.handleAccountAliasCommand(bgCtx, replyToken, userID, cmd)
+		return
+	}
+
+	// Transfers between known accounts/nicknames: "โอน 5000 จากบัญชีเงินเดือนไปบัญชีออม" (no AI)
+	if h.handleAccountTransfer(bgCtx, replyToken, userID, message.Text) {
+		return
+	}
+
+	// One account's balance from the balance quick replies: "ยอด กสิกร", "ยอด บัญชีเงินเดือน" (no AI)
+	if h.handleAccountBalanceQuery(bgCtx, replyToken, userID, message.Text) {
+		return
+	}
+
+	// Card FX markup vs mid-market rates: "บัตรไหนชาร์จ FX แพง" (no AI)
+	if services.IsFXFeeQuery(message.Text) {
+		h.replyFXFees(bgCtx, replyToken, userID)
+		return
+	}
+
+	// Travel mode: "ไปญี่ปุ่น 10 วัน", "เรท 0.23", "สรุปทริป", "จบทริป" (no AI)
+	if cmd, ok := services.ParseTravelCommand(message.Text); ok {
+		h.handleTravelCommand(bgCtx, replyToken, userID, cmd)
+		return
+	}
+
+	// Round-up savings: "ปัดเศษ 10", "เศษสะสม", "ยกเลิกปัดเศษ" (no AI)
+	if cmd, ok := parseRoundUpCommand(message.Text); ok {
+		h.handleRoundUpCommand(bgCtx, replyToken, userID, cmd)
+		return
+	}
+
+	// Read-only share links: "แชร์สรุปเดือนนี้", "ลิงก์แชร์", "ยกเลิกแชร์" (no AI)
+	if cmd, ok := services.ParseShareCommand(message.Text, time.Now()); ok {
+		h.handleShareCommand(bgCtx, replyToken, userID, cmd)
+		return
+	}
+
+	// Review queue of entries auto-captured from images/SMS (no AI)
+	if isReviewQueueCommand(message.Text) {
+		h.replyReviewQueue(bgCtx, replyToken, userID)
+		return
+	}
+
+	// Anonymized peer benchmark (opt-in): "เทียบค่าใช้จ่าย", "เข้าร่วมเทียบค่าใช้จ่าย" (no AI)
+	if action, ok := parsePeerBenchmarkCommand(message.Text); ok {
+		h.handlePeerBenchmarkCommand(bgCtx, replyToken, userID, action)
+		return
+	}
+
+	// PromptPay ID and QR for friends to pay back (no AI)
+	if action, arg, amount, ok := parsePromptPayCommand(message.Text); ok {
+		h.handlePromptPayCommand(bgCtx, replyToken, userID, action, arg, amount)
+		return
+	}
+
+	// Chat transcript export (no AI)
+	if from, to, format, ok := parseChatExportCommand(message.Text, time.Now()); ok {
+		h.replyChatTranscript(bgCtx, replyToken, userID, from, to, format)
+		return
+	}
+
+	// Month-end close: lock past periods (no AI)
+	if action, through, ok := parsePeriodCloseCommand(message.Text, time.Now()); ok {
+		h.handlePeriodCloseCommand(bgCtx, replyToken, userID, action, through)
+		return
+	}
+
+	// Historical closing balances from daily snapshots (no AI)
+	if isSnapshotBackfillCommand(message.Text) {
+		h.handleSnapshotBackfill(bgCtx, replyToken, userID)
+		return
 	}
-	if len(expenseCategories) > 0 {
-		if schema != "" {
-			schema += "|"
+	if account, date, ok := parseBalanceAsOfCommand(message.Text, time.Now()); ok {
+		if h.replyBalanceAsOf(bgCtx, replyToken, userID, account, date) {
+			return
 		}
-		schema += "หมวด:" + strings.Join(expenseCategories, ",")
 	}
 
-	// Add balance summary for AI context (important!)
-	balanceSummary := h.buildBalanceSummaryForAI(bgCtx, userID)
-	if balanceSummary != "" {
-		schema += "\n" + balanceSummary
+	// Pasted SMS history (several SMS): show records not yet entered (no AI)
+	if statement := services.ParseStatementSMS(message.Text, time.Now()); len(statement) > 1 {
+		h.replyStatementDiff(bgCtx, replyToken, userID, statement)
+		return
+	}
+
+	// Pasted bank/card SMS: parsed in Go, confirmed with one tap (no AI)
+	if h.handleBankSMS(bgCtx, replyToken, userID, message.Text) {
+		return
+	}
+
+	// "#เบิกได้" alone tags the latest entry (receipt photos can't carry the tag)
+	if h.handleReimbursableTag(bgCtx, replyToken, userID, message.Text) {
+		return
+	}
+
+	// Investment holdings (cost basis per fund/stock, no AI)
+	switch strings.TrimSpace(message.Text) {
+	case "พอร์ต", "พอร์ตลงทุน", "เงินลงทุน", "การลงทุน":
+		h.replyInvestmentHoldings(bgCtx, replyToken, userID)
+		return
+	}
+
+	// Warranty receipts: "เก็บใบเสร็จประกัน 2 ปี" for the latest entry, "ประกันสินค้า" lists them (no AI)
+	if months, ok := parseWarrantyCommand(message.Text); ok {
+		h.handleWarrantyCommand(bgCtx, replyToken, userID, months)
+		return
+	}
+	switch strings.TrimSpace(message.Text) {
+	case "ประกันสินค้า", "ใบรับประกัน", "ใบเสร็จประกัน":
+		h.replyWarrantyList(bgCtx, replyToken, userID)
+		return
+	}
+
+	// Company reimbursement claim export of #เบิกได้ expenses (no AI)
+	if from, to, ok := parseReimbursementExport(message.Text, time.Now()); ok {
+		h.handleReimbursementExport(bgCtx, replyToken, userID, from, to)
+		return
+	}
+
+	// Business mode commands (toggle, project P&L, project export) are handled in Go (no AI)
+	if action, arg := matchBusinessCommand(message.Text); action != "" {
+		h.handleBusinessCommand(bgCtx, replyToken, userID, action, arg)
+		return
+	}
+
+	// Recent transactions carousel with per-item edit/delete (no AI)
+	if isRecentTransactionsCommand(message.Text) {
+		h.replyRecentTransactions(bgCtx, replyToken, userID)
+		return
+	}
+
+	// Delete by description: "ลบค่ากาแฟเมื่อวาน" deletes the match or asks which one (no AI)
+	if h.handleDeleteRequest(bgCtx, replyToken, userID, message.Text) {
+		return
+	}
+
+	// Budget overview with progress bars (no AI)
+	if isBudgetOverviewCommand(message.Text) {
+		h.replyBudgetOverview(bgCtx, replyToken, userID)
+		return
+	}
+
+	// Spending by card/bank/cash over a date range: statement flex (no AI)
+	if h.handlePaymentQuery(bgCtx, replyToken, userID, message.Text) {
+		return
+	}
+
+	// Follow-ups of the last search/analyze Go can resolve: "แล้วเดือนก่อนล่ะ", "เอาเฉพาะบัตรเครดิต" (no AI)
+	if h.handleFollowUpQuery(bgCtx, replyToken, userID, message.Text) {
+		return
+	}
+
+	// Top-10 merchants over a period: "ร้านไหนเปลืองสุด", "ร้านไหนเปลืองสุดปีนี้" (no AI)
+	if h.handleTopMerchantQuery(bgCtx, replyToken, userID, message.Text) {
+		return
+	}
+
+	// Quick summaries are computed in Go (AI only writes the advice sentence)
+	if period := matchQuickSummary(message.Text); period != "" {
+		h.replyQuickSummary(bgCtx, replyToken, userID, message.Text, period)
+		return
+	}
+
+	// Description typed after a bare number completes that entry (goes to AI as "ข้าวมันไก่ 100")
+	if completed := h.completeAmountOnly(bgCtx, userID, message.Text); completed != "" {
+		message.Text = completed
+	}
+
+	// "ซื้อทีวี 25000 เก็บใบเสร็จประกัน 2 ปี": keep the period out of the AI text, store it after saving
+	var warrantyMonths int
+	message.Text, warrantyMonths = stripWarrantyPeriod(message.Text)
+
+	// Math is evaluated in Go ("ค่าอาหาร 120+80+65"): AI only sees the result
+	calc, _ := services.ParseArithmetic(message.Text)
+	if calc != nil {
+		message.Text = calc.Text
 	}
 
-	// Get chat history (last 20 messages)
-	chatHistory := ""
-	if history, err := h.mongo.GetChatHistory(bgCtx, userID, 20); err == nil && len(history) > 0 {
-		var historyLines []string
-		for _, msg := range history {
-			historyLines = append(historyLines, msg.Role+": "+msg.Content)
+	// Short word without amount: suggest frequent descriptions (no AI)
+	if h.replyAutocomplete(bgCtx, replyToken, userID, message.Text) {
+		return
+	}
+
+	// Independent context reads run concurrently under one timeout (errors only drop that part)
+	var (
+		lastTx                      *services.Transaction
+		profile                     *services.UserProfile
+		settings                    *services.UserSettings
+		balanceSummary, incomeText  string
+		comparisonText, chatHistory string
+		retrievalText, statsText    string
+	)
+	readCtx, cancelReads := context.WithTimeout(bgCtx, contextReadTimeout)
+	g, gctx := errgroup.WithContext(readCtx)
+	g.Go(func() error {
+		// Last transaction for update reference
+		lastTx, _, _ = h.mongo.GetLastTransaction(gctx, userID)
+		return nil
+	})
+	g.Go(func() error {
+		// Known banks/cards/categories from the user profile (single read)
+		profile, _ = h.mongo.GetUserProfile(gctx, userID)
+		if profile != nil {
+			h.refreshLineProfileAsync(profile, userID)
+		}
+		return nil
+	})
+	g.Go(func() error {
+		// Business mode: AI tags transactions with known customers/projects
+		settings, _ = h.mongo.GetUserSettings(gctx, userID)
+		return nil
+	})
+	g.Go(func() error {
+		// Balance summary for AI context (important!)
+		balanceSummary = h.buildBalanceSummaryForAI(gctx, userID)
+		return nil
+	})
+	// Income report only when user asks about income (save tokens)
+	if needsIncomeContext(message.Text) {
+		g.Go(func() error {
+			if report, err := h.mongo.GetIncomeReport(gctx, userID, "month"); err == nil {
+				incomeText = report.ToAIText()
+			}
+			return nil
+		})
+	}
+	// Period comparison only when user asks to compare (save tokens)
+	if needsComparisonContext(message.Text) {
+		g.Go(func() error {
+			comparisonText = h.mongo.GetComparisonContextText(gctx, userID)
+			return nil
+		})
+	}
+	// Counts/averages only when user asks for stats (save tokens)
+	if needsStatsContext(message.Text) {
+		g.Go(func() error {
+			if stats, err := h.mongo.GetTransactionStats(gctx, userID, "month"); err == nil {
+				statsText = stats.ToAIText()
+			}
+			return nil
+		})
+	}
+	// Open question ("ช่วงนี้ฟุ่มเฟือยไหม"): recent days plus similar past spending (flagged rollout)
+	if services.IsOpenQuestion(message.Text) && h.featureEnabled(bgCtx, services.FlagVectorSearch, userID) {
+		question := message.Text
+		g.Go(func() error {
+			recent := h.mongo.GetRecentTransactionsContext(gctx, userID, 7)
+			retrievalText = strings.TrimSpace(recent + "\n" + h.mongo.GetVectorSearchResultText(gctx, userID, question, 15))
+			return nil
+		})
+	}
+	g.Go(func() error {
+		// Chat history (last 20 messages)
+		if history, err := h.mongo.GetChatHistory(gctx, userID, 20); err == nil && len(history) > 0 {
+			var historyLines []string
+			for _, msg := range history {
+				historyLines = append(historyLines, msg.Role+": "+msg.Content)
+			}
+			chatHistory = strings.Join(historyLines, "\n")
+		}
+		return nil
+	})
+	_ = g.Wait()
+	cancelReads()
+
+	// Build compact schema for AI (most used names only; AI can ask for the rest with "list_names")
+	namesSchema := ""
+	var userBanks, userCards []string
+	var aliases []services.AccountAlias
+	if profile != nil {
+		namesSchema = profile.BuildAISchema()
+		// Nicknames count as naming the payment method too
+		userBanks = append(append([]string(nil), profile.Banks...), profile.AliasNames()...)
+		userCards, aliases = profile.CreditCards, profile.AccountAliases
+	}
+	// Follow-up of a search/analyze ("แล้วเดือนก่อนล่ะ"): AI sees the previous query and answers only the change
+	var lastQuery *services.LastQuery
+	lastQueryText := ""
+	if services.IsFollowUpQuery(message.Text) {
+		if q, ok := h.mongo.GetLastQuery(bgCtx, userID); ok {
+			lastQuery, lastQueryText = q, q.AIText()
+		}
+	}
+	contextSchema := ""
+	for _, part := range []string{buildBusinessSchema(settings), balanceSummary, incomeText, comparisonText, statsText, retrievalText, lastQueryText} {
+		if part != "" {
+			contextSchema += "\n" + part
 		}
-		chatHistory = strings.Join(historyLines, "\n")
 	}
+	schema := namesSchema + contextSchema
 
 	// Save user message to history
 	h.mongo.SaveChatMessage(bgCtx, userID, "user", message.Text)
@@ -213,9 +735,23 @@ func (h *LineWebhookHandler) handleTextMessage(ctx context.Context, source webho
 	log.Printf("Calling AI with message: %s", message.Text)
 
 	// Send schema and chat history to AI
-	response, err := h.ai.ChatWithContext(bgCtx, message.Text, schema, chatHistory)
+	aiCtx := bgCtx
+	if h.featureEnabled(bgCtx, services.FlagTwoStageAI, userID) {
+		aiCtx = services.WithTwoStageAI(bgCtx)
+	}
+	aiStart := time.Now()
+	response, err := h.ai.ChatWithContext(aiCtx, message.Text, schema, chatHistory)
+	// Follow-up call with every known name when the trimmed list didn't have the one the user meant
+	if err == nil && profile != nil && services.IsListNamesResponse(response) {
+		if full := profile.BuildFullAISchema(); full != namesSchema {
+			log.Printf("AI asked for full names list")
+			response, err = h.ai.ChatWithContext(aiCtx, message.Text, full+contextSchema, chatHistory)
+		}
+	}
 	if err != nil {
 		log.Printf("Failed to chat with AI: %v", err)
+		services.ReportError("ai.chat", userID, err)
+		h.trackEvent(userID, "ai_error", services.AnalyticsSourceAI, aiStart, false, false)
 		h.replyText(replyToken, "ขออภัยค่ะ เกิดข้อผิดพลาด กรุณาลองใหม่อีกครั้ง")
 		return
 	}
@@ -231,6 +767,7 @@ func (h *LineWebhookHandler) handleTextMessage(ctx context.Context, source webho
 	// Parse AI response
 	var aiResp services.AIResponse
 	if err := json.Unmarshal([]byte(response), &aiResp); err != nil {
+		h.trackEvent(userID, "ai_unparsed", services.AnalyticsSourceAI, aiStart, false, false)
 		if response != "" {
 			h.replyText(replyToken, response)
 		} else {
@@ -239,39 +776,207 @@ func (h *LineWebhookHandler) handleTextMessage(ctx context.Context, source webho
 		return
 	}
 
+	// "update" means the user corrected what was just recorded
+	h.trackEvent(userID, orDefault(aiResp.Action, "unknown"), services.AnalyticsSourceAI, aiStart, aiResp.Action != "", aiResp.Action == "update")
+
+	// Date range in the message wins over AI's date_from/date_to
+	if aiResp.Query != nil {
+		if r, ok := services.ParseThaiDate(message.Text, time.Now()); ok {
+			aiResp.Query.DateFrom = r.FromString()
+			aiResp.Query.DateTo = r.ToString()
+		}
+	}
+
 	// Go handles query and flex creation
 	flexSent := false
+	var undoReply *messaging_api.QuickReply // "↩️ ไม่ใช่อันนี้" after an edit or transfer the AI decided on
+
+	// Nicknames the AI copied instead of the real account ("บัญชีเงินเดือน" -> กสิกร 1234)
+	services.ResolveTransactionAliases(aliases, aiResp.Transactions)
+	services.ResolveTransferAliases(aliases, aiResp.Transfer)
 
 	// Process actions
 	switch aiResp.Action {
 	case "new":
-		for _, tx := range aiResp.Transactions {
-			if tx.Amount > 0 {
-				h.mongo.SaveTransaction(bgCtx, userID, &tx)
+		// Resolve entry date in Go ("เมื่อวาน", "จันทร์ที่แล้ว", "15 ต.ค. 67") instead of trusting AI
+		for i := range aiResp.Transactions {
+			date, mismatch := services.ResolveTransactionDate(message.Text, aiResp.Transactions[i].Date, time.Now())
+			if mismatch {
+				log.Printf("Date mismatch: ai=%s parsed=%s", aiResp.Transactions[i].Date, date)
+			}
+			aiResp.Transactions[i].Date = date
+		}
+
+		// Amounts typed as math are the Go result, components go in the description
+		services.ApplyArithmetic(calc, aiResp.Transactions)
+
+		// Fund/stock purchases move money into investments instead of counting as spending
+		services.ApplyInvestmentType(aiResp.Transactions, message.Text)
+
+		// "#เบิกได้" in the message tags every entry for the reimbursement export (AI may drop hashtags)
+		if services.HasReimbursableTag(message.Text) {
+			for i := range aiResp.Transactions {
+				aiResp.Transactions[i].Description = services.TagReimbursable(aiResp.Transactions[i].Description)
+			}
+		}
+
+		// Closed period: record as adjustment entry today instead of changing reconciled history
+		adjusted := false
+		for i := range aiResp.Transactions {
+			tx := &aiResp.Transactions[i]
+			if !settings.IsDateLocked(tx.Date) {
+				continue
 			}
+			tx.Description = strings.TrimSpace(fmt.Sprintf("%s (ปรับปรุงงวด %s)", tx.Description, tx.Date))
+			tx.Date = time.Now().Format("2006-01-02")
+			adjusted = true
 		}
-		// Send flex for new transaction
-		if len(aiResp.Transactions) > 0 {
-			flexSent = h.replyTransactionsFlex(bgCtx, userID, replyToken, aiResp.Transactions, aiResp.Message)
+		if adjusted {
+			aiResp.Message = strings.TrimSpace("🔒 งวดนั้นปิดแล้ว บันทึกเป็นรายการปรับปรุงวันนี้แทนค่ะ\n" + aiResp.Message)
 		}
 
+		// Infer payment method from history when user didn't say how they paid
+		paymentSource := ""
+		if !services.HasPaymentHint(message.Text, userBanks, userCards) {
+			paymentSource = "default"
+			// Category rules ("ค่าเดินทางตัดกสิกรเสมอ") win over guessing from history
+			if rules, err := h.mongo.GetPaymentRules(bgCtx, userID); err == nil && len(rules) > 0 {
+				if applied := services.ApplyPaymentRules(rules, aiResp.Transactions); len(applied) > 0 && applied[0] == 0 {
+					paymentSource = "rule"
+				}
+			}
+			for i := range aiResp.Transactions {
+				tx := &aiResp.Transactions[i]
+				if tx.UseType != 0 || tx.BankName != "" || tx.CreditCardName != "" {
+					continue
+				}
+				guess, err := h.mongo.InferPaymentMethod(bgCtx, userID, tx.Type, tx.Merchant, tx.Description, tx.Category)
+				if err != nil || guess == nil || guess.UseType == 0 {
+					continue
+				}
+				tx.UseType, tx.BankName, tx.CreditCardName = guess.UseType, guess.BankName, guess.CreditCardName
+				if i == 0 {
+					paymentSource = "inferred"
+				}
+			}
+		}
+
+		// Project tags only apply in business mode; new project names are remembered for P&L
+		for i := range aiResp.Transactions {
+			tx := &aiResp.Transactions[i]
+			if tx.Project == "" {
+				continue
+			}
+			if settings == nil || !settings.BusinessMode {
+				tx.Project = ""
+				continue
+			}
+			if err := h.mongo.AddProject(bgCtx, userID, tx.Project); err != nil {
+				log.Printf("Failed to add project: %v", err)
+			}
+		}
+
+		// Validate AI's amount against the raw message before saving (single transaction)
+		if len(aiResp.Transactions) == 1 {
+			if check := services.CheckAmount(message.Text, aiResp.Transactions[0].Amount); check.Mismatch {
+				log.Printf("Amount mismatch: ai=%.2f parsed=%v", aiResp.Transactions[0].Amount, check.Parsed)
+				if h.replyAmountConfirm(bgCtx, replyToken, userID, &aiResp.Transactions[0], check, services.MentionsBaht(message.Text)) {
+					flexSent = true
+					aiResp.Message = "รอยืนยันยอดเงิน"
+					break
+				}
+			}
+		}
+		// Travel mode: typed amounts are in the trip currency, converted to THB before limits and saving
+		if settings != nil && settings.Trip != nil {
+			if services.ApplyTripCurrency(settings.Trip, aiResp.Transactions, services.MentionsBaht(message.Text)) > 0 {
+				aiResp.Message = tripSavedText(aiResp.Transactions)
+			}
+		}
+		// Bank/card never used before: saved as cash, then asked before creating the account
+		batch := batchSave{PaymentSource: paymentSource, WarrantyMonths: warrantyMonths}
+		if paymentSource != "inferred" && paymentSource != "rule" {
+			batch.NewAccount = services.FindUnknownAccount(aiResp.Transactions, userBanks, userCards)
+		}
+		// Per-transaction category limits: hold the batch until user confirms
+		if h.replyGuardrailConfirm(bgCtx, replyToken, userID, aiResp.Transactions, batch) {
+			flexSent = true
+			aiResp.Message = "รอยืนยันรายการเกินลิมิต"
+			break
+		}
+		// Child account: expenses above the cap wait for the parent's approval
+		if h.holdForParentApproval(bgCtx, replyToken, userID, settings, aiResp.Transactions) {
+			flexSent = true
+			aiResp.Message = "รอผู้ปกครองอนุมัติ"
+			break
+		}
+		flexSent = h.saveTransactionBatch(bgCtx, replyToken, userID, aiResp.Transactions, batch, aiResp.Message)
+
 	case "balance":
 		// Go queries MongoDB and creates flex
 		balances, _ := h.mongo.GetBalanceByPaymentType(bgCtx, userID)
 		flexSent = h.replyBalanceFlex(bgCtx, userID, replyToken, balances, aiResp.Query, aiResp.Message)
 
 	case "search", "analyze":
-		// Go queries using AI's query filter
-		results := h.queryTransactions(bgCtx, userID, aiResp.Query)
-		flexSent = h.replyQueryResultsFlex(bgCtx, userID, replyToken, results, aiResp.Query, aiResp.Message)
+		// Go queries using AI's query filter; a follow-up only carries what changes, merged into the last query
+		q := services.LastQuery{Action: aiResp.Action}
+		if lastQuery != nil {
+			q = *lastQuery
+			q.Action = aiResp.Action
+			q.Query = services.MergeQueryFilter(lastQuery.Query, aiResp.Query)
+			if account, ok := services.FollowUpAccount(message.Text, userBanks, userCards, aliases); ok {
+				q.Account = account
+			}
+			if t := services.FollowUpType(message.Text); t != "" {
+				q.OnlyType = t
+			}
+		} else if aiResp.Query != nil {
+			q.Query = *aiResp.Query
+		}
+		results, total := h.runQuery(bgCtx, userID, q)
+		flexSent = h.replyQueryResultsFlex(bgCtx, userID, replyToken, results, total, &q.Query, aiResp.Message)
+		h.rememberLastQuery(bgCtx, userID, q)
+
+	case "compare":
+		// Go computes comparison and creates flex
+		period := "month"
+		if aiResp.Query != nil && aiResp.Query.Period != "" {
+			period = aiResp.Query.Period
+		}
+		if comparison, err := h.mongo.GetPeriodComparison(bgCtx, userID, period); err == nil {
+			styles := h.mongo.GetCategoryStyles(bgCtx, userID)
+			flexSent = h.replyComparisonFlex(replyToken, comparison, styles, aiResp.Message)
+		}
+
+	case "income":
+		// Go computes income sources and creates flex
+		period := "month"
+		if aiResp.Query != nil && aiResp.Query.Period != "" {
+			period = aiResp.Query.Period
+		}
+		if report, err := h.mongo.GetIncomeReport(bgCtx, userID, period); err == nil {
+			flexSent = h.replyIncomeReportFlex(replyToken, report, aiResp.Message)
+		}
 
 	case "update":
-		if lastTx != nil {
-			txID := lastTx.ID.Hex()
+		// Transaction chosen with ✏️ takes priority over today's last transaction
+		txID, date, hasTarget := h.pendingEditTarget(bgCtx, userID)
+		if !hasTarget && lastTx != nil {
+			txID, date = lastTx.ID.Hex(), time.Now().Format("2006-01-02")
+		}
+		if hasTarget {
+			h.mongo.DeleteTempData(bgCtx, editTargetKey(userID))
+		}
+		if txID != "" {
+			before, _ := h.mongo.GetTransactionOnDate(bgCtx, userID, txID, date)
 			switch aiResp.UpdateField {
 			case "amount":
 				if val, ok := aiResp.UpdateValue.(float64); ok {
-					h.mongo.UpdateTransactionAmount(bgCtx, userID, txID, val)
+					if err := h.mongo.UpdateTransactionAmountOnDate(bgCtx, userID, txID, date, val); err != nil {
+						if msg, ok := h.spendingCapText(err); ok {
+							aiResp.Message = msg
+						}
+					}
 				}
 			case "usetype":
 				bankName := ""
@@ -290,16 +995,17 @@ func (h *LineWebhookHandler) handleTextMessage(ctx context.Context, source webho
 						creditCard = cc
 					}
 				}
-				h.mongo.UpdateTransactionPayment(bgCtx, userID, txID, useType, bankName, creditCard)
+				h.mongo.UpdateTransactionPaymentOnDate(bgCtx, userID, txID, date, useType, bankName, creditCard)
 			case "bankname":
 				if val, ok := aiResp.UpdateValue.(string); ok {
-					h.mongo.UpdateTransactionPayment(bgCtx, userID, txID, 2, val, "")
+					h.mongo.UpdateTransactionPaymentOnDate(bgCtx, userID, txID, date, 2, val, "")
 				}
 			case "creditcardname":
 				if val, ok := aiResp.UpdateValue.(string); ok {
-					h.mongo.UpdateTransactionPayment(bgCtx, userID, txID, 1, "", val)
+					h.mongo.UpdateTransactionPaymentOnDate(bgCtx, userID, txID, date, 1, "", val)
 				}
 			}
+			undoReply = h.rememberUpdateUndo(bgCtx, userID, txID, date, before)
 		}
 
 	case "transfer":
@@ -325,7 +1031,12 @@ func (h *LineWebhookHandler) handleTextMessage(ctx context.Context, source webho
 					CreditCardName: e.CreditCardName,
 				}
 			}
-			h.mongo.SaveTransfer(bgCtx, userID, transfer)
+			if transferID, _, err := h.mongo.SaveTransfer(bgCtx, userID, transfer); err != nil {
+				log.Printf("Failed to save transfer: %v", err)
+				aiResp.Message = "ขออภัยค่ะ บันทึกการโอนไม่สำเร็จ กรุณาลองใหม่อีกครั้ง"
+			} else {
+				undoReply = h.rememberTransferUndo(bgCtx, userID, transferID, time.Now().Format("2006-01-02"))
+			}
 		}
 
 	case "budget":
@@ -333,6 +1044,10 @@ func (h *LineWebhookHandler) handleTextMessage(ctx context.Context, source webho
 			h.mongo.SetBudget(bgCtx, userID, aiResp.Budget.Category, aiResp.Budget.Amount)
 		}
 
+	case "chart":
+		// Only the bar chart Flex for now; image charts can hook in here later
+		flexSent = h.replyChartFlex(replyToken, userID, aiResp.Query)
+
 	case "export":
 		if aiResp.Export != nil {
 			format := aiResp.Export.Format
@@ -343,16 +1058,26 @@ func (h *LineWebhookHandler) handleTextMessage(ctx context.Context, source webho
 			if days <= 0 {
 				days = 30
 			}
+			password := exportPassword
+			if exportProtect && password == "" {
+				password = services.GenerateExportPassword()
+			}
+			// Language from the message ("export excel english"), else the user's profile
+			profileLang := ""
+			if profile != nil {
+				profileLang = profile.Language
+			}
+			lang := services.ParseExportLanguage(message.Text, profileLang)
 			if format == "pdf" {
-				data, filename, err := h.export.ExportToPDF(bgCtx, userID, days)
+				data, filename, err := h.export.ExportToPDF(bgCtx, userID, days, password, lang)
 				if err == nil {
-					h.replyAndSendFile(replyToken, userID, aiResp.Message, data, filename, "application/pdf")
+					h.replyAndSendFileWithPassword(replyToken, userID, aiResp.Message, data, filename, "application/pdf", password)
 					flexSent = true
 				}
 			} else {
-				data, filename, err := h.export.ExportToExcel(bgCtx, userID, days)
+				data, filename, err := h.export.ExportToExcel(bgCtx, userID, days, password, lang)
 				if err == nil {
-					h.replyAndSendFile(replyToken, userID, aiResp.Message, data, filename, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+					h.replyAndSendFileWithPassword(replyToken, userID, aiResp.Message, data, filename, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", password)
 					flexSent = true
 				}
 			}
@@ -366,7 +1091,7 @@ func (h *LineWebhookHandler) handleTextMessage(ctx context.Context, source webho
 			msg = response
 		}
 		if msg != "" {
-			h.replyText(replyToken, msg)
+			h.replyTextWithQuickReply(replyToken, msg, undoReply)
 		}
 	}
 
@@ -395,7 +1120,7 @@ func (h *LineWebhookHandler) getUserID(source webhook.SourceInterface) string {
 }
 
 func (h *LineWebhookHandler) replyText(replyToken, text string) {
-	_, err := h.bot.ReplyMessage(&messaging_api.ReplyMessageRequest{
+	_, err := h.sendReply(&messaging_api.ReplyMessageRequest{
 		ReplyToken: replyToken,
 		Messages: []messaging_api.MessageInterface{
 			messaging_api.TextMessage{
@@ -435,6 +1160,11 @@ func cleanFlexData(data interface{}) interface{} {
 
 // replyFlexFromAI sends Flex Message created by AI
 func (h *LineWebhookHandler) replyFlexFromAI(replyToken string, flex interface{}, altText string) bool {
+	return h.replyFlexWithQuickReply(replyToken, flex, altText, nil)
+}
+
+// replyFlexWithQuickReply sends Flex Message with optional quick reply buttons
+func (h *LineWebhookHandler) replyFlexWithQuickReply(replyToken string, flex interface{}, altText string, quickReply *messaging_api.QuickReply) bool {
 	if flex == nil {
 		return false
 	}
@@ -485,12 +1215,13 @@ func (h *LineWebhookHandler) replyFlexFromAI(replyToken string, flex interface{}
 		altText = "สติสตางค์"
 	}
 
-	_, err = h.bot.ReplyMessage(&messaging_api.ReplyMessageRequest{
+	_, err = h.sendReply(&messaging_api.ReplyMessageRequest{
 		ReplyToken: replyToken,
 		Messages: []messaging_api.MessageInterface{
 			messaging_api.FlexMessage{
-				AltText:  altText,
-				Contents: container,
+				AltText:    altText,
+				Contents:   container,
+				QuickReply: quickReply,
 			},
 		},
 	})
@@ -502,9 +1233,10 @@ func (h *LineWebhookHandler) replyFlexFromAI(replyToken string, flex interface{}
 }
 
 // queryTransactions queries MongoDB using AI's query filter
-func (h *LineWebhookHandler) queryTransactions(ctx context.Context, userID string, query *services.QueryFilter) []services.SearchResult {
+// total is the number of matches in the date range (more than len(results) when the limit cut it off)
+func (h *LineWebhookHandler) queryTransactions(ctx context.Context, userID string, query *services.QueryFilter) ([]services.SearchResult, int) {
 	if query == nil {
-		return nil
+		return nil, 0
 	}
 
 	days := query.Days
@@ -515,29 +1247,38 @@ func (h *LineWebhookHandler) queryTransactions(ctx context.Context, userID strin
 	// Use keyword search if provided (Regex Only)
 	if query.Keyword != "" {
 		results, _ := h.mongo.SearchTransactions(ctx, userID, query.Keyword, query.Limit)
-		return results
+		return results, len(results)
 	}
 
 	// Use category search if provided
 	if len(query.Categories) > 0 {
 		results, _ := h.mongo.SearchTransactions(ctx, userID, query.Categories[0], query.Limit)
-		return results
+		return results, len(results)
 	}
 
-	// Default: get recent transactions
+	// Default: get recent transactions (explicit date range first)
 	limit := query.Limit
 	if limit <= 0 {
 		limit = 20
 	}
-	results, _ := h.mongo.SearchByDateRange(ctx, userID,
-		time.Now().AddDate(0, 0, -days).Format("2006-01-02"),
-		time.Now().Format("2006-01-02"),
-		limit)
-	return results
+	dateFrom := time.Now().AddDate(0, 0, -days).Format("2006-01-02")
+	dateTo := time.Now().Format("2006-01-02")
+	if query.DateFrom != "" {
+		dateFrom = query.DateFrom
+		if query.DateTo != "" {
+			dateTo = query.DateTo
+		}
+	}
+	results, total, err := h.mongo.SearchByDateRangePage(ctx, userID, dateFrom, dateTo, 0, limit)
+	if err != nil {
+		log.Printf("Failed to search transactions: %v", err)
+	}
+	return results, total
 }
 
 // replyTransactionsFlex sends flex for new transactions (carousel: transaction + summary)
-func (h *LineWebhookHandler) replyTransactionsFlex(ctx context.Context, userID, replyToken string, txs []services.TransactionData, msg string) bool {
+// txID enables one-tap payment change; paymentSource is "inferred" (from history), "rule" (category rule), "default" (cash, not stated) or ""
+func (h *LineWebhookHandler) replyTransactionsFlex(ctx context.Context, userID, replyToken string, txs []services.TransactionData, msg string, txID, paymentSource string) bool {
 	if len(txs) == 0 {
 		return false
 	}
@@ -546,10 +1287,15 @@ func (h *LineWebhookHandler) replyTransactionsFlex(ctx context.Context, userID,
 	emoji := "💸"
 	headerColor := "#E74C3C" // Red for expense
 	typeText := "รายจ่าย"
-	if tx.Type == "income" {
+	switch tx.Type {
+	case "income":
 		emoji = "💰"
 		headerColor = "#27AE60" // Green for income
 		typeText = "รายรับ"
+	case "investment":
+		emoji = "📈"
+		headerColor = "#8E44AD" // Purple for investment
+		typeText = "ลงทุน"
 	}
 
 	// Fallback for empty values
@@ -572,6 +1318,12 @@ func (h *LineWebhookHandler) replyTransactionsFlex(ctx context.Context, userID,
 	if paymentText == "" {
 		paymentText = "เงินสด"
 	}
+	switch paymentSource {
+	case "inferred":
+		paymentText += " (เดาจากประวัติ)"
+	case "rule":
+		paymentText += " (ตามกฎหมวด)"
+	}
 
 	// Get balance summary
 	balances, _ := h.mongo.GetBalanceByPaymentType(ctx, userID)
@@ -587,20 +1339,21 @@ func (h *LineWebhookHandler) replyTransactionsFlex(ctx context.Context, userID,
 		}
 	}
 
-	// Assets = cash + bank, Liabilities = credit card debt
-	assets := cashTotal + bankTotal
-	liabilities := 0.0
-	if creditTotal < 0 {
-		liabilities = -creditTotal
-	}
-	equity := assets - liabilities
-
 	// Get income/expense totals
-	var totalIncome, totalExpense float64
+	var totalIncome, totalExpense, invested float64
 	if summary, err := h.mongo.GetBalanceSummary(ctx, userID); err == nil && summary != nil {
 		totalIncome = summary.TotalIncome
 		totalExpense = summary.TotalExpense
+		invested = summary.TotalInvested
+	}
+
+	// Assets = cash + bank + money invested (cost), Liabilities = credit card debt
+	assets := cashTotal + bankTotal + max(invested, 0)
+	liabilities := 0.0
+	if creditTotal < 0 {
+		liabilities = -creditTotal
 	}
+	equity := assets - liabilities
 
 	// Build body contents - AI message at top, summary at bottom
 	bodyContents := []interface{}{
@@ -614,6 +1367,14 @@ func (h *LineWebhookHandler) replyTransactionsFlex(ctx context.Context, userID,
 				map[string]interface{}{"type": "text", "text": "📎 " + tx.Category, "size": "xxs", "color": "#888888", "flex": 1},
 			},
 		},
+		map[string]interface{}{"type": "text", "text": paymentText, "size": "xxs", "color": "#888888"},
+	}
+
+	// Today's spending against the daily cap (transfers and investments left out)
+	if st := h.savedDailyLimitStatus(ctx, userID, []services.TransactionData{{Type: tx.Type, Date: txDate}}); st != nil {
+		bodyContents = append(bodyContents,
+			map[string]interface{}{"type": "text", "text": st.ProgressText(), "size": "xs", "weight": "bold", "color": dailyLimitColor(st), "wrap": true, "margin": "sm"},
+		)
 	}
 
 	// Add AI message after transaction detail (activity log at top)
@@ -665,6 +1426,43 @@ func (h *LineWebhookHandler) replyTransactionsFlex(ctx context.Context, userID,
 		},
 	)
 
+	// Proactive notices ride along with the confirmation (no push)
+	if noticeBox := buildNoticeBox(h.notifications.Pending(ctx, userID)); noticeBox != nil {
+		bodyContents = append(bodyContents, noticeBox)
+	}
+
+	// Same-day entry of the same amount on another account: likely a transfer recorded as two entries
+	var pair *services.Transaction
+	if txID != "" {
+		pair, _ = h.mongo.FindTransferPairOnDate(ctx, userID, txID, txDate)
+	}
+	if pair != nil {
+		bodyContents = append(bodyContents, transferPairNotice(pair))
+	}
+
+	footerButtons := []interface{}{}
+	if pair != nil {
+		footerButtons = append(footerButtons, mergeTransferButton(txID, pair.ID.Hex(), txDate))
+	}
+	if txID != "" {
+		footerButtons = append(footerButtons, map[string]interface{}{
+			"type": "button", "style": "secondary", "height": "sm",
+			"action": map[string]interface{}{
+				"type":        "postback",
+				"label":       "💳 เปลี่ยนวิธีจ่าย",
+				"data":        fmt.Sprintf("action=change_payment&txid=%s&date=%s", txID, txDate),
+				"displayText": "เปลี่ยนวิธีจ่าย",
+			},
+		})
+		if editButton := h.editAmountButton(txID, txDate, tx.Amount); editButton != nil {
+			footerButtons = append(footerButtons, editButton)
+		}
+	}
+	footerButtons = append(footerButtons, map[string]interface{}{
+		"type": "button", "style": "secondary", "height": "sm",
+		"action": map[string]interface{}{"type": "message", "label": "🗑️ ลบรายการนี้", "text": "ลบรายการล่าสุด"},
+	})
+
 	// Single bubble with transaction + summary
 	flex := map[string]interface{}{
 		"type": "bubble",
@@ -688,16 +1486,18 @@ func (h *LineWebhookHandler) replyTransactionsFlex(ctx context.Context, userID,
 			"type":       "box",
 			"layout":     "vertical",
 			"paddingAll": "sm",
-			"contents": []interface{}{
-				map[string]interface{}{
-					"type": "button", "style": "secondary", "height": "sm",
-					"action": map[string]interface{}{"type": "message", "label": "🗑️ ลบรายการนี้", "text": "ลบรายการล่าสุด"},
-				},
-			},
+			"spacing":    "sm",
+			"contents":   footerButtons,
 		},
 	}
 
-	return h.replyFlexFromAI(replyToken, flex, msg)
+	// Cash was only a default: offer user's banks/cards as one-tap quick replies
+	var quickReply *messaging_api.QuickReply
+	if paymentSource == "default" && txID != "" && tx.UseType == 0 && tx.BankName == "" && tx.CreditCardName == "" {
+		quickReply = h.buildPaymentQuickReply(ctx, userID, txID, txDate)
+	}
+
+	return h.replyFlexWithQuickReply(replyToken, flex, msg, quickReply)
 }
 
 // replyBalanceFlex sends flex for balance query
@@ -785,8 +1585,8 @@ func (h *LineWebhookHandler) replyBalanceFlex(ctx context.Context, userID, reply
 	return h.replyFlexFromAI(replyToken, flex, msg)
 }
 
-// replyQueryResultsFlex sends flex for search/analyze results
-func (h *LineWebhookHandler) replyQueryResultsFlex(ctx context.Context, userID, replyToken string, results []services.SearchResult, query *services.QueryFilter, msg string) bool {
+// replyQueryResultsFlex sends flex for search/analyze results; total is the match count before any limit
+func (h *LineWebhookHandler) replyQueryResultsFlex(ctx context.Context, userID, replyToken string, results []services.SearchResult, total int, query *services.QueryFilter, msg string) bool {
 	if len(results) == 0 {
 		return false
 	}
@@ -799,16 +1599,25 @@ func (h *LineWebhookHandler) replyQueryResultsFlex(ctx context.Context, userID,
 
 	contents := []interface{}{}
 	var totalIncome, totalExpense float64
+	styles := h.mongo.GetCategoryStyles(ctx, userID)
+	shown := len(results)
 
 	if groupBy == "category" {
-		// Group by category
+		// Group by category (date range of results is used for tap-to-drill-down)
 		categoryTotals := make(map[string]float64)
+		from, to := results[0].Date, results[0].Date
 		for _, r := range results {
 			categoryTotals[r.Transaction.Category] += r.Transaction.Amount * float64(r.Transaction.Type)
+			if r.Date < from {
+				from = r.Date
+			}
+			if r.Date > to {
+				to = r.Date
+			}
 		}
 
 		for cat, amount := range categoryTotals {
-			emoji := getCategoryEmoji(cat)
+			emoji := styles.Emoji(cat)
 			color := "#27AE60"
 			if amount < 0 {
 				color = "#E74C3C"
@@ -821,8 +1630,9 @@ func (h *LineWebhookHandler) replyQueryResultsFlex(ctx context.Context, userID,
 			contents = append(contents, map[string]interface{}{
 				"type":   "box",
 				"layout": "horizontal",
+				"action": categoryDetailAction(cat, from, to),
 				"contents": []interface{}{
-					map[string]interface{}{"type": "text", "text": emoji + " " + cat, "size": "sm", "flex": 2},
+					map[string]interface{}{"type": "text", "text": emoji + " " + cat + " ›", "size": "sm", "flex": 2},
 					map[string]interface{}{"type": "text", "text": formatNumber(amount), "size": "sm", "weight": "bold", "color": color, "align": "end", "flex": 2},
 				},
 			})
@@ -833,10 +1643,11 @@ func (h *LineWebhookHandler) replyQueryResultsFlex(ctx context.Context, userID,
 		if len(results) < limit {
 			limit = len(results)
 		}
+		shown = limit
 
 		for i := 0; i < limit; i++ {
 			r := results[i]
-			emoji := getCategoryEmoji(r.Transaction.Category)
+			emoji := styles.Emoji(r.Transaction.Category)
 			color := "#27AE60"
 			amount := r.Transaction.Amount
 			if r.Transaction.Type == -1 {
@@ -850,6 +1661,7 @@ func (h *LineWebhookHandler) replyQueryResultsFlex(ctx context.Context, userID,
 			if desc == "" {
 				desc = r.Transaction.Category
 			}
+			desc += archivedTag(r)
 
 			contents = append(contents, map[string]interface{}{
 				"type":   "box",
@@ -862,6 +1674,12 @@ func (h *LineWebhookHandler) replyQueryResultsFlex(ctx context.Context, userID,
 		}
 	}
 
+	if total > shown {
+		contents = append(contents, map[string]interface{}{
+			"type": "text", "text": fmt.Sprintf("...และอีก %d รายการ", total-shown), "size": "xs", "color": "#888888", "align": "center", "margin": "md",
+		})
+	}
+
 	// Add summary
 	contents = append(contents, map[string]interface{}{"type": "separator", "margin": "md"})
 	if totalIncome > 0 {
@@ -911,7 +1729,7 @@ func (h *LineWebhookHandler) replyQueryResultsFlex(ctx context.Context, userID,
 
 // buildBalanceSummaryContents returns flex contents for balance summary footer
 func (h *LineWebhookHandler) buildBalanceSummaryContents(ctx context.Context, userID string) []interface{} {
-	balances, _ := h.mongo.GetBalanceByPaymentType(ctx, userID)
+	balances, _ := h.mongo.ProjectedBalances(ctx, userID)
 	if len(balances) == 0 {
 		return nil
 	}
@@ -997,88 +1815,19 @@ func (h *LineWebhookHandler) buildBalanceSummaryContents(ctx context.Context, us
 	return contents
 }
 
-// buildBalanceSummaryForAI returns text summary of balances for AI context
+// buildBalanceSummaryForAI returns text summary of balances for AI context (cached, patched on every write)
 func (h *LineWebhookHandler) buildBalanceSummaryForAI(ctx context.Context, userID string) string {
-	// Get balance by payment type
-	balances, _ := h.mongo.GetBalanceByPaymentType(ctx, userID)
-
-	// Get income/expense summary
-	summary, _ := h.mongo.GetBalanceSummary(ctx, userID)
-
-	var parts []string
-
-	// Build balance details
-	var cashTotal, bankTotal, creditTotal, grandTotal float64
-	var bankDetails, cardDetails []string
-
-	for _, b := range balances {
-		switch b.UseType {
-		case 0:
-			cashTotal += b.Balance
-		case 1:
-			creditTotal += b.Balance
-			name := b.CreditCardName
-			if name == "" {
-				name = "บัตรเครดิต"
-			}
-			cardDetails = append(cardDetails, fmt.Sprintf("%s:%.0f", name, b.Balance))
-		case 2:
-			bankTotal += b.Balance
-			name := b.BankName
-			if name == "" {
-				name = "ธนาคาร"
-			}
-			bankDetails = append(bankDetails, fmt.Sprintf("%s:%.0f", name, b.Balance))
-		}
-		grandTotal += b.Balance
-	}
-
-	// Add summary line
-	parts = append(parts, fmt.Sprintf("ยอดรวม:%.0f", grandTotal))
-
-	if cashTotal != 0 {
-		parts = append(parts, fmt.Sprintf("เงินสด:%.0f", cashTotal))
-	}
-	if bankTotal != 0 {
-		parts = append(parts, fmt.Sprintf("ธนาคารรวม:%.0f", bankTotal))
-	}
-	if len(bankDetails) > 0 {
-		parts = append(parts, strings.Join(bankDetails, ","))
-	}
-	if creditTotal != 0 {
-		parts = append(parts, fmt.Sprintf("บัตรเครดิตรวม:%.0f", creditTotal))
-	}
-	if len(cardDetails) > 0 {
-		parts = append(parts, strings.Join(cardDetails, ","))
-	}
-
-	// Add income/expense from summary
-	if summary != nil {
-		parts = append(parts, fmt.Sprintf("รายได้รวม:%.0f", summary.TotalIncome))
-		parts = append(parts, fmt.Sprintf("รายจ่ายรวม:%.0f", summary.TotalExpense))
-		if summary.TodayIncome > 0 || summary.TodayExpense > 0 {
-			parts = append(parts, fmt.Sprintf("วันนี้รับ:%.0f,จ่าย:%.0f", summary.TodayIncome, summary.TodayExpense))
-		}
-	}
-
-	if len(parts) == 0 {
+	balance, err := h.mongo.GetBalanceContext(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to get balance context: %v", err)
 		return ""
 	}
-
-	return "สรุปยอด|" + strings.Join(parts, "|")
+	return balance.ToAIText()
 }
 
-// getCategoryEmoji returns emoji for category
+// getCategoryEmoji returns default emoji for category (use user's CategoryStyles when userID is known)
 func getCategoryEmoji(category string) string {
-	emojis := map[string]string{
-		"อาหาร": "🍔", "เดินทาง": "🚗", "ที่อยู่": "🏠", "ค่าน้ำ": "💧", "ค่าไฟ": "💡",
-		"ช้อปปิ้ง": "🛒", "บันเทิง": "🎬", "สุขภาพ": "💊", "การศึกษา": "📚", "ของใช้": "🧴",
-		"เงินเดือน": "💵", "โบนัส": "🎁", "โอนเงิน": "🔄",
-	}
-	if e, ok := emojis[category]; ok {
-		return e
-	}
-	return "💰"
+	return services.DefaultCategoryEmoji(category)
 }
 
 // replyDeleteConfirmFlex sends flex message for delete confirmation
@@ -1113,7 +1862,7 @@ func (h *LineWebhookHandler) replyDeleteConfirmFlex(replyToken string, balance f
 		return
 	}
 
-	_, err = h.bot.ReplyMessage(&messaging_api.ReplyMessageRequest{
+	_, err = h.sendReply(&messaging_api.ReplyMessageRequest{
 		ReplyToken: replyToken,
 		Messages: []messaging_api.MessageInterface{
 			messaging_api.FlexMessage{
@@ -1129,7 +1878,7 @@ func (h *LineWebhookHandler) replyDeleteConfirmFlex(replyToken string, balance f
 
 // replyTextWithSuggestions sends text with quick reply suggestions
 func (h *LineWebhookHandler) replyTextWithSuggestions(replyToken, text string) {
-	_, err := h.bot.ReplyMessage(&messaging_api.ReplyMessageRequest{
+	_, err := h.sendReply(&messaging_api.ReplyMessageRequest{
 		ReplyToken: replyToken,
 		Messages: []messaging_api.MessageInterface{
 			messaging_api.TextMessage{
@@ -1444,7 +2193,7 @@ func (h *LineWebhookHandler) replyTransferFlex(replyToken, userID string, transf
 		},
 	}
 
-	_, err := h.bot.ReplyMessage(&messaging_api.ReplyMessageRequest{
+	_, err := h.sendReply(&messaging_api.ReplyMessageRequest{
 		ReplyToken: replyToken,
 		Messages:   []messaging_api.MessageInterface{flexMessage},
 	})
@@ -1505,14 +2254,8 @@ func (h *LineWebhookHandler) replySlipConfirmFlex(replyToken, userID string, sli
 		toBankInfo = toBank + " (" + toAccount + ")"
 	}
 
-	// Smart suggestion based on sender
-	// If sender name matches user's display name, suggest expense; otherwise suggest income
-	suggestion := "💡 น่าจะเป็นรายรับ (เงินโอนเข้า)"
-	suggestionColor := "#27AE60"
-	// Check if user is the sender (simple heuristic - can be improved with user profile matching)
-	// For now, we'll show a neutral message
-	suggestion = "💡 เลือกว่าเป็นรายรับหรือรายจ่าย"
-	suggestionColor = "#666666"
+	// Smart suggestion: user's LINE name on the sender side means expense, receiver side means income
+	suggestion, suggestionColor := slipSuggestion(h.mongo.GetDisplayName(ctx, userID), slip)
 
 	// Build Flex message showing slip details
 	flex := map[string]interface{}{
@@ -1531,9 +2274,7 @@ func (h *LineWebhookHandler) replySlipConfirmFlex(replyToken, userID string, sli
 			"type":       "box",
 			"layout":     "vertical",
 			"paddingAll": "md",
-			"contents": []interface{}{
-				// Amount
-				map[string]interface{}{"type": "text", "text": formatNumber(slip.Amount) + " บาท", "size": "xl", "weight": "bold", "color": "#3498DB", "align": "center"},
+			"contents": append(slipAmountContents(slip),
 				map[string]interface{}{"type": "separator", "margin": "md"},
 				// From section
 				map[string]interface{}{"type": "text", "text": "ผู้โอน", "size": "xxs", "color": "#888888", "margin": "md"},
@@ -1578,7 +2319,7 @@ func (h *LineWebhookHandler) replySlipConfirmFlex(replyToken, userID string, sli
 				map[string]interface{}{"type": "text", "text": suggestion, "size": "xs", "color": suggestionColor, "align": "center", "margin": "md"},
 				// Status
 				map[string]interface{}{"type": "text", "text": "⏳ รอบันทึกบัญชี", "size": "sm", "color": "#E67E22", "align": "center", "weight": "bold", "margin": "sm"},
-			},
+			),
 		},
 		"footer": map[string]interface{}{
 			"type":       "box",
@@ -1611,7 +2352,7 @@ func (h *LineWebhookHandler) replySlipConfirmFlex(replyToken, userID string, sli
 		return
 	}
 
-	_, err = h.bot.ReplyMessage(&messaging_api.ReplyMessageRequest{
+	_, err = h.sendReply(&messaging_api.ReplyMessageRequest{
 		ReplyToken: replyToken,
 		Messages: []messaging_api.MessageInterface{
 			messaging_api.FlexMessage{
@@ -1642,7 +2383,7 @@ func (h *LineWebhookHandler) handleSlipCategoryText(ctx context.Context, replyTo
 	slipJSON, err := h.mongo.GetTempData(ctx, pending.SlipKey)
 	if err != nil {
 		log.Printf("Failed to get slip data: %v", err)
-		h.replyText(replyToken, "ข้อมูลสลิปหมดอายุ กรุณาส่งรูปใหม่")
+		h.replyText(replyToken, slipExpiredText)
 		return
 	}
 
@@ -1671,28 +2412,36 @@ func (h *LineWebhookHandler) handleSlipCategoryText(ctx context.Context, replyTo
 	h.mongo.DeleteTempData(ctx, pendingKey)
 	h.mongo.DeleteTempData(ctx, pending.SlipKey)
 
-	// Save transaction and reply with flex
-	h.replyTransactionFlex(replyToken, userID, &slip)
+	// Save transaction (and its fee as a separate expense) and reply with flex
+	h.replyTransactionFlexMultiple(replyToken, userID, services.SplitSlipFee(slip))
 }
 
-// replyTransactionFlex sends transaction flex message using reply (free, no quota)
-func (h *LineWebhookHandler) replyTransactionFlex(replyToken, userID string, tx *services.TransactionData) {
+// replyTransactionFlex saves and sends transaction flex using reply (free, no quota), returns saved txID
+func (h *LineWebhookHandler) replyTransactionFlex(replyToken, userID string, tx *services.TransactionData) string {
 	ctx := context.Background()
 
-	// Auto save to MongoDB
-	txID, err := h.mongo.SaveTransaction(ctx, userID, tx)
+	// Auto save to MongoDB (buttons below carry this date, not the day they're tapped)
+	date := time.Now().Format("2006-01-02")
+	txID, err := h.mongo.SaveTransactionOnDate(ctx, userID, tx, date)
 	if err != nil {
+		if msg, ok := h.spendingCapText(err); ok {
+			h.replyText(replyToken, msg)
+			return ""
+		}
 		log.Printf("Failed to save transaction: %v", err)
 		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถบันทึกข้อมูลได้")
-		return
+		return ""
 	}
 	log.Printf("Transaction saved with ID: %s", txID)
 
 	// Get balance summary
 	balance, _ := h.mongo.GetBalanceSummary(ctx, userID)
 
-	// Build transaction bubble
-	bubble := h.buildTransactionBubble(tx)
+	// Build transaction bubble (with budget progress when its category is budgeted, and today's total
+	// against the daily cap)
+	budgets := h.savedBudgetStatuses(ctx, userID, []services.TransactionData{*tx})
+	daily := h.savedDailyLimitStatus(ctx, userID, []services.TransactionData{{Type: tx.Type, Date: date}})
+	bubble := h.buildTransactionBubble(tx, budgets, daily)
 
 	// Build bubbles for carousel (transaction + balance)
 	bubbles := []messaging_api.FlexBubble{bubble}
@@ -1709,23 +2458,18 @@ func (h *LineWebhookHandler) replyTransactionFlex(replyToken, userID string, tx
 		},
 		QuickReply: &messaging_api.QuickReply{
 			Items: []messaging_api.QuickReplyItem{
-				{
-					Action: &messaging_api.PostbackAction{
-						Label: "✏️ แก้ไข",
-						Data:  fmt.Sprintf("action=edit_request&txid=%s", txID),
-					},
-				},
+				h.editQuickReplyItem("✏️ แก้ไข", txID, date, tx.Amount),
 				{
 					Action: &messaging_api.PostbackAction{
 						Label: "🗑️ ลบรายการนี้",
-						Data:  fmt.Sprintf("action=delete&txid=%s", txID),
+						Data:  deletePostbackData(txID, date, services.TransactionDataKind(tx)),
 					},
 				},
 			},
 		},
 	}
 
-	_, replyErr := h.bot.ReplyMessage(&messaging_api.ReplyMessageRequest{
+	_, replyErr := h.sendReply(&messaging_api.ReplyMessageRequest{
 		ReplyToken: replyToken,
 		Messages:   []messaging_api.MessageInterface{flexMessage},
 	})
@@ -1738,6 +2482,7 @@ func (h *LineWebhookHandler) replyTransactionFlex(replyToken, userID string, tx
 		}
 		log.Printf("Fallback: %s: %.2f บาท (บันทึกแล้ว)", typeText, tx.Amount)
 	}
+	return txID
 }
 
 // replyTransactionFlexMultiple sends multiple transactions using reply (free, no quota)
@@ -1757,26 +2502,42 @@ func (h *LineWebhookHandler) replyTransactionFlexMultipleWithAlert(replyToken, u
 		return
 	}
 
-	// Auto save all transactions
+	// Auto save all transactions to one day's record (🗑️ ลบทั้งหมด carries its date)
+	date := time.Now().Format("2006-01-02")
 	var txIDs []string
+	var shown []services.TransactionData
 	for i := range transactions {
 		tx := &transactions[i]
-		txID, err := h.mongo.SaveTransaction(context.Background(), userID, tx)
+		txID, err := h.mongo.SaveTransactionOnDate(context.Background(), userID, tx, date)
 		if err != nil {
+			// Held for the parent: not in the ledger, so it gets a note instead of a bubble
+			if msg, ok := h.spendingCapText(err); ok {
+				alertMsgs = append(alertMsgs, msg)
+				continue
+			}
 			log.Printf("Failed to save transaction: %v", err)
+			shown = append(shown, *tx)
 			continue
 		}
 		txIDs = append(txIDs, txID)
+		shown = append(shown, *tx)
 	}
+	if len(shown) == 0 {
+		h.replyText(replyToken, strings.Join(alertMsgs, "\n\n"))
+		return
+	}
+	transactions = shown
 
 	// Get balance summary
 	balance, _ := h.mongo.GetBalanceSummary(context.Background(), userID)
 
 	// Build bubbles for carousel
+	budgets := h.savedBudgetStatuses(context.Background(), userID, transactions)
+	daily := h.savedDailyLimitStatus(context.Background(), userID, transactions)
 	var bubbles []messaging_api.FlexBubble
 	for i := range transactions {
 		tx := &transactions[i]
-		bubble := h.buildTransactionBubble(tx)
+		bubble := h.buildTransactionBubble(tx, budgets, daily)
 		bubbles = append(bubbles, bubble)
 	}
 
@@ -1797,29 +2558,24 @@ func (h *LineWebhookHandler) replyTransactionFlexMultipleWithAlert(replyToken, u
 				{
 					Action: &messaging_api.PostbackAction{
 						Label: "🗑️ ลบทั้งหมด",
-						Data:  "action=delete_all&txids=" + strings.Join(txIDs, ","),
+						Data:  fmt.Sprintf("action=delete_all&date=%s&txids=%s", date, strings.Join(txIDs, ",")),
 					},
 				},
 			},
 		},
 	}
 
-	// Build messages array - flex message first, then alerts
-	messages := []messaging_api.MessageInterface{flexMessage}
+	// Flex message first, then alerts merged into one text (many alerts used to exceed 5 messages)
+	reply := h.newReplyComposer(replyToken, userID).Add(flexMessage)
 	for _, alertMsg := range alertMsgs {
-		messages = append(messages, messaging_api.TextMessage{Text: alertMsg})
-	}
-
-	_, err := h.bot.ReplyMessage(&messaging_api.ReplyMessageRequest{
-		ReplyToken: replyToken,
-		Messages:   messages,
-	})
-	if err != nil {
-		log.Printf("Failed to send flex carousel reply: %v", err)
+		reply.AddAlert(alertMsg)
 	}
+	reply.Send()
 }
 
-func (h *LineWebhookHandler) buildTransactionBubble(tx *services.TransactionData) messaging_api.FlexBubble {
+// buildTransactionBubble builds the saved-transaction bubble; budgets adds the category's progress line
+// and daily today's total against the daily cap
+func (h *LineWebhookHandler) buildTransactionBubble(tx *services.TransactionData, budgets []services.BudgetStatus, daily *services.DailyLimitStatus) messaging_api.FlexBubble {
 	typeText := "💸 รายจ่าย"
 	typeColor := "#E74C3C"
 	if tx.Type == "income" {
@@ -1827,6 +2583,60 @@ func (h *LineWebhookHandler) buildTransactionBubble(tx *services.TransactionData
 		typeColor = "#27AE60"
 	}
 
+	bodyContents := []messaging_api.FlexComponentInterface{
+		&messaging_api.FlexText{
+			Text:   tx.Description,
+			Size:   "md",
+			Color:  "#333333",
+			Weight: messaging_api.FlexTextWEIGHT_BOLD,
+		},
+		&messaging_api.FlexText{
+			Text:   fmt.Sprintf("%s", formatNumber(tx.Amount)),
+			Size:   "lg",
+			Color:  typeColor,
+			Weight: messaging_api.FlexTextWEIGHT_BOLD,
+			Margin: "sm",
+		},
+		&messaging_api.FlexText{
+			Text:   fmt.Sprintf("📅 %s | 🏷️ %s", tx.Date, tx.Category),
+			Size:   "xs",
+			Color:  "#888888",
+			Margin: "md",
+		},
+	}
+	if line := budgetProgressLine(tx, budgets); line != nil {
+		bodyContents = append(bodyContents, line)
+	}
+	if line := dailyLimitLine(tx, daily); line != nil {
+		bodyContents = append(bodyContents, line)
+	}
+
+	// Travel mode: amount paid in the trip currency
+	if tx.ForeignAmount > 0 {
+		bodyContents = append(bodyContents, &messaging_api.FlexText{
+			Text:  fmt.Sprintf("✈️ %s (เรท %s)", formatForeign(tx.ForeignAmount, tx.Currency), formatRate(tx.FXRate)),
+			Size:  "xs",
+			Color: "#0984E3",
+		})
+	}
+
+	// Tax invoice info (for VAT report)
+	if tx.VATAmount > 0 || tx.TaxID != "" {
+		vatText := fmt.Sprintf("🧾 VAT %s", formatNumber(tx.VATAmount))
+		if tx.ServiceCharge > 0 {
+			vatText += fmt.Sprintf(" | SC %s", formatNumber(tx.ServiceCharge))
+		}
+		if tx.ReceiptNo != "" {
+			vatText += " | เลขที่ " + tx.ReceiptNo
+		}
+		bodyContents = append(bodyContents, &messaging_api.FlexText{
+			Text:  vatText,
+			Size:  "xxs",
+			Color: "#888888",
+			Wrap:  true,
+		})
+	}
+
 	return messaging_api.FlexBubble{
 		Size: messaging_api.FlexBubbleSIZE_KILO,
 		Header: &messaging_api.FlexBox{
@@ -1851,27 +2661,7 @@ func (h *LineWebhookHandler) buildTransactionBubble(tx *services.TransactionData
 		Body: &messaging_api.FlexBox{
 			Layout:     messaging_api.FlexBoxLAYOUT_VERTICAL,
 			PaddingAll: "15px",
-			Contents: []messaging_api.FlexComponentInterface{
-				&messaging_api.FlexText{
-					Text:   tx.Description,
-					Size:   "md",
-					Color:  "#333333",
-					Weight: messaging_api.FlexTextWEIGHT_BOLD,
-				},
-				&messaging_api.FlexText{
-					Text:   fmt.Sprintf("%s", formatNumber(tx.Amount)),
-					Size:   "lg",
-					Color:  typeColor,
-					Weight: messaging_api.FlexTextWEIGHT_BOLD,
-					Margin: "sm",
-				},
-				&messaging_api.FlexText{
-					Text:   fmt.Sprintf("📅 %s | 🏷️ %s", tx.Date, tx.Category),
-					Size:   "xs",
-					Color:  "#888888",
-					Margin: "md",
-				},
-			},
+			Contents:   bodyContents,
 		},
 	}
 }
@@ -1957,7 +2747,7 @@ func (h *LineWebhookHandler) buildBalanceBubble(balance *services.BalanceSummary
 	}
 }
 
-func (h *LineWebhookHandler) replyUpdatedTransaction(replyToken, userID string, tx *services.Transaction, message string, txID string) {
+func (h *LineWebhookHandler) replyUpdatedTransaction(replyToken, userID string, tx *services.Transaction, message string, txID, date string) {
 	ctx := context.Background()
 
 	// Get balance by payment type for detailed view
@@ -2206,23 +2996,18 @@ func (h *LineWebhookHandler) replyUpdatedTransaction(replyToken, userID string,
 		},
 		QuickReply: &messaging_api.QuickReply{
 			Items: []messaging_api.QuickReplyItem{
-				{
-					Action: &messaging_api.PostbackAction{
-						Label: "✏️ แก้ไขอีก",
-						Data:  fmt.Sprintf("action=edit_request&txid=%s", txID),
-					},
-				},
+				h.editQuickReplyItem("✏️ แก้ไขอีก", txID, date, tx.Amount),
 				{
 					Action: &messaging_api.PostbackAction{
 						Label: "🗑️ ลบรายการนี้",
-						Data:  "action=delete&txid=" + txID,
+						Data:  deletePostbackData(txID, date, services.TransactionKind(tx.Type)),
 					},
 				},
 			},
 		},
 	}
 
-	_, err := h.bot.ReplyMessage(&messaging_api.ReplyMessageRequest{
+	_, err := h.sendReply(&messaging_api.ReplyMessageRequest{
 		ReplyToken: replyToken,
 		Messages:   []messaging_api.MessageInterface{flexMessage},
 	})
@@ -2252,6 +3037,7 @@ func (h *LineWebhookHandler) handlePostback(ctx context.Context, event webhook.P
 	}
 
 	action := params["action"]
+	h.trackEvent(userID, action, services.AnalyticsSourcePostback, time.Now(), true, action == "edit_request" || action == "change_payment")
 
 	switch action {
 	case "delete":
@@ -2261,8 +3047,17 @@ func (h *LineWebhookHandler) handlePostback(ctx context.Context, event webhook.P
 			return
 		}
 
-		err := h.mongo.DeleteTransaction(ctx, userID, txID)
+		// Buttons carry the saved date and kind, older buttons mean today (both arrays searched)
+		date := params["date"]
+		if date == "" {
+			date = time.Now().Format("2006-01-02")
+		}
+		err := h.mongo.DeleteTransactionOfKind(ctx, userID, txID, date, params["kind"])
 		if err != nil {
+			if msg, locked := periodLockedText(err); locked {
+				h.replyText(replyToken, msg)
+				return
+			}
 			log.Printf("Failed to delete transaction: %v", err)
 			h.replyText(replyToken, "ไม่สามารถลบรายการได้")
 			return
@@ -2285,24 +3080,47 @@ func (h *LineWebhookHandler) handlePostback(ctx context.Context, event webhook.P
 			return
 		}
 
+		date := params["date"]
+		if date == "" {
+			date = time.Now().Format("2006-01-02")
+		}
+
+		h.backupBeforeDelete(ctx, userID)
 		ids := strings.Split(txIDs, ",")
 		deletedCount := 0
 		for _, txID := range ids {
 			if txID == "" {
 				continue
 			}
-			err := h.mongo.DeleteTransaction(ctx, userID, txID)
+			err := h.mongo.DeleteTransactionOnDate(ctx, userID, txID, date)
 			if err != nil {
+				// All items share one date, so a closed period refuses every one of them
+				if msg, locked := periodLockedText(err); locked {
+					h.replyText(replyToken, msg)
+					return
+				}
 				log.Printf("Failed to delete transaction %s: %v", txID, err)
 				continue
 			}
 			deletedCount++
 		}
+		if deletedCount == 0 {
+			h.replyText(replyToken, "ไม่สามารถลบรายการได้")
+			return
+		}
+
+		h.mongo.LogSecurityEvent(ctx, services.SecurityEvent{LineID: userID, Event: services.SecurityDataDeleted, Detail: fmt.Sprintf("deleted %d transactions", deletedCount)})
 
 		// Get updated balance
 		balanceText := h.getBalanceText(ctx, userID)
 		h.replyText(replyToken, fmt.Sprintf("🗑️ ลบ %d รายการเรียบร้อยแล้ว\n\n%s", deletedCount, balanceText))
 
+	case "delete_pick":
+		h.deleteWithUndo(ctx, replyToken, userID, params["txid"], params["date"], params["kind"])
+
+	case "undo":
+		h.handleUndo(ctx, replyToken, userID, params)
+
 	case "delete_transfer":
 		transferID := params["transfer_id"]
 		if transferID == "" {
@@ -2312,6 +3130,10 @@ func (h *LineWebhookHandler) handlePostback(ctx context.Context, event webhook.P
 
 		err := h.mongo.DeleteTransfer(ctx, userID, transferID)
 		if err != nil {
+			if msg, locked := periodLockedText(err); locked {
+				h.replyText(replyToken, msg)
+				return
+			}
 			log.Printf("Failed to delete transfer: %v", err)
 			h.replyText(replyToken, "ไม่สามารถยกเลิกการโอนได้")
 			return
@@ -2321,17 +3143,84 @@ func (h *LineWebhookHandler) handlePostback(ctx context.Context, event webhook.P
 		balanceText := h.getBalanceText(ctx, userID)
 		h.replyText(replyToken, fmt.Sprintf("🗑️ ยกเลิกการโอนเรียบร้อยแล้ว\n\n%s", balanceText))
 
+	case "amount_confirm":
+		h.handleAmountConfirm(ctx, replyToken, userID, params)
+
+	case "export_password":
+		h.handleExportPassword(ctx, replyToken, userID, params)
+
+	case "guardrail_confirm":
+		h.handleGuardrailConfirm(ctx, replyToken, userID, params)
+
+	case "statement_add":
+		h.handleStatementAdd(ctx, replyToken, userID, params)
+
+	case "receipt_stitch":
+		h.handleReceiptStitch(ctx, replyToken, userID, params)
+
+	case "budget_edit", "budget_delete":
+		h.handleBudgetPostback(ctx, replyToken, userID, action, params)
+
+	case "cap_approve":
+		h.handleSpendingApproval(ctx, replyToken, userID, params)
+
+	case "roundup_transfer":
+		h.handleRoundUpTransfer(ctx, replyToken, userID)
+
+	case "card_paid":
+		h.handleCardPaid(ctx, replyToken, userID, params["card"])
+
+	case "card_snooze":
+		h.handleCardSnooze(ctx, replyToken, userID, params["card"])
+
+	case "review_ok":
+		h.handleReviewConfirm(ctx, replyToken, userID, params)
+
+	case "review_all":
+		delete(params, "txid")
+		h.handleReviewConfirm(ctx, replyToken, userID, params)
+
+	case "share_revoke":
+		h.handleShareRevoke(ctx, replyToken, userID, params)
+
+	case "backup_restore":
+		h.handleBackupRestore(ctx, replyToken, userID, params)
+
+	case "category_detail":
+		h.handleCategoryDetail(ctx, replyToken, userID, params)
+
+	case "change_payment":
+		h.handleChangePayment(ctx, replyToken, userID, params)
+
+	case "merge_transfer":
+		h.handleMergeTransfer(ctx, replyToken, userID, params)
+
+	case "set_payment":
+		h.handleSetPayment(ctx, replyToken, userID, params)
+
+	case "cancel_sub":
+		sub, err := h.mongo.CancelSubscription(ctx, userID, params["id"])
+		if err != nil {
+			log.Printf("Failed to cancel subscription: %v", err)
+			h.replyText(replyToken, "ไม่สามารถยกเลิก subscription ได้")
+			return
+		}
+		h.replyText(replyToken, fmt.Sprintf("ยกเลิก %s แล้วค่ะ จะไม่นับเป็นรายจ่ายประจำและไม่แจ้งเตือนอีก", sub.Name))
+
 	case "edit_request":
-		// Handle edit request - guide user how to edit
-		// We don't need txID here as the user will type the edit command naturally
-		// But keeping it in data is good for future context if we implement stateful conversation
+		// Remember which transaction to edit so the next message targets it (not just today's last one)
+		if params["txid"] != "" && params["date"] != "" {
+			h.mongo.SaveTempData(ctx, editTargetKey(userID), params["txid"]+"|"+params["date"], 10*time.Minute)
+			h.replyText(replyToken, "✏️ พิมพ์ยอดใหม่ได้เลยค่ะ เช่น \"250\"\nหรือบอกสิ่งที่จะแก้ เช่น \"เปลี่ยนเป็นบัตรเครดิต\"")
+			return
+		}
 		h.replyText(replyToken, "✏️ หากต้องการแก้ไข ให้พิมพ์บอกได้เลยค่ะ\nเช่น \"แก้เป็นค่าอาหาร 500 บาท\" หรือ \"เปลี่ยนเป็นบัตรเครดิต\"")
 
 	case "slip_income", "slip_expense":
 		// Handle slip type selection - ask for category
 		key := params["key"]
 		if key == "" {
-			h.replyText(replyToken, "ข้อมูลสลิปหมดอายุ กรุณาส่งรูปใหม่")
+			h.replyText(replyToken, slipExpiredText)
 			return
 		}
 
@@ -2339,7 +3228,7 @@ func (h *LineWebhookHandler) handlePostback(ctx context.Context, event webhook.P
 		_, err := h.mongo.GetTempData(ctx, key)
 		if err != nil {
 			log.Printf("Failed to get slip data: %v", err)
-			h.replyText(replyToken, "ข้อมูลสลิปหมดอายุ กรุณาส่งรูปใหม่")
+			h.replyText(replyToken, slipExpiredText)
 			return
 		}
 
@@ -2380,7 +3269,7 @@ func (h *LineWebhookHandler) handlePostback(ctx context.Context, event webhook.P
 			}
 		}
 
-		_, err = h.bot.ReplyMessage(&messaging_api.ReplyMessageRequest{
+		_, err = h.sendReply(&messaging_api.ReplyMessageRequest{
 			ReplyToken: replyToken,
 			Messages: []messaging_api.MessageInterface{
 				messaging_api.TextMessage{
@@ -2402,7 +3291,7 @@ func (h *LineWebhookHandler) handlePostback(ctx context.Context, event webhook.P
 		category := params["category"]
 
 		if key == "" {
-			h.replyText(replyToken, "ข้อมูลสลิปหมดอายุ กรุณาส่งรูปใหม่")
+			h.replyText(replyToken, slipExpiredText)
 			return
 		}
 
@@ -2410,7 +3299,7 @@ func (h *LineWebhookHandler) handlePostback(ctx context.Context, event webhook.P
 		slipJSON, err := h.mongo.GetTempData(ctx, key)
 		if err != nil {
 			log.Printf("Failed to get slip data: %v", err)
-			h.replyText(replyToken, "ข้อมูลสลิปหมดอายุ กรุณาส่งรูปใหม่")
+			h.replyText(replyToken, slipExpiredText)
 			return
 		}
 
@@ -2439,8 +3328,8 @@ func (h *LineWebhookHandler) handlePostback(ctx context.Context, event webhook.P
 		pendingKey := fmt.Sprintf("slip_pending_%s", userID)
 		h.mongo.DeleteTempData(ctx, pendingKey)
 
-		// Save transaction and reply with flex
-		h.replyTransactionFlex(replyToken, userID, &slip)
+		// Save transaction (and its fee as a separate expense) and reply with flex
+		h.replyTransactionFlexMultiple(replyToken, userID, services.SplitSlipFee(slip))
 
 	default:
 		log.Printf("Unknown postback action: %s", action)
@@ -2452,7 +3341,7 @@ func (h *LineWebhookHandler) replyBalanceByPaymentType(replyToken, userID string
 	ctx := context.Background()
 
 	// Get balance by payment type
-	balances, err := h.mongo.GetBalanceByPaymentType(ctx, userID)
+	balances, err := h.mongo.ProjectedBalances(ctx, userID)
 	if err != nil || len(balances) == 0 {
 		h.replyText(replyToken, "ยังไม่มีรายการค่ะ")
 		return
@@ -2770,7 +3659,7 @@ func (h *LineWebhookHandler) replyBalanceByPaymentType(replyToken, userID string
 		}
 	}
 
-	_, err = h.bot.ReplyMessage(&messaging_api.ReplyMessageRequest{
+	_, err = h.sendReply(&messaging_api.ReplyMessageRequest{
 		ReplyToken: replyToken,
 		Messages:   []messaging_api.MessageInterface{flexMessage},
 	})
@@ -2781,7 +3670,7 @@ func (h *LineWebhookHandler) replyBalanceByPaymentType(replyToken, userID string
 
 // getBalanceText returns balance summary text for combining with other messages
 func (h *LineWebhookHandler) getBalanceText(ctx context.Context, userID string) string {
-	balances, err := h.mongo.GetBalanceByPaymentType(ctx, userID)
+	balances, err := h.mongo.ProjectedBalances(ctx, userID)
 	if err != nil || len(balances) == 0 {
 		return ""
 	}
@@ -3037,7 +3926,7 @@ func (h *LineWebhookHandler) replyAnalysisFlex(replyToken, userID string, analys
 		},
 	}
 
-	_, err := h.bot.ReplyMessage(&messaging_api.ReplyMessageRequest{
+	_, err := h.sendReply(&messaging_api.ReplyMessageRequest{
 		ReplyToken: replyToken,
 		Messages:   []messaging_api.MessageInterface{flexMessage},
 	})
@@ -3174,7 +4063,7 @@ func (h *LineWebhookHandler) replyBudgetFlex(replyToken, userID string, category
 		},
 	}
 
-	_, err := h.bot.ReplyMessage(&messaging_api.ReplyMessageRequest{
+	_, err := h.sendReply(&messaging_api.ReplyMessageRequest{
 		ReplyToken: replyToken,
 		Messages:   []messaging_api.MessageInterface{flexMessage},
 	})
@@ -3185,10 +4074,17 @@ func (h *LineWebhookHandler) replyBudgetFlex(replyToken, userID string, category
 
 // replyAndSendFile replies with text and then sends file download link
 func (h *LineWebhookHandler) replyAndSendFile(replyToken, userID, message string, data []byte, filename string, mimeType string) {
+	h.replyAndSendFileWithPassword(replyToken, userID, message, data, filename, mimeType, "")
+}
+
+// replyAndSendFileWithPassword uploads file and replies download link, password goes in a separate message
+func (h *LineWebhookHandler) replyAndSendFileWithPassword(replyToken, userID, message string, data []byte, filename string, mimeType string, password string) {
 	fileSize := len(data) / 1024 // KB
 	var fileType string
 	if strings.Contains(mimeType, "pdf") {
 		fileType = "PDF"
+	} else if strings.HasPrefix(mimeType, "text/") {
+		fileType = "ข้อความ"
 	} else {
 		fileType = "Excel"
 	}
@@ -3204,22 +4100,60 @@ func (h *LineWebhookHandler) replyAndSendFile(replyToken, userID, message string
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	downloadURL, err := h.firebase.UploadFile(ctx, data, filename, mimeType)
+	downloadURL, oneTime, err := h.uploadForDownload(ctx, userID, data, filename, mimeType)
 	if err != nil {
 		log.Printf("Failed to upload file to Firebase: %v", err)
 		h.replyText(replyToken, "❌ ไม่สามารถอัปโหลดไฟล์ได้\n\nกรุณาลองใหม่อีกครั้งค่ะ")
 		return
 	}
 
+	detail := "exported " + filename
+	if password != "" {
+		detail += " (password protected)"
+	}
+	h.mongo.LogSecurityEvent(ctx, services.SecurityEvent{LineID: userID, Event: services.SecurityDataExport, Detail: detail})
+
 	// Reply with Flex Message containing download button
-	h.replyFileDownloadFlex(replyToken, userID, message, fileType, filename, fileSize, downloadURL)
+	h.replyFileDownloadFlex(replyToken, userID, message, fileType, filename, fileSize, downloadURL, oneTime, password)
+}
+
+// uploadForDownload uploads file and returns download URL and whether it's a one-time link
+// Uses short one-time link (/d/:token) when public base URL is configured, otherwise public Firebase URL
+func (h *LineWebhookHandler) uploadForDownload(ctx context.Context, userID string, data []byte, filename, mimeType string) (string, bool, error) {
+	if h.publicBaseURL == "" {
+		url, err := h.firebase.UploadFile(ctx, data, filename, mimeType)
+		return url, false, err
+	}
+
+	objectPath, err := h.firebase.UploadPrivateFile(ctx, data, filename, mimeType)
+	if err != nil {
+		return "", false, err
+	}
+	link, err := h.mongo.CreateDownloadLink(ctx, userID, objectPath, filename, mimeType)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to create download link: %w", err)
+	}
+	return h.publicBaseURL + "/d/" + link.Token, true, nil
+}
+
+// downloadLinkNote warns how long a download link works: one-time links expire on first download,
+// public storage URLs keep working for anyone who has them
+func downloadLinkNote(oneTime bool) string {
+	if oneTime {
+		return fmt.Sprintf("⚠️ ลิงก์จะหมดอายุหลังดาวน์โหลดครั้งแรก หรือใน %d วัน", int(services.DownloadLinkTTL.Hours()/24))
+	}
+	return "⚠️ ใครมีลิงก์นี้ก็ดาวน์โหลดได้ อย่าส่งต่อให้คนอื่น"
 }
 
 // replyFileDownloadFlex replies with a Flex Message with download button (uses ReplyMessage)
-func (h *LineWebhookHandler) replyFileDownloadFlex(replyToken, userID, message, fileType, filename string, fileSize int, downloadURL string) {
+// Non-empty password is sent as a separate text message so the link alone can't open the file
+func (h *LineWebhookHandler) replyFileDownloadFlex(replyToken, userID, message, fileType, filename string, fileSize int, downloadURL string, oneTime bool, password string) {
 	emoji := "📊"
-	if fileType == "PDF" {
+	switch fileType {
+	case "PDF":
 		emoji = "📄"
+	case "ข้อความ":
+		emoji = "📝"
 	}
 
 	flexMessage := &messaging_api.FlexMessage{
@@ -3287,7 +4221,7 @@ func (h *LineWebhookHandler) replyFileDownloadFlex(replyToken, userID, message,
 					},
 					&messaging_api.FlexSeparator{Margin: "lg"},
 					&messaging_api.FlexText{
-						Text:  "⚠️ ลิงก์จะหมดอายุหลังดาวน์โหลดครั้งแรก หรือใน 14 วัน",
+						Text:  downloadLinkNote(oneTime),
 						Color: "#FF6B6B",
 						Size:  "xs",
 						Wrap:  true,
@@ -3312,24 +4246,60 @@ func (h *LineWebhookHandler) replyFileDownloadFlex(replyToken, userID, message,
 		},
 	}
 
-	_, err := h.bot.ReplyMessage(&messaging_api.ReplyMessageRequest{
-		ReplyToken: replyToken,
-		Messages:   []messaging_api.MessageInterface{flexMessage},
-	})
-	if err != nil {
-		log.Printf("Failed to send file download flex: %v", err)
+	// The password is never in the link's message: the user taps for it and it comes as its own reply
+	if password != "" {
+		key := fmt.Sprintf("export_password_%s_%d", userID, time.Now().UnixNano())
+		if err := h.mongo.SaveTempData(context.Background(), key, password, exportPasswordTTL); err != nil {
+			log.Printf("Failed to save export password: %v", err)
+			h.replyText(replyToken, "❌ ไม่สามารถส่งไฟล์ได้ กรุณาลองใหม่อีกครั้งค่ะ")
+			return
+		}
+		footer := flexMessage.Contents.(*messaging_api.FlexBubble).Footer
+		footer.Spacing = "sm"
+		footer.Contents = append(footer.Contents, &messaging_api.FlexButton{
+			Style:  messaging_api.FlexButtonSTYLE_SECONDARY,
+			Height: "sm",
+			Action: &messaging_api.PostbackAction{
+				Label:       "🔑 ขอรหัสเปิดไฟล์",
+				Data:        "action=export_password&key=" + key,
+				DisplayText: "ขอรหัสเปิดไฟล์",
+			},
+		})
 	}
+
+	h.newReplyComposer(replyToken, userID).Add(flexMessage).Send()
 }
 
-// replyChartFlex displays spending chart as Flex Message with visual bars
-func (h *LineWebhookHandler) replyChartFlex(replyToken, userID string) {
+// exportPasswordTTL is how long the "ขอรหัสเปิดไฟล์" button works
+const exportPasswordTTL = 30 * time.Minute
+
+// handleExportPassword replies the file password once, as a message of its own
+func (h *LineWebhookHandler) handleExportPassword(ctx context.Context, replyToken, userID string, params map[string]string) {
+	key := params["key"]
+	if !strings.HasPrefix(key, "export_password_"+userID+"_") {
+		h.replyText(replyToken, "รายการไม่ถูกต้องค่ะ")
+		return
+	}
+	password, err := h.mongo.GetTempData(ctx, key)
+	if err != nil || password == "" {
+		h.replyText(replyToken, "รหัสนี้ถูกขอไปแล้วหรือหมดอายุค่ะ กรุณา export ใหม่อีกครั้ง")
+		return
+	}
+	h.mongo.DeleteTempData(ctx, key)
+	h.replyText(replyToken, fmt.Sprintf("🔒 รหัสเปิดไฟล์: %s\nอย่าส่งต่อรหัสนี้พร้อมลิงก์ไฟล์นะคะ", password))
+}
+
+// replyChartFlex displays spending chart of the query's period as Flex Message with visual bars
+// Returns false when there is nothing to chart so the caller can fall back to text
+func (h *LineWebhookHandler) replyChartFlex(replyToken, userID string, query *services.QueryFilter) bool {
 	bgCtx := context.Background()
 
 	// Get spending data
-	chartData, total, err := h.export.GetCategorySpendingForChart(bgCtx, userID)
+	from, to, label := services.ChartRange(query, time.Now())
+	chartData, total, err := h.export.GetCategorySpendingForChart(bgCtx, userID, from.Format("2006-01-02"), to.Format("2006-01-02"))
 	if err != nil || len(chartData) == 0 {
-		h.replyText(replyToken, "ไม่มีข้อมูลรายจ่ายเดือนนี้ค่ะ")
-		return
+		h.replyText(replyToken, fmt.Sprintf("ไม่มีข้อมูลรายจ่าย %s ค่ะ", label))
+		return true
 	}
 
 	// Build chart items
@@ -3369,7 +4339,7 @@ func (h *LineWebhookHandler) replyChartFlex(replyToken, userID string) {
 				Margin: "md",
 				Contents: []messaging_api.FlexComponentInterface{
 					&messaging_api.FlexText{
-						Text:  item.Category,
+						Text:  item.Emoji + " " + item.Category,
 						Size:  "sm",
 						Color: "#555555",
 						Flex:  4,
@@ -3442,7 +4412,7 @@ func (h *LineWebhookHandler) replyChartFlex(replyToken, userID string) {
 				PaddingAll:      "20px",
 				Contents: []messaging_api.FlexComponentInterface{
 					&messaging_api.FlexText{
-						Text:  "📊 กราฟรายจ่ายเดือนนี้",
+						Text:  "📊 กราฟรายจ่าย " + label,
 						Size:  "sm",
 						Color: "#FFFFFF",
 					},
@@ -3470,13 +4440,15 @@ func (h *LineWebhookHandler) replyChartFlex(replyToken, userID string) {
 		},
 	}
 
-	_, err = h.bot.ReplyMessage(&messaging_api.ReplyMessageRequest{
+	_, err = h.sendReply(&messaging_api.ReplyMessageRequest{
 		ReplyToken: replyToken,
 		Messages:   []messaging_api.MessageInterface{flexMessage},
 	})
 	if err != nil {
 		log.Printf("Failed to send chart flex: %v", err)
+		return false
 	}
+	return true
 }
 
 // replySearchResults displays search results with Flex Message carousel
@@ -3612,7 +4584,7 @@ func (h *LineWebhookHandler) replySearchResults(replyToken, userID string, resul
 				},
 			},
 			&messaging_api.FlexText{
-				Text:   fmt.Sprintf("   📅 %s %s", r.Date, paymentIcon),
+				Text:   fmt.Sprintf("   📅 %s %s%s", r.Date, paymentIcon, archivedTag(r)),
 				Size:   "xs",
 				Color:  "#AAAAAA",
 				Margin: "xs",
@@ -3682,7 +4654,7 @@ func (h *LineWebhookHandler) replySearchResults(replyToken, userID string, resul
 		},
 	}
 
-	_, err := h.bot.ReplyMessage(&messaging_api.ReplyMessageRequest{
+	_, err := h.sendReply(&messaging_api.ReplyMessageRequest{
 		ReplyToken: replyToken,
 		Messages:   []messaging_api.MessageInterface{flexMessage},
 	})
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"log"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/webhook"
+	"github.com/satisatang/backend/services"
+)
+
+// unsupportedMediaText guides users who send receipts as something other than a photo or text
+const unsupportedMediaText = "ส่งเป็นรูปภาพนะคะ 📷 หรือพิมพ์รายการ เช่น \"กาแฟ 60\" ก็ได้ค่ะ"
+
+// handleVideoMessage tries to read a receipt from the video's preview frame (LINE-hosted videos only),
+// falling back to guidance when there is no frame to read
+func (h *LineWebhookHandler) handleVideoMessage(ctx context.Context, source webhook.SourceInterface, message webhook.VideoMessageContent, replyToken string) {
+	userID := h.getUserID(source)
+	if userID == "" {
+		log.Println("Failed to get user ID")
+		return
+	}
+	// Externally hosted videos have no LINE preview to download
+	if message.ContentProvider != nil && message.ContentProvider.Type != webhook.ContentProviderTYPE_LINE {
+		h.replyText(replyToken, "ยังอ่านใบเสร็จจากวิดีโอไม่ได้ค่ะ "+unsupportedMediaText)
+		return
+	}
+
+	content, err := h.blobAPI.GetMessageContentPreview(message.Id)
+	if err != nil {
+		log.Printf("Failed to get video preview: %v", err)
+		h.replyText(replyToken, "ยังอ่านใบเสร็จจากวิดีโอไม่ได้ค่ะ "+unsupportedMediaText)
+		return
+	}
+	defer content.Body.Close()
+
+	frame, err := io.ReadAll(content.Body)
+	if err != nil || len(frame) == 0 {
+		log.Printf("Failed to read video preview: %v", err)
+		h.replyText(replyToken, "ยังอ่านใบเสร็จจากวิดีโอไม่ได้ค่ะ "+unsupportedMediaText)
+		return
+	}
+	contentType := content.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "image/jpeg"
+	}
+	frame, contentType = services.CompressImage(frame, contentType, h.imageOpts)
+	log.Printf("Reading receipt from video preview frame (%d bytes)", len(frame))
+
+	h.processReceiptImage(ctx, replyToken, userID, frame, contentType)
+}
+
+// handleAudioMessage replies with guidance: voice notes are not transcribed (saves AI tokens)
+func (h *LineWebhookHandler) handleAudioMessage(replyToken string) {
+	h.replyText(replyToken, "ยังฟังข้อความเสียงไม่ได้ค่ะ "+unsupportedMediaText)
+}
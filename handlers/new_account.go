@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+	"github.com/satisatang/backend/services"
+)
+
+// pendingNewAccount is a bank/card the user hasn't used yet and the transactions saved as cash meanwhile
+type pendingNewAccount struct {
+	Account services.AccountRef `json:"account"`
+	Txs     []pendingTxRef      `json:"txs"`
+}
+
+// pendingTxRef identifies a saved transaction by ID and record date
+type pendingTxRef struct {
+	ID   string `json:"id"`
+	Date string `json:"date"`
+}
+
+// newAccountKey is the temp data key for the pending "add account?" question
+func newAccountKey(userID string) string {
+	return "new_account_" + userID
+}
+
+// holdUnknownAccount switches transactions paid with account to cash until the user confirms the account
+func holdUnknownAccount(txs []services.TransactionData, account services.AccountRef) []int {
+	var held []int
+	for i := range txs {
+		if account.Matches(txs[i]) {
+			txs[i].UseType, txs[i].BankName, txs[i].CreditCardName = 0, "", ""
+			held = append(held, i)
+		}
+	}
+	return held
+}
+
+// askNewAccount remembers saved transactions and asks whether to create the account
+func (h *LineWebhookHandler) askNewAccount(ctx context.Context, replyToken, userID string, account services.AccountRef, tx services.TransactionData, refs []pendingTxRef) bool {
+	data, _ := json.Marshal(pendingNewAccount{Account: account, Txs: refs})
+	if err := h.mongo.SaveTempData(ctx, newAccountKey(userID), string(data), 10*time.Minute); err != nil {
+		log.Printf("Failed to save pending account: %v", err)
+		return false
+	}
+
+	kind := "บัญชี"
+	if account.UseType == 1 {
+		kind = "บัตร"
+	}
+	text := fmt.Sprintf("บันทึก %s %s บาท เป็นเงินสดไว้ก่อนค่ะ\n🆕 ยังไม่มี%s \"%s\" ต้องการเพิ่ม%sใหม่และย้ายรายการนี้ไปไหมคะ?",
+		orDefault(tx.Description, tx.Category), formatNumber(tx.Amount), kind, account.Name(), kind)
+	_, err := h.sendReply(&messaging_api.ReplyMessageRequest{
+		ReplyToken: replyToken,
+		Messages: []messaging_api.MessageInterface{
+			messaging_api.TextMessage{
+				Text: text,
+				QuickReply: &messaging_api.QuickReply{Items: []messaging_api.QuickReplyItem{
+					{Action: &messaging_api.MessageAction{Label: "✅ ใช่", Text: "ใช่"}},
+					{Action: &messaging_api.MessageAction{Label: "❌ ไม่ใช่", Text: "ไม่ใช่"}},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to ask new account: %v", err)
+		return false
+	}
+	return true
+}
+
+// handleNewAccountAnswer applies "ใช่"/"ไม่ใช่" to a pending new account question (no AI)
+func (h *LineWebhookHandler) handleNewAccountAnswer(ctx context.Context, replyToken, userID, text string) bool {
+	yes, ok := services.ParseYesNo(text)
+	if !ok {
+		return false
+	}
+	data, err := h.mongo.GetTempData(ctx, newAccountKey(userID))
+	if err != nil || data == "" {
+		return false
+	}
+	h.mongo.DeleteTempData(ctx, newAccountKey(userID))
+
+	var pending pendingNewAccount
+	if err := json.Unmarshal([]byte(data), &pending); err != nil {
+		log.Printf("Failed to parse pending account: %v", err)
+		return false
+	}
+	if !yes {
+		h.replyText(replyToken, "ได้ค่ะ เก็บเป็นเงินสดตามเดิม")
+		return true
+	}
+
+	// Accounts are derived from transactions, so moving them creates the account
+	a := pending.Account
+	moved := 0
+	for _, ref := range pending.Txs {
+		if err := h.mongo.UpdateTransactionPaymentOnDate(ctx, userID, ref.ID, ref.Date, a.UseType, a.BankName, a.CreditCardName); err != nil {
+			log.Printf("Failed to move transaction %s: %v", ref.ID, err)
+			continue
+		}
+		moved++
+	}
+	if moved == 0 {
+		h.replyText(replyToken, "ไม่พบรายการที่จะย้ายค่ะ")
+		return true
+	}
+	h.replyText(replyToken, fmt.Sprintf("✅ เพิ่ม %s และย้าย %d รายการแล้วค่ะ\n\n%s",
+		getPaymentName(a.UseType, a.BankName, a.CreditCardName), moved, h.getBalanceText(ctx, userID)))
+	return true
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/satisatang/backend/services"
+)
+
+// quietHoursPattern matches "ช่วงเงียบ 22:00-07:00"
+var quietHoursPattern = regexp.MustCompile(`^ช่วงเงียบ\s*(\d{1,2})[:.](\d{2})\s*-\s*(\d{1,2})[:.](\d{2})$`)
+
+// dailyCapPattern matches "แจ้งเตือนวันละ 3"
+var dailyCapPattern = regexp.MustCompile(`^แจ้งเตือนวันละ\s*(\d{1,2})(?:\s*ครั้ง)?$`)
+
+// notificationCommand is a parsed notification settings command
+type notificationCommand struct {
+	action     string // "list", "enable", "disable", "quiet", "quiet_off", "cap"
+	notifyType string
+	quietStart string
+	quietEnd   string
+	cap        int
+}
+
+// parseNotificationCommand parses notification settings commands, ok=false if not one
+func parseNotificationCommand(text string) (cmd notificationCommand, ok bool) {
+	text = strings.TrimSpace(text)
+	switch strings.ReplaceAll(text, " ", "") {
+	case "แจ้งเตือน", "ตั้งค่าแจ้งเตือน":
+		return notificationCommand{action: "list"}, true
+	case "ปิดช่วงเงียบ":
+		return notificationCommand{action: "quiet_off"}, true
+	}
+
+	if m := quietHoursPattern.FindStringSubmatch(text); m != nil {
+		startHour, _ := strconv.Atoi(m[1])
+		endHour, _ := strconv.Atoi(m[3])
+		if startHour > 23 || endHour > 23 {
+			return notificationCommand{}, false
+		}
+		return notificationCommand{
+			action:     "quiet",
+			quietStart: fmt.Sprintf("%02d:%s", startHour, m[2]),
+			quietEnd:   fmt.Sprintf("%02d:%s", endHour, m[4]),
+		}, true
+	}
+	if m := dailyCapPattern.FindStringSubmatch(text); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return notificationCommand{action: "cap", cap: n}, true
+	}
+
+	for _, prefix := range []string{"ปิดแจ้งเตือน", "เปิดแจ้งเตือน"} {
+		if !strings.HasPrefix(text, prefix) {
+			continue
+		}
+		name := strings.TrimSpace(strings.TrimPrefix(text, prefix))
+		for notifyType, thai := range services.NotificationTypeNames {
+			if name == thai || name == notifyType {
+				action := "enable"
+				if prefix == "ปิดแจ้งเตือน" {
+					action = "disable"
+				}
+				return notificationCommand{action: action, notifyType: notifyType}, true
+			}
+		}
+	}
+	return notificationCommand{}, false
+}
+
+// handleNotificationCommand updates user's notification preferences
+func (h *LineWebhookHandler) handleNotificationCommand(ctx context.Context, replyToken, userID string, cmd notificationCommand) {
+	settings, err := h.mongo.GetUserSettings(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to get user settings: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ เกิดข้อผิดพลาด กรุณาลองใหม่")
+		return
+	}
+	prefs := settings.Notifications
+
+	switch cmd.action {
+	case "enable", "disable":
+		var disabled []string
+		for _, t := range prefs.Disabled {
+			if t != cmd.notifyType {
+				disabled = append(disabled, t)
+			}
+		}
+		if cmd.action == "disable" {
+			disabled = append(disabled, cmd.notifyType)
+		}
+		prefs.Disabled = disabled
+	case "quiet":
+		prefs.QuietStart, prefs.QuietEnd, prefs.QuietOff = cmd.quietStart, cmd.quietEnd, false
+	case "quiet_off":
+		prefs.QuietOff = true
+	case "cap":
+		if cmd.cap < 1 {
+			h.replyText(replyToken, "จำนวนต้องมากกว่า 0 ค่ะ (ถ้าไม่อยากได้รับ ให้ปิดแจ้งเตือนแต่ละประเภท)")
+			return
+		}
+		prefs.DailyCap = cmd.cap
+	}
+
+	if cmd.action != "list" {
+		if err := h.mongo.SetNotificationPrefs(ctx, userID, prefs); err != nil {
+			log.Printf("Failed to save notification prefs: %v", err)
+			h.replyText(replyToken, "บันทึกการตั้งค่าไม่สำเร็จ กรุณาลองใหม่")
+			return
+		}
+	}
+	h.replyText(replyToken, formatNotificationPrefs(prefs))
+}
+
+// formatNotificationPrefs shows current notification settings with commands
+func formatNotificationPrefs(prefs services.NotificationPrefs) string {
+	lines := []string{"🔔 การแจ้งเตือน (แสดงพร้อมข้อความตอบกลับ)"}
+	for _, notifyType := range services.NotificationTypeOrder {
+		status := "✅"
+		if !prefs.IsEnabled(notifyType) {
+			status = "⛔"
+		}
+		lines = append(lines, fmt.Sprintf("%s %s", status, services.NotificationTypeNames[notifyType]))
+	}
+	if start, end := prefs.QuietHours(); start != "" {
+		lines = append(lines, fmt.Sprintf("🌙 ช่วงเงียบ %s-%s", start, end))
+	} else {
+		lines = append(lines, "🌙 ไม่มีช่วงเงียบ")
+	}
+	lines = append(lines, fmt.Sprintf("📮 สูงสุดวันละ %d ครั้ง", prefs.Cap()))
+	lines = append(lines, "", "คำสั่ง: ปิดแจ้งเตือน งบ | เปิดแจ้งเตือน งบ | ช่วงเงียบ 22:00-07:00 | ปิดช่วงเงียบ | แจ้งเตือนวันละ 3")
+	return strings.Join(lines, "\n")
+}
+
+// buildNoticeBox creates flex box listing notices (nil if none)
+func buildNoticeBox(notices []services.Notice) map[string]interface{} {
+	if len(notices) == 0 {
+		return nil
+	}
+	var contents []interface{}
+	for _, notice := range notices {
+		contents = append(contents, map[string]interface{}{
+			"type": "text", "text": notice.Text, "size": "xxs", "color": "#666666", "wrap": true,
+		})
+		if len(notice.Actions) == 0 {
+			continue
+		}
+		var buttons []interface{}
+		for _, a := range notice.Actions {
+			buttons = append(buttons, map[string]interface{}{
+				"type": "button", "style": "link", "height": "sm",
+				"action": map[string]interface{}{"type": "postback", "label": truncateLabel(a.Label, 20), "data": a.Data, "displayText": a.Label},
+			})
+		}
+		contents = append(contents, map[string]interface{}{"type": "box", "layout": "horizontal", "contents": buttons})
+	}
+	return map[string]interface{}{
+		"type": "box", "layout": "vertical", "margin": "md", "spacing": "xs",
+		"backgroundColor": "#FEF5E7", "cornerRadius": "8px", "paddingAll": "8px",
+		"contents": contents,
+	}
+}
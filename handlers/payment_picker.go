@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+)
+
+// buildPaymentQuickReply lists user's cash/banks/cards as one-tap postbacks to change a transaction's payment
+// LINE allows at most 13 quick reply items
+func (h *LineWebhookHandler) buildPaymentQuickReply(ctx context.Context, userID, txID, date string) *messaging_api.QuickReply {
+	banks, cards, _ := h.mongo.GetDistinctPaymentMethods(ctx, userID)
+
+	data := func(useType int, bank, card string) string {
+		return fmt.Sprintf("action=set_payment&txid=%s&date=%s&usetype=%d&bank=%s&card=%s", txID, date, useType, bank, card)
+	}
+
+	items := []messaging_api.QuickReplyItem{{
+		Action: &messaging_api.PostbackAction{Label: "💵 เงินสด", Data: data(0, "", ""), DisplayText: "จ่ายด้วยเงินสด"},
+	}}
+	for _, bank := range banks {
+		if len(items) >= 13 {
+			break
+		}
+		items = append(items, messaging_api.QuickReplyItem{
+			Action: &messaging_api.PostbackAction{Label: truncateLabel("🏦 "+bank, 20), Data: data(2, bank, ""), DisplayText: "จ่ายด้วย ธ." + bank},
+		})
+	}
+	for _, card := range cards {
+		if len(items) >= 13 {
+			break
+		}
+		items = append(items, messaging_api.QuickReplyItem{
+			Action: &messaging_api.PostbackAction{Label: truncateLabel("💳 "+card, 20), Data: data(1, "", card), DisplayText: "จ่ายด้วยบัตร" + card},
+		})
+	}
+	if len(banks) == 0 && len(items) < 13 {
+		items = append(items, messaging_api.QuickReplyItem{
+			Action: &messaging_api.PostbackAction{Label: "🏦 ธนาคาร", Data: data(2, "", ""), DisplayText: "จ่ายด้วยธนาคาร"},
+		})
+	}
+	if len(cards) == 0 && len(items) < 13 {
+		items = append(items, messaging_api.QuickReplyItem{
+			Action: &messaging_api.PostbackAction{Label: "💳 บัตรเครดิต", Data: data(1, "", ""), DisplayText: "จ่ายด้วยบัตรเครดิต"},
+		})
+	}
+
+	return &messaging_api.QuickReply{Items: items}
+}
+
+// handleChangePayment replies with payment picker for a transaction
+func (h *LineWebhookHandler) handleChangePayment(ctx context.Context, replyToken, userID string, params map[string]string) {
+	if params["txid"] == "" {
+		h.replyText(replyToken, "ไม่พบรหัสรายการ")
+		return
+	}
+
+	_, err := h.sendReply(&messaging_api.ReplyMessageRequest{
+		ReplyToken: replyToken,
+		Messages: []messaging_api.MessageInterface{
+			messaging_api.TextMessage{
+				Text:       "จ่ายด้วยอะไรคะ?",
+				QuickReply: h.buildPaymentQuickReply(ctx, userID, params["txid"], params["date"]),
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to send payment picker: %v", err)
+	}
+}
+
+// handleSetPayment updates transaction's payment method from picker postback
+func (h *LineWebhookHandler) handleSetPayment(ctx context.Context, replyToken, userID string, params map[string]string) {
+	useType, err := strconv.Atoi(params["usetype"])
+	if err != nil || params["txid"] == "" {
+		h.replyText(replyToken, "ข้อมูลไม่ถูกต้อง")
+		return
+	}
+
+	date := params["date"]
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
+	}
+	if err := h.mongo.UpdateTransactionPaymentOnDate(ctx, userID, params["txid"], date, useType, params["bank"], params["card"]); err != nil {
+		log.Printf("Failed to update payment: %v", err)
+		if text, ok := periodLockedText(err); ok {
+			h.replyText(replyToken, text)
+			return
+		}
+		h.replyText(replyToken, "ไม่สามารถเปลี่ยนวิธีจ่ายได้")
+		return
+	}
+
+	h.replyText(replyToken, fmt.Sprintf("✅ เปลี่ยนเป็น %s แล้วค่ะ", getPaymentName(useType, params["bank"], params["card"])))
+}
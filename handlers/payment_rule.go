@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/satisatang/backend/services"
+)
+
+// paymentRuleSetPattern matches "ค่าเดินทางตัดกสิกรเสมอ" / "หมวดช้อปปิ้ง รูดบัตร KTC ทุกครั้ง"
+var paymentRuleSetPattern = regexp.MustCompile(`^(?:หมวด)?\s*(.+?)\s*(?:ตัด|จ่ายด้วย|รูด)\s*(.+?)\s*(?:เสมอ|ทุกครั้ง)$`)
+
+// paymentRuleDeletePrefixes remove a category's rule
+var paymentRuleDeletePrefixes = []string{"ยกเลิกกฎการจ่าย", "ลบกฎการจ่าย", "ยกเลิกกฎ"}
+
+// paymentRuleCommand is a parsed default-payment command
+type paymentRuleCommand struct {
+	Action   string // "set", "delete", "list"
+	Category string
+	Target   string // raw payment text, resolved with the user's names
+}
+
+// parsePaymentRuleCommand parses default payment per category commands (no AI)
+func parsePaymentRuleCommand(text string) (paymentRuleCommand, bool) {
+	text = strings.TrimSpace(text)
+	switch text {
+	case "กฎการจ่าย", "ดูกฎการจ่าย":
+		return paymentRuleCommand{Action: "list"}, true
+	}
+
+	if m := paymentRuleSetPattern.FindStringSubmatch(text); m != nil {
+		return paymentRuleCommand{Action: "set", Category: m[1], Target: m[2]}, true
+	}
+
+	for _, prefix := range paymentRuleDeletePrefixes {
+		if !strings.HasPrefix(text, prefix) {
+			continue
+		}
+		category := strings.TrimSpace(strings.TrimPrefix(text, prefix))
+		category = strings.TrimSpace(strings.TrimPrefix(category, "หมวด"))
+		if category == "" {
+			return paymentRuleCommand{}, false
+		}
+		return paymentRuleCommand{Action: "delete", Category: category}, true
+	}
+	return paymentRuleCommand{}, false
+}
+
+// handlePaymentRuleCommand creates, lists or removes default payment rules
+func (h *LineWebhookHandler) handlePaymentRuleCommand(ctx context.Context, replyToken, userID string, cmd paymentRuleCommand) {
+	switch cmd.Action {
+	case "set":
+		var banks, cards []string
+		var aliases []services.AccountAlias
+		if profile, err := h.mongo.GetUserProfile(ctx, userID); err == nil {
+			banks, cards, aliases = profile.Banks, profile.CreditCards, profile.AccountAliases
+		}
+		account, ok := services.ParsePaymentTarget(cmd.Target, banks, cards, aliases)
+		if !ok {
+			h.replyText(replyToken, "ไม่เข้าใจช่องทางจ่ายค่ะ ตัวอย่าง: ค่าเดินทางตัดกสิกรเสมอ")
+			return
+		}
+		if err := h.mongo.SetPaymentRule(ctx, userID, cmd.Category, account); err != nil {
+			log.Printf("Failed to set payment rule: %v", err)
+			h.replyText(replyToken, "ไม่สามารถตั้งกฎได้ กรุณาลองใหม่")
+			return
+		}
+		h.replyText(replyToken, fmt.Sprintf("ตั้งแล้วค่ะ รายจ่าย%s จะบันทึกเป็น %s อัตโนมัติ (ถ้าไม่ได้บอกช่องทางจ่าย)\nยกเลิก: พิมพ์ \"ยกเลิกกฎ %s\"",
+			cmd.Category, getPaymentName(account.UseType, account.BankName, account.CreditCardName), cmd.Category))
+
+	case "delete":
+		deleted, err := h.mongo.DeletePaymentRule(ctx, userID, cmd.Category)
+		if err != nil {
+			log.Printf("Failed to delete payment rule: %v", err)
+			h.replyText(replyToken, "ไม่สามารถยกเลิกกฎได้ กรุณาลองใหม่")
+			return
+		}
+		if !deleted {
+			h.replyText(replyToken, fmt.Sprintf("ไม่พบกฎการจ่ายของ %s ค่ะ", cmd.Category))
+			return
+		}
+		h.replyText(replyToken, fmt.Sprintf("ยกเลิกกฎการจ่ายของ %s แล้วค่ะ", cmd.Category))
+
+	default:
+		rules, err := h.mongo.GetPaymentRules(ctx, userID)
+		if err != nil {
+			log.Printf("Failed to get payment rules: %v", err)
+			h.replyText(replyToken, "ไม่สามารถดึงข้อมูลได้ กรุณาลองใหม่")
+			return
+		}
+		if len(rules) == 0 {
+			h.replyText(replyToken, "ยังไม่มีกฎการจ่ายค่ะ\nตัวอย่าง: ค่าเดินทางตัดกสิกรเสมอ")
+			return
+		}
+		lines := []string{"📌 กฎการจ่ายประจำหมวด"}
+		for _, rule := range rules {
+			lines = append(lines, fmt.Sprintf("• %s → %s", rule.Category, getPaymentName(rule.UseType, rule.BankName, rule.CreditCardName)))
+		}
+		lines = append(lines, "", "ยกเลิก: ยกเลิกกฎ <หมวด>")
+		h.replyText(replyToken, strings.Join(lines, "\n"))
+	}
+}
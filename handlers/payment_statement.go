@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// paymentStatementRows is max rows shown in the statement flex
+const paymentStatementRows = 15
+
+// handlePaymentQuery answers "เดือนนี้รูดบัตร KTC ไปเท่าไหร่" with a statement flex (no AI)
+func (h *LineWebhookHandler) handlePaymentQuery(ctx context.Context, replyToken, userID, text string) bool {
+	if !services.IsPaymentQuestion(text) {
+		return false
+	}
+	profile, err := h.mongo.GetUserProfile(ctx, userID)
+	if err != nil {
+		return false
+	}
+	q, ok := services.ParsePaymentQuery(text, profile.Banks, profile.CreditCards, time.Now())
+	if !ok {
+		return false
+	}
+
+	results, err := h.mongo.SearchByPaymentRange(ctx, userID, q.Account, q.From, q.To, 0)
+	if err != nil {
+		log.Printf("Failed to search by payment: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถดึงรายการได้")
+		return true
+	}
+	h.replyPaymentStatement(replyToken, q, results)
+	return true
+}
+
+// replyPaymentStatement renders a card/bank statement: totals on top, newest rows below
+func (h *LineWebhookHandler) replyPaymentStatement(replyToken string, q *services.PaymentQuery, results []services.SearchResult) {
+	a := q.Account
+	name := getPaymentName(a.UseType, a.BankName, a.CreditCardName)
+	headerColor := "#2C3E50"
+	if a.UseType == 1 {
+		headerColor = "#8E44AD"
+	}
+
+	var spent, received float64
+	for _, r := range results {
+		if r.Transaction.Type == 1 {
+			received += r.Transaction.Amount
+		} else {
+			spent += r.Transaction.Amount
+		}
+	}
+
+	body := []interface{}{
+		map[string]interface{}{
+			"type": "box", "layout": "horizontal",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "ใช้ไป", "size": "sm", "color": "#888888"},
+				map[string]interface{}{"type": "text", "text": formatNumber(spent) + " บาท", "size": "lg", "weight": "bold", "color": "#E74C3C", "align": "end"},
+			},
+		},
+	}
+	if received > 0 {
+		// Card payments / bank deposits
+		label := "รับเข้า"
+		if a.UseType == 1 {
+			label = "ชำระ/คืนเงิน"
+		}
+		body = append(body, map[string]interface{}{
+			"type": "box", "layout": "horizontal",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": label, "size": "sm", "color": "#888888"},
+				map[string]interface{}{"type": "text", "text": formatNumber(received) + " บาท", "size": "sm", "weight": "bold", "color": "#27AE60", "align": "end"},
+			},
+		})
+	}
+	body = append(body, map[string]interface{}{"type": "separator", "margin": "md"})
+
+	if len(results) == 0 {
+		body = append(body, map[string]interface{}{"type": "text", "text": "ไม่มีรายการในช่วงนี้", "size": "sm", "color": "#888888", "align": "center", "margin": "md"})
+	}
+	for i, r := range results {
+		if i >= paymentStatementRows {
+			body = append(body, map[string]interface{}{"type": "text", "text": fmt.Sprintf("...และอีก %d รายการ", len(results)-paymentStatementRows), "size": "xs", "color": "#888888", "margin": "sm"})
+			break
+		}
+		tx := r.Transaction
+		amountColor, sign := "#E74C3C", "-"
+		if tx.Type == 1 {
+			amountColor, sign = "#27AE60", "+"
+		}
+		body = append(body, map[string]interface{}{
+			"type": "box", "layout": "horizontal", "margin": "sm",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": formatThaiShortDate(r.Date), "size": "xxs", "color": "#888888", "flex": 2},
+				map[string]interface{}{"type": "text", "text": orDefault(tx.Description, tx.Category), "size": "xs", "flex": 4, "wrap": true, "maxLines": 1},
+				map[string]interface{}{"type": "text", "text": sign + formatNumber(tx.Amount), "size": "xs", "color": amountColor, "align": "end", "flex": 3},
+			},
+		})
+	}
+
+	flex := map[string]interface{}{
+		"type": "bubble",
+		"header": map[string]interface{}{
+			"type":            "box",
+			"layout":          "vertical",
+			"backgroundColor": headerColor,
+			"paddingAll":      "md",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": name, "color": "#FFFFFF", "weight": "bold", "size": "md"},
+				map[string]interface{}{"type": "text", "text": fmt.Sprintf("%s (%d รายการ)", q.Period, len(results)), "color": "#FFFFFF", "size": "xs"},
+			},
+		},
+		"body": map[string]interface{}{
+			"type":       "box",
+			"layout":     "vertical",
+			"paddingAll": "md",
+			"contents":   body,
+		},
+	}
+	if !h.replyFlexFromAI(replyToken, flex, fmt.Sprintf("%s %s ใช้ไป %s บาท", name, q.Period, formatNumber(spent))) {
+		h.replyText(replyToken, fmt.Sprintf("%s %s ใช้ไป %s บาท (%d รายการ)", name, q.Period, formatNumber(spent), len(results)))
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// parsePeerBenchmarkCommand parses peer benchmark commands (no AI): "show", "on", "off"
+func parsePeerBenchmarkCommand(text string) (string, bool) {
+	switch strings.TrimSpace(text) {
+	case "เทียบค่าใช้จ่าย", "เทียบกับคนอื่น", "เทียบรายจ่าย":
+		return "show", true
+	case "เข้าร่วมเทียบค่าใช้จ่าย", "เปิดเทียบค่าใช้จ่าย":
+		return "on", true
+	case "ยกเลิกเทียบค่าใช้จ่าย", "ปิดเทียบค่าใช้จ่าย":
+		return "off", true
+	}
+	return "", false
+}
+
+// handlePeerBenchmarkCommand opts in/out of anonymized benchmarking or shows the comparison
+func (h *LineWebhookHandler) handlePeerBenchmarkCommand(ctx context.Context, replyToken, userID, action string) {
+	switch action {
+	case "on", "off":
+		if err := h.mongo.SetPeerBenchmark(ctx, userID, action == "on"); err != nil {
+			log.Printf("Failed to set peer benchmark: %v", err)
+			h.replyText(replyToken, "ไม่สามารถตั้งค่าได้ กรุณาลองใหม่")
+			return
+		}
+		if action == "off" {
+			h.replyText(replyToken, "ยกเลิกการเข้าร่วมแล้วค่ะ ยอดของคุณจะไม่ถูกนำไปคำนวณค่าเฉลี่ยรอบถัดไป")
+			return
+		}
+		h.replyText(replyToken, fmt.Sprintf("✅ เข้าร่วมเทียบค่าใช้จ่ายแล้วค่ะ\nระบบใช้เฉพาะยอดรวมรายเดือนแบบไม่ระบุตัวตน และแสดงค่าเฉลี่ยเมื่อมีผู้เข้าร่วมอย่างน้อย %d คนเท่านั้น\nดูผล: เทียบค่าใช้จ่าย • ยกเลิก: ยกเลิกเทียบค่าใช้จ่าย", services.PeerBenchmarkMinUsers))
+
+	default:
+		h.replyPeerBenchmark(ctx, replyToken, userID)
+	}
+}
+
+// replyPeerBenchmark compares user's last month spending with the average of users in the same income band
+func (h *LineWebhookHandler) replyPeerBenchmark(ctx context.Context, replyToken, userID string) {
+	settings, err := h.mongo.GetUserSettings(ctx, userID)
+	if err != nil {
+		h.replyText(replyToken, "ไม่สามารถดึงข้อมูลได้ กรุณาลองใหม่")
+		return
+	}
+	if !settings.PeerBenchmark {
+		h.replyText(replyToken, "ฟีเจอร์นี้เปิดเฉพาะคนที่ยินยอมแบ่งยอดแบบไม่ระบุตัวตนค่ะ\nพิมพ์ \"เข้าร่วมเทียบค่าใช้จ่าย\" เพื่อเข้าร่วม")
+		return
+	}
+
+	benchmark, own, err := h.mongo.GetPeerBenchmark(ctx, userID, time.Now())
+	if err != nil {
+		log.Printf("Failed to get peer benchmark: %v", err)
+		h.replyText(replyToken, "ไม่สามารถดึงข้อมูลได้ กรุณาลองใหม่")
+		return
+	}
+	if benchmark == nil {
+		h.replyText(replyToken, "เดือนที่แล้วยังไม่มีรายรับที่บันทึกไว้ เลยยังจัดกลุ่มรายได้ไม่ได้ค่ะ")
+		return
+	}
+	if len(benchmark.Categories) == 0 {
+		h.replyText(replyToken, fmt.Sprintf("กลุ่มรายได้ %s บาท/เดือน ยังมีผู้เข้าร่วมไม่ถึง %d คน เลยยังแสดงค่าเฉลี่ยไม่ได้ค่ะ",
+			benchmark.Band, services.PeerBenchmarkMinUsers))
+		return
+	}
+
+	categories := make([]string, 0, len(benchmark.Categories))
+	for category := range benchmark.Categories {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return benchmark.Categories[categories[i]] > benchmark.Categories[categories[j]]
+	})
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("👥 เทียบกับคนรายได้ใกล้เคียงกัน (%s บาท/เดือน)\nเดือน %s • ผู้เข้าร่วม %d คน\n", benchmark.Band, benchmark.Month, benchmark.Users))
+	for i, category := range categories {
+		if i >= 8 {
+			break
+		}
+		average := benchmark.Categories[category]
+		mine := own.Spending[category]
+		mark := "🟢"
+		if mine > average {
+			mark = "🔴"
+		}
+		sb.WriteString(fmt.Sprintf("\n%s %s: คุณ %s • เฉลี่ย %s", mark, category, formatNumber(mine), formatNumber(average)))
+	}
+	sb.WriteString("\n\nค่าเฉลี่ยคำนวณจากยอดรวมแบบไม่ระบุตัวตนเท่านั้นค่ะ")
+	h.replyText(replyToken, sb.String())
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+)
+
+// Replies to a tap or answer that arrives after its pending state expired (nothing was saved)
+const (
+	slipExpiredText    = "⌛ สลิปนี้หมดเวลาแล้ว (เกิน 10 นาที) ยังไม่ได้บันทึกค่ะ กรุณาส่งรูปใหม่"
+	pendingExpiredText = "⌛ รายการนี้หมดเวลาแล้ว (เกิน 10 นาที) ยังไม่ได้บันทึกค่ะ กรุณาพิมพ์ใหม่อีกครั้ง"
+)
+
+// pendingReminderWindow is how long before expiry a pending state is reminded (states live 10 minutes)
+const pendingReminderWindow = 2 * time.Minute
+
+// isCancelCommand reports whether text asks to drop whatever the bot is waiting for
+func isCancelCommand(text string) bool {
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "ยกเลิก", "ยกเลิกทั้งหมด", "cancel":
+		return true
+	}
+	return false
+}
+
+// handleCancelCommand clears every pending slip/edit/confirmation of the user and says what was dropped (no AI)
+func (h *LineWebhookHandler) handleCancelCommand(ctx context.Context, replyToken, userID string) {
+	labels, err := h.mongo.ClearPendingStates(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to clear pending states: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ ยกเลิกไม่สำเร็จ กรุณาลองใหม่อีกครั้ง")
+		return
+	}
+	if len(labels) == 0 {
+		h.replyText(replyToken, "ไม่มีรายการที่รอดำเนินการอยู่ค่ะ")
+		return
+	}
+	h.replyText(replyToken, "ยกเลิกแล้วค่ะ ❌\n• "+strings.Join(labels, "\n• ")+"\n\nยังไม่ได้บันทึกรายการเหล่านี้นะคะ")
+}
+
+// PendingReminderCronHandler pushes a reminder shortly before a pending state expires, called by an external scheduler
+type PendingReminderCronHandler struct {
+	line   *LineWebhookHandler
+	secret string
+}
+
+// NewPendingReminderCronHandler creates scheduler endpoint handler
+func NewPendingReminderCronHandler(line *LineWebhookHandler, secret string) *PendingReminderCronHandler {
+	return &PendingReminderCronHandler{line: line, secret: secret}
+}
+
+// HandlePendingReminders reminds users of states expiring within 2 minutes (POST, Authorization: Bearer <secret>)
+// One push per user; nothing is sent when push is disabled (LINE_PUSH_ENABLED) or quota is low
+func (h *PendingReminderCronHandler) HandlePendingReminders(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.secret)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if !h.line.canPush() {
+		c.JSON(http.StatusOK, gin.H{"reminded": 0, "skipped": "push disabled"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+	due, err := h.line.mongo.DuePendingReminders(ctx, pendingReminderWindow)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var users []string
+	labels := map[string][]string{}
+	for _, r := range due {
+		if _, ok := labels[r.LineID]; !ok {
+			users = append(users, r.LineID)
+		}
+		labels[r.LineID] = append(labels[r.LineID], r.Label)
+	}
+
+	reminded := 0
+	for _, userID := range users {
+		if !h.line.canPush() {
+			break
+		}
+		_, err := h.line.bot.PushMessage(&messaging_api.PushMessageRequest{
+			To: userID,
+			Messages: []messaging_api.MessageInterface{
+				messaging_api.TextMessage{
+					Text: "⏳ ยังรอคุณอยู่นะคะ อีกไม่กี่นาทีจะหมดเวลา\n• " + strings.Join(labels[userID], "\n• ") + "\n\nตอบต่อได้เลย หรือพิมพ์ \"ยกเลิก\" ค่ะ",
+					QuickReply: &messaging_api.QuickReply{Items: []messaging_api.QuickReplyItem{
+						{Action: &messaging_api.MessageAction{Label: "❌ ยกเลิก", Text: "ยกเลิก"}},
+					}},
+				},
+			},
+		}, "")
+		if err != nil {
+			log.Printf("Failed to push pending reminder: %v", err)
+			continue
+		}
+		h.line.recordPush()
+		reminded++
+	}
+	c.JSON(http.StatusOK, gin.H{"reminded": reminded})
+}
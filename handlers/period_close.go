@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// parsePeriodCloseCommand parses "ปิดงวด [วันที่]", "เปิดงวด" and "สถานะงวด"
+// Without a date, ปิดงวด closes through the end of last month
+func parsePeriodCloseCommand(text string, now time.Time) (action, through string, ok bool) {
+	text = strings.TrimSpace(text)
+	switch text {
+	case "เปิดงวด", "ปลดล็อกงวด", "ยกเลิกปิดงวด":
+		return "reopen", "", true
+	case "สถานะงวด", "งวดบัญชี":
+		return "status", "", true
+	}
+	if !strings.HasPrefix(text, "ปิดงวด") {
+		return "", "", false
+	}
+
+	rest := strings.TrimSpace(strings.TrimPrefix(text, "ปิดงวด"))
+	if rest == "" {
+		monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return "close", monthStart.AddDate(0, 0, -1).Format("2006-01-02"), true
+	}
+	r, found := services.ParseThaiDate(rest, now)
+	if !found {
+		return "", "", false
+	}
+	return "close", r.ToString(), true
+}
+
+// handlePeriodCloseCommand closes, reopens or shows the locked period
+func (h *LineWebhookHandler) handlePeriodCloseCommand(ctx context.Context, replyToken, userID, action, through string) {
+	switch action {
+	case "close":
+		if err := h.mongo.ClosePeriod(ctx, userID, through); err != nil {
+			log.Printf("Failed to close period: %v", err)
+			h.replyText(replyToken, fmt.Sprintf("ปิดงวดไม่สำเร็จ: %v", err))
+			return
+		}
+		msg := fmt.Sprintf("🔒 ปิดงวดถึง %s แล้วค่ะ\nรายการก่อนหน้านี้แก้ไข/ลบไม่ได้ หากต้องการปรับปรุงให้บันทึกเป็นรายการใหม่\nปลดล็อก: พิมพ์ \"เปิดงวด\"", through)
+		// Closing balance of the period (also caches the snapshot)
+		if balances, err := h.mongo.GetBalanceAsOf(ctx, userID, through); err == nil && len(balances) > 0 {
+			var total float64
+			for _, b := range balances {
+				total += b.Balance
+			}
+			msg += fmt.Sprintf("\n\nยอดปิดงวดรวม %s บาท", formatNumber(total))
+		}
+		h.replyText(replyToken, msg)
+
+	case "reopen":
+		if err := h.mongo.ReopenPeriod(ctx, userID); err != nil {
+			log.Printf("Failed to reopen period: %v", err)
+			h.replyText(replyToken, "ปลดล็อกงวดไม่สำเร็จ กรุณาลองใหม่")
+			return
+		}
+		h.mongo.LogSecurityEvent(ctx, services.SecurityEvent{LineID: userID, Event: services.SecurityPeriodReopened, Detail: "closed period reopened"})
+		h.replyText(replyToken, "🔓 เปิดงวดแล้วค่ะ แก้ไขรายการย้อนหลังได้ตามปกติ")
+
+	default:
+		settings, err := h.mongo.GetUserSettings(ctx, userID)
+		if err != nil {
+			h.replyText(replyToken, "ไม่สามารถดึงข้อมูลได้ กรุณาลองใหม่")
+			return
+		}
+		if settings.LockedUntil == "" {
+			h.replyText(replyToken, "ยังไม่ได้ปิดงวดค่ะ\nปิดงวดเดือนที่แล้ว: พิมพ์ \"ปิดงวด\"")
+			return
+		}
+		h.replyText(replyToken, fmt.Sprintf("🔒 ปิดงวดถึง %s\nปลดล็อก: พิมพ์ \"เปิดงวด\"", settings.LockedUntil))
+	}
+}
+
+// periodLockedText returns user message when err is caused by a closed period
+func periodLockedText(err error) (string, bool) {
+	if !errors.Is(err, services.ErrPeriodLocked) {
+		return "", false
+	}
+	if errors.Is(err, services.ErrDateArchived) {
+		return "🗄️ รายการของวันนั้นย้ายเข้าคลังข้อมูลเก่าแล้ว แก้ไขไม่ได้ค่ะ\nยังค้นหาและส่งออกได้ตามปกติ", true
+	}
+	return "🔒 รายการนี้อยู่ในงวดที่ปิดแล้ว แก้ไขไม่ได้ค่ะ\nบันทึกรายการปรับปรุงเป็นรายการใหม่ หรือพิมพ์ \"เปิดงวด\" เพื่อปลดล็อก", true
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satisatang/backend/services"
+)
+
+// PromptReloadHandler swaps in edited prompt files without a redeploy
+type PromptReloadHandler struct {
+	ai     *services.AIService
+	secret string
+}
+
+// NewPromptReloadHandler creates admin endpoint handler for prompt reloads
+func NewPromptReloadHandler(ai *services.AIService, secret string) *PromptReloadHandler {
+	return &PromptReloadHandler{ai: ai, secret: secret}
+}
+
+// HandleReload rereads prompts/*.md (POST, Authorization: Bearer <secret>)
+func (h *PromptReloadHandler) HandleReload(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.secret)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	prompts, previous, err := h.ai.ReloadPrompts()
+	if errors.Is(err, services.ErrPromptsMissing) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "version": previous})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"version":   prompts.Version,
+		"previous":  previous,
+		"changed":   prompts.Version != previous,
+		"dir":       prompts.Dir,
+		"missing":   prompts.Missing,
+		"loaded_at": prompts.LoadedAt,
+	})
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+	"github.com/satisatang/backend/services"
+)
+
+// promptPayQRPattern matches "qr 500", "ขอ qr 350 ค่าข้าว", "คิวอาร์พร้อมเพย์ 1,200"
+var promptPayQRPattern = regexp.MustCompile(`(?i)^(?:ขอ\s*)?(?:qr|คิวอาร์)\s*(?:พร้อมเพย์|promptpay)?\s*(.*)$`)
+
+// parsePromptPayCommand parses "ตั้งพร้อมเพย์ <id>" (action "set") and QR requests (action "qr", amount 0 = any amount)
+func parsePromptPayCommand(text string) (action, arg string, amount float64, ok bool) {
+	text = strings.TrimSpace(text)
+	for _, prefix := range []string{"ตั้งพร้อมเพย์", "ตั้งค่าพร้อมเพย์"} {
+		if strings.HasPrefix(text, prefix) {
+			return "set", strings.TrimSpace(strings.TrimPrefix(text, prefix)), 0, true
+		}
+	}
+
+	m := promptPayQRPattern.FindStringSubmatch(text)
+	if m == nil {
+		return "", "", 0, false
+	}
+	rest := strings.Fields(m[1])
+	if len(rest) == 0 {
+		return "qr", "", 0, true
+	}
+	value, parsed := services.ParseThaiAmount(rest[0])
+	if !parsed || value <= 0 {
+		return "", "", 0, false
+	}
+	return "qr", strings.Join(rest[1:], " "), value, true
+}
+
+// maskPromptPayID hides the middle of PromptPay ID ("081-xxx-5678")
+func maskPromptPayID(id string) string {
+	if len(id) < 8 {
+		return id
+	}
+	return id[:3] + "-xxx-" + id[len(id)-4:]
+}
+
+// handlePromptPayCommand registers PromptPay ID or replies PromptPay QR for the amount
+func (h *LineWebhookHandler) handlePromptPayCommand(ctx context.Context, replyToken, userID, action, arg string, amount float64) {
+	if action == "set" {
+		if err := h.mongo.SetPromptPayID(ctx, userID, arg); err != nil {
+			h.replyText(replyToken, fmt.Sprintf("ตั้งพร้อมเพย์ไม่สำเร็จ: %v\nตัวอย่าง: ตั้งพร้อมเพย์ 0812345678", err))
+			return
+		}
+		h.replyText(replyToken, "ตั้งพร้อมเพย์แล้วค่ะ\nเวลาเพื่อนติดเงิน พิมพ์ \"qr 500\" เพื่อสร้าง QR ส่งต่อให้เพื่อนได้เลย")
+		return
+	}
+
+	settings, err := h.mongo.GetUserSettings(ctx, userID)
+	if err != nil || settings.PromptPayID == "" {
+		h.replyText(replyToken, "ยังไม่ได้ตั้งพร้อมเพย์ค่ะ\nพิมพ์: ตั้งพร้อมเพย์ 0812345678")
+		return
+	}
+	if h.firebase == nil {
+		h.replyText(replyToken, "❌ ระบบยังไม่พร้อมส่งรูปค่ะ\n\nกรุณาติดต่อผู้ดูแลระบบ")
+		return
+	}
+
+	png, err := services.PromptPayQRPNG(settings.PromptPayID, amount)
+	if err != nil {
+		log.Printf("Failed to generate PromptPay QR: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ สร้าง QR ไม่สำเร็จ")
+		return
+	}
+	uploadCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	imageURL, err := h.firebase.UploadFile(uploadCtx, png, fmt.Sprintf("promptpay_%d.png", time.Now().UnixNano()), "image/png")
+	if err != nil {
+		log.Printf("Failed to upload PromptPay QR: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ ส่ง QR ไม่สำเร็จ กรุณาลองใหม่")
+		return
+	}
+
+	caption := fmt.Sprintf("📲 QR พร้อมเพย์ %s", maskPromptPayID(settings.PromptPayID))
+	if amount > 0 {
+		caption += fmt.Sprintf("\nยอด %s บาท", formatNumber(amount))
+	}
+	if arg != "" {
+		caption += "\n" + arg
+	}
+	caption += "\n\nกดค้างที่รูปแล้วส่งต่อให้เพื่อนได้เลยค่ะ"
+
+	h.newReplyComposer(replyToken, userID).
+		Add(messaging_api.ImageMessage{OriginalContentUrl: imageURL, PreviewImageUrl: imageURL}).
+		AddText(caption).
+		Send()
+}
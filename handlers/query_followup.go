@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// handleFollowUpQuery refines the last search/analyze with a follow-up Go understands on its own
+// ("แล้วเดือนก่อนล่ะ", "เอาเฉพาะบัตรเครดิต") (no AI)
+func (h *LineWebhookHandler) handleFollowUpQuery(ctx context.Context, replyToken, userID, text string) bool {
+	if !services.IsFollowUpQuery(text) {
+		return false
+	}
+	last, ok := h.mongo.GetLastQuery(ctx, userID)
+	if !ok {
+		return false
+	}
+	var banks, cards []string
+	var aliases []services.AccountAlias
+	if profile, err := h.mongo.GetUserProfile(ctx, userID); err == nil {
+		banks, cards, aliases = profile.Banks, profile.CreditCards, profile.AccountAliases
+	}
+	f, ok := services.ParseFollowUp(text, banks, cards, aliases, time.Now())
+	if !ok {
+		return false
+	}
+
+	next := last.Refine(f)
+	results, total := h.runQuery(ctx, userID, next)
+	h.rememberLastQuery(ctx, userID, next)
+	if !h.replyQueryResultsFlex(ctx, userID, replyToken, results, total, &next.Query, f.Label) {
+		h.replyText(replyToken, fmt.Sprintf("ไม่พบรายการ (%s) ค่ะ", f.Label))
+	}
+	return true
+}
+
+// runQuery runs a search/analyze; narrowed queries read more rows and filter them in Go
+func (h *LineWebhookHandler) runQuery(ctx context.Context, userID string, q services.LastQuery) ([]services.SearchResult, int) {
+	if !q.Narrowed() {
+		return h.queryTransactions(ctx, userID, &q.Query)
+	}
+	limit := q.Query.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	wide := q.Query
+	wide.Limit = services.FollowUpScanLimit
+	results, _ := h.queryTransactions(ctx, userID, &wide)
+	results = q.Filter(results)
+	total := len(results)
+	if total > limit {
+		results = results[:limit]
+	}
+	return results, total
+}
+
+// rememberLastQuery keeps q so the next question can refine it
+func (h *LineWebhookHandler) rememberLastQuery(ctx context.Context, userID string, q services.LastQuery) {
+	if err := h.mongo.RememberLastQuery(ctx, userID, q); err != nil {
+		log.Printf("Failed to remember last query: %v", err)
+	}
+}
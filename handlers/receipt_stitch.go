@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/webhook"
+	"github.com/satisatang/backend/services"
+)
+
+const (
+	receiptStitchWindow = 2 * time.Minute // next photo within this window may continue the receipt
+	maxStitchImages     = 5
+)
+
+// receiptImageBuffer holds recent receipt photos of one user in temp data
+type receiptImageBuffer struct {
+	Images    []bufferedImage `json:"images"`
+	SetID     string          `json:"set_id,omitempty"` // LINE image set (several photos sent at once)
+	SetTotal  int             `json:"set_total,omitempty"`
+	TxID      string          `json:"txid,omitempty"` // saved from earlier photos, replaced when merged
+	Pending   bool            `json:"pending"`        // waiting for "same receipt?" answer
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+type bufferedImage struct {
+	Data     string `json:"data"` // base64
+	MimeType string `json:"mime"`
+}
+
+// receiptBufferKey is the temp data key of user's photo buffer
+func receiptBufferKey(userID string) string {
+	return "receipt_images_" + userID
+}
+
+// loadReceiptBuffer returns user's photo buffer, empty when none or outside the window
+func (h *LineWebhookHandler) loadReceiptBuffer(ctx context.Context, userID string) receiptImageBuffer {
+	var buf receiptImageBuffer
+	data, err := h.mongo.GetTempData(ctx, receiptBufferKey(userID))
+	if err != nil || json.Unmarshal([]byte(data), &buf) != nil || time.Since(buf.UpdatedAt) > receiptStitchWindow {
+		return receiptImageBuffer{}
+	}
+	return buf
+}
+
+// saveReceiptBuffer stores user's photo buffer (kept a bit longer than the window for the answer)
+func (h *LineWebhookHandler) saveReceiptBuffer(ctx context.Context, userID string, buf receiptImageBuffer) {
+	buf.UpdatedAt = time.Now()
+	data, _ := json.Marshal(buf)
+	if err := h.mongo.SaveTempData(ctx, receiptBufferKey(userID), string(data), 10*time.Minute); err != nil {
+		log.Printf("Failed to save receipt images: %v", err)
+	}
+}
+
+// rememberReceiptImages records photos of a saved receipt so the next photo can be merged into it
+func (h *LineWebhookHandler) rememberReceiptImages(ctx context.Context, userID string, images []services.ReceiptImage, txID string) {
+	buf := receiptImageBuffer{TxID: txID}
+	for _, img := range images {
+		buf.Images = append(buf.Images, bufferedImage{Data: base64.StdEncoding.EncodeToString(img.Data), MimeType: img.MimeType})
+	}
+	h.stitchMu.Lock()
+	defer h.stitchMu.Unlock()
+	h.saveReceiptBuffer(ctx, userID, buf)
+}
+
+// bufferReceiptImage keeps photos that may belong to one receipt and asks the user, true if handled
+// Photos sent together (image set) wait silently until the whole set arrives;
+// a single photo right after a saved receipt asks whether it continues that receipt
+func (h *LineWebhookHandler) bufferReceiptImage(ctx context.Context, replyToken, userID string, set *webhook.ImageSet, data []byte, mimeType string) bool {
+	h.stitchMu.Lock()
+	defer h.stitchMu.Unlock()
+
+	buf := h.loadReceiptBuffer(ctx, userID)
+	image := bufferedImage{Data: base64.StdEncoding.EncodeToString(data), MimeType: mimeType}
+
+	if set != nil && set.Total > 1 {
+		if buf.SetID != set.Id {
+			buf = receiptImageBuffer{SetID: set.Id, SetTotal: int(set.Total)}
+		}
+		buf.Images = append(buf.Images, image)
+		if len(buf.Images) < buf.SetTotal {
+			h.saveReceiptBuffer(ctx, userID, buf) // Reply token left unused, no cost
+			return true
+		}
+		buf.Pending = true
+		h.saveReceiptBuffer(ctx, userID, buf)
+		h.replyReceiptStitchQuestion(replyToken, fmt.Sprintf("ได้รับรูป %d รูป เป็นใบเสร็จเดียวกันไหมคะ?", len(buf.Images)))
+		return true
+	}
+
+	if buf.TxID == "" || buf.Pending || len(buf.Images) >= maxStitchImages {
+		return false
+	}
+	buf.Images = append(buf.Images, image)
+	buf.Pending = true
+	h.saveReceiptBuffer(ctx, userID, buf)
+	h.replyReceiptStitchQuestion(replyToken, "รูปนี้เป็นใบเสร็จเดียวกับรูปก่อนหน้าไหมคะ?")
+	return true
+}
+
+// replyReceiptStitchQuestion asks "เป็นใบเสร็จเดียวกันไหม?" with yes/no postbacks
+func (h *LineWebhookHandler) replyReceiptStitchQuestion(replyToken, question string) {
+	flex := map[string]interface{}{
+		"type": "bubble",
+		"size": "kilo",
+		"body": map[string]interface{}{
+			"type":       "box",
+			"layout":     "vertical",
+			"paddingAll": "md",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "🧾 " + question, "size": "sm", "weight": "bold", "wrap": true},
+				map[string]interface{}{"type": "text", "text": "ใบเสร็จยาวถ่ายหลายรูป รวมเป็นรายการเดียวได้ค่ะ", "size": "xs", "color": "#888888", "wrap": true, "margin": "sm"},
+			},
+		},
+		"footer": map[string]interface{}{
+			"type":    "box",
+			"layout":  "horizontal",
+			"spacing": "sm",
+			"contents": []interface{}{
+				map[string]interface{}{
+					"type": "button", "style": "primary", "height": "sm", "color": "#27AE60",
+					"action": map[string]interface{}{
+						"type": "postback", "label": "ใบเดียวกัน", "data": "action=receipt_stitch&same=1", "displayText": "ใบเสร็จเดียวกัน",
+					},
+				},
+				map[string]interface{}{
+					"type": "button", "style": "secondary", "height": "sm",
+					"action": map[string]interface{}{
+						"type": "postback", "label": "แยกรายการ", "data": "action=receipt_stitch&same=0", "displayText": "แยกรายการ",
+					},
+				},
+			},
+		},
+	}
+	if !h.replyFlexFromAI(replyToken, flex, question) {
+		h.replyText(replyToken, question)
+	}
+}
+
+// handleReceiptStitch combines buffered photos into one transaction, or processes them separately
+func (h *LineWebhookHandler) handleReceiptStitch(ctx context.Context, replyToken, userID string, params map[string]string) {
+	h.stitchMu.Lock()
+	buf := h.loadReceiptBuffer(ctx, userID)
+	h.mongo.DeleteTempData(ctx, receiptBufferKey(userID))
+	h.stitchMu.Unlock()
+
+	if !buf.Pending || len(buf.Images) == 0 {
+		h.replyText(replyToken, "รูปหมดอายุแล้ว กรุณาส่งรูปใหม่อีกครั้งค่ะ")
+		return
+	}
+
+	var images []services.ReceiptImage
+	for _, img := range buf.Images {
+		data, err := base64.StdEncoding.DecodeString(img.Data)
+		if err != nil {
+			continue
+		}
+		images = append(images, services.ReceiptImage{Data: data, MimeType: img.MimeType})
+	}
+	if len(images) == 0 {
+		h.replyText(replyToken, "รูปหมดอายุแล้ว กรุณาส่งรูปใหม่อีกครั้งค่ะ")
+		return
+	}
+
+	if params["same"] != "1" {
+		h.replySeparateReceipts(ctx, replyToken, userID, buf, images)
+		return
+	}
+
+	tx, err := h.ai.ProcessReceiptImages(ctx, images)
+	if err != nil {
+		log.Printf("Failed to process receipt images: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถอ่านข้อมูลจากรูปภาพได้ กรุณาลองใหม่อีกครั้ง")
+		return
+	}
+	// Combined transaction replaces the one saved from the earlier photos
+	if buf.TxID != "" {
+		if err := h.mongo.DeleteTransaction(ctx, userID, buf.TxID); err != nil {
+			log.Printf("Failed to delete partial receipt %s: %v", buf.TxID, err)
+		}
+	}
+	tx.ImageBase64 = base64.StdEncoding.EncodeToString(images[0].Data)
+	tx.ImageMimeType = images[0].MimeType
+	if tx.Description == "" {
+		tx.Description = fmt.Sprintf("ใบเสร็จ %d รูป", len(images))
+	}
+
+	h.applyTripToReceipt(ctx, userID, tx)
+	if txID := h.replyTransactionFlex(replyToken, userID, tx); txID != "" {
+		h.rememberReceiptImages(ctx, userID, images, txID)
+	}
+}
+
+// replySeparateReceipts processes photos one by one ("แยกรายการ")
+func (h *LineWebhookHandler) replySeparateReceipts(ctx context.Context, replyToken, userID string, buf receiptImageBuffer, images []services.ReceiptImage) {
+	// Earlier photos were already saved, only the new photo needs reading
+	if buf.TxID != "" {
+		last := images[len(images)-1]
+		h.processReceiptImage(ctx, replyToken, userID, last.Data, last.MimeType)
+		return
+	}
+
+	var txs []services.TransactionData
+	for _, img := range images {
+		tx, err := h.ai.ProcessReceiptImages(ctx, []services.ReceiptImage{img})
+		if err != nil {
+			log.Printf("Failed to process receipt image: %v", err)
+			continue
+		}
+		tx.ImageBase64 = base64.StdEncoding.EncodeToString(img.Data)
+		tx.ImageMimeType = img.MimeType
+		txs = append(txs, *tx)
+	}
+	if len(txs) == 0 {
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถอ่านข้อมูลจากรูปภาพได้ กรุณาลองใหม่อีกครั้ง")
+		return
+	}
+	h.replyTransactionFlexMultiple(replyToken, userID, txs)
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// recentTransactionsLimit is number of bubbles in the carousel (LINE max 12)
+const recentTransactionsLimit = 10
+
+// isRecentTransactionsCommand matches "ดูรายการล่าสุด"
+func isRecentTransactionsCommand(text string) bool {
+	switch strings.ReplaceAll(strings.TrimSpace(text), " ", "") {
+	case "ดูรายการล่าสุด", "รายการล่าสุด", "ดูรายการ":
+		return true
+	}
+	return false
+}
+
+// replyRecentTransactions shows latest transactions as a carousel, each with its own ✏️/🗑️
+func (h *LineWebhookHandler) replyRecentTransactions(ctx context.Context, replyToken, userID string) {
+	results, err := h.mongo.GetRecentTransactions(ctx, userID, recentTransactionsLimit)
+	if err != nil {
+		log.Printf("Failed to get recent transactions: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถดึงรายการได้")
+		return
+	}
+	if len(results) == 0 {
+		h.replyText(replyToken, "ยังไม่มีรายการค่ะ")
+		return
+	}
+
+	var bubbles []interface{}
+	for _, r := range results {
+		bubbles = append(bubbles, h.recentTransactionBubble(r))
+	}
+	if !h.replyFlexFromAI(replyToken, bubbles, fmt.Sprintf("รายการล่าสุด %d รายการ", len(results))) {
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถแสดงรายการได้")
+	}
+}
+
+// recentTransactionBubble builds one transaction bubble with edit/delete postbacks carrying txid and date
+func (h *LineWebhookHandler) recentTransactionBubble(r services.SearchResult) map[string]interface{} {
+	tx := r.Transaction
+	txID := tx.ID.Hex()
+	headerColor, sign := "#E74C3C", "-"
+	if tx.Type == 1 {
+		headerColor, sign = "#27AE60", "+"
+	}
+
+	editButton := h.editAmountButton(txID, r.Date, tx.Amount)
+	if editButton == nil {
+		editButton = map[string]interface{}{
+			"type": "button", "style": "secondary", "height": "sm",
+			"action": map[string]interface{}{
+				"type":        "postback",
+				"label":       "✏️ แก้ไข",
+				"data":        fmt.Sprintf("action=edit_request&txid=%s&date=%s", txID, r.Date),
+				"displayText": "แก้ไข " + orDefault(tx.Description, tx.Category),
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"type": "bubble",
+		"size": "kilo",
+		"header": map[string]interface{}{
+			"type":            "box",
+			"layout":          "vertical",
+			"backgroundColor": headerColor,
+			"paddingAll":      "sm",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": formatThaiShortDate(r.Date), "color": "#FFFFFF", "size": "xs"},
+			},
+		},
+		"body": map[string]interface{}{
+			"type":       "box",
+			"layout":     "vertical",
+			"paddingAll": "md",
+			"spacing":    "xs",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": orDefault(tx.Description, tx.Category), "weight": "bold", "size": "sm", "wrap": true, "maxLines": 2},
+				map[string]interface{}{"type": "text", "text": sign + formatNumber(tx.Amount) + " บาท", "size": "lg", "weight": "bold", "color": headerColor},
+				map[string]interface{}{"type": "text", "text": orDefault(tx.Category, "อื่นๆ"), "size": "xs", "color": "#888888"},
+				map[string]interface{}{"type": "text", "text": getPaymentName(tx.UseType, tx.BankName, tx.CreditCardName), "size": "xs", "color": "#888888", "wrap": true},
+			},
+		},
+		"footer": map[string]interface{}{
+			"type":    "box",
+			"layout":  "horizontal",
+			"spacing": "sm",
+			"contents": []interface{}{
+				editButton,
+				map[string]interface{}{
+					"type": "button", "style": "secondary", "height": "sm",
+					"action": map[string]interface{}{
+						"type":        "postback",
+						"label":       "🗑️ ลบ",
+						"data":        deletePostbackData(txID, r.Date, services.TransactionKind(tx.Type)),
+						"displayText": "ลบ " + orDefault(tx.Description, tx.Category),
+					},
+				},
+			},
+		},
+	}
+}
+
+// formatThaiShortDate formats YYYY-MM-DD as "16 ต.ค. 69" (วันนี้/เมื่อวาน for recent days)
+func formatThaiShortDate(date string) string {
+	d, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	today := time.Now().Format("2006-01-02")
+	switch date {
+	case today:
+		return "วันนี้"
+	case time.Now().AddDate(0, 0, -1).Format("2006-01-02"):
+		return "เมื่อวาน"
+	}
+	months := []string{"ม.ค.", "ก.พ.", "มี.ค.", "เม.ย.", "พ.ค.", "มิ.ย.", "ก.ค.", "ส.ค.", "ก.ย.", "ต.ค.", "พ.ย.", "ธ.ค."}
+	return fmt.Sprintf("%d %s %02d", d.Day(), months[d.Month()-1], (d.Year()+543)%100)
+}
+
+// deletePostbackData is the 🗑️ payload: record date and kind let the delete hit the right record
+// however long after saving the button is tapped
+func deletePostbackData(txID, date, kind string) string {
+	return fmt.Sprintf("action=delete&txid=%s&date=%s&kind=%s", txID, date, kind)
+}
+
+// editTargetKey is the temp data key of the transaction chosen by ✏️ (used by the next edit message)
+func editTargetKey(userID string) string {
+	return "edit_target_" + userID
+}
+
+// pendingEditTarget returns transaction chosen by ✏️ within the last 10 minutes
+func (h *LineWebhookHandler) pendingEditTarget(ctx context.Context, userID string) (txID, date string, ok bool) {
+	pending, err := h.mongo.GetTempData(ctx, editTargetKey(userID))
+	if err != nil || pending == "" {
+		return "", "", false
+	}
+	parts := strings.SplitN(pending, "|", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// handleEditTargetAmount updates the chosen transaction when user replies with only a number
+func (h *LineWebhookHandler) handleEditTargetAmount(ctx context.Context, replyToken, userID, text string) bool {
+	txID, date, ok := h.pendingEditTarget(ctx, userID)
+	if !ok {
+		return false
+	}
+	amount, ok := services.ParseThaiAmount(strings.TrimSpace(text))
+	if !ok || amount <= 0 {
+		return false
+	}
+	h.mongo.DeleteTempData(ctx, editTargetKey(userID))
+
+	if err := h.mongo.UpdateTransactionAmountOnDate(ctx, userID, txID, date, amount); err != nil {
+		if msg, locked := periodLockedText(err); locked {
+			h.replyText(replyToken, msg)
+			return true
+		}
+		if msg, ok := h.spendingCapText(err); ok {
+			h.replyText(replyToken, msg)
+			return true
+		}
+		log.Printf("Failed to update amount: %v", err)
+		h.replyText(replyToken, "ไม่สามารถแก้ไขรายการได้ค่ะ")
+		return true
+	}
+	tx, err := h.mongo.GetTransactionOnDate(ctx, userID, txID, date)
+	if err != nil {
+		h.replyText(replyToken, fmt.Sprintf("แก้ยอดเป็น %s บาทแล้วค่ะ", formatNumber(amount)))
+		return true
+	}
+	h.replyUpdatedTransaction(replyToken, userID, tx, fmt.Sprintf("แก้ยอดเป็น %s บาทแล้วค่ะ", formatNumber(amount)), txID, date)
+	return true
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satisatang/backend/services"
+)
+
+// friendlyErrorText is sent when an unexpected error happens while handling a LINE event
+const friendlyErrorText = "ขออภัยค่ะ ระบบขัดข้องชั่วคราว กรุณาลองใหม่อีกครั้ง 🙏"
+
+// RecoveryMiddleware reports panics in Gin routes and returns a plain 500 (replaces gin.Recovery)
+func RecoveryMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				services.ReportPanic(fmt.Sprintf("%s %s", c.Request.Method, c.FullPath()), "", rec)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			}
+		}()
+		c.Next()
+	}
+}
+
+// recoverEvent reports a panic while handling one LINE event and still answers the user
+// Other events in the same webhook call keep being processed
+func (h *LineWebhookHandler) recoverEvent(action, replyToken, userID string) {
+	if rec := recover(); rec != nil {
+		services.ReportPanic(action, userID, rec)
+		if replyToken != "" {
+			h.replyText(replyToken, friendlyErrorText)
+		}
+	}
+}
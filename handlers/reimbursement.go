@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// reimbursementExportPrefixes start a claim export ("export รายการเบิกบริษัท เดือนที่แล้ว")
+var reimbursementExportPrefixes = []string{"export รายการเบิกบริษัท", "ส่งออกรายการเบิกบริษัท", "export รายการเบิก", "ส่งออกรายการเบิก", "รายการเบิกบริษัท"}
+
+// parseReimbursementExport returns the date range of a claim export command (default last 30 days)
+func parseReimbursementExport(text string, now time.Time) (from, to string, ok bool) {
+	lower := strings.ToLower(strings.TrimSpace(text))
+	for _, prefix := range reimbursementExportPrefixes {
+		if !strings.HasPrefix(lower, prefix) {
+			continue
+		}
+		if r, found := services.ParseThaiDate(strings.TrimPrefix(lower, prefix), now); found {
+			return r.FromString(), r.ToString(), true
+		}
+		return now.AddDate(0, 0, -30).Format("2006-01-02"), now.Format("2006-01-02"), true
+	}
+	return "", "", false
+}
+
+// handleReimbursableTag tags the latest entry when the message is just "#เบิกได้" (e.g. after a receipt photo)
+func (h *LineWebhookHandler) handleReimbursableTag(ctx context.Context, replyToken, userID, text string) bool {
+	if strings.TrimSpace(text) != services.ReimbursableTag {
+		return false
+	}
+	recent, err := h.mongo.GetRecentTransactions(ctx, userID, 1)
+	if err != nil || len(recent) == 0 {
+		h.replyText(replyToken, "ยังไม่มีรายการให้ติดแท็กค่ะ")
+		return true
+	}
+	r := recent[0]
+	desc := services.TagReimbursable(r.Transaction.Description)
+	if err := h.mongo.UpdateTransactionDescriptionOnDate(ctx, userID, r.Transaction.ID.Hex(), r.Date, desc); err != nil {
+		if msg, locked := periodLockedText(err); locked {
+			h.replyText(replyToken, msg)
+			return true
+		}
+		log.Printf("Failed to tag reimbursable: %v", err)
+		h.replyText(replyToken, "ไม่สามารถติดแท็กได้ค่ะ")
+		return true
+	}
+	h.replyText(replyToken, fmt.Sprintf("🧾 ติดแท็ก %s ให้ \"%s\" %s บาทแล้วค่ะ\nส่งออกด้วย: export รายการเบิกบริษัท",
+		services.ReimbursableTag, orDefault(r.Transaction.Description, r.Transaction.Category), formatNumber(r.Transaction.Amount)))
+	return true
+}
+
+// handleReimbursementExport sends Excel of #เบิกได้ expenses with cover summary and receipt images
+func (h *LineWebhookHandler) handleReimbursementExport(ctx context.Context, replyToken, userID, from, to string) {
+	data, filename, count, err := h.export.ExportReimbursement(ctx, userID, from, to)
+	if err != nil {
+		log.Printf("Failed to export reimbursement: %v", err)
+		h.replyText(replyToken, "ไม่สามารถสร้างไฟล์รายการเบิกได้ กรุณาลองใหม่")
+		return
+	}
+	if count == 0 {
+		h.replyText(replyToken, fmt.Sprintf("ไม่พบรายการ %s ในช่วง %s ถึง %s ค่ะ\nติดแท็กตอนจด เช่น \"แท็กซี่ 250 %s\"", services.ReimbursableTag, from, to, services.ReimbursableTag))
+		return
+	}
+	h.replyAndSendFile(replyToken, userID, fmt.Sprintf("🧾 รายการเบิก %d รายการ (แนบรูปใบเสร็จ)", count), data, filename, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+}
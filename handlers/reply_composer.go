@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+)
+
+const (
+	maxReplyMessages   = 5    // LINE limit per reply token
+	maxTextLength      = 5000 // LINE limit per text message
+	pushQuotaRefresh   = 10 * time.Minute
+	pushQuotaReservePc = 10 // keep last 10% of monthly quota unused
+)
+
+// pushQuota caches monthly push quota from LINE API (push is off unless allowed in rules.md)
+type pushQuota struct {
+	mu         sync.Mutex
+	enabled    bool
+	limited    bool
+	limit      int64
+	used       int64
+	checkedAt  time.Time
+	refreshing bool // one caller is fetching quota from LINE; others use the cached values
+}
+
+// canPush reports whether one more push fits the monthly quota (keeping a reserve)
+// The mutex is never held across LINE API calls, so a slow quota check doesn't stall other replies
+func (h *LineWebhookHandler) canPush() bool {
+	q := &h.push
+	q.mu.Lock()
+	if !q.enabled {
+		q.mu.Unlock()
+		return false
+	}
+	if time.Since(q.checkedAt) > pushQuotaRefresh && !q.refreshing {
+		q.refreshing = true
+		q.mu.Unlock()
+		ok := h.refreshPushQuota()
+		q.mu.Lock()
+		q.refreshing = false
+		if !ok {
+			q.mu.Unlock()
+			return false
+		}
+	}
+	defer q.mu.Unlock()
+
+	// Another caller is fetching the first quota: nothing known yet
+	if q.checkedAt.IsZero() {
+		return false
+	}
+	if !q.limited {
+		return true
+	}
+	return q.used < q.limit-q.limit*pushQuotaReservePc/100
+}
+
+// refreshPushQuota fetches monthly quota and usage from LINE and caches them
+// Called without q.mu held; takes the lock only to store the result
+func (h *LineWebhookHandler) refreshPushQuota() bool {
+	quota, err := h.bot.GetMessageQuota()
+	if err != nil {
+		log.Printf("Failed to get message quota: %v", err)
+		return false
+	}
+	consumption, err := h.bot.GetMessageQuotaConsumption()
+	if err != nil {
+		log.Printf("Failed to get quota consumption: %v", err)
+		return false
+	}
+	q := &h.push
+	q.mu.Lock()
+	q.limited = quota.Type == messaging_api.QuotaType_LIMITED
+	q.limit, q.used, q.checkedAt = quota.Value, consumption.TotalUsage, time.Now()
+	log.Printf("Push quota: %d/%d (limited=%v)", q.used, q.limit, q.limited)
+	q.mu.Unlock()
+	return true
+}
+
+// recordPush counts a push locally until the next quota refresh
+func (h *LineWebhookHandler) recordPush() {
+	h.push.mu.Lock()
+	h.push.used++
+	h.push.mu.Unlock()
+}
+
+// EnablePush allows overflow messages to be pushed while monthly quota lasts
+func (h *LineWebhookHandler) EnablePush() {
+	h.push.mu.Lock()
+	h.push.enabled = true
+	h.push.mu.Unlock()
+}
+
+// ReplyComposer collects messages for one reply token and sends them in a single reply
+// Alerts are low priority: they are merged into one text and dropped last
+type ReplyComposer struct {
+	h          *LineWebhookHandler
+	replyToken string
+	userID     string
+	messages   []messaging_api.MessageInterface
+	alerts     []string
+}
+
+// newReplyComposer starts a reply batch for replyToken
+func (h *LineWebhookHandler) newReplyComposer(replyToken, userID string) *ReplyComposer {
+	return &ReplyComposer{h: h, replyToken: replyToken, userID: userID}
+}
+
+// Add queues a message
+func (c *ReplyComposer) Add(msg messaging_api.MessageInterface) *ReplyComposer {
+	c.messages = append(c.messages, msg)
+	return c
+}
+
+// AddText queues a text message
+func (c *ReplyComposer) AddText(text string) *ReplyComposer {
+	return c.Add(messaging_api.TextMessage{Text: text})
+}
+
+// AddAlert queues a low-priority notice (budget alert etc.), merged with other alerts on send
+func (c *ReplyComposer) AddAlert(text string) *ReplyComposer {
+	if text != "" {
+		c.alerts = append(c.alerts, text)
+	}
+	return c
+}
+
+// Len returns number of queued messages before merging
+func (c *ReplyComposer) Len() int {
+	return len(c.messages) + len(c.alerts)
+}
+
+// Send replies with up to 5 messages; overflow is pushed after the reply succeeds when quota
+// allows, otherwise folded into the last text message so nothing important is silently lost
+func (c *ReplyComposer) Send() error {
+	messages := append([]messaging_api.MessageInterface{}, c.messages...)
+	if len(c.alerts) > 0 {
+		messages = append(messages, messaging_api.TextMessage{Text: joinTexts(c.alerts)})
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	reply := messages
+	var overflow []messaging_api.MessageInterface
+	if len(messages) > maxReplyMessages {
+		if c.userID != "" && c.h.canPush() {
+			reply, overflow = messages[:maxReplyMessages], messages[maxReplyMessages:]
+		} else {
+			reply = foldOverflow(messages)
+		}
+	}
+
+	_, err := c.h.sendReply(&messaging_api.ReplyMessageRequest{
+		ReplyToken: c.replyToken,
+		Messages:   reply,
+	})
+	if err != nil {
+		log.Printf("Failed to send composed reply: %v", err)
+		return err
+	}
+	// The rest follows only a delivered reply, so it never arrives without the start of the answer
+	if len(overflow) > 0 {
+		c.pushOverflow(overflow)
+	}
+	return nil
+}
+
+// pushOverflow sends messages that didn't fit the reply (5 per push)
+func (c *ReplyComposer) pushOverflow(overflow []messaging_api.MessageInterface) {
+	for start := 0; start < len(overflow); start += maxReplyMessages {
+		end := start + maxReplyMessages
+		if end > len(overflow) {
+			end = len(overflow)
+		}
+		if _, err := c.h.bot.PushMessage(&messaging_api.PushMessageRequest{
+			To:       c.userID,
+			Messages: overflow[start:end],
+		}, ""); err != nil {
+			log.Printf("Failed to push overflow messages: %v", err)
+			return
+		}
+		c.h.recordPush()
+	}
+}
+
+// foldOverflow keeps the first 4 messages and merges the rest into one text in the 5th slot
+// Rich messages in the tail can't be merged, so they are counted in a note instead
+func foldOverflow(messages []messaging_api.MessageInterface) []messaging_api.MessageInterface {
+	reply := append([]messaging_api.MessageInterface{}, messages[:maxReplyMessages-1]...)
+
+	var texts []string
+	dropped := 0
+	for _, msg := range messages[maxReplyMessages-1:] {
+		if text, ok := messageText(msg); ok {
+			texts = append(texts, text)
+		} else {
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		texts = append(texts, fmt.Sprintf("(มีอีก %d ข้อความที่แสดงไม่ได้ กรุณาพิมพ์ถามอีกครั้งค่ะ)", dropped))
+	}
+	return append(reply, messaging_api.TextMessage{Text: joinTexts(texts)})
+}
+
+// messageText returns text of a plain text message (with or without pointer)
+func messageText(msg messaging_api.MessageInterface) (string, bool) {
+	switch m := msg.(type) {
+	case messaging_api.TextMessage:
+		return m.Text, true
+	case *messaging_api.TextMessage:
+		return m.Text, true
+	}
+	return "", false
+}
+
+// joinTexts joins texts with blank lines, truncated to LINE's text limit
+func joinTexts(texts []string) string {
+	joined := strings.Join(texts, "\n\n")
+	if runes := []rune(joined); len(runes) > maxTextLength {
+		joined = string(runes[:maxTextLength-1]) + "…"
+	}
+	return joined
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+)
+
+// replyMetrics counts reply outcomes, exposed on /health to see how often tokens expire
+type replyMetrics struct {
+	replies         atomic.Int64
+	tokenFailures   atomic.Int64
+	pushFallbacks   atomic.Int64
+	fallbackSkipped atomic.Int64 // push disabled or quota low
+}
+
+// ReplyMetrics returns reply counters since start
+func (h *LineWebhookHandler) ReplyMetrics() map[string]int64 {
+	return map[string]int64{
+		"replies":          h.metrics.replies.Load(),
+		"token_failures":   h.metrics.tokenFailures.Load(),
+		"push_fallbacks":   h.metrics.pushFallbacks.Load(),
+		"fallback_skipped": h.metrics.fallbackSkipped.Load(),
+	}
+}
+
+// replyTokenExpiry is when a rejected reply token is taken as expired, a little under LINE's one minute
+// so a small clock skew with the event timestamp doesn't hide an expiry
+const replyTokenExpiry = 50 * time.Second
+
+// replyOwner is who sent the event of a reply token, when, and whether a reply already went through
+type replyOwner struct {
+	userID     string
+	receivedAt time.Time
+	used       atomic.Bool
+}
+
+// isReplyTokenError reports whether LINE rejected the reply token (expired after slow AI or already used)
+func isReplyTokenError(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "invalid reply token")
+}
+
+// ReplyTokenExpired reports whether a reply rejected with err failed because its token expired
+// LINE answers "Invalid reply token" for a token already used too; pushing then would send the
+// messages twice, so only an unused token older than replyTokenExpiry counts
+func ReplyTokenExpired(err error, receivedAt time.Time, used bool, now time.Time) bool {
+	return isReplyTokenError(err) && !used && now.Sub(receivedAt) >= replyTokenExpiry
+}
+
+// rememberReplyToken maps reply token to user so a failed reply can fall back to push
+// receivedAt is the event timestamp the token's lifetime counts from
+func (h *LineWebhookHandler) rememberReplyToken(replyToken, userID string, receivedAt time.Time) {
+	if replyToken != "" && userID != "" {
+		h.replyOwners.Store(replyToken, &replyOwner{userID: userID, receivedAt: receivedAt})
+	}
+}
+
+// forgetReplyToken drops token mapping after the event is handled
+func (h *LineWebhookHandler) forgetReplyToken(replyToken string) {
+	h.replyOwners.Delete(replyToken)
+}
+
+// sendReply replies and, when the token expired, pushes the same messages instead
+// Push only happens when enabled (LINE_PUSH_ENABLED) and monthly quota allows; a token
+// rejected because it was already used is not pushed
+func (h *LineWebhookHandler) sendReply(req *messaging_api.ReplyMessageRequest) (*messaging_api.ReplyMessageResponse, error) {
+	h.metrics.replies.Add(1)
+	resp, err := h.bot.ReplyMessage(req)
+	stored, known := h.replyOwners.Load(req.ReplyToken)
+	if err == nil && known {
+		stored.(*replyOwner).used.Store(true)
+	}
+	if !isReplyTokenError(err) {
+		return resp, err
+	}
+	h.metrics.tokenFailures.Add(1)
+
+	if !known {
+		h.metrics.fallbackSkipped.Add(1)
+		log.Printf("Reply token failed, push fallback skipped: %v", err)
+		return resp, err
+	}
+	owner := stored.(*replyOwner)
+	if !ReplyTokenExpired(err, owner.receivedAt, owner.used.Load(), time.Now()) {
+		h.metrics.fallbackSkipped.Add(1)
+		log.Printf("Reply token rejected before expiry (already used), push fallback skipped: %v", err)
+		return resp, err
+	}
+	if !h.canPush() {
+		h.metrics.fallbackSkipped.Add(1)
+		log.Printf("Reply token failed, push fallback skipped: %v", err)
+		return resp, err
+	}
+
+	if _, pushErr := h.bot.PushMessage(&messaging_api.PushMessageRequest{
+		To:       owner.userID,
+		Messages: req.Messages,
+	}, ""); pushErr != nil {
+		log.Printf("Push fallback failed: %v", pushErr)
+		return resp, err
+	}
+	h.recordPush()
+	owner.used.Store(true)
+	h.metrics.pushFallbacks.Add(1)
+	log.Printf("Reply token failed, sent as push instead")
+	return &messaging_api.ReplyMessageResponse{}, nil
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/satisatang/backend/services"
+)
+
+// reviewQueueLimit is number of entries in the review carousel (one bubble is left for "confirm all")
+const reviewQueueLimit = 9
+
+// isReviewQueueCommand matches "รอตรวจ" / "รายการรอตรวจ"
+func isReviewQueueCommand(text string) bool {
+	switch strings.ReplaceAll(strings.TrimSpace(text), " ", "") {
+	case "รอตรวจ", "รายการรอตรวจ", "ตรวจรายการ":
+		return true
+	}
+	return false
+}
+
+// replyReviewQueue shows auto-captured entries not yet confirmed, each with ✓/✏️/🗑️ and a confirm-all bubble
+func (h *LineWebhookHandler) replyReviewQueue(ctx context.Context, replyToken, userID string) {
+	results, total, err := h.mongo.GetUnreviewedTransactions(ctx, userID, reviewQueueLimit)
+	if err != nil {
+		log.Printf("Failed to get review queue: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถดึงรายการได้")
+		return
+	}
+	if total == 0 {
+		h.replyText(replyToken, "✅ ตรวจครบทุกรายการแล้วค่ะ ไม่มีรายการจากรูป/SMS ที่รอตรวจ")
+		return
+	}
+
+	var bubbles []interface{}
+	for _, r := range results {
+		bubble := h.recentTransactionBubble(r)
+		source := "📷 จากรูป"
+		if r.Transaction.Source == services.TransactionSourceSMS {
+			source = "📩 จาก SMS"
+		}
+		if r.Transaction.SlipStatus == services.SlipSuspicious {
+			source = "⚠️ สลิปน่าสงสัย"
+		}
+		header := bubble["header"].(map[string]interface{})
+		header["contents"] = append(header["contents"].([]interface{}),
+			map[string]interface{}{"type": "text", "text": source, "color": "#FFFFFF", "size": "xxs"})
+		bubble["footer"] = map[string]interface{}{
+			"type":    "box",
+			"layout":  "vertical",
+			"spacing": "sm",
+			"contents": []interface{}{
+				map[string]interface{}{
+					"type": "button", "style": "primary", "height": "sm", "color": "#27AE60",
+					"action": map[string]interface{}{
+						"type":        "postback",
+						"label":       "✓ ถูกต้อง",
+						"data":        fmt.Sprintf("action=review_ok&txid=%s&date=%s", r.Transaction.ID.Hex(), r.Date),
+						"displayText": "ถูกต้อง " + orDefault(r.Transaction.Description, r.Transaction.Category),
+					},
+				},
+				bubble["footer"],
+			},
+		}
+		bubbles = append(bubbles, bubble)
+	}
+
+	bubbles = append(bubbles, map[string]interface{}{
+		"type": "bubble",
+		"size": "kilo",
+		"body": map[string]interface{}{
+			"type": "box", "layout": "vertical", "paddingAll": "md", "spacing": "sm", "justifyContent": "center",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": fmt.Sprintf("🔍 รอตรวจ %d รายการ", total), "weight": "bold", "size": "md", "wrap": true},
+				map[string]interface{}{"type": "text", "text": "บันทึกอัตโนมัติจากรูปใบเสร็จหรือ SMS ตรวจแล้วถูกต้องกดยืนยันได้เลยค่ะ", "size": "xs", "color": "#888888", "wrap": true},
+			},
+		},
+		"footer": map[string]interface{}{
+			"type": "box", "layout": "vertical",
+			"contents": []interface{}{
+				map[string]interface{}{
+					"type": "button", "style": "primary", "height": "sm", "color": "#27AE60",
+					"action": map[string]interface{}{
+						"type":        "postback",
+						"label":       truncateLabel(fmt.Sprintf("✓ ยืนยันทั้งหมด %d", total), 20),
+						"data":        "action=review_all",
+						"displayText": "ยืนยันรายการทั้งหมด",
+					},
+				},
+			},
+		},
+	})
+
+	if !h.replyFlexFromAI(replyToken, bubbles, fmt.Sprintf("รอตรวจ %d รายการ", total)) {
+		h.replyText(replyToken, fmt.Sprintf("ยังไม่ได้ตรวจ %d รายการค่ะ", total))
+	}
+}
+
+// handleReviewConfirm confirms one entry (review_ok) or every unreviewed entry (review_all)
+func (h *LineWebhookHandler) handleReviewConfirm(ctx context.Context, replyToken, userID string, params map[string]string) {
+	if _, err := h.mongo.MarkTransactionsReviewed(ctx, userID, params["txid"], params["date"]); err != nil {
+		log.Printf("Failed to mark reviewed: %v", err)
+		h.replyText(replyToken, "ไม่สามารถยืนยันได้ กรุณาลองใหม่")
+		return
+	}
+	_, remaining, _ := h.mongo.GetUnreviewedTransactions(ctx, userID, 0)
+	if remaining == 0 {
+		h.replyText(replyToken, "✅ ยืนยันแล้วค่ะ ตรวจครบทุกรายการแล้ว")
+		return
+	}
+	h.replyText(replyToken, fmt.Sprintf("✅ ยืนยันแล้วค่ะ เหลือรอตรวจอีก %d รายการ พิมพ์ \"รอตรวจ\" เพื่อดูต่อ", remaining))
+}
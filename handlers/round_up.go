@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/satisatang/backend/services"
+)
+
+// roundUpSetPattern matches "ปัดเศษ 10" / "ปัดเศษ 100 เข้าออมสิน"
+var roundUpSetPattern = regexp.MustCompile(`^ปัดเศษ\s*(10|100)\s*(?:บาท)?(?:\s*เข้า\s*(.+))?$`)
+
+// roundUpCommand is a parsed round-up savings command
+type roundUpCommand struct {
+	Action string // "set", "off", "show"
+	Step   int
+	Bank   string // savings account, "" = default
+}
+
+// parseRoundUpCommand parses round-up savings commands (no AI)
+func parseRoundUpCommand(text string) (roundUpCommand, bool) {
+	text = strings.TrimSpace(text)
+	switch text {
+	case "ปัดเศษ", "เศษสะสม", "ดูเศษสะสม":
+		return roundUpCommand{Action: "show"}, true
+	case "ยกเลิกปัดเศษ", "ปิดปัดเศษ":
+		return roundUpCommand{Action: "off"}, true
+	}
+	if m := roundUpSetPattern.FindStringSubmatch(text); m != nil {
+		step, _ := strconv.Atoi(m[1])
+		return roundUpCommand{Action: "set", Step: step, Bank: strings.TrimSpace(m[2])}, true
+	}
+	return roundUpCommand{}, false
+}
+
+// handleRoundUpCommand turns the round-up rule on/off or shows the pot
+func (h *LineWebhookHandler) handleRoundUpCommand(ctx context.Context, replyToken, userID string, cmd roundUpCommand) {
+	switch cmd.Action {
+	case "set":
+		bank := ""
+		if cmd.Bank != "" {
+			var banks, cards []string
+			var aliases []services.AccountAlias
+			if profile, err := h.mongo.GetUserProfile(ctx, userID); err == nil {
+				banks, cards, aliases = profile.Banks, profile.CreditCards, profile.AccountAliases
+			}
+			account, ok := services.ParsePaymentTarget(cmd.Bank, banks, cards, aliases)
+			if !ok || account.UseType != 2 {
+				h.replyText(replyToken, "บัญชีออมต้องเป็นบัญชีธนาคารค่ะ ตัวอย่าง: ปัดเศษ 10 เข้าออมสิน")
+				return
+			}
+			bank = account.BankName
+		}
+		if err := h.mongo.SetRoundUp(ctx, userID, cmd.Step, bank); err != nil {
+			log.Printf("Failed to set round-up: %v", err)
+			h.replyText(replyToken, "ไม่สามารถตั้งค่าได้ กรุณาลองใหม่")
+			return
+		}
+		h.replyText(replyToken, fmt.Sprintf("🐷 เปิดปัดเศษแล้วค่ะ รายจ่ายทุกรายการจะปัดขึ้นเป็นหลัก %d แล้วสะสมส่วนต่างไว้\nเช่น จ่าย 47 บาท เก็บเศษ %s บาท\nดูยอด: เศษสะสม • ปิด: ยกเลิกปัดเศษ",
+			cmd.Step, formatNumber(services.RoundUpAmount(47, cmd.Step))))
+
+	case "off":
+		if err := h.mongo.SetRoundUp(ctx, userID, 0, ""); err != nil {
+			log.Printf("Failed to turn off round-up: %v", err)
+			h.replyText(replyToken, "ไม่สามารถตั้งค่าได้ กรุณาลองใหม่")
+			return
+		}
+		h.replyText(replyToken, "ปิดปัดเศษแล้วค่ะ")
+
+	default:
+		h.replyRoundUpPot(ctx, replyToken, userID)
+	}
+}
+
+// replyRoundUpPot shows collected round-ups with a one-tap transfer into savings
+func (h *LineWebhookHandler) replyRoundUpPot(ctx context.Context, replyToken, userID string) {
+	settings, err := h.mongo.GetUserSettings(ctx, userID)
+	if err != nil {
+		h.replyText(replyToken, "ไม่สามารถดึงข้อมูลได้ กรุณาลองใหม่")
+		return
+	}
+	if settings.RoundUpStep <= 0 {
+		h.replyText(replyToken, "ยังไม่ได้เปิดปัดเศษค่ะ\nพิมพ์ \"ปัดเศษ 10\" หรือ \"ปัดเศษ 100 เข้าออมสิน\"")
+		return
+	}
+	pot, err := h.mongo.GetRoundUpPot(ctx, settings)
+	if err != nil {
+		log.Printf("Failed to get round-up pot: %v", err)
+		h.replyText(replyToken, "ไม่สามารถดึงข้อมูลได้ กรุณาลองใหม่")
+		return
+	}
+
+	contents := []interface{}{
+		map[string]interface{}{"type": "text", "text": "🐷 เศษสะสม", "size": "sm", "color": "#888888"},
+		map[string]interface{}{"type": "text", "text": formatNumber(pot) + " บาท", "size": "xl", "weight": "bold", "color": "#E67E22"},
+		map[string]interface{}{"type": "text", "text": fmt.Sprintf("ปัดขึ้นหลัก %d ตั้งแต่ %s • โอนแล้ว %s บาท", settings.RoundUpStep, formatThaiShortDate(settings.RoundUpSince), formatNumber(settings.RoundUpSaved)), "size": "xxs", "color": "#888888", "wrap": true},
+	}
+	bubble := map[string]interface{}{
+		"type": "bubble",
+		"size": "kilo",
+		"body": map[string]interface{}{"type": "box", "layout": "vertical", "paddingAll": "md", "contents": contents},
+	}
+	source, ok := h.transferSource(ctx, userID, pot, settings.RoundUpBank)
+	if pot >= 1 && ok {
+		bubble["footer"] = map[string]interface{}{
+			"type": "box", "layout": "vertical",
+			"contents": []interface{}{
+				map[string]interface{}{
+					"type": "button", "style": "primary", "color": "#E67E22", "height": "sm",
+					"action": map[string]interface{}{
+						"type":  "postback",
+						"label": truncateLabel(fmt.Sprintf("โอน %s เข้า%s", formatNumber(pot), settings.RoundUpBank), 20),
+						"data":  "action=roundup_transfer",
+					},
+				},
+				map[string]interface{}{"type": "text", "text": "จาก " + getPaymentName(source.UseType, source.BankName, source.CreditCardName), "size": "xxs", "color": "#888888", "align": "center", "margin": "sm"},
+			},
+		}
+	}
+	if !h.replyFlexFromAI(replyToken, bubble, fmt.Sprintf("เศษสะสม %s บาท", formatNumber(pot))) {
+		h.replyText(replyToken, fmt.Sprintf("🐷 เศษสะสม %s บาท", formatNumber(pot)))
+	}
+}
+
+// transferSource picks the account a transfer is paid from (round-ups, card bills): richest bank other
+// than savingsBank holding amount, else cash
+func (h *LineWebhookHandler) transferSource(ctx context.Context, userID string, amount float64, savingsBank string) (services.TransferEntry, bool) {
+	balances, err := h.mongo.GetBalanceByPaymentType(ctx, userID)
+	if err != nil {
+		return services.TransferEntry{}, false
+	}
+	var best *services.PaymentBalance
+	for i, b := range balances {
+		if b.UseType != 2 || b.BankName == savingsBank || b.Balance < amount {
+			continue
+		}
+		if best == nil || b.Balance > best.Balance {
+			best = &balances[i]
+		}
+	}
+	if best != nil {
+		return services.TransferEntry{UseType: 2, BankName: best.BankName}, true
+	}
+	return services.TransferEntry{UseType: 0}, true
+}
+
+// handleRoundUpTransfer moves the whole pot into savings (pot is recomputed, so a second tap moves nothing)
+func (h *LineWebhookHandler) handleRoundUpTransfer(ctx context.Context, replyToken, userID string) {
+	settings, err := h.mongo.GetUserSettings(ctx, userID)
+	if err != nil || settings.RoundUpStep <= 0 {
+		h.replyText(replyToken, "ยังไม่ได้เปิดปัดเศษค่ะ")
+		return
+	}
+	pot, err := h.mongo.GetRoundUpPot(ctx, settings)
+	if err != nil || pot < 1 {
+		h.replyText(replyToken, "ยังไม่มีเศษสะสมให้โอนค่ะ")
+		return
+	}
+	source, _ := h.transferSource(ctx, userID, pot, settings.RoundUpBank)
+	if err := h.mongo.MoveRoundUpPot(ctx, userID, pot, source, settings.RoundUpBank); err != nil {
+		if msg, locked := periodLockedText(err); locked {
+			h.replyText(replyToken, msg)
+			return
+		}
+		log.Printf("Failed to move round-up pot: %v", err)
+		h.replyText(replyToken, "ไม่สามารถบันทึกการโอนได้ กรุณาลองใหม่")
+		return
+	}
+	h.replyText(replyToken, fmt.Sprintf("✅ บันทึกโอน %s บาท จาก%s เข้า%s แล้วค่ะ\nอย่าลืมโอนจริงในแอปธนาคารด้วยนะคะ",
+		formatNumber(pot), getPaymentName(source.UseType, source.BankName, source.CreditCardName), settings.RoundUpBank))
+}
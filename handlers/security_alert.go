@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+	"github.com/satisatang/backend/services"
+)
+
+// AlertSecurity reports a suspicious security event and pushes it to admins
+// Registered as a security alert hook, so it only queues the work and returns
+func (h *LineWebhookHandler) AlertSecurity(event services.SecurityEvent, reason string) {
+	services.GoSafe("security.alert", func() {
+		services.ReportError("security.alert", event.LineID, fmt.Errorf("security alert (%s): %s %s", reason, event.Event, event.Detail))
+		text := fmt.Sprintf("⚠️ แจ้งเตือนความปลอดภัย (%s)\n%s\nผู้ใช้: %s", reason, event.Event, event.LineID)
+		if event.ClientIP != "" {
+			text += "\nIP: " + event.ClientIP
+		}
+		if event.Detail != "" {
+			text += "\n" + event.Detail
+		}
+		for adminID := range h.admins {
+			h.pushMessages(adminID, &messaging_api.TextMessage{Text: text})
+		}
+	})
+}
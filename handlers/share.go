@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satisatang/backend/services"
+)
+
+//go:embed share_report.html
+var shareReportPage string
+
+// shareReportTemplate renders the read-only report page (html/template escapes user text)
+var shareReportTemplate = template.Must(template.New("share").Funcs(template.FuncMap{
+	"money": formatNumber,
+}).Parse(shareReportPage))
+
+// shareCategory is one expense category row on the report page
+type shareCategory struct {
+	Category string
+	Amount   float64
+	Percent  float64
+}
+
+// shareReportData is the report page model
+type shareReportData struct {
+	Title      string
+	Range      string
+	ExpiresAt  string
+	Summary    *services.PeriodSummary
+	Categories []shareCategory
+}
+
+// ShareHandler serves read-only report pages of share links (/s/:token)
+type ShareHandler struct {
+	mongo *services.MongoDBService
+}
+
+// NewShareHandler creates a new share link handler
+func NewShareHandler(mongo *services.MongoDBService) *ShareHandler {
+	return &ShareHandler{mongo: mongo}
+}
+
+// HandleShare renders the report of a share link, enforcing expiry and revocation
+func (h *ShareHandler) HandleShare(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Robots-Tag", "noindex, nofollow")
+
+	link, err := h.mongo.OpenShareLink(ctx, c.Param("token"))
+	switch {
+	case errors.Is(err, services.ErrShareNotFound):
+		h.mongo.LogSecurityEvent(ctx, services.SecurityEvent{Event: services.SecurityAccessDenied, Detail: "share token not found", ClientIP: c.ClientIP()})
+		c.String(http.StatusNotFound, "ไม่พบรายงานค่ะ")
+		return
+	case errors.Is(err, services.ErrShareExpired):
+		c.String(http.StatusGone, "ลิงก์นี้หมดอายุหรือถูกยกเลิกแล้วค่ะ")
+		return
+	case err != nil:
+		log.Printf("Failed to open share link: %v", err)
+		c.String(http.StatusInternalServerError, "เกิดข้อผิดพลาด กรุณาลองใหม่")
+		return
+	}
+
+	summary, err := h.mongo.GetRangeSummary(ctx, link.LineID, link.Label, link.From, link.To)
+	if err != nil {
+		log.Printf("Failed to get shared summary: %v", err)
+		c.String(http.StatusInternalServerError, "เกิดข้อผิดพลาด กรุณาลองใหม่")
+		return
+	}
+
+	data := shareReportData{
+		Title:     services.ReportOwnerTitle(h.mongo.GetDisplayName(ctx, link.LineID)),
+		Range:     fmt.Sprintf("%s - %s", formatThaiShortDate(link.From), formatThaiShortDate(link.To)),
+		ExpiresAt: link.ExpiresAt.Format("02/01/2006 15:04"),
+		Summary:   summary,
+	}
+	for _, ca := range summary.ExpenseByCategory {
+		percent := 0.0
+		if summary.TotalExpense > 0 {
+			percent = ca.Amount / summary.TotalExpense * 100
+		}
+		data.Categories = append(data.Categories, shareCategory{Category: ca.Category, Amount: ca.Amount, Percent: percent})
+	}
+
+	var buf bytes.Buffer
+	if err := shareReportTemplate.Execute(&buf, data); err != nil {
+		log.Printf("Failed to render share report: %v", err)
+		c.String(http.StatusInternalServerError, "เกิดข้อผิดพลาด กรุณาลองใหม่")
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// handleShareCommand creates, lists or revokes read-only report links
+func (h *LineWebhookHandler) handleShareCommand(ctx context.Context, replyToken, userID string, cmd services.ShareCommand) {
+	if h.publicBaseURL == "" {
+		h.replyText(replyToken, "ระบบยังไม่เปิดใช้ลิงก์แชร์ค่ะ")
+		return
+	}
+
+	switch cmd.Action {
+	case "create":
+		link, err := h.mongo.CreateShareLink(ctx, userID, cmd.Label, cmd.From, cmd.To, cmd.Days)
+		if err != nil {
+			log.Printf("Failed to create share link: %v", err)
+			h.replyText(replyToken, "ไม่สามารถสร้างลิงก์ได้ กรุณาลองใหม่")
+			return
+		}
+		h.mongo.LogSecurityEvent(ctx, services.SecurityEvent{LineID: userID, Event: services.SecurityTokenIssued, Detail: fmt.Sprintf("share link %s-%s", cmd.From, cmd.To)})
+		h.replyText(replyToken, fmt.Sprintf("🔗 ลิงก์สรุป%s (ดูได้อย่างเดียว ไม่ต้องมี LINE)\n%s/s/%s\n\nหมดอายุ %s\nยกเลิกได้ทุกเมื่อ: พิมพ์ \"ยกเลิกแชร์\" หรือ \"ลิงก์แชร์\"",
+			link.Label, h.publicBaseURL, link.Token, link.ExpiresAt.Format("02/01/2006 15:04")))
+
+	case "revoke":
+		count, err := h.mongo.RevokeShareLinks(ctx, userID, "")
+		if err != nil {
+			log.Printf("Failed to revoke share links: %v", err)
+			h.replyText(replyToken, "ไม่สามารถยกเลิกลิงก์ได้ กรุณาลองใหม่")
+			return
+		}
+		if count == 0 {
+			h.replyText(replyToken, "ไม่มีลิงก์แชร์ที่เปิดอยู่ค่ะ")
+			return
+		}
+		h.replyText(replyToken, fmt.Sprintf("ยกเลิกลิงก์แชร์ %d ลิงก์แล้วค่ะ", count))
+
+	default:
+		h.replyShareLinks(ctx, replyToken, userID)
+	}
+}
+
+// replyShareLinks lists open share links with a revoke button each
+func (h *LineWebhookHandler) replyShareLinks(ctx context.Context, replyToken, userID string) {
+	links, err := h.mongo.GetActiveShareLinks(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to get share links: %v", err)
+		h.replyText(replyToken, "ไม่สามารถดึงข้อมูลได้ กรุณาลองใหม่")
+		return
+	}
+	if len(links) == 0 {
+		h.replyText(replyToken, "ไม่มีลิงก์แชร์ที่เปิดอยู่ค่ะ\nสร้างใหม่: แชร์สรุปเดือนนี้")
+		return
+	}
+
+	contents := []interface{}{}
+	for i, link := range links {
+		if i >= 10 {
+			break
+		}
+		if i > 0 {
+			contents = append(contents, map[string]interface{}{"type": "separator", "margin": "md"})
+		}
+		contents = append(contents, map[string]interface{}{
+			"type": "box", "layout": "horizontal", "margin": "md",
+			"contents": []interface{}{
+				map[string]interface{}{
+					"type": "box", "layout": "vertical", "flex": 3,
+					"contents": []interface{}{
+						map[string]interface{}{"type": "text", "text": "สรุป" + link.Label, "size": "sm", "weight": "bold"},
+						map[string]interface{}{"type": "text", "text": fmt.Sprintf("เปิดดู %d ครั้ง • หมด %s", link.Views, link.ExpiresAt.Format("02/01 15:04")), "size": "xxs", "color": "#888888"},
+					},
+				},
+				map[string]interface{}{
+					"type": "button", "style": "secondary", "height": "sm", "flex": 2,
+					"action": map[string]interface{}{"type": "postback", "label": "ยกเลิก", "data": "action=share_revoke&token=" + link.Token},
+				},
+			},
+		})
+	}
+	flex := map[string]interface{}{
+		"type": "bubble",
+		"size": "kilo",
+		"header": map[string]interface{}{
+			"type": "box", "layout": "vertical", "backgroundColor": "#1E88E5", "paddingAll": "md",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "🔗 ลิงก์แชร์ที่เปิดอยู่", "weight": "bold", "color": "#FFFFFF"},
+			},
+		},
+		"body": map[string]interface{}{"type": "box", "layout": "vertical", "paddingAll": "md", "contents": contents},
+	}
+	if !h.replyFlexFromAI(replyToken, flex, fmt.Sprintf("ลิงก์แชร์ %d ลิงก์", len(links))) {
+		h.replyText(replyToken, fmt.Sprintf("มีลิงก์แชร์เปิดอยู่ %d ลิงก์ พิมพ์ \"ยกเลิกแชร์\" เพื่อยกเลิกทั้งหมด", len(links)))
+	}
+}
+
+// handleShareRevoke revokes one share link from the list button
+// An empty token would revoke every link, so it's refused before the update
+func (h *LineWebhookHandler) handleShareRevoke(ctx context.Context, replyToken, userID string, params map[string]string) {
+	if params["token"] == "" {
+		h.replyText(replyToken, "ไม่สามารถยกเลิกลิงก์ได้ กรุณาลองใหม่")
+		return
+	}
+	count, err := h.mongo.RevokeShareLinks(ctx, userID, params["token"])
+	if err != nil {
+		log.Printf("Failed to revoke share link: %v", err)
+		h.replyText(replyToken, "ไม่สามารถยกเลิกลิงก์ได้ กรุณาลองใหม่")
+		return
+	}
+	if count == 0 {
+		h.replyText(replyToken, "ลิงก์นี้หมดอายุหรือถูกยกเลิกไปแล้วค่ะ")
+		return
+	}
+	h.replyText(replyToken, "ยกเลิกลิงก์แล้วค่ะ คนที่มีลิงก์จะเปิดดูไม่ได้อีก")
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satisatang/backend/services"
+)
+
+// SheetsHandler handles Google OAuth pages for Sheets sync
+type SheetsHandler struct {
+	sheets *services.SheetsService
+}
+
+// NewSheetsHandler creates a new Sheets OAuth handler
+func NewSheetsHandler(sheets *services.SheetsService) *SheetsHandler {
+	return &SheetsHandler{sheets: sheets}
+}
+
+// HandleConnect redirects to Google consent page (state was issued in LINE chat)
+func (h *SheetsHandler) HandleConnect(c *gin.Context) {
+	state := c.Query("state")
+	if state == "" {
+		c.String(http.StatusBadRequest, "ลิงก์ไม่ถูกต้อง")
+		return
+	}
+	c.Redirect(http.StatusFound, h.sheets.AuthURL(state))
+}
+
+// HandleCallback completes OAuth, creates/rebuilds the sheet and shows result page
+func (h *SheetsHandler) HandleCallback(c *gin.Context) {
+	if errParam := c.Query("error"); errParam != "" {
+		c.String(http.StatusOK, "ยกเลิกการเชื่อมต่อ Google Sheets แล้วค่ะ กลับไปที่ LINE ได้เลย")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
+	defer cancel()
+
+	conn, err := h.sheets.CompleteConnect(ctx, c.Query("state"), c.Query("code"))
+	if err != nil {
+		log.Printf("Failed to connect Google Sheets: %v", err)
+		c.String(http.StatusBadRequest, "เชื่อมต่อไม่สำเร็จ: "+err.Error())
+		return
+	}
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.String(http.StatusOK, fmt.Sprintf(`<html><body style="font-family:sans-serif;text-align:center;padding:40px">
+<h2>✅ เชื่อมต่อ Google Sheets แล้ว</h2>
+<p>รายการใหม่จะถูกเพิ่มลงชีตอัตโนมัติ</p>
+<p><a href="%s">เปิดชีต</a></p>
+<p>กลับไปที่ LINE ได้เลยค่ะ</p>
+</body></html>`, conn.SpreadsheetURL()))
+}
+
+// parseSheetsCommand parses "sheets [เชื่อม|ตั้ง <url>|สร้างใหม่|ยกเลิก]" (also "ชีต")
+func parseSheetsCommand(text string) (action, arg string, ok bool) {
+	fields := strings.Fields(strings.TrimSpace(text))
+	if len(fields) == 0 {
+		return "", "", false
+	}
+	first := strings.ToLower(fields[0])
+	if first != "sheets" && first != "ชีต" && first != "googlesheets" {
+		return "", "", false
+	}
+	if len(fields) == 1 {
+		return "status", "", true
+	}
+	switch strings.ToLower(fields[1]) {
+	case "เชื่อม", "connect":
+		return "connect", "", true
+	case "ตั้ง", "set":
+		if len(fields) > 2 {
+			arg = fields[2]
+		}
+		return "select", arg, true
+	case "สร้างใหม่", "rebuild":
+		return "rebuild", "", true
+	case "ยกเลิก", "disconnect":
+		return "disconnect", "", true
+	}
+	return "status", "", true
+}
+
+// handleSheetsCommand manages Google Sheets sync from chat
+func (h *LineWebhookHandler) handleSheetsCommand(ctx context.Context, replyToken, userID, action, arg string) {
+	if h.sheets == nil {
+		h.replyText(replyToken, "ระบบยังไม่เปิดใช้ Google Sheets ค่ะ")
+		return
+	}
+
+	switch action {
+	case "connect":
+		state, err := h.sheets.CreateConnectState(ctx, userID)
+		if err != nil {
+			log.Printf("Failed to create sheets state: %v", err)
+			h.replyText(replyToken, "ขออภัยค่ะ เกิดข้อผิดพลาด กรุณาลองใหม่")
+			return
+		}
+		// Google blocks OAuth inside in-app browsers, so open in external browser
+		link := fmt.Sprintf("%s/sheets/connect?state=%s&openExternalBrowser=1", h.publicBaseURL, url.QueryEscape(state))
+		flex := map[string]interface{}{
+			"type": "bubble",
+			"size": "kilo",
+			"body": map[string]interface{}{
+				"type": "box", "layout": "vertical", "spacing": "sm",
+				"contents": []interface{}{
+					map[string]interface{}{"type": "text", "text": "📗 เชื่อม Google Sheets", "weight": "bold", "size": "md"},
+					map[string]interface{}{"type": "text", "text": "รายการใหม่จะถูกเพิ่มลงชีตอัตโนมัติ ลิงก์ใช้ได้ 15 นาที", "size": "xs", "color": "#888888", "wrap": true},
+				},
+			},
+			"footer": map[string]interface{}{
+				"type": "box", "layout": "vertical",
+				"contents": []interface{}{
+					map[string]interface{}{
+						"type": "button", "style": "primary", "color": "#0F9D58", "height": "sm",
+						"action": map[string]interface{}{"type": "uri", "label": "เชื่อมต่อ", "uri": link},
+					},
+				},
+			},
+		}
+		if !h.replyFlexFromAI(replyToken, flex, "เชื่อม Google Sheets") {
+			h.replyText(replyToken, "เปิดลิงก์นี้เพื่อเชื่อม Google Sheets:\n"+link)
+		}
+
+	case "select":
+		spreadsheetID := services.ParseSpreadsheetID(arg)
+		if spreadsheetID == "" {
+			h.replyText(replyToken, "กรุณาวางลิงก์ชีต เช่น\nsheets ตั้ง https://docs.google.com/spreadsheets/d/xxxx/edit")
+			return
+		}
+		count, err := h.sheets.SelectSpreadsheet(ctx, userID, spreadsheetID)
+		if err != nil {
+			h.replyText(replyToken, "ตั้งชีตไม่สำเร็จ: "+err.Error())
+			return
+		}
+		h.replyText(replyToken, fmt.Sprintf("📗 เปลี่ยนชีตแล้วค่ะ เขียนประวัติ %d รายการเรียบร้อย", count))
+
+	case "rebuild":
+		count, err := h.sheets.Rebuild(ctx, userID)
+		if err != nil {
+			h.replyText(replyToken, "สร้างชีตใหม่ไม่สำเร็จ: "+err.Error())
+			return
+		}
+		h.replyText(replyToken, fmt.Sprintf("📗 สร้างชีตใหม่จากประวัติแล้วค่ะ (%d รายการ)", count))
+
+	case "disconnect":
+		if err := h.mongo.DeleteSheetsConnection(ctx, userID); err != nil {
+			h.replyText(replyToken, "ยกเลิกไม่สำเร็จ กรุณาลองใหม่")
+			return
+		}
+		h.replyText(replyToken, "ยกเลิกการเชื่อม Google Sheets แล้วค่ะ (ข้อมูลในชีตยังอยู่)")
+
+	default:
+		conn, err := h.mongo.GetSheetsConnection(ctx, userID)
+		if err != nil || conn == nil {
+			h.replyText(replyToken, "ยังไม่ได้เชื่อม Google Sheets ค่ะ\nพิมพ์ \"sheets เชื่อม\" เพื่อเริ่ม")
+			return
+		}
+		status := "🟢 ซิงก์อยู่"
+		if conn.LastError != "" {
+			status = "⚠️ ซิงก์ล่าสุดผิดพลาด: " + truncateLabel(conn.LastError, 60)
+		}
+		h.replyText(replyToken, fmt.Sprintf("📗 Google Sheets\n%s\n%s\n\nคำสั่ง: sheets ตั้ง <ลิงก์ชีต> | sheets สร้างใหม่ | sheets ยกเลิก", status, conn.SpreadsheetURL()))
+	}
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+	"github.com/satisatang/backend/services"
+)
+
+// childCommand is a parsed restricted-account command
+type childCommand struct {
+	Action string // "create", "link", "cap", "unlink", "pending"
+	Amount float64
+	Code   string
+}
+
+// parseChildCommand parses "ตั้งบัญชีเด็ก 500", "เชื่อมผู้ปกครอง 123456", "วงเงินเด็ก 800", "ยกเลิกบัญชีเด็ก", "รออนุมัติ" (no AI)
+func parseChildCommand(text string) (childCommand, bool) {
+	text = strings.TrimSpace(text)
+	switch text {
+	case "ยกเลิกบัญชีเด็ก":
+		return childCommand{Action: "unlink"}, true
+	case "รออนุมัติ", "คำขออนุมัติ":
+		return childCommand{Action: "pending"}, true
+	}
+	for prefix, action := range map[string]string{"ตั้งบัญชีเด็ก": "create", "วงเงินเด็ก": "cap"} {
+		if !strings.HasPrefix(text, prefix) {
+			continue
+		}
+		amount, ok := services.ParseThaiAmount(strings.TrimSpace(strings.TrimPrefix(text, prefix)))
+		if !ok || amount <= 0 {
+			return childCommand{}, false
+		}
+		return childCommand{Action: action, Amount: amount}, true
+	}
+	if strings.HasPrefix(text, "เชื่อมผู้ปกครอง") {
+		code := strings.TrimSpace(strings.TrimPrefix(text, "เชื่อมผู้ปกครอง"))
+		if code == "" {
+			return childCommand{}, false
+		}
+		return childCommand{Action: "link", Code: code}, true
+	}
+	return childCommand{}, false
+}
+
+// handleChildCommand links/unlinks child accounts, changes caps and lists pending approvals
+func (h *LineWebhookHandler) handleChildCommand(ctx context.Context, replyToken, userID string, cmd childCommand) {
+	switch cmd.Action {
+	case "create":
+		code, err := h.mongo.CreateChildLinkCode(ctx, userID, cmd.Amount)
+		if err != nil {
+			log.Printf("Failed to create child link code: %v", err)
+			h.replyText(replyToken, "ไม่สามารถสร้างรหัสได้ กรุณาลองใหม่")
+			return
+		}
+		h.replyText(replyToken, fmt.Sprintf("👨‍👧 รหัสเชื่อมบัญชีเด็ก: %s (ใช้ได้ 30 นาที)\nให้ลูกพิมพ์ในแชทของตัวเองว่า\n\"เชื่อมผู้ปกครอง %s\"\n\nรายการที่เกิน %s บาท จะรอคุณอนุมัติก่อนบันทึก", code, code, formatNumber(cmd.Amount)))
+
+	case "link":
+		parentID, spendingCap, err := h.mongo.LinkChildAccount(ctx, userID, cmd.Code)
+		if err != nil {
+			h.replyText(replyToken, err.Error())
+			return
+		}
+		h.mongo.LogSecurityEvent(ctx, services.SecurityEvent{LineID: userID, Event: services.SecurityAccountLink, Detail: "parent " + services.AnonymizeUserID(parentID)})
+		h.replyText(replyToken, fmt.Sprintf("✅ เชื่อมกับผู้ปกครองแล้วค่ะ\nรายการที่เกิน %s บาท ต้องรอผู้ปกครองอนุมัติก่อนบันทึก", formatNumber(spendingCap)))
+
+	case "cap":
+		n, err := h.mongo.SetChildrenSpendingCap(ctx, userID, cmd.Amount)
+		if err != nil {
+			log.Printf("Failed to set spending cap: %v", err)
+			h.replyText(replyToken, "ไม่สามารถเปลี่ยนวงเงินได้ กรุณาลองใหม่")
+			return
+		}
+		if n == 0 {
+			h.replyText(replyToken, "ยังไม่มีบัญชีเด็กค่ะ พิมพ์ \"ตั้งบัญชีเด็ก 500\" เพื่อเริ่ม")
+			return
+		}
+		h.replyText(replyToken, fmt.Sprintf("เปลี่ยนวงเงินต่อรายการของบัญชีเด็ก %d บัญชีเป็น %s บาทแล้วค่ะ", n, formatNumber(cmd.Amount)))
+
+	case "unlink":
+		n, err := h.mongo.UnlinkChildren(ctx, userID)
+		if err != nil {
+			log.Printf("Failed to unlink children: %v", err)
+			h.replyText(replyToken, "ไม่สามารถยกเลิกได้ กรุณาลองใหม่")
+			return
+		}
+		h.replyText(replyToken, fmt.Sprintf("ยกเลิกโหมดบัญชีเด็ก %d บัญชีแล้วค่ะ", n))
+
+	default:
+		approvals, err := h.mongo.GetPendingApprovals(ctx, userID)
+		if err != nil {
+			log.Printf("Failed to get approvals: %v", err)
+			h.replyText(replyToken, "ไม่สามารถดึงข้อมูลได้ กรุณาลองใหม่")
+			return
+		}
+		if len(approvals) == 0 {
+			h.replyText(replyToken, "ไม่มีรายการรออนุมัติค่ะ")
+			return
+		}
+		var bubbles []interface{}
+		for i := range approvals {
+			bubbles = append(bubbles, approvalBubble(&approvals[i]))
+		}
+		if !h.replyFlexFromAI(replyToken, bubbles, fmt.Sprintf("รออนุมัติ %d รายการ", len(approvals))) {
+			h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถแสดงรายการได้")
+		}
+	}
+}
+
+// holdForParentApproval keeps a child's batch above the cap out of the ledger until the parent approves
+// Returns false when the user is not restricted or nothing exceeds the cap
+// Saves are checked again in MongoDBService; this holds the whole batch as one request
+func (h *LineWebhookHandler) holdForParentApproval(ctx context.Context, replyToken, userID string, settings *services.UserSettings, txs []services.TransactionData) bool {
+	if !services.NeedsParentApproval(settings, txs) {
+		return false
+	}
+	approval, err := h.mongo.HoldForParentApproval(ctx, userID, settings, txs)
+	if err != nil {
+		log.Printf("Failed to create spending approval: %v", err)
+		h.replyText(replyToken, "ไม่สามารถส่งคำขออนุมัติได้ กรุณาลองใหม่")
+		return true
+	}
+	h.replyText(replyToken, h.notifyParentOfHold(approval, settings.SpendingCap))
+	return true
+}
+
+// notifyParentOfHold pushes a held request to the parent and returns the text for the child
+func (h *LineWebhookHandler) notifyParentOfHold(approval *services.SpendingApproval, spendingCap float64) string {
+	// Parent is notified by push only when enabled; otherwise they see it with "รออนุมัติ"
+	notified := false
+	if container, err := toFlexContainer(approvalBubble(approval)); err == nil {
+		notified = h.pushMessages(approval.ParentLineID, messaging_api.FlexMessage{
+			AltText:  fmt.Sprintf("คำขออนุมัติ %s บาท", formatNumber(approval.Total())),
+			Contents: container,
+		})
+	}
+	note := "ผู้ปกครองพิมพ์ \"รออนุมัติ\" เพื่อดูคำขอได้ค่ะ"
+	if notified {
+		note = "แจ้งผู้ปกครองแล้วค่ะ"
+	}
+	return fmt.Sprintf("⏳ รายการ %s บาท เกินวงเงิน %s บาท\nรอผู้ปกครองอนุมัติก่อนบันทึก\n%s",
+		formatNumber(approval.Total()), formatNumber(spendingCap), note)
+}
+
+// spendingCapText explains a save held (parent notified) or an edit refused by a child account's cap
+func (h *LineWebhookHandler) spendingCapText(err error) (string, bool) {
+	if !errors.Is(err, services.ErrSpendingCap) {
+		return "", false
+	}
+	var held *services.SpendingHeldError
+	if errors.As(err, &held) {
+		return h.notifyParentOfHold(held.Approval, held.Cap), true
+	}
+	return "⛔ แก้ยอดให้เกินวงเงินต่อรายการไม่ได้ค่ะ\nจดเป็นรายการใหม่เพื่อขออนุมัติจากผู้ปกครอง", true
+}
+
+// handleSpendingApproval lets the parent approve (save to the child's ledger) or reject a held batch
+func (h *LineWebhookHandler) handleSpendingApproval(ctx context.Context, replyToken, userID string, params map[string]string) {
+	approve := params["ok"] == "1"
+	approval, err := h.mongo.ResolveSpendingApproval(ctx, userID, params["id"], approve)
+	if err != nil {
+		h.replyText(replyToken, err.Error())
+		return
+	}
+
+	child := orDefault(approval.ChildName, "บัญชีเด็ก")
+	if !approve {
+		h.pushMessages(approval.ChildLineID, messaging_api.TextMessage{Text: fmt.Sprintf("❌ ผู้ปกครองไม่อนุมัติรายการ %s บาท", formatNumber(approval.Total()))})
+		h.replyText(replyToken, fmt.Sprintf("ไม่อนุมัติรายการของ %s แล้วค่ะ", child))
+		return
+	}
+
+	saved := h.mongo.SaveApprovedTransactions(ctx, approval)
+	h.pushMessages(approval.ChildLineID, messaging_api.TextMessage{Text: fmt.Sprintf("✅ ผู้ปกครองอนุมัติแล้ว บันทึก %d รายการ (%s บาท)", saved, formatNumber(approval.Total()))})
+	h.replyText(replyToken, fmt.Sprintf("อนุมัติและบันทึกรายการของ %s %d รายการแล้วค่ะ", child, saved))
+}
+
+// approvalBubble shows a held batch with approve/reject postbacks
+func approvalBubble(a *services.SpendingApproval) map[string]interface{} {
+	id := a.ID.Hex()
+	var rows []interface{}
+	rows = append(rows, map[string]interface{}{"type": "text", "text": orDefault(a.ChildName, "บัญชีเด็ก"), "weight": "bold", "size": "sm"})
+	for _, tx := range a.Transactions {
+		rows = append(rows, map[string]interface{}{
+			"type": "box", "layout": "horizontal", "margin": "sm",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": orDefault(tx.Description, tx.Category), "size": "xs", "flex": 3, "wrap": true},
+				map[string]interface{}{"type": "text", "text": formatNumber(tx.Amount) + " บาท", "size": "xs", "align": "end", "flex": 2},
+			},
+		})
+	}
+
+	return map[string]interface{}{
+		"type": "bubble",
+		"size": "kilo",
+		"header": map[string]interface{}{
+			"type":            "box",
+			"layout":          "vertical",
+			"backgroundColor": "#F39C12",
+			"paddingAll":      "sm",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "🙋 ขออนุมัติรายจ่าย", "color": "#FFFFFF", "weight": "bold", "size": "sm"},
+			},
+		},
+		"body": map[string]interface{}{
+			"type":       "box",
+			"layout":     "vertical",
+			"paddingAll": "md",
+			"contents":   rows,
+		},
+		"footer": map[string]interface{}{
+			"type":    "box",
+			"layout":  "horizontal",
+			"spacing": "sm",
+			"contents": []interface{}{
+				map[string]interface{}{
+					"type": "button", "style": "primary", "height": "sm", "color": "#27AE60",
+					"action": map[string]interface{}{"type": "postback", "label": "✅ อนุมัติ", "data": "action=cap_approve&ok=1&id=" + id, "displayText": "อนุมัติ"},
+				},
+				map[string]interface{}{
+					"type": "button", "style": "secondary", "height": "sm",
+					"action": map[string]interface{}{"type": "postback", "label": "❌ ไม่อนุมัติ", "data": "action=cap_approve&ok=0&id=" + id, "displayText": "ไม่อนุมัติ"},
+				},
+			},
+		},
+	}
+}
+
+// toFlexContainer converts a map-based bubble to an SDK flex container
+func toFlexContainer(flex map[string]interface{}) (messaging_api.FlexContainerInterface, error) {
+	data, err := json.Marshal(flex)
+	if err != nil {
+		return nil, err
+	}
+	return messaging_api.UnmarshalFlexContainer(data)
+}
+
+// pushMessages sends to another user only when push is enabled and quota allows
+func (h *LineWebhookHandler) pushMessages(to string, messages ...messaging_api.MessageInterface) bool {
+	if to == "" || !h.canPush() {
+		return false
+	}
+	if _, err := h.bot.PushMessage(&messaging_api.PushMessageRequest{To: to, Messages: messages}, ""); err != nil {
+		log.Printf("Failed to push message: %v", err)
+		return false
+	}
+	h.recordPush()
+	return true
+}
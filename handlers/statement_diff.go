@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/webhook"
+	"github.com/satisatang/backend/services"
+)
+
+// maxStatementRows limits rows shown in the missing-records flex (each row has its own button)
+const maxStatementRows = 10
+
+// pendingStatementItem is one missing statement row waiting for the user to add it
+type pendingStatementItem struct {
+	Transaction services.TransactionData `json:"transaction"`
+	Added       bool                     `json:"added"`
+}
+
+// handleFileMessage diffs an uploaded statement (CSV or SMS history text) against recorded transactions
+func (h *LineWebhookHandler) handleFileMessage(ctx context.Context, source webhook.SourceInterface, message webhook.FileMessageContent, replyToken string) {
+	userID := h.getUserID(source)
+	if userID == "" {
+		log.Println("Failed to get user ID")
+		return
+	}
+
+	name := strings.ToLower(message.FileName)
+	if !strings.HasSuffix(name, ".csv") && !strings.HasSuffix(name, ".txt") {
+		h.replyText(replyToken, "รองรับไฟล์ statement แบบ .csv หรือประวัติ SMS แบบ .txt ค่ะ")
+		return
+	}
+
+	content, err := h.blobAPI.GetMessageContent(message.Id)
+	if err != nil {
+		log.Printf("Failed to get file content: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถดาวน์โหลดไฟล์ได้")
+		return
+	}
+	defer content.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(content.Body, 5<<20))
+	if err != nil {
+		log.Printf("Failed to read file: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถอ่านไฟล์ได้")
+		return
+	}
+
+	var statement []services.TransactionData
+	if strings.HasSuffix(name, ".csv") {
+		statement, err = services.ParseStatementCSV(data, time.Now())
+		if err != nil {
+			log.Printf("Failed to parse statement CSV: %v", err)
+			h.replyText(replyToken, "อ่าน statement ไม่ได้ค่ะ ต้องมีคอลัมน์วันที่ และยอดเงิน (หรือถอน/ฝาก)")
+			return
+		}
+		// CSV rows carry no bank, so take it from the file name when present
+		if bank, useType, ok := services.DetectBankName(message.FileName); ok {
+			for i := range statement {
+				statement[i].UseType = useType
+				if useType == 1 {
+					statement[i].CreditCardName = bank
+				} else {
+					statement[i].BankName = bank
+				}
+			}
+		}
+	} else {
+		statement = services.ParseStatementSMS(string(data), time.Now())
+	}
+
+	h.replyStatementDiff(ctx, replyToken, userID, statement)
+}
+
+// replyStatementDiff shows statement rows not yet recorded ("รายการที่ยังไม่ได้จด") with one-tap add buttons
+func (h *LineWebhookHandler) replyStatementDiff(ctx context.Context, replyToken, userID string, statement []services.TransactionData) {
+	if len(statement) == 0 {
+		h.replyText(replyToken, "ไม่พบรายการใน statement ค่ะ")
+		return
+	}
+
+	missing, err := h.mongo.FindUnrecordedTransactions(ctx, userID, statement)
+	if err != nil {
+		log.Printf("Failed to diff statement: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถตรวจสอบรายการได้")
+		return
+	}
+	if len(missing) == 0 {
+		h.replyText(replyToken, fmt.Sprintf("✅ ตรวจ %d รายการ จดครบทุกรายการแล้วค่ะ", len(statement)))
+		return
+	}
+
+	// Saved transactions should use the user's own account names
+	banks, cards, _ := h.mongo.GetDistinctPaymentMethods(ctx, userID)
+	items := make([]pendingStatementItem, len(missing))
+	for i, tx := range missing {
+		if tx.UseType == 1 {
+			tx.CreditCardName = services.ResolveAccountName(tx.CreditCardName, 1, banks, cards)
+		} else if tx.UseType == 2 {
+			tx.BankName = services.ResolveAccountName(tx.BankName, 2, banks, cards)
+		}
+		items[i] = pendingStatementItem{Transaction: tx}
+	}
+
+	itemsJSON, _ := json.Marshal(items)
+	key := fmt.Sprintf("statement_%s_%d", userID, time.Now().Unix())
+	if err := h.mongo.SaveTempData(ctx, key, string(itemsJSON), 30*time.Minute); err != nil {
+		log.Printf("Failed to save statement diff: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ เกิดข้อผิดพลาด")
+		return
+	}
+
+	var rows []interface{}
+	for i, item := range items {
+		if i >= maxStatementRows {
+			rows = append(rows, map[string]interface{}{
+				"type": "text", "text": fmt.Sprintf("...และอีก %d รายการ (กดจดทั้งหมดได้)", len(items)-maxStatementRows),
+				"size": "xxs", "color": "#888888", "margin": "md",
+			})
+			break
+		}
+		tx := item.Transaction
+		amountColor, sign := "#E74C3C", "-"
+		if tx.Type == "income" {
+			amountColor, sign = "#27AE60", "+"
+		}
+		rows = append(rows, map[string]interface{}{
+			"type": "box", "layout": "horizontal", "margin": "md", "alignItems": "center",
+			"contents": []interface{}{
+				map[string]interface{}{
+					"type": "box", "layout": "vertical", "flex": 5,
+					"contents": []interface{}{
+						map[string]interface{}{"type": "text", "text": orDefault(tx.Description, tx.Category), "size": "xs", "wrap": true, "maxLines": 2},
+						map[string]interface{}{"type": "text", "text": tx.Date, "size": "xxs", "color": "#888888"},
+					},
+				},
+				map[string]interface{}{"type": "text", "text": sign + formatNumber(tx.Amount), "size": "xs", "color": amountColor, "align": "end", "flex": 3},
+				map[string]interface{}{
+					"type": "button", "style": "link", "height": "sm", "flex": 2,
+					"action": map[string]interface{}{
+						"type":        "postback",
+						"label":       "+ จด",
+						"data":        fmt.Sprintf("action=statement_add&key=%s&i=%d", key, i),
+						"displayText": fmt.Sprintf("จด %s %s บาท", truncateLabel(orDefault(tx.Description, tx.Category), 20), formatNumber(tx.Amount)),
+					},
+				},
+			},
+		})
+	}
+
+	flex := map[string]interface{}{
+		"type": "bubble",
+		"header": map[string]interface{}{
+			"type":            "box",
+			"layout":          "vertical",
+			"backgroundColor": "#8E44AD",
+			"paddingAll":      "md",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "🧾 รายการที่ยังไม่ได้จด", "color": "#FFFFFF", "weight": "bold", "size": "md"},
+				map[string]interface{}{"type": "text", "text": fmt.Sprintf("ตรวจ %d รายการ ยังไม่ได้จด %d รายการ", len(statement), len(items)), "color": "#FFFFFF", "size": "xs"},
+			},
+		},
+		"body": map[string]interface{}{
+			"type":       "box",
+			"layout":     "vertical",
+			"paddingAll": "md",
+			"contents":   rows,
+		},
+		"footer": map[string]interface{}{
+			"type":   "box",
+			"layout": "vertical",
+			"contents": []interface{}{
+				map[string]interface{}{
+					"type": "button", "style": "primary", "height": "sm", "color": "#8E44AD",
+					"action": map[string]interface{}{
+						"type":        "postback",
+						"label":       fmt.Sprintf("จดทั้งหมด (%d)", len(items)),
+						"data":        "action=statement_add&key=" + key + "&i=all",
+						"displayText": "จดทั้งหมด",
+					},
+				},
+			},
+		},
+	}
+
+	if !h.replyFlexFromAI(replyToken, flex, fmt.Sprintf("รายการที่ยังไม่ได้จด %d รายการ", len(items))) {
+		h.mongo.DeleteTempData(ctx, key)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถแสดงรายการได้")
+	}
+}
+
+// handleStatementAdd saves one (i=N) or all (i=all) missing statement rows
+func (h *LineWebhookHandler) handleStatementAdd(ctx context.Context, replyToken, userID string, params map[string]string) {
+	key := params["key"]
+	if !strings.HasPrefix(key, "statement_"+userID+"_") {
+		h.replyText(replyToken, "รายการไม่ถูกต้องค่ะ")
+		return
+	}
+	itemsJSON, err := h.mongo.GetTempData(ctx, key)
+	if err != nil {
+		h.replyText(replyToken, "รายการหมดอายุ กรุณาส่ง statement ใหม่อีกครั้ง")
+		return
+	}
+	var items []pendingStatementItem
+	if err := json.Unmarshal([]byte(itemsJSON), &items); err != nil {
+		log.Printf("Failed to parse statement diff: %v", err)
+		h.replyText(replyToken, "เกิดข้อผิดพลาด กรุณาส่ง statement ใหม่อีกครั้ง")
+		return
+	}
+
+	var indexes []int
+	if params["i"] == "all" {
+		for i := range items {
+			indexes = append(indexes, i)
+		}
+	} else if i, err := strconv.Atoi(params["i"]); err == nil && i >= 0 && i < len(items) {
+		indexes = []int{i}
+	}
+
+	var saved []services.TransactionData
+	var lastID, lockedMsg string
+	var heldMsgs []string
+	for _, i := range indexes {
+		if items[i].Added {
+			continue // Button tapped twice
+		}
+		tx := items[i].Transaction
+		txID, err := h.mongo.SaveTransactionOnDate(ctx, userID, &tx, tx.Date)
+		if err != nil {
+			if msg, ok := periodLockedText(err); ok {
+				lockedMsg = msg
+				continue
+			}
+			if msg, ok := h.spendingCapText(err); ok {
+				items[i].Added = true // held for the parent; tapping again would send a second request
+				heldMsgs = append(heldMsgs, msg)
+				continue
+			}
+			log.Printf("Failed to save statement row: %v", err)
+			continue
+		}
+		items[i].Added = true
+		saved = append(saved, tx)
+		lastID = txID
+	}
+
+	if updated, err := json.Marshal(items); err == nil {
+		h.mongo.SaveTempData(ctx, key, string(updated), 30*time.Minute)
+	}
+
+	switch {
+	case len(saved) == 0 && len(heldMsgs) > 0:
+		h.replyText(replyToken, strings.Join(heldMsgs, "\n\n"))
+	case len(saved) == 0 && lockedMsg != "":
+		h.replyText(replyToken, lockedMsg)
+	case len(saved) == 0:
+		h.replyText(replyToken, "รายการนี้จดไปแล้วค่ะ")
+	case len(saved) == 1 && len(heldMsgs) == 0:
+		if !h.replyTransactionsFlex(ctx, userID, replyToken, saved, "", lastID, "") {
+			h.replyText(replyToken, fmt.Sprintf("บันทึก %s %s บาทแล้วค่ะ", orDefault(saved[0].Description, saved[0].Category), formatNumber(saved[0].Amount)))
+		}
+	default:
+		var income, expense float64
+		for _, tx := range saved {
+			if tx.Type == "income" {
+				income += tx.Amount
+			} else {
+				expense += tx.Amount
+			}
+		}
+		msg := fmt.Sprintf("✅ จดเพิ่ม %d รายการแล้วค่ะ\n💰 รายรับ %s บาท\n💸 รายจ่าย %s บาท", len(saved), formatNumber(income), formatNumber(expense))
+		if lockedMsg != "" {
+			msg += "\n\nบางรายการอยู่ในงวดที่ปิดแล้ว จึงไม่ได้บันทึกค่ะ"
+		}
+		for _, held := range heldMsgs {
+			msg += "\n\n" + held
+		}
+		h.replyText(replyToken, msg)
+	}
+}
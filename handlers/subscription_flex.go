@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// isSubscriptionCommand checks if message asks to see subscriptions ("ดู subscription ทั้งหมด")
+func isSubscriptionCommand(text string) bool {
+	normalized := strings.ToLower(strings.ReplaceAll(text, " ", ""))
+	return strings.Contains(normalized, "subscription") || strings.Contains(normalized, "ซับสคริป")
+}
+
+// daysUntil returns days from today until date (YYYY-MM-DD), -1 if invalid
+func daysUntil(date string) int {
+	d, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return -1
+	}
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	return int(d.Sub(today).Hours() / 24)
+}
+
+// formatRenewal formats next renewal date as "ตัดเงิน 17/10 (อีก 2 วัน)"
+func formatRenewal(date string) string {
+	d, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return ""
+	}
+	text := "ตัดเงิน " + d.Format("02/01")
+	switch days := daysUntil(date); {
+	case days == 0:
+		text += " (วันนี้)"
+	case days == 1:
+		text += " (พรุ่งนี้)"
+	case days > 1:
+		text += fmt.Sprintf(" (อีก %d วัน)", days)
+	}
+	return text
+}
+
+// replySubscriptions detects subscriptions and replies with list flex
+func (h *LineWebhookHandler) replySubscriptions(ctx context.Context, replyToken, userID string) {
+	if err := h.mongo.DetectSubscriptions(ctx, userID); err != nil {
+		log.Printf("Failed to detect subscriptions: %v", err)
+	}
+
+	subs, err := h.mongo.GetSubscriptions(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to get subscriptions: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถดึงข้อมูล subscription ได้")
+		return
+	}
+
+	var active []services.Subscription
+	for _, sub := range subs {
+		if sub.Status == "active" {
+			active = append(active, sub)
+		}
+	}
+	if len(active) == 0 {
+		h.replyText(replyToken, "ยังไม่พบรายการ subscription ค่ะ\nระบบจะตรวจจับอัตโนมัติเมื่อมีรายจ่ายยอดเดิมร้านเดิมทุกเดือน")
+		return
+	}
+
+	if !h.replySubscriptionFlex(replyToken, active) {
+		var lines []string
+		for _, sub := range active {
+			lines = append(lines, fmt.Sprintf("• %s %s บาท %s", sub.Name, formatNumber(sub.Amount), formatRenewal(sub.NextRenewal)))
+		}
+		h.replyText(replyToken, fmt.Sprintf("📺 Subscription ทั้งหมด\n%s\nรวม %s บาท/เดือน",
+			strings.Join(lines, "\n"), formatNumber(services.GetSubscriptionMonthlyTotal(active))))
+	}
+}
+
+// replySubscriptionFlex sends active subscriptions with monthly total and cancel actions
+func (h *LineWebhookHandler) replySubscriptionFlex(replyToken string, subs []services.Subscription) bool {
+	total := services.GetSubscriptionMonthlyTotal(subs)
+
+	contents := []interface{}{
+		map[string]interface{}{
+			"type": "box", "layout": "horizontal",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "💳 รวมต่อเดือน", "size": "sm", "weight": "bold", "flex": 3},
+				map[string]interface{}{"type": "text", "text": formatNumber(total), "size": "md", "weight": "bold", "color": "#E74C3C", "align": "end", "flex": 3},
+			},
+		},
+		map[string]interface{}{"type": "text", "text": fmt.Sprintf("≈ %s บาท/ปี", formatNumber(total*12)), "size": "xxs", "color": "#888888", "align": "end"},
+		map[string]interface{}{"type": "separator", "margin": "md"},
+	}
+
+	for i, sub := range subs {
+		if i >= 10 {
+			break
+		}
+		renewalColor := "#888888"
+		if days := daysUntil(sub.NextRenewal); days >= 0 && days <= 3 {
+			renewalColor = "#E67E22" // Renewing soon
+		}
+		contents = append(contents, map[string]interface{}{
+			"type": "box", "layout": "horizontal", "margin": "md",
+			"contents": []interface{}{
+				map[string]interface{}{
+					"type": "box", "layout": "vertical", "flex": 5,
+					"contents": []interface{}{
+						map[string]interface{}{"type": "text", "text": truncateLabel(sub.Name, 20), "size": "sm", "weight": "bold", "color": "#333333"},
+						map[string]interface{}{"type": "text", "text": formatRenewal(sub.NextRenewal), "size": "xxs", "color": renewalColor},
+						map[string]interface{}{"type": "text", "text": getPaymentName(sub.UseType, sub.BankName, sub.CreditCardName), "size": "xxs", "color": "#AAAAAA"},
+					},
+				},
+				map[string]interface{}{
+					"type": "box", "layout": "vertical", "flex": 3,
+					"contents": []interface{}{
+						map[string]interface{}{"type": "text", "text": formatNumber(sub.Amount), "size": "sm", "weight": "bold", "align": "end"},
+						map[string]interface{}{
+							"type": "text", "text": "ยกเลิก", "size": "xxs", "color": "#E74C3C", "align": "end", "decoration": "underline",
+							"action": map[string]interface{}{
+								"type":        "postback",
+								"label":       "ยกเลิก",
+								"data":        "action=cancel_sub&id=" + sub.ID.Hex(),
+								"displayText": "ยกเลิก " + sub.Name,
+							},
+						},
+					},
+				},
+			},
+		})
+	}
+
+	flex := map[string]interface{}{
+		"type": "bubble",
+		"size": "mega",
+		"header": map[string]interface{}{
+			"type":            "box",
+			"layout":          "vertical",
+			"backgroundColor": "#8E44AD",
+			"paddingAll":      "md",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "📺 Subscription ทั้งหมด", "color": "#FFFFFF", "weight": "bold", "size": "md"},
+				map[string]interface{}{"type": "text", "text": fmt.Sprintf("%d รายการที่ยังใช้งาน", len(subs)), "color": "#E8DAEF", "size": "xxs", "margin": "xs"},
+			},
+		},
+		"body": map[string]interface{}{
+			"type":       "box",
+			"layout":     "vertical",
+			"paddingAll": "md",
+			"contents":   contents,
+		},
+	}
+
+	return h.replyFlexFromAI(replyToken, flex, fmt.Sprintf("Subscription รวม %s บาท/เดือน", formatNumber(total)))
+}
+
+// buildRenewalReminder creates reminder box for subscriptions renewing soon (nil if none)
+func buildRenewalReminder(renewals []services.Subscription) map[string]interface{} {
+	if len(renewals) == 0 {
+		return nil
+	}
+
+	contents := []interface{}{
+		map[string]interface{}{"type": "text", "text": "🔔 ใกล้ตัดเงิน subscription", "size": "xs", "weight": "bold", "color": "#E67E22"},
+	}
+	for _, sub := range renewals {
+		contents = append(contents, map[string]interface{}{
+			"type": "text", "text": fmt.Sprintf("• %s %s บาท %s", sub.Name, formatNumber(sub.Amount), formatRenewal(sub.NextRenewal)),
+			"size": "xxs", "color": "#666666", "wrap": true,
+		})
+	}
+	return map[string]interface{}{
+		"type": "box", "layout": "vertical", "margin": "lg",
+		"backgroundColor": "#FEF5E7", "cornerRadius": "8px", "paddingAll": "8px",
+		"contents": contents,
+	}
+}
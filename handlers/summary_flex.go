@@ -0,0 +1,409 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// comparisonKeywords are phrases that indicate the user wants to compare periods
+var comparisonKeywords = []string{
+	"เทียบ", "เดือนที่แล้ว", "เดือนก่อน", "สัปดาห์ที่แล้ว", "อาทิตย์ที่แล้ว", "สัปดาห์ก่อน", "อาทิตย์ก่อน",
+	"มากกว่า", "น้อยกว่า", "เพิ่มขึ้น", "ลดลง", "compare",
+}
+
+// statsKeywords are phrases that ask about counts, averages or extremes of spending
+var statsKeywords = []string{"สถิติ", "เฉลี่ย", "กี่รายการ", "กี่ครั้ง", "บ่อย", "สูงสุด", "ต่ำสุด", "แพงสุด", "วิเคราะห์"}
+
+// needsComparisonContext checks if message asks about period comparison
+func needsComparisonContext(text string) bool {
+	lower := strings.ToLower(text)
+	for _, kw := range comparisonKeywords {
+		if strings.Contains(lower, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// needsStatsContext checks if message asks for transaction stats (analyze)
+func needsStatsContext(text string) bool {
+	lower := strings.ToLower(text)
+	for _, kw := range statsKeywords {
+		if strings.Contains(lower, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// replyComparisonFlex sends flex comparing spending per category with up/down arrows
+func (h *LineWebhookHandler) replyComparisonFlex(replyToken string, comparison *services.PeriodComparison, styles services.CategoryStyles, msg string) bool {
+	if comparison == nil || len(comparison.Categories) == 0 {
+		return false
+	}
+
+	contents := []interface{}{
+		map[string]interface{}{
+			"type": "box", "layout": "horizontal",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "หมวด", "size": "xxs", "color": "#888888", "flex": 3},
+				map[string]interface{}{"type": "text", "text": comparison.PreviousLabel, "size": "xxs", "color": "#888888", "align": "end", "flex": 3},
+				map[string]interface{}{"type": "text", "text": comparison.CurrentLabel, "size": "xxs", "color": "#888888", "align": "end", "flex": 3},
+				map[string]interface{}{"type": "text", "text": " ", "size": "xxs", "flex": 1},
+			},
+		},
+		map[string]interface{}{"type": "separator", "margin": "sm"},
+	}
+
+	// Show top 10 categories
+	limit := 10
+	if len(comparison.Categories) < limit {
+		limit = len(comparison.Categories)
+	}
+
+	for i := 0; i < limit; i++ {
+		cc := comparison.Categories[i]
+		arrow, color := getChangeArrow(cc.Diff)
+
+		contents = append(contents, map[string]interface{}{
+			"type": "box", "layout": "horizontal", "margin": "sm",
+			"action": categoryDetailAction(cc.Category, comparison.CurrentFrom, comparison.CurrentTo),
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": styles.Emoji(cc.Category) + " " + cc.Category, "size": "xs", "color": "#555555", "flex": 3, "wrap": true},
+				map[string]interface{}{"type": "text", "text": formatNumber(cc.Previous), "size": "xs", "color": "#888888", "align": "end", "flex": 3},
+				map[string]interface{}{"type": "text", "text": formatNumber(cc.Current), "size": "xs", "weight": "bold", "color": "#333333", "align": "end", "flex": 3},
+				map[string]interface{}{"type": "text", "text": arrow, "size": "xs", "weight": "bold", "color": color, "align": "end", "flex": 1},
+			},
+		})
+	}
+
+	// Total row
+	totalDiff := comparison.CurrentTotal - comparison.PreviousTotal
+	arrow, color := getChangeArrow(totalDiff)
+	diffText := formatBalanceText(totalDiff)
+	if totalDiff > 0 {
+		diffText = "+" + diffText
+	}
+	if comparison.PreviousTotal > 0 {
+		diffText += fmt.Sprintf(" (%+.0f%%)", (totalDiff/comparison.PreviousTotal)*100)
+	}
+
+	contents = append(contents,
+		map[string]interface{}{"type": "separator", "margin": "md"},
+		map[string]interface{}{
+			"type": "box", "layout": "horizontal", "margin": "md",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "💰 รวม", "size": "sm", "weight": "bold", "flex": 3},
+				map[string]interface{}{"type": "text", "text": formatNumber(comparison.PreviousTotal), "size": "sm", "color": "#888888", "align": "end", "flex": 3},
+				map[string]interface{}{"type": "text", "text": formatNumber(comparison.CurrentTotal), "size": "sm", "weight": "bold", "align": "end", "flex": 3},
+				map[string]interface{}{"type": "text", "text": arrow, "size": "sm", "weight": "bold", "color": color, "align": "end", "flex": 1},
+			},
+		},
+		map[string]interface{}{"type": "text", "text": "ส่วนต่าง " + diffText, "size": "xs", "color": color, "align": "end", "margin": "sm"},
+	)
+
+	// Add AI message at the bottom if provided
+	if msg != "" {
+		contents = append(contents,
+			map[string]interface{}{"type": "separator", "margin": "md"},
+			map[string]interface{}{"type": "text", "text": msg, "size": "sm", "color": "#666666", "wrap": true, "margin": "md"},
+		)
+	}
+
+	flex := map[string]interface{}{
+		"type": "bubble",
+		"size": "mega",
+		"header": map[string]interface{}{
+			"type":            "box",
+			"layout":          "vertical",
+			"backgroundColor": "#6C5CE7",
+			"paddingAll":      "md",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": fmt.Sprintf("📊 %s vs %s", comparison.CurrentLabel, comparison.PreviousLabel), "color": "#FFFFFF", "weight": "bold", "size": "md"},
+				map[string]interface{}{"type": "text", "text": fmt.Sprintf("%s ถึง %s", comparison.CurrentFrom, comparison.CurrentTo), "color": "#DDD6FE", "size": "xxs", "margin": "xs"},
+			},
+		},
+		"body": map[string]interface{}{
+			"type":       "box",
+			"layout":     "vertical",
+			"paddingAll": "md",
+			"contents":   contents,
+		},
+	}
+
+	altText := msg
+	if altText == "" {
+		altText = fmt.Sprintf("เทียบ%sกับ%s", comparison.CurrentLabel, comparison.PreviousLabel)
+	}
+	return h.replyFlexFromAI(replyToken, flex, altText)
+}
+
+// getChangeArrow returns arrow and color for spending change (more spending = red)
+func getChangeArrow(diff float64) (string, string) {
+	switch {
+	case diff > 0:
+		return "▲", "#E74C3C"
+	case diff < 0:
+		return "▼", "#27AE60"
+	}
+	return "•", "#888888"
+}
+
+// quickSummaryCommands maps normalized commands (no spaces) to summary period
+var quickSummaryCommands = map[string]string{
+	"สรุปวันนี้":          "day",
+	"สรุปสัปดาห์นี้":      "week",
+	"สรุปอาทิตย์นี้":      "week",
+	"สรุปเดือนนี้":        "month",
+	"ยอดใช้วันนี้":        "day",
+	"ยอดใช้เดือนนี้":      "month",
+	"วันนี้ใช้ไปเท่าไหร่": "day",
+}
+
+// matchQuickSummary returns summary period ("day", "week", "month") if text is a quick summary command
+func matchQuickSummary(text string) string {
+	normalized := strings.ReplaceAll(strings.TrimSpace(text), " ", "")
+	return quickSummaryCommands[normalized]
+}
+
+// replyQuickSummary computes summary in Go and replies with flex (AI only for advice)
+func (h *LineWebhookHandler) replyQuickSummary(ctx context.Context, replyToken, userID, userText, period string) {
+	var summary *services.PeriodSummary
+	var err error
+	switch period {
+	case "week":
+		summary, err = h.mongo.GetWeeklySummary(ctx, userID)
+	default:
+		// Day and month come precomputed from the read model
+		summary, err = h.mongo.ProjectedPeriodSummary(ctx, userID, period)
+	}
+	if err != nil {
+		log.Printf("Failed to get %s summary: %v", period, err)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถสรุปข้อมูลได้ กรุณาลองใหม่อีกครั้ง")
+		return
+	}
+
+	// Monthly budget pace is computed in Go (week/month summaries only)
+	if period != "day" {
+		summary.Pace, _ = h.mongo.GetBudgetPace(ctx, userID, time.Now())
+	}
+
+	// AI writes only the advice sentence (skip when there is nothing to advise)
+	advice := ""
+	if summary.TransactionCount > 0 {
+		adviceCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+		advice, err = h.ai.GenerateAdvice(adviceCtx, summary.ToAIText())
+		cancel()
+		if err != nil {
+			log.Printf("Failed to generate advice: %v", err)
+			advice = ""
+		}
+	}
+
+	// Remind subscriptions renewing soon in the reply (no push) unless user turned it off
+	var renewals []services.Subscription
+	if h.notifications.IsEnabled(ctx, userID, services.NotifyRenewal) {
+		renewals, _ = h.mongo.GetUpcomingRenewals(ctx, userID, 3)
+	}
+
+	styles := h.mongo.GetCategoryStyles(ctx, userID)
+
+	h.mongo.SaveChatMessage(ctx, userID, "user", userText)
+	if !h.replySummaryFlex(replyToken, summary, advice, renewals, styles) {
+		h.replyText(replyToken, fmt.Sprintf("สรุป%s\nรายรับ %s\nรายจ่าย %s\nคงเหลือ %s",
+			summary.Label, formatNumber(summary.TotalIncome), formatNumber(summary.TotalExpense), formatBalanceText(summary.Balance)))
+	}
+	h.mongo.SaveChatMessage(ctx, userID, "assistant", fmt.Sprintf("สรุป%s รายรับ %.0f รายจ่าย %.0f", summary.Label, summary.TotalIncome, summary.TotalExpense))
+}
+
+// replySummaryFlex sends deterministic period summary flex
+func (h *LineWebhookHandler) replySummaryFlex(replyToken string, summary *services.PeriodSummary, advice string, renewals []services.Subscription, styles services.CategoryStyles) bool {
+	dateText := summary.From
+	if summary.From != summary.To {
+		dateText = fmt.Sprintf("%s ถึง %s", summary.From, summary.To)
+	}
+
+	contents := []interface{}{
+		map[string]interface{}{
+			"type": "box", "layout": "horizontal",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "📈 รายรับ", "size": "sm", "color": "#27AE60", "flex": 2},
+				map[string]interface{}{"type": "text", "text": formatNumber(summary.TotalIncome), "size": "sm", "weight": "bold", "color": "#27AE60", "align": "end", "flex": 3},
+			},
+		},
+		map[string]interface{}{
+			"type": "box", "layout": "horizontal", "margin": "sm",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "📉 รายจ่าย", "size": "sm", "color": "#E74C3C", "flex": 2},
+				map[string]interface{}{"type": "text", "text": formatNumber(summary.TotalExpense), "size": "sm", "weight": "bold", "color": "#E74C3C", "align": "end", "flex": 3},
+			},
+		},
+		map[string]interface{}{
+			"type": "box", "layout": "horizontal", "margin": "sm",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "💰 คงเหลือ", "size": "sm", "weight": "bold", "flex": 2},
+				map[string]interface{}{"type": "text", "text": formatBalanceText(summary.Balance), "size": "md", "weight": "bold", "color": getBalanceColor(summary.Balance), "align": "end", "flex": 3},
+			},
+		},
+		map[string]interface{}{"type": "text", "text": fmt.Sprintf("🧾 %d รายการ", summary.TransactionCount), "size": "xxs", "color": "#888888", "margin": "sm"},
+	}
+
+	if summary.TotalInvested > 0 {
+		contents = append(contents,
+			map[string]interface{}{"type": "text", "text": "📈 ลงทุน " + formatNumber(summary.TotalInvested) + " (ไม่นับเป็นรายจ่าย)", "size": "xxs", "color": "#8E44AD"},
+		)
+	}
+
+	if summary.Period != "day" && summary.TotalExpense > 0 {
+		contents = append(contents,
+			map[string]interface{}{"type": "text", "text": "📅 เฉลี่ยจ่ายวันละ " + formatNumber(summary.DailyAverage), "size": "xxs", "color": "#888888"},
+		)
+	}
+
+	if summary.Pace != nil {
+		contents = append(contents, buildPaceIndicator(summary.Pace)...)
+	}
+
+	// Top spending categories
+	if len(summary.ExpenseByCategory) > 0 {
+		contents = append(contents,
+			map[string]interface{}{"type": "separator", "margin": "md"},
+			map[string]interface{}{"type": "text", "text": "🏷️ รายจ่ายตามหมวด", "size": "xs", "color": "#888888", "margin": "md"},
+		)
+		for i, ca := range summary.ExpenseByCategory {
+			if i >= 5 {
+				break
+			}
+			percentage := 0.0
+			if summary.TotalExpense > 0 {
+				percentage = (ca.Amount / summary.TotalExpense) * 100
+			}
+			contents = append(contents, map[string]interface{}{
+				"type": "box", "layout": "horizontal", "margin": "sm",
+				"action": categoryDetailAction(ca.Category, summary.From, summary.To),
+				"contents": []interface{}{
+					map[string]interface{}{"type": "text", "text": styles.Emoji(ca.Category) + " " + ca.Category + " ›", "size": "xs", "color": "#555555", "flex": 3},
+					map[string]interface{}{"type": "text", "text": fmt.Sprintf("%s (%.0f%%)", formatNumber(ca.Amount), percentage), "size": "xs", "color": "#333333", "align": "end", "flex": 3},
+				},
+			})
+		}
+	}
+
+	// Advice from AI
+	if advice != "" {
+		contents = append(contents, map[string]interface{}{
+			"type": "box", "layout": "vertical", "margin": "lg",
+			"backgroundColor": "#FFF9E6", "cornerRadius": "8px", "paddingAll": "8px",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "💡 " + advice, "size": "xs", "color": "#666666", "wrap": true},
+			},
+		})
+	}
+
+	if reminder := buildRenewalReminder(renewals); reminder != nil {
+		contents = append(contents, reminder)
+	}
+
+	flex := map[string]interface{}{
+		"type": "bubble",
+		"size": "kilo",
+		"header": map[string]interface{}{
+			"type":            "box",
+			"layout":          "vertical",
+			"backgroundColor": "#1E88E5",
+			"paddingAll":      "md",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "📊 สรุป" + summary.Label, "color": "#FFFFFF", "weight": "bold", "size": "md"},
+				map[string]interface{}{"type": "text", "text": dateText, "color": "#B3E5FC", "size": "xxs", "margin": "xs"},
+			},
+		},
+		"body": map[string]interface{}{
+			"type":       "box",
+			"layout":     "vertical",
+			"paddingAll": "md",
+			"contents":   contents,
+		},
+		"footer": map[string]interface{}{
+			"type":       "box",
+			"layout":     "horizontal",
+			"paddingAll": "sm",
+			"spacing":    "sm",
+			"contents":   summaryFooterButtons(summary.Period),
+		},
+	}
+
+	return h.replyFlexFromAI(replyToken, flex, "สรุป"+summary.Label)
+}
+
+// summaryFooterButtons returns buttons to switch to the other summary periods
+func summaryFooterButtons(current string) []interface{} {
+	periods := []struct{ period, label, text string }{
+		{"day", "วันนี้", "สรุปวันนี้"},
+		{"week", "สัปดาห์นี้", "สรุปสัปดาห์นี้"},
+		{"month", "เดือนนี้", "สรุปเดือนนี้"},
+	}
+
+	var buttons []interface{}
+	for _, p := range periods {
+		if p.period == current {
+			continue
+		}
+		buttons = append(buttons, map[string]interface{}{
+			"type": "button", "style": "secondary", "height": "sm",
+			"action": map[string]interface{}{"type": "message", "label": p.label, "text": p.text},
+		})
+	}
+	return buttons
+}
+
+// buildPaceIndicator shows budget used vs month elapsed as two bars with a pace sentence
+func buildPaceIndicator(pace *services.BudgetPace) []interface{} {
+	color := "#27AE60"
+	switch pace.Status {
+	case "over":
+		color = "#E74C3C"
+	case "ahead":
+		color = "#F39C12"
+	}
+	return []interface{}{
+		map[string]interface{}{"type": "separator", "margin": "md"},
+		map[string]interface{}{"type": "text", "text": "🎯 งบเดือนนี้ " + formatNumber(pace.Spent) + "/" + formatNumber(pace.Budget), "size": "xs", "color": "#888888", "margin": "md"},
+		paceBar("ใช้งบ", pace.UsedPercent, color),
+		paceBar("เวลา", pace.ElapsedPercent, "#95A5A6"),
+		map[string]interface{}{"type": "text", "text": pace.Text(), "size": "xxs", "color": color, "wrap": true, "margin": "sm"},
+	}
+}
+
+// paceBar builds one labelled progress bar (filler segments like the budget overview)
+func paceBar(label string, percent float64, color string) map[string]interface{} {
+	filled := int(percent)
+	if filled > 100 {
+		filled = 100
+	}
+	var segments []interface{}
+	if filled > 0 {
+		segments = append(segments, map[string]interface{}{
+			"type": "box", "layout": "vertical", "contents": []interface{}{map[string]interface{}{"type": "filler"}},
+			"backgroundColor": color, "flex": filled,
+		})
+	}
+	if filled < 100 {
+		segments = append(segments, map[string]interface{}{
+			"type": "box", "layout": "vertical", "contents": []interface{}{map[string]interface{}{"type": "filler"}},
+			"backgroundColor": "#EEEEEE", "flex": 100 - filled,
+		})
+	}
+	return map[string]interface{}{
+		"type": "box", "layout": "horizontal", "margin": "sm", "alignItems": "center",
+		"contents": []interface{}{
+			map[string]interface{}{"type": "text", "text": label, "size": "xxs", "color": "#888888", "flex": 2},
+			map[string]interface{}{"type": "box", "layout": "horizontal", "height": "6px", "cornerRadius": "3px", "flex": 6, "contents": segments},
+			map[string]interface{}{"type": "text", "text": fmt.Sprintf("%.0f%%", percent), "size": "xxs", "color": "#555555", "align": "end", "flex": 2},
+		},
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satisatang/backend/services"
+)
+
+// SyncHandler serves the differential sync API of the mobile/web client
+type SyncHandler struct {
+	mongo     *services.MongoDBService
+	channelID string
+}
+
+// NewSyncHandler creates sync API handler; clients sign in with LINE Login of channelID
+func NewSyncHandler(mongo *services.MongoDBService, channelID string) *SyncHandler {
+	return &SyncHandler{mongo: mongo, channelID: channelID}
+}
+
+// HandleSync returns changes since ?cursor= (GET, Authorization: Bearer <LINE access token>)
+// No cursor starts a full download; keep calling with the returned cursor while has_more is true
+func (h *SyncHandler) HandleSync(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	userID, err := services.VerifyLIFFAccessToken(ctx, strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "), h.channelID)
+	if err != nil {
+		log.Printf("Sync token rejected: %v", err)
+		h.mongo.LogSecurityEvent(ctx, services.SecurityEvent{Event: services.SecurityAccessDenied, Detail: "sync token rejected", ClientIP: c.ClientIP()})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	page, err := h.mongo.SyncChangesSince(ctx, userID, c.Query("cursor"), limit)
+	if errors.Is(err, services.ErrInvalidSyncCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to sync: %v", err)
+		services.ReportError("sync", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "sync failed"})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// handleTopMerchantQuery answers "ร้านไหนเปลืองสุด" with a ranked merchant flex (no AI)
+func (h *LineWebhookHandler) handleTopMerchantQuery(ctx context.Context, replyToken, userID, text string) bool {
+	q, ok := services.ParseTopMerchantQuery(text, time.Now())
+	if !ok {
+		return false
+	}
+	merchants, err := h.mongo.GetTopMerchants(ctx, userID, q.From, q.To, services.TopMerchantLimit)
+	if err != nil {
+		log.Printf("Failed to get top merchants: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถจัดอันดับร้านค้าได้")
+		return true
+	}
+	if len(merchants) == 0 {
+		h.replyText(replyToken, fmt.Sprintf("%s ยังไม่มีรายจ่ายที่ระบุชื่อร้านค่ะ (ชื่อร้านมาจากสลิป/ใบเสร็จที่ส่งมา)", q.Period))
+		return true
+	}
+	h.replyTopMerchants(replyToken, q, merchants)
+	return true
+}
+
+// replyTopMerchants renders the merchant ranking: rank, name, visits and amount with a bar of the top spender
+func (h *LineWebhookHandler) replyTopMerchants(replyToken string, q *services.MerchantQuery, merchants []services.MerchantSpend) {
+	top := merchants[0].Amount
+	var body []interface{}
+	for i, m := range merchants {
+		rank := fmt.Sprintf("%d.", i+1)
+		switch i {
+		case 0:
+			rank = "🥇"
+		case 1:
+			rank = "🥈"
+		case 2:
+			rank = "🥉"
+		}
+		barWidth := 5
+		if top > 0 {
+			barWidth = int(m.Amount / top * 100)
+		}
+		if barWidth < 5 {
+			barWidth = 5
+		}
+		body = append(body,
+			map[string]interface{}{
+				"type": "box", "layout": "horizontal", "margin": "md",
+				"contents": []interface{}{
+					map[string]interface{}{"type": "text", "text": rank, "size": "sm", "flex": 1},
+					map[string]interface{}{"type": "text", "text": m.Merchant, "size": "sm", "weight": "bold", "flex": 5, "wrap": true, "maxLines": 1},
+					map[string]interface{}{"type": "text", "text": formatNumber(m.Amount), "size": "sm", "color": "#E74C3C", "align": "end", "flex": 3},
+				},
+			},
+			map[string]interface{}{
+				"type": "box", "layout": "horizontal", "margin": "xs",
+				"contents": []interface{}{
+					map[string]interface{}{"type": "filler", "flex": 1},
+					map[string]interface{}{
+						"type": "box", "layout": "horizontal", "flex": 5,
+						"contents": []interface{}{
+							map[string]interface{}{"type": "box", "layout": "vertical", "backgroundColor": "#E67E22", "height": "6px", "cornerRadius": "3px", "flex": barWidth, "contents": []interface{}{map[string]interface{}{"type": "filler"}}},
+							map[string]interface{}{"type": "box", "layout": "vertical", "height": "6px", "flex": 100 - barWidth, "contents": []interface{}{map[string]interface{}{"type": "filler"}}},
+						},
+					},
+					map[string]interface{}{"type": "text", "text": fmt.Sprintf("%d ครั้ง", m.Visits), "size": "xxs", "color": "#888888", "align": "end", "flex": 3},
+				},
+			},
+		)
+	}
+
+	flex := map[string]interface{}{
+		"type": "bubble",
+		"header": map[string]interface{}{
+			"type":            "box",
+			"layout":          "vertical",
+			"backgroundColor": "#E67E22",
+			"paddingAll":      "md",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "🏪 ร้านที่จ่ายมากสุด", "color": "#FFFFFF", "weight": "bold", "size": "md"},
+				map[string]interface{}{"type": "text", "text": fmt.Sprintf("%s (%d ร้าน)", q.Period, len(merchants)), "color": "#FFFFFF", "size": "xs"},
+			},
+		},
+		"body": map[string]interface{}{
+			"type":       "box",
+			"layout":     "vertical",
+			"paddingAll": "md",
+			"contents":   body,
+		},
+	}
+	first := merchants[0]
+	summary := fmt.Sprintf("%s จ่ายที่ %s มากสุด %s บาท (%d ครั้ง)", q.Period, first.Merchant, formatNumber(first.Amount), first.Visits)
+	if !h.replyFlexFromAI(replyToken, flex, summary) {
+		h.replyText(replyToken, summary)
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/satisatang/backend/services"
+)
+
+// transferPairNotice tells the user a just-saved entry looks like one side of a transfer
+func transferPairNotice(pair *services.Transaction) map[string]interface{} {
+	direction := "เงินเข้า"
+	if pair.Type == -1 {
+		direction = "เงินออก"
+	}
+	return map[string]interface{}{
+		"type": "text", "wrap": true, "size": "xxs", "color": "#2980B9", "margin": "md",
+		"text": fmt.Sprintf("🔁 วันเดียวกันมี%s %s %s บาท ยอดเท่ากัน น่าจะเป็นการโอนระหว่างบัญชี",
+			direction, getPaymentName(pair.UseType, pair.BankName, pair.CreditCardName), formatNumber(pair.Amount)),
+	}
+}
+
+// mergeTransferButton merges the saved entry and its pair into one transfer
+func mergeTransferButton(txID, pairID, date string) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "button", "style": "primary", "height": "sm", "color": "#2980B9",
+		"action": map[string]interface{}{
+			"type":        "postback",
+			"label":       "🔁 รวมเป็นโอนเงิน",
+			"data":        fmt.Sprintf("action=merge_transfer&txid=%s&pair=%s&date=%s", txID, pairID, date),
+			"displayText": "รวมเป็นโอนเงิน",
+		},
+	}
+}
+
+// handleMergeTransfer turns the pair into a transfer so neither side counts as income/expense
+func (h *LineWebhookHandler) handleMergeTransfer(ctx context.Context, replyToken, userID string, params map[string]string) {
+	transfer, err := h.mongo.MergeTransferPair(ctx, userID, params["date"], params["txid"], params["pair"])
+	switch {
+	case errors.Is(err, services.ErrPeriodLocked):
+		h.replyText(replyToken, "🔒 รายการนี้อยู่ในงวดที่ปิดแล้ว รวมไม่ได้ค่ะ")
+		return
+	case errors.Is(err, services.ErrNotTransferPair):
+		h.replyText(replyToken, "รายการนี้ถูกแก้ไขหรือรวมไปแล้วค่ะ")
+		return
+	case err != nil:
+		log.Printf("Failed to merge transfer pair: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ รวมเป็นรายการโอนไม่สำเร็จ")
+		return
+	}
+
+	from, to := transfer.From[0], transfer.To[0]
+	h.replyText(replyToken, fmt.Sprintf("🔁 รวมเป็นโอนเงินแล้วค่ะ\n%s → %s %s บาท\nไม่นับเป็นรายรับ/รายจ่าย ยอดคงเหลือแต่ละบัญชีถูกต้องตามเดิม",
+		getPaymentName(from.UseType, from.BankName, from.CreditCardName),
+		getPaymentName(to.UseType, to.BankName, to.CreditCardName),
+		formatNumber(transfer.TotalAmount)))
+}
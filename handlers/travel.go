@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// formatForeign formats an amount in a trip currency ("¥1,200")
+func formatForeign(amount float64, currency string) string {
+	return services.CurrencySymbol(currency) + formatNumber(amount)
+}
+
+// handleTravelCommand starts/stops travel mode, sets its rate or shows the trip summary (no AI)
+func (h *LineWebhookHandler) handleTravelCommand(ctx context.Context, replyToken, userID string, cmd services.TravelCommand) {
+	settings, err := h.mongo.GetUserSettings(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to get settings: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถดึงการตั้งค่าได้")
+		return
+	}
+
+	switch cmd.Action {
+	case "start":
+		rate := 0.0
+		if h.fx != nil {
+			if rate, err = h.fx.Rate(ctx, cmd.Currency, ""); err != nil {
+				log.Printf("Failed to get %s rate: %v", cmd.Currency, err)
+				rate = 0
+			}
+		}
+		trip := services.NewTrip(cmd.Destination, cmd.Currency, cmd.Days, rate, time.Now())
+		if err := h.mongo.StartTrip(ctx, userID, trip); err != nil {
+			log.Printf("Failed to start trip: %v", err)
+			h.replyText(replyToken, "ไม่สามารถเปิดโหมดเที่ยวได้ กรุณาลองใหม่")
+			return
+		}
+		text := fmt.Sprintf("✈️ เปิดโหมดเที่ยว%s %d วันแล้วค่ะ (ถึง %s)\nรายจ่ายที่พิมพ์ช่วงนี้จะบันทึกเป็น %s และติดแท็กทริปให้",
+			trip.Destination, cmd.Days, formatThaiShortDate(trip.EndDate), trip.Currency)
+		if rate > 0 {
+			text += fmt.Sprintf("\n💱 เรทวันนี้ 1 %s = %s บาท (แก้: เรท 0.25)", trip.Currency, formatRate(rate))
+		} else {
+			text += fmt.Sprintf("\n💱 ยังไม่มีเรท %s พิมพ์ เรท 0.25 (บาทต่อ 1 %s) ก่อนนะคะ ระหว่างนี้จะบันทึกเป็นบาท", trip.Currency, trip.Currency)
+		}
+		text += "\nจ่ายเป็นบาทให้พิมพ์ บาท ต่อท้าย • ดูยอด: สรุปทริป • ปิด: จบทริป"
+		h.replyText(replyToken, text)
+
+	case "rate":
+		if settings.Trip == nil {
+			h.replyText(replyToken, "ยังไม่ได้เปิดโหมดเที่ยวค่ะ ตัวอย่าง: ไปญี่ปุ่น 10 วัน")
+			return
+		}
+		if err := h.mongo.SetTripRate(ctx, userID, cmd.Rate); err != nil {
+			log.Printf("Failed to set trip rate: %v", err)
+			h.replyText(replyToken, "ไม่สามารถตั้งเรทได้ กรุณาลองใหม่")
+			return
+		}
+		h.replyText(replyToken, fmt.Sprintf("💱 ตั้งเรท 1 %s = %s บาทแล้วค่ะ ใช้กับรายการที่บันทึกต่อจากนี้", settings.Trip.Currency, formatRate(cmd.Rate)))
+
+	case "stop":
+		if settings.Trip == nil {
+			h.replyText(replyToken, "ตอนนี้ไม่ได้อยู่ในโหมดเที่ยวค่ะ")
+			return
+		}
+		if err := h.mongo.StopTrip(ctx, userID); err != nil {
+			log.Printf("Failed to stop trip: %v", err)
+			h.replyText(replyToken, "ไม่สามารถปิดโหมดเที่ยวได้ กรุณาลองใหม่")
+			return
+		}
+		h.replyTripSummary(ctx, replyToken, userID, settings.Trip, "🏠 จบทริปแล้ว ยินดีต้อนรับกลับค่ะ")
+
+	default:
+		if settings.Trip == nil {
+			h.replyText(replyToken, "ยังไม่ได้เปิดโหมดเที่ยวค่ะ ตัวอย่าง: ไปญี่ปุ่น 10 วัน")
+			return
+		}
+		h.replyTripSummary(ctx, replyToken, userID, settings.Trip, "")
+	}
+}
+
+// replyTripSummary shows the trip's spending per day in the trip currency and in baht
+func (h *LineWebhookHandler) replyTripSummary(ctx context.Context, replyToken, userID string, trip *services.Trip, note string) {
+	summary, err := h.mongo.GetTripSummary(ctx, userID, trip)
+	if err != nil {
+		log.Printf("Failed to get trip summary: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถสรุปทริปได้")
+		return
+	}
+	if len(summary.Days) == 0 {
+		h.replyText(replyToken, strings.TrimSpace(note+"\nทริป"+trip.Destination+"ยังไม่มีรายจ่ายค่ะ"))
+		return
+	}
+
+	body := []interface{}{
+		map[string]interface{}{
+			"type": "box", "layout": "horizontal",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "วันที่", "size": "xxs", "color": "#888888", "flex": 3},
+				map[string]interface{}{"type": "text", "text": trip.Currency, "size": "xxs", "color": "#888888", "align": "end", "flex": 4},
+				map[string]interface{}{"type": "text", "text": "บาท", "size": "xxs", "color": "#888888", "align": "end", "flex": 4},
+			},
+		},
+	}
+	for _, day := range summary.Days {
+		foreign := "-"
+		if day.Foreign > 0 {
+			foreign = formatForeign(day.Foreign, trip.Currency)
+		}
+		body = append(body, map[string]interface{}{
+			"type": "box", "layout": "horizontal", "margin": "sm",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": formatThaiShortDate(day.Date), "size": "sm", "flex": 3},
+				map[string]interface{}{"type": "text", "text": foreign, "size": "sm", "align": "end", "flex": 4},
+				map[string]interface{}{"type": "text", "text": formatNumber(day.THB), "size": "sm", "weight": "bold", "color": "#E74C3C", "align": "end", "flex": 4},
+			},
+		})
+	}
+	body = append(body,
+		map[string]interface{}{"type": "separator", "margin": "md"},
+		map[string]interface{}{
+			"type": "box", "layout": "horizontal", "margin": "md",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "รวม", "size": "sm", "weight": "bold", "flex": 3},
+				map[string]interface{}{"type": "text", "text": formatForeign(summary.Foreign, trip.Currency), "size": "sm", "weight": "bold", "align": "end", "flex": 4},
+				map[string]interface{}{"type": "text", "text": formatNumber(summary.THB), "size": "sm", "weight": "bold", "color": "#E74C3C", "align": "end", "flex": 4},
+			},
+		},
+		map[string]interface{}{"type": "text", "text": fmt.Sprintf("เฉลี่ย %s บาท/วัน", formatNumber(summary.THB/float64(len(summary.Days)))), "size": "xxs", "color": "#888888", "align": "end"},
+	)
+	if trip.Rate > 0 {
+		body = append(body, map[string]interface{}{"type": "text", "text": fmt.Sprintf("เรทปัจจุบัน 1 %s = %s บาท", trip.Currency, formatRate(trip.Rate)), "size": "xxs", "color": "#AAAAAA", "align": "end"})
+	}
+
+	subtitle := fmt.Sprintf("%s - %s", formatThaiShortDate(trip.StartDate), formatThaiShortDate(trip.EndDate))
+	if note != "" {
+		subtitle = note
+	}
+	flex := map[string]interface{}{
+		"type": "bubble",
+		"header": map[string]interface{}{
+			"type":            "box",
+			"layout":          "vertical",
+			"backgroundColor": "#0984E3",
+			"paddingAll":      "md",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "✈️ ทริป" + trip.Destination, "color": "#FFFFFF", "weight": "bold", "size": "md"},
+				map[string]interface{}{"type": "text", "text": subtitle, "color": "#FFFFFF", "size": "xs", "wrap": true},
+			},
+		},
+		"body": map[string]interface{}{
+			"type":       "box",
+			"layout":     "vertical",
+			"paddingAll": "md",
+			"contents":   body,
+		},
+	}
+	text := fmt.Sprintf("ทริป%s ใช้ไป %s (%s บาท) ใน %d วัน", trip.Destination,
+		formatForeign(summary.Foreign, trip.Currency), formatNumber(summary.THB), len(summary.Days))
+	if !h.replyFlexFromAI(replyToken, flex, text) {
+		h.replyText(replyToken, strings.TrimSpace(note+"\n"+text))
+	}
+}
+
+// tripSavedText replaces the AI message after amounts were converted ("✈️ ราเมง ¥1,200 ≈ 276 บาท")
+func tripSavedText(txs []services.TransactionData) string {
+	var lines []string
+	var rate string
+	for _, tx := range txs {
+		if tx.ForeignAmount <= 0 {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("✈️ %s %s ≈ %s บาท", orDefault(tx.Description, tx.Category),
+			formatForeign(tx.ForeignAmount, tx.Currency), formatNumber(tx.Amount)))
+		rate = fmt.Sprintf("เรท 1 %s = %s บาท", tx.Currency, formatRate(tx.FXRate))
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "บันทึกแล้วค่ะ\n" + strings.Join(lines, "\n") + "\n(" + rate + " • จ่ายเป็นบาทพิมพ์ บาท ต่อท้าย)"
+}
+
+// formatRate shows an exchange rate with up to 4 decimals ("0.2291")
+func formatRate(rate float64) string {
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.4f", rate), "0"), ".")
+}
+
+// applyTripToReceipt converts a receipt photographed during a trip; Thai receipts were paid in baht
+func (h *LineWebhookHandler) applyTripToReceipt(ctx context.Context, userID string, tx *services.TransactionData) {
+	settings, err := h.mongo.GetUserSettings(ctx, userID)
+	if err != nil || settings == nil || settings.Trip == nil {
+		return
+	}
+	// Receipts are saved on the day they're sent, whatever date is printed
+	txs := []services.TransactionData{*tx}
+	txs[0].Date = time.Now().Format("2006-01-02")
+	services.ApplyTripCurrency(settings.Trip, txs, services.NormalizeReceiptLanguage(tx.Language) == "th")
+	txs[0].Date = tx.Date
+	*tx = txs[0]
+}
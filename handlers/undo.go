@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+	"github.com/satisatang/backend/services"
+)
+
+// undoQuickReply is the "↩️ ไม่ใช่อันนี้" button of an undoable AI action
+func undoQuickReply(token string) *messaging_api.QuickReply {
+	return &messaging_api.QuickReply{Items: []messaging_api.QuickReplyItem{
+		{Action: &messaging_api.PostbackAction{Label: "↩️ ไม่ใช่อันนี้", Data: "action=undo&token=" + token, DisplayText: "↩️ ไม่ใช่อันนี้"}},
+	}}
+}
+
+// rememberUpdateUndo keeps before/after of an AI edit for UndoWindow and returns its undo button
+// (nil when the edit changed nothing)
+func (h *LineWebhookHandler) rememberUpdateUndo(ctx context.Context, userID, txID, date string, before *services.Transaction) *messaging_api.QuickReply {
+	if before == nil {
+		return nil
+	}
+	after, err := h.mongo.GetTransactionOnDate(ctx, userID, txID, date)
+	if err != nil {
+		return nil
+	}
+	action := services.UndoAction{
+		Kind:   services.UndoKindUpdate,
+		TxID:   txID,
+		Date:   date,
+		Before: services.UndoStateOf(*before),
+		After:  services.UndoStateOf(*after),
+	}
+	if action.Before == action.After {
+		return nil
+	}
+	token, err := h.mongo.RememberUndo(ctx, userID, action)
+	if err != nil {
+		log.Printf("Failed to remember undo: %v", err)
+		return nil
+	}
+	return undoQuickReply(token)
+}
+
+// rememberTransferUndo keeps an AI transfer undoable for UndoWindow and returns its undo button
+func (h *LineWebhookHandler) rememberTransferUndo(ctx context.Context, userID, transferID, date string) *messaging_api.QuickReply {
+	token, err := h.mongo.RememberUndo(ctx, userID, services.UndoAction{Kind: services.UndoKindTransfer, TransferID: transferID, Date: date})
+	if err != nil {
+		log.Printf("Failed to remember undo: %v", err)
+		return nil
+	}
+	return undoQuickReply(token)
+}
+
+// replyTextWithQuickReply sends text with quick reply buttons (plain text when quickReply is nil)
+func (h *LineWebhookHandler) replyTextWithQuickReply(replyToken, text string, quickReply *messaging_api.QuickReply) {
+	_, err := h.sendReply(&messaging_api.ReplyMessageRequest{
+		ReplyToken: replyToken,
+		Messages:   []messaging_api.MessageInterface{messaging_api.TextMessage{Text: text, QuickReply: quickReply}},
+	})
+	if err != nil {
+		log.Printf("Failed to send reply: %v", err)
+	}
+}
+
+// handleUndo reverses the AI update/transfer or delete the tapped "↩️ ไม่ใช่อันนี้" belongs to
+func (h *LineWebhookHandler) handleUndo(ctx context.Context, replyToken, userID string, params map[string]string) {
+	action, err := h.mongo.Undo(ctx, userID, params["token"])
+	switch {
+	case errors.Is(err, services.ErrUndoExpired):
+		h.replyText(replyToken, "⌛ ย้อนกลับไม่ได้แล้วค่ะ (เกิน 5 นาที หรือมีรายการใหม่กว่า)\nแก้ไขเองได้จากปุ่ม ✏️ หรือ 🗑️ ของรายการนั้น")
+		return
+	case errors.Is(err, services.ErrUndoConflict):
+		h.replyText(replyToken, "รายการนี้ถูกแก้ไขไปแล้วหลังจากนั้น เลยไม่ได้ย้อนกลับค่ะ")
+		return
+	case err != nil:
+		if msg, locked := periodLockedText(err); locked {
+			h.replyText(replyToken, msg)
+			return
+		}
+		log.Printf("Failed to undo: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ ย้อนกลับไม่สำเร็จ กรุณาลองใหม่อีกครั้ง")
+		return
+	}
+
+	done := "↩️ ย้อนการแก้ไขกลับแล้วค่ะ"
+	switch action.Kind {
+	case services.UndoKindTransfer:
+		done = "↩️ ยกเลิกการโอนแล้วค่ะ"
+	case services.UndoKindDelete:
+		done = "↩️ กู้คืนรายการที่ลบแล้วค่ะ"
+	}
+	h.replyText(replyToken, fmt.Sprintf("%s\n\n%s", done, h.getBalanceText(ctx, userID)))
+}
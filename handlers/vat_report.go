@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// isVATReportCommand checks if message asks for VAT report ("รายงาน VAT")
+func isVATReportCommand(text string) bool {
+	normalized := strings.ToLower(strings.ReplaceAll(text, " ", ""))
+	return strings.Contains(normalized, "รายงานvat") || strings.Contains(normalized, "vatreport") || strings.Contains(normalized, "รายงานภาษีซื้อ")
+}
+
+// replyVATReport exports deductible receipts as Excel (period from message, default this month)
+func (h *LineWebhookHandler) replyVATReport(ctx context.Context, replyToken, userID, text string) {
+	from, to := "", ""
+	if r, ok := services.ParseThaiDate(text, time.Now()); ok {
+		from, to = r.FromString(), r.ToString()
+	}
+
+	data, filename, err := h.export.ExportVATReport(ctx, userID, from, to)
+	if err != nil {
+		log.Printf("Failed to export VAT report: %v", err)
+		h.replyText(replyToken, "ไม่สามารถสร้างรายงาน VAT ได้ กรุณาลองใหม่")
+		return
+	}
+	h.replyAndSendFile(replyToken, userID, "🧾 รายงาน VAT (ใบเสร็จที่มีภาษีซื้อ)", data, filename, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satisatang/backend/services"
+)
+
+// WarmupHandler primes connections and caches on a fresh serverless instance (GET /warmup)
+type WarmupHandler struct {
+	mongo *services.MongoDBService
+	ai    *services.AIService
+	flags *services.FeatureFlagService
+}
+
+// NewWarmupHandler creates a new warm-up handler
+func NewWarmupHandler(mongo *services.MongoDBService, ai *services.AIService, flags *services.FeatureFlagService) *WarmupHandler {
+	return &WarmupHandler{mongo: mongo, ai: ai, flags: flags}
+}
+
+// HandleWarmup runs every warm-up step; returns 503 when Mongo is unreachable so the platform retries
+// Steps are idempotent and cheap once warm (fonts/excel run once per process)
+func (h *WarmupHandler) HandleWarmup(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 20*time.Second)
+	defer cancel()
+
+	steps := []services.WarmUpStep{
+		services.RunWarmUpStep("mongo", func() error { return h.mongo.Ping(ctx) }),
+		services.RunWarmUpStep("prompts", func() error {
+			if !h.ai.PromptsReady() {
+				return errors.New("prompt files not found, using built-in defaults")
+			}
+			return nil
+		}),
+		services.RunWarmUpStep("feature_flags", func() error {
+			h.flags.Prime(ctx)
+			return nil
+		}),
+		services.RunWarmUpStep("pdf_fonts", services.WarmUpFonts),
+		services.RunWarmUpStep("excel", services.WarmUpExcel),
+	}
+
+	status := http.StatusOK
+	if steps[0].Error != "" {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, gin.H{"status": http.StatusText(status), "steps": steps})
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// warrantyCommandPattern matches a standalone "เก็บใบเสร็จประกัน 2 ปี" for the latest entry
+var warrantyCommandPattern = regexp.MustCompile(`^(?:เก็บ)?(?:ใบเสร็จ)?\s*(?:รับ)?ประกัน\s*\d+\s*(?:ปี|เดือน)$`)
+
+// warrantySuffixPattern finds "เก็บใบเสร็จประกัน 2 ปี" / "รับประกัน 1 ปี" inside a purchase message
+// (plain "ค่าประกัน 2 ปี" is an insurance expense, so it isn't matched)
+var warrantySuffixPattern = regexp.MustCompile(`\s*(?:เก็บใบเสร็จ\s*(?:รับ)?|รับ)ประกัน\s*\d+\s*(?:ปี|เดือน)\s*`)
+
+// maxWarrantyReceiptLinks limits receipt uploads per warranty list
+const maxWarrantyReceiptLinks = 5
+
+// parseWarrantyCommand returns warranty months of a standalone command (no AI)
+func parseWarrantyCommand(text string) (int, bool) {
+	text = strings.TrimSpace(text)
+	if !warrantyCommandPattern.MatchString(text) {
+		return 0, false
+	}
+	return services.ParseWarrantyPeriod(text)
+}
+
+// stripWarrantyPeriod removes the warranty phrase from a purchase message ("ซื้อทีวี 25000 ประกัน 2 ปี")
+// so the "2" can't be mistaken for an amount; months is 0 when there is none
+func stripWarrantyPeriod(text string) (string, int) {
+	match := warrantySuffixPattern.FindString(text)
+	if match == "" {
+		return text, 0
+	}
+	months, ok := services.ParseWarrantyPeriod(match)
+	if !ok {
+		return text, 0
+	}
+	stripped := strings.TrimSpace(warrantySuffixPattern.ReplaceAllString(text, " "))
+	if stripped == "" {
+		return text, 0
+	}
+	return stripped, months
+}
+
+// handleWarrantyCommand stores the warranty of the latest entry (e.g. a receipt photo just sent)
+func (h *LineWebhookHandler) handleWarrantyCommand(ctx context.Context, replyToken, userID string, months int) {
+	recent, err := h.mongo.GetRecentTransactions(ctx, userID, 1)
+	if err != nil || len(recent) == 0 {
+		h.replyText(replyToken, "ยังไม่มีรายการให้เก็บประกันค่ะ ส่งรูปใบเสร็จหรือบันทึกรายการก่อนนะคะ")
+		return
+	}
+	r := recent[0]
+	w, err := h.mongo.SaveWarranty(ctx, userID, r.Transaction.ID.Hex(), r.Date, months)
+	if err != nil {
+		log.Printf("Failed to save warranty: %v", err)
+		h.replyText(replyToken, "ไม่สามารถบันทึกประกันได้ กรุณาลองใหม่")
+		return
+	}
+	h.replyText(replyToken, warrantySavedText(w))
+}
+
+// saveWarrantyForBatch stores the warranty on the most expensive saved expense of a batch
+func (h *LineWebhookHandler) saveWarrantyForBatch(ctx context.Context, userID string, txs []services.TransactionData, txIDs []string, months int) *services.Warranty {
+	best := -1
+	for i, tx := range txs {
+		if tx.Type == "income" || txIDs[i] == "" {
+			continue
+		}
+		if best < 0 || tx.Amount > txs[best].Amount {
+			best = i
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+	w, err := h.mongo.SaveWarranty(ctx, userID, txIDs[best], txs[best].Date, months)
+	if err != nil {
+		log.Printf("Failed to save warranty: %v", err)
+		return nil
+	}
+	return w
+}
+
+// warrantySavedText confirms a stored warranty
+func warrantySavedText(w *services.Warranty) string {
+	text := fmt.Sprintf("🛡️ เก็บใบเสร็จประกัน %s %s บาท ถึง %s แล้วค่ะ\nจะเตือนก่อนหมดประกัน 30 วัน", w.Description, formatNumber(w.Amount), formatWarrantyDate(w.ExpiresAt))
+	if !w.HasReceipt {
+		text += "\n(รายการนี้ยังไม่มีรูปใบเสร็จ)"
+	}
+	return text
+}
+
+// formatWarrantyDate formats YYYY-MM-DD as DD/MM/YYYY
+func formatWarrantyDate(date string) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return t.Format("02/01/2006")
+}
+
+// replyWarrantyList lists active warranties with links to their receipt images
+func (h *LineWebhookHandler) replyWarrantyList(ctx context.Context, replyToken, userID string) {
+	warranties, err := h.mongo.GetWarranties(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to get warranties: %v", err)
+		h.replyText(replyToken, "ไม่สามารถดึงข้อมูลได้ กรุณาลองใหม่")
+		return
+	}
+	if len(warranties) == 0 {
+		h.replyText(replyToken, "ยังไม่มีใบเสร็จประกันค่ะ\nส่งรูปใบเสร็จแล้วพิมพ์ \"เก็บใบเสร็จประกัน 2 ปี\"")
+		return
+	}
+
+	soon := time.Now().AddDate(0, 0, 30).Format("2006-01-02")
+	links := 0
+	contents := []interface{}{}
+	for i, w := range warranties {
+		if i >= 10 {
+			break
+		}
+		color := "#888888"
+		if w.ExpiresAt <= soon {
+			color = "#E67E22" // Expiring soon
+		}
+		item := []interface{}{
+			map[string]interface{}{
+				"type": "box", "layout": "horizontal",
+				"contents": []interface{}{
+					map[string]interface{}{"type": "text", "text": truncateLabel(w.Description, 24), "size": "sm", "weight": "bold", "flex": 3, "wrap": true},
+					map[string]interface{}{"type": "text", "text": formatNumber(w.Amount), "size": "sm", "align": "end", "flex": 2},
+				},
+			},
+			map[string]interface{}{"type": "text", "text": fmt.Sprintf("ซื้อ %s • หมด %s", formatWarrantyDate(w.PurchaseDate), formatWarrantyDate(w.ExpiresAt)), "size": "xxs", "color": color},
+		}
+		if w.HasReceipt && h.firebase != nil && links < maxWarrantyReceiptLinks {
+			if url := h.warrantyReceiptURL(ctx, userID, w); url != "" {
+				links++
+				item = append(item, map[string]interface{}{
+					"type": "button", "style": "link", "height": "sm",
+					"action": map[string]interface{}{"type": "uri", "label": "🧾 ดูใบเสร็จ", "uri": url},
+				})
+			}
+		}
+		if i > 0 {
+			contents = append(contents, map[string]interface{}{"type": "separator", "margin": "md"})
+		}
+		contents = append(contents, map[string]interface{}{"type": "box", "layout": "vertical", "margin": "md", "contents": item})
+	}
+
+	flex := map[string]interface{}{
+		"type": "bubble",
+		"size": "kilo",
+		"header": map[string]interface{}{
+			"type":            "box",
+			"layout":          "vertical",
+			"backgroundColor": "#16A085",
+			"paddingAll":      "md",
+			"contents": []interface{}{
+				map[string]interface{}{"type": "text", "text": "🛡️ ใบเสร็จประกันสินค้า", "weight": "bold", "color": "#FFFFFF", "size": "md"},
+				map[string]interface{}{"type": "text", "text": fmt.Sprintf("%d รายการ", len(warranties)), "size": "xs", "color": "#FFFFFF"},
+			},
+		},
+		"body": map[string]interface{}{
+			"type":       "box",
+			"layout":     "vertical",
+			"paddingAll": "md",
+			"contents":   contents,
+		},
+	}
+	if !h.replyFlexFromAI(replyToken, flex, fmt.Sprintf("ใบเสร็จประกัน %d รายการ", len(warranties))) {
+		lines := []string{"🛡️ ใบเสร็จประกันสินค้า"}
+		for _, w := range warranties {
+			lines = append(lines, fmt.Sprintf("• %s %s บาท หมด %s", w.Description, formatNumber(w.Amount), formatWarrantyDate(w.ExpiresAt)))
+		}
+		h.replyText(replyToken, strings.Join(lines, "\n"))
+	}
+}
+
+// warrantyReceiptURL uploads the receipt image and returns its download link ("" on failure)
+func (h *LineWebhookHandler) warrantyReceiptURL(ctx context.Context, userID string, w services.Warranty) string {
+	data, mimeType, err := h.mongo.GetWarrantyReceipt(ctx, userID, w)
+	if err != nil || data == nil {
+		return ""
+	}
+	ext := ".jpg"
+	if mimeType == "image/png" {
+		ext = ".png"
+	}
+	url, _, err := h.uploadForDownload(ctx, userID, data, "receipt_"+w.TransactionID+ext, mimeType)
+	if err != nil {
+		log.Printf("Failed to upload warranty receipt: %v", err)
+		return ""
+	}
+	return url
+}
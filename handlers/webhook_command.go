@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/satisatang/backend/services"
+)
+
+// webhookSubcommands map sub command words to action
+var webhookSubcommands = map[string]string{
+	"เพิ่ม":   "add",
+	"add":     "add",
+	"ลบ":      "remove",
+	"remove":  "remove",
+	"ทดสอบ":   "test",
+	"test":    "test",
+	"เปิด":    "enable",
+	"enable":  "enable",
+	"ปิด":     "disable",
+	"disable": "disable",
+}
+
+// parseWebhookCommand parses "webhook [เพิ่ม <url>|ลบ <n>|ทดสอบ|เปิด <n>|ปิด <n>]"
+// Returns action ("list" when no sub command) and argument
+func parseWebhookCommand(text string) (action, arg string, ok bool) {
+	fields := strings.Fields(strings.TrimSpace(text))
+	if len(fields) == 0 {
+		return "", "", false
+	}
+	first := strings.ToLower(fields[0])
+	if first != "webhook" && first != "เว็บฮุค" {
+		return "", "", false
+	}
+	if len(fields) == 1 {
+		return "list", "", true
+	}
+	action, known := webhookSubcommands[strings.ToLower(fields[1])]
+	if !known {
+		return "list", "", true
+	}
+	if len(fields) > 2 {
+		arg = fields[2]
+	}
+	return action, arg, true
+}
+
+// handleWebhookCommand manages user's outbound webhook endpoints
+func (h *LineWebhookHandler) handleWebhookCommand(ctx context.Context, replyToken, userID, action, arg string) {
+	if action == "add" {
+		endpoint, err := h.mongo.AddWebhookEndpoint(ctx, userID, arg)
+		if err != nil {
+			h.replyText(replyToken, fmt.Sprintf("ไม่สามารถเพิ่ม webhook ได้: %v\nตัวอย่าง: webhook เพิ่ม https://example.com/hook", err))
+			return
+		}
+		h.mongo.LogSecurityEvent(ctx, services.SecurityEvent{LineID: userID, Event: services.SecurityTokenIssued, Detail: "webhook secret for " + endpoint.URL})
+		h.replyText(replyToken, fmt.Sprintf("🔗 เพิ่ม webhook แล้วค่ะ\n%s\n\nทุกรายการใหม่/แก้ไขจะส่งเป็น JSON (POST)\nตรวจลายเซ็นจาก header X-Satisatang-Signature (HMAC-SHA256)\n\n🔑 Secret (แสดงครั้งเดียว):\n%s\n\nพิมพ์ \"webhook ทดสอบ\" เพื่อส่งข้อมูลทดสอบ", endpoint.URL, endpoint.Secret))
+		return
+	}
+
+	endpoints, err := h.mongo.GetWebhookEndpoints(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to get webhook endpoints: %v", err)
+		h.replyText(replyToken, "ขออภัยค่ะ ไม่สามารถดึงข้อมูล webhook ได้")
+		return
+	}
+	if len(endpoints) == 0 {
+		h.replyText(replyToken, "ยังไม่มี webhook ค่ะ\nเพิ่มด้วย: webhook เพิ่ม https://example.com/hook\n(ใช้กับ Google Apps Script เพื่อส่งเข้า Google Sheets ได้)")
+		return
+	}
+
+	// Commands with index refer to numbers shown in the list
+	var target *services.WebhookEndpoint
+	if action == "remove" || action == "enable" || action == "disable" {
+		index, err := strconv.Atoi(arg)
+		if err != nil || index < 1 || index > len(endpoints) {
+			h.replyText(replyToken, fmt.Sprintf("กรุณาระบุหมายเลข 1-%d เช่น \"webhook ลบ 1\"", len(endpoints)))
+			return
+		}
+		target = &endpoints[index-1]
+	}
+
+	switch action {
+	case "remove":
+		if err := h.mongo.DeleteWebhookEndpoint(ctx, userID, target.ID); err != nil {
+			h.replyText(replyToken, "ไม่สามารถลบ webhook ได้ กรุณาลองใหม่")
+			return
+		}
+		h.replyText(replyToken, "ลบ webhook แล้วค่ะ\n"+target.URL)
+
+	case "enable", "disable":
+		if err := h.mongo.SetWebhookEndpointActive(ctx, userID, target.ID, action == "enable"); err != nil {
+			h.replyText(replyToken, "ไม่สามารถตั้งค่า webhook ได้ กรุณาลองใหม่")
+			return
+		}
+		status := "เปิด"
+		if action == "disable" {
+			status = "ปิด"
+		}
+		h.replyText(replyToken, fmt.Sprintf("%s webhook แล้วค่ะ\n%s", status, target.URL))
+
+	case "test":
+		var lines []string
+		for i, endpoint := range endpoints {
+			status, err := h.webhooks.SendPing(ctx, endpoint)
+			result := fmt.Sprintf("✅ %d", status)
+			if err != nil {
+				result = "❌ " + err.Error()
+			}
+			lines = append(lines, fmt.Sprintf("%d. %s\n   %s", i+1, truncateLabel(endpoint.URL, 40), result))
+		}
+		h.replyText(replyToken, "🧪 ผลทดสอบ webhook\n"+strings.Join(lines, "\n"))
+
+	default:
+		var lines []string
+		for i, endpoint := range endpoints {
+			status := "🟢 ใช้งาน"
+			if !endpoint.Active {
+				status = "⚪ ปิด"
+			}
+			if endpoint.FailCount > 0 {
+				status += fmt.Sprintf(" (ล้มเหลว %d ครั้งติด)", endpoint.FailCount)
+			}
+			lines = append(lines, fmt.Sprintf("%d. %s\n   %s", i+1, truncateLabel(endpoint.URL, 40), status))
+		}
+		h.replyText(replyToken, "🔗 Webhook ของคุณ\n"+strings.Join(lines, "\n")+"\n\nคำสั่ง: webhook เพิ่ม <url> | ลบ <n> | เปิด <n> | ปิด <n> | ทดสอบ")
+	}
+}
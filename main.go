@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/satisatang/backend/config"
@@ -16,16 +19,44 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Error reporting (Sentry when DSN is set, otherwise log only)
+	if cfg.SentryDSN != "" {
+		reporter, err := services.NewSentryReporter(cfg.SentryDSN, cfg.SentryEnvironment)
+		if err != nil {
+			log.Printf("Warning: Sentry disabled: %v", err)
+		} else {
+			services.SetErrorReporter(reporter)
+		}
+	}
+
+	services.SetPDFFontLite(cfg.PDFFontLite)
+
 	// Initialize MongoDB service
 	mongoService, err := services.NewMongoDBService(cfg.MongoDBURI, cfg.MongoDBName)
 	if err != nil {
 		log.Fatalf("Failed to initialize MongoDB service: %v", err)
 	}
 	defer mongoService.Close()
+	if err := mongoService.ConfigureChatRetention(cfg.ChatHistoryLimit, cfg.ChatArchiveDays); err != nil {
+		log.Printf("Warning: Failed to configure chat archive retention: %v", err)
+	}
+	if err := mongoService.EnsureAnalyticsIndexes(); err != nil {
+		log.Printf("Warning: Failed to create analytics TTL index: %v", err)
+	}
+	if cfg.HashChainSecret == "" {
+		log.Println("Warning: HASH_CHAIN_SECRET not set, transaction hash chains are plain SHA-256")
+	}
+	mongoService.SetHashChainKey(cfg.HashChainSecret)
 
 	// Initialize AI service
 	aiService := services.NewAIService()
 	defer aiService.Close()
+	if cfg.AITwoStage {
+		aiService.EnableTwoStage()
+	}
+	if cfg.PromptWatchIntervalSecond > 0 {
+		go aiService.WatchPrompts(context.Background(), time.Duration(cfg.PromptWatchIntervalSecond)*time.Second)
+	}
 
 	// Initialize Firebase service (optional)
 	var firebaseService *services.FirebaseService
@@ -41,11 +72,44 @@ func main() {
 		log.Println("Firebase not configured - file upload feature disabled")
 	}
 
+	// Initialize Google Sheets sync (optional)
+	var sheetsService *services.SheetsService
+	if cfg.HasGoogleSheets() {
+		sheetsService = services.NewSheetsService(mongoService, cfg.GoogleClientID, cfg.GoogleClientSecret, strings.TrimRight(cfg.PublicBaseURL, "/")+"/sheets/callback")
+	} else {
+		log.Println("Google Sheets not configured - sheets sync disabled")
+	}
+
 	// Initialize Line webhook handler
-	lineWebhook, err := handlers.NewLineWebhookHandler(cfg.LineChannelSecret, cfg.LineChannelAccessToken, aiService, mongoService, firebaseService)
+	lineWebhook, err := handlers.NewLineWebhookHandler(cfg.LineChannelSecret, cfg.LineChannelAccessToken, aiService, mongoService, firebaseService, sheetsService, cfg.PublicBaseURL, cfg.LIFFID)
 	if err != nil {
 		log.Fatalf("Failed to initialize Line webhook handler: %v", err)
 	}
+	if cfg.LinePushEnabled {
+		lineWebhook.EnablePush()
+	}
+	lineWebhook.SetAdmins(cfg.AdminLineIDs)
+	mongoService.AddSecurityAlertHook(lineWebhook.AlertSecurity)
+	var backupService *services.BackupService
+	if firebaseService != nil {
+		backupService = services.NewBackupService(mongoService, firebaseService)
+		lineWebhook.SetBackup(backupService)
+	}
+	featureFlags := services.NewFeatureFlagService(mongoService, services.ParseFeatureFlags(cfg.FeatureFlags))
+	lineWebhook.SetFeatureFlags(featureFlags)
+	if verifier := services.NewSlipVerifier(cfg.SlipVerifyURL, cfg.SlipVerifyAPIKey); verifier != nil {
+		lineWebhook.SetSlipVerifier(verifier)
+	} else {
+		log.Println("Slip verification not configured - slips are recorded without bank check")
+	}
+	if cfg.FXRateURL != "off" {
+		lineWebhook.SetFXRates(services.NewFXRates(cfg.FXRateURL))
+	}
+	lineWebhook.SetImageCompression(services.ImageCompressOptions{
+		MaxDimension: cfg.ImageMaxDimension,
+		MaxBytes:     cfg.ImageMaxKB << 10,
+		Quality:      cfg.ImageJPEGQuality,
+	})
 
 	// Initialize Proxy Handler
 	proxyHandler := handlers.NewProxyHandler()
@@ -54,19 +118,105 @@ func main() {
 	if cfg.GinMode == "release" {
 		gin.SetMode(gin.ReleaseMode)
 	}
-	r := gin.Default()
+	r := gin.New()
+	r.Use(gin.Logger(), handlers.RecoveryMiddleware())
 
 	// Health check
 	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok", "service": "satisatang"})
+		c.JSON(200, gin.H{"status": "ok", "service": "satisatang", "reply": lineWebhook.ReplyMetrics()})
 	})
 
-	// Line webhook
-	r.POST("/webhook/line", lineWebhook.HandleWebhook)
+	// Warm-up for serverless cold starts (call from the platform's warm-up hook or a scheduler)
+	warmupHandler := handlers.NewWarmupHandler(mongoService, aiService, featureFlags)
+	r.GET("/warmup", warmupHandler.HandleWarmup)
+
+	// Line webhook (path and middleware chain from config)
+	if err := handlers.RegisterWebhookRoute(r, cfg.LineWebhookPath, "primary", cfg.LineWebhookMiddleware, lineWebhook.HandleWebhook); err != nil {
+		log.Fatalf("Failed to register LINE webhook: %v", err)
+	}
+
+	// Secondary webhook: canary of the same bot, or a second bot persona with its own channel
+	if cfg.LineWebhookSecondaryPath != "" {
+		secondary := lineWebhook
+		if cfg.HasSecondaryPersona() {
+			secondary, err = lineWebhook.WithChannel(cfg.LineSecondaryChannelSecret, cfg.LineSecondaryChannelAccessToken)
+			if err != nil {
+				log.Fatalf("Failed to initialize secondary Line webhook handler: %v", err)
+			}
+			if cfg.LinePushEnabled {
+				secondary.EnablePush()
+			}
+		}
+		if err := handlers.RegisterWebhookRoute(r, cfg.LineWebhookSecondaryPath, "secondary", cfg.LineWebhookSecondaryMiddleware, secondary.HandleWebhook); err != nil {
+			log.Fatalf("Failed to register secondary LINE webhook: %v", err)
+		}
+	}
 
 	// AI API Proxy
 	r.POST("/api/chat", proxyHandler.HandleChat)
 
+	// Short download links (files stay private in storage)
+	if firebaseService != nil {
+		downloadHandler := handlers.NewDownloadHandler(mongoService, firebaseService)
+		r.GET("/d/:token", downloadHandler.HandleDownload)
+	}
+
+	// Scheduled backups (call daily from Cloud Scheduler / cron)
+	if backupService != nil && cfg.BackupCronSecret != "" {
+		backupHandler := handlers.NewBackupCronHandler(backupService, cfg.BackupCronSecret)
+		r.POST("/cron/backup", backupHandler.HandleBackup)
+	}
+
+	// Reminders before pending slips/edits expire (call every minute; pushes only with LINE_PUSH_ENABLED)
+	if cfg.PendingReminderCronSecret != "" {
+		reminderHandler := handlers.NewPendingReminderCronHandler(lineWebhook, cfg.PendingReminderCronSecret)
+		r.POST("/cron/pending-reminders", reminderHandler.HandlePendingReminders)
+	}
+
+	// Cold-data archival: days older than ARCHIVE_AFTER_YEARS move to daily_records_archive (call weekly)
+	if cfg.ArchiveCronSecret != "" && cfg.ArchiveAfterYears > 0 {
+		archiveHandler := handlers.NewArchiveCronHandler(mongoService, cfg.ArchiveAfterYears, cfg.ArchiveCronSecret)
+		r.POST("/cron/archive", archiveHandler.HandleArchive)
+	}
+
+	// Prompt iterations without redeploy: reread prompts/*.md (also done by PROMPT_WATCH_INTERVAL)
+	if cfg.PromptReloadSecret != "" {
+		promptHandler := handlers.NewPromptReloadHandler(aiService, cfg.PromptReloadSecret)
+		r.POST("/admin/reload-prompts", promptHandler.HandleReload)
+	}
+
+	// Google Sheets OAuth
+	if sheetsService != nil {
+		sheetsHandler := handlers.NewSheetsHandler(sheetsService)
+		r.GET("/sheets/connect", sheetsHandler.HandleConnect)
+		r.GET("/sheets/callback", sheetsHandler.HandleCallback)
+	}
+
+	// LIFF number pad for amount correction
+	if cfg.LIFFID != "" {
+		liffHandler := handlers.NewLIFFHandler(mongoService, cfg.LIFFID)
+		r.GET("/liff/amount", liffHandler.HandleAmountPage)
+		r.POST("/api/liff/amount", liffHandler.HandleAmountUpdate)
+	}
+
+	// Differential sync for the mobile/web client (LINE Login access token of the user)
+	if channelID := cfg.SyncChannelID(); channelID != "" {
+		syncHandler := handlers.NewSyncHandler(mongoService, channelID)
+		r.GET("/api/v1/sync", syncHandler.HandleSync)
+	}
+
+	// Monthly close report as JSON for accountants/external tools (per-user report token)
+	closeReportHandler := handlers.NewCloseReportHandler(mongoService)
+	r.GET("/api/v1/close-report", closeReportHandler.HandleCloseReport)
+
+	// Read-only iCal feed of bills and card due dates
+	calendarHandler := handlers.NewCalendarHandler(mongoService)
+	r.GET("/cal/:token", calendarHandler.HandleFeed)
+
+	// Read-only report pages of time-limited share links
+	shareHandler := handlers.NewShareHandler(mongoService)
+	r.GET("/s/:token", shareHandler.HandleShare)
+
 	// Start server
 	log.Printf("Starting Satisatang server on port %s", cfg.Port)
 	if err := r.Run(":" + cfg.Port); err != nil {
@@ -0,0 +1,174 @@
+// Package migrations applies versioned MongoDB schema changes tracked in schema_migrations
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// collectionName stores applied versions
+const collectionName = "schema_migrations"
+
+// Migration is one reversible schema change
+// Up and Down must be safe to re-run (e.g. create/drop index by name)
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+	Down    func(ctx context.Context, db *mongo.Database) error
+}
+
+// AppliedMigration is a row in schema_migrations
+type AppliedMigration struct {
+	Version   int       `bson:"version"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+// Validate checks versions are positive, unique and every migration has Up and Down
+func Validate(all []Migration) error {
+	seen := make(map[int]bool)
+	for _, m := range all {
+		if m.Version <= 0 {
+			return fmt.Errorf("migration %q: version must be positive", m.Name)
+		}
+		if seen[m.Version] {
+			return fmt.Errorf("migration %d: duplicate version", m.Version)
+		}
+		if m.Up == nil || m.Down == nil {
+			return fmt.Errorf("migration %d: up and down are required", m.Version)
+		}
+		seen[m.Version] = true
+	}
+	return nil
+}
+
+// Pending returns migrations not yet applied, oldest first
+func Pending(all []Migration, applied map[int]bool) []Migration {
+	var pending []Migration
+	for _, m := range sorted(all) {
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+// sorted returns migrations ordered by version
+func sorted(all []Migration) []Migration {
+	list := append([]Migration(nil), all...)
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list
+}
+
+// Runner applies migrations against a database
+type Runner struct {
+	db         *mongo.Database
+	migrations []Migration
+}
+
+// NewRunner creates runner for the registered migrations (All)
+func NewRunner(db *mongo.Database) *Runner {
+	return &Runner{db: db, migrations: All}
+}
+
+// Applied returns applied migrations, oldest first
+func (r *Runner) Applied(ctx context.Context) ([]AppliedMigration, error) {
+	cursor, err := r.db.Collection(collectionName).Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"version": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var applied []AppliedMigration
+	if err := cursor.All(ctx, &applied); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// appliedSet returns applied versions as a set
+func (r *Runner) appliedSet(ctx context.Context) (map[int]bool, error) {
+	applied, err := r.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[int]bool, len(applied))
+	for _, a := range applied {
+		set[a.Version] = true
+	}
+	return set, nil
+}
+
+// Up applies pending migrations up to target version (0 = all), returns number applied
+func (r *Runner) Up(ctx context.Context, target int) (int, error) {
+	if err := Validate(r.migrations); err != nil {
+		return 0, err
+	}
+	applied, err := r.appliedSet(ctx)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, m := range Pending(r.migrations, applied) {
+		if target > 0 && m.Version > target {
+			break
+		}
+		log.Printf("Applying migration %d %s", m.Version, m.Name)
+		if err := m.Up(ctx, r.db); err != nil {
+			return count, fmt.Errorf("migration %d %s: %w", m.Version, m.Name, err)
+		}
+		if _, err := r.db.Collection(collectionName).InsertOne(ctx, AppliedMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Down rolls back the latest steps applied migrations (newest first), returns number rolled back
+func (r *Runner) Down(ctx context.Context, steps int) (int, error) {
+	applied, err := r.appliedSet(ctx)
+	if err != nil {
+		return 0, err
+	}
+	list := sorted(r.migrations)
+	count := 0
+	for i := len(list) - 1; i >= 0 && count < steps; i-- {
+		m := list[i]
+		if !applied[m.Version] {
+			continue
+		}
+		log.Printf("Rolling back migration %d %s", m.Version, m.Name)
+		if err := m.Down(ctx, r.db); err != nil {
+			return count, fmt.Errorf("rollback %d %s: %w", m.Version, m.Name, err)
+		}
+		if _, err := r.db.Collection(collectionName).DeleteOne(ctx, bson.M{"version": m.Version}); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Status returns every registered migration with whether it is applied
+func (r *Runner) Status(ctx context.Context) ([]string, error) {
+	applied, err := r.appliedSet(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, m := range sorted(r.migrations) {
+		state := "pending"
+		if applied[m.Version] {
+			state = "applied"
+		}
+		lines = append(lines, fmt.Sprintf("%4d  %-8s %s", m.Version, state, m.Name))
+	}
+	return lines, nil
+}
@@ -0,0 +1,236 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// All lists migrations in version order; append new ones, never renumber applied ones
+var All = []Migration{
+	{
+		Version: 1,
+		Name:    "daily_records_lineid_date_index",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndex(ctx, db.Collection("daily_records"), "lineid_date", bson.D{{Key: "lineid", Value: 1}, {Key: "date", Value: -1}}, nil)
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndex(ctx, db.Collection("daily_records"), "lineid_date")
+		},
+	},
+	{
+		Version: 2,
+		Name:    "temp_data_key_and_ttl_indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			coll := db.Collection("temp_data")
+			if err := createIndex(ctx, coll, "key", bson.D{{Key: "key", Value: 1}}, options.Index().SetUnique(true)); err != nil {
+				return err
+			}
+			// Expired pending confirmations are removed by Mongo instead of on read
+			return createIndex(ctx, coll, "expires_at_ttl", bson.D{{Key: "expires_at", Value: 1}}, options.Index().SetExpireAfterSeconds(0))
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			coll := db.Collection("temp_data")
+			if err := dropIndex(ctx, coll, "expires_at_ttl"); err != nil {
+				return err
+			}
+			return dropIndex(ctx, coll, "key")
+		},
+	},
+	{
+		Version: 3,
+		Name:    "user_collections_lineid_indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			for _, name := range []string{"transfers", "budgets", "subscriptions", "card_accounts"} {
+				if err := createIndex(ctx, db.Collection(name), "lineid", bson.D{{Key: "lineid", Value: 1}}, nil); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			for _, name := range []string{"transfers", "budgets", "subscriptions", "card_accounts"} {
+				if err := dropIndex(ctx, db.Collection(name), "lineid"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 4,
+		Name:    "user_profiles_lineid_unique_index",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndex(ctx, db.Collection("user_profiles"), "lineid", bson.D{{Key: "lineid", Value: 1}}, options.Index().SetUnique(true))
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndex(ctx, db.Collection("user_profiles"), "lineid")
+		},
+	},
+	{
+		Version: 5,
+		Name:    "transactions_is_transfer_flag",
+		// Transfers were recognized by the category "โอนเงิน" only; flag them so totals no longer depend on the name
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			for _, list := range []string{"incomes", "expenses"} {
+				_, err := db.Collection("daily_records").UpdateMany(ctx,
+					bson.M{list: bson.M{"$elemMatch": legacyTransfer("")}},
+					bson.M{"$set": bson.M{list + ".$[t].is_transfer": true}},
+					options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{legacyTransfer("t.")}}),
+				)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			for _, list := range []string{"incomes", "expenses"} {
+				_, err := db.Collection("daily_records").UpdateMany(ctx,
+					bson.M{list + ".is_transfer": true},
+					bson.M{"$unset": bson.M{list + ".$[t].is_transfer": ""}},
+					options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"t.is_transfer": true}}}),
+				)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 6,
+		Name:    "user_projections_lineid_unique_index",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndex(ctx, db.Collection("user_projections"), "lineid", bson.D{{Key: "lineid", Value: 1}}, options.Index().SetUnique(true))
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndex(ctx, db.Collection("user_projections"), "lineid")
+		},
+	},
+	{
+		Version: 7,
+		Name:    "daily_records_archive_lineid_date_index",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndex(ctx, db.Collection("daily_records_archive"), "lineid_date", bson.D{{Key: "lineid", Value: 1}, {Key: "date", Value: -1}}, nil)
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndex(ctx, db.Collection("daily_records_archive"), "lineid_date")
+		},
+	},
+	{
+		Version: 8,
+		Name:    "sync_changes_indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			changes := db.Collection("sync_changes")
+			if err := createIndex(ctx, changes, "lineid_seq", bson.D{{Key: "lineid", Value: 1}, {Key: "seq", Value: 1}}, options.Index().SetUnique(true)); err != nil {
+				return err
+			}
+			// Matches services.SyncRetention (90 days)
+			if err := createIndex(ctx, changes, "created_at_ttl", bson.D{{Key: "created_at", Value: 1}}, options.Index().SetExpireAfterSeconds(90*24*60*60)); err != nil {
+				return err
+			}
+			return createIndex(ctx, db.Collection("sync_counters"), "lineid", bson.D{{Key: "lineid", Value: 1}}, options.Index().SetUnique(true))
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			changes := db.Collection("sync_changes")
+			if err := dropIndex(ctx, changes, "lineid_seq"); err != nil {
+				return err
+			}
+			if err := dropIndex(ctx, changes, "created_at_ttl"); err != nil {
+				return err
+			}
+			return dropIndex(ctx, db.Collection("sync_counters"), "lineid")
+		},
+	},
+	{
+		Version: 9,
+		Name:    "tx_hash_chain_unique_seq_index",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndex(ctx, db.Collection("tx_hash_chain"), "lineid_month_seq", bson.D{{Key: "lineid", Value: 1}, {Key: "month", Value: 1}, {Key: "seq", Value: 1}}, options.Index().SetUnique(true))
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndex(ctx, db.Collection("tx_hash_chain"), "lineid_month_seq")
+		},
+	},
+	{
+		Version: 10,
+		Name:    "tx_hash_chain_pending_index",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndex(ctx, db.Collection("tx_hash_chain_pending"), "lineid_recorded_at", bson.D{{Key: "lineid", Value: 1}, {Key: "recorded_at", Value: 1}}, nil)
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndex(ctx, db.Collection("tx_hash_chain_pending"), "lineid_recorded_at")
+		},
+	},
+	{
+		Version: 11,
+		Name:    "share_links_token_index",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			coll := db.Collection("share_links")
+			// Every /s/:token view and revoke looks a link up by token
+			if err := createIndex(ctx, coll, "token", bson.D{{Key: "token", Value: 1}}, options.Index().SetUnique(true)); err != nil {
+				return err
+			}
+			return createIndex(ctx, coll, "lineid_created_at", bson.D{{Key: "lineid", Value: 1}, {Key: "created_at", Value: -1}}, nil)
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			coll := db.Collection("share_links")
+			if err := dropIndex(ctx, coll, "lineid_created_at"); err != nil {
+				return err
+			}
+			return dropIndex(ctx, coll, "token")
+		},
+	},
+	{
+		Version: 12,
+		Name:    "balance_snapshot_state_lineid_index",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndex(ctx, db.Collection("balance_snapshot_state"), "lineid", bson.D{{Key: "lineid", Value: 1}}, options.Index().SetUnique(true))
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndex(ctx, db.Collection("balance_snapshot_state"), "lineid")
+		},
+	},
+	{
+		Version: 13,
+		Name:    "user_settings_report_token_index",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// The close report API looks users up by token; only users who asked for one have it
+			opts := options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"report_token": bson.M{"$type": "string"}})
+			return createIndex(ctx, db.Collection("user_settings"), "report_token", bson.D{{Key: "report_token", Value: 1}}, opts)
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndex(ctx, db.Collection("user_settings"), "report_token")
+		},
+	},
+}
+
+// legacyTransfer matches an income/expense entry saved as a transfer before the is_transfer flag;
+// prefix is the array filter identifier ("t.") or "" inside $elemMatch
+func legacyTransfer(prefix string) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{prefix + "transfer_id": bson.M{"$nin": bson.A{nil, ""}}},
+		bson.M{prefix + "category": "โอนเงิน"},
+	}}
+}
+
+// createIndex creates a named index (no-op if it already exists with the same spec)
+func createIndex(ctx context.Context, coll *mongo.Collection, name string, keys bson.D, opts *options.IndexOptions) error {
+	if opts == nil {
+		opts = options.Index()
+	}
+	_, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: keys, Options: opts.SetName(name)})
+	return err
+}
+
+// dropIndex drops a named index, ignoring "index not found"
+func dropIndex(ctx context.Context, coll *mongo.Collection, name string) error {
+	_, err := coll.Indexes().DropOne(ctx, name)
+	if cmdErr, ok := err.(mongo.CommandError); ok && cmdErr.Name == "IndexNotFound" {
+		return nil
+	}
+	return err
+}
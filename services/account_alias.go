@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// AccountAlias is a user's nickname for an account ("บัญชีเงินเดือน" = กสิกร xxx-1234), kept on the profile
+// so the AI schema and the deterministic parsers resolve it without another read
+type AccountAlias struct {
+	Alias          string `bson:"alias" json:"alias"`
+	UseType        int    `bson:"usetype" json:"usetype"`
+	BankName       string `bson:"bankname,omitempty" json:"bankname,omitempty"`
+	CreditCardName string `bson:"creditcardname,omitempty" json:"creditcardname,omitempty"`
+}
+
+// Account returns the account the alias stands for
+func (a AccountAlias) Account() AccountRef {
+	return AccountRef{UseType: a.UseType, BankName: a.BankName, CreditCardName: a.CreditCardName}
+}
+
+// Target returns the real account's name ("เงินสด" for cash)
+func (a AccountAlias) Target() string {
+	if a.UseType == 0 {
+		return "เงินสด"
+	}
+	return a.Account().Name()
+}
+
+// aliasKey normalizes a nickname for matching: case, spaces and a leading "บัญชี" don't matter
+// ("บัญชีเงินเดือน", "บัญชี เงินเดือน" and "เงินเดือน" are the same alias)
+func aliasKey(name string) string {
+	key := strings.ToLower(strings.Join(strings.Fields(name), ""))
+	if trimmed := strings.TrimPrefix(key, "บัญชี"); trimmed != "" {
+		key = trimmed
+	}
+	return key
+}
+
+// FindAccountAlias returns the alias matching name
+func FindAccountAlias(aliases []AccountAlias, name string) (AccountAlias, bool) {
+	key := aliasKey(name)
+	if key == "" {
+		return AccountAlias{}, false
+	}
+	for _, a := range aliases {
+		if aliasKey(a.Alias) == key {
+			return a, true
+		}
+	}
+	return AccountAlias{}, false
+}
+
+// ResolveAliasPayment replaces a nickname the AI put in bankname/creditcardname with the real account
+func ResolveAliasPayment(aliases []AccountAlias, useType *int, bankName, creditCardName *string) bool {
+	for _, name := range []string{*bankName, *creditCardName} {
+		if a, ok := FindAccountAlias(aliases, name); ok {
+			*useType, *bankName, *creditCardName = a.UseType, a.BankName, a.CreditCardName
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveTransferAliases replaces nicknames in every transfer entry with the real accounts
+func ResolveTransferAliases(aliases []AccountAlias, transfer *TransferData) {
+	if transfer == nil || len(aliases) == 0 {
+		return
+	}
+	for _, entries := range [][]TransferEntry{transfer.From, transfer.To} {
+		for i := range entries {
+			e := &entries[i]
+			ResolveAliasPayment(aliases, &e.UseType, &e.BankName, &e.CreditCardName)
+		}
+	}
+}
+
+// ResolveTransactionAliases replaces nicknames in the payment of each transaction with the real accounts
+func ResolveTransactionAliases(aliases []AccountAlias, txs []TransactionData) {
+	if len(aliases) == 0 {
+		return
+	}
+	for i := range txs {
+		tx := &txs[i]
+		ResolveAliasPayment(aliases, &tx.UseType, &tx.BankName, &tx.CreditCardName)
+	}
+}
+
+// ResolveKnownAccount resolves a nickname, "เงินสด" or a bank/card the user already has; unknown names
+// return false so free text (a person's name, a shop) is left to the AI
+func ResolveKnownAccount(name string, banks, cards []string, aliases []AccountAlias) (AccountRef, bool) {
+	name = strings.TrimSpace(name)
+	if a, ok := FindAccountAlias(aliases, name); ok {
+		return a.Account(), true
+	}
+	if name == "เงินสด" {
+		return AccountRef{UseType: 0}, true
+	}
+	for _, p := range paymentTargetPrefixes {
+		if strings.HasPrefix(name, p.prefix) {
+			name = strings.TrimSpace(strings.TrimPrefix(name, p.prefix))
+			break
+		}
+	}
+	for _, c := range cards {
+		if c != "" && strings.EqualFold(c, name) {
+			return AccountRef{UseType: 1, CreditCardName: c}, true
+		}
+	}
+	for _, b := range banks {
+		if b != "" && strings.EqualFold(b, name) {
+			return AccountRef{UseType: 2, BankName: b}, true
+		}
+	}
+	return AccountRef{}, false
+}
+
+// accountTransferPattern matches "โอน 5000 จากบัญชีเงินเดือนไปบัญชีออม" / "โอนจาก กสิกร ไป เงินสด 500"
+var accountTransferPattern = regexp.MustCompile(`^โอน(?:เงิน)?\s*(.*?)\s*จาก\s*(.+?)\s*(?:ไปยัง|ไปที่|ไปเข้า|ไป|เข้า)\s*(.+)$`)
+
+// trailingAmountPattern splits "บัญชีออม 5,000 บาท" into target and amount
+var trailingAmountPattern = regexp.MustCompile(`^(.+?)\s*(\d[\d,]*(?:\.\d+)?\s*(?:[kK]|พัน|หมื่น|แสน|ล้าน)?)\s*(?:บาท)?$`)
+
+// ParseAccountTransfer parses a transfer between two known accounts (nicknames included) without AI
+func ParseAccountTransfer(text string, banks, cards []string, aliases []AccountAlias) (*TransferData, bool) {
+	m := accountTransferPattern.FindStringSubmatch(strings.TrimSpace(thaiDigitReplacer.Replace(text)))
+	if m == nil {
+		return nil, false
+	}
+	amountText, fromText, toText := m[1], m[2], m[3]
+	if amountText == "" {
+		tail := trailingAmountPattern.FindStringSubmatch(toText)
+		if tail == nil {
+			return nil, false
+		}
+		toText, amountText = tail[1], tail[2]
+	}
+	amount, ok := ParseThaiAmount(amountText)
+	if !ok || amount <= 0 {
+		return nil, false
+	}
+
+	from, ok := ResolveKnownAccount(fromText, banks, cards, aliases)
+	if !ok {
+		return nil, false
+	}
+	to, ok := ResolveKnownAccount(toText, banks, cards, aliases)
+	if !ok || from == to {
+		return nil, false
+	}
+	return &TransferData{
+		From:        []TransferEntry{{Amount: amount, UseType: from.UseType, BankName: from.BankName, CreditCardName: from.CreditCardName}},
+		To:          []TransferEntry{{Amount: amount, UseType: to.UseType, BankName: to.BankName, CreditCardName: to.CreditCardName}},
+		Description: fmt.Sprintf("โอนจาก%s ไป%s", strings.TrimSpace(fromText), strings.TrimSpace(toText)),
+	}, true
+}
+
+// AliasNames returns the profile's account nicknames
+func (p *UserProfile) AliasNames() []string {
+	names := make([]string, 0, len(p.AccountAliases))
+	for _, a := range p.AccountAliases {
+		names = append(names, a.Alias)
+	}
+	return names
+}
+
+// SetAccountAlias creates or replaces a nickname for an account
+func (s *MongoDBService) SetAccountAlias(ctx context.Context, lineID, alias string, account AccountRef) error {
+	profile, err := s.GetUserProfile(ctx, lineID) // builds the profile on first use
+	if err != nil {
+		return err
+	}
+	aliases := []AccountAlias{{
+		Alias:          strings.TrimSpace(alias),
+		UseType:        account.UseType,
+		BankName:       account.BankName,
+		CreditCardName: account.CreditCardName,
+	}}
+	for _, a := range profile.AccountAliases {
+		if aliasKey(a.Alias) != aliasKey(alias) {
+			aliases = append(aliases, a)
+		}
+	}
+	_, err = s.profileCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID},
+		bson.M{"$set": bson.M{"account_aliases": aliases, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+// DeleteAccountAlias removes a nickname, false if there was none
+func (s *MongoDBService) DeleteAccountAlias(ctx context.Context, lineID, alias string) (bool, error) {
+	profile, err := s.GetUserProfile(ctx, lineID)
+	if err != nil {
+		return false, err
+	}
+	found, ok := FindAccountAlias(profile.AccountAliases, alias)
+	if !ok {
+		return false, nil
+	}
+	_, err = s.profileCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID},
+		bson.M{
+			"$pull": bson.M{"account_aliases": bson.M{"alias": found.Alias}},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+	)
+	return err == nil, err
+}
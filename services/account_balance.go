@@ -0,0 +1,31 @@
+package services
+
+import "strings"
+
+// ParseAccountBalanceQuery parses "ยอด กสิกร" / "ยอดบัญชีเงินเดือน" / "ยอด เงินสด" (the balance flex quick replies)
+// Only a known bank, card or nickname matches, so "ยอดคงเหลือ" and other questions stay with the AI
+func ParseAccountBalanceQuery(text string, banks, cards []string, aliases []AccountAlias) (AccountRef, bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "ยอด") {
+		return AccountRef{}, false
+	}
+	name := strings.TrimSpace(strings.TrimPrefix(text, "ยอด"))
+	if name == "" {
+		return AccountRef{}, false
+	}
+	return ResolveKnownAccount(name, banks, cards, aliases)
+}
+
+// FindPaymentBalance returns the balance of account from GetBalanceByPaymentType (zero balance if never used)
+func FindPaymentBalance(balances []PaymentBalance, account AccountRef) PaymentBalance {
+	for _, pb := range balances {
+		if pb.UseType != account.UseType {
+			continue
+		}
+		if (account.UseType == 1 && strings.EqualFold(pb.CreditCardName, account.CreditCardName)) ||
+			(account.UseType != 1 && strings.EqualFold(pb.BankName, account.BankName)) {
+			return pb
+		}
+	}
+	return PaymentBalance{UseType: account.UseType, BankName: account.BankName, CreditCardName: account.CreditCardName}
+}
@@ -10,8 +10,8 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -26,23 +26,41 @@ type TransactionData struct {
 	Merchant       string            `json:"merchant"`
 	Amount         float64           `json:"amount"`
 	Category       string            `json:"category"`
-	Type           string            `json:"type"`
+	Type           string            `json:"type"` // "income", "expense" or "investment"
 	Description    string            `json:"description"`
 	Items          []TransactionItem `json:"items"`
 	UseType        int               `json:"usetype"` // 0=เงินสด, 1=บัตรเครดิต, 2=ธนาคาร
 	BankName       string            `json:"bankname"`
 	CreditCardName string            `json:"creditcardname"`
+	Project        string            `json:"project"` // ลูกค้า/โปรเจกต์ (business mode, stored in CustName)
 	// Slip-specific fields
-	FromName    string `json:"from_name"`    // ผู้โอน
-	FromBank    string `json:"from_bank"`    // ธนาคารผู้โอน
-	FromAccount string `json:"from_account"` // เลขบัญชีผู้โอน
-	ToName      string `json:"to_name"`      // ผู้รับ
-	ToBank      string `json:"to_bank"`      // ธนาคารผู้รับ
-	ToAccount   string `json:"to_account"`   // เลขบัญชีผู้รับ
-	RefNo       string `json:"ref_no"`       // เลขอ้างอิง
+	FromName    string  `json:"from_name"`             // ผู้โอน
+	FromBank    string  `json:"from_bank"`             // ธนาคารผู้โอน
+	FromAccount string  `json:"from_account"`          // เลขบัญชีผู้โอน
+	ToName      string  `json:"to_name"`               // ผู้รับ
+	ToBank      string  `json:"to_bank"`               // ธนาคารผู้รับ
+	ToAccount   string  `json:"to_account"`            // เลขบัญชีผู้รับ
+	RefNo       string  `json:"ref_no"`                // เลขอ้างอิง
+	Fee         float64 `json:"fee"`                   // ค่าธรรมเนียมโอน (not included in Amount)
+	SlipStatus  string  `json:"slip_status,omitempty"` // set in Go by slip verification (SlipVerified, ...)
+	SlipReason  string  `json:"slip_reason,omitempty"`
+	// Tax invoice fields (receipts)
+	VATAmount     float64 `json:"vat"`                 // ภาษีมูลค่าเพิ่ม
+	ServiceCharge float64 `json:"service_charge"`      // ค่าบริการ
+	TaxID         string  `json:"tax_id"`              // เลขประจำตัวผู้เสียภาษีของร้าน
+	ReceiptNo     string  `json:"receipt_no"`          // เลขที่ใบเสร็จ/ใบกำกับภาษี
+	Language      string  `json:"language,omitempty"`  // receipt language from the OCR pass (th, en, ja, zh)
+	DateText      string  `json:"date_text,omitempty"` // date as printed, converted in Go for foreign receipts
+	// Travel mode, set in Go (Amount is converted to THB)
+	Currency      string  `json:"currency,omitempty"`
+	ForeignAmount float64 `json:"foreign_amount,omitempty"`
+	FXRate        float64 `json:"fx_rate,omitempty"`
+	Trip          string  `json:"trip,omitempty"`
 	// Image storage fields
-	ImageBase64   string `json:"image_base64,omitempty"`   // รูปภาพ base64
+	ImageBase64   string `json:"image_base64,omitempty"`    // รูปภาพ base64
 	ImageMimeType string `json:"image_mime_type,omitempty"` // mime type ของรูป
+	// Set in Go for auto-captured entries that go to the review queue
+	Source string `json:"source,omitempty"` // "image", "sms"
 }
 
 // TransferEntry represents a single transfer source or destination
@@ -99,11 +117,12 @@ type QueryFilter struct {
 	Keyword    string   `json:"keyword"`    // search keyword
 	GroupBy    string   `json:"group_by"`   // "category", "date", "payment", "none"
 	Limit      int      `json:"limit"`      // max results
+	Period     string   `json:"period"`     // "month", "week" (for compare), "year" (for income)
 }
 
 // AIResponse represents the AI's response with action
 type AIResponse struct {
-	Action       string            `json:"action"`       // "new", "update", "transfer", "balance", "search", "analyze", "budget", "export", "chat"
+	Action       string            `json:"action"`       // "new", "update", "transfer", "balance", "search", "analyze", "compare", "income", "budget", "export", "chart", "chat", "list_names"
 	Transactions []TransactionData `json:"transactions"` // for "new" action
 	Transfer     *TransferData     `json:"transfer"`     // for "transfer" action
 	UpdateField  string            `json:"update_field"` // "amount", "usetype", etc.
@@ -128,16 +147,22 @@ const (
 // AIChat interface for AI services
 type AIChat interface {
 	ChatWithContext(ctx context.Context, message string, lastTxInfo string, chatHistory string) (string, error)
+	GenerateAdvice(ctx context.Context, summaryText string) (string, error)
 	ProcessReceiptImage(ctx context.Context, imageData io.Reader, mimeType string) (*TransactionData, error)
+	ProcessReceiptImages(ctx context.Context, images []ReceiptImage) (*TransactionData, error)
 	Close() error
 }
 
 // AIService handles AI chat via external API
 type AIService struct {
-	httpClient     *http.Client
-	systemPrompt   string
-	examplesPrompt string
-	receiptPrompt  string
+	httpClient *http.Client
+
+	// Prompt files, swapped as a whole by ReloadPrompts (each request keeps the set it started with)
+	promptsMu sync.RWMutex
+	prompts   *PromptSet
+
+	// Two-stage pipeline (intent classifier, then action-specific extraction)
+	twoStage bool // on for everyone, otherwise per request via WithTwoStageAI
 }
 
 // AIAPIRequest represents the request to AI API
@@ -170,33 +195,11 @@ func NewAIService() *AIService {
 			Timeout: aiAPITimeout,
 		},
 	}
-	svc.loadPrompts()
+	svc.prompts = LoadPromptSet(findPromptsDir())
+	log.Printf("Loaded prompts from: %s (version %s)", svc.prompts.Dir, svc.prompts.Version)
 	return svc
 }
 
-// loadPrompts loads prompt templates from markdown files
-func (s *AIService) loadPrompts() {
-	// Try to find prompts directory
-	promptsDir := findPromptsDir()
-
-	// Load system prompt
-	s.systemPrompt = loadPromptFile(filepath.Join(promptsDir, "system.md"))
-	if s.systemPrompt == "" {
-		s.systemPrompt = getDefaultSystemPrompt()
-	}
-
-	// Load examples prompt
-	s.examplesPrompt = loadPromptFile(filepath.Join(promptsDir, "examples.md"))
-
-	// Load receipt prompt
-	s.receiptPrompt = loadPromptFile(filepath.Join(promptsDir, "receipt.md"))
-	if s.receiptPrompt == "" {
-		s.receiptPrompt = getDefaultReceiptPrompt()
-	}
-
-	log.Printf("Loaded prompts from: %s", promptsDir)
-}
-
 // findPromptsDir finds the prompts directory
 func findPromptsDir() string {
 	// Try relative paths
@@ -227,25 +230,36 @@ func loadPromptFile(path string) string {
 
 func getDefaultSystemPrompt() string {
 	return `คุณคือ "สติสตางค์" ตอบ JSON เท่านั้น
-action: new|update|transfer|balance|search|analyze|budget|export|chat
+action: new|update|transfer|balance|search|analyze|compare|income|budget|export|chat
 usetype: 0=เงินสด, 1=บัตรเครดิต, 2=ธนาคาร
-type: income|expense`
+type: income|expense|investment`
 }
 
 func getDefaultReceiptPrompt() string {
-	return `วิเคราะห์ใบเสร็จนี้และตอบเป็น JSON: {"date":"YYYY-MM-DD","merchant":"ร้าน","amount":0,"category":"หมวด","type":"expense","description":"รายละเอียด","usetype":0}`
+	return `วิเคราะห์ใบเสร็จนี้และตอบเป็น JSON: {"date":"YYYY-MM-DD","merchant":"ร้าน","amount":0,"category":"หมวด","type":"expense","description":"รายละเอียด","usetype":0,"vat":0,"service_charge":0,"tax_id":"","receipt_no":""}`
 }
 
 // ChatWithContext sends a message to AI API with context
 // schema contains user's data structure: "ธนาคาร:SCB,KBank|บัตร:CITI|หมวด:อาหาร,เดินทาง"
 // chatHistory contains recent messages in format "user: xxx\nassistant: yyy\n..."
 func (s *AIService) ChatWithContext(ctx context.Context, message string, schema string, chatHistory string) (string, error) {
+	p := s.currentPrompts()
+	if s.useTwoStage(ctx, p) {
+		log.Printf("AI prompt version %s (two-stage)", p.Version)
+		response, err := s.chatTwoStage(ctx, p, message, schema, chatHistory)
+		if err == nil {
+			return response, nil
+		}
+		log.Printf("Two-stage AI failed, using single prompt: %v", err)
+	}
+	log.Printf("AI prompt version %s", p.Version)
+
 	// Build prompt with system instruction, examples, and context
-	prompt := s.systemPrompt
+	prompt := p.System
 
 	// Add examples if available
-	if s.examplesPrompt != "" {
-		prompt += "\n\n" + s.examplesPrompt
+	if p.Examples != "" {
+		prompt += "\n\n" + p.Examples
 	}
 
 	prompt += "\n\n---\n\n"
@@ -262,6 +276,25 @@ func (s *AIService) ChatWithContext(ctx context.Context, message string, schema
 
 	prompt += "\n\nผู้ใช้: " + message
 
+	return s.sendPrompt(ctx, prompt)
+}
+
+// GenerateAdvice asks AI for a short plain-text advice sentence based on Go-computed summary
+// Uses a minimal prompt (no system prompt/examples) to save tokens
+func (s *AIService) GenerateAdvice(ctx context.Context, summaryText string) (string, error) {
+	prompt := "คุณคือ \"สติสตางค์\" ผู้ช่วยการเงินส่วนตัว\n" +
+		"จากสรุปต่อไปนี้ ให้คำแนะนำสั้นๆ 1 ประโยค (ไม่เกิน 80 ตัวอักษร) เป็นข้อความธรรมดา ห้ามตอบ JSON ห้ามคำนวณตัวเลขใหม่\n\n" +
+		summaryText
+
+	response, err := s.sendPrompt(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return cleanJSONResponse(response), nil
+}
+
+// sendPrompt sends a prompt to AI API and returns the response text
+func (s *AIService) sendPrompt(ctx context.Context, prompt string) (string, error) {
 	// Call AI API
 	reqBody := AIAPIRequest{Message: prompt}
 	jsonBody, err := json.Marshal(reqBody)
@@ -316,6 +349,12 @@ func (s *AIService) ChatWithContext(ctx context.Context, message string, schema
 	return geminiResp.Candidates[0].Content.Parts[0].Text, nil
 }
 
+// ReceiptImage is one photo of a receipt
+type ReceiptImage struct {
+	Data     []byte
+	MimeType string
+}
+
 // ProcessReceiptImage processes receipt image via AI API simplified image endpoint
 func (s *AIService) ProcessReceiptImage(ctx context.Context, imageData io.Reader, mimeType string) (*TransactionData, error) {
 	// Read image data
@@ -323,27 +362,64 @@ func (s *AIService) ProcessReceiptImage(ctx context.Context, imageData io.Reader
 	if err != nil {
 		return nil, fmt.Errorf("failed to read image data: %w", err)
 	}
+	return s.ProcessReceiptImages(ctx, []ReceiptImage{{Data: imgBytes, MimeType: mimeType}})
+}
 
-	// Convert to base64
-	base64Image := base64.StdEncoding.EncodeToString(imgBytes)
+// ProcessReceiptImages reads several photos of one long receipt as a single transaction
+func (s *AIService) ProcessReceiptImages(ctx context.Context, images []ReceiptImage) (*TransactionData, error) {
+	if len(images) == 0 {
+		return nil, fmt.Errorf("no images")
+	}
 
 	// Use receipt prompt from file + current date
-	receiptPrompt := s.receiptPrompt + "\n\nวันที่ปัจจุบัน: " + getCurrentDate()
+	p := s.currentPrompts()
+	log.Printf("AI receipt prompt version %s", p.Version)
+	txData, err := s.extractReceipt(ctx, p.Receipt, images)
+	if err != nil {
+		return nil, err
+	}
+
+	// Foreign receipts get a second pass with their language's prompt (the Thai prompt misreads
+	// dates, totals and items); Thai receipts and slips cost a single call as before
+	lang := NormalizeReceiptLanguage(txData.Language)
+	if prompt := p.ReceiptPrompt(lang); prompt != "" && lang != "th" && txData.ImageType != "slip" {
+		log.Printf("AI receipt language %s, prompt version %s", lang, p.Version)
+		if again, err := s.extractReceipt(ctx, prompt, images); err == nil {
+			again.Language = lang
+			txData = again
+		} else {
+			log.Printf("Receipt %s pass failed, keeping first pass: %v", lang, err)
+		}
+	}
+	if date, ok := ParseReceiptDate(txData.DateText, lang, time.Now()); ok {
+		txData.Date = date
+	}
+	return txData, nil
+}
+
+// extractReceipt sends one OCR/extraction request for the receipt images with prompt
+func (s *AIService) extractReceipt(ctx context.Context, prompt string, images []ReceiptImage) (*TransactionData, error) {
+	receiptPrompt := prompt + "\n\nวันที่ปัจจุบัน: " + getCurrentDate()
+	if len(images) > 1 {
+		receiptPrompt += fmt.Sprintf("\n\nรูปทั้ง %d รูปเป็นใบเสร็จใบเดียวกัน (ถ่ายต่อกันตามลำดับ) ให้รวมเป็นรายการเดียว ใช้ยอดรวมสุทธิท้ายใบเสร็จ ห้ามนับรายการซ้ำในส่วนที่ซ้อนกัน", len(images))
+	}
+
+	parts := []map[string]interface{}{{"text": receiptPrompt}}
+	for _, img := range images {
+		parts = append(parts, map[string]interface{}{
+			"inlineData": map[string]string{
+				"mimeType": img.MimeType,
+				"data":     base64.StdEncoding.EncodeToString(img.Data),
+			},
+		})
+	}
 
 	// Use /api/chat with contents format (Gemini full mode)
 	reqBody := map[string]interface{}{
 		"contents": []map[string]interface{}{
 			{
-				"role": "user",
-				"parts": []map[string]interface{}{
-					{"text": receiptPrompt},
-					{
-						"inlineData": map[string]string{
-							"mimeType": mimeType,
-							"data":     base64Image,
-						},
-					},
-				},
+				"role":  "user",
+				"parts": parts,
 			},
 		},
 	}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// intentActions are actions the classifier may return (same as AIResponse.Action)
+var intentActions = []string{"new", "update", "transfer", "balance", "search", "analyze", "compare", "income", "budget", "export", "chart", "chat"}
+
+// paymentActions need usetype/bank name rules in the extraction prompt
+var paymentActions = map[string]bool{"new": true, "update": true, "transfer": true}
+
+// twoStageKey marks a request context for the two-stage pipeline (per-user feature flag)
+type twoStageKey struct{}
+
+// WithTwoStageAI requests two-stage pipeline for calls made with the returned context
+func WithTwoStageAI(ctx context.Context) context.Context {
+	return context.WithValue(ctx, twoStageKey{}, true)
+}
+
+// useTwoStage reports whether this call should classify intent first
+func (s *AIService) useTwoStage(ctx context.Context, p *PromptSet) bool {
+	if !p.HasPipeline() {
+		return false
+	}
+	requested, _ := ctx.Value(twoStageKey{}).(bool)
+	return s.twoStage || requested
+}
+
+// EnableTwoStage turns on intent classification before action-specific extraction
+// Falls back to the single prompt when intent/extract prompts are missing
+func (s *AIService) EnableTwoStage() {
+	if !s.currentPrompts().HasPipeline() {
+		log.Printf("Two-stage AI disabled: intent.md or extract.md not found")
+		return
+	}
+	s.twoStage = true
+}
+
+// ParsePromptSections splits a prompt file into sections keyed by "### name" headers
+func ParsePromptSections(content string) map[string]string {
+	sections := make(map[string]string)
+	name := ""
+	var body []string
+	flush := func() {
+		if name != "" {
+			sections[name] = strings.TrimSpace(strings.Join(body, "\n"))
+		}
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "### ") {
+			flush()
+			name = strings.TrimSpace(strings.TrimPrefix(line, "### "))
+			body = nil
+			continue
+		}
+		body = append(body, line)
+	}
+	flush()
+	return sections
+}
+
+// ParseIntent extracts action name from classifier response ("" if unknown)
+func ParseIntent(response string) string {
+	cleaned := strings.ToLower(cleanJSONResponse(response))
+	cleaned = strings.Trim(cleaned, " \t\r\n\"'`.:")
+	for _, action := range intentActions {
+		if cleaned == action {
+			return action
+		}
+	}
+	// Tolerate extra words ("action: new") by taking the first known word
+	for _, word := range strings.FieldsFunc(cleaned, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z')
+	}) {
+		for _, action := range intentActions {
+			if word == action {
+				return action
+			}
+		}
+	}
+	return ""
+}
+
+// classifyIntent asks AI for action only (small prompt, one word answer)
+func (s *AIService) classifyIntent(ctx context.Context, p *PromptSet, message, chatHistory string) (string, error) {
+	prompt := p.Intent
+	// Last assistant line helps short follow-ups like "แก้เป็น 150"
+	if lines := strings.Split(strings.TrimSpace(chatHistory), "\n"); chatHistory != "" {
+		prompt += "\n\nข้อความก่อนหน้า: " + lines[len(lines)-1]
+	}
+	prompt += "\n\nผู้ใช้: " + message
+
+	response, err := s.sendPrompt(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	intent := ParseIntent(response)
+	if intent == "" {
+		return "", fmt.Errorf("unknown intent: %s", response)
+	}
+	return intent, nil
+}
+
+// buildExtractPrompt builds narrow prompt with only the schema/rules of one action
+func (s *AIService) buildExtractPrompt(p *PromptSet, intent, message, schema, chatHistory string) string {
+	parts := []string{p.Extract["common"]}
+	if paymentActions[intent] {
+		parts = append(parts, p.Extract["payment"])
+	}
+	parts = append(parts, p.Extract[intent])
+
+	prompt := strings.Join(parts, "\n\n")
+	prompt += "\n\n---\n\nวันนี้: " + getCurrentDate()
+	if schema != "" {
+		prompt += "\nข้อมูลที่มี: " + schema
+	}
+	if chatHistory != "" {
+		prompt += "\n\nประวัติการสนทนา:\n" + chatHistory
+	}
+	prompt += "\n\nผู้ใช้: " + message
+	return prompt
+}
+
+// chatTwoStage classifies intent then extracts with the action-specific prompt
+func (s *AIService) chatTwoStage(ctx context.Context, p *PromptSet, message, schema, chatHistory string) (string, error) {
+	intent, err := s.classifyIntent(ctx, p, message, chatHistory)
+	if err != nil {
+		return "", err
+	}
+	if p.Extract[intent] == "" {
+		return "", fmt.Errorf("no extract prompt for intent %s", intent)
+	}
+	return s.sendPrompt(ctx, s.buildExtractPrompt(p, intent, message, schema, chatHistory))
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// thaiDigitReplacer converts Thai digits (๐-๙) to Arabic digits
+var thaiDigitReplacer = strings.NewReplacer(
+	"๐", "0", "๑", "1", "๒", "2", "๓", "3", "๔", "4",
+	"๕", "5", "๖", "6", "๗", "7", "๘", "8", "๙", "9",
+)
+
+// numericAmountPattern matches numbers like "1,500", "2.5k", "3 หมื่น", "1.2ล้าน"
+var numericAmountPattern = regexp.MustCompile(`\d[\d,]*(?:\.\d+)?\s*(?:[kKmM]|ร้อย|พัน|หมื่น|แสน|ล้าน)?`)
+
+// amountMultipliers are shorthand suffixes after a number
+var amountMultipliers = map[string]float64{
+	"k": 1e3, "K": 1e3, "m": 1e6, "M": 1e6,
+	"ร้อย": 100, "พัน": 1e3, "หมื่น": 1e4, "แสน": 1e5, "ล้าน": 1e6,
+}
+
+// thaiNumberWord is a token of Thai number words
+type thaiNumberWord struct {
+	word  string
+	value float64
+	unit  bool // true for สิบ, ร้อย, พัน, หมื่น, แสน, ล้าน
+}
+
+// thaiNumberWords are ordered longest first for greedy matching
+var thaiNumberWords = []thaiNumberWord{
+	{"ศูนย์", 0, false}, {"หนึ่ง", 1, false}, {"เอ็ด", 1, false}, {"สอง", 2, false}, {"ยี่", 2, false},
+	{"สาม", 3, false}, {"สี่", 4, false}, {"ห้า", 5, false}, {"หก", 6, false}, {"เจ็ด", 7, false},
+	{"แปด", 8, false}, {"เก้า", 9, false},
+	{"หมื่น", 1e4, true}, {"ล้าน", 1e6, true}, {"สิบ", 10, true}, {"ร้อย", 100, true},
+	{"พัน", 1e3, true}, {"แสน", 1e5, true}, {"ครึ่ง", 0.5, false},
+}
+
+// ParseThaiAmount parses a single amount such as "2.5k", "1,500", "๑๕๐", "สองร้อยห้าสิบ", "พันครึ่ง"
+func ParseThaiAmount(text string) (float64, bool) {
+	text = strings.TrimSpace(thaiDigitReplacer.Replace(text))
+	text = strings.TrimSuffix(strings.TrimSpace(strings.TrimSuffix(text, "บาท")), "฿")
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, false
+	}
+
+	if loc := numericAmountPattern.FindStringIndex(text); loc != nil && loc[0] == 0 && loc[1] == len(text) {
+		return parseNumericAmount(text)
+	}
+
+	value, consumed, ok := parseThaiWords(text)
+	if !ok || consumed != len(text) {
+		return 0, false
+	}
+	return value, true
+}
+
+// parseNumericAmount parses "1,500", "2.5k", "3 หมื่น"
+func parseNumericAmount(text string) (float64, bool) {
+	numEnd := 0
+	for numEnd < len(text) && (text[numEnd] >= '0' && text[numEnd] <= '9' || text[numEnd] == ',' || text[numEnd] == '.') {
+		numEnd++
+	}
+	value, err := strconv.ParseFloat(strings.ReplaceAll(text[:numEnd], ",", ""), 64)
+	if err != nil {
+		return 0, false
+	}
+	if suffix := strings.TrimSpace(text[numEnd:]); suffix != "" {
+		multiplier, ok := amountMultipliers[suffix]
+		if !ok {
+			return 0, false
+		}
+		value *= multiplier
+	}
+	return math.Round(value*100) / 100, true
+}
+
+// parseThaiWords parses Thai number words from the start of text
+// Returns value, bytes consumed and whether at least one word was read
+func parseThaiWords(text string) (float64, int, bool) {
+	var total, section, current, lastUnit float64
+	consumed, tokens := 0, 0
+
+	for consumed < len(text) {
+		matched := false
+		for _, w := range thaiNumberWords {
+			if !strings.HasPrefix(text[consumed:], w.word) {
+				continue
+			}
+			switch {
+			case w.word == "ครึ่ง":
+				if lastUnit == 0 {
+					return 0, 0, false
+				}
+				section += lastUnit / 2 // พันครึ่ง = 1500
+			case w.value == 1e6:
+				if section+current == 0 {
+					current = 1
+				}
+				total += (section + current) * 1e6
+				section, current = 0, 0
+				lastUnit = 1e6
+			case w.unit:
+				if current == 0 {
+					current = 1 // ร้อย = 100
+				}
+				section += current * w.value
+				current = 0
+				lastUnit = w.value
+			default:
+				current = w.value
+			}
+			consumed += len(w.word)
+			tokens++
+			matched = true
+			break
+		}
+		if !matched {
+			break
+		}
+	}
+
+	if tokens == 0 {
+		return 0, 0, false
+	}
+	return total + section + current, consumed, true
+}
+
+// ExtractAmounts returns all amounts found in a message (numbers, shorthand and Thai words)
+// Dates and times (15/10, 12:30) are skipped
+func ExtractAmounts(message string) []float64 {
+	text := thaiDigitReplacer.Replace(message)
+	var amounts []float64
+
+	for _, loc := range numericAmountPattern.FindAllStringIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		// Skip dates/times and parts of words like "5kg" or "7eleven"
+		if start > 0 && strings.ContainsAny(text[start-1:start], "/:-") {
+			continue
+		}
+		if end < len(text) {
+			next := rune(text[end])
+			if strings.ContainsRune("/:", next) || (next < unicode.MaxASCII && unicode.IsLetter(next)) {
+				continue
+			}
+		}
+		match := strings.TrimSpace(text[start:end])
+		if value, ok := parseNumericAmount(match); ok && value > 0 {
+			amounts = append(amounts, value)
+		}
+	}
+
+	// Thai number words need a unit and either 2+ words or a following "บาท"
+	// so common words like "ห้าง" or "พันธุ์" are not read as amounts
+	for i := 0; i < len(text); {
+		value, consumed, ok := parseThaiWords(text[i:])
+		if !ok {
+			_, size := nextRune(text[i:])
+			i += size
+			continue
+		}
+		word := text[i : i+consumed]
+		rest := strings.TrimSpace(text[i+consumed:])
+		if hasThaiUnit(word) && (countThaiWords(word) >= 2 || strings.HasPrefix(rest, "บาท")) && value > 0 {
+			amounts = append(amounts, value)
+		}
+		i += consumed
+	}
+
+	return amounts
+}
+
+// nextRune returns first rune and its byte size
+func nextRune(s string) (rune, int) {
+	for _, r := range s {
+		return r, len(string(r))
+	}
+	return 0, 1
+}
+
+// hasThaiUnit checks if Thai number words contain a unit word
+func hasThaiUnit(word string) bool {
+	for _, w := range thaiNumberWords {
+		if w.unit && strings.Contains(word, w.word) {
+			return true
+		}
+	}
+	return false
+}
+
+// countThaiWords counts Thai number word tokens in word
+func countThaiWords(word string) int {
+	count := 0
+	for i := 0; i < len(word); {
+		matched := false
+		for _, w := range thaiNumberWords {
+			if strings.HasPrefix(word[i:], w.word) {
+				i += len(w.word)
+				count++
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			break
+		}
+	}
+	return count
+}
+
+// AmountCheck is the result of validating AI's amount against the raw message
+type AmountCheck struct {
+	Parsed    []float64 `json:"parsed"`    // amounts found in message
+	Suggested float64   `json:"suggested"` // parsed amount to use instead (0 if unknown)
+	Mismatch  bool      `json:"mismatch"`
+}
+
+// CheckAmount validates AI's extracted amount against amounts parsed from the message
+func CheckAmount(message string, aiAmount float64) AmountCheck {
+	check := AmountCheck{Parsed: ExtractAmounts(message)}
+	if len(check.Parsed) == 0 {
+		return check // Nothing to validate against
+	}
+
+	var sum float64
+	for _, amount := range check.Parsed {
+		if math.Abs(amount-aiAmount) < 0.01 {
+			return check
+		}
+		sum += amount
+	}
+	if math.Abs(sum-aiAmount) < 0.01 {
+		return check // AI summed several items
+	}
+
+	check.Mismatch = true
+	if len(check.Parsed) == 1 {
+		check.Suggested = check.Parsed[0]
+	}
+	return check
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Analytics event sources
+const (
+	AnalyticsSourceAI       = "ai"
+	AnalyticsSourcePostback = "postback"
+)
+
+// analyticsRetention is how long raw events are kept (TTL index)
+const analyticsRetention = 90 * 24 * time.Hour
+
+// AnalyticsEvent is one anonymized product event, stored in analytics_events
+type AnalyticsEvent struct {
+	UserHash   string    `bson:"user_hash" json:"user_hash"` // never the LINE user ID
+	Action     string    `bson:"action" json:"action"`
+	Source     string    `bson:"source" json:"source"`
+	Success    bool      `bson:"success" json:"success"`
+	LatencyMs  int64     `bson:"latency_ms" json:"latency_ms"`
+	Correction bool      `bson:"correction" json:"correction"` // user fixed what was just recorded
+	CreatedAt  time.Time `bson:"created_at" json:"created_at"`
+}
+
+// AnalyticsActionStat is aggregated usage of one action
+type AnalyticsActionStat struct {
+	Action       string  `bson:"_id" json:"action"`
+	Count        int     `bson:"count" json:"count"`
+	Failures     int     `bson:"failures" json:"failures"`
+	Corrections  int     `bson:"corrections" json:"corrections"`
+	AvgLatencyMs float64 `bson:"avg_latency_ms" json:"avg_latency_ms"`
+	Users        int     `bson:"users" json:"users"`
+}
+
+// AnonymizeUserID hashes LINE user ID so events can count users without identifying them
+func AnonymizeUserID(lineID string) string {
+	sum := sha256.Sum256([]byte("satisatang:" + lineID))
+	return hex.EncodeToString(sum[:8])
+}
+
+// EnsureAnalyticsIndexes expires raw events after analyticsRetention
+func (s *MongoDBService) EnsureAnalyticsIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := s.analyticsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"created_at": 1},
+		Options: options.Index().SetName("analytics_ttl").SetExpireAfterSeconds(int32(analyticsRetention.Seconds())),
+	})
+	return err
+}
+
+// LogAnalyticsEvent stores event; errors are only logged (analytics must never break the user's action)
+func (s *MongoDBService) LogAnalyticsEvent(ctx context.Context, lineID string, event AnalyticsEvent) {
+	event.UserHash = AnonymizeUserID(lineID)
+	event.CreatedAt = time.Now()
+	if _, err := s.analyticsCollection.InsertOne(ctx, event); err != nil {
+		log.Printf("Failed to log analytics event %s: %v", event.Action, err)
+	}
+}
+
+// GetAnalyticsReport aggregates events of the last N days by action (most used first)
+func (s *MongoDBService) GetAnalyticsReport(ctx context.Context, days int) ([]AnalyticsActionStat, error) {
+	if days <= 0 {
+		days = 7
+	}
+	pipeline := []bson.M{
+		{"$match": bson.M{"created_at": bson.M{"$gte": time.Now().AddDate(0, 0, -days)}}},
+		{"$group": bson.M{
+			"_id":            "$action",
+			"count":          bson.M{"$sum": 1},
+			"failures":       bson.M{"$sum": bson.M{"$cond": []interface{}{"$success", 0, 1}}},
+			"corrections":    bson.M{"$sum": bson.M{"$cond": []interface{}{"$correction", 1, 0}}},
+			"avg_latency_ms": bson.M{"$avg": "$latency_ms"},
+			"user_set":       bson.M{"$addToSet": "$user_hash"},
+		}},
+		{"$project": bson.M{
+			"count": 1, "failures": 1, "corrections": 1, "avg_latency_ms": 1,
+			"users": bson.M{"$size": "$user_set"},
+		}},
+		{"$sort": bson.M{"count": -1}},
+	}
+	cursor, err := s.analyticsCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var stats []AnalyticsActionStat
+	if err := cursor.All(ctx, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,301 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ArchiveCarry is what a user's archived days add to all-time totals, so balances stay whole
+// after the records leave daily_records
+type ArchiveCarry struct {
+	Balances      []PaymentBalance `bson:"balances" json:"balances"`
+	TotalIncome   float64          `bson:"total_income" json:"total_income"`
+	TotalExpense  float64          `bson:"total_expense" json:"total_expense"`
+	TotalInvested float64          `bson:"total_invested" json:"total_invested"`
+}
+
+// ArchiveResult is the outcome of one archive run
+type ArchiveResult struct {
+	Through string `json:"through"`
+	Users   int    `json:"users"`
+	Records int    `json:"records"`
+}
+
+// ArchiveCutoff returns the last date (YYYY-MM-DD) archived when keeping years of hot data
+func ArchiveCutoff(now time.Time, years int) string {
+	return now.AddDate(-years, 0, -1).Format("2006-01-02")
+}
+
+// BuildArchiveCarry sums archived days the way GetBalanceByPaymentType and GetBalanceSummary do
+func BuildArchiveCarry(records []DailyRecord) ArchiveCarry {
+	var carry ArchiveCarry
+	balanceMap := make(map[string]*PaymentBalance)
+	for _, record := range records {
+		accumulatePaymentBalances(balanceMap, record.Incomes)
+		accumulatePaymentBalances(balanceMap, record.Expenses)
+		for _, tx := range record.Incomes {
+			switch {
+			case tx.Category == InvestmentCategory:
+				carry.TotalInvested -= tx.Amount
+			case !tx.IsTransfer:
+				carry.TotalIncome += tx.Amount
+			}
+		}
+		for _, tx := range record.Expenses {
+			switch {
+			case tx.Category == InvestmentCategory:
+				carry.TotalInvested += tx.Amount
+			case !tx.IsTransfer:
+				carry.TotalExpense += tx.Amount
+			}
+		}
+	}
+	carry.Balances = sortedPaymentBalances(balanceMap)
+	return carry
+}
+
+// seedBalances returns the carried balances keyed like accumulatePaymentBalances
+func (c *ArchiveCarry) seedBalances() map[string]*PaymentBalance {
+	balanceMap := make(map[string]*PaymentBalance)
+	if c == nil {
+		return balanceMap
+	}
+	for i := range c.Balances {
+		pb := c.Balances[i]
+		balanceMap[fmt.Sprintf("%d:%s:%s", pb.UseType, pb.BankName, pb.CreditCardName)] = &pb
+	}
+	return balanceMap
+}
+
+// hotRecordFilter limits filter to days still in daily_records; days an interrupted run already copied to
+// the archive are skipped so they never count twice
+func hotRecordFilter(filter bson.M, archivedThrough string) bson.M {
+	if archivedThrough == "" {
+		return filter
+	}
+	return bson.M{"$and": []bson.M{filter, {"date": bson.M{"$gt": archivedThrough}}}}
+}
+
+// errEnoughRecords stops eachDailyRecord once the caller has what it needs
+var errEnoughRecords = errors.New("enough records")
+
+// recordSource is a collection of daily records and the filter to read it with
+type recordSource struct {
+	coll   *mongo.Collection
+	filter bson.M
+}
+
+// eachDailyRecord streams a user's daily records matching filter, reading through to the archive when
+// from (the earliest date asked for, "" for all) reaches archived days; those come flagged Archived.
+// Archived days are all older than hot ones, so oldestFirst only decides which collection goes first.
+func (s *MongoDBService) eachDailyRecord(ctx context.Context, lineID, from string, filter bson.M, opts *options.FindOptions, oldestFirst bool, fn func(DailyRecord) error) error {
+	settings, err := s.GetUserSettings(ctx, lineID)
+	if err != nil {
+		return err
+	}
+	through := settings.ArchivedThrough
+
+	hot := recordSource{s.collection, hotRecordFilter(filter, through)}
+	sources := []recordSource{hot}
+	if through != "" && from <= through {
+		archived := recordSource{s.archiveCollection, filter}
+		if oldestFirst {
+			sources = []recordSource{archived, hot}
+		} else {
+			sources = append(sources, archived)
+		}
+	}
+
+	for _, src := range sources {
+		cursor, err := src.coll.Find(ctx, src.filter, opts)
+		if err != nil {
+			return err
+		}
+		for cursor.Next(ctx) {
+			var record DailyRecord
+			if err := cursor.Decode(&record); err != nil {
+				continue
+			}
+			if err := fn(record); err != nil {
+				cursor.Close(ctx)
+				return err
+			}
+		}
+		err = cursor.Err()
+		cursor.Close(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ArchiveOldRecords moves every user's daily records older than years into daily_records_archive
+// Per user: copy (idempotent upsert), recompute the carry from the whole archive, mark the days archived,
+// then delete the hot copies; a run stopped halfway is finished by the next one
+func (s *MongoDBService) ArchiveOldRecords(ctx context.Context, years int) (*ArchiveResult, error) {
+	if years <= 0 {
+		return nil, fmt.Errorf("archive years must be positive")
+	}
+	through := ArchiveCutoff(time.Now(), years)
+	result := &ArchiveResult{Through: through}
+
+	values, err := s.collection.Distinct(ctx, "lineid", bson.M{"date": bson.M{"$lte": through}})
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		lineID, ok := v.(string)
+		if !ok || lineID == "" {
+			continue
+		}
+		moved, err := s.archiveUserRecords(ctx, lineID, through)
+		if err != nil {
+			log.Printf("Failed to archive records of %s: %v", lineID, err)
+			ReportError("archive.user", lineID, err)
+			continue
+		}
+		result.Users++
+		result.Records += moved
+	}
+	return result, nil
+}
+
+// archiveUserRecords archives one user's days dated on or before through, returning days moved
+func (s *MongoDBService) archiveUserRecords(ctx context.Context, lineID, through string) (int, error) {
+	settings, err := s.GetUserSettings(ctx, lineID)
+	if err != nil {
+		return 0, err
+	}
+	if settings.ArchivedThrough > through {
+		through = settings.ArchivedThrough // a shorter ARCHIVE_AFTER_YEARS never un-archives days
+	}
+	snapshotState, err := s.getSnapshotState(ctx, lineID)
+	if err != nil {
+		return 0, err
+	}
+
+	cursor, err := s.collection.Find(ctx, bson.M{"lineid": lineID, "date": bson.M{"$lte": through}})
+	if err != nil {
+		return 0, err
+	}
+	var copies []archivedCopy
+	for cursor.Next(ctx) {
+		raw := append(bson.Raw{}, cursor.Current...)
+		id, err := s.copyToArchive(ctx, raw)
+		if err != nil {
+			cursor.Close(ctx)
+			return 0, err
+		}
+		copies = append(copies, archivedCopy{id: id, raw: raw})
+	}
+	err = cursor.Err()
+	cursor.Close(ctx)
+	if err != nil || len(copies) == 0 {
+		return 0, err
+	}
+
+	carry, err := s.buildUserArchiveCarry(ctx, lineID)
+	if err != nil {
+		return 0, err
+	}
+	// From here reads use the carry and skip hot days <= through, and writes to them are refused
+	if err := s.setArchivedThrough(ctx, lineID, through, carry); err != nil {
+		return 0, err
+	}
+
+	// A write that passed the archived check just before the line above may still land on a copied
+	// day; only an unchanged record is deleted, a changed one is copied again first
+	recopied := false
+	for _, c := range copies {
+		for attempt := 0; ; attempt++ {
+			result, err := s.collection.DeleteOne(ctx, bson.M{"_id": c.id, "$expr": bson.M{"$eq": bson.A{"$$ROOT", bson.M{"$literal": c.raw}}}})
+			if err != nil {
+				return 0, err
+			}
+			if result.DeletedCount > 0 {
+				break
+			}
+			if attempt == archiveRetries {
+				return 0, fmt.Errorf("daily record %s kept changing while archived", c.id.Hex())
+			}
+			var raw bson.Raw
+			err = s.collection.FindOne(ctx, bson.M{"_id": c.id}).Decode(&raw)
+			if err == mongo.ErrNoDocuments {
+				break
+			}
+			if err != nil {
+				return 0, err
+			}
+			if _, err := s.copyToArchive(ctx, raw); err != nil {
+				return 0, err
+			}
+			c.raw, recopied = raw, true
+		}
+	}
+	if recopied {
+		if carry, err = s.buildUserArchiveCarry(ctx, lineID); err != nil {
+			return 0, err
+		}
+		if err := s.setArchivedThrough(ctx, lineID, through, carry); err != nil {
+			return 0, err
+		}
+	}
+	if err := s.saveBalanceSnapshot(ctx, lineID, through, snapshotState.Version, carry.Balances); err != nil {
+		log.Printf("Failed to save archive balance snapshot: %v", err)
+	}
+	s.distinctCache.Delete(lineID)
+	return len(copies), nil
+}
+
+// archiveRetries is how often a record changed during archival is copied again before the run gives up
+const archiveRetries = 3
+
+// archivedCopy is a hot record copied to the archive, as it was when copied
+type archivedCopy struct {
+	id  primitive.ObjectID
+	raw bson.Raw
+}
+
+// copyToArchive writes a hot record (raw BSON) to the archive flagged Archived, returning its ID
+func (s *MongoDBService) copyToArchive(ctx context.Context, raw bson.Raw) (primitive.ObjectID, error) {
+	var record DailyRecord
+	if err := bson.Unmarshal(raw, &record); err != nil {
+		return primitive.NilObjectID, err
+	}
+	record.Archived = true
+	_, err := s.archiveCollection.ReplaceOne(ctx, bson.M{"_id": record.ID}, record, options.Replace().SetUpsert(true))
+	return record.ID, err
+}
+
+// buildUserArchiveCarry sums everything in the user's archive
+func (s *MongoDBService) buildUserArchiveCarry(ctx context.Context, lineID string) (ArchiveCarry, error) {
+	archived, err := s.archiveCollection.Find(ctx, bson.M{"lineid": lineID},
+		options.Find().SetProjection(bson.M{"incomes.imagebase64": 0, "expenses.imagebase64": 0}))
+	if err != nil {
+		return ArchiveCarry{}, err
+	}
+	var records []DailyRecord
+	if err := archived.All(ctx, &records); err != nil {
+		return ArchiveCarry{}, err
+	}
+	return BuildArchiveCarry(records), nil
+}
+
+// setArchivedThrough stores the archived range and what it carries into all-time totals
+func (s *MongoDBService) setArchivedThrough(ctx context.Context, lineID, through string, carry ArchiveCarry) error {
+	_, err := s.settingsCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID},
+		bson.M{"$set": bson.M{"archived_through": through, "archive_carry": carry, "updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
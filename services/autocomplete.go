@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FrequentDescription is a description the user records often
+type FrequentDescription struct {
+	Description string  `json:"description"`
+	Category    string  `json:"category"`
+	Count       int     `json:"count"`
+	LastAmount  float64 `json:"last_amount"`
+}
+
+// GetFrequentDescriptions returns descriptions used in the last N days, most frequent first
+func (s *MongoDBService) GetFrequentDescriptions(ctx context.Context, lineID string, days int) ([]FrequentDescription, error) {
+	if days <= 0 {
+		days = 90
+	}
+	filter := bson.M{
+		"lineid": lineID,
+		"date":   bson.M{"$gte": time.Now().AddDate(0, 0, -days).Format("2006-01-02")},
+	}
+	// Oldest first so LastAmount ends up as the latest amount
+	opts := options.Find().
+		SetSort(bson.D{{Key: "date", Value: 1}}).
+		SetProjection(bson.M{"expenses.description": 1, "expenses.category": 1, "expenses.amount": 1, "expenses.transfer_id": 1})
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	byDesc := make(map[string]*FrequentDescription)
+	for cursor.Next(ctx) {
+		var record DailyRecord
+		if err := cursor.Decode(&record); err != nil {
+			continue
+		}
+		for _, tx := range record.Expenses {
+			desc := strings.TrimSpace(tx.Description)
+			if desc == "" || tx.TransferID != "" {
+				continue
+			}
+			fd, ok := byDesc[desc]
+			if !ok {
+				fd = &FrequentDescription{Description: desc}
+				byDesc[desc] = fd
+			}
+			fd.Count++
+			fd.Category = tx.Category
+			fd.LastAmount = tx.Amount
+		}
+	}
+
+	list := make([]FrequentDescription, 0, len(byDesc))
+	for _, fd := range byDesc {
+		list = append(list, *fd)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Description < list[j].Description
+	})
+	return list, nil
+}
+
+// MatchDescriptionPrefix returns frequent descriptions starting with (or containing) prefix
+// Prefix matches come first; a description must be used at least twice to be suggested
+func MatchDescriptionPrefix(list []FrequentDescription, prefix string, limit int) []FrequentDescription {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if prefix == "" {
+		return nil
+	}
+	var starts, contains []FrequentDescription
+	for _, fd := range list {
+		if fd.Count < 2 {
+			continue
+		}
+		desc := strings.ToLower(fd.Description)
+		switch {
+		case strings.HasPrefix(desc, prefix):
+			starts = append(starts, fd)
+		case strings.Contains(desc, prefix):
+			contains = append(contains, fd)
+		}
+	}
+	matches := append(starts, contains...)
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// backupVersion is bumped when the snapshot layout changes
+const backupVersion = 1
+
+// backupKeep is how many snapshots are kept per user (older ones are deleted)
+const backupKeep = 14
+
+// UserBackup is a JSON snapshot of one user's data
+// Documents are kept raw (bson.M) so old snapshots restore even after DailyRecord schema changes
+type UserBackup struct {
+	Version     int                 `bson:"version"`
+	LineID      string              `bson:"lineid"`
+	CreatedAt   time.Time           `bson:"created_at"`
+	Reason      string              `bson:"reason"` // "scheduled", "before delete", "before restore"
+	Collections map[string][]bson.M `bson:"collections"`
+}
+
+// RestoreResult is number of documents restored per collection
+type RestoreResult map[string]int
+
+// backupCollections returns user data collections included in backups
+func (s *MongoDBService) backupCollections() map[string]*mongo.Collection {
+	return map[string]*mongo.Collection{
+		"daily_records":         s.collection,
+		"daily_records_archive": s.archiveCollection,
+		"transfers":             s.transferCollection,
+		"budgets":               s.budgetCollection,
+		"subscriptions":         s.subscriptionCollection,
+		"category_styles":       s.categoryStyleCollection,
+		"user_settings":         s.settingsCollection,
+		"card_accounts":         s.cardCollection,
+		"guardrails":            s.guardrailCollection,
+		"payment_rules":         s.paymentRuleCollection,
+		"warranties":            s.warrantyCollection,
+	}
+}
+
+// ExportUserBackup returns all user data as canonical extended JSON (keeps ObjectIDs and dates)
+func (s *MongoDBService) ExportUserBackup(ctx context.Context, lineID, reason string) ([]byte, error) {
+	backup := UserBackup{
+		Version:     backupVersion,
+		LineID:      lineID,
+		CreatedAt:   time.Now(),
+		Reason:      reason,
+		Collections: make(map[string][]bson.M),
+	}
+	for name, coll := range s.backupCollections() {
+		cursor, err := coll.Find(ctx, bson.M{"lineid": lineID})
+		if err != nil {
+			return nil, fmt.Errorf("backup %s: %w", name, err)
+		}
+		var docs []bson.M
+		if err := cursor.All(ctx, &docs); err != nil {
+			return nil, fmt.Errorf("backup %s: %w", name, err)
+		}
+		backup.Collections[name] = docs
+	}
+	return bson.MarshalExtJSON(backup, true, false)
+}
+
+// RestoreUserBackup replaces user's data with the snapshot (only collections present in it)
+func (s *MongoDBService) RestoreUserBackup(ctx context.Context, lineID string, data []byte) (RestoreResult, error) {
+	var backup UserBackup
+	if err := bson.UnmarshalExtJSON(data, true, &backup); err != nil {
+		return nil, fmt.Errorf("invalid backup: %w", err)
+	}
+	if backup.LineID != lineID {
+		return nil, fmt.Errorf("backup belongs to another user")
+	}
+	if backup.Version > backupVersion {
+		return nil, fmt.Errorf("backup version %d is newer than supported %d", backup.Version, backupVersion)
+	}
+
+	result := make(RestoreResult)
+	collections := s.backupCollections()
+	for name, docs := range backup.Collections {
+		coll, ok := collections[name]
+		if !ok {
+			continue
+		}
+		if _, err := coll.DeleteMany(ctx, bson.M{"lineid": lineID}); err != nil {
+			return result, fmt.Errorf("restore %s: %w", name, err)
+		}
+		if len(docs) == 0 {
+			result[name] = 0
+			continue
+		}
+		items := make([]interface{}, 0, len(docs))
+		for _, doc := range docs {
+			doc["lineid"] = lineID // never restore into someone else's data
+			items = append(items, doc)
+		}
+		if _, err := coll.InsertMany(ctx, items); err != nil {
+			return result, fmt.Errorf("restore %s: %w", name, err)
+		}
+		result[name] = len(docs)
+	}
+
+	// Cached closing balances no longer match restored records
+	s.snapshotCollection.DeleteMany(ctx, bson.M{"lineid": lineID})
+	s.distinctCache.Delete(lineID)
+	s.markProjectionStale(lineID)
+	s.recordSyncChange(SyncChange{LineID: lineID, Op: SyncOpReset})
+	if _, err := s.rebuildUserProfile(ctx, lineID); err != nil {
+		log.Printf("Failed to rebuild profile after restore: %v", err)
+	}
+	return result, nil
+}
+
+// GetActiveUserIDs returns users with records since the given date (for scheduled backups)
+func (s *MongoDBService) GetActiveUserIDs(ctx context.Context, since time.Time) ([]string, error) {
+	values, err := s.collection.Distinct(ctx, "lineid", bson.M{"date": bson.M{"$gte": since.Format("2006-01-02")}})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(values))
+	for _, v := range values {
+		if id, ok := v.(string); ok && id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// BackupService stores user snapshots in the storage backend (Firebase)
+type BackupService struct {
+	mongo   *MongoDBService
+	storage *FirebaseService
+}
+
+// NewBackupService creates backup service (storage is required)
+func NewBackupService(mongo *MongoDBService, storage *FirebaseService) *BackupService {
+	return &BackupService{mongo: mongo, storage: storage}
+}
+
+// backupPrefix is the storage folder of one user's snapshots
+func backupPrefix(lineID string) string {
+	return "backups/" + lineID + "/"
+}
+
+// BackupUser uploads a snapshot and prunes old ones, returns snapshot name
+func (b *BackupService) BackupUser(ctx context.Context, lineID, reason string) (string, error) {
+	data, err := b.mongo.ExportUserBackup(ctx, lineID, reason)
+	if err != nil {
+		return "", err
+	}
+	name := time.Now().Format("20060102-150405") + ".json"
+	if err := b.storage.WriteObject(ctx, backupPrefix(lineID)+name, data, "application/json"); err != nil {
+		return "", err
+	}
+
+	// Keep only the latest backupKeep snapshots
+	names, err := b.ListBackups(ctx, lineID)
+	if err == nil && len(names) > backupKeep {
+		for _, old := range names[backupKeep:] {
+			if err := b.storage.DeleteFile(ctx, backupPrefix(lineID)+old); err != nil {
+				log.Printf("Failed to prune backup %s: %v", old, err)
+			}
+		}
+	}
+	return name, nil
+}
+
+// ListBackups returns snapshot names of the user, newest first
+func (b *BackupService) ListBackups(ctx context.Context, lineID string) ([]string, error) {
+	paths, err := b.storage.ListObjects(ctx, backupPrefix(lineID))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(paths))
+	for _, p := range paths {
+		names = append(names, strings.TrimPrefix(p, backupPrefix(lineID)))
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	return names, nil
+}
+
+// Restore backs up current data first, then restores the named snapshot
+func (b *BackupService) Restore(ctx context.Context, lineID, name string) (RestoreResult, error) {
+	if name == "" || strings.ContainsAny(name, "/\\") {
+		return nil, fmt.Errorf("invalid backup name")
+	}
+	reader, err := b.storage.GetFileReader(ctx, backupPrefix(lineID)+name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := b.BackupUser(ctx, lineID, "before restore"); err != nil {
+		return nil, fmt.Errorf("backup before restore: %w", err)
+	}
+	return b.mongo.RestoreUserBackup(ctx, lineID, data)
+}
+
+// BackupActiveUsers snapshots every user active in the last days (called by the scheduler endpoint)
+func (b *BackupService) BackupActiveUsers(ctx context.Context, days int) (int, error) {
+	ids, err := b.mongo.GetActiveUserIDs(ctx, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return 0, err
+	}
+	done := 0
+	for _, id := range ids {
+		if _, err := b.BackupUser(ctx, id, "scheduled"); err != nil {
+			log.Printf("Failed to back up user: %v", err)
+			ReportError("backup.scheduled", id, err)
+			continue
+		}
+		done++
+	}
+	return done, nil
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BalanceContext is the balances and totals behind the AI "สรุปยอด" context
+// Cached per user and patched by every write, so a follow-up message sees what was just saved
+type BalanceContext struct {
+	Balances []PaymentBalance
+	Summary  BalanceSummary
+	Version  int64  // profile balance_version this context reflects
+	Day      string // YYYY-MM-DD the "today" totals belong to
+}
+
+// clone copies the context so a cached one is never modified in place
+func (c *BalanceContext) clone() *BalanceContext {
+	next := *c
+	next.Balances = append([]PaymentBalance(nil), c.Balances...)
+	return &next
+}
+
+// Apply adds a created transaction to (or removes a deleted one from) balances and totals the same way
+// GetBalanceByPaymentType and GetBalanceSummary count it; today is YYYY-MM-DD
+// Returns false for events it can't patch (updates don't carry the old values)
+func (c *BalanceContext) Apply(event, date string, tx Transaction, today string) bool {
+	sign := 1.0
+	switch event {
+	case TransactionCreated:
+	case TransactionDeleted:
+		sign = -1
+	default:
+		return false
+	}
+	amount := tx.Amount * sign
+
+	balanceMap := make(map[string]*PaymentBalance, len(c.Balances)+1)
+	for i := range c.Balances {
+		b := c.Balances[i]
+		balanceMap[fmt.Sprintf("%d:%s:%s", b.UseType, b.BankName, b.CreditCardName)] = &b
+	}
+	patched := tx
+	patched.Amount = amount
+	accumulatePaymentBalances(balanceMap, []Transaction{patched})
+	c.Balances = sortedPaymentBalances(balanceMap)
+
+	sum := &c.Summary
+	switch {
+	case tx.Category == InvestmentCategory:
+		sum.TotalInvested += amount * float64(-tx.Type) // buying (expense) invests, selling (income) returns
+	case tx.IsTransfer:
+	case tx.Type == 1:
+		sum.TotalIncome += amount
+		if date == today {
+			sum.TodayIncome += amount
+		}
+	default:
+		sum.TotalExpense += amount
+		if date == today {
+			sum.TodayExpense += amount
+		}
+	}
+	sum.Balance = sum.TotalIncome - sum.TotalExpense - sum.TotalInvested
+	sum.TodayBalance = sum.TodayIncome - sum.TodayExpense
+	return true
+}
+
+// ToAIText returns compact balances for AI context
+// Format: "สรุปยอด|ยอดรวม:52000|เงินสด:2000|ธนาคารรวม:50000|กสิกร:50000|รายได้รวม:60000|รายจ่ายรวม:8000"
+func (c *BalanceContext) ToAIText() string {
+	var cashTotal, bankTotal, creditTotal, grandTotal float64
+	var bankDetails, cardDetails []string
+	for _, b := range c.Balances {
+		switch b.UseType {
+		case 0:
+			cashTotal += b.Balance
+		case 1:
+			creditTotal += b.Balance
+			cardDetails = append(cardDetails, fmt.Sprintf("%s:%.0f", orName(b.CreditCardName, "บัตรเครดิต"), b.Balance))
+		case 2:
+			bankTotal += b.Balance
+			bankDetails = append(bankDetails, fmt.Sprintf("%s:%.0f", orName(b.BankName, "ธนาคาร"), b.Balance))
+		}
+		grandTotal += b.Balance
+	}
+
+	parts := []string{fmt.Sprintf("ยอดรวม:%.0f", grandTotal)}
+	if cashTotal != 0 {
+		parts = append(parts, fmt.Sprintf("เงินสด:%.0f", cashTotal))
+	}
+	if bankTotal != 0 {
+		parts = append(parts, fmt.Sprintf("ธนาคารรวม:%.0f", bankTotal))
+	}
+	if len(bankDetails) > 0 {
+		parts = append(parts, strings.Join(bankDetails, ","))
+	}
+	if creditTotal != 0 {
+		parts = append(parts, fmt.Sprintf("บัตรเครดิตรวม:%.0f", creditTotal))
+	}
+	if len(cardDetails) > 0 {
+		parts = append(parts, strings.Join(cardDetails, ","))
+	}
+
+	sum := c.Summary
+	parts = append(parts, fmt.Sprintf("รายได้รวม:%.0f", sum.TotalIncome), fmt.Sprintf("รายจ่ายรวม:%.0f", sum.TotalExpense))
+	if sum.TotalInvested > 0 {
+		parts = append(parts, fmt.Sprintf("ลงทุนรวม:%.0f", sum.TotalInvested))
+	}
+	if sum.TodayIncome > 0 || sum.TodayExpense > 0 {
+		parts = append(parts, fmt.Sprintf("วันนี้รับ:%.0f,จ่าย:%.0f", sum.TodayIncome, sum.TodayExpense))
+	}
+	return "สรุปยอด|" + strings.Join(parts, "|")
+}
+
+// orName returns name, or fallback when empty
+func orName(name, fallback string) string {
+	if name == "" {
+		return fallback
+	}
+	return name
+}
+
+// getBalanceVersion reads the profile's balance_version (bumped after every transaction change)
+func (s *MongoDBService) getBalanceVersion(ctx context.Context, lineID string) (int64, error) {
+	var profile UserProfile
+	opts := options.FindOne().SetProjection(bson.M{"balance_version": 1})
+	if err := s.profileCollection.FindOne(ctx, bson.M{"lineid": lineID}, opts).Decode(&profile); err != nil {
+		return 0, err
+	}
+	return profile.BalanceVersion, nil
+}
+
+// GetBalanceContext returns the user's balances and totals for AI context
+// The cached context is reused while the profile balance_version matches; writes from other
+// instances bump the version and force a reload
+func (s *MongoDBService) GetBalanceContext(ctx context.Context, lineID string) (*BalanceContext, error) {
+	today := time.Now().Format("2006-01-02")
+	version, versionErr := s.getBalanceVersion(ctx, lineID)
+	if cached, ok := s.balanceContexts.Load(lineID); ok && versionErr == nil {
+		if c := cached.(*BalanceContext); c.Version == version && c.Day == today {
+			return c, nil
+		}
+	}
+
+	balances, err := s.ProjectedBalances(ctx, lineID)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := s.ProjectedBalanceSummary(ctx, lineID)
+	if err != nil {
+		return nil, err
+	}
+	c := &BalanceContext{Balances: balances, Summary: *summary, Version: version, Day: today}
+	// Users without a profile have no version to validate against, so nothing is cached
+	if versionErr == nil {
+		s.balanceContexts.Store(lineID, c)
+	}
+	return c, nil
+}
+
+// patchBalanceContext applies a write to the cached context right away (transaction hook)
+// Expects the profile version bump that follows the write; an update or any other mismatch reloads
+func (s *MongoDBService) patchBalanceContext(event, lineID, date string, tx Transaction) {
+	s.balanceContextMu.Lock()
+	defer s.balanceContextMu.Unlock()
+
+	cached, ok := s.balanceContexts.Load(lineID)
+	if !ok {
+		return
+	}
+	next := cached.(*BalanceContext).clone()
+	if !next.Apply(event, date, tx, next.Day) {
+		s.balanceContexts.Delete(lineID)
+		return
+	}
+	next.Version++
+	s.balanceContexts.Store(lineID, next)
+}
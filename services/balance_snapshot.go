@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BalanceSnapshot is the closing balance of every payment method at the end of a day
+// Only closed days (before today) are snapshotted, so same-day deletes never invalidate them
+type BalanceSnapshot struct {
+	LineID    string           `bson:"lineid" json:"lineid"`
+	Date      string           `bson:"date" json:"date"`
+	Accounts  []PaymentBalance `bson:"accounts" json:"accounts"`
+	Version   int64            `bson:"version" json:"version"` // user's BalanceSnapshotState.Version when computing started
+	CreatedAt time.Time        `bson:"created_at" json:"created_at"`
+}
+
+// snapshotInvalidationsKept bounds the invalidation history; older snapshots count as outdated
+const snapshotInvalidationsKept = 50
+
+// SnapshotInvalidation is one backdated change: snapshots from From on computed before Version are outdated
+type SnapshotInvalidation struct {
+	Version int64  `bson:"version" json:"version"`
+	From    string `bson:"from" json:"from"` // YYYY-MM-DD
+}
+
+// BalanceSnapshotState is a user's snapshot version (balance_snapshot_state), shared by every instance
+// Each backdated change bumps Version and records from which date snapshots are outdated
+type BalanceSnapshotState struct {
+	LineID        string                 `bson:"lineid" json:"lineid"`
+	Version       int64                  `bson:"version" json:"version"`
+	Invalidations []SnapshotInvalidation `bson:"invalidations" json:"invalidations"` // newest last
+}
+
+// Outdated reports whether a backdated change after the snapshot was computed affects its date
+func (st *BalanceSnapshotState) Outdated(snapshot *BalanceSnapshot) bool {
+	if len(st.Invalidations) == snapshotInvalidationsKept && snapshot.Version < st.Invalidations[0].Version-1 {
+		return true // changes between them were dropped from the history
+	}
+	for _, inv := range st.Invalidations {
+		if inv.Version > snapshot.Version && inv.From <= snapshot.Date {
+			return true
+		}
+	}
+	return false
+}
+
+// getSnapshotState returns a user's snapshot state (version 0 before any backdated change)
+func (s *MongoDBService) getSnapshotState(ctx context.Context, lineID string) (*BalanceSnapshotState, error) {
+	state := &BalanceSnapshotState{LineID: lineID}
+	err := s.snapshotStateCollection.FindOne(ctx, bson.M{"lineid": lineID}).Decode(state)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	return state, nil
+}
+
+// accumulatePaymentBalances adds transactions to balances keyed by "usetype:bankname:creditcardname"
+func accumulatePaymentBalances(balanceMap map[string]*PaymentBalance, txs []Transaction) {
+	for _, tx := range txs {
+		key := fmt.Sprintf("%d:%s:%s", tx.UseType, tx.BankName, tx.CreditCardName)
+		if _, exists := balanceMap[key]; !exists {
+			balanceMap[key] = &PaymentBalance{
+				UseType:        tx.UseType,
+				BankName:       tx.BankName,
+				CreditCardName: tx.CreditCardName,
+			}
+		}
+		// คำนวณ: amount * type (type=1 รายรับ, type=-1 รายจ่าย)
+		balanceMap[key].Balance += tx.Amount * float64(tx.Type)
+		if tx.Type == 1 {
+			balanceMap[key].TotalIncome += tx.Amount
+		} else {
+			balanceMap[key].TotalExpense += tx.Amount
+		}
+	}
+}
+
+// sortedPaymentBalances returns balances in stable order (cash, card, bank then by name)
+func sortedPaymentBalances(balanceMap map[string]*PaymentBalance) []PaymentBalance {
+	result := make([]PaymentBalance, 0, len(balanceMap))
+	for _, pb := range balanceMap {
+		result = append(result, *pb)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].UseType != result[j].UseType {
+			return result[i].UseType < result[j].UseType
+		}
+		if result[i].BankName != result[j].BankName {
+			return result[i].BankName < result[j].BankName
+		}
+		return result[i].CreditCardName < result[j].CreditCardName
+	})
+	return result
+}
+
+// GetBalanceAsOf returns balance of every payment method at the end of date (YYYY-MM-DD)
+// Starts from the latest snapshot on or before date and replays only the days after it
+func (s *MongoDBService) GetBalanceAsOf(ctx context.Context, lineID, date string) ([]PaymentBalance, error) {
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return nil, fmt.Errorf("invalid date: %s", date)
+	}
+
+	balanceMap := make(map[string]*PaymentBalance)
+	recordFilter := bson.M{"lineid": lineID, "date": bson.M{"$lte": date}}
+	from := ""
+
+	// Read before the records, so a change landing while they're summed outdates what's saved below
+	state, err := s.getSnapshotState(ctx, lineID)
+	if err != nil {
+		return nil, err
+	}
+	// Latest snapshot no backdated change has outdated yet
+	cursor, err := s.snapshotCollection.Find(ctx,
+		bson.M{"lineid": lineID, "date": bson.M{"$lte": date}},
+		options.Find().SetSort(bson.M{"date": -1}).SetLimit(10),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []BalanceSnapshot
+	if err := cursor.All(ctx, &snapshots); err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		if state.Outdated(&snapshot) {
+			continue
+		}
+		if snapshot.Date == date {
+			return snapshot.Accounts, nil
+		}
+		for i := range snapshot.Accounts {
+			pb := snapshot.Accounts[i]
+			balanceMap[fmt.Sprintf("%d:%s:%s", pb.UseType, pb.BankName, pb.CreditCardName)] = &pb
+		}
+		recordFilter["date"] = bson.M{"$gt": snapshot.Date, "$lte": date}
+		from = snapshot.Date
+		break
+	}
+
+	err = s.eachDailyRecord(ctx, lineID, from, recordFilter, nil, true, func(record DailyRecord) error {
+		accumulatePaymentBalances(balanceMap, record.Incomes)
+		accumulatePaymentBalances(balanceMap, record.Expenses)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	accounts := sortedPaymentBalances(balanceMap)
+
+	// Cache closed days so the next query for this date is a single read
+	if date < time.Now().Format("2006-01-02") {
+		if err := s.saveBalanceSnapshot(ctx, lineID, date, state.Version, accounts); err != nil {
+			log.Printf("Failed to save balance snapshot: %v", err)
+		}
+	}
+	return accounts, nil
+}
+
+// saveBalanceSnapshot creates or replaces snapshot of a day computed at state version
+func (s *MongoDBService) saveBalanceSnapshot(ctx context.Context, lineID, date string, version int64, accounts []PaymentBalance) error {
+	_, err := s.snapshotCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID, "date": date},
+		bson.M{"$set": bson.M{"accounts": accounts, "version": version, "created_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// BackfillBalanceSnapshots rebuilds snapshots for every closed day that has transactions
+// Returns number of snapshots written
+func (s *MongoDBService) BackfillBalanceSnapshots(ctx context.Context, lineID string) (int, error) {
+	today := time.Now().Format("2006-01-02")
+	state, err := s.getSnapshotState(ctx, lineID)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := s.snapshotCollection.DeleteMany(ctx, bson.M{"lineid": lineID}); err != nil {
+		return 0, err
+	}
+
+	balanceMap := make(map[string]*PaymentBalance)
+	var snapshots []interface{}
+	err = s.eachDailyRecord(ctx, lineID, "",
+		bson.M{"lineid": lineID, "date": bson.M{"$lt": today}},
+		options.Find().SetSort(bson.M{"date": 1}).SetProjection(bson.M{"incomes.imagebase64": 0, "expenses.imagebase64": 0}),
+		true,
+		func(record DailyRecord) error {
+			accumulatePaymentBalances(balanceMap, record.Incomes)
+			accumulatePaymentBalances(balanceMap, record.Expenses)
+			snapshots = append(snapshots, BalanceSnapshot{
+				LineID:    lineID,
+				Date:      record.Date,
+				Accounts:  sortedPaymentBalances(balanceMap),
+				Version:   state.Version,
+				CreatedAt: time.Now(),
+			})
+			return nil
+		})
+	if err != nil {
+		return 0, err
+	}
+	if len(snapshots) == 0 {
+		return 0, nil
+	}
+	if _, err := s.snapshotCollection.InsertMany(ctx, snapshots); err != nil {
+		return 0, err
+	}
+	return len(snapshots), nil
+}
+
+// invalidateBalanceSnapshots outdates snapshots on or after a backdated change
+// The version bump in balance_snapshot_state makes every instance skip them; runs in background,
+// in order per user, and deletes the outdated snapshots afterwards
+func (s *MongoDBService) invalidateBalanceSnapshots(event, lineID, date string, tx Transaction) {
+	if date == "" || date >= time.Now().Format("2006-01-02") {
+		return
+	}
+	s.snapshotQueue.Enqueue(lineID, "snapshot.invalidate", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := s.outdateBalanceSnapshots(ctx, lineID, date); err != nil {
+			log.Printf("Failed to invalidate balance snapshots: %v", err)
+		}
+	})
+}
+
+// outdateBalanceSnapshots bumps the user's snapshot version, records from which date snapshots are
+// outdated, then deletes them
+func (s *MongoDBService) outdateBalanceSnapshots(ctx context.Context, lineID, from string) error {
+	version := bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", 0}}, 1}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"version": version, "updated_at": time.Now()}}},
+		{{Key: "$set", Value: bson.M{"invalidations": bson.M{"$slice": bson.A{
+			bson.M{"$concatArrays": bson.A{bson.M{"$ifNull": bson.A{"$invalidations", bson.A{}}}, bson.A{bson.M{"version": "$version", "from": from}}}},
+			-snapshotInvalidationsKept,
+		}}}}},
+	}
+	var state BalanceSnapshotState
+	err := s.snapshotStateCollection.FindOneAndUpdate(ctx, bson.M{"lineid": lineID}, update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&state)
+	if err != nil {
+		return err
+	}
+	_, err = s.snapshotCollection.DeleteMany(ctx, bson.M{"lineid": lineID, "date": bson.M{"$gte": from}, "version": bson.M{"$not": bson.M{"$gte": state.Version}}})
+	return err
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BankSMS is a transaction extracted from a pasted bank/card notification (no AI)
+type BankSMS struct {
+	Bank      string  `json:"bank"`
+	UseType   int     `json:"usetype"` // 1 = credit card, 2 = bank account
+	Type      string  `json:"type"`    // "income" or "expense"
+	Amount    float64 `json:"amount"`
+	Account   string  `json:"account,omitempty"` // masked account/card number, e.g. "x1234x"
+	Balance   float64 `json:"balance,omitempty"` // คงเหลือ/วงเงินใช้ได้ (0 = not given)
+	Merchant  string  `json:"merchant,omitempty"`
+	Date      string  `json:"date"` // YYYY-MM-DD
+	Direction string  `json:"direction"`
+}
+
+// bankSMSSender identifies bank/card issuer by markers in the message
+type bankSMSSender struct {
+	name    string
+	useType int
+	markers []string // lowercase
+}
+
+var bankSMSSenders = []bankSMSSender{
+	{"กสิกร", 2, []string{"kbank", "k-bank", "กสิกร", "k plus"}},
+	{"ไทยพาณิชย์", 2, []string{"scb", "ไทยพาณิชย์"}},
+	{"กรุงไทย", 2, []string{"krungthai", "ktb", "กรุงไทย"}},
+	{"กรุงเทพ", 2, []string{"bangkok bank", "bualuang", "bbl", "กรุงเทพ"}},
+	{"กรุงศรี", 2, []string{"krungsri", "กรุงศรี"}},
+	{"ทีทีบี", 2, []string{"ttb", "ทีทีบี", "ทหารไทย"}},
+	{"ออมสิน", 2, []string{"gsb", "mymo", "ออมสิน"}},
+	{"KTC", 1, []string{"ktc"}},
+	{"UOB", 1, []string{"uob"}},
+	{"Citi", 1, []string{"citi"}},
+	{"First Choice", 1, []string{"first choice", "เฟิร์สช้อยส์"}},
+}
+
+// bankSMSDirections map keywords to transaction type (longer/more specific first)
+var bankSMSDirections = []struct{ keyword, txType string }{
+	{"รับโอน", "income"}, {"เงินเข้า", "income"}, {"โอนเข้า", "income"}, {"ฝากเงิน", "income"},
+	{"รับเงิน", "income"}, {"คืนเงิน", "income"}, {"deposit", "income"}, {"received", "income"},
+	{"โอน/ถอน", "expense"}, {"ถอน/โอน", "expense"}, {"โอนออก", "expense"}, {"ถอน", "expense"},
+	{"ใช้จ่าย", "expense"}, {"ชำระ", "expense"}, {"จ่าย", "expense"}, {"หักบัญชี", "expense"},
+	{"ตัดบัญชี", "expense"}, {"withdraw", "expense"}, {"spent", "expense"}, {"purchase", "expense"},
+	{"payment", "expense"}, {"transfer", "expense"},
+}
+
+var (
+	// SMS amounts always carry satang: "1,500.00"
+	smsMoneyPattern    = regexp.MustCompile(`\d[\d,]*\.\d{2}`)
+	smsBalancePattern  = regexp.MustCompile(`(?i)(?:คงเหลือ|ใช้ได้|avail(?:able)?|bal(?:ance)?)\S*\s*[:.]?\s*(\d[\d,]*\.\d{2})`)
+	smsAccountPattern  = regexp.MustCompile(`(?i)(?:บช|บ/ช|บัญชี|a/c|acct|บัตร|card)\s*[:.]?\s*([xX*]*\d{3,6}[xX*]*)`)
+	smsMerchantPattern = regexp.MustCompile(`(?i)(?:\sที่|\sat)\s+([^\d\n]{2,40}?)(?:\s+(?:คงเหลือ|ใช้ได้|avail|bal|วันที่)|\s+\d|$)`)
+	smsDatePattern     = regexp.MustCompile(`(\d{1,2})/(\d{1,2})(?:/\d{2,4})?(?:\s|@)`)
+	smsTimePattern     = regexp.MustCompile(`\b\d{1,2}:\d{2}\b`)
+)
+
+// isMaskedAccount reports whether an account number is masked the way banks print it ("x1234", "X123456X")
+func isMaskedAccount(account string) bool {
+	return strings.ContainsAny(account, "xX*")
+}
+
+// detectBankSender returns the first bank/card issuer whose marker appears in lowercase text
+func detectBankSender(lower string) *bankSMSSender {
+	for i := range bankSMSSenders {
+		for _, marker := range bankSMSSenders[i].markers {
+			if strings.Contains(lower, marker) {
+				return &bankSMSSenders[i]
+			}
+		}
+	}
+	return nil
+}
+
+// ParseBankSMS extracts bank, direction, amount, account and balance from a pasted bank SMS
+// Returns false unless a known bank, a direction keyword, a 2-decimal amount, a masked account and a
+// balance or timestamp are all found, so a typed note ("จ่ายค่าไฟ 1,250.50 บาท ผ่าน scb") goes to the AI
+func ParseBankSMS(text string, now time.Time) (*BankSMS, bool) {
+	text = thaiDigitReplacer.Replace(strings.TrimSpace(text))
+	lower := strings.ToLower(text)
+
+	sender := detectBankSender(lower)
+	if sender == nil {
+		return nil, false
+	}
+
+	// Earliest direction keyword wins ("รับโอน" before "ถอน" in the same SMS is income)
+	dirIndex, dirKeyword, txType := -1, "", ""
+	for _, d := range bankSMSDirections {
+		if idx := strings.Index(lower, d.keyword); idx >= 0 && (dirIndex < 0 || idx < dirIndex) {
+			dirIndex, dirKeyword, txType = idx, d.keyword, d.txType
+		}
+	}
+	if dirIndex < 0 {
+		return nil, false
+	}
+
+	// Amount is the first money value after the direction keyword
+	loc := smsMoneyPattern.FindStringIndex(lower[dirIndex:])
+	if loc == nil {
+		return nil, false
+	}
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(lower[dirIndex+loc[0]:dirIndex+loc[1]], ",", ""), 64)
+	if err != nil || amount <= 0 {
+		return nil, false
+	}
+
+	sms := &BankSMS{
+		Bank:      sender.name,
+		UseType:   sender.useType,
+		Type:      txType,
+		Amount:    amount,
+		Direction: dirKeyword,
+		Date:      now.Format("2006-01-02"),
+	}
+	m := smsAccountPattern.FindStringSubmatch(text)
+	if m == nil || !isMaskedAccount(m[1]) {
+		return nil, false
+	}
+	sms.Account = m[1]
+	if m := smsBalancePattern.FindStringSubmatch(text); m != nil {
+		sms.Balance, _ = strconv.ParseFloat(strings.ReplaceAll(m[1], ",", ""), 64)
+	}
+	if sms.Balance == 0 && !smsTimePattern.MatchString(text) && !smsDatePattern.MatchString(text+" ") {
+		return nil, false
+	}
+	if m := smsMerchantPattern.FindStringSubmatch(text); m != nil {
+		sms.Merchant = strings.TrimSpace(m[1])
+	}
+	if m := smsDatePattern.FindStringSubmatch(text); m != nil {
+		d, _ := strconv.Atoi(m[1])
+		mo, _ := strconv.Atoi(m[2])
+		// Year in SMS is ambiguous (BE/CE 2-digit), day/month is enough for recent messages
+		if date, ok := makeDate(0, mo, d, now); ok {
+			sms.Date = date.Format("2006-01-02")
+		}
+	}
+	return sms, true
+}
+
+// ResolveAccountName maps a detected bank/card issuer to the user's own account name when one matches
+func ResolveAccountName(bank string, useType int, userBanks, userCards []string) string {
+	candidates := userBanks
+	if useType == 1 {
+		candidates = userCards
+	}
+	for _, candidate := range candidates {
+		if sender := detectBankSender(strings.ToLower(candidate)); sender != nil && sender.name == bank {
+			return candidate
+		}
+	}
+	return bank
+}
+
+// ToTransaction converts SMS to transaction data, using the user's own bank/card name when it matches
+func (b *BankSMS) ToTransaction(userBanks, userCards []string) TransactionData {
+	name := ResolveAccountName(b.Bank, b.UseType, userBanks, userCards)
+
+	description := b.Merchant
+	if description == "" {
+		description = strings.TrimSpace(b.Direction + " " + b.Account)
+	}
+	tx := TransactionData{
+		Date:        b.Date,
+		Merchant:    b.Merchant,
+		Amount:      b.Amount,
+		Category:    "อื่นๆ",
+		Type:        b.Type,
+		Description: description,
+		UseType:     b.UseType,
+	}
+	if b.UseType == 1 {
+		tx.CreditCardName = name
+	} else {
+		tx.BankName = name
+	}
+	return tx
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UserSettings represents per-user feature settings
+type UserSettings struct {
+	LineID          string            `bson:"lineid" json:"lineid"`
+	BusinessMode    bool              `bson:"business_mode" json:"business_mode"`
+	Projects        []string          `bson:"projects" json:"projects"`          // customers/projects used in business mode (stored in CustName)
+	CalendarToken   string            `bson:"calendar_token,omitempty" json:"-"` // secret token of iCal feed URL
+	ReportToken     string            `bson:"report_token,omitempty" json:"-"`   // secret token of the close report API
+	Notifications   NotificationPrefs `bson:"notifications" json:"notifications"`
+	LockedUntil     string            `bson:"locked_until,omitempty" json:"locked_until,omitempty"`         // ปิดงวดถึงวันที่ (YYYY-MM-DD)
+	PromptPayID     string            `bson:"promptpay_id,omitempty" json:"promptpay_id,omitempty"`         // เบอร์/เลขบัตรพร้อมเพย์สำหรับสร้าง QR
+	ParentLineID    string            `bson:"parent_lineid,omitempty" json:"parent_lineid,omitempty"`       // บัญชีเด็ก: ผู้ปกครองที่อนุมัติรายการเกินวงเงิน
+	SpendingCap     float64           `bson:"spending_cap,omitempty" json:"spending_cap,omitempty"`         // วงเงินต่อรายการของบัญชีเด็ก
+	RoundUpStep     int               `bson:"round_up_step,omitempty" json:"round_up_step,omitempty"`       // ปัดเศษรายจ่ายขึ้นเป็นหลัก 10/100 (0 = ปิด)
+	RoundUpBank     string            `bson:"round_up_bank,omitempty" json:"round_up_bank,omitempty"`       // บัญชีออมที่โอนเศษเข้า
+	RoundUpSince    string            `bson:"round_up_since,omitempty" json:"round_up_since,omitempty"`     // เริ่มสะสมเศษ (YYYY-MM-DD)
+	RoundUpSaved    float64           `bson:"round_up_saved,omitempty" json:"round_up_saved,omitempty"`     // เศษที่โอนเข้าออมแล้ว
+	PeerBenchmark   bool              `bson:"peer_benchmark,omitempty" json:"peer_benchmark,omitempty"`     // ยินยอมให้นำยอดไปคำนวณค่าเฉลี่ยแบบไม่ระบุตัวตน
+	ArchivedThrough string            `bson:"archived_through,omitempty" json:"archived_through,omitempty"` // วันที่ย้ายเข้าคลังถาวรแล้ว (YYYY-MM-DD)
+	ArchiveCarry    *ArchiveCarry     `bson:"archive_carry,omitempty" json:"-"`                             // ยอดสะสมของวันที่อยู่ในคลัง
+	Trip            *Trip             `bson:"trip,omitempty" json:"trip,omitempty"`                         // โหมดเที่ยว (nil = ปิด)
+	HashChain       bool              `bson:"hash_chain,omitempty" json:"hash_chain,omitempty"`             // บันทึกแฮชรายการเพื่อพิสูจน์ว่าไม่ถูกแก้
+	HashChainSince  time.Time         `bson:"hash_chain_since,omitempty" json:"hash_chain_since,omitempty"` // เปิดแฮชครั้งแรก
+	DailyLimit      float64           `bson:"daily_limit,omitempty" json:"daily_limit,omitempty"`           // วงเงินใช้จ่ายต่อวัน (0 = ปิด)
+	UpdatedAt       time.Time         `bson:"updated_at" json:"updated_at"`
+}
+
+// ProjectSummary represents profit and loss of one customer/project
+type ProjectSummary struct {
+	Project          string  `json:"project"`
+	Income           float64 `json:"income"`
+	Expense          float64 `json:"expense"`
+	Profit           float64 `json:"profit"` // income - expense
+	TransactionCount int     `json:"transaction_count"`
+}
+
+// GetUserSettings returns user's settings (defaults if not set)
+func (s *MongoDBService) GetUserSettings(ctx context.Context, lineID string) (*UserSettings, error) {
+	var settings UserSettings
+	err := s.settingsCollection.FindOne(ctx, bson.M{"lineid": lineID}).Decode(&settings)
+	if err == mongo.ErrNoDocuments {
+		return &UserSettings{LineID: lineID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// SetBusinessMode turns business mode on or off
+func (s *MongoDBService) SetBusinessMode(ctx context.Context, lineID string, enabled bool) error {
+	_, err := s.settingsCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID},
+		bson.M{"$set": bson.M{"business_mode": enabled, "updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// AddProject remembers a customer/project name for P&L grouping
+func (s *MongoDBService) AddProject(ctx context.Context, lineID, project string) error {
+	_, err := s.settingsCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID},
+		bson.M{
+			"$addToSet": bson.M{"projects": project},
+			"$set":      bson.M{"updated_at": time.Now()},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// GetProjectSummaries returns P&L per project between dates (empty dates = all time)
+// Only CustName values registered as projects are counted so merchant names are not mixed in
+func (s *MongoDBService) GetProjectSummaries(ctx context.Context, lineID, from, to string) ([]ProjectSummary, error) {
+	settings, err := s.GetUserSettings(ctx, lineID)
+	if err != nil {
+		return nil, err
+	}
+	projects := make(map[string]*ProjectSummary)
+	for _, p := range settings.Projects {
+		projects[p] = &ProjectSummary{Project: p}
+	}
+	if len(projects) == 0 {
+		return []ProjectSummary{}, nil
+	}
+
+	err = s.eachProjectTransaction(ctx, lineID, from, to, func(date string, tx Transaction) {
+		summary, ok := projects[tx.CustName]
+		if !ok {
+			return
+		}
+		summary.TransactionCount++
+		if tx.Type == 1 {
+			summary.Income += tx.Amount
+		} else {
+			summary.Expense += tx.Amount
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ProjectSummary, 0, len(projects))
+	for _, summary := range projects {
+		if summary.TransactionCount == 0 {
+			continue
+		}
+		summary.Profit = summary.Income - summary.Expense
+		result = append(result, *summary)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Profit > result[j].Profit
+	})
+	return result, nil
+}
+
+// GetProjectTransactions returns transactions tagged with a project (all time, oldest first)
+func (s *MongoDBService) GetProjectTransactions(ctx context.Context, lineID, project string) ([]SearchResult, error) {
+	var results []SearchResult
+	err := s.eachProjectTransaction(ctx, lineID, "", "", func(date string, tx Transaction) {
+		if strings.EqualFold(tx.CustName, project) {
+			results = append(results, SearchResult{Transaction: tx, Date: date})
+		}
+	})
+	return results, err
+}
+
+// eachProjectTransaction iterates non-transfer transactions with CustName between dates
+func (s *MongoDBService) eachProjectTransaction(ctx context.Context, lineID, from, to string, fn func(date string, tx Transaction)) error {
+	filter := bson.M{"lineid": lineID}
+	dateFilter := bson.M{}
+	if from != "" {
+		dateFilter["$gte"] = from
+	}
+	if to != "" {
+		dateFilter["$lte"] = to
+	}
+	if len(dateFilter) > 0 {
+		filter["date"] = dateFilter
+	}
+
+	cursor, err := s.collection.Find(ctx, filter, options.Find().SetSort(bson.M{"date": 1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var record DailyRecord
+		if err := cursor.Decode(&record); err != nil {
+			continue
+		}
+		for _, txs := range [][]Transaction{record.Incomes, record.Expenses} {
+			for _, tx := range txs {
+				if tx.CustName == "" || tx.IsAssetMove() {
+					continue
+				}
+				fn(record.Date, tx)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// arithmeticPattern finds "120+80+65", "150*3", "(100+50)x2", "1,200 ÷ 4" in a message
+var arithmeticPattern = regexp.MustCompile(`\(?\s*\d[\d,]*(?:\.\d+)?\s*\)?(?:\s*[-+*/×xX÷]\s*\(?\s*\d[\d,]*(?:\.\d+)?\s*\)?)+`)
+
+// ambiguousOperatorPattern matches "-" and "/" written without spaces (dates 15/10, phones 081-234-5678)
+var ambiguousOperatorPattern = regexp.MustCompile(`\S[-/]|[-/]\S`)
+
+// dimensionUnitPattern matches a size unit right after an expression ("60x120 ซม.", "4x8 ฟุต"): a size, not a price
+var dimensionUnitPattern = regexp.MustCompile(`^\s*(?:ซม|ซ\.ม|มม|ม\.|เมตร|ฟุต|นิ้ว|ตร\.|cm|mm|ft|in|m\b|")`)
+
+// labelNumberPattern finds numbers left in the message after the expressions are taken out
+var labelNumberPattern = regexp.MustCompile(`\d[\d,]*(?:\.\d+)?`)
+
+// countWordPattern matches a classifier after a number ("4 คน", "3 ชิ้น"): a count, not an amount
+var countWordPattern = regexp.MustCompile(`^\s*(?:คน|ชิ้น|อัน|ตัว|แก้ว|จาน|ที่|ครั้ง|วัน|เดือน|ปี|ชม|นาที|ข้อ|ห่อ|กล่อง|ขวด)`)
+
+// ArithmeticPart is one expression and its result rounded to satang
+type ArithmeticPart struct {
+	Expr  string // as displayed: "120+80+65", "150×3"
+	Value float64
+}
+
+// Arithmetic is the math found in a message
+type Arithmetic struct {
+	Parts []ArithmeticPart
+	Label string // the message without the expressions ("ค่าอาหาร")
+	Text  string // the message with each expression replaced by its result ("ค่าอาหาร 265")
+}
+
+// ParseArithmetic evaluates the math in a message locally so the AI never sums; ok=false when there is
+// none. "-" and "/" count only next to another operator or with spaces ("500 - 50"), so dates and phone
+// numbers aren't read as math. Sizes ("60x120 ซม.") aren't math, and nothing is evaluated when the
+// message has another amount ("ไม้อัด 4x8 ฟุต 890 บาท") since the expression may not be the price
+func ParseArithmetic(text string) (*Arithmetic, bool) {
+	text = thaiDigitReplacer.Replace(text)
+	calc := &Arithmetic{}
+	var label, replaced strings.Builder
+	last := 0
+	for _, loc := range arithmeticPattern.FindAllStringIndex(text, -1) {
+		raw := strings.TrimSpace(text[loc[0]:loc[1]])
+		if !strings.ContainsAny(raw, "+*×xX÷") && ambiguousOperatorPattern.MatchString(raw) {
+			continue
+		}
+		// "2x" in "2xl" or "x5" after a letter isn't multiplication
+		if loc[1] < len(text) && isASCIILetter(text[loc[1]]) || loc[0] > 0 && isASCIILetter(text[loc[0]-1]) {
+			continue
+		}
+		if dimensionUnitPattern.MatchString(text[loc[1]:]) {
+			continue
+		}
+		value, err := evalArithmetic(raw)
+		if err != nil || value <= 0 {
+			continue
+		}
+		value = math.Round(value*100) / 100
+		calc.Parts = append(calc.Parts, ArithmeticPart{Expr: displayExpr(raw), Value: value})
+		label.WriteString(text[last:loc[0]] + " ")
+		replaced.WriteString(text[last:loc[0]] + " " + strconv.FormatFloat(value, 'f', -1, 64) + " ")
+		last = loc[1]
+	}
+	if len(calc.Parts) == 0 {
+		return nil, false
+	}
+	label.WriteString(text[last:])
+	replaced.WriteString(text[last:])
+	if hasOtherAmount(label.String()) {
+		return nil, false
+	}
+	calc.Label = strings.Join(strings.Fields(label.String()), " ")
+	calc.Text = strings.Join(strings.Fields(replaced.String()), " ")
+	return calc, true
+}
+
+// hasOtherAmount reports whether the message without its expressions still has a price; dates and phone
+// numbers ("15/10", "081-234-5678") and counts ("4 คน") don't count
+func hasOtherAmount(label string) bool {
+	for _, loc := range labelNumberPattern.FindAllStringIndex(label, -1) {
+		if loc[0] > 0 && strings.ContainsRune("/-:", rune(label[loc[0]-1])) ||
+			loc[1] < len(label) && strings.ContainsRune("/-:", rune(label[loc[1]])) {
+			continue
+		}
+		if countWordPattern.MatchString(label[loc[1]:]) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// ApplyArithmetic lists the components on every entry whose amount is an expression's result
+// ("ค่าอาหาร (120+80+65)"). The AI's amount is never replaced: an entry that doesn't match any result
+// keeps what the AI read
+func ApplyArithmetic(calc *Arithmetic, txs []TransactionData) {
+	if calc == nil {
+		return
+	}
+	used := make([]bool, len(calc.Parts))
+	for i := range txs {
+		for j, part := range calc.Parts {
+			if used[j] || math.Abs(txs[i].Amount-part.Value) >= 0.01 {
+				continue
+			}
+			used[j] = true
+			txs[i].Description = strings.TrimSpace(txs[i].Description + " (" + part.Expr + ")")
+			break
+		}
+	}
+}
+
+// displayExpr normalizes an expression for descriptions ("150 x 3" -> "150×3")
+func displayExpr(raw string) string {
+	return strings.NewReplacer(" ", "", "*", "×", "x", "×", "X", "×", "/", "÷").Replace(raw)
+}
+
+// isASCIILetter reports whether b is a-z or A-Z
+func isASCIILetter(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// arithmeticParser evaluates + - × ÷ with precedence and parentheses
+type arithmeticParser struct {
+	tokens []string
+	pos    int
+}
+
+// evalArithmetic evaluates an expression matched by arithmeticPattern
+func evalArithmetic(expr string) (float64, error) {
+	var tokens []string
+	for i := 0; i < len(expr); {
+		switch c := expr[i]; {
+		case c == ' ':
+			i++
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(expr) && (expr[j] >= '0' && expr[j] <= '9' || expr[j] == ',' || expr[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, strings.ReplaceAll(expr[i:j], ",", ""))
+			i = j
+		case strings.HasPrefix(expr[i:], "×"):
+			tokens = append(tokens, "*")
+			i += len("×")
+		case strings.HasPrefix(expr[i:], "÷"):
+			tokens = append(tokens, "/")
+			i += len("÷")
+		case c == 'x' || c == 'X':
+			tokens = append(tokens, "*")
+			i++
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	p := &arithmeticParser{tokens: tokens}
+	value, err := p.sum()
+	if err != nil {
+		return 0, err
+	}
+	if p.pos != len(p.tokens) {
+		return 0, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return value, nil
+}
+
+// sum parses term (("+"|"-") term)*
+func (p *arithmeticParser) sum() (float64, error) {
+	value, err := p.product()
+	for err == nil && p.pos < len(p.tokens) && (p.tokens[p.pos] == "+" || p.tokens[p.pos] == "-") {
+		op := p.tokens[p.pos]
+		p.pos++
+		var right float64
+		if right, err = p.product(); op == "+" {
+			value += right
+		} else {
+			value -= right
+		}
+	}
+	return value, err
+}
+
+// product parses factor (("*"|"/") factor)*
+func (p *arithmeticParser) product() (float64, error) {
+	value, err := p.factor()
+	for err == nil && p.pos < len(p.tokens) && (p.tokens[p.pos] == "*" || p.tokens[p.pos] == "/") {
+		op := p.tokens[p.pos]
+		p.pos++
+		var right float64
+		if right, err = p.factor(); err != nil {
+			break
+		}
+		if op == "*" {
+			value *= right
+		} else if right == 0 {
+			err = fmt.Errorf("division by zero")
+		} else {
+			value /= right
+		}
+	}
+	return value, err
+}
+
+// factor parses a number or "(" sum ")"
+func (p *arithmeticParser) factor() (float64, error) {
+	if p.pos >= len(p.tokens) {
+		return 0, fmt.Errorf("unexpected end")
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	if token == "(" {
+		value, err := p.sum()
+		if err != nil {
+			return 0, err
+		}
+		if p.pos >= len(p.tokens) || p.tokens[p.pos] != ")" {
+			return 0, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return value, nil
+	}
+	return strconv.ParseFloat(token, 64)
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// icalEscaper escapes text values (RFC 5545 section 3.3.11)
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+// GetCalendarToken returns user's calendar feed token, creating one if missing
+func (s *MongoDBService) GetCalendarToken(ctx context.Context, lineID string) (string, error) {
+	settings, err := s.GetUserSettings(ctx, lineID)
+	if err != nil {
+		return "", err
+	}
+	if settings.CalendarToken != "" {
+		return settings.CalendarToken, nil
+	}
+	return s.ResetCalendarToken(ctx, lineID)
+}
+
+// ResetCalendarToken issues a new feed token (old subscription URL stops working)
+func (s *MongoDBService) ResetCalendarToken(ctx context.Context, lineID string) (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	_, err := s.settingsCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID},
+		bson.M{"$set": bson.M{"calendar_token": token, "updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return token, err
+}
+
+// GetLineIDByCalendarToken finds user of a feed token ("" if not found)
+func (s *MongoDBService) GetLineIDByCalendarToken(ctx context.Context, token string) string {
+	var settings UserSettings
+	if token == "" || s.settingsCollection.FindOne(ctx, bson.M{"calendar_token": token}).Decode(&settings) != nil {
+		return ""
+	}
+	return settings.LineID
+}
+
+// icalEvent is one recurring all-day event
+type icalEvent struct {
+	UID         string
+	Start       time.Time
+	Summary     string
+	Description string
+	RRule       string
+}
+
+// BuildICalFeed creates iCalendar feed with card due dates and recurring bills
+func BuildICalFeed(subs []Subscription, cards []CardAccount, now time.Time) string {
+	var events []icalEvent
+
+	for _, card := range cards {
+		if card.DueDay > 0 {
+			events = append(events, icalEvent{
+				UID:         fmt.Sprintf("card-due-%s@satisatang", hex.EncodeToString([]byte(card.CardName))),
+				Start:       NextMonthDay(card.DueDay, now),
+				Summary:     fmt.Sprintf("💳 ครบกำหนดจ่ายบัตร %s", card.CardName),
+				Description: "อย่าลืมจ่ายบัตรเครดิต แล้วบันทึกใน สติสตางค์",
+				RRule:       monthlyRRule(card.DueDay),
+			})
+		}
+		if card.StatementDay > 0 {
+			events = append(events, icalEvent{
+				UID:     fmt.Sprintf("card-statement-%s@satisatang", hex.EncodeToString([]byte(card.CardName))),
+				Start:   NextMonthDay(card.StatementDay, now),
+				Summary: fmt.Sprintf("🧾 ตัดรอบบัตร %s", card.CardName),
+				RRule:   monthlyRRule(card.StatementDay),
+			})
+		}
+	}
+
+	for _, sub := range subs {
+		if sub.Status != "active" {
+			continue
+		}
+		start, err := time.ParseInLocation("2006-01-02", sub.NextRenewal, now.Location())
+		if err != nil {
+			continue
+		}
+		events = append(events, icalEvent{
+			UID:         fmt.Sprintf("sub-%s@satisatang", sub.ID.Hex()),
+			Start:       start,
+			Summary:     fmt.Sprintf("🔁 %s %.0f บาท", sub.Name, sub.Amount),
+			Description: fmt.Sprintf("ตัดเงิน %s", getPaymentInfo(sub.UseType, sub.BankName, sub.CreditCardName)),
+			RRule:       monthlyRRule(start.Day()),
+		})
+	}
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Satisatang//Bills//TH",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:สติสตางค์ - บิลและบัตร",
+		"X-WR-TIMEZONE:Asia/Bangkok",
+		"REFRESH-INTERVAL;VALUE=DURATION:PT12H",
+	}
+	for _, e := range events {
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+e.UID,
+			"DTSTAMP:"+stamp,
+			"DTSTART;VALUE=DATE:"+e.Start.Format("20060102"),
+			"DTEND;VALUE=DATE:"+e.Start.AddDate(0, 0, 1).Format("20060102"),
+			"RRULE:"+e.RRule,
+			"SUMMARY:"+icalEscaper.Replace(e.Summary),
+		)
+		if e.Description != "" {
+			lines = append(lines, "DESCRIPTION:"+icalEscaper.Replace(e.Description))
+		}
+		lines = append(lines,
+			"BEGIN:VALARM",
+			"ACTION:DISPLAY",
+			"DESCRIPTION:"+icalEscaper.Replace(e.Summary),
+			"TRIGGER:-P1D",
+			"END:VALARM",
+			"END:VEVENT",
+		)
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(foldICalLine(line))
+		b.WriteString("\r\n")
+	}
+	return b.String()
+}
+
+// monthlyRRule repeats monthly on day, falling back to the last day of shorter months the way
+// NextMonthDay does (30 -> 28/29 Feb but 30 Mar), so DTSTART from NextMonthDay is always an occurrence
+func monthlyRRule(day int) string {
+	switch {
+	case day >= 31:
+		return "FREQ=MONTHLY;BYMONTHDAY=-1"
+	case day > 28:
+		// Last of the listed days that exist in the month
+		days := make([]string, 0, day-27)
+		for d := 28; d <= day; d++ {
+			days = append(days, fmt.Sprint(d))
+		}
+		return fmt.Sprintf("FREQ=MONTHLY;BYMONTHDAY=%s;BYSETPOS=-1", strings.Join(days, ","))
+	}
+	return fmt.Sprintf("FREQ=MONTHLY;BYMONTHDAY=%d", day)
+}
+
+// foldICalLine splits lines longer than 75 octets without breaking UTF-8 characters
+func foldICalLine(line string) string {
+	if len(line) <= 75 {
+		return line
+	}
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CardAccount stores billing cycle of a user's credit card
+type CardAccount struct {
+	LineID       string    `bson:"lineid" json:"lineid"`
+	CardName     string    `bson:"card_name" json:"card_name"`
+	StatementDay int       `bson:"statement_day" json:"statement_day"` // วันตัดรอบ (1-31, 0 = unknown)
+	DueDay       int       `bson:"due_day" json:"due_day"`             // วันครบกำหนดชำระ (1-31)
+	UpdatedAt    time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// SetCardAccount creates or updates billing days of a card
+func (s *MongoDBService) SetCardAccount(ctx context.Context, lineID, cardName string, statementDay, dueDay int) error {
+	if dueDay < 1 || dueDay > 31 || statementDay < 0 || statementDay > 31 {
+		return fmt.Errorf("วันที่ต้องอยู่ระหว่าง 1-31")
+	}
+	_, err := s.cardCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID, "card_name": cardName},
+		bson.M{"$set": bson.M{
+			"statement_day": statementDay,
+			"due_day":       dueDay,
+			"updated_at":    time.Now(),
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if err == nil {
+		s.recordSyncChange(SyncChange{LineID: lineID, Entity: SyncEntityCard, Op: SyncOpUpsert, ID: cardName,
+			Card: &CardAccount{LineID: lineID, CardName: cardName, StatementDay: statementDay, DueDay: dueDay, UpdatedAt: time.Now()}})
+	}
+	return err
+}
+
+// GetCardAccounts returns all cards with billing days
+func (s *MongoDBService) GetCardAccounts(ctx context.Context, lineID string) ([]CardAccount, error) {
+	cursor, err := s.cardCollection.Find(ctx, bson.M{"lineid": lineID}, options.Find().SetSort(bson.M{"card_name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var cards []CardAccount
+	if err := cursor.All(ctx, &cards); err != nil {
+		return nil, err
+	}
+	return cards, nil
+}
+
+// NextMonthDay returns the next date (today or later) falling on day of month
+// Days past month end use the last day of that month (31 -> 30 Apr, 28/29 Feb)
+func NextMonthDay(day int, now time.Time) time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for i := 0; i < 2; i++ {
+		first := time.Date(today.Year(), today.Month()+time.Month(i), 1, 0, 0, 0, 0, now.Location())
+		lastDay := first.AddDate(0, 1, -1).Day()
+		d := day
+		if d > lastDay {
+			d = lastDay
+		}
+		date := time.Date(first.Year(), first.Month(), d, 0, 0, 0, 0, now.Location())
+		if !date.Before(today) {
+			return date
+		}
+	}
+	return today
+}
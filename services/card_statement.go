@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// cardSnoozeTTL is how long "เตือนพรุ่งนี้" hides a card's bill reminders
+const cardSnoozeTTL = 24 * time.Hour
+
+// CardStatement is the last closed billing cycle of a card and what is still unpaid
+type CardStatement struct {
+	Card        string  `json:"card"`
+	From        string  `json:"from"`        // first day of the cycle (YYYY-MM-DD)
+	CloseDate   string  `json:"close_date"`  // statement day
+	DueDate     string  `json:"due_date"`    // payment due
+	Charged     float64 `json:"charged"`     // card spending in the cycle
+	Credited    float64 `json:"credited"`    // refunds within the cycle (payments there settle the previous bill)
+	Paid        float64 `json:"paid"`        // payments into the card after the statement closed
+	Outstanding float64 `json:"outstanding"` // Charged - Credited - Paid
+}
+
+// monthDay returns day of year/month, using the month's last day when day is past it
+func monthDay(year int, month time.Month, day int, loc *time.Location) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+// CardCycle returns the last closed cycle (from..close, close before today) and its due date
+func CardCycle(statementDay, dueDay int, now time.Time) (from, close, due time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	close = monthDay(today.Year(), today.Month(), statementDay, now.Location())
+	if !close.Before(today) {
+		close = monthDay(today.Year(), today.Month()-1, statementDay, now.Location())
+	}
+	from = monthDay(close.Year(), close.Month()-1, statementDay, now.Location()).AddDate(0, 0, 1)
+	due = NextMonthDay(dueDay, close.AddDate(0, 0, 1))
+	return from, close, due
+}
+
+// BuildCardStatement totals a card's transactions (from SearchByPaymentRange, cycle start to today)
+// for the last closed cycle; nil when the card has no statement day
+func BuildCardStatement(card CardAccount, results []SearchResult, now time.Time) *CardStatement {
+	if card.StatementDay <= 0 || card.DueDay <= 0 {
+		return nil
+	}
+	from, close, due := CardCycle(card.StatementDay, card.DueDay, now)
+	st := &CardStatement{
+		Card:      card.CardName,
+		From:      from.Format("2006-01-02"),
+		CloseDate: close.Format("2006-01-02"),
+		DueDate:   due.Format("2006-01-02"),
+	}
+	for _, r := range results {
+		if r.Date < st.From {
+			continue
+		}
+		switch {
+		case r.Transaction.Type != 1 && r.Date <= st.CloseDate:
+			st.Charged += r.Transaction.Amount
+		case r.Transaction.Type == 1 && r.Date <= st.CloseDate && !r.Transaction.IsTransfer:
+			st.Credited += r.Transaction.Amount
+		case r.Transaction.Type == 1 && r.Date > st.CloseDate && r.Transaction.IsTransfer:
+			st.Paid += r.Transaction.Amount
+		}
+	}
+	st.Charged, st.Credited, st.Paid = roundBaht(st.Charged), roundBaht(st.Credited), roundBaht(st.Paid)
+	st.Outstanding = roundBaht(st.Charged - st.Credited - st.Paid)
+	return st
+}
+
+// ReminderNotice escalates with the due date: once when the statement closes, once 3 days before,
+// on the due day, then every day while overdue (ok=false when nothing is owed)
+func (st *CardStatement) ReminderNotice(now time.Time) (Notice, bool) {
+	if st == nil || st.Outstanding < 1 {
+		return Notice{}, false
+	}
+	due, err := time.ParseInLocation("2006-01-02", st.DueDate, now.Location())
+	if err != nil {
+		return Notice{}, false
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	days := int(due.Sub(today).Hours() / 24)
+	key := fmt.Sprintf("card_bill:%s:%s:", st.Card, st.CloseDate)
+	amount := fmt.Sprintf("%.0f", st.Outstanding)
+
+	notice := Notice{Type: NotifyCardDue, Actions: []NoticeAction{
+		{Label: "✅ จ่ายแล้ว", Data: fmt.Sprintf("action=card_paid&card=%s", st.Card)},
+		{Label: "⏰ เตือนพรุ่งนี้", Data: fmt.Sprintf("action=card_snooze&card=%s", st.Card)},
+	}}
+	switch {
+	case days < 0:
+		notice.Key = key + "overdue:" + today.Format("2006-01-02")
+		notice.Text = fmt.Sprintf("🚨 บัตร %s เลยกำหนดจ่ายมา %d วัน ค้าง %s บาท", st.Card, -days, amount)
+	case days == 0:
+		notice.Key = key + "due"
+		notice.Text = fmt.Sprintf("⏰ วันนี้ครบกำหนดจ่ายบัตร %s ยอด %s บาท", st.Card, amount)
+	case days <= 3:
+		notice.Key = key + "soon"
+		notice.Text = fmt.Sprintf("💳 อีก %d วันครบกำหนดจ่ายบัตร %s (%s) ยอด %s บาท", days, st.Card, due.Format("02/01"), amount)
+	default:
+		notice.Key = key + "closed"
+		notice.Text = fmt.Sprintf("🧾 บัตร %s ตัดรอบแล้ว ยอด %s บาท ครบกำหนด %s", st.Card, amount, due.Format("02/01"))
+	}
+	return notice, true
+}
+
+// GetCardStatement returns the last closed cycle of cardName (nil when the card has no statement day)
+func (s *MongoDBService) GetCardStatement(ctx context.Context, lineID, cardName string, now time.Time) (*CardStatement, error) {
+	cards, err := s.GetCardAccounts(ctx, lineID)
+	if err != nil {
+		return nil, err
+	}
+	for _, card := range cards {
+		if card.CardName == cardName {
+			return s.cardStatement(ctx, lineID, card, now)
+		}
+	}
+	return nil, nil
+}
+
+// cardStatement loads a card's transactions since its cycle start
+func (s *MongoDBService) cardStatement(ctx context.Context, lineID string, card CardAccount, now time.Time) (*CardStatement, error) {
+	if card.StatementDay <= 0 || card.DueDay <= 0 {
+		return nil, nil
+	}
+	from, _, _ := CardCycle(card.StatementDay, card.DueDay, now)
+	results, err := s.SearchByPaymentRange(ctx, lineID, AccountRef{UseType: 1, CreditCardName: card.CardName},
+		from.Format("2006-01-02"), now.Format("2006-01-02"), 1000)
+	if err != nil {
+		return nil, err
+	}
+	return BuildCardStatement(card, results, now), nil
+}
+
+// SnoozeCardReminder hides a card's bill reminders for a day
+func (s *MongoDBService) SnoozeCardReminder(ctx context.Context, lineID, cardName string) error {
+	return s.SaveTempData(ctx, cardSnoozeKey(lineID, cardName), "1", cardSnoozeTTL)
+}
+
+// isCardReminderSnoozed reports whether "เตือนพรุ่งนี้" was tapped within a day
+func (s *MongoDBService) isCardReminderSnoozed(ctx context.Context, lineID, cardName string) bool {
+	data, err := s.GetTempData(ctx, cardSnoozeKey(lineID, cardName))
+	return err == nil && data != ""
+}
+
+// cardSnoozeKey is the temp data key of a card's snooze
+func cardSnoozeKey(lineID, cardName string) string {
+	return "card_snooze_" + lineID + "_" + cardName
+}
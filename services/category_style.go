@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CategoryStyle represents user's emoji and color for a category
+type CategoryStyle struct {
+	LineID    string    `bson:"lineid" json:"lineid"`
+	Category  string    `bson:"category" json:"category"`
+	Emoji     string    `bson:"emoji,omitempty" json:"emoji,omitempty"`
+	Color     string    `bson:"color,omitempty" json:"color,omitempty"` // #RRGGBB
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// CategoryStyles maps category to user's style (falls back to defaults)
+type CategoryStyles map[string]CategoryStyle
+
+// defaultCategoryEmojis are built-in emojis for common categories
+var defaultCategoryEmojis = map[string]string{
+	"อาหาร": "🍔", "เดินทาง": "🚗", "ที่อยู่": "🏠", "ค่าน้ำ": "💧", "ค่าไฟ": "💡",
+	"ช้อปปิ้ง": "🛒", "บันเทิง": "🎬", "สุขภาพ": "💊", "การศึกษา": "📚", "ของใช้": "🧴",
+	"เงินเดือน": "💵", "โบนัส": "🎁", "โอนเงิน": "🔄",
+}
+
+// emojiKeywords give sensible emojis to new categories by keyword
+var emojiKeywords = []struct{ keyword, emoji string }{
+	{"กาแฟ", "☕"}, {"ชา", "🧋"}, {"ขนม", "🍰"}, {"เครื่องดื่ม", "🥤"},
+	{"น้ำมัน", "⛽"}, {"รถ", "🚗"}, {"แท็กซี่", "🚕"}, {"เที่ยว", "✈️"},
+	{"เสื้อ", "👕"}, {"แต่งตัว", "👗"}, {"ความงาม", "💄"},
+	{"โทรศัพท์", "📱"}, {"เน็ต", "🌐"}, {"หนังสือ", "📚"}, {"เรียน", "🎓"},
+	{"หมอ", "🏥"}, {"ยา", "💊"}, {"ประกัน", "🛡️"}, {"สัตว์", "🐾"},
+	{"ลูก", "👶"}, {"บ้าน", "🏠"}, {"เกม", "🎮"}, {"ลงทุน", "📈"}, {"ออม", "🐷"},
+	{"ภาษี", "🧾"}, {"ธรรมเนียม", "🏧"}, {"บริจาค", "🙏"}, {"ทำบุญ", "🙏"}, {"ของขวัญ", "🎁"},
+}
+
+// defaultCategoryColors are built-in colors for common categories
+var defaultCategoryColors = map[string]string{
+	"อาหาร": "#FAB1A0", "เดินทาง": "#74B9FF", "ที่อยู่": "#A29BFE", "ค่าน้ำ": "#81ECEC", "ค่าไฟ": "#FFEAA7",
+	"ช้อปปิ้ง": "#FD79A8", "บันเทิง": "#E17055", "สุขภาพ": "#55EFC4", "การศึกษา": "#00CEC9", "ของใช้": "#DFE6E9",
+	"เงินเดือน": "#55EFC4", "โบนัส": "#FFEAA7", "โอนเงิน": "#DFE6E9",
+}
+
+// hexColorPattern validates #RRGGBB colors
+var hexColorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// IsValidHexColor checks if color is #RRGGBB
+func IsValidHexColor(color string) bool {
+	return hexColorPattern.MatchString(color)
+}
+
+// DefaultCategoryEmoji returns built-in emoji for category (keyword match for new categories)
+func DefaultCategoryEmoji(category string) string {
+	if e, ok := defaultCategoryEmojis[category]; ok {
+		return e
+	}
+	for _, kw := range emojiKeywords {
+		if strings.Contains(category, kw.keyword) {
+			return kw.emoji
+		}
+	}
+	return "💰"
+}
+
+// DefaultCategoryColor returns built-in color for category (stable pastel for new categories)
+func DefaultCategoryColor(category string) string {
+	if c, ok := defaultCategoryColors[category]; ok {
+		return c
+	}
+	h := fnv.New32a()
+	h.Write([]byte(category))
+	return categoryColors[h.Sum32()%uint32(len(categoryColors))]
+}
+
+// Emoji returns user's emoji for category or default
+func (cs CategoryStyles) Emoji(category string) string {
+	if style, ok := cs[category]; ok && style.Emoji != "" {
+		return style.Emoji
+	}
+	return DefaultCategoryEmoji(category)
+}
+
+// Color returns user's color for category or default
+func (cs CategoryStyles) Color(category string) string {
+	if style, ok := cs[category]; ok && style.Color != "" {
+		return style.Color
+	}
+	return DefaultCategoryColor(category)
+}
+
+// RGB returns category color as RGB values (for PDF)
+func (cs CategoryStyles) RGB(category string) (uint8, uint8, uint8) {
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(cs.Color(category), "#%02x%02x%02x", &r, &g, &b); err != nil {
+		return 223, 230, 233 // Light gray
+	}
+	return r, g, b
+}
+
+// GetCategoryStyles returns user's category styles (empty on error, defaults still apply)
+func (s *MongoDBService) GetCategoryStyles(ctx context.Context, lineID string) CategoryStyles {
+	styles := make(CategoryStyles)
+
+	cursor, err := s.categoryStyleCollection.Find(ctx, bson.M{"lineid": lineID})
+	if err != nil {
+		return styles
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var style CategoryStyle
+		if err := cursor.Decode(&style); err == nil {
+			styles[style.Category] = style
+		}
+	}
+	return styles
+}
+
+// SetCategoryEmoji saves user's emoji for a category
+func (s *MongoDBService) SetCategoryEmoji(ctx context.Context, lineID, category, emoji string) error {
+	return s.setCategoryStyleField(ctx, lineID, category, "emoji", emoji)
+}
+
+// SetCategoryColor saves user's color (#RRGGBB) for a category
+func (s *MongoDBService) SetCategoryColor(ctx context.Context, lineID, category, color string) error {
+	if !IsValidHexColor(color) {
+		return fmt.Errorf("invalid color: %s", color)
+	}
+	return s.setCategoryStyleField(ctx, lineID, category, "color", strings.ToUpper(color))
+}
+
+// setCategoryStyleField upserts one style field for a category
+func (s *MongoDBService) setCategoryStyleField(ctx context.Context, lineID, category, field, value string) error {
+	_, err := s.categoryStyleCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID, "category": category},
+		bson.M{"$set": bson.M{field: value, "updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
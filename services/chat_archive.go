@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ChatArchiveMessage is one chat message kept beyond the short AI context window
+type ChatArchiveMessage struct {
+	LineID    string    `bson:"lineid" json:"lineid"`
+	Role      string    `bson:"role" json:"role"` // "user" or "assistant"
+	Content   string    `bson:"content" json:"content"`
+	Timestamp time.Time `bson:"timestamp" json:"timestamp"`
+}
+
+// archiveChatMessage stores message in the full chat archive
+func (s *MongoDBService) archiveChatMessage(ctx context.Context, lineID string, msg ChatMessage) error {
+	_, err := s.chatArchiveCollection.InsertOne(ctx, ChatArchiveMessage{
+		LineID:    lineID,
+		Role:      msg.Role,
+		Content:   msg.Content,
+		Timestamp: msg.Timestamp,
+	})
+	return err
+}
+
+// GetChatArchive returns archived messages between from and to (inclusive), oldest first
+func (s *MongoDBService) GetChatArchive(ctx context.Context, lineID string, from, to time.Time) ([]ChatMessage, error) {
+	cursor, err := s.chatArchiveCollection.Find(ctx,
+		bson.M{"lineid": lineID, "timestamp": bson.M{"$gte": from, "$lte": to}},
+		options.Find().SetSort(bson.M{"timestamp": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var messages []ChatMessage
+	for cursor.Next(ctx) {
+		var archived ChatArchiveMessage
+		if err := cursor.Decode(&archived); err != nil {
+			continue
+		}
+		messages = append(messages, ChatMessage{Role: archived.Role, Content: archived.Content, Timestamp: archived.Timestamp})
+	}
+	return messages, nil
+}
+
+// defaultChatHistoryLimit is the live chat window used for AI context
+const defaultChatHistoryLimit = 20
+
+// ConfigureChatRetention sets live chat window and archive TTL (archiveDays 0 keeps messages forever)
+func (s *MongoDBService) ConfigureChatRetention(liveLimit, archiveDays int) error {
+	if liveLimit > 0 {
+		s.chatHistoryLimit = liveLimit
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Every instance runs this at startup: leave a matching index alone and change a different TTL
+	// in place (collMod), so the archive is never without its TTL while the index is rebuilt
+	const indexName = "chat_archive_ttl"
+	indexes := s.chatArchiveCollection.Indexes()
+	specs, err := indexes.ListSpecifications(ctx)
+	if err != nil {
+		return err
+	}
+	var current *mongo.IndexSpecification
+	for _, spec := range specs {
+		if spec.Name == indexName {
+			current = spec
+		}
+	}
+
+	if archiveDays <= 0 {
+		if current == nil {
+			return nil
+		}
+		_, err := indexes.DropOne(ctx, indexName)
+		return err
+	}
+	ttl := int32(archiveDays * 24 * 60 * 60)
+	switch {
+	case current == nil:
+		_, err = indexes.CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.M{"timestamp": 1},
+			Options: options.Index().SetName(indexName).SetExpireAfterSeconds(ttl),
+		})
+	case current.ExpireAfterSeconds == nil || *current.ExpireAfterSeconds != ttl:
+		err = s.database.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: s.chatArchiveCollection.Name()},
+			{Key: "index", Value: bson.M{"name": indexName, "expireAfterSeconds": ttl}},
+		}).Err()
+	}
+	return err
+}
+
+// GetRecentChatArchive returns the last limit archived messages, oldest first
+func (s *MongoDBService) GetRecentChatArchive(ctx context.Context, lineID string, limit int) ([]ChatMessage, error) {
+	cursor, err := s.chatArchiveCollection.Find(ctx,
+		bson.M{"lineid": lineID},
+		options.Find().SetSort(bson.M{"timestamp": -1}).SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var messages []ChatMessage
+	for cursor.Next(ctx) {
+		var archived ChatArchiveMessage
+		if err := cursor.Decode(&archived); err != nil {
+			continue
+		}
+		messages = append(messages, ChatMessage{Role: archived.Role, Content: archived.Content, Timestamp: archived.Timestamp})
+	}
+	// Reverse to chronological order
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, nil
+}
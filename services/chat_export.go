@@ -0,0 +1,135 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/signintech/gopdf"
+)
+
+// chatRoleName returns speaker label used in transcripts
+func chatRoleName(role string) string {
+	if role == "assistant" {
+		return "สติสตางค์"
+	}
+	return "คุณ"
+}
+
+// stripEmoji removes symbols Sarabun has no glyphs for
+func stripEmoji(text string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 0x1F000, r >= 0x2600 && r <= 0x27BF, r == 0xFE0F, r == 0x200D:
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// getChatTranscript loads archived chat between dates (YYYY-MM-DD, inclusive)
+func (s *ExportService) getChatTranscript(ctx context.Context, lineID, from, to string) ([]ChatMessage, error) {
+	fromDate, err := time.ParseInLocation("2006-01-02", from, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date: %s", from)
+	}
+	toDate, err := time.ParseInLocation("2006-01-02", to, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date: %s", to)
+	}
+	messages, err := s.mongo.GetChatArchive(ctx, lineID, fromDate, toDate.AddDate(0, 0, 1).Add(-time.Nanosecond))
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("ไม่พบประวัติแชทช่วง %s ถึง %s", from, to)
+	}
+	return messages, nil
+}
+
+// ExportChatTranscriptText exports chat history as plain text transcript
+func (s *ExportService) ExportChatTranscriptText(ctx context.Context, lineID, from, to string) ([]byte, string, error) {
+	messages, err := s.getChatTranscript(ctx, lineID, from, to)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "ประวัติแชท สติสตางค์ (%s ถึง %s)\n\n", from, to)
+	for _, msg := range messages {
+		fmt.Fprintf(&buf, "[%s] %s:\n%s\n\n", msg.Timestamp.Local().Format("2006-01-02 15:04"), chatRoleName(msg.Role), msg.Content)
+	}
+	return buf.Bytes(), fmt.Sprintf("chat_%s_%s.txt", from, to), nil
+}
+
+// ExportChatTranscriptPDF exports chat history as PDF transcript
+func (s *ExportService) ExportChatTranscriptPDF(ctx context.Context, lineID, from, to string) ([]byte, string, error) {
+	messages, err := s.getChatTranscript(ctx, lineID, from, to)
+	if err != nil {
+		return nil, "", err
+	}
+
+	pdf := gopdf.GoPdf{}
+	pdf.Start(gopdf.Config{PageSize: *gopdf.PageSizeA4})
+	if err := addThaiFonts(&pdf); err != nil {
+		return nil, "", err
+	}
+
+	const (
+		left       = 40.0
+		width      = 515.0
+		lineHeight = 18.0
+		pageBottom = 800.0
+	)
+	pdf.AddPage()
+	pdf.SetTextColor(45, 52, 54)
+	pdf.SetFont("SarabunBold", "", 18)
+	pdf.SetXY(left, 40)
+	pdf.Cell(nil, fmt.Sprintf("ประวัติแชท สติสตางค์ (%s ถึง %s)", from, to))
+	y := 80.0
+
+	newLine := func() {
+		y += lineHeight
+		if y > pageBottom {
+			pdf.AddPage()
+			y = 40
+		}
+	}
+
+	for _, msg := range messages {
+		// Speaker line
+		pdf.SetFont("SarabunBold", "", 12)
+		if msg.Role == "assistant" {
+			pdf.SetTextColor(108, 92, 231)
+		} else {
+			pdf.SetTextColor(0, 150, 120)
+		}
+		pdf.SetXY(left, y)
+		pdf.Cell(nil, fmt.Sprintf("%s  %s", chatRoleName(msg.Role), msg.Timestamp.Local().Format("2006-01-02 15:04")))
+		newLine()
+
+		// Message body, wrapped to page width
+		pdf.SetFont("Sarabun", "", 12)
+		pdf.SetTextColor(45, 52, 54)
+		for _, paragraph := range strings.Split(stripEmoji(msg.Content), "\n") {
+			lines, err := pdf.SplitText(paragraph, width)
+			if err != nil || len(lines) == 0 {
+				lines = []string{paragraph}
+			}
+			for _, line := range lines {
+				pdf.SetXY(left, y)
+				pdf.Cell(nil, line)
+				newLine()
+			}
+		}
+		newLine()
+	}
+
+	var buf bytes.Buffer
+	if _, err := pdf.WriteTo(&buf); err != nil {
+		return nil, "", fmt.Errorf("ไม่สามารถสร้างไฟล์ PDF: %w", err)
+	}
+	return buf.Bytes(), fmt.Sprintf("chat_%s_%s.pdf", from, to), nil
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CloseReportSchemaVersion is bumped on breaking changes to CloseReport (renamed/removed fields or
+// changed meaning); new fields are added without a bump
+const CloseReportSchemaVersion = "1"
+
+// CloseReport is the machine-readable month-end report (same numbers as the PDF), amounts in THB
+type CloseReport struct {
+	SchemaVersion string          `json:"schema_version"`
+	Month         string          `json:"month"` // YYYY-MM
+	From          string          `json:"from"`  // YYYY-MM-DD
+	To            string          `json:"to"`    // YYYY-MM-DD
+	Currency      string          `json:"currency"`
+	Closed        bool            `json:"closed"` // whole month inside the closed (read-only) period
+	GeneratedAt   time.Time       `json:"generated_at"`
+	Totals        CloseTotals     `json:"totals"`
+	Categories    []CloseCategory `json:"categories"`                // highest amount first, expenses then incomes
+	Accounts      []CloseAccount  `json:"accounts"`                  // by label
+	Budgets       []CloseBudget   `json:"budgets"`                   // by category
+	HashChainHead string          `json:"hash_chain_head,omitempty"` // month's hash chain head when hashing is on
+}
+
+// CloseTotals are the month's totals; transfers and investments are not income or expense
+type CloseTotals struct {
+	Income           float64 `json:"income"`
+	Expense          float64 `json:"expense"`
+	Net              float64 `json:"net"`      // income - expense
+	Invested         float64 `json:"invested"` // bought minus sold, negative when more was sold
+	InvestmentBought float64 `json:"investment_bought"`
+	InvestmentSold   float64 `json:"investment_sold"`
+	TransactionCount int     `json:"transaction_count"`
+}
+
+// CloseCategory is one category's total
+type CloseCategory struct {
+	Type     string  `json:"type"` // "expense" or "income"
+	Category string  `json:"category"`
+	Amount   float64 `json:"amount"`
+	Count    int     `json:"count"`
+	Percent  float64 `json:"percent"` // share of the type's total
+}
+
+// CloseAccount is the money in and out of one account, transfers and investments included
+type CloseAccount struct {
+	Account string  `json:"account"` // display label ("บัญชีกสิกร", "บัตร KTC", "เงินสด")
+	Type    string  `json:"type"`    // "cash", "bank" or "credit_card"
+	Name    string  `json:"name"`    // bank or card name, "" for cash
+	In      float64 `json:"in"`      // money in
+	Out     float64 `json:"out"`     // money out (card spending is out)
+	Net     float64 `json:"net"`     // in - out
+}
+
+// CloseBudget is a monthly budget against the month's spending
+type CloseBudget struct {
+	Category  string  `json:"category"`
+	Budget    float64 `json:"budget"`
+	Spent     float64 `json:"spent"`
+	Remaining float64 `json:"remaining"`
+	Percent   float64 `json:"percent"`
+	Over      bool    `json:"over"`
+}
+
+// accountTypes name usetype values in the report
+var accountTypes = map[int]string{0: "cash", 1: "credit_card", 2: "bank"}
+
+// MonthRange returns the first and last day of month (YYYY-MM)
+func MonthRange(month string) (string, string, error) {
+	first, err := time.Parse("2006-01", month)
+	if err != nil {
+		return "", "", fmt.Errorf("month must be YYYY-MM: %w", err)
+	}
+	return first.Format("2006-01-02"), first.AddDate(0, 1, -1).Format("2006-01-02"), nil
+}
+
+// BuildCloseReport totals the month's records; budgets are the current monthly budgets and
+// lockedThrough the last read-only date of the user
+func BuildCloseReport(month string, records []DailyRecord, budgets []Budget, lockedThrough string, now time.Time) (*CloseReport, error) {
+	from, to, err := MonthRange(month)
+	if err != nil {
+		return nil, err
+	}
+	report := &CloseReport{
+		SchemaVersion: CloseReportSchemaVersion,
+		Month:         month,
+		From:          from,
+		To:            to,
+		Currency:      "THB",
+		Closed:        lockedThrough != "" && to <= lockedThrough,
+		GeneratedAt:   now,
+		Categories:    []CloseCategory{},
+		Accounts:      []CloseAccount{},
+		Budgets:       []CloseBudget{},
+	}
+
+	categories := make(map[string]*CloseCategory)
+	accounts := make(map[string]*CloseAccount)
+	addCategory := func(kind string, tx Transaction) {
+		name := tx.Category
+		if name == "" {
+			name = "อื่นๆ"
+		}
+		key := kind + "|" + name
+		if categories[key] == nil {
+			categories[key] = &CloseCategory{Type: kind, Category: name}
+		}
+		categories[key].Amount += tx.Amount
+		categories[key].Count++
+	}
+	account := func(tx Transaction) *CloseAccount {
+		label := getPaymentInfo(tx.UseType, tx.BankName, tx.CreditCardName)
+		if accounts[label] == nil {
+			name := ""
+			switch tx.UseType {
+			case 1:
+				name = tx.CreditCardName
+			case 2:
+				name = tx.BankName
+			}
+			accounts[label] = &CloseAccount{Account: label, Type: accountTypes[tx.UseType], Name: name}
+		}
+		return accounts[label]
+	}
+
+	for _, record := range records {
+		if record.Date < from || record.Date > to {
+			continue
+		}
+		for _, tx := range record.Incomes {
+			account(tx).In += tx.Amount
+			if tx.Category == InvestmentCategory {
+				report.Totals.InvestmentSold += tx.Amount
+				continue
+			}
+			if tx.IsTransfer {
+				continue
+			}
+			report.Totals.Income += tx.Amount
+			report.Totals.TransactionCount++
+			addCategory("income", tx)
+		}
+		for _, tx := range record.Expenses {
+			account(tx).Out += tx.Amount
+			if tx.Category == InvestmentCategory {
+				report.Totals.InvestmentBought += tx.Amount
+				continue
+			}
+			if tx.IsTransfer {
+				continue
+			}
+			report.Totals.Expense += tx.Amount
+			report.Totals.TransactionCount++
+			addCategory("expense", tx)
+		}
+	}
+	report.Totals.Income = roundBaht(report.Totals.Income)
+	report.Totals.Expense = roundBaht(report.Totals.Expense)
+	report.Totals.InvestmentBought = roundBaht(report.Totals.InvestmentBought)
+	report.Totals.InvestmentSold = roundBaht(report.Totals.InvestmentSold)
+	report.Totals.Invested = roundBaht(report.Totals.InvestmentBought - report.Totals.InvestmentSold)
+	report.Totals.Net = roundBaht(report.Totals.Income - report.Totals.Expense)
+
+	spent := make(map[string]float64)
+	for _, c := range categories {
+		total := report.Totals.Expense
+		if c.Type == "income" {
+			total = report.Totals.Income
+		} else {
+			spent[c.Category] = c.Amount
+		}
+		c.Amount = roundBaht(c.Amount)
+		if total > 0 {
+			c.Percent = math.Round(c.Amount/total*1000) / 10
+		}
+		report.Categories = append(report.Categories, *c)
+	}
+	sort.Slice(report.Categories, func(i, j int) bool {
+		a, b := report.Categories[i], report.Categories[j]
+		if a.Type != b.Type {
+			return a.Type == "expense"
+		}
+		if a.Amount != b.Amount {
+			return a.Amount > b.Amount
+		}
+		return a.Category < b.Category
+	})
+
+	for _, a := range accounts {
+		a.In, a.Out = roundBaht(a.In), roundBaht(a.Out)
+		a.Net = roundBaht(a.In - a.Out)
+		report.Accounts = append(report.Accounts, *a)
+	}
+	sort.Slice(report.Accounts, func(i, j int) bool { return report.Accounts[i].Account < report.Accounts[j].Account })
+
+	for _, b := range budgets {
+		used := roundBaht(spent[b.Category])
+		budget := CloseBudget{Category: b.Category, Budget: b.Amount, Spent: used, Remaining: roundBaht(b.Amount - used), Over: used > b.Amount}
+		if b.Amount > 0 {
+			budget.Percent = math.Round(used/b.Amount*1000) / 10
+		}
+		report.Budgets = append(report.Budgets, budget)
+	}
+	sort.Slice(report.Budgets, func(i, j int) bool { return report.Budgets[i].Category < report.Budgets[j].Category })
+
+	return report, nil
+}
+
+// GetCloseReport builds the close report of month (YYYY-MM), reading archived days when needed
+func (s *MongoDBService) GetCloseReport(ctx context.Context, lineID, month string) (*CloseReport, error) {
+	from, to, err := MonthRange(month)
+	if err != nil {
+		return nil, err
+	}
+	var records []DailyRecord
+	filter := bson.M{"lineid": lineID, "date": bson.M{"$gte": from, "$lte": to}}
+	err = s.eachDailyRecord(ctx, lineID, from, filter, nil, true, func(record DailyRecord) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	budgets, err := s.GetAllBudgets(ctx, lineID)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := s.GetUserSettings(ctx, lineID)
+	if err != nil {
+		return nil, err
+	}
+	report, err := BuildCloseReport(month, records, budgets, settings.LockedThrough(), time.Now())
+	if err == nil && settings.HashChain {
+		report.HashChainHead = s.GetHashChainHead(ctx, lineID, month)
+	}
+	return report, err
+}
+
+// GetReportToken returns user's report API token, creating one if missing
+func (s *MongoDBService) GetReportToken(ctx context.Context, lineID string) (string, error) {
+	settings, err := s.GetUserSettings(ctx, lineID)
+	if err != nil {
+		return "", err
+	}
+	if settings.ReportToken != "" {
+		return settings.ReportToken, nil
+	}
+	return s.ResetReportToken(ctx, lineID)
+}
+
+// ResetReportToken issues a new report API token (tools using the old one get 401)
+func (s *MongoDBService) ResetReportToken(ctx context.Context, lineID string) (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	_, err := s.settingsCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID},
+		bson.M{"$set": bson.M{"report_token": token, "updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return token, err
+}
+
+// GetLineIDByReportToken finds user of a report API token ("" if not found)
+func (s *MongoDBService) GetLineIDByReportToken(ctx context.Context, token string) string {
+	var settings UserSettings
+	if token == "" || s.settingsCollection.FindOne(ctx, bson.M{"report_token": token}).Decode(&settings) != nil {
+		return ""
+	}
+	return settings.LineID
+}
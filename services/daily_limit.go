@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// dailyLimitPattern matches "จำกัดวันละ 500", "จำกัดรายวัน 500 บาท", "งบวันละ 300"
+var dailyLimitPattern = regexp.MustCompile(`^(?:จำกัด\s*(?:วันละ|รายวัน|ต่อวัน)|งบ\s*วันละ)\s*(.*)$`)
+
+// DailyLimitCommand is a parsed daily spending cap command
+type DailyLimitCommand struct {
+	Action string // "set", "off" or "show"
+	Limit  float64
+}
+
+// ParseDailyLimitCommand parses "จำกัดวันละ 500", "จำกัดวันละ" (show) and "ยกเลิกจำกัดวันละ"/"จำกัดวันละ 0" (no AI)
+func ParseDailyLimitCommand(text string) (DailyLimitCommand, bool) {
+	text = strings.TrimSpace(text)
+	switch strings.ReplaceAll(text, " ", "") {
+	case "ยกเลิกจำกัดวันละ", "ยกเลิกจำกัดรายวัน", "ปิดจำกัดวันละ", "ปิดจำกัดรายวัน", "ยกเลิกงบวันละ":
+		return DailyLimitCommand{Action: "off"}, true
+	}
+	m := dailyLimitPattern.FindStringSubmatch(text)
+	if m == nil {
+		return DailyLimitCommand{}, false
+	}
+	rest := strings.TrimSpace(m[1])
+	if rest == "" {
+		return DailyLimitCommand{Action: "show"}, true
+	}
+	limit, ok := ParseThaiAmount(rest)
+	if !ok || limit < 0 {
+		return DailyLimitCommand{}, false
+	}
+	if limit == 0 {
+		return DailyLimitCommand{Action: "off"}, true
+	}
+	return DailyLimitCommand{Action: "set", Limit: limit}, true
+}
+
+// DailyLimitStatus is one day's spending against the daily cap
+type DailyLimitStatus struct {
+	Spent   float64 `json:"spent"`
+	Limit   float64 `json:"limit"`
+	Percent float64 `json:"percent"` // spent/limit * 100
+	Over    bool    `json:"over"`
+}
+
+// NewDailyLimitStatus compares a day's expense total with limit (nil when no limit is set)
+func NewDailyLimitStatus(spent, limit float64) *DailyLimitStatus {
+	if limit <= 0 {
+		return nil
+	}
+	spent = roundBaht(spent)
+	return &DailyLimitStatus{
+		Spent:   spent,
+		Limit:   limit,
+		Percent: math.Round(spent/limit*1000) / 10,
+		Over:    spent > limit,
+	}
+}
+
+// ProgressText returns "📆 วันนี้ใช้ไป 320/500 บาท (64%)" or the amount over the cap
+func (st *DailyLimitStatus) ProgressText() string {
+	if st.Over {
+		return fmt.Sprintf("⚠️ วันนี้ใช้ไป %.0f/%.0f บาท เกินวงเงินรายวัน %.0f บาท", st.Spent, st.Limit, st.Spent-st.Limit)
+	}
+	return fmt.Sprintf("📆 วันนี้ใช้ไป %.0f/%.0f บาท (%.0f%%)", st.Spent, st.Limit, st.Percent)
+}
+
+// SetDailyLimit sets the daily spending cap (0 turns it off)
+func (s *MongoDBService) SetDailyLimit(ctx context.Context, lineID string, limit float64) error {
+	update := bson.M{"$set": bson.M{"daily_limit": limit, "updated_at": time.Now()}}
+	if limit <= 0 {
+		update = bson.M{"$unset": bson.M{"daily_limit": ""}, "$set": bson.M{"updated_at": time.Now()}}
+	}
+	_, err := s.settingsCollection.UpdateOne(ctx, bson.M{"lineid": lineID}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// DailySpending sums a day's spending; transfer legs (card payments, moves to savings) and investment
+// buys are not spending
+func DailySpending(record DailyRecord) float64 {
+	var total float64
+	for _, tx := range record.Expenses {
+		if tx.IsTransfer || tx.Category == InvestmentCategory {
+			continue
+		}
+		total += tx.Amount
+	}
+	return total
+}
+
+// GetDailyLimitStatus returns date's (YYYY-MM-DD) spending against the user's daily cap
+// (nil when no cap is set, so users without one pay only the settings read)
+func (s *MongoDBService) GetDailyLimitStatus(ctx context.Context, lineID, date string) (*DailyLimitStatus, error) {
+	settings, err := s.GetUserSettings(ctx, lineID)
+	if err != nil || settings.DailyLimit <= 0 {
+		return nil, err
+	}
+	var record DailyRecord
+	err = s.collection.FindOne(ctx, bson.M{"lineid": lineID, "date": date},
+		options.FindOne().SetProjection(bson.M{"expenses.amount": 1, "expenses.category": 1, "expenses.is_transfer": 1})).Decode(&record)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	return NewDailyLimitStatus(DailySpending(record), settings.DailyLimit), nil
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateRange is a date expression resolved from Thai text
+type DateRange struct {
+	From       time.Time
+	To         time.Time
+	Anchor     time.Time // single date to use for backdated entries (zero for week/month/year ranges)
+	Expression string    // matched text
+}
+
+// FromString returns From as YYYY-MM-DD
+func (r DateRange) FromString() string { return r.From.Format("2006-01-02") }
+
+// ToString returns To as YYYY-MM-DD
+func (r DateRange) ToString() string { return r.To.Format("2006-01-02") }
+
+// thaiMonths maps Thai month names and abbreviations to month
+var thaiMonths = map[string]time.Month{
+	"มกราคม": 1, "กุมภาพันธ์": 2, "มีนาคม": 3, "เมษายน": 4, "พฤษภาคม": 5, "มิถุนายน": 6,
+	"กรกฎาคม": 7, "สิงหาคม": 8, "กันยายน": 9, "ตุลาคม": 10, "พฤศจิกายน": 11, "ธันวาคม": 12,
+	"ม.ค.": 1, "ก.พ.": 2, "มี.ค.": 3, "เม.ย.": 4, "พ.ค.": 5, "มิ.ย.": 6,
+	"ก.ค.": 7, "ส.ค.": 8, "ก.ย.": 9, "ต.ค.": 10, "พ.ย.": 11, "ธ.ค.": 12,
+}
+
+// thaiWeekdays maps Thai weekday names to weekday
+var thaiWeekdays = []struct {
+	name    string
+	weekday time.Weekday
+}{
+	{"จันทร์", time.Monday}, {"อังคาร", time.Tuesday}, {"พุธ", time.Wednesday},
+	{"พฤหัสบดี", time.Thursday}, {"พฤหัส", time.Thursday}, {"ศุกร์", time.Friday},
+	{"เสาร์", time.Saturday}, {"อาทิตย์", time.Sunday},
+}
+
+var (
+	isoDatePattern    = regexp.MustCompile(`(\d{4})-(\d{1,2})-(\d{1,2})`)
+	slashDatePattern  = regexp.MustCompile(`(\d{1,2})/(\d{1,2})(?:/(\d{2,4}))?`)
+	thaiDatePattern   = regexp.MustCompile(`(\d{1,2})\s*(` + thaiMonthAlternation() + `)\s*(\d{2,4})?`)
+	dayOfMonthPattern = regexp.MustCompile(`วันที่\s*(\d{1,2})`)
+	daysAgoPattern    = regexp.MustCompile(`(\d+)\s*วัน(?:ก่อน|ที่แล้ว)`)
+)
+
+// thaiMonthAlternation builds regex alternation of Thai month names (longest first)
+func thaiMonthAlternation() string {
+	names := make([]string, 0, len(thaiMonths))
+	for name := range thaiMonths {
+		names = append(names, regexp.QuoteMeta(name))
+	}
+	// Longer names first so "มีนาคม" wins over "มี.ค."
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	return strings.Join(names, "|")
+}
+
+// normalizeYear converts Buddhist-era and 2-digit years to Gregorian (0 = not given)
+func normalizeYear(year int) int {
+	switch {
+	case year == 0:
+		return 0
+	case year < 100:
+		return 2500 + year - 543 // 2-digit years in Thai text are Buddhist era (67 = 2567)
+	case year > 2400:
+		return year - 543
+	}
+	return year
+}
+
+// makeDate builds a valid date; without year uses current year (or last year if in the future)
+func makeDate(year, month, day int, today time.Time) (time.Time, bool) {
+	givenYear := normalizeYear(year)
+	y := givenYear
+	if y == 0 {
+		y = today.Year()
+	}
+	d := time.Date(y, time.Month(month), day, 0, 0, 0, 0, today.Location())
+	if d.Month() != time.Month(month) || d.Day() != day {
+		return time.Time{}, false // e.g. 31/02
+	}
+	if givenYear == 0 && d.After(today) {
+		d = d.AddDate(-1, 0, 0)
+	}
+	return d, true
+}
+
+// singleDay returns DateRange for one date
+func singleDay(d time.Time, expr string) DateRange {
+	return DateRange{From: d, To: d, Anchor: d, Expression: expr}
+}
+
+// ParseThaiDate resolves Thai date expressions in text such as "เมื่อวาน", "จันทร์ที่แล้ว",
+// "ต้นเดือน", "เดือนที่แล้ว", "15 ต.ค. 67" or "15/10/2567"
+func ParseThaiDate(text string, now time.Time) (DateRange, bool) {
+	text = thaiDigitReplacer.Replace(text)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	// Explicit dates first (most specific)
+	if m := isoDatePattern.FindStringSubmatch(text); m != nil {
+		y, _ := strconv.Atoi(m[1])
+		mo, _ := strconv.Atoi(m[2])
+		d, _ := strconv.Atoi(m[3])
+		if date, ok := makeDate(y, mo, d, today); ok {
+			return singleDay(date, m[0]), true
+		}
+	}
+	if m := thaiDatePattern.FindStringSubmatch(text); m != nil {
+		d, _ := strconv.Atoi(m[1])
+		y, _ := strconv.Atoi(m[3])
+		if date, ok := makeDate(y, int(thaiMonths[m[2]]), d, today); ok {
+			return singleDay(date, m[0]), true
+		}
+	}
+	if m := slashDatePattern.FindStringSubmatch(text); m != nil {
+		d, _ := strconv.Atoi(m[1])
+		mo, _ := strconv.Atoi(m[2])
+		y, _ := strconv.Atoi(m[3])
+		if date, ok := makeDate(y, mo, d, today); ok {
+			return singleDay(date, m[0]), true
+		}
+	}
+
+	// Relative days
+	switch {
+	case strings.Contains(text, "เมื่อวานซืน"):
+		return singleDay(today.AddDate(0, 0, -2), "เมื่อวานซืน"), true
+	case strings.Contains(text, "เมื่อวาน"):
+		return singleDay(today.AddDate(0, 0, -1), "เมื่อวาน"), true
+	}
+	if m := daysAgoPattern.FindStringSubmatch(text); m != nil {
+		if n, err := strconv.Atoi(m[1]); err == nil && n < 3650 {
+			return singleDay(today.AddDate(0, 0, -n), m[0]), true
+		}
+	}
+
+	// Weeks (check before weekdays: "อาทิตย์ที่แล้ว" means last week, "วันอาทิตย์ที่แล้ว" means last Sunday)
+	weekStart := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	if !strings.Contains(text, "วันอาทิตย์") {
+		for _, expr := range []string{"สัปดาห์ที่แล้ว", "อาทิตย์ที่แล้ว", "สัปดาห์ก่อน", "อาทิตย์ก่อน"} {
+			if strings.Contains(text, expr) {
+				return DateRange{From: weekStart.AddDate(0, 0, -7), To: weekStart.AddDate(0, 0, -1), Expression: expr}, true
+			}
+		}
+		for _, expr := range []string{"สัปดาห์นี้", "อาทิตย์นี้"} {
+			if strings.Contains(text, expr) {
+				return DateRange{From: weekStart, To: today, Expression: expr}, true
+			}
+		}
+	}
+
+	// Weekdays: "จันทร์" = latest Monday, "จันทร์ที่แล้ว" = Monday of last week
+	for _, wd := range thaiWeekdays {
+		idx := strings.Index(text, wd.name)
+		if idx < 0 || (wd.weekday == time.Sunday && !strings.Contains(text, "วันอาทิตย์")) {
+			continue
+		}
+		rest := text[idx+len(wd.name):]
+		offset := (int(wd.weekday) + 6) % 7 // days from Monday
+		if strings.HasPrefix(rest, "ที่แล้ว") || strings.HasPrefix(rest, "ก่อน") {
+			return singleDay(weekStart.AddDate(0, 0, offset-7), wd.name+"ที่แล้ว"), true
+		}
+		diff := (int(today.Weekday()) - int(wd.weekday) + 7) % 7
+		return singleDay(today.AddDate(0, 0, -diff), wd.name), true
+	}
+
+	// Parts of month: ต้นเดือน (1-10), กลางเดือน (11-20), ปลายเดือน/สิ้นเดือน (21-end)
+	monthStart := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+	for _, part := range []string{"ต้นเดือน", "กลางเดือน", "ปลายเดือน", "สิ้นเดือน"} {
+		idx := strings.Index(text, part)
+		if idx < 0 {
+			continue
+		}
+		start := monthStart
+		rest := text[idx+len(part):]
+		if strings.HasPrefix(rest, "ที่แล้ว") || strings.HasPrefix(rest, "ก่อน") {
+			start = monthStart.AddDate(0, -1, 0)
+		}
+		monthEnd := start.AddDate(0, 1, -1)
+		var r DateRange
+		switch part {
+		case "ต้นเดือน":
+			r = DateRange{From: start, To: start.AddDate(0, 0, 9), Anchor: start}
+		case "กลางเดือน":
+			r = DateRange{From: start.AddDate(0, 0, 10), To: start.AddDate(0, 0, 19), Anchor: start.AddDate(0, 0, 14)}
+		case "ปลายเดือน":
+			r = DateRange{From: start.AddDate(0, 0, 20), To: monthEnd}
+		default:
+			r = DateRange{From: monthEnd, To: monthEnd, Anchor: monthEnd}
+		}
+		r.Expression = part
+		return r, true
+	}
+
+	// Months and years
+	switch {
+	case strings.Contains(text, "เดือนที่แล้ว") || strings.Contains(text, "เดือนก่อน"):
+		return DateRange{From: monthStart.AddDate(0, -1, 0), To: monthStart.AddDate(0, 0, -1), Expression: "เดือนที่แล้ว"}, true
+	case strings.Contains(text, "เดือนนี้"):
+		return DateRange{From: monthStart, To: today, Expression: "เดือนนี้"}, true
+	case strings.Contains(text, "ปีที่แล้ว") || strings.Contains(text, "ปีก่อน"):
+		yearStart := time.Date(today.Year()-1, 1, 1, 0, 0, 0, 0, today.Location())
+		return DateRange{From: yearStart, To: yearStart.AddDate(1, 0, -1), Expression: "ปีที่แล้ว"}, true
+	case strings.Contains(text, "ปีนี้"):
+		return DateRange{From: time.Date(today.Year(), 1, 1, 0, 0, 0, 0, today.Location()), To: today, Expression: "ปีนี้"}, true
+	}
+
+	// "วันที่ 5" = 5th of this month (or last month if in the future)
+	if m := dayOfMonthPattern.FindStringSubmatch(text); m != nil {
+		d, _ := strconv.Atoi(m[1])
+		date := time.Date(today.Year(), today.Month(), d, 0, 0, 0, 0, today.Location())
+		if date.After(today) {
+			date = date.AddDate(0, -1, 0)
+		}
+		if date.Day() == d {
+			return singleDay(date, m[0]), true
+		}
+	}
+
+	if strings.Contains(text, "วันนี้") {
+		return singleDay(today, "วันนี้"), true
+	}
+	return DateRange{}, false
+}
+
+// ResolveTransactionDate returns the date (YYYY-MM-DD) for a new entry from the message,
+// cross-checked against AI's date. Go's parse wins; future dates fall back to today.
+// mismatch is true when AI gave a different date.
+func ResolveTransactionDate(message, aiDate string, now time.Time) (date string, mismatch bool) {
+	date = now.Format("2006-01-02")
+	if r, ok := ParseThaiDate(message, now); ok && !r.Anchor.IsZero() && !r.Anchor.After(now) {
+		date = r.Anchor.Format("2006-01-02")
+	}
+	return date, aiDate != "" && aiDate != date
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DeleteCandidateLimit is the most matches shown in the "ลบอันไหน" carousel (LINE max 12)
+const DeleteCandidateLimit = 10
+
+// deleteAmountPattern matches an amount naming which entry to delete ("ลบกาแฟ 65")
+var deleteAmountPattern = regexp.MustCompile(`(\d[\d,]*(?:\.\d+)?)\s*(?:บาท)?`)
+
+// deleteSkipPrefixes are "ลบ..." messages that are not one entry by description
+var deleteSkipPrefixes = []string{"งบ", "ทั้งหมด", "ทุกรายการ", "รายการล่าสุด", "ล่าสุด", "บัญชี", "บัตร", "หมวด"}
+
+// DeleteRequest is "ลบค่ากาแฟเมื่อวาน": which entries to look for
+type DeleteRequest struct {
+	Keyword string  // description/category/merchant text
+	Amount  float64 // 0 = any amount
+	From    string  // YYYY-MM-DD, "" = any date (newest first)
+	To      string
+	DateExp string // matched date text ("เมื่อวาน"), for replies
+}
+
+// ParseDeleteRequest parses "ลบค่ากาแฟเมื่อวาน", "ลบ กาแฟ 65 วันนี้", "ลบรายการแท็กซี่ 15/10" (no AI)
+func ParseDeleteRequest(text string, now time.Time) (*DeleteRequest, bool) {
+	text = strings.TrimSpace(thaiDigitReplacer.Replace(text))
+	if !strings.HasPrefix(text, "ลบ") {
+		return nil, false
+	}
+	rest := strings.TrimSpace(strings.TrimPrefix(text, "ลบ"))
+	for _, prefix := range deleteSkipPrefixes {
+		if strings.HasPrefix(rest, prefix) {
+			return nil, false
+		}
+	}
+	rest = strings.TrimSpace(strings.TrimPrefix(rest, "รายการ"))
+
+	req := &DeleteRequest{}
+	if r, ok := ParseThaiDate(rest, now); ok {
+		req.From, req.To, req.DateExp = r.FromString(), r.ToString(), r.Expression
+		rest = strings.Replace(rest, r.Expression, " ", 1)
+	}
+	if m := deleteAmountPattern.FindStringSubmatchIndex(rest); m != nil {
+		if amount, ok := ParseThaiAmount(rest[m[2]:m[3]]); ok && amount > 0 {
+			req.Amount = amount
+			rest = rest[:m[0]] + " " + rest[m[1]:]
+		}
+	}
+	for _, filler := range []string{"ของ", "อันที่", "ที่จ่าย", "เมื่อ"} {
+		rest = strings.TrimSuffix(strings.TrimSpace(rest), filler)
+	}
+	req.Keyword = strings.Join(strings.Fields(rest), " ")
+	if req.Keyword == "" {
+		return nil, false
+	}
+	return req, true
+}
+
+// Matches reports whether a transaction is one the request points at; "ค่ากาแฟ" also matches "กาแฟ".
+// Transfer legs are left out (a transfer is cancelled as a whole with its 🗑️ button)
+func (r *DeleteRequest) Matches(date string, tx Transaction) bool {
+	if tx.IsTransfer || r.From != "" && (date < r.From || date > r.To) {
+		return false
+	}
+	if r.Amount > 0 && roundBaht(tx.Amount) != roundBaht(r.Amount) {
+		return false
+	}
+	if matchesKeyword(tx, r.Keyword) {
+		return true
+	}
+	short := strings.TrimPrefix(r.Keyword, "ค่า")
+	return short != r.Keyword && short != "" && matchesKeyword(tx, short)
+}
+
+// SearchKeyword is the text to search with: "ค่ากาแฟ" searches "กาแฟ" so both spellings are found
+func (r *DeleteRequest) SearchKeyword() string {
+	if short := strings.TrimPrefix(r.Keyword, "ค่า"); short != "" {
+		return short
+	}
+	return r.Keyword
+}
+
+// FilterDeleteCandidates keeps the results the request points at, skipping archived days (read-only),
+// at most DeleteCandidateLimit
+func FilterDeleteCandidates(req *DeleteRequest, results []SearchResult) []SearchResult {
+	var out []SearchResult
+	for _, r := range results {
+		if r.Archived || !req.Matches(r.Date, r.Transaction) {
+			continue
+		}
+		out = append(out, r)
+		if len(out) >= DeleteCandidateLimit {
+			break
+		}
+	}
+	return out
+}
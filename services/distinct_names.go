@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// distinctCacheTTL bounds staleness after deletes (names removed from history)
+const distinctCacheTTL = 10 * time.Minute
+
+// distinctNames is a user's known banks, cards and categories (cached per user)
+type distinctNames struct {
+	Banks             []string
+	CreditCards       []string
+	IncomeCategories  []string
+	ExpenseCategories []string
+	loadedAt          time.Time
+}
+
+// distinctFacet is one $facet output: names collected with $addToSet
+type distinctFacet struct {
+	Banks []string `bson:"banks"`
+	Cards []string `bson:"cards"`
+	Cats  []string `bson:"cats"`
+}
+
+// GetDistinctPaymentMethods returns unique banks and credit cards for a user
+func (s *MongoDBService) GetDistinctPaymentMethods(ctx context.Context, lineID string) ([]string, []string, error) {
+	names, err := s.getDistinctNames(ctx, lineID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return names.Banks, names.CreditCards, nil
+}
+
+// GetDistinctCategories returns unique income and expense categories for a user (transfers excluded)
+func (s *MongoDBService) GetDistinctCategories(ctx context.Context, lineID string) ([]string, []string, error) {
+	names, err := s.getDistinctNames(ctx, lineID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return names.IncomeCategories, names.ExpenseCategories, nil
+}
+
+// getDistinctNames returns cached names or loads them with one aggregation
+func (s *MongoDBService) getDistinctNames(ctx context.Context, lineID string) (*distinctNames, error) {
+	if cached, ok := s.distinctCache.Load(lineID); ok {
+		names := cached.(*distinctNames)
+		if time.Since(names.loadedAt) < distinctCacheTTL {
+			return names, nil
+		}
+	}
+
+	names, err := s.loadDistinctNames(ctx, lineID)
+	if err != nil {
+		return nil, err
+	}
+	s.distinctCache.Store(lineID, names)
+	return names, nil
+}
+
+// loadDistinctNames groups names in Mongo ($unwind/$group) instead of decoding every record
+func (s *MongoDBService) loadDistinctNames(ctx context.Context, lineID string) (*distinctNames, error) {
+	txFacet := func(field string) []bson.M {
+		return []bson.M{
+			{"$unwind": "$" + field},
+			{"$group": bson.M{
+				"_id":   nil,
+				"banks": bson.M{"$addToSet": "$" + field + ".bankname"},
+				"cards": bson.M{"$addToSet": "$" + field + ".creditcardname"},
+				"cats":  bson.M{"$addToSet": "$" + field + ".category"},
+			}},
+		}
+	}
+	pipeline := []bson.M{
+		{"$match": bson.M{"lineid": lineID}},
+		{"$project": bson.M{
+			"bankname": 1, "creditcardname": 1,
+			"incomes.bankname": 1, "incomes.creditcardname": 1, "incomes.category": 1,
+			"expenses.bankname": 1, "expenses.creditcardname": 1, "expenses.category": 1,
+		}},
+		{"$facet": bson.M{
+			"records": []bson.M{{"$group": bson.M{
+				"_id":   nil,
+				"banks": bson.M{"$addToSet": "$bankname"},
+				"cards": bson.M{"$addToSet": "$creditcardname"},
+			}}},
+			"incomes":  txFacet("incomes"),
+			"expenses": txFacet("expenses"),
+		}},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var result struct {
+		Records  []distinctFacet `bson:"records"`
+		Incomes  []distinctFacet `bson:"incomes"`
+		Expenses []distinctFacet `bson:"expenses"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
+			return nil, err
+		}
+	}
+
+	var banks, cards, incomeCats, expenseCats []string
+	for _, facets := range [][]distinctFacet{result.Records, result.Incomes, result.Expenses} {
+		for _, f := range facets {
+			banks = append(banks, f.Banks...)
+			cards = append(cards, f.Cards...)
+		}
+	}
+	for _, f := range result.Incomes {
+		incomeCats = append(incomeCats, f.Cats...)
+	}
+	for _, f := range result.Expenses {
+		expenseCats = append(expenseCats, f.Cats...)
+	}
+
+	return &distinctNames{
+		Banks:             uniqueNames(banks),
+		CreditCards:       uniqueNames(cards),
+		IncomeCategories:  uniqueNames(incomeCats, "โอนเงิน"),
+		ExpenseCategories: uniqueNames(expenseCats, "โอนเงิน"),
+		loadedAt:          time.Now(),
+	}, nil
+}
+
+// uniqueNames removes empty, duplicate and excluded names and sorts the rest
+func uniqueNames(names []string, exclude ...string) []string {
+	seen := make(map[string]bool)
+	for _, ex := range exclude {
+		seen[ex] = true
+	}
+	list := make([]string, 0, len(names))
+	for _, n := range names {
+		if n == "" || seen[n] {
+			continue
+		}
+		seen[n] = true
+		list = append(list, n)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// invalidateDistinctNames drops cached names when a write introduces a name not cached yet
+func (s *MongoDBService) invalidateDistinctNames(event, lineID, date string, tx Transaction) {
+	cached, ok := s.distinctCache.Load(lineID)
+	if !ok || event == TransactionDeleted {
+		return // deletes only make the cache a superset; TTL refreshes it
+	}
+	names := cached.(*distinctNames)
+	categories := names.ExpenseCategories
+	if tx.Type == 1 {
+		categories = names.IncomeCategories
+	}
+	if !containsName(names.Banks, tx.BankName) ||
+		!containsName(names.CreditCards, tx.CreditCardName) ||
+		(tx.Category != "โอนเงิน" && !containsName(categories, tx.Category)) {
+		s.distinctCache.Delete(lineID)
+	}
+}
+
+// containsName reports whether name is empty or already in list
+func containsName(list []string, name string) bool {
+	if name == "" {
+		return true
+	}
+	for _, n := range list {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DownloadLinkTTL is how long a short download link stays valid
+const DownloadLinkTTL = 14 * 24 * time.Hour
+
+// Download link errors
+var (
+	ErrDownloadNotFound = errors.New("download link not found")
+	ErrDownloadExpired  = errors.New("download link expired")
+	ErrDownloadUsed     = errors.New("download link already used")
+)
+
+// DownloadLink maps a short token to a private storage object
+type DownloadLink struct {
+	Token        string     `bson:"token" json:"token"`
+	LineID       string     `bson:"lineid" json:"lineid"`
+	ObjectPath   string     `bson:"object_path" json:"object_path"`
+	Filename     string     `bson:"filename" json:"filename"`
+	ContentType  string     `bson:"content_type" json:"content_type"`
+	Downloaded   bool       `bson:"downloaded" json:"downloaded"`
+	DownloadedAt *time.Time `bson:"downloaded_at,omitempty" json:"downloaded_at,omitempty"`
+	ExpiresAt    time.Time  `bson:"expires_at" json:"expires_at"`
+	CreatedAt    time.Time  `bson:"created_at" json:"created_at"`
+}
+
+// DownloadEvent records each download attempt
+type DownloadEvent struct {
+	Token     string    `bson:"token" json:"token"`
+	LineID    string    `bson:"lineid,omitempty" json:"lineid,omitempty"`
+	Status    string    `bson:"status" json:"status"` // "ok", "not_found", "expired", "used", "error"
+	IP        string    `bson:"ip" json:"ip"`
+	UserAgent string    `bson:"user_agent" json:"user_agent"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+const downloadTokenChars = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// generateDownloadToken returns random 10-char token (no look-alike characters)
+func generateDownloadToken() (string, error) {
+	token := make([]byte, 10)
+	for i := range token {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(downloadTokenChars))))
+		if err != nil {
+			return "", err
+		}
+		token[i] = downloadTokenChars[n.Int64()]
+	}
+	return string(token), nil
+}
+
+// CreateDownloadLink stores a new short link for an uploaded object and returns it
+func (s *MongoDBService) CreateDownloadLink(ctx context.Context, lineID, objectPath, filename, contentType string) (*DownloadLink, error) {
+	token, err := generateDownloadToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	link := &DownloadLink{
+		Token:       token,
+		LineID:      lineID,
+		ObjectPath:  objectPath,
+		Filename:    filename,
+		ContentType: contentType,
+		ExpiresAt:   now.Add(DownloadLinkTTL),
+		CreatedAt:   now,
+	}
+	if _, err := s.downloadCollection.InsertOne(ctx, link); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// ClaimDownloadLink atomically marks link as downloaded (one-time) and returns it
+func (s *MongoDBService) ClaimDownloadLink(ctx context.Context, token string) (*DownloadLink, error) {
+	now := time.Now()
+	var link DownloadLink
+	err := s.downloadCollection.FindOneAndUpdate(ctx,
+		bson.M{"token": token, "downloaded": false, "expires_at": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"downloaded": true, "downloaded_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&link)
+	if err == nil {
+		return &link, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	// Find out why the claim failed
+	if err := s.downloadCollection.FindOne(ctx, bson.M{"token": token}).Decode(&link); err != nil {
+		return nil, ErrDownloadNotFound
+	}
+	if link.Downloaded {
+		return &link, ErrDownloadUsed
+	}
+	return &link, ErrDownloadExpired
+}
+
+// ReleaseDownloadLink allows the link to be used again (when streaming failed)
+func (s *MongoDBService) ReleaseDownloadLink(ctx context.Context, token string) error {
+	_, err := s.downloadCollection.UpdateOne(ctx,
+		bson.M{"token": token},
+		bson.M{"$set": bson.M{"downloaded": false}, "$unset": bson.M{"downloaded_at": ""}},
+	)
+	return err
+}
+
+// LogDownloadEvent saves a download attempt
+func (s *MongoDBService) LogDownloadEvent(ctx context.Context, event DownloadEvent) error {
+	event.CreatedAt = time.Now()
+	_, err := s.downloadEventCollection.InsertOne(ctx, event)
+	return err
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrorReport is one captured error or panic with user-safe context
+type ErrorReport struct {
+	Err    error
+	Action string // e.g. "ai.chat", "webhook.message", "GET /d/:token"
+	LineID string // anonymized before sending
+	Stack  string // set for panics
+	Panic  bool
+}
+
+// ErrorReporter sends errors to an error tracking backend
+// Implementations must not block (send in background)
+type ErrorReporter interface {
+	Report(report ErrorReport)
+}
+
+// LogReporter only writes errors to the log (default when no DSN is configured)
+type LogReporter struct{}
+
+// Report logs the error
+func (LogReporter) Report(report ErrorReport) {
+	kind := "ERROR"
+	if report.Panic {
+		kind = "PANIC"
+	}
+	log.Printf("%s [%s]: %v\n%s", kind, report.Action, report.Err, report.Stack)
+}
+
+var (
+	reporterMu      sync.RWMutex
+	defaultReporter ErrorReporter = LogReporter{}
+)
+
+// SetErrorReporter replaces the process-wide reporter (call once at startup)
+func SetErrorReporter(r ErrorReporter) {
+	reporterMu.Lock()
+	defer reporterMu.Unlock()
+	defaultReporter = r
+}
+
+// ReportError sends an error to the configured reporter
+func ReportError(action, lineID string, err error) {
+	if err == nil {
+		return
+	}
+	reporterMu.RLock()
+	r := defaultReporter
+	reporterMu.RUnlock()
+	r.Report(ErrorReport{Err: err, Action: action, LineID: lineID})
+}
+
+// ReportPanic sends a recovered panic value with stack trace
+func ReportPanic(action, lineID string, recovered interface{}) {
+	reporterMu.RLock()
+	r := defaultReporter
+	reporterMu.RUnlock()
+	r.Report(ErrorReport{
+		Err:    fmt.Errorf("panic: %v", recovered),
+		Action: action,
+		LineID: lineID,
+		Stack:  string(debug.Stack()),
+		Panic:  true,
+	})
+}
+
+// GoSafe runs fn in a goroutine and reports a panic instead of crashing the process
+func GoSafe(action string, fn func()) {
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				ReportPanic(action, "", rec)
+			}
+		}()
+		fn()
+	}()
+}
+
+// SentryReporter posts events to Sentry's store API (no SDK dependency)
+type SentryReporter struct {
+	endpoint    string
+	publicKey   string
+	environment string
+	httpClient  *http.Client
+}
+
+// ParseSentryDSN converts "https://KEY@HOST/PROJECT" into store endpoint and public key
+func ParseSentryDSN(dsn string) (endpoint, publicKey string, err error) {
+	u, err := url.Parse(strings.TrimSpace(dsn))
+	if err != nil {
+		return "", "", fmt.Errorf("invalid sentry dsn: %w", err)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return "", "", fmt.Errorf("invalid sentry dsn: missing public key")
+	}
+	project := strings.Trim(u.Path, "/")
+	if project == "" || u.Host == "" {
+		return "", "", fmt.Errorf("invalid sentry dsn: missing host or project")
+	}
+	// Self-hosted Sentry may live under a path prefix: /prefix/PROJECT
+	prefix := ""
+	if i := strings.LastIndex(project, "/"); i >= 0 {
+		prefix, project = "/"+project[:i], project[i+1:]
+	}
+	endpoint = fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, prefix, project)
+	return endpoint, u.User.Username(), nil
+}
+
+// NewSentryReporter creates a reporter from DSN
+func NewSentryReporter(dsn, environment string) (*SentryReporter, error) {
+	endpoint, key, err := ParseSentryDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &SentryReporter{
+		endpoint:    endpoint,
+		publicKey:   key,
+		environment: environment,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Report logs locally and sends the event to Sentry in background
+func (s *SentryReporter) Report(report ErrorReport) {
+	LogReporter{}.Report(report)
+
+	event := s.buildEvent(report)
+	go func() {
+		body, err := json.Marshal(event)
+		if err != nil {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+		if err != nil {
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=satisatang/1.0, sentry_key=%s", s.publicKey))
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			log.Printf("Failed to send error to Sentry: %v", err)
+			return
+		}
+		resp.Body.Close()
+	}()
+}
+
+// buildEvent converts report to Sentry event JSON (LINE user ID is hashed)
+func (s *SentryReporter) buildEvent(report ErrorReport) map[string]interface{} {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	level := "error"
+	if report.Panic {
+		level = "fatal"
+	}
+	event := map[string]interface{}{
+		"event_id":    hex.EncodeToString(id),
+		"timestamp":   time.Now().UTC().Format(time.RFC3339),
+		"level":       level,
+		"platform":    "go",
+		"environment": s.environment,
+		"transaction": report.Action,
+		"tags":        map[string]string{"action": report.Action},
+		"exception": map[string]interface{}{
+			"values": []map[string]interface{}{{
+				"type":  fmt.Sprintf("%T", report.Err),
+				"value": report.Err.Error(),
+			}},
+		},
+	}
+	if report.LineID != "" {
+		event["user"] = map[string]string{"id": AnonymizeUserID(report.LineID)}
+	}
+	if report.Stack != "" {
+		event["extra"] = map[string]string{"stack": report.Stack}
+	}
+	return event
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"time"
@@ -46,8 +47,15 @@ var (
 	}
 )
 
+// MaxExcelExportRows caps transaction rows per Excel file (rows are streamed, so this guards file size, not memory)
+const MaxExcelExportRows = 100000
+
+// errExportRowLimit stops streaming once MaxExcelExportRows rows are written
+var errExportRowLimit = errors.New("export row limit reached")
+
 // ExportToExcel generates Excel file for user's transactions - สไตล์วัยรุ่น
-func (s *ExportService) ExportToExcel(ctx context.Context, lineID string, days int) ([]byte, string, error) {
+// password encrypts the workbook (empty = no password); lang translates headers and categories (stored data stays Thai)
+func (s *ExportService) ExportToExcel(ctx context.Context, lineID string, days int, password, lang string) ([]byte, string, error) {
 	if days <= 0 {
 		days = 30
 	}
@@ -56,18 +64,12 @@ func (s *ExportService) ExportToExcel(ctx context.Context, lineID string, days i
 	endDate := time.Now()
 	startDate := endDate.AddDate(0, 0, -days)
 
-	// Get transactions
-	results, err := s.mongo.SearchByDateRange(ctx, lineID, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"), 1000)
-	if err != nil {
-		return nil, "", fmt.Errorf("ไม่สามารถดึงข้อมูลได้: %w", err)
-	}
-
 	// Create Excel file
 	f := excelize.NewFile()
 	defer f.Close()
 
-	// ===== Sheet 1: รายการทั้งหมด =====
-	sheetName := "รายการทั้งหมด"
+	// ===== Sheet 1: รายการทั้งหมด (streamed, rows are not kept in memory) =====
+	sheetName := SheetName(lang, "รายการทั้งหมด")
 	f.SetSheetName("Sheet1", sheetName)
 
 	// Title row with gradient effect
@@ -88,10 +90,7 @@ func (s *ExportService) ExportToExcel(ctx context.Context, lineID string, days i
 			Vertical:   "center",
 		},
 	})
-	f.MergeCell(sheetName, "A1", "F1")
-	f.SetCellValue(sheetName, "A1", fmt.Sprintf("📊 สติสตางค์ - รายงาน %d วัน", days))
-	f.SetCellStyle(sheetName, "A1", "F1", titleStyle)
-	f.SetRowHeight(sheetName, 1, 35)
+	title := "📊 " + exportTitle(lang, s.mongo.GetDisplayName(ctx, lineID), days)
 
 	// Subtitle with date range
 	subtitleStyle, _ := f.NewStyle(&excelize.Style{
@@ -104,13 +103,10 @@ func (s *ExportService) ExportToExcel(ctx context.Context, lineID string, days i
 			Horizontal: "center",
 		},
 	})
-	f.MergeCell(sheetName, "A2", "F2")
-	f.SetCellValue(sheetName, "A2", fmt.Sprintf("วันที่ %s ถึง %s", startDate.Format("02/01/2006"), endDate.Format("02/01/2006")))
-	f.SetCellStyle(sheetName, "A2", "F2", subtitleStyle)
-	f.SetRowHeight(sheetName, 2, 20)
 
 	// Headers - Row 3
-	headers := []string{"📅 วันที่", "💰 ประเภท", "🏷️ หมวดหมู่", "📝 รายละเอียด", "💵 จำนวน (บาท)", "🏦 ช่องทาง"}
+	headers := []string{"📅 " + Translate(lang, "วันที่"), "💰 " + Translate(lang, "ประเภท"), "🏷️ " + Translate(lang, "หมวดหมู่"),
+		"📝 " + Translate(lang, "รายละเอียด"), "💵 " + Translate(lang, "จำนวน (บาท)"), "🏦 " + Translate(lang, "ช่องทาง")}
 	headerStyle, _ := f.NewStyle(&excelize.Style{
 		Font: &excelize.Font{
 			Bold:  true,
@@ -130,12 +126,6 @@ func (s *ExportService) ExportToExcel(ctx context.Context, lineID string, days i
 			{Type: "bottom", Color: colorSecondary, Style: 2},
 		},
 	})
-	for i, header := range headers {
-		cell := fmt.Sprintf("%c3", 'A'+i)
-		f.SetCellValue(sheetName, cell, header)
-	}
-	f.SetCellStyle(sheetName, "A3", "F3", headerStyle)
-	f.SetRowHeight(sheetName, 3, 25)
 
 	// Data styles
 	incomeStyle, _ := f.NewStyle(&excelize.Style{
@@ -154,47 +144,101 @@ func (s *ExportService) ExportToExcel(ctx context.Context, lineID string, days i
 		NumFmt:    4, // #,##0.00
 	})
 
-	// Add data (excluding transfers)
+	sw, err := f.NewStreamWriter(sheetName)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot create Excel: %w", err)
+	}
+	// Column widths must be set before the first row
+	for i, width := range []float64{14, 14, 16, 28, 16, 18} {
+		sw.SetColWidth(i+1, i+1, width)
+	}
+
+	// styledRow fills cells A.. with values, all in one style (merged cells need the style on every cell)
+	styledRow := func(style int, values ...interface{}) []interface{} {
+		cells := make([]interface{}, len(values))
+		for i, v := range values {
+			cells[i] = excelize.Cell{StyleID: style, Value: v}
+		}
+		return cells
+	}
+	sw.MergeCell("A1", "F1")
+	sw.SetRow("A1", styledRow(titleStyle, title, "", "", "", "", ""), excelize.RowOpts{Height: 35})
+	sw.MergeCell("A2", "F2")
+	subtitle := fmt.Sprintf("วันที่ %s ถึง %s", startDate.Format("02/01/2006"), endDate.Format("02/01/2006"))
+	if lang == LangEnglish {
+		subtitle = fmt.Sprintf("%s to %s", startDate.Format("02/01/2006"), endDate.Format("02/01/2006"))
+	}
+	sw.SetRow("A2", styledRow(subtitleStyle, subtitle, "", "", "", "", ""), excelize.RowOpts{Height: 20})
+	headerCells := make([]interface{}, len(headers))
+	for i, header := range headers {
+		headerCells[i] = header
+	}
+	sw.SetRow("A3", styledRow(headerStyle, headerCells...), excelize.RowOpts{Height: 25})
+
+	// Add data (excluding transfers), streamed from Mongo row by row
 	var totalIncome, totalExpense float64
 	row := 4
-	for _, result := range results {
+	truncated := false
+	err = s.mongo.EachTransactionInRange(ctx, lineID, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"), func(result SearchResult) error {
 		tx := result.Transaction
 
 		// Skip transfer transactions
-		if tx.Category == "โอนเงิน" {
-			continue
+		if tx.IsTransfer {
+			return nil
+		}
+		if row-4 >= MaxExcelExportRows {
+			truncated = true
+			return errExportRowLimit
 		}
 
-		// Type
-		txType := "💸 รายจ่าย"
+		// Type (investments are listed but not totaled as income/expense)
+		txType := "💸 " + Translate(lang, "รายจ่าย")
 		rowStyle := expenseStyle
-		if tx.Type == 1 {
-			txType = "💚 รายรับ"
+		switch {
+		case tx.Category == InvestmentCategory:
+			txType = "📈 " + Translate(lang, "ลงทุน")
+			if tx.Type == 1 {
+				txType = "📈 " + Translate(lang, "ขายการลงทุน")
+			}
+		case tx.Type == 1:
+			txType = "💚 " + Translate(lang, "รายรับ")
 			rowStyle = incomeStyle
 			totalIncome += tx.Amount
-		} else {
+		default:
 			totalExpense += tx.Amount
 		}
 
 		// Payment method
-		payment := getPaymentInfo(tx.UseType, tx.BankName, tx.CreditCardName)
+		payment := PaymentLabel(lang, tx.UseType, tx.BankName, tx.CreditCardName)
 
 		// Description
 		desc := tx.Description
 		if desc == "" {
 			desc = tx.CustName
 		}
+		if result.Archived {
+			desc += " [" + Translate(lang, "เก็บถาวร") + "]"
+		}
 
-		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), result.Date)
-		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), txType)
-		f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), tx.Category)
-		f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), desc)
-		f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), tx.Amount)
-		f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), payment)
-
-		f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("D%d", row), rowStyle)
-		f.SetCellStyle(sheetName, fmt.Sprintf("E%d", row), fmt.Sprintf("E%d", row), numberStyle)
-		f.SetCellStyle(sheetName, fmt.Sprintf("F%d", row), fmt.Sprintf("F%d", row), rowStyle)
+		cells := []interface{}{
+			excelize.Cell{StyleID: rowStyle, Value: result.Date},
+			excelize.Cell{StyleID: rowStyle, Value: txType},
+			excelize.Cell{StyleID: rowStyle, Value: Translate(lang, tx.Category)},
+			excelize.Cell{StyleID: rowStyle, Value: desc},
+			excelize.Cell{StyleID: numberStyle, Value: tx.Amount},
+			excelize.Cell{StyleID: rowStyle, Value: payment},
+		}
+		if err := sw.SetRow(fmt.Sprintf("A%d", row), cells); err != nil {
+			return err
+		}
+		row++
+		return nil
+	})
+	if err != nil && err != errExportRowLimit {
+		return nil, "", fmt.Errorf("ไม่สามารถดึงข้อมูลได้: %w", err)
+	}
+	if truncated {
+		sw.SetRow(fmt.Sprintf("A%d", row), styledRow(subtitleStyle, fmt.Sprintf("แสดง %d รายการล่าสุด (ครบจำนวนสูงสุดต่อไฟล์) เลือกช่วงวันที่สั้นลงเพื่อดูรายการที่เหลือ", MaxExcelExportRows)))
 		row++
 	}
 
@@ -207,9 +251,6 @@ func (s *ExportService) ExportToExcel(ctx context.Context, lineID string, days i
 		Fill:      excelize.Fill{Type: "pattern", Color: []string{colorSecondary}, Pattern: 1},
 		Alignment: &excelize.Alignment{Horizontal: "center"},
 	})
-	f.MergeCell(sheetName, fmt.Sprintf("D%d", summaryStartRow), fmt.Sprintf("E%d", summaryStartRow))
-	f.SetCellValue(sheetName, fmt.Sprintf("D%d", summaryStartRow), "📊 สรุปยอด")
-	f.SetCellStyle(sheetName, fmt.Sprintf("D%d", summaryStartRow), fmt.Sprintf("E%d", summaryStartRow), summaryTitleStyle)
 
 	// Summary values
 	summaryLabelStyle, _ := f.NewStyle(&excelize.Style{
@@ -233,41 +274,40 @@ func (s *ExportService) ExportToExcel(ctx context.Context, lineID string, days i
 		Alignment: &excelize.Alignment{Horizontal: "right"},
 	})
 
-	f.SetCellValue(sheetName, fmt.Sprintf("D%d", summaryStartRow+1), "💚 รวมรายรับ:")
-	f.SetCellValue(sheetName, fmt.Sprintf("E%d", summaryStartRow+1), totalIncome)
-	f.SetCellStyle(sheetName, fmt.Sprintf("D%d", summaryStartRow+1), fmt.Sprintf("D%d", summaryStartRow+1), summaryLabelStyle)
-	f.SetCellStyle(sheetName, fmt.Sprintf("E%d", summaryStartRow+1), fmt.Sprintf("E%d", summaryStartRow+1), incomeValueStyle)
-
-	f.SetCellValue(sheetName, fmt.Sprintf("D%d", summaryStartRow+2), "💸 รวมรายจ่าย:")
-	f.SetCellValue(sheetName, fmt.Sprintf("E%d", summaryStartRow+2), totalExpense)
-	f.SetCellStyle(sheetName, fmt.Sprintf("D%d", summaryStartRow+2), fmt.Sprintf("D%d", summaryStartRow+2), summaryLabelStyle)
-	f.SetCellStyle(sheetName, fmt.Sprintf("E%d", summaryStartRow+2), fmt.Sprintf("E%d", summaryStartRow+2), expenseValueStyle)
-
-	f.SetCellValue(sheetName, fmt.Sprintf("D%d", summaryStartRow+3), "💰 คงเหลือ:")
-	f.SetCellValue(sheetName, fmt.Sprintf("E%d", summaryStartRow+3), totalIncome-totalExpense)
-	f.SetCellStyle(sheetName, fmt.Sprintf("D%d", summaryStartRow+3), fmt.Sprintf("D%d", summaryStartRow+3), summaryLabelStyle)
-	f.SetCellStyle(sheetName, fmt.Sprintf("E%d", summaryStartRow+3), fmt.Sprintf("E%d", summaryStartRow+3), balanceStyle)
-
-	// Set column widths
-	f.SetColWidth(sheetName, "A", "A", 14)
-	f.SetColWidth(sheetName, "B", "B", 14)
-	f.SetColWidth(sheetName, "C", "C", 16)
-	f.SetColWidth(sheetName, "D", "D", 28)
-	f.SetColWidth(sheetName, "E", "E", 16)
-	f.SetColWidth(sheetName, "F", "F", 18)
+	sw.MergeCell(fmt.Sprintf("D%d", summaryStartRow), fmt.Sprintf("E%d", summaryStartRow))
+	sw.SetRow(fmt.Sprintf("D%d", summaryStartRow), styledRow(summaryTitleStyle, "📊 "+Translate(lang, "สรุปยอด"), ""))
+	summaryRows := []struct {
+		label string
+		value float64
+		style int
+	}{
+		{"💚 " + Translate(lang, "รวมรายรับ:"), totalIncome, incomeValueStyle},
+		{"💸 " + Translate(lang, "รวมรายจ่าย:"), totalExpense, expenseValueStyle},
+		{"💰 " + Translate(lang, "คงเหลือ:"), totalIncome - totalExpense, balanceStyle},
+	}
+	for i, sr := range summaryRows {
+		sw.SetRow(fmt.Sprintf("D%d", summaryStartRow+1+i), []interface{}{
+			excelize.Cell{StyleID: summaryLabelStyle, Value: sr.label},
+			excelize.Cell{StyleID: sr.style, Value: sr.value},
+		})
+	}
+	if err := sw.Flush(); err != nil {
+		return nil, "", fmt.Errorf("cannot create Excel: %w", err)
+	}
 
 	// ===== Sheet 2: สรุปหมวดหมู่ =====
-	summarySheet := "สรุปหมวดหมู่"
+	summarySheet := SheetName(lang, "สรุปหมวดหมู่")
 	f.NewSheet(summarySheet)
 
 	// Title
 	f.MergeCell(summarySheet, "A1", "D1")
-	f.SetCellValue(summarySheet, "A1", "🏷️ สรุปรายจ่ายตามหมวดหมู่")
+	f.SetCellValue(summarySheet, "A1", "🏷️ "+Translate(lang, "สรุปรายจ่ายตามหมวดหมู่"))
 	f.SetCellStyle(summarySheet, "A1", "D1", titleStyle)
 	f.SetRowHeight(summarySheet, 1, 35)
 
 	// Get spending by category
 	spending, _ := s.mongo.GetMonthlySpendingByCategory(ctx, lineID)
+	styles := s.mongo.GetCategoryStyles(ctx, lineID)
 
 	// Sort by amount (highest first)
 	type catSpend struct {
@@ -283,7 +323,7 @@ func (s *ExportService) ExportToExcel(ctx context.Context, lineID string, days i
 	})
 
 	// Headers
-	catHeaders := []string{"🏆 อันดับ", "🏷️ หมวดหมู่", "💵 จำนวนเงิน", "📊 สัดส่วน"}
+	catHeaders := []string{"🏆 " + Translate(lang, "อันดับ"), "🏷️ " + Translate(lang, "หมวดหมู่"), "💵 " + Translate(lang, "จำนวนเงิน"), "📊 " + Translate(lang, "สัดส่วน")}
 	for i, header := range catHeaders {
 		cell := fmt.Sprintf("%c2", 'A'+i)
 		f.SetCellValue(summarySheet, cell, header)
@@ -308,15 +348,15 @@ func (s *ExportService) ExportToExcel(ctx context.Context, lineID string, days i
 			rankEmoji = "🥉"
 		}
 
-		// Color based on rank
+		// User's category color
 		catStyle, _ := f.NewStyle(&excelize.Style{
 			Font:      &excelize.Font{Size: 11},
-			Fill:      excelize.Fill{Type: "pattern", Color: []string{categoryColors[i%len(categoryColors)]}, Pattern: 1},
+			Fill:      excelize.Fill{Type: "pattern", Color: []string{styles.Color(cs.Category)}, Pattern: 1},
 			Alignment: &excelize.Alignment{Vertical: "center"},
 		})
 
 		f.SetCellValue(summarySheet, fmt.Sprintf("A%d", row), rankEmoji)
-		f.SetCellValue(summarySheet, fmt.Sprintf("B%d", row), cs.Category)
+		f.SetCellValue(summarySheet, fmt.Sprintf("B%d", row), styles.Emoji(cs.Category)+" "+Translate(lang, cs.Category))
 		f.SetCellValue(summarySheet, fmt.Sprintf("C%d", row), cs.Amount)
 		f.SetCellValue(summarySheet, fmt.Sprintf("D%d", row), fmt.Sprintf("%.1f%%", percentage))
 		f.SetCellStyle(summarySheet, fmt.Sprintf("A%d", row), fmt.Sprintf("D%d", row), catStyle)
@@ -330,7 +370,7 @@ func (s *ExportService) ExportToExcel(ctx context.Context, lineID string, days i
 		Alignment: &excelize.Alignment{Horizontal: "center"},
 	})
 	f.SetCellValue(summarySheet, fmt.Sprintf("A%d", row), "")
-	f.SetCellValue(summarySheet, fmt.Sprintf("B%d", row), "รวมทั้งหมด")
+	f.SetCellValue(summarySheet, fmt.Sprintf("B%d", row), Translate(lang, "รวมทั้งหมด"))
 	f.SetCellValue(summarySheet, fmt.Sprintf("C%d", row), totalExpense)
 	f.SetCellValue(summarySheet, fmt.Sprintf("D%d", row), "100%")
 	f.SetCellStyle(summarySheet, fmt.Sprintf("A%d", row), fmt.Sprintf("D%d", row), totalStyle)
@@ -340,12 +380,44 @@ func (s *ExportService) ExportToExcel(ctx context.Context, lineID string, days i
 	f.SetColWidth(summarySheet, "C", "C", 16)
 	f.SetColWidth(summarySheet, "D", "D", 12)
 
+	// ===== Sheet 3: ร้านค้า (top merchants of the same range) =====
+	merchants, _ := s.mongo.GetTopMerchants(ctx, lineID, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"), TopMerchantLimit)
+	if len(merchants) > 0 {
+		merchantSheet := SheetName(lang, "ร้านค้า")
+		f.NewSheet(merchantSheet)
+		f.MergeCell(merchantSheet, "A1", "D1")
+		f.SetCellValue(merchantSheet, "A1", "🏪 "+Translate(lang, "ร้านที่จ่ายมากสุด"))
+		f.SetCellStyle(merchantSheet, "A1", "D1", titleStyle)
+		f.SetRowHeight(merchantSheet, 1, 35)
+		for i, header := range []string{"🏆 " + Translate(lang, "อันดับ"), "🏪 " + Translate(lang, "ร้านค้า"), "💵 " + Translate(lang, "จำนวนเงิน"), "🧾 " + Translate(lang, "จำนวนครั้ง")} {
+			f.SetCellValue(merchantSheet, fmt.Sprintf("%c2", 'A'+i), header)
+		}
+		f.SetCellStyle(merchantSheet, "A2", "D2", headerStyle)
+		f.SetRowHeight(merchantSheet, 2, 25)
+		for i, m := range merchants {
+			row := i + 3
+			f.SetCellValue(merchantSheet, fmt.Sprintf("A%d", row), i+1)
+			f.SetCellValue(merchantSheet, fmt.Sprintf("B%d", row), m.Merchant)
+			f.SetCellValue(merchantSheet, fmt.Sprintf("C%d", row), m.Amount)
+			f.SetCellValue(merchantSheet, fmt.Sprintf("D%d", row), m.Visits)
+			f.SetCellStyle(merchantSheet, fmt.Sprintf("C%d", row), fmt.Sprintf("C%d", row), numberStyle)
+		}
+		f.SetColWidth(merchantSheet, "A", "A", 10)
+		f.SetColWidth(merchantSheet, "B", "B", 28)
+		f.SetColWidth(merchantSheet, "C", "C", 16)
+		f.SetColWidth(merchantSheet, "D", "D", 14)
+	}
+
 	// Set active sheet to first
 	f.SetActiveSheet(0)
 
-	// Write to buffer
+	// Write to buffer (encrypted when password is set)
+	var opts []excelize.Options
+	if password != "" {
+		opts = append(opts, excelize.Options{Password: password})
+	}
 	var buf bytes.Buffer
-	if err := f.Write(&buf); err != nil {
+	if err := f.Write(&buf, opts...); err != nil {
 		return nil, "", fmt.Errorf("cannot create Excel: %w", err)
 	}
 
@@ -356,7 +428,8 @@ func (s *ExportService) ExportToExcel(ctx context.Context, lineID string, days i
 }
 
 // ExportToPDF generates PDF report with Thai font support using gopdf
-func (s *ExportService) ExportToPDF(ctx context.Context, lineID string, days int) ([]byte, string, error) {
+// password is required to open the PDF (empty = no password); lang as in ExportToExcel
+func (s *ExportService) ExportToPDF(ctx context.Context, lineID string, days int, password, lang string) ([]byte, string, error) {
 	if days <= 0 {
 		days = 30
 	}
@@ -369,20 +442,27 @@ func (s *ExportService) ExportToPDF(ctx context.Context, lineID string, days int
 
 	// Get spending by category
 	spending, _ := s.mongo.GetMonthlySpendingByCategory(ctx, lineID)
+	styles := s.mongo.GetCategoryStyles(ctx, lineID)
 
 	// Get budget status
 	budgetStatus, _ := s.mongo.GetBudgetStatus(ctx, lineID)
 
 	// Create PDF with gopdf
 	pdf := gopdf.GoPdf{}
-	pdf.Start(gopdf.Config{PageSize: *gopdf.PageSizeA4})
-
-	// Add Thai font from embedded bytes
-	if err := pdf.AddTTFFontData("Sarabun", SarabunRegular); err != nil {
-		return nil, "", fmt.Errorf("ไม่สามารถโหลดฟอนต์: %w", err)
+	config := gopdf.Config{PageSize: *gopdf.PageSizeA4}
+	if password != "" {
+		config.Protection = gopdf.PDFProtectionConfig{
+			UseProtection: true,
+			Permissions:   gopdf.PermissionsPrint | gopdf.PermissionsCopy,
+			UserPass:      []byte(password),
+			OwnerPass:     []byte(password),
+		}
 	}
-	if err := pdf.AddTTFFontData("SarabunBold", SarabunBold); err != nil {
-		return nil, "", fmt.Errorf("ไม่สามารถโหลดฟอนต์ตัวหนา: %w", err)
+	pdf.Start(config)
+
+	// Add Thai font from embedded bytes (parsed once per process)
+	if err := addThaiFonts(&pdf); err != nil {
+		return nil, "", err
 	}
 
 	pdf.AddPage()
@@ -401,12 +481,22 @@ func (s *ExportService) ExportToPDF(ctx context.Context, lineID string, days int
 	pdf.SetFont("Sarabun", "", 16)
 	pdf.SetX(40)
 	pdf.SetY(70)
-	pdf.Cell(nil, "รายงานสรุปการเงินส่วนตัว")
+	owner := ReportOwnerTitle(s.mongo.GetDisplayName(ctx, lineID))
+	if lang == LangEnglish {
+		owner = exportTitle(lang, s.mongo.GetDisplayName(ctx, lineID), days)
+	}
+	pdf.Cell(nil, owner)
 
 	pdf.SetFont("Sarabun", "", 12)
 	pdf.SetX(40)
 	pdf.SetY(95)
-	pdf.Cell(nil, fmt.Sprintf("วันที่: %s", time.Now().Format("02/01/2006")))
+	pdf.Cell(nil, fmt.Sprintf("%s: %s", Translate(lang, "วันที่"), time.Now().Format("02/01/2006")))
+
+	// Amounts read "บาท", "THB" in English
+	currency := "บาท"
+	if lang == LangEnglish {
+		currency = "THB"
+	}
 
 	// Summary Box
 	pdf.SetFillColor(245, 247, 250)
@@ -416,37 +506,37 @@ func (s *ExportService) ExportToPDF(ctx context.Context, lineID string, days int
 	pdf.SetFont("SarabunBold", "", 18)
 	pdf.SetX(50)
 	pdf.SetY(150)
-	pdf.Cell(nil, "สรุปยอด")
+	pdf.Cell(nil, Translate(lang, "สรุปยอด"))
 
 	// Income
 	pdf.SetFont("Sarabun", "", 14)
 	pdf.SetX(50)
 	pdf.SetY(180)
 	pdf.SetTextColor(0, 184, 148)
-	pdf.Cell(nil, "รายรับทั้งหมด:")
+	pdf.Cell(nil, Translate(lang, "รายรับทั้งหมด:"))
 	pdf.SetFont("SarabunBold", "", 14)
 	pdf.SetX(180)
-	pdf.Cell(nil, fmt.Sprintf("%.2f บาท", balance.TotalIncome))
+	pdf.Cell(nil, fmt.Sprintf("%.2f %s", balance.TotalIncome, currency))
 
 	// Expense
 	pdf.SetFont("Sarabun", "", 14)
 	pdf.SetX(300)
 	pdf.SetY(180)
 	pdf.SetTextColor(214, 48, 49)
-	pdf.Cell(nil, "รายจ่ายทั้งหมด:")
+	pdf.Cell(nil, Translate(lang, "รายจ่ายทั้งหมด:"))
 	pdf.SetFont("SarabunBold", "", 14)
 	pdf.SetX(420)
-	pdf.Cell(nil, fmt.Sprintf("%.2f บาท", balance.TotalExpense))
+	pdf.Cell(nil, fmt.Sprintf("%.2f %s", balance.TotalExpense, currency))
 
 	// Balance
 	pdf.SetFont("Sarabun", "", 14)
 	pdf.SetX(50)
 	pdf.SetY(210)
 	pdf.SetTextColor(108, 92, 231)
-	pdf.Cell(nil, "ยอดคงเหลือ:")
+	pdf.Cell(nil, Translate(lang, "ยอดคงเหลือ:"))
 	pdf.SetFont("SarabunBold", "", 16)
 	pdf.SetX(180)
-	pdf.Cell(nil, fmt.Sprintf("%.2f บาท", balance.Balance))
+	pdf.Cell(nil, fmt.Sprintf("%.2f %s", balance.Balance, currency))
 
 	// Category section
 	yPos := 260.0
@@ -469,18 +559,9 @@ func (s *ExportService) ExportToPDF(ctx context.Context, lineID string, days int
 		pdf.SetFont("SarabunBold", "", 16)
 		pdf.SetX(30)
 		pdf.SetY(yPos)
-		pdf.Cell(nil, "รายจ่ายแยกตามหมวดหมู่")
+		pdf.Cell(nil, Translate(lang, "รายจ่ายแยกตามหมวดหมู่"))
 		yPos += 30
 
-		// Category bars
-		colors := [][]uint8{
-			{162, 155, 254}, // Light Purple
-			{116, 185, 255}, // Light Blue
-			{129, 236, 236}, // Light Teal
-			{255, 234, 167}, // Light Yellow
-			{250, 177, 160}, // Light Coral
-		}
-
 		pdf.SetFont("Sarabun", "", 12)
 		maxWidth := 250.0
 		for i, cs := range sortedSpending {
@@ -493,14 +574,14 @@ func (s *ExportService) ExportToPDF(ctx context.Context, lineID string, days int
 				percentage = (cs.Amount / balance.TotalExpense) * 100
 			}
 
-			colorIdx := i % len(colors)
-			pdf.SetFillColor(colors[colorIdx][0], colors[colorIdx][1], colors[colorIdx][2])
+			// User's category color (emoji is skipped, Sarabun has no emoji glyphs)
+			pdf.SetFillColor(styles.RGB(cs.Category))
 
 			// Category name
 			pdf.SetTextColor(45, 52, 54)
 			pdf.SetX(30)
 			pdf.SetY(yPos)
-			pdf.Cell(nil, cs.Category)
+			pdf.Cell(nil, Translate(lang, cs.Category))
 
 			// Bar
 			barWidth := (percentage / 100.0) * maxWidth
@@ -512,7 +593,7 @@ func (s *ExportService) ExportToPDF(ctx context.Context, lineID string, days int
 			// Percentage
 			pdf.SetX(420)
 			pdf.SetY(yPos)
-			pdf.Cell(nil, fmt.Sprintf("%.1f%% (%.0f บาท)", percentage, cs.Amount))
+			pdf.Cell(nil, fmt.Sprintf("%.1f%% (%.0f %s)", percentage, cs.Amount, currency))
 
 			yPos += 22
 		}
@@ -524,7 +605,7 @@ func (s *ExportService) ExportToPDF(ctx context.Context, lineID string, days int
 		pdf.SetFont("SarabunBold", "", 16)
 		pdf.SetX(30)
 		pdf.SetY(yPos)
-		pdf.Cell(nil, "สถานะงบประมาณ")
+		pdf.Cell(nil, Translate(lang, "สถานะงบประมาณ"))
 		yPos += 30
 
 		pdf.SetFont("Sarabun", "", 12)
@@ -534,24 +615,24 @@ func (s *ExportService) ExportToPDF(ctx context.Context, lineID string, days int
 				pdf.SetTextColor(214, 48, 49) // Red
 				pdf.SetX(30)
 				pdf.SetY(yPos)
-				pdf.Cell(nil, "[เกิน]")
+				pdf.Cell(nil, "["+Translate(lang, "เกิน")+"]")
 			} else if status.Percentage >= 80 {
 				pdf.SetTextColor(253, 203, 110) // Yellow
 				pdf.SetX(30)
 				pdf.SetY(yPos)
-				pdf.Cell(nil, "[เตือน]")
+				pdf.Cell(nil, "["+Translate(lang, "เตือน")+"]")
 			} else {
 				pdf.SetTextColor(0, 184, 148) // Green
 				pdf.SetX(30)
 				pdf.SetY(yPos)
-				pdf.Cell(nil, "[ปกติ]")
+				pdf.Cell(nil, "["+Translate(lang, "ปกติ")+"]")
 			}
 
 			pdf.SetTextColor(45, 52, 54)
 			pdf.SetX(80)
-			pdf.Cell(nil, status.Category)
+			pdf.Cell(nil, Translate(lang, status.Category))
 			pdf.SetX(200)
-			pdf.Cell(nil, fmt.Sprintf("%.0f / %.0f บาท (%.0f%%)", status.Spent, status.Budget, status.Percentage))
+			pdf.Cell(nil, fmt.Sprintf("%.0f / %.0f %s (%.0f%%)", status.Spent, status.Budget, currency, status.Percentage))
 			yPos += 20
 		}
 	}
@@ -578,9 +659,32 @@ func (s *ExportService) ExportToPDF(ctx context.Context, lineID string, days int
 	return buf.Bytes(), filename, nil
 }
 
-// GetCategorySpendingForChart returns spending data formatted for chart display
-func (s *ExportService) GetCategorySpendingForChart(ctx context.Context, lineID string) ([]CategoryChartData, float64, error) {
-	spending, err := s.mongo.GetMonthlySpendingByCategory(ctx, lineID)
+// ChartRange resolves the chart period of an AI query filter with its Thai label
+// Explicit date_from/date_to wins, then days (last N days), then period (week/year/day), default this month
+func ChartRange(query *QueryFilter, now time.Time) (from, to time.Time, label string) {
+	if query == nil {
+		return StatsPeriodRange("month", now)
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if f, err := time.ParseInLocation("2006-01-02", query.DateFrom, now.Location()); err == nil {
+		t, err := time.ParseInLocation("2006-01-02", query.DateTo, now.Location())
+		if err != nil || t.Before(f) {
+			t = today
+		}
+		if f.Equal(t) {
+			return f, t, f.Format("2/1/2006")
+		}
+		return f, t, fmt.Sprintf("%s - %s", f.Format("2/1"), t.Format("2/1/2006"))
+	}
+	if query.Days > 0 {
+		return today.AddDate(0, 0, 1-query.Days), today, fmt.Sprintf("%d วันล่าสุด", query.Days)
+	}
+	return StatsPeriodRange(query.Period, now)
+}
+
+// GetCategorySpendingForChart returns spending between two dates (inclusive) formatted for chart display
+func (s *ExportService) GetCategorySpendingForChart(ctx context.Context, lineID, startDate, endDate string) ([]CategoryChartData, float64, error) {
+	spending, err := s.mongo.GetSpendingByCategoryRange(ctx, lineID, startDate, endDate)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -590,8 +694,9 @@ func (s *ExportService) GetCategorySpendingForChart(ctx context.Context, lineID
 		total += amount
 	}
 
+	styles := s.mongo.GetCategoryStyles(ctx, lineID)
+
 	var result []CategoryChartData
-	i := 0
 	for category, amount := range spending {
 		percentage := 0.0
 		if total > 0 {
@@ -601,9 +706,9 @@ func (s *ExportService) GetCategorySpendingForChart(ctx context.Context, lineID
 			Category:   category,
 			Amount:     amount,
 			Percentage: percentage,
-			Color:      categoryColors[i%len(categoryColors)],
+			Emoji:      styles.Emoji(category),
+			Color:      styles.Color(category),
 		})
-		i++
 	}
 
 	// Sort by amount descending
@@ -619,5 +724,6 @@ type CategoryChartData struct {
 	Category   string  `json:"category"`
 	Amount     float64 `json:"amount"`
 	Percentage float64 `json:"percentage"`
+	Emoji      string  `json:"emoji"`
 	Color      string  `json:"color"`
 }
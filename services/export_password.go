@@ -0,0 +1,62 @@
+package services
+
+import (
+	"crypto/rand"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// exportPasswordKeywords trigger password-protected export
+var exportPasswordKeywords = []string{"ใส่รหัส", "รหัสผ่าน", "ล็อครหัส", "password"}
+
+// exportPasswordPattern captures user-specified password after keyword ("ใส่รหัส 1234")
+var exportPasswordPattern = regexp.MustCompile(`(?i)(?:ใส่รหัส|รหัสผ่าน|ล็อครหัส|password)\s*[:=]?\s*([A-Za-z0-9!@#$%^&*._-]{4,32})`)
+
+// ParseExportPassword checks if export message asks for password protection
+// Returns user-specified password ("" = generate one) and whether protection was requested
+func ParseExportPassword(message string) (password string, requested bool) {
+	lower := strings.ToLower(message)
+	for _, kw := range exportPasswordKeywords {
+		if strings.Contains(lower, kw) {
+			requested = true
+			break
+		}
+	}
+	if !requested {
+		return "", false
+	}
+	if m := exportPasswordPattern.FindStringSubmatch(message); m != nil {
+		// "export password excel" means file format, not password
+		if format := strings.ToLower(m[1]); format != "excel" && format != "pdf" {
+			return m[1], true
+		}
+	}
+	return "", true
+}
+
+// RedactExportPassword masks a typed password ("ใส่รหัส 1234" -> "ใส่รหัส ****") so it never reaches
+// chat history, transcripts, logs or the AI prompt
+func RedactExportPassword(message string) string {
+	return exportPasswordPattern.ReplaceAllStringFunc(message, func(match string) string {
+		m := exportPasswordPattern.FindStringSubmatch(match)
+		if format := strings.ToLower(m[1]); format == "excel" || format == "pdf" {
+			return match
+		}
+		return strings.TrimSuffix(match, m[1]) + "****"
+	})
+}
+
+// GenerateExportPassword returns random 8-digit password (easy to type on mobile)
+func GenerateExportPassword() string {
+	digits := make([]byte, 8)
+	for i := range digits {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			digits[i] = '0'
+			continue
+		}
+		digits[i] = byte('0' + n.Int64())
+	}
+	return string(digits)
+}
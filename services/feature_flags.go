@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Feature flags for risky features rolled out gradually
+const (
+	FlagTwoStageAI   = "two_stage_ai"
+	FlagVectorSearch = "vector_search"
+	FlagDraftMode    = "draft_mode"
+)
+
+// KnownFeatureFlags lists flags accepted by the admin command
+var KnownFeatureFlags = []string{FlagTwoStageAI, FlagVectorSearch, FlagDraftMode}
+
+// featureFlagCacheTTL is how long rollout percentages are cached before re-reading Mongo
+const featureFlagCacheTTL = time.Minute
+
+// featureFlagDoc is one flag in feature_flags: rollout percent (LineID empty) or a user override
+type featureFlagDoc struct {
+	Name      string    `bson:"name"`
+	LineID    string    `bson:"lineid"`
+	Percent   int       `bson:"percent"` // 0-100, global doc only
+	Enabled   bool      `bson:"enabled"` // user override only
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+// FeatureFlagService decides per user whether a flag is on
+// Order: user override > rollout percent in Mongo > default from config
+type FeatureFlagService struct {
+	mongo    *MongoDBService
+	defaults map[string]int
+
+	mu       sync.Mutex
+	percents map[string]int
+	loadedAt time.Time
+}
+
+// NewFeatureFlagService creates flag service with default percentages (FEATURE_FLAGS)
+func NewFeatureFlagService(mongo *MongoDBService, defaults map[string]int) *FeatureFlagService {
+	return &FeatureFlagService{mongo: mongo, defaults: defaults}
+}
+
+// ParseFeatureFlags parses "two_stage_ai:20,draft_mode" (no percent = 100)
+func ParseFeatureFlags(value string) map[string]int {
+	flags := make(map[string]int)
+	for _, item := range strings.Split(value, ",") {
+		name, percentText, hasPercent := strings.Cut(strings.TrimSpace(item), ":")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		percent := 100
+		if hasPercent {
+			p, err := strconv.Atoi(strings.TrimSpace(percentText))
+			if err != nil {
+				continue
+			}
+			percent = clampPercent(p)
+		}
+		flags[name] = percent
+	}
+	return flags
+}
+
+// RolloutBucket maps user to a stable bucket 0-99 per flag (different flags pick different users)
+func RolloutBucket(flag, lineID string) int {
+	sum := sha256.Sum256([]byte(flag + ":" + lineID))
+	return int(binary.BigEndian.Uint32(sum[:4]) % 100)
+}
+
+// IsEnabled reports whether flag is on for the user
+func (f *FeatureFlagService) IsEnabled(ctx context.Context, flag, lineID string) bool {
+	var override featureFlagDoc
+	err := f.mongo.featureFlagCollection.FindOne(ctx, bson.M{"name": flag, "lineid": lineID}).Decode(&override)
+	if err == nil && lineID != "" {
+		return override.Enabled
+	}
+	return RolloutBucket(flag, lineID) < f.Percent(ctx, flag)
+}
+
+// Percent returns rollout percent of flag (cached)
+func (f *FeatureFlagService) Percent(ctx context.Context, flag string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.percents == nil || time.Since(f.loadedAt) > featureFlagCacheTTL {
+		f.reload(ctx)
+	}
+	return f.percents[flag]
+}
+
+// reload reads rollout percentages from Mongo on top of config defaults (caller holds mu)
+func (f *FeatureFlagService) reload(ctx context.Context) {
+	percents := make(map[string]int, len(f.defaults))
+	for name, p := range f.defaults {
+		percents[name] = p
+	}
+	cursor, err := f.mongo.featureFlagCollection.Find(ctx, bson.M{"lineid": ""})
+	if err == nil {
+		var docs []featureFlagDoc
+		if cursor.All(ctx, &docs) == nil {
+			for _, doc := range docs {
+				percents[doc.Name] = doc.Percent
+			}
+		}
+	}
+	f.percents = percents
+	f.loadedAt = time.Now()
+}
+
+// SetPercent changes rollout percent without redeploy
+func (f *FeatureFlagService) SetPercent(ctx context.Context, flag string, percent int) error {
+	_, err := f.mongo.featureFlagCollection.UpdateOne(ctx,
+		bson.M{"name": flag, "lineid": ""},
+		bson.M{"$set": bson.M{"percent": clampPercent(percent), "updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	f.mu.Lock()
+	f.percents = nil // reload on next check
+	f.mu.Unlock()
+	return err
+}
+
+// SetOverride forces flag on/off for one user
+func (f *FeatureFlagService) SetOverride(ctx context.Context, flag, lineID string, enabled bool) error {
+	_, err := f.mongo.featureFlagCollection.UpdateOne(ctx,
+		bson.M{"name": flag, "lineid": lineID},
+		bson.M{"$set": bson.M{"enabled": enabled, "updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// ClearOverride removes user's override so rollout percent applies again
+func (f *FeatureFlagService) ClearOverride(ctx context.Context, flag, lineID string) error {
+	_, err := f.mongo.featureFlagCollection.DeleteOne(ctx, bson.M{"name": flag, "lineid": lineID})
+	return err
+}
+
+// clampPercent keeps percent within 0-100
+func clampPercent(p int) int {
+	if p < 0 {
+		return 0
+	}
+	if p > 100 {
+		return 100
+	}
+	return p
+}
@@ -9,6 +9,7 @@ import (
 
 	"cloud.google.com/go/storage"
 	firebase "firebase.google.com/go/v4"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
@@ -118,6 +119,56 @@ func (s *FirebaseService) UploadFileWithSignedURL(ctx context.Context, data []by
 	return publicURL, nil
 }
 
+// UploadPrivateFile uploads a file without public ACL and returns its object path
+// Used with short download links so the bucket URL is never exposed
+func (s *FirebaseService) UploadPrivateFile(ctx context.Context, data []byte, filename string, contentType string) (string, error) {
+	objectPath := fmt.Sprintf("exports/%s/%s", time.Now().Format("2006-01-02"), filename)
+
+	writer := s.bucket.Object(objectPath).NewWriter(ctx)
+	writer.ContentType = contentType
+	writer.CacheControl = "private, no-store"
+
+	if _, err := writer.Write(data); err != nil {
+		return "", fmt.Errorf("failed to write to storage: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("failed to close writer: %w", err)
+	}
+	return objectPath, nil
+}
+
+// WriteObject writes data to an exact private object path (used for backups)
+func (s *FirebaseService) WriteObject(ctx context.Context, objectPath string, data []byte, contentType string) error {
+	writer := s.bucket.Object(objectPath).NewWriter(ctx)
+	writer.ContentType = contentType
+	writer.CacheControl = "private, no-store"
+
+	if _, err := writer.Write(data); err != nil {
+		return fmt.Errorf("failed to write to storage: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to close writer: %w", err)
+	}
+	return nil
+}
+
+// ListObjects returns object paths under prefix
+func (s *FirebaseService) ListObjects(ctx context.Context, prefix string) ([]string, error) {
+	var paths []string
+	it := s.bucket.Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, attrs.Name)
+	}
+	return paths, nil
+}
+
 // DeleteFile deletes a file from Firebase Cloud Storage
 func (s *FirebaseService) DeleteFile(ctx context.Context, objectPath string) error {
 	obj := s.bucket.Object(objectPath)
@@ -2,6 +2,10 @@ package services
 
 import (
 	_ "embed"
+	"fmt"
+	"sync"
+
+	"github.com/signintech/gopdf"
 )
 
 //go:embed fonts/Sarabun-Regular.ttf
@@ -9,3 +13,62 @@ var SarabunRegular []byte
 
 //go:embed fonts/Sarabun-Bold.ttf
 var SarabunBold []byte
+
+// Thai fonts are parsed on first PDF (or /warmup), not at startup, then reused by every PDF.
+// gopdf already subsets output: each PDF embeds only the glyphs it uses, not the whole TTF.
+var (
+	thaiFontsOnce sync.Once
+	thaiFonts     *gopdf.FontContainer
+	thaiFontsErr  error
+	pdfFontLite   bool
+)
+
+// SetPDFFontLite parses only the regular face and reuses it for bold (about half the font memory)
+// Call before the first export
+func SetPDFFontLite(enabled bool) {
+	pdfFontLite = enabled
+}
+
+// NewThaiFonts parses "Sarabun" and "SarabunBold" into a new font container
+// lite reuses the regular face for bold, so only one TTF is parsed
+func NewThaiFonts(lite bool) (*gopdf.FontContainer, error) {
+	fonts := &gopdf.FontContainer{}
+	if err := fonts.AddTTFFontData("Sarabun", SarabunRegular); err != nil {
+		return nil, fmt.Errorf("ไม่สามารถโหลดฟอนต์: %w", err)
+	}
+	bold := SarabunBold
+	if lite {
+		bold = SarabunRegular
+	}
+	if err := fonts.AddTTFFontData("SarabunBold", bold); err != nil {
+		return nil, fmt.Errorf("ไม่สามารถโหลดฟอนต์ตัวหนา: %w", err)
+	}
+	return fonts, nil
+}
+
+// loadThaiFonts parses the Thai fonts once per process
+func loadThaiFonts() (*gopdf.FontContainer, error) {
+	thaiFontsOnce.Do(func() {
+		thaiFonts, thaiFontsErr = NewThaiFonts(pdfFontLite)
+	})
+	return thaiFonts, thaiFontsErr
+}
+
+// AddThaiFonts adds "Sarabun" and "SarabunBold" to pdf from fonts without re-parsing the TTF data
+func AddThaiFonts(pdf *gopdf.GoPdf, fonts *gopdf.FontContainer) error {
+	for _, family := range []string{"Sarabun", "SarabunBold"} {
+		if err := pdf.AddTTFFontFromFontContainer(family, fonts); err != nil {
+			return fmt.Errorf("ไม่สามารถโหลดฟอนต์: %w", err)
+		}
+	}
+	return nil
+}
+
+// addThaiFonts adds the process-wide cached Thai fonts to pdf
+func addThaiFonts(pdf *gopdf.GoPdf) error {
+	fonts, err := loadThaiFonts()
+	if err != nil {
+		return err
+	}
+	return AddThaiFonts(pdf, fonts)
+}
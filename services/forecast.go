@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+// forecastKeywords ask for the month-end cash-flow forecast
+var forecastKeywords = []string{"คาดการณ์", "พยากรณ์", "forecast", "สิ้นเดือนจะเหลือ", "สิ้นเดือนเหลือเท่าไหร่", "ปลายเดือนจะเหลือ"}
+
+// IsForecastQuery checks if message asks how much will be left at month end
+func IsForecastQuery(text string) bool {
+	normalized := strings.ToLower(strings.ReplaceAll(text, " ", ""))
+	for _, kw := range forecastKeywords {
+		if strings.Contains(normalized, strings.ReplaceAll(kw, " ", "")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Commitment is a payment already committed before month end (a subscription renewal)
+type Commitment struct {
+	Name   string  `json:"name"`
+	Amount float64 `json:"amount"`
+	Date   string  `json:"date"` // YYYY-MM-DD
+}
+
+// CashFlowForecast projects the balance at month end
+type CashFlowForecast struct {
+	Balance            float64      `json:"balance"`             // income - expense to date
+	Committed          float64      `json:"committed"`           // subscriptions still due this month
+	Discretionary      float64      `json:"discretionary"`       // other spending expected at this month's pace
+	DailyDiscretionary float64      `json:"daily_discretionary"` // pace per day, subscriptions excluded
+	ExpectedBalance    float64      `json:"expected_balance"`
+	DaysLeft           int          `json:"days_left"` // after today
+	MonthEnd           string       `json:"month_end"` // YYYY-MM-DD
+	Commitments        []Commitment `json:"commitments"`
+}
+
+// BuildCashFlowForecast projects balance to month end: committed = active subscriptions renewing after
+// today (or today and not charged yet), discretionary = this month's non-subscription spending per
+// day times the days left
+func BuildCashFlowForecast(balance, monthExpense float64, subs []Subscription, now time.Time) *CashFlowForecast {
+	today := now.Format("2006-01-02")
+	monthStart := now.Format("2006-01") + "-01"
+	monthEnd := time.Date(now.Year(), now.Month()+1, 0, 0, 0, 0, 0, now.Location())
+	f := &CashFlowForecast{
+		Balance:  balance,
+		DaysLeft: monthEnd.Day() - now.Day(),
+		MonthEnd: monthEnd.Format("2006-01-02"),
+	}
+
+	chargedThisMonth := 0.0
+	for _, sub := range subs {
+		if sub.Status != "active" {
+			continue
+		}
+		if sub.LastCharged >= monthStart && sub.LastCharged <= today {
+			chargedThisMonth += sub.Amount
+		}
+		due := sub.NextRenewal > today || (sub.NextRenewal == today && sub.LastCharged != today)
+		if due && sub.NextRenewal <= f.MonthEnd {
+			f.Commitments = append(f.Commitments, Commitment{Name: sub.Name, Amount: sub.Amount, Date: sub.NextRenewal})
+			f.Committed += sub.Amount
+		}
+	}
+	sort.Slice(f.Commitments, func(i, j int) bool { return f.Commitments[i].Date < f.Commitments[j].Date })
+
+	if discretionary := monthExpense - chargedThisMonth; discretionary > 0 {
+		f.DailyDiscretionary = discretionary / float64(now.Day())
+	}
+	f.Discretionary = f.DailyDiscretionary * float64(f.DaysLeft)
+	f.ExpectedBalance = balance - f.Committed - f.Discretionary
+	return f
+}
+
+// GetCashFlowForecast builds the month-end forecast from the balance, this month's spending and subscriptions
+func (s *MongoDBService) GetCashFlowForecast(ctx context.Context, lineID string, now time.Time) (*CashFlowForecast, error) {
+	balance, err := s.ProjectedBalanceSummary(ctx, lineID)
+	if err != nil {
+		return nil, err
+	}
+	month, err := s.ProjectedPeriodSummary(ctx, lineID, "month")
+	if err != nil {
+		return nil, err
+	}
+	subs, err := s.GetSubscriptions(ctx, lineID)
+	if err != nil {
+		return nil, err
+	}
+	return BuildCashFlowForecast(balance.Balance, month.TotalExpense, subs, now), nil
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// fxFeeKeywords ask how much cards charge on foreign spending ("บัตรไหนชาร์จ FX แพง", "ค่าธรรมเนียม FX")
+var fxFeeKeywords = []string{"ชาร์จfx", "ค่าfx", "ค่าธรรมเนียมfx", "fxfee", "fxmarkup", "ค่าธรรมเนียมต่างประเทศ", "ค่าธรรมเนียมแลกเงิน", "เช็คfx"}
+
+// IsFXFeeQuery checks if message asks for the FX fee report
+func IsFXFeeQuery(text string) bool {
+	normalized := strings.ToLower(strings.ReplaceAll(text, " ", ""))
+	for _, kw := range fxFeeKeywords {
+		if strings.Contains(normalized, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// FXFeeLookbackDays is how far back foreign charges are analyzed
+const FXFeeLookbackDays = 365
+
+// FXCharge is a foreign-currency expense paid by card or bank account
+type FXCharge struct {
+	Date          string
+	Card          string // credit card or bank name
+	Currency      string
+	ForeignAmount float64
+	Amount        float64 // THB charged
+}
+
+// CardFXFee is the cumulative FX cost of one card: what was charged vs the mid-market value
+type CardFXFee struct {
+	Card      string  `json:"card"`
+	Count     int     `json:"count"`
+	Charged   float64 `json:"charged"`    // THB
+	MidMarket float64 `json:"mid_market"` // THB at the mid-market rate of each charge date
+	Fee       float64 `json:"fee"`        // Charged - MidMarket
+	Percent   float64 `json:"percent"`    // Fee / MidMarket
+}
+
+// ForeignCharge returns the charge of a foreign-currency expense, false for cash and for entries whose
+// THB amount is still the travel-mode estimate (not yet corrected to what the card actually charged)
+func ForeignCharge(date string, tx Transaction) (FXCharge, bool) {
+	if tx.ForeignAmount <= 0 || tx.Currency == "" || tx.Currency == "THB" || tx.Amount <= 0 {
+		return FXCharge{}, false
+	}
+	card := ""
+	switch tx.UseType {
+	case 1:
+		card = tx.CreditCardName
+	case 2:
+		card = tx.BankName
+	}
+	if card == "" {
+		return FXCharge{}, false
+	}
+	if tx.FXRate > 0 && math.Abs(tx.Amount-roundBaht(tx.ForeignAmount*tx.FXRate)) < 0.01 {
+		return FXCharge{}, false
+	}
+	return FXCharge{Date: date, Card: card, Currency: tx.Currency, ForeignAmount: tx.ForeignAmount, Amount: tx.Amount}, true
+}
+
+// AnalyzeFXFees compares each charge with the mid-market rate of its date (rate returns THB per unit)
+// and totals the markup per card, most expensive card first; charges without a rate are skipped
+func AnalyzeFXFees(charges []FXCharge, rate func(currency, date string) (float64, error)) []CardFXFee {
+	byCard := make(map[string]*CardFXFee)
+	for _, c := range charges {
+		mid, err := rate(c.Currency, c.Date)
+		if err != nil || mid <= 0 {
+			continue
+		}
+		fee := byCard[c.Card]
+		if fee == nil {
+			fee = &CardFXFee{Card: c.Card}
+			byCard[c.Card] = fee
+		}
+		fee.Count++
+		fee.Charged += c.Amount
+		fee.MidMarket += c.ForeignAmount * mid
+	}
+
+	fees := make([]CardFXFee, 0, len(byCard))
+	for _, fee := range byCard {
+		fee.Charged = roundBaht(fee.Charged)
+		fee.MidMarket = roundBaht(fee.MidMarket)
+		fee.Fee = roundBaht(fee.Charged - fee.MidMarket)
+		fee.Percent = math.Round(fee.Fee/fee.MidMarket*1000) / 10
+		fees = append(fees, *fee)
+	}
+	sort.Slice(fees, func(i, j int) bool {
+		if fees[i].Percent != fees[j].Percent {
+			return fees[i].Percent > fees[j].Percent
+		}
+		return fees[i].Card < fees[j].Card
+	})
+	return fees
+}
+
+// GetForeignCharges loads foreign-currency card/bank expenses since date (YYYY-MM-DD)
+func (s *MongoDBService) GetForeignCharges(ctx context.Context, lineID, since string) ([]FXCharge, error) {
+	cursor, err := s.collection.Find(ctx,
+		bson.M{"lineid": lineID, "date": bson.M{"$gte": since}, "expenses.foreign_amount": bson.M{"$gt": 0}},
+		options.Find().SetProjection(bson.M{"date": 1, "expenses.amount": 1, "expenses.usetype": 1, "expenses.bankname": 1,
+			"expenses.creditcardname": 1, "expenses.currency": 1, "expenses.foreign_amount": 1, "expenses.fx_rate": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var records []DailyRecord
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	var charges []FXCharge
+	for _, record := range records {
+		for _, tx := range record.Expenses {
+			if c, ok := ForeignCharge(record.Date, tx); ok {
+				charges = append(charges, c)
+			}
+		}
+	}
+	return charges, nil
+}
+
+// GetFXFees analyzes the last FXFeeLookbackDays of foreign charges against fx's mid-market rates
+func (s *MongoDBService) GetFXFees(ctx context.Context, lineID string, fx *FXRates, now time.Time) ([]CardFXFee, error) {
+	charges, err := s.GetForeignCharges(ctx, lineID, now.AddDate(0, 0, -FXFeeLookbackDays).Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	return AnalyzeFXFees(charges, func(currency, date string) (float64, error) {
+		return fx.Rate(ctx, currency, date)
+	}), nil
+}
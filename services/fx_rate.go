@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FXRates fetches mid-market rates to THB from a Frankfurter-compatible API (ECB reference rates)
+type FXRates struct {
+	baseURL string
+	client  *http.Client
+	mu      sync.Mutex
+	cache   map[string]float64 // "JPY|2026-10-16" -> THB per unit
+}
+
+// NewFXRates returns nil when baseURL is empty (rates must then be typed by the user)
+func NewFXRates(baseURL string) *FXRates {
+	if baseURL == "" {
+		return nil
+	}
+	return &FXRates{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: 5 * time.Second},
+		cache:   make(map[string]float64),
+	}
+}
+
+// fxRateResponse is the API answer: {"amount":1,"base":"JPY","date":"2026-10-16","rates":{"THB":0.2291}}
+type fxRateResponse struct {
+	Rates map[string]float64 `json:"rates"`
+}
+
+// Rate returns THB per one unit of currency on date (YYYY-MM-DD, "" = latest); weekends and holidays
+// get the last published rate
+func (r *FXRates) Rate(ctx context.Context, currency, date string) (float64, error) {
+	currency = strings.ToUpper(currency)
+	if currency == "THB" {
+		return 1, nil
+	}
+	path := date
+	if path == "" {
+		path = "latest"
+	}
+	key := currency + "|" + path
+	r.mu.Lock()
+	rate, ok := r.cache[key]
+	r.mu.Unlock()
+	if ok {
+		return rate, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/%s?from=%s&to=THB", r.baseURL, path, currency), nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("fx rate request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("fx rate API status %d for %s", resp.StatusCode, currency)
+	}
+	var body fxRateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("fx rate response: %w", err)
+	}
+	rate = body.Rates["THB"]
+	if rate <= 0 {
+		return 0, fmt.Errorf("no THB rate for %s", currency)
+	}
+	// Latest rates change daily, dated rates never do
+	if date != "" {
+		r.mu.Lock()
+		r.cache[key] = rate
+		r.mu.Unlock()
+	}
+	return rate, nil
+}
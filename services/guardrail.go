@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Guardrail warns before saving a single expense above MaxAmount in a category
+// Separate from monthly budgets: checked per transaction, not per month
+type Guardrail struct {
+	LineID    string    `bson:"lineid" json:"lineid"`
+	Category  string    `bson:"category" json:"category"`
+	MaxAmount float64   `bson:"max_amount" json:"max_amount"` // เตือนถ้าครั้งเดียวเกินยอดนี้
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// GuardrailBreach is one transaction exceeding a guardrail
+type GuardrailBreach struct {
+	Index int // index in the transaction batch
+	Rule  Guardrail
+}
+
+// normalizeGuardrailCategory lets "ค่าบันเทิง" match category "บันเทิง"
+func normalizeGuardrailCategory(category string) string {
+	category = strings.ToLower(strings.TrimSpace(category))
+	if strings.HasPrefix(category, "ค่า") && category != "ค่า" {
+		category = strings.TrimPrefix(category, "ค่า")
+	}
+	return category
+}
+
+// SetGuardrail creates or updates per-transaction limit of a category
+func (s *MongoDBService) SetGuardrail(ctx context.Context, lineID, category string, maxAmount float64) error {
+	if maxAmount <= 0 {
+		return fmt.Errorf("ยอดต้องมากกว่า 0")
+	}
+	_, err := s.guardrailCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID, "category": normalizeGuardrailCategory(category)},
+		bson.M{"$set": bson.M{
+			"max_amount": maxAmount,
+			"updated_at": time.Now(),
+		}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// GetGuardrails returns all per-transaction limits of a user
+func (s *MongoDBService) GetGuardrails(ctx context.Context, lineID string) ([]Guardrail, error) {
+	cursor, err := s.guardrailCollection.Find(ctx, bson.M{"lineid": lineID}, options.Find().SetSort(bson.M{"category": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rules []Guardrail
+	if err := cursor.All(ctx, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// DeleteGuardrail removes limit of a category, false if there was none
+func (s *MongoDBService) DeleteGuardrail(ctx context.Context, lineID, category string) (bool, error) {
+	result, err := s.guardrailCollection.DeleteOne(ctx, bson.M{"lineid": lineID, "category": normalizeGuardrailCategory(category)})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// FindGuardrailBreaches returns expenses in txs that exceed a matching guardrail
+func FindGuardrailBreaches(rules []Guardrail, txs []TransactionData) []GuardrailBreach {
+	var breaches []GuardrailBreach
+	for i, tx := range txs {
+		if tx.Type != "expense" {
+			continue
+		}
+		category := normalizeGuardrailCategory(tx.Category)
+		for _, rule := range rules {
+			if rule.Category == category && tx.Amount > rule.MaxAmount {
+				breaches = append(breaches, GuardrailBreach{Index: i, Rule: rule})
+				break
+			}
+		}
+	}
+	return breaches
+}
@@ -0,0 +1,363 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// HashChainEntry is one recorded change in a user's per-month hash chain (tx_hash_chain)
+// Hash covers Prev and the change, so editing or removing an entry breaks every later link
+// A change that couldn't be chained waits in tx_hash_chain_pending with Seq 0 and Prev ""
+type HashChainEntry struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	LineID     string             `bson:"lineid" json:"-"`
+	Month      string             `bson:"month" json:"month"` // YYYY-MM of the transaction date
+	Seq        int                `bson:"seq" json:"seq"`     // 1, 2, ... per user and month
+	Event      string             `bson:"event" json:"event"`
+	TxID       string             `bson:"tx_id" json:"tx_id"`
+	Date       string             `bson:"date" json:"date"`
+	TxHash     string             `bson:"tx_hash,omitempty" json:"tx_hash,omitempty"` // TransactionHash, "" for deletes
+	Prev       string             `bson:"prev" json:"prev"`
+	Hash       string             `bson:"hash" json:"hash"`
+	RecordedAt time.Time          `bson:"recorded_at" json:"recorded_at"`
+}
+
+// hashChainRetries is how often a chain append is retried when another instance took the same seq
+const hashChainRetries = 5
+
+// HashChainGenesis is the Prev of a month's first entry
+func HashChainGenesis(lineID, month string) string {
+	sum := sha256.Sum256([]byte("satisatang:" + lineID + ":" + month))
+	return hex.EncodeToString(sum[:])
+}
+
+// TransactionHash fingerprints what a statement shows of a transaction (review flags, slip checks
+// and timestamps are left out; the receipt image is covered by its own hash)
+func TransactionHash(date string, tx Transaction) string {
+	image := ""
+	if tx.ImageBase64 != "" {
+		sum := sha256.Sum256([]byte(tx.ImageBase64))
+		image = hex.EncodeToString(sum[:])
+	}
+	fields := []string{
+		tx.ID.Hex(), date, fmt.Sprint(tx.Type), fmt.Sprintf("%.2f", tx.Amount), tx.Category, tx.Description,
+		tx.CustName, tx.Merchant, fmt.Sprint(tx.UseType), tx.BankName, tx.CreditCardName, tx.TransferID,
+		fmt.Sprint(tx.IsTransfer), fmt.Sprintf("%.2f", tx.VATAmount), fmt.Sprintf("%.2f", tx.ServiceCharge),
+		tx.TaxID, tx.ReceiptNo, tx.Currency, fmt.Sprintf("%.2f", tx.ForeignAmount), image,
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x1f")))
+	return hex.EncodeToString(sum[:])
+}
+
+// ChainHash links a change to the previous entry's hash
+// With a key it's an HMAC, so someone with database access alone can't rebuild a valid chain
+func ChainHash(key []byte, prev, event, txID, date, txHash string) string {
+	data := []byte(strings.Join([]string{prev, event, txID, date, txHash}, "\x1f"))
+	if len(key) == 0 {
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:])
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// HashMismatch is a transaction whose stored data doesn't match the chain
+type HashMismatch struct {
+	TxID        string  `json:"tx_id"`
+	Date        string  `json:"date"`
+	Reason      string  `json:"reason"` // "modified", "missing" (deleted outside the app) or "unrecorded"
+	Description string  `json:"description,omitempty"`
+	Amount      float64 `json:"amount,omitempty"`
+}
+
+// HashChainReport is the verification result of one month
+type HashChainReport struct {
+	Month      string         `json:"month"`
+	Head       string         `json:"head"` // hash of the last entry, "" when nothing was recorded
+	Entries    int            `json:"entries"`
+	Verified   int            `json:"verified"`  // transactions matching the chain
+	Unsealed   int            `json:"unsealed"`  // recorded before hashing was turned on
+	Pending    int            `json:"pending"`   // changed, but the chain append is still waiting to be retried
+	BrokenAt   int            `json:"broken_at"` // seq of the first entry whose link doesn't verify, 0 = intact
+	Mismatches []HashMismatch `json:"mismatches"`
+}
+
+// OK reports whether the chain is intact and every sealed transaction is unchanged
+func (r *HashChainReport) OK() bool {
+	return r.BrokenAt == 0 && len(r.Mismatches) == 0
+}
+
+// VerifyHashChain checks a month's chain links (entries sorted by seq) and compares the latest recorded
+// state of each transaction with records; transactions created before since weren't sealed
+// pending are changes not chained yet (oldest first); a transaction matching one is counted as pending
+// instead of reported as unrecorded, and a pending entry whose hash doesn't verify with key is ignored
+func VerifyHashChain(key []byte, lineID, month string, entries, pending []HashChainEntry, records []DailyRecord, since time.Time) *HashChainReport {
+	report := &HashChainReport{Month: month, Entries: len(entries), Mismatches: []HashMismatch{}}
+
+	prev := HashChainGenesis(lineID, month)
+	latest := make(map[string]HashChainEntry)
+	for i, e := range entries {
+		if e.Seq != i+1 || e.Prev != prev || e.Hash != ChainHash(key, e.Prev, e.Event, e.TxID, e.Date, e.TxHash) {
+			report.BrokenAt = i + 1
+			break
+		}
+		prev = e.Hash
+		latest[e.TxID] = e
+	}
+	if report.BrokenAt == 0 && len(entries) > 0 {
+		report.Head = prev
+	}
+	waiting := make(map[string]HashChainEntry)
+	for _, e := range pending {
+		if e.Hash == ChainHash(key, "", e.Event, e.TxID, e.Date, e.TxHash) {
+			waiting[e.TxID] = e
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, record := range records {
+		if !strings.HasPrefix(record.Date, month) {
+			continue
+		}
+		for _, tx := range append(append([]Transaction{}, record.Incomes...), record.Expenses...) {
+			id := tx.ID.Hex()
+			seen[id] = true
+			e, ok := latest[id]
+			if p, waits := waiting[id]; waits {
+				e, ok = p, true
+			}
+			switch {
+			case !ok && !since.IsZero() && tx.CreatedAt.Before(since):
+				report.Unsealed++
+			case !ok || e.Event == TransactionDeleted:
+				report.Mismatches = append(report.Mismatches, HashMismatch{TxID: id, Date: record.Date, Reason: "unrecorded", Description: tx.Description, Amount: tx.Amount})
+			case e.Date != record.Date || e.TxHash != TransactionHash(record.Date, tx):
+				report.Mismatches = append(report.Mismatches, HashMismatch{TxID: id, Date: record.Date, Reason: "modified", Description: tx.Description, Amount: tx.Amount})
+			case e.Seq == 0:
+				report.Pending++
+			default:
+				report.Verified++
+			}
+		}
+	}
+	for id, e := range latest {
+		if seen[id] || e.Event == TransactionDeleted {
+			continue
+		}
+		if p, waits := waiting[id]; waits && p.Event == TransactionDeleted {
+			report.Pending++
+			continue
+		}
+		report.Mismatches = append(report.Mismatches, HashMismatch{TxID: id, Date: e.Date, Reason: "missing"})
+	}
+	return report
+}
+
+// hashChainFlagTTL bounds how long another instance's opt-in change can go unnoticed
+const hashChainFlagTTL = 5 * time.Minute
+
+// hashChainFlag is a user's cached hashing opt-in
+type hashChainFlag struct {
+	enabled  bool
+	loadedAt time.Time
+}
+
+// cachedHashChainFlag returns the cached opt-in; ok is false when it's unknown or expired
+func (s *MongoDBService) cachedHashChainFlag(lineID string) (enabled, ok bool) {
+	cached, found := s.hashChainFlags.Load(lineID)
+	if !found {
+		return false, false
+	}
+	flag := cached.(hashChainFlag)
+	if time.Since(flag.loadedAt) >= hashChainFlagTTL {
+		return false, false
+	}
+	return flag.enabled, true
+}
+
+// hashChainEnabled returns the opt-in, loading and caching it on a miss
+func (s *MongoDBService) hashChainEnabled(ctx context.Context, lineID string) bool {
+	if enabled, ok := s.cachedHashChainFlag(lineID); ok {
+		return enabled
+	}
+	settings, err := s.GetUserSettings(ctx, lineID)
+	if err != nil {
+		return false
+	}
+	s.hashChainFlags.Store(lineID, hashChainFlag{enabled: settings.HashChain, loadedAt: time.Now()})
+	return settings.HashChain
+}
+
+// recordHashChain is the transaction hook appending changes of users who turned hashing on
+// Users known to be opted out return right away; the rest is appended in background, in order per user
+func (s *MongoDBService) recordHashChain(event, lineID, date string, tx Transaction) {
+	if len(date) < 7 {
+		return
+	}
+	if enabled, ok := s.cachedHashChainFlag(lineID); ok && !enabled {
+		return
+	}
+	s.hashChainQueue.Enqueue(lineID, "hashchain.append", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if s.hashChainEnabled(ctx, lineID) {
+			s.appendHashChain(ctx, event, lineID, date, tx)
+		}
+	})
+}
+
+// SetHashChainKey sets the server secret chain hashes are keyed with ("" = plain SHA-256)
+// Chains recorded under another key stop verifying, so it's set once before users turn hashing on
+func (s *MongoDBService) SetHashChainKey(secret string) {
+	s.hashChainKey = []byte(secret)
+}
+
+// appendHashChain adds one change after the month's last entry
+// Changes that couldn't be chained earlier go first; when the chain can't be written the change
+// is kept as pending, so verification doesn't report it as made outside the app
+func (s *MongoDBService) appendHashChain(ctx context.Context, event, lineID, date string, tx Transaction) {
+	entry := HashChainEntry{LineID: lineID, Month: date[:7], Event: event, TxID: tx.ID.Hex(), Date: date, RecordedAt: time.Now()}
+	if event != TransactionDeleted {
+		entry.TxHash = TransactionHash(date, tx)
+	}
+
+	err := s.flushPendingHashChain(ctx, lineID)
+	if err == nil {
+		err = s.insertHashChain(ctx, entry)
+	}
+	if err == nil {
+		return
+	}
+	log.Printf("Failed to append hash chain, keeping the change as pending: %v", err)
+	entry.Hash = ChainHash(s.hashChainKey, "", entry.Event, entry.TxID, entry.Date, entry.TxHash)
+	if _, err := s.hashPendingCollection.InsertOne(ctx, entry); err != nil {
+		log.Printf("Failed to save pending hash chain entry: %v", err)
+	}
+}
+
+// insertHashChain links entry after its month's last entry
+func (s *MongoDBService) insertHashChain(ctx context.Context, entry HashChainEntry) error {
+	entry.ID = primitive.NilObjectID
+	// The unique (lineid, month, seq) index makes a concurrent append fail instead of forking the chain
+	for attempt := 0; attempt < hashChainRetries; attempt++ {
+		entry.Seq, entry.Prev = 1, HashChainGenesis(entry.LineID, entry.Month)
+		var last HashChainEntry
+		err := s.hashChainCollection.FindOne(ctx, bson.M{"lineid": entry.LineID, "month": entry.Month},
+			options.FindOne().SetSort(bson.M{"seq": -1})).Decode(&last)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return fmt.Errorf("failed to read hash chain: %w", err)
+		}
+		if err == nil {
+			entry.Seq, entry.Prev = last.Seq+1, last.Hash
+		}
+		entry.Hash = ChainHash(s.hashChainKey, entry.Prev, entry.Event, entry.TxID, entry.Date, entry.TxHash)
+		_, err = s.hashChainCollection.InsertOne(ctx, entry)
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		}
+		return err
+	}
+	return fmt.Errorf("too many concurrent writes to the %s %s hash chain", entry.LineID, entry.Month)
+}
+
+// flushPendingHashChain chains a user's pending changes, oldest first, stopping at the first failure
+func (s *MongoDBService) flushPendingHashChain(ctx context.Context, lineID string) error {
+	cursor, err := s.hashPendingCollection.Find(ctx, bson.M{"lineid": lineID},
+		options.Find().SetSort(bson.D{{Key: "recorded_at", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	var pending []HashChainEntry
+	if err := cursor.All(ctx, &pending); err != nil {
+		return err
+	}
+	for _, entry := range pending {
+		if err := s.insertHashChain(ctx, entry); err != nil {
+			return err
+		}
+		if _, err := s.hashPendingCollection.DeleteOne(ctx, bson.M{"_id": entry.ID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetHashChain turns transaction hashing on or off; the first time on is kept as the sealing start
+func (s *MongoDBService) SetHashChain(ctx context.Context, lineID string, enabled bool) error {
+	update := bson.M{"$set": bson.M{"hash_chain": enabled, "updated_at": time.Now()}}
+	if enabled {
+		update["$min"] = bson.M{"hash_chain_since": time.Now()}
+	}
+	_, err := s.settingsCollection.UpdateOne(ctx, bson.M{"lineid": lineID}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+	s.hashChainFlags.Store(lineID, hashChainFlag{enabled: enabled, loadedAt: time.Now()})
+	return nil
+}
+
+// GetHashChainHead returns the hash of a month's last entry ("" when nothing was recorded)
+func (s *MongoDBService) GetHashChainHead(ctx context.Context, lineID, month string) string {
+	var last HashChainEntry
+	err := s.hashChainCollection.FindOne(ctx, bson.M{"lineid": lineID, "month": month},
+		options.FindOne().SetSort(bson.M{"seq": -1})).Decode(&last)
+	if err != nil {
+		return ""
+	}
+	return last.Hash
+}
+
+// VerifyMonthHashChain recomputes month's (YYYY-MM) chain and checks the stored transactions against it
+func (s *MongoDBService) VerifyMonthHashChain(ctx context.Context, lineID, month string) (*HashChainReport, error) {
+	from, to, err := MonthRange(month)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.flushPendingHashChain(ctx, lineID); err != nil {
+		log.Printf("Failed to chain pending hash chain entries: %v", err)
+	}
+	cursor, err := s.hashChainCollection.Find(ctx, bson.M{"lineid": lineID, "month": month}, options.Find().SetSort(bson.M{"seq": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var entries []HashChainEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	cursor, err = s.hashPendingCollection.Find(ctx, bson.M{"lineid": lineID, "month": month},
+		options.Find().SetSort(bson.D{{Key: "recorded_at", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var pending []HashChainEntry
+	if err := cursor.All(ctx, &pending); err != nil {
+		return nil, err
+	}
+
+	var records []DailyRecord
+	filter := bson.M{"lineid": lineID, "date": bson.M{"$gte": from, "$lte": to}}
+	err = s.eachDailyRecord(ctx, lineID, from, filter, nil, true, func(record DailyRecord) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	settings, err := s.GetUserSettings(ctx, lineID)
+	if err != nil {
+		return nil, err
+	}
+	return VerifyHashChain(s.hashChainKey, lineID, month, entries, pending, records, settings.HashChainSince), nil
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Export languages (UserProfile.Language or picked in the export message)
+const (
+	LangThai      = "th"
+	LangEnglish   = "en"
+	LangBilingual = "th-en"
+)
+
+// categoryCatalog maps built-in Thai categories to English (user categories not listed stay Thai)
+var categoryCatalog = map[string]string{
+	"อาหาร": "Food", "เครื่องดื่ม": "Drinks", "กาแฟ": "Coffee", "ขนม": "Snacks",
+	"เดินทาง": "Transport", "น้ำมัน": "Fuel", "ที่อยู่": "Housing", "ค่าเช่า": "Rent",
+	"ค่าน้ำ": "Water bill", "ค่าไฟ": "Electricity", "ค่าโทรศัพท์": "Phone", "ค่าเน็ต": "Internet",
+	"ช้อปปิ้ง": "Shopping", "ของใช้": "Household", "เสื้อผ้า": "Clothing", "ความงาม": "Beauty",
+	"บันเทิง": "Entertainment", "ท่องเที่ยว": "Travel", "สุขภาพ": "Health", "ประกัน": "Insurance",
+	"การศึกษา": "Education", "ภาษี": "Tax", "ค่าธรรมเนียม": "Fees", "บริจาค": "Donation",
+	"ทำบุญ": "Merit making", "ของขวัญ": "Gifts", "สัตว์เลี้ยง": "Pets", "ลูก": "Children",
+	"เงินเดือน": "Salary", "โบนัส": "Bonus", "ค่าคอม": "Commission", "ดอกเบี้ย": "Interest",
+	"เงินปันผล": "Dividends", "ขายของ": "Sales", "รายได้เสริม": "Side income",
+	"โอนเงิน": "Transfer", InvestmentCategory: "Investment", "ออม": "Savings", "อื่นๆ": "Other",
+}
+
+// labelCatalog holds the English of fixed export texts (headers, row types, totals, sheet names)
+var labelCatalog = map[string]string{
+	"รายการทั้งหมด": "Transactions", "สรุปหมวดหมู่": "Categories", "ร้านค้า": "Merchants",
+	"วันที่": "Date", "ประเภท": "Type", "หมวดหมู่": "Category", "รายละเอียด": "Description",
+	"จำนวน (บาท)": "Amount (THB)", "ช่องทาง": "Account", "อันดับ": "Rank", "จำนวนเงิน": "Amount",
+	"สัดส่วน": "Share", "จำนวนครั้ง": "Visits",
+	"รายจ่าย": "Expense", "รายรับ": "Income", "ลงทุน": "Investment", "ขายการลงทุน": "Investment sale",
+	"สรุปยอด": "Summary", "รวมรายรับ:": "Total income:", "รวมรายจ่าย:": "Total expenses:", "คงเหลือ:": "Balance:",
+	"รวมทั้งหมด": "Total", "สรุปรายจ่ายตามหมวดหมู่": "Spending by category", "ร้านที่จ่ายมากสุด": "Top merchants",
+	"เก็บถาวร": "archived", "รายรับทั้งหมด:": "Total income:", "รายจ่ายทั้งหมด:": "Total expenses:",
+	"ยอดคงเหลือ:": "Balance:", "รายจ่ายแยกตามหมวดหมู่": "Expenses by category", "สถานะงบประมาณ": "Budget status",
+	"เกิน": "over", "เตือน": "warn", "ปกติ": "ok", "บาท": "THB",
+}
+
+// exportLanguageKeywords pick the export language from the message ("export excel english")
+var exportLanguageKeywords = []struct{ keyword, lang string }{
+	{"สองภาษา", LangBilingual}, {"2 ภาษา", LangBilingual}, {"bilingual", LangBilingual},
+	{"ภาษาอังกฤษ", LangEnglish}, {"อังกฤษ", LangEnglish}, {"english", LangEnglish},
+}
+
+// NormalizeLanguage returns a supported export language (Thai for empty or unknown values)
+func NormalizeLanguage(lang string) string {
+	switch strings.ToLower(strings.TrimSpace(lang)) {
+	case "en", "en-us", "en-gb", "english":
+		return LangEnglish
+	case "th-en", "en-th", "bilingual":
+		return LangBilingual
+	}
+	return LangThai
+}
+
+// ParseExportLanguage returns the language asked for in the export message, else the profile language
+func ParseExportLanguage(message, profileLang string) string {
+	lower := strings.ToLower(message)
+	for _, kw := range exportLanguageKeywords {
+		if strings.Contains(lower, kw.keyword) {
+			return kw.lang
+		}
+	}
+	return NormalizeLanguage(profileLang)
+}
+
+// Translate returns a Thai category or export label in lang ("อาหาร / Food" when bilingual);
+// texts missing from the catalog are returned unchanged
+func Translate(lang, text string) string {
+	if lang == LangThai || lang == "" {
+		return text
+	}
+	en, ok := categoryCatalog[text]
+	if !ok {
+		en, ok = labelCatalog[text]
+	}
+	if !ok {
+		return text
+	}
+	if lang == LangBilingual {
+		return text + " / " + en
+	}
+	return en
+}
+
+// SheetName returns an Excel sheet name in lang (bilingual keeps Thai, "/" is not allowed in sheet names)
+func SheetName(lang, text string) string {
+	if lang == LangEnglish {
+		return Translate(lang, text)
+	}
+	return text
+}
+
+// PaymentLabel is getPaymentInfo in lang (bank and card names are kept as entered)
+func PaymentLabel(lang string, useType int, bankName, creditCardName string) string {
+	if lang != LangEnglish {
+		return getPaymentInfo(useType, bankName, creditCardName)
+	}
+	switch useType {
+	case 1:
+		return strings.TrimSpace("Card " + creditCardName)
+	case 2:
+		return strings.TrimSpace("Bank " + bankName)
+	}
+	return "Cash"
+}
+
+// exportTitle is the report title of an export covering days
+func exportTitle(lang, displayName string, days int) string {
+	if lang == LangEnglish {
+		if name := strings.TrimSpace(displayName); name != "" {
+			return fmt.Sprintf("%s's report (%d days)", name, days)
+		}
+		return fmt.Sprintf("Satisatang - %d-day report", days)
+	}
+	if strings.TrimSpace(displayName) != "" {
+		return fmt.Sprintf("%s (%d วัน)", ReportOwnerTitle(displayName), days)
+	}
+	return fmt.Sprintf("สติสตางค์ - รายงาน %d วัน", days)
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"image/jpeg"
+
+	_ "image/gif" // Register decoders for LINE image uploads
+	_ "image/png"
+)
+
+// ImageCompressOptions limits images before AI OCR and storage
+type ImageCompressOptions struct {
+	MaxDimension int // longest side in pixels
+	MaxBytes     int // target encoded size
+	Quality      int // starting JPEG quality (1-100)
+}
+
+// DefaultImageCompressOptions keeps receipts readable while cutting upload size
+var DefaultImageCompressOptions = ImageCompressOptions{MaxDimension: 1600, MaxBytes: 1 << 20, Quality: 80}
+
+// minJPEGQuality is the lowest quality tried before shrinking dimensions further
+const minJPEGQuality = 50
+
+// minImageDimension is the smallest longest side tried; the last attempt is returned even if it's too big
+const minImageDimension = 400
+
+// CompressImage downscales and re-encodes an image as JPEG when it exceeds the limits
+// Returns the original data when it's already small enough or can't be decoded
+func CompressImage(data []byte, mimeType string, opts ImageCompressOptions) ([]byte, string) {
+	if opts.MaxDimension <= 0 || opts.MaxBytes <= 0 {
+		return data, mimeType
+	}
+	if opts.Quality <= 0 || opts.Quality > 100 {
+		opts.Quality = DefaultImageCompressOptions.Quality
+	}
+	if opts.Quality < minJPEGQuality {
+		opts.Quality = minJPEGQuality // below it the quality loop would never run
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return data, mimeType
+	}
+	if len(data) <= opts.MaxBytes && cfg.Width <= opts.MaxDimension && cfg.Height <= opts.MaxDimension {
+		return data, mimeType
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return data, mimeType
+	}
+	// Re-encoding drops EXIF, so apply phone camera rotation to the pixels
+	img := applyJPEGOrientation(toRGBA(src), jpegOrientation(data))
+
+	maxDim := opts.MaxDimension
+	for {
+		scaled := downscale(img, maxDim)
+		for quality := opts.Quality; quality >= minJPEGQuality; quality -= 10 {
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: quality}); err != nil {
+				return data, mimeType
+			}
+			if buf.Len() <= opts.MaxBytes || (quality-10 < minJPEGQuality && maxDim <= minImageDimension) {
+				if buf.Len() >= len(data) && cfg.Width <= opts.MaxDimension && cfg.Height <= opts.MaxDimension {
+					return data, mimeType // Re-encoding didn't help
+				}
+				return buf.Bytes(), "image/jpeg"
+			}
+		}
+		if maxDim <= minImageDimension {
+			return data, mimeType // unreachable with a clamped quality, but never loop forever
+		}
+		maxDim = max(maxDim*3/4, minImageDimension)
+	}
+}
+
+// toRGBA converts any image to RGBA (draw has fast paths for JPEG's YCbCr)
+func toRGBA(src image.Image) *image.RGBA {
+	if rgba, ok := src.(*image.RGBA); ok {
+		return rgba
+	}
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+	return dst
+}
+
+// downscale shrinks img so its longest side is at most maxDim, averaging source pixels (box filter)
+func downscale(img *image.RGBA, maxDim int) *image.RGBA {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if w <= maxDim && h <= maxDim {
+		return img
+	}
+	dw, dh := maxDim, h*maxDim/w
+	if h > w {
+		dw, dh = w*maxDim/h, maxDim
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, (y+1)*h/dh
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, (x+1)*w/dw
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				off := sy*img.Stride + x0*4
+				for sx := x0; sx < x1; sx++ {
+					r += int(img.Pix[off])
+					g += int(img.Pix[off+1])
+					b += int(img.Pix[off+2])
+					a += int(img.Pix[off+3])
+					off += 4
+					n++
+				}
+			}
+			d := y*dst.Stride + x*4
+			dst.Pix[d], dst.Pix[d+1], dst.Pix[d+2], dst.Pix[d+3] = uint8(r/n), uint8(g/n), uint8(b/n), uint8(a/n)
+		}
+	}
+	return dst
+}
+
+// jpegOrientation reads EXIF orientation (1-8) from JPEG data, 1 when absent
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if marker == 0xDA || size < 2 || i+2+size > len(data) {
+			return 1 // Start of scan: no EXIF before image data
+		}
+		segment := data[i+4 : i+2+size]
+		if marker == 0xE1 && len(segment) > 14 && string(segment[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+// tiffOrientation finds tag 0x0112 in the first IFD of EXIF TIFF data
+func tiffOrientation(tiff []byte) int {
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if v := int(order.Uint16(tiff[entry+8:])); v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// applyJPEGOrientation rotates/flips pixels so the image is upright (EXIF orientation 2-8)
+func applyJPEGOrientation(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w // 5-8 swap width and height
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			s := y*img.Stride + x*4
+			d := dy*dst.Stride + dx*4
+			copy(dst.Pix[d:d+4], img.Pix[s:s+4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// IncomeSource represents income of one source (category or destination) with deltas
+type IncomeSource struct {
+	Name     string  `json:"name"`
+	Amount   float64 `json:"amount"`
+	Previous float64 `json:"previous"`  // previous period (MoM / WoW)
+	LastYear float64 `json:"last_year"` // same range last year (YoY)
+}
+
+// IncomeReport represents where income came from over a period
+type IncomeReport struct {
+	Period           string         `json:"period"` // "week", "month", "year"
+	Label            string         `json:"label"`
+	PreviousLabel    string         `json:"previous_label"`
+	From             string         `json:"from"`
+	To               string         `json:"to"`
+	Total            float64        `json:"total"`
+	PreviousTotal    float64        `json:"previous_total"`
+	LastYearTotal    float64        `json:"last_year_total"`
+	HasLastYear      bool           `json:"has_last_year"`     // false for "year" (previous period is last year)
+	ByCategory       []IncomeSource `json:"by_category"`       // sorted highest first
+	ByDestination    []IncomeSource `json:"by_destination"`    // cash / bank / card, sorted highest first
+	TransactionCount int            `json:"transaction_count"` // income transactions in current period
+}
+
+// incomeBreakdown holds income totals grouped by category and payment destination
+type incomeBreakdown struct {
+	total         float64
+	count         int
+	byCategory    map[string]float64
+	byDestination map[string]float64
+}
+
+// getIncomeBreakdown sums income between two dates, excluding transfers
+func (s *MongoDBService) getIncomeBreakdown(ctx context.Context, lineID string, from, to time.Time) (*incomeBreakdown, error) {
+	filter := bson.M{
+		"lineid": lineID,
+		"date":   bson.M{"$gte": from.Format("2006-01-02"), "$lte": to.Format("2006-01-02")},
+	}
+	cursor, err := s.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	result := &incomeBreakdown{
+		byCategory:    make(map[string]float64),
+		byDestination: make(map[string]float64),
+	}
+	for cursor.Next(ctx) {
+		var record DailyRecord
+		if err := cursor.Decode(&record); err != nil {
+			continue
+		}
+		for _, tx := range record.Incomes {
+			if tx.IsAssetMove() {
+				continue // Transfers and investment sales are not income
+			}
+			category := tx.Category
+			if category == "" {
+				category = "อื่นๆ"
+			}
+			result.total += tx.Amount
+			result.count++
+			result.byCategory[category] += tx.Amount
+			result.byDestination[getPaymentInfo(tx.UseType, tx.BankName, tx.CreditCardName)] += tx.Amount
+		}
+	}
+	return result, nil
+}
+
+// GetIncomeReport summarizes income by category and destination with MoM/YoY deltas
+// period: "week", "month" (default) or "year"
+func (s *MongoDBService) GetIncomeReport(ctx context.Context, lineID, period string) (*IncomeReport, error) {
+	now := time.Now()
+	var curFrom, curTo, prevFrom, prevTo time.Time
+
+	report := &IncomeReport{Period: period}
+	switch period {
+	case "week":
+		curFrom, curTo, prevFrom, prevTo = PeriodRanges("week", now)
+		report.Label, report.PreviousLabel = "สัปดาห์นี้", "สัปดาห์ที่แล้ว"
+		report.HasLastYear = true
+	case "year":
+		curTo = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		curFrom = time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+		prevFrom = curFrom.AddDate(-1, 0, 0)
+		prevTo = curTo.AddDate(-1, 0, 0) // Same range last year
+		report.Label, report.PreviousLabel = "ปีนี้", "ปีที่แล้ว"
+	default:
+		report.Period = "month"
+		curFrom, curTo, prevFrom, prevTo = PeriodRanges("month", now)
+		report.Label, report.PreviousLabel = "เดือนนี้", "เดือนที่แล้ว"
+		report.HasLastYear = true
+	}
+	report.From = curFrom.Format("2006-01-02")
+	report.To = curTo.Format("2006-01-02")
+
+	current, err := s.getIncomeBreakdown(ctx, lineID, curFrom, curTo)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.getIncomeBreakdown(ctx, lineID, prevFrom, prevTo)
+	if err != nil {
+		return nil, err
+	}
+	lastYear := &incomeBreakdown{byCategory: map[string]float64{}, byDestination: map[string]float64{}}
+	if report.HasLastYear {
+		lastYear, err = s.getIncomeBreakdown(ctx, lineID, curFrom.AddDate(-1, 0, 0), curTo.AddDate(-1, 0, 0))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	report.Total = current.total
+	report.TransactionCount = current.count
+	report.PreviousTotal = previous.total
+	report.LastYearTotal = lastYear.total
+	report.ByCategory = mergeIncomeSources(current.byCategory, previous.byCategory, lastYear.byCategory)
+	report.ByDestination = mergeIncomeSources(current.byDestination, previous.byDestination, lastYear.byDestination)
+
+	return report, nil
+}
+
+// mergeIncomeSources combines current/previous/last-year maps into sorted sources
+func mergeIncomeSources(current, previous, lastYear map[string]float64) []IncomeSource {
+	names := make(map[string]bool)
+	for name := range current {
+		names[name] = true
+	}
+	for name := range previous {
+		names[name] = true
+	}
+	for name := range lastYear {
+		names[name] = true
+	}
+
+	sources := make([]IncomeSource, 0, len(names))
+	for name := range names {
+		sources = append(sources, IncomeSource{
+			Name:     name,
+			Amount:   current[name],
+			Previous: previous[name],
+			LastYear: lastYear[name],
+		})
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		if sources[i].Amount != sources[j].Amount {
+			return sources[i].Amount > sources[j].Amount
+		}
+		return sources[i].Previous > sources[j].Previous
+	})
+	return sources
+}
+
+// ToAIText returns compact income report text for AI context
+// Format: "รายได้เดือนนี้:30000|ก่อน:28000|ปีก่อน:25000|เงินเดือน:25000/25000|..."
+func (r *IncomeReport) ToAIText() string {
+	parts := []string{
+		fmt.Sprintf("รายได้%s:%.0f", r.Label, r.Total),
+		fmt.Sprintf("%s:%.0f", r.PreviousLabel, r.PreviousTotal),
+	}
+	if r.HasLastYear {
+		parts = append(parts, fmt.Sprintf("ปีก่อน:%.0f", r.LastYearTotal))
+	}
+	for i, src := range r.ByCategory {
+		if i >= 5 {
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s:%.0f/%.0f", src.Name, src.Amount, src.Previous))
+	}
+	return strings.Join(parts, "|")
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// InvestmentCategory marks money moved into investments: expense side = buy/DCA, income side = sell
+// Like transfers it changes cash/bank balances but is neither spending nor earning
+const InvestmentCategory = "ลงทุน"
+
+// investmentKeywords mark a purchase as an investment when AI calls it an expense
+var investmentKeywords = []string{
+	"ซื้อกองทุน", "กองทุนรวม", "dca", "ซื้อหุ้น", "rmf", "ssf", "thaiesg", "ลงทุน", "ออมทอง", "ซื้อทองคำแท่ง", "ซื้อคริปโต", "ซื้อ bitcoin",
+}
+
+// IsAssetMove reports whether tx moves money between the user's own accounts or assets
+// (transfer flag, investments), so it is left out of spending, income, budgets and analytics
+func (t Transaction) IsAssetMove() bool {
+	return t.IsTransfer || t.Category == InvestmentCategory
+}
+
+// IsInvestmentText reports whether the message describes an investment contribution
+func IsInvestmentText(text string) bool {
+	lower := strings.ToLower(text)
+	for _, kw := range investmentKeywords {
+		if strings.Contains(lower, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyInvestmentType turns expenses of an investment message into type "investment" (AI may call them expenses)
+// and fills the asset name from the description; returns true if any entry is an investment
+func ApplyInvestmentType(txs []TransactionData, text string) bool {
+	isInvestmentText := IsInvestmentText(text)
+	found := false
+	for i := range txs {
+		tx := &txs[i]
+		if tx.Type != "investment" && !(tx.Type == "expense" && isInvestmentText) {
+			continue
+		}
+		tx.Type = "investment"
+		tx.Category = InvestmentCategory
+		if tx.Merchant == "" {
+			tx.Merchant = tx.Description
+		}
+		found = true
+	}
+	return found
+}
+
+// InvestmentHolding is money put into one asset (fund, stock, gold) minus what was sold
+type InvestmentHolding struct {
+	Asset       string  `json:"asset"`
+	Contributed float64 `json:"contributed"`
+	Count       int     `json:"count"`     // number of buys
+	LastDate    string  `json:"last_date"` // YYYY-MM-DD of latest buy/sell
+}
+
+// investmentAsset returns the asset name of an investment entry
+func investmentAsset(tx Transaction) string {
+	for _, name := range []string{tx.CustName, tx.Description} {
+		if name = strings.TrimSpace(name); name != "" {
+			return name
+		}
+	}
+	return "อื่นๆ"
+}
+
+// GetInvestmentHoldings returns cost basis per asset, largest first (sells reduce it, never below zero)
+func (s *MongoDBService) GetInvestmentHoldings(ctx context.Context, lineID string) ([]InvestmentHolding, error) {
+	filter := bson.M{
+		"lineid": lineID,
+		"$or": []bson.M{
+			{"expenses.category": InvestmentCategory},
+			{"incomes.category": InvestmentCategory},
+		},
+	}
+	byAsset := make(map[string]*InvestmentHolding)
+	holding := func(name string) *InvestmentHolding {
+		key := strings.ToLower(name)
+		if byAsset[key] == nil {
+			byAsset[key] = &InvestmentHolding{Asset: name}
+		}
+		return byAsset[key]
+	}
+	// Cost basis goes back to the first buy, so archived days are read too
+	err := s.eachDailyRecord(ctx, lineID, "", filter, nil, true, func(record DailyRecord) error {
+		for _, tx := range record.Expenses {
+			if tx.Category != InvestmentCategory {
+				continue
+			}
+			h := holding(investmentAsset(tx))
+			h.Contributed += tx.Amount
+			h.Count++
+			if record.Date > h.LastDate {
+				h.LastDate = record.Date
+			}
+		}
+		for _, tx := range record.Incomes {
+			if tx.Category != InvestmentCategory {
+				continue
+			}
+			h := holding(investmentAsset(tx))
+			h.Contributed -= tx.Amount
+			if record.Date > h.LastDate {
+				h.LastDate = record.Date
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var holdings []InvestmentHolding
+	for _, h := range byAsset {
+		if h.Contributed <= 0 {
+			continue
+		}
+		holdings = append(holdings, *h)
+	}
+	sort.Slice(holdings, func(i, j int) bool { return holdings[i].Contributed > holdings[j].Contributed })
+	return holdings, nil
+}
+
+// TotalInvested sums cost basis of all holdings
+func TotalInvested(holdings []InvestmentHolding) float64 {
+	total := 0.0
+	for _, h := range holdings {
+		total += h.Contributed
+	}
+	return total
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var liffHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// LIFFChannelID returns LINE Login channel ID from LIFF ID ("1234567890-AbCdEfGh")
+func LIFFChannelID(liffID string) string {
+	return strings.SplitN(liffID, "-", 2)[0]
+}
+
+// VerifyLIFFAccessToken checks LIFF access token belongs to our channel and returns user ID
+func VerifyLIFFAccessToken(ctx context.Context, accessToken, channelID string) (string, error) {
+	if accessToken == "" {
+		return "", fmt.Errorf("missing access token")
+	}
+
+	// 1. Token must be issued for our LINE Login channel and not expired
+	var verify struct {
+		ClientID  string `json:"client_id"`
+		ExpiresIn int    `json:"expires_in"`
+	}
+	verifyURL := "https://api.line.me/oauth2/v2.1/verify?access_token=" + url.QueryEscape(accessToken)
+	if err := liffGetJSON(ctx, verifyURL, "", &verify); err != nil {
+		return "", fmt.Errorf("invalid access token: %w", err)
+	}
+	if verify.ClientID != channelID || verify.ExpiresIn <= 0 {
+		return "", fmt.Errorf("access token is not for this channel")
+	}
+
+	// 2. Profile gives the user ID
+	var profile struct {
+		UserID string `json:"userId"`
+	}
+	if err := liffGetJSON(ctx, "https://api.line.me/v2/profile", accessToken, &profile); err != nil {
+		return "", fmt.Errorf("failed to get profile: %w", err)
+	}
+	if profile.UserID == "" {
+		return "", fmt.Errorf("empty user ID")
+	}
+	return profile.UserID, nil
+}
+
+// liffGetJSON performs GET (with optional bearer token) and decodes JSON
+func liffGetJSON(ctx context.Context, endpoint, bearer string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	resp, err := liffHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/width"
+)
+
+// knownMerchantChains maps spellings OCR and users produce (Thai, English, abbreviations) to one chain name
+// Keys are matchable forms (see merchantKey); the first spelling that prefixes a name wins
+var knownMerchantChains = []struct {
+	name      string
+	spellings []string
+}{
+	{"7-Eleven", []string{"7 eleven", "7 11", "seven eleven", "7eleven", "เซเว่นอีเลฟเว่น", "เซเว่น อีเลฟเว่น", "เซเว่น", "cp all"}},
+	{"Lotus's", []string{"lotus s", "lotuss", "lotus", "tesco lotus", "เทสโก้ โลตัส", "เทสโก้โลตัส", "โลตัส"}},
+	{"Big C", []string{"big c", "bigc", "บิ๊กซี", "บิ๊ก ซี"}},
+	{"Makro", []string{"makro", "siam makro", "แม็คโคร", "แมคโคร", "สยามแม็คโคร"}},
+	{"FamilyMart", []string{"family mart", "familymart", "แฟมิลี่มาร์ท", "แฟมิลี่ มาร์ท"}},
+	{"Tops", []string{"tops market", "tops daily", "tops", "ท็อปส์"}},
+	{"Villa Market", []string{"villa market", "วิลล่า มาร์เก็ต"}},
+	{"Starbucks", []string{"starbucks", "สตาร์บัคส์", "สตาร์บัค"}},
+	{"Café Amazon", []string{"cafe amazon", "café amazon", "คาเฟ่ อเมซอน", "คาเฟ่อเมซอน", "อเมซอน"}},
+	{"McDonald's", []string{"mcdonald s", "mcdonalds", "mcdonald", "แมคโดนัลด์"}},
+	{"KFC", []string{"kfc", "เคเอฟซี"}},
+	{"MK", []string{"mk restaurant", "mk suki", "เอ็มเค"}},
+	{"Swensen's", []string{"swensen s", "swensens", "สเวนเซ่นส์"}},
+	{"PTT Station", []string{"ptt station", "ptt", "ปตท"}},
+	{"Shell", []string{"shell", "เชลล์"}},
+	{"Bangchak", []string{"bangchak", "บางจาก"}},
+	{"Central", []string{"central department", "central", "เซ็นทรัล"}},
+	{"Robinson", []string{"robinson", "โรบินสัน"}},
+	{"Watsons", []string{"watsons", "วัตสัน"}},
+	{"Boots", []string{"boots", "บู๊ทส์"}},
+	{"HomePro", []string{"homepro", "home pro", "โฮมโปร"}},
+	{"IKEA", []string{"ikea", "อิเกีย"}},
+	{"Uniqlo", []string{"uniqlo", "ยูนิโคล่"}},
+	{"Grab", []string{"grabfood", "grab food", "grab", "แกร็บ"}},
+	{"LINE MAN", []string{"line man", "lineman", "ไลน์แมน"}},
+	{"foodpanda", []string{"foodpanda", "food panda", "ฟู้ดแพนด้า"}},
+	{"Shopee", []string{"shopee", "ช้อปปี้"}},
+	{"Lazada", []string{"lazada", "ลาซาด้า"}},
+}
+
+// merchantBranchPatterns strip branch/outlet markers OCR copies from receipt headers
+var merchantBranchPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\s*[\(\[]?\s*(?:สาขา(?:ที่)?|\bbranch|\bbr\.|\bstore|\bshop)\s*(?:no\.?|#)?\s*[:\-]?\s*[\w\-/]*\d[\w\-/]*\s*[\)\]]?`),
+	regexp.MustCompile(`(?i)\s*[\(\[]?\s*(?:สำนักงานใหญ่|\bhead\s*office\b|\bhq\b)\s*[\)\]]?`),
+	regexp.MustCompile(`(?i)\s*(?:#|no\.)\s*\d+\s*$`),
+	regexp.MustCompile(`\s*[\(\[]\s*\d+\s*[\)\]]\s*$`),
+}
+
+// merchantKey is a comparable form: full-width folded, lower case, punctuation as single spaces
+func merchantKey(name string) string {
+	name = strings.ToLower(width.Fold.String(name))
+	var b strings.Builder
+	space := false
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) {
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteRune(r)
+			space = false
+			continue
+		}
+		space = true
+	}
+	return b.String()
+}
+
+// KnownMerchant returns the chain name of a merchant spelling ("เซเว่น", "7-11 สาขา 1234" -> "7-Eleven"),
+// "" when it isn't a known chain
+func KnownMerchant(name string) string {
+	key := merchantKey(stripMerchantBranch(name))
+	if key == "" {
+		return ""
+	}
+	for _, chain := range knownMerchantChains {
+		for _, spelling := range chain.spellings {
+			s := merchantKey(spelling)
+			if key == s || strings.HasPrefix(key, s+" ") || (!isASCII(s) && strings.HasPrefix(key, s)) {
+				return chain.name
+			}
+		}
+	}
+	return ""
+}
+
+// NormalizeMerchant returns the merchant name used for search and analytics: a known chain's name, or the
+// raw name without branch numbers, extra spaces and ALL-CAPS ("STARBUCKS #123" -> "Starbucks",
+// "SOMTAM NUA (สาขา 2)" -> "Somtam Nua")
+func NormalizeMerchant(raw string) string {
+	if chain := KnownMerchant(raw); chain != "" {
+		return chain
+	}
+	name := strings.Join(strings.Fields(width.Fold.String(stripMerchantBranch(raw))), " ")
+	name = strings.Trim(name, " -,.:")
+	if name != "" && name == strings.ToUpper(name) && isASCII(name) {
+		words := strings.Fields(strings.ToLower(name))
+		for i, w := range words {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+		name = strings.Join(words, " ")
+	}
+	return name
+}
+
+// stripMerchantBranch removes branch/head-office markers
+func stripMerchantBranch(name string) string {
+	for _, p := range merchantBranchPatterns {
+		name = p.ReplaceAllString(name, "")
+	}
+	return strings.TrimSpace(name)
+}
+
+// isASCII reports whether s has only ASCII characters
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
+
+// MerchantName returns the normalized merchant, falling back to the raw name of entries saved before
+// normalization
+func (t Transaction) MerchantName() string {
+	if t.Merchant != "" {
+		return t.Merchant
+	}
+	return strings.TrimSpace(t.CustName)
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -28,6 +31,7 @@ type DailyRecord struct {
 	TotalExpense   float64            `bson:"totalExpense" json:"totalExpense"`
 	CreatedAt      time.Time          `bson:"createdAt" json:"createdAt"`
 	UpdatedAt      time.Time          `bson:"updatedAt" json:"updatedAt"`
+	Archived       bool               `bson:"archived,omitempty" json:"archived,omitempty"` // Moved to daily_records_archive (read-only)
 }
 
 // ChatMessage represents a chat history message
@@ -50,6 +54,7 @@ type Transaction struct {
 	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Type           int                `bson:"type" json:"type"` // 1 = income, -1 = expense
 	CustName       string             `bson:"custname" json:"custname"`
+	Merchant       string             `bson:"merchant,omitempty" json:"merchant,omitempty"` // Normalized shop name (see NormalizeMerchant); CustName keeps what was read
 	Amount         float64            `bson:"amount" json:"amount"`
 	Category       string             `bson:"category" json:"category"`
 	Description    string             `bson:"description" json:"description"`
@@ -57,7 +62,19 @@ type Transaction struct {
 	UseType        int                `bson:"usetype" json:"usetype"` // 0=เงินสด, 1=บัตรเครดิต, 2=ธนาคาร
 	BankName       string             `bson:"bankname" json:"bankname"`
 	CreditCardName string             `bson:"creditcardname" json:"creditcardname"`
-	TransferID     string             `bson:"transfer_id" json:"transfer_id"` // link to transfers collection
+	TransferID     string             `bson:"transfer_id" json:"transfer_id"`                     // link to transfers collection
+	IsTransfer     bool               `bson:"is_transfer,omitempty" json:"is_transfer,omitempty"` // one side of a transfer, not income/expense
+	VATAmount      float64            `bson:"vat,omitempty" json:"vat,omitempty"`
+	ServiceCharge  float64            `bson:"service_charge,omitempty" json:"service_charge,omitempty"`
+	TaxID          string             `bson:"tax_id,omitempty" json:"tax_id,omitempty"`         // เลขผู้เสียภาษีของร้าน
+	ReceiptNo      string             `bson:"receipt_no,omitempty" json:"receipt_no,omitempty"` // เลขที่ใบเสร็จ
+	Source         string             `bson:"source,omitempty" json:"source,omitempty"`         // "image", "sms" ("" = typed)
+	NeedsReview    bool               `bson:"needs_review,omitempty" json:"needs_review,omitempty"`
+	SlipStatus     string             `bson:"slip_status,omitempty" json:"slip_status,omitempty"`       // slip verification result
+	Currency       string             `bson:"currency,omitempty" json:"currency,omitempty"`             // paid in this foreign currency, Amount is THB
+	ForeignAmount  float64            `bson:"foreign_amount,omitempty" json:"foreign_amount,omitempty"` // amount in Currency
+	FXRate         float64            `bson:"fx_rate,omitempty" json:"fx_rate,omitempty"`               // THB per 1 unit of Currency used
+	Trip           string             `bson:"trip,omitempty" json:"trip,omitempty"`                     // Trip.Tag() of travel mode
 	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
 }
 
@@ -104,13 +121,55 @@ type BudgetStatus struct {
 }
 
 type MongoDBService struct {
-	client             *mongo.Client
-	database           *mongo.Database
-	collection         *mongo.Collection
-	chatCollection     *mongo.Collection
-	transferCollection *mongo.Collection
-	budgetCollection   *mongo.Collection
-	tempCollection     *mongo.Collection
+	client                  *mongo.Client
+	database                *mongo.Database
+	collection              *mongo.Collection
+	chatCollection          *mongo.Collection
+	chatArchiveCollection   *mongo.Collection
+	transferCollection      *mongo.Collection
+	budgetCollection        *mongo.Collection
+	tempCollection          *mongo.Collection
+	subscriptionCollection  *mongo.Collection
+	categoryStyleCollection *mongo.Collection
+	settingsCollection      *mongo.Collection
+	downloadCollection      *mongo.Collection
+	downloadEventCollection *mongo.Collection
+	webhookCollection       *mongo.Collection
+	sheetsCollection        *mongo.Collection
+	cardCollection          *mongo.Collection
+	notificationCollection  *mongo.Collection
+	guardrailCollection     *mongo.Collection
+	securityCollection      *mongo.Collection
+	snapshotCollection      *mongo.Collection
+	snapshotStateCollection *mongo.Collection
+	analyticsCollection     *mongo.Collection
+	featureFlagCollection   *mongo.Collection
+	profileCollection       *mongo.Collection
+	paymentRuleCollection   *mongo.Collection
+	approvalCollection      *mongo.Collection
+	warrantyCollection      *mongo.Collection
+	shareCollection         *mongo.Collection
+	benchmarkCollection     *mongo.Collection
+	projectionCollection    *mongo.Collection
+	archiveCollection       *mongo.Collection
+	syncCollection          *mongo.Collection
+	syncCounterCollection   *mongo.Collection
+	hashChainCollection     *mongo.Collection
+	hashPendingCollection   *mongo.Collection
+	hashChainQueue          OrderedQueues
+	snapshotQueue           OrderedQueues
+	distinctCache           sync.Map // lineID -> *distinctNames (AI schema names)
+	rankingProfiles         sync.Map // lineID -> struct{} while names are being re-ranked
+	balanceContexts         sync.Map // lineID -> *BalanceContext (AI balance context)
+	projecting              sync.Map // lineID -> struct{} while this instance runs the user's projection builds
+	hashChainFlags          sync.Map // lineID -> hashChainFlag (hashing opt-in)
+	syncQueues              sync.Map // lineID -> *orderedQueue (changes waiting for a sync seq)
+	balanceContextMu        sync.Mutex
+	noTransactions          atomic.Bool // standalone server: transfers use the compensating fallback
+	chatHistoryLimit        int         // messages kept in chat_history for AI context
+	hashChainKey            []byte      // server secret the hash chain is keyed with
+	txHooks                 []TransactionHook
+	securityHooks           []SecurityAlertHook
 }
 
 func NewMongoDBService(uri, dbName string) (*MongoDBService, error) {
@@ -132,24 +191,116 @@ func NewMongoDBService(uri, dbName string) (*MongoDBService, error) {
 	database := client.Database(dbName)
 	collection := database.Collection("daily_records")
 	chatCollection := database.Collection("chat_history")
+	chatArchiveCollection := database.Collection("chat_archive")
 	transferCollection := database.Collection("transfers")
 	budgetCollection := database.Collection("budgets")
 	tempCollection := database.Collection("temp_data")
-
-	return &MongoDBService{
-		client:             client,
-		database:           database,
-		collection:         collection,
-		chatCollection:     chatCollection,
-		transferCollection: transferCollection,
-		budgetCollection:   budgetCollection,
-		tempCollection:     tempCollection,
-	}, nil
+	subscriptionCollection := database.Collection("subscriptions")
+	categoryStyleCollection := database.Collection("category_styles")
+	settingsCollection := database.Collection("user_settings")
+	downloadCollection := database.Collection("download_links")
+	downloadEventCollection := database.Collection("download_events")
+	webhookCollection := database.Collection("webhook_endpoints")
+	sheetsCollection := database.Collection("sheets_connections")
+	cardCollection := database.Collection("card_accounts")
+	notificationCollection := database.Collection("notification_log")
+	guardrailCollection := database.Collection("guardrails")
+	securityCollection := database.Collection("security_events")
+	snapshotCollection := database.Collection("balance_snapshots")
+	snapshotStateCollection := database.Collection("balance_snapshot_state")
+	analyticsCollection := database.Collection("analytics_events")
+	featureFlagCollection := database.Collection("feature_flags")
+	profileCollection := database.Collection("user_profiles")
+	paymentRuleCollection := database.Collection("payment_rules")
+	approvalCollection := database.Collection("spending_approvals")
+	warrantyCollection := database.Collection("warranties")
+	shareCollection := database.Collection("share_links")
+	benchmarkCollection := database.Collection("peer_benchmarks")
+	projectionCollection := database.Collection("user_projections")
+	archiveCollection := database.Collection("daily_records_archive")
+	syncCollection := database.Collection("sync_changes")
+	syncCounterCollection := database.Collection("sync_counters")
+	hashChainCollection := database.Collection("tx_hash_chain")
+	hashPendingCollection := database.Collection("tx_hash_chain_pending")
+
+	s := &MongoDBService{
+		client:                  client,
+		database:                database,
+		collection:              collection,
+		chatCollection:          chatCollection,
+		chatArchiveCollection:   chatArchiveCollection,
+		chatHistoryLimit:        defaultChatHistoryLimit,
+		transferCollection:      transferCollection,
+		budgetCollection:        budgetCollection,
+		tempCollection:          tempCollection,
+		subscriptionCollection:  subscriptionCollection,
+		categoryStyleCollection: categoryStyleCollection,
+		settingsCollection:      settingsCollection,
+		downloadCollection:      downloadCollection,
+		downloadEventCollection: downloadEventCollection,
+		webhookCollection:       webhookCollection,
+		sheetsCollection:        sheetsCollection,
+		cardCollection:          cardCollection,
+		notificationCollection:  notificationCollection,
+		guardrailCollection:     guardrailCollection,
+		securityCollection:      securityCollection,
+		snapshotCollection:      snapshotCollection,
+		snapshotStateCollection: snapshotStateCollection,
+		analyticsCollection:     analyticsCollection,
+		featureFlagCollection:   featureFlagCollection,
+		profileCollection:       profileCollection,
+		paymentRuleCollection:   paymentRuleCollection,
+		approvalCollection:      approvalCollection,
+		warrantyCollection:      warrantyCollection,
+		shareCollection:         shareCollection,
+		benchmarkCollection:     benchmarkCollection,
+		projectionCollection:    projectionCollection,
+		archiveCollection:       archiveCollection,
+		syncCollection:          syncCollection,
+		syncCounterCollection:   syncCounterCollection,
+		hashChainCollection:     hashChainCollection,
+		hashPendingCollection:   hashPendingCollection,
+	}
+	// Backdated entries change every later closing balance
+	s.AddTransactionHook(s.invalidateBalanceSnapshots)
+	// New bank/card/category names must reach the AI schema right away
+	s.AddTransactionHook(s.invalidateDistinctNames)
+	s.AddTransactionHook(s.updateProfileFromTransaction)
+	s.AddTransactionHook(s.markReviewedOnEdit)
+	// Follow-up messages in the same conversation must see what was just saved
+	s.AddTransactionHook(s.patchBalanceContext)
+	// Balance/summary replies read the precomputed projection
+	s.AddTransactionHook(s.onProjectionTransaction)
+	// Change feed of the sync API
+	s.AddTransactionHook(s.onSyncTransaction)
+	// Opt-in tamper evidence for exported statements
+	s.AddTransactionHook(s.recordHashChain)
+	return s, nil
 }
 
 // SaveTransaction saves a transaction to the daily record
 func (s *MongoDBService) SaveTransaction(ctx context.Context, lineID string, tx *TransactionData) (string, error) {
-	today := time.Now().Format("2006-01-02")
+	return s.SaveTransactionOnDate(ctx, lineID, tx, time.Now().Format("2006-01-02"))
+}
+
+// SaveTransactionOnDate saves a transaction to the daily record of given date (YYYY-MM-DD)
+// A child account's expense above its cap is held for the parent instead (*SpendingHeldError)
+func (s *MongoDBService) SaveTransactionOnDate(ctx context.Context, lineID string, tx *TransactionData, date string) (string, error) {
+	settings, err := s.GetUserSettings(ctx, lineID)
+	if err != nil {
+		return "", err
+	}
+	if err := periodOpen(settings, date); err != nil {
+		return "", err
+	}
+	if err := s.holdOverCap(ctx, lineID, settings, tx, date); err != nil {
+		return "", err
+	}
+	return s.saveTransactionOnDate(ctx, lineID, tx, date)
+}
+
+// saveTransactionOnDate writes a transaction whose period and cap were already checked
+func (s *MongoDBService) saveTransactionOnDate(ctx context.Context, lineID string, tx *TransactionData, date string) (string, error) {
 	currentTime := time.Now().Format("15:04")
 
 	// Determine transaction type
@@ -158,23 +309,49 @@ func (s *MongoDBService) SaveTransaction(ctx context.Context, lineID string, tx
 		txType = 1
 	}
 
+	// Business mode: project/customer takes CustName, merchant falls back to description
+	custName := tx.Merchant
+	description := tx.Description
+	category := tx.Category
+	if tx.Type == "investment" {
+		category = InvestmentCategory // Asset name stays in CustName
+	}
+	if tx.Project != "" {
+		custName = tx.Project
+		if description == "" {
+			description = tx.Merchant
+		}
+	}
+
 	newTx := Transaction{
 		ID:             primitive.NewObjectID(),
 		Type:           txType,
-		CustName:       tx.Merchant,
+		CustName:       custName,
+		Merchant:       NormalizeMerchant(tx.Merchant),
 		Amount:         tx.Amount,
-		Category:       tx.Category,
-		Description:    tx.Description,
+		Category:       category,
+		Description:    description,
 		UseType:        tx.UseType,
 		BankName:       tx.BankName,
 		CreditCardName: tx.CreditCardName,
+		VATAmount:      tx.VATAmount,
+		ServiceCharge:  tx.ServiceCharge,
+		TaxID:          tx.TaxID,
+		ReceiptNo:      tx.ReceiptNo,
+		Source:         tx.Source,
+		NeedsReview:    tx.Source != "" || tx.SlipStatus == SlipSuspicious, // Auto-captured or doubtful until user confirms or edits it
+		SlipStatus:     tx.SlipStatus,
+		Currency:       tx.Currency,
+		ForeignAmount:  tx.ForeignAmount,
+		FXRate:         tx.FXRate,
+		Trip:           tx.Trip,
 		CreatedAt:      time.Now(),
 	}
 
 	// Find or create daily record
 	filter := bson.M{
 		"lineid": lineID,
-		"date":   today,
+		"date":   date,
 	}
 
 	var record DailyRecord
@@ -184,7 +361,7 @@ func (s *MongoDBService) SaveTransaction(ctx context.Context, lineID string, tx
 		// Create new daily record
 		record = DailyRecord{
 			LineID:    lineID,
-			Date:      today,
+			Date:      date,
 			Time:      currentTime,
 			Incomes:   []Transaction{},
 			Expenses:  []Transaction{},
@@ -204,6 +381,7 @@ func (s *MongoDBService) SaveTransaction(ctx context.Context, lineID string, tx
 		if err != nil {
 			return "", fmt.Errorf("failed to insert daily record: %w", err)
 		}
+		s.notifyTransaction(TransactionCreated, lineID, date, newTx)
 		return newTx.ID.Hex(), nil
 	} else if err != nil {
 		return "", fmt.Errorf("failed to find daily record: %w", err)
@@ -230,116 +408,132 @@ func (s *MongoDBService) SaveTransaction(ctx context.Context, lineID string, tx
 		return "", fmt.Errorf("failed to update daily record: %w", err)
 	}
 
+	s.notifyTransaction(TransactionCreated, lineID, date, newTx)
 	return newTx.ID.Hex(), nil
 }
 
-// DeleteTransaction removes a transaction from the daily record
+// DeleteTransaction removes a transaction from today's record
 func (s *MongoDBService) DeleteTransaction(ctx context.Context, lineID, txID string) error {
+	return s.DeleteTransactionOnDate(ctx, lineID, txID, time.Now().Format("2006-01-02"))
+}
+
+// DeleteTransactionOnDate removes a transaction from the record of date (YYYY-MM-DD)
+func (s *MongoDBService) DeleteTransactionOnDate(ctx context.Context, lineID, txID, date string) error {
+	return s.DeleteTransactionOfKind(ctx, lineID, txID, date, "")
+}
+
+// DeleteTransactionOfKind removes a transaction from the record of date, looking in the array of kind
+// (TransactionKindIncome/Expense) first
+func (s *MongoDBService) DeleteTransactionOfKind(ctx context.Context, lineID, txID, date, kind string) error {
 	objectID, err := primitive.ObjectIDFromHex(txID)
 	if err != nil {
 		return fmt.Errorf("invalid transaction ID: %w", err)
 	}
-
-	today := time.Now().Format("2006-01-02")
-	filter := bson.M{
-		"lineid": lineID,
-		"date":   today,
+	if err := s.checkPeriodOpen(ctx, lineID, date); err != nil {
+		return err
 	}
 
-	// Try to find and remove from incomes
-	updateIncome := bson.M{
-		"$pull": bson.M{"incomes": bson.M{"_id": objectID}},
-		"$set":  bson.M{"updatedAt": time.Now()},
-	}
+	// Keep a copy for hooks (snapshots, webhooks) before it's gone
+	deleted, _ := s.GetTransactionOnDate(ctx, lineID, txID, date)
 
-	result, err := s.collection.UpdateOne(ctx, filter, updateIncome)
-	if err != nil {
-		return fmt.Errorf("failed to delete from incomes: %w", err)
+	filter := bson.M{
+		"lineid": lineID,
+		"date":   date,
 	}
 
-	if result.ModifiedCount == 0 {
-		// Try to remove from expenses
-		updateExpense := bson.M{
-			"$pull": bson.M{"expenses": bson.M{"_id": objectID}},
+	for _, field := range TransactionFields(kind) {
+		result, err := s.collection.UpdateOne(ctx, filter, bson.M{
+			"$pull": bson.M{field: bson.M{"_id": objectID}},
 			"$set":  bson.M{"updatedAt": time.Now()},
-		}
-
-		_, err = s.collection.UpdateOne(ctx, filter, updateExpense)
+		})
 		if err != nil {
-			return fmt.Errorf("failed to delete from expenses: %w", err)
+			return fmt.Errorf("failed to delete from %s: %w", field, err)
+		}
+		if result.ModifiedCount > 0 {
+			break
 		}
 	}
 
 	// Recalculate totals
-	return s.recalculateTotals(ctx, lineID, today)
+	if err := s.recalculateTotals(ctx, lineID, date); err != nil {
+		return err
+	}
+	if deleted != nil {
+		s.notifyTransaction(TransactionDeleted, lineID, date, *deleted)
+	}
+	return nil
 }
 
+// recalculateTotals sets totalIncome/totalExpense of one day from its embedded transactions
+// in a single update, so a concurrent $push/$inc can't be lost between read and write
 func (s *MongoDBService) recalculateTotals(ctx context.Context, lineID, date string) error {
 	filter := bson.M{
 		"lineid": lineID,
 		"date":   date,
 	}
 
-	var record DailyRecord
-	if err := s.collection.FindOne(ctx, filter).Decode(&record); err != nil {
+	result, err := s.collection.UpdateOne(ctx, filter, recordTotalsUpdate())
+	if err != nil {
 		return err
 	}
-
-	var totalIncome, totalExpense float64
-	for _, tx := range record.Incomes {
-		totalIncome += tx.Amount
-	}
-	for _, tx := range record.Expenses {
-		totalExpense += tx.Amount
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
 	}
-
-	update := bson.M{
-		"$set": bson.M{
-			"totalIncome":  totalIncome,
-			"totalExpense": totalExpense,
-			"updatedAt":    time.Now(),
-		},
-	}
-
-	_, err := s.collection.UpdateOne(ctx, filter, update)
-	return err
+	return nil
 }
 
 // BalanceSummary represents the balance information
 type BalanceSummary struct {
-	TotalIncome  float64 `json:"totalIncome"`
-	TotalExpense float64 `json:"totalExpense"`
-	Balance      float64 `json:"balance"`
-	TodayIncome  float64 `json:"todayIncome"`
-	TodayExpense float64 `json:"todayExpense"`
-	TodayBalance float64 `json:"todayBalance"`
+	TotalIncome   float64 `json:"totalIncome"`
+	TotalExpense  float64 `json:"totalExpense"`
+	TotalInvested float64 `json:"totalInvested"` // net money moved into investments (not an expense)
+	Balance       float64 `json:"balance"`
+	TodayIncome   float64 `json:"todayIncome"`
+	TodayExpense  float64 `json:"todayExpense"`
+	TodayBalance  float64 `json:"todayBalance"`
 }
 
 // GetBalanceSummary returns the balance summary for a user
-// Note: Excludes "โอนเงิน" (transfers) as they don't affect actual balance
+// Note: Excludes transfers (IsTransfer) as they don't affect actual balance
+// Investments are kept out of income/expense but still leave the balance
 func (s *MongoDBService) GetBalanceSummary(ctx context.Context, lineID string) (*BalanceSummary, error) {
 	today := time.Now().Format("2006-01-02")
+	settings, err := s.GetUserSettings(ctx, lineID)
+	if err != nil {
+		return nil, err
+	}
 
-	// Get all records for this user
-	filter := bson.M{"lineid": lineID}
+	// Get all records for this user (archived days come from the carry)
+	filter := hotRecordFilter(bson.M{"lineid": lineID}, settings.ArchivedThrough)
 	cursor, err := s.collection.Find(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find records: %w", err)
 	}
 	defer cursor.Close(ctx)
 
-	var totalIncome, totalExpense float64
+	var totalIncome, totalExpense, totalInvested float64
 	var todayIncome, todayExpense float64
+	if carry := settings.ArchiveCarry; carry != nil {
+		totalIncome, totalExpense, totalInvested = carry.TotalIncome, carry.TotalExpense, carry.TotalInvested
+	}
+	var drifted []string
 
 	for cursor.Next(ctx) {
 		var record DailyRecord
 		if err := cursor.Decode(&record); err != nil {
 			continue
 		}
+		if HasTotalsDrift(record) {
+			drifted = append(drifted, record.Date)
+		}
 
 		// Calculate from individual transactions, excluding transfers
 		for _, tx := range record.Incomes {
-			if tx.Category == "โอนเงิน" {
+			if tx.Category == InvestmentCategory {
+				totalInvested -= tx.Amount // Sold back to cash/bank
+				continue
+			}
+			if tx.IsTransfer {
 				continue // Skip transfer income
 			}
 			totalIncome += tx.Amount
@@ -349,7 +543,11 @@ func (s *MongoDBService) GetBalanceSummary(ctx context.Context, lineID string) (
 		}
 
 		for _, tx := range record.Expenses {
-			if tx.Category == "โอนเงิน" {
+			if tx.Category == InvestmentCategory {
+				totalInvested += tx.Amount
+				continue
+			}
+			if tx.IsTransfer {
 				continue // Skip transfer expense
 			}
 			totalExpense += tx.Amount
@@ -358,14 +556,16 @@ func (s *MongoDBService) GetBalanceSummary(ctx context.Context, lineID string) (
 			}
 		}
 	}
+	s.repairTotalsAsync(lineID, drifted)
 
 	return &BalanceSummary{
-		TotalIncome:  totalIncome,
-		TotalExpense: totalExpense,
-		Balance:      totalIncome - totalExpense,
-		TodayIncome:  todayIncome,
-		TodayExpense: todayExpense,
-		TodayBalance: todayIncome - todayExpense,
+		TotalIncome:   totalIncome,
+		TotalExpense:  totalExpense,
+		TotalInvested: totalInvested,
+		Balance:       totalIncome - totalExpense - totalInvested,
+		TodayIncome:   todayIncome,
+		TodayExpense:  todayExpense,
+		TodayBalance:  todayIncome - todayExpense,
 	}, nil
 }
 
@@ -383,7 +583,7 @@ func (s *MongoDBService) SaveChatMessage(ctx context.Context, lineID, role, cont
 		"$push": bson.M{
 			"messages": bson.M{
 				"$each":  []ChatMessage{msg},
-				"$slice": -20, // Keep only last 20 messages
+				"$slice": -s.chatHistoryLimit, // Older messages stay in chat_archive
 			},
 		},
 		"$set": bson.M{
@@ -395,8 +595,12 @@ func (s *MongoDBService) SaveChatMessage(ctx context.Context, lineID, role, cont
 	}
 
 	opts := options.Update().SetUpsert(true)
-	_, err := s.chatCollection.UpdateOne(ctx, filter, update, opts)
-	return err
+	if _, err := s.chatCollection.UpdateOne(ctx, filter, update, opts); err != nil {
+		return err
+	}
+
+	// Full history for transcripts (live window above stays small for AI context)
+	return s.archiveChatMessage(ctx, lineID, msg)
 }
 
 // GetChatHistory returns recent chat messages for a user
@@ -412,6 +616,13 @@ func (s *MongoDBService) GetChatHistory(ctx context.Context, lineID string, limi
 		return nil, err
 	}
 
+	// Beyond the live window, read the archive instead
+	if limit > s.chatHistoryLimit && len(userChat.Messages) >= s.chatHistoryLimit {
+		if archived, err := s.GetRecentChatArchive(ctx, lineID, limit); err == nil && len(archived) > len(userChat.Messages) {
+			return archived, nil
+		}
+	}
+
 	// Return last N messages
 	messages := userChat.Messages
 	if len(messages) > limit {
@@ -450,19 +661,71 @@ func (s *MongoDBService) GetLastTransaction(ctx context.Context, lineID string)
 	return nil, "", fmt.Errorf("no transactions found")
 }
 
+// GetRecentTransactions returns the latest transactions across days (newest first), transfers excluded
+func (s *MongoDBService) GetRecentTransactions(ctx context.Context, lineID string, limit int) ([]SearchResult, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: -1}}).SetLimit(31)
+	cursor, err := s.collection.Find(ctx, bson.M{"lineid": lineID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []SearchResult
+	for cursor.Next(ctx) && len(results) < limit {
+		var record DailyRecord
+		if err := cursor.Decode(&record); err != nil {
+			continue
+		}
+
+		var day []SearchResult
+		for _, list := range [][]Transaction{record.Incomes, record.Expenses} {
+			for _, tx := range list {
+				if tx.TransferID != "" {
+					continue
+				}
+				day = append(day, SearchResult{Transaction: tx, Date: record.Date, RecordID: record.ID.Hex()})
+			}
+		}
+		sort.SliceStable(day, func(i, j int) bool {
+			return day[i].Transaction.CreatedAt.After(day[j].Transaction.CreatedAt)
+		})
+		results = append(results, day...)
+	}
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
 // UpdateTransactionPayment updates the payment method of a transaction
 func (s *MongoDBService) UpdateTransactionPayment(ctx context.Context, lineID, txID string, useType int, bankName, creditCardName string) (*Transaction, error) {
+	today := time.Now().Format("2006-01-02")
+	if err := s.UpdateTransactionPaymentOnDate(ctx, lineID, txID, today, useType, bankName, creditCardName); err != nil {
+		return nil, err
+	}
+
+	// Return updated transaction
+	return s.GetTransactionByID(ctx, lineID, txID)
+}
+
+// UpdateTransactionPaymentOnDate updates the payment method of a transaction saved on date (YYYY-MM-DD)
+func (s *MongoDBService) UpdateTransactionPaymentOnDate(ctx context.Context, lineID, txID, date string, useType int, bankName, creditCardName string) error {
 	objectID, err := primitive.ObjectIDFromHex(txID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid transaction ID: %w", err)
+		return fmt.Errorf("invalid transaction ID: %w", err)
+	}
+	if err := s.checkPeriodOpen(ctx, lineID, date); err != nil {
+		return err
 	}
-
-	today := time.Now().Format("2006-01-02")
 
 	// Try updating in expenses
 	filter := bson.M{
 		"lineid":       lineID,
-		"date":         today,
+		"date":         date,
 		"expenses._id": objectID,
 	}
 
@@ -477,14 +740,14 @@ func (s *MongoDBService) UpdateTransactionPayment(ctx context.Context, lineID, t
 
 	result, err := s.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	if result.ModifiedCount == 0 {
 		// Try updating in incomes
 		filter = bson.M{
 			"lineid":      lineID,
-			"date":        today,
+			"date":        date,
 			"incomes._id": objectID,
 		}
 
@@ -497,29 +760,57 @@ func (s *MongoDBService) UpdateTransactionPayment(ctx context.Context, lineID, t
 			},
 		}
 
-		_, err = s.collection.UpdateOne(ctx, filter, update)
+		result, err = s.collection.UpdateOne(ctx, filter, update)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return fmt.Errorf("transaction not found")
 		}
 	}
-
-	// Return updated transaction
-	return s.GetTransactionByID(ctx, lineID, txID)
+	s.notifyTransactionUpdated(ctx, lineID, txID, date)
+	return nil
 }
 
-// UpdateTransactionAmount updates the amount of a transaction
+// UpdateTransactionAmount updates the amount of a transaction saved today
 func (s *MongoDBService) UpdateTransactionAmount(ctx context.Context, lineID, txID string, amount float64) error {
+	return s.UpdateTransactionAmountOnDate(ctx, lineID, txID, time.Now().Format("2006-01-02"), amount)
+}
+
+// UpdateTransactionAmountOnDate updates the amount of a transaction saved on date (YYYY-MM-DD)
+// A child account can't raise an expense above its cap (ErrSpendingCap)
+func (s *MongoDBService) UpdateTransactionAmountOnDate(ctx context.Context, lineID, txID, date string, amount float64) error {
 	objectID, err := primitive.ObjectIDFromHex(txID)
 	if err != nil {
 		return fmt.Errorf("invalid transaction ID: %w", err)
 	}
+	settings, err := s.GetUserSettings(ctx, lineID)
+	if err != nil {
+		return err
+	}
+	if err := periodOpen(settings, date); err != nil {
+		return err
+	}
+	if settings.IsRestricted() && amount > settings.SpendingCap {
+		n, err := s.collection.CountDocuments(ctx, bson.M{"lineid": lineID, "date": date, "expenses._id": objectID})
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return fmt.Errorf("%w: %.2f above %.2f", ErrSpendingCap, amount, settings.SpendingCap)
+		}
+	}
+	return s.updateTransactionAmount(ctx, lineID, objectID, date, amount)
+}
 
-	today := time.Now().Format("2006-01-02")
+// updateTransactionAmount writes a new amount whose period and cap were already checked
+func (s *MongoDBService) updateTransactionAmount(ctx context.Context, lineID string, objectID primitive.ObjectID, date string, amount float64) error {
+	txID := objectID.Hex()
 
 	// Try updating in expenses
 	filter := bson.M{
 		"lineid":       lineID,
-		"date":         today,
+		"date":         date,
 		"expenses._id": objectID,
 	}
 
@@ -539,7 +830,7 @@ func (s *MongoDBService) UpdateTransactionAmount(ctx context.Context, lineID, tx
 		// Try updating in incomes
 		filter = bson.M{
 			"lineid":      lineID,
-			"date":        today,
+			"date":        date,
 			"incomes._id": objectID,
 		}
 
@@ -557,20 +848,54 @@ func (s *MongoDBService) UpdateTransactionAmount(ctx context.Context, lineID, tx
 	}
 
 	// Recalculate totals
-	return s.recalculateTotals(ctx, lineID, today)
+	if err := s.recalculateTotals(ctx, lineID, date); err != nil {
+		return err
+	}
+	s.notifyTransactionUpdated(ctx, lineID, txID, date)
+	return nil
+}
+
+// UpdateTransactionDescriptionOnDate updates the description of a transaction saved on date (YYYY-MM-DD)
+func (s *MongoDBService) UpdateTransactionDescriptionOnDate(ctx context.Context, lineID, txID, date, description string) error {
+	objectID, err := primitive.ObjectIDFromHex(txID)
+	if err != nil {
+		return fmt.Errorf("invalid transaction ID: %w", err)
+	}
+	if err := s.checkPeriodOpen(ctx, lineID, date); err != nil {
+		return err
+	}
+
+	for _, field := range []string{"expenses", "incomes"} {
+		result, err := s.collection.UpdateOne(ctx,
+			bson.M{"lineid": lineID, "date": date, field + "._id": objectID},
+			bson.M{"$set": bson.M{field + ".$.description": description, "updatedAt": time.Now()}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount > 0 {
+			s.notifyTransactionUpdated(ctx, lineID, txID, date)
+			return nil
+		}
+	}
+	return fmt.Errorf("transaction not found")
 }
 
 // GetTransactionByID returns a transaction by its ID
 func (s *MongoDBService) GetTransactionByID(ctx context.Context, lineID, txID string) (*Transaction, error) {
+	return s.GetTransactionOnDate(ctx, lineID, txID, time.Now().Format("2006-01-02"))
+}
+
+// GetTransactionOnDate returns a transaction by its ID from the daily record of date (YYYY-MM-DD)
+func (s *MongoDBService) GetTransactionOnDate(ctx context.Context, lineID, txID, date string) (*Transaction, error) {
 	objectID, err := primitive.ObjectIDFromHex(txID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid transaction ID: %w", err)
 	}
 
-	today := time.Now().Format("2006-01-02")
 	filter := bson.M{
 		"lineid": lineID,
-		"date":   today,
+		"date":   date,
 	}
 
 	var record DailyRecord
@@ -613,119 +938,22 @@ type PaymentBalance struct {
 	Balance        float64 `json:"balance"`
 }
 
-// GetDistinctPaymentMethods returns unique banks and credit cards for a user
-func (s *MongoDBService) GetDistinctPaymentMethods(ctx context.Context, lineID string) ([]string, []string, error) {
-	filter := bson.M{"lineid": lineID}
-	cursor, err := s.collection.Find(ctx, filter)
-	if err != nil {
-		return nil, nil, err
-	}
-	defer cursor.Close(ctx)
-
-	bankSet := make(map[string]bool)
-	creditCardSet := make(map[string]bool)
-
-	for cursor.Next(ctx) {
-		var record DailyRecord
-		if err := cursor.Decode(&record); err != nil {
-			continue
-		}
-
-		// Check record-level payment info
-		if record.BankName != "" {
-			bankSet[record.BankName] = true
-		}
-		if record.CreditCardName != "" {
-			creditCardSet[record.CreditCardName] = true
-		}
-
-		// Check transaction-level payment info
-		for _, tx := range record.Incomes {
-			if tx.BankName != "" {
-				bankSet[tx.BankName] = true
-			}
-			if tx.CreditCardName != "" {
-				creditCardSet[tx.CreditCardName] = true
-			}
-		}
-		for _, tx := range record.Expenses {
-			if tx.BankName != "" {
-				bankSet[tx.BankName] = true
-			}
-			if tx.CreditCardName != "" {
-				creditCardSet[tx.CreditCardName] = true
-			}
-		}
-	}
-
-	banks := make([]string, 0, len(bankSet))
-	for bank := range bankSet {
-		banks = append(banks, bank)
-	}
-
-	creditCards := make([]string, 0, len(creditCardSet))
-	for cc := range creditCardSet {
-		creditCards = append(creditCards, cc)
-	}
-
-	return banks, creditCards, nil
-}
-
-// GetDistinctCategories returns unique categories for a user
-func (s *MongoDBService) GetDistinctCategories(ctx context.Context, lineID string) ([]string, []string, error) {
-	filter := bson.M{"lineid": lineID}
-	cursor, err := s.collection.Find(ctx, filter)
-	if err != nil {
-		return nil, nil, err
-	}
-	defer cursor.Close(ctx)
-
-	incomeCategories := make(map[string]bool)
-	expenseCategories := make(map[string]bool)
-
-	for cursor.Next(ctx) {
-		var record DailyRecord
-		if err := cursor.Decode(&record); err != nil {
-			continue
-		}
-
-		for _, tx := range record.Incomes {
-			if tx.Category != "" && tx.Category != "โอนเงิน" {
-				incomeCategories[tx.Category] = true
-			}
-		}
-		for _, tx := range record.Expenses {
-			if tx.Category != "" && tx.Category != "โอนเงิน" {
-				expenseCategories[tx.Category] = true
-			}
-		}
-	}
-
-	incomes := make([]string, 0, len(incomeCategories))
-	for cat := range incomeCategories {
-		incomes = append(incomes, cat)
-	}
-
-	expenses := make([]string, 0, len(expenseCategories))
-	for cat := range expenseCategories {
-		expenses = append(expenses, cat)
-	}
-
-	return incomes, expenses, nil
-}
-
 // GetBalanceByPaymentType returns balance breakdown by payment type
 // การคำนวณ: balance = sum(amount * type) โดย type=1 คือ income, type=-1 คือ expense
 func (s *MongoDBService) GetBalanceByPaymentType(ctx context.Context, lineID string) ([]PaymentBalance, error) {
-	filter := bson.M{"lineid": lineID}
+	settings, err := s.GetUserSettings(ctx, lineID)
+	if err != nil {
+		return nil, err
+	}
+	filter := hotRecordFilter(bson.M{"lineid": lineID}, settings.ArchivedThrough)
 	cursor, err := s.collection.Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	// Key: "usetype:bankname:creditcardname"
-	balanceMap := make(map[string]*PaymentBalance)
+	// Key: "usetype:bankname:creditcardname" (archived days start from the carry)
+	balanceMap := settings.ArchiveCarry.seedBalances()
 
 	for cursor.Next(ctx) {
 		var record DailyRecord
@@ -733,27 +961,8 @@ func (s *MongoDBService) GetBalanceByPaymentType(ctx context.Context, lineID str
 			continue
 		}
 
-		// Process all transactions (both incomes and expenses arrays)
-		allTx := append(record.Incomes, record.Expenses...)
-		for _, tx := range allTx {
-			key := fmt.Sprintf("%d:%s:%s", tx.UseType, tx.BankName, tx.CreditCardName)
-			if _, exists := balanceMap[key]; !exists {
-				balanceMap[key] = &PaymentBalance{
-					UseType:        tx.UseType,
-					BankName:       tx.BankName,
-					CreditCardName: tx.CreditCardName,
-				}
-			}
-			// คำนวณ: amount * type (type=1 รายรับ, type=-1 รายจ่าย)
-			balanceMap[key].Balance += tx.Amount * float64(tx.Type)
-
-			// เก็บ income/expense แยกสำหรับแสดงรายละเอียด
-			if tx.Type == 1 {
-				balanceMap[key].TotalIncome += tx.Amount
-			} else {
-				balanceMap[key].TotalExpense += tx.Amount
-			}
-		}
+		accumulatePaymentBalances(balanceMap, record.Incomes)
+		accumulatePaymentBalances(balanceMap, record.Expenses)
 	}
 
 	// Convert to slice
@@ -765,183 +974,6 @@ func (s *MongoDBService) GetBalanceByPaymentType(ctx context.Context, lineID str
 	return result, nil
 }
 
-// SaveTransfer saves a transfer and creates corresponding transactions
-// Returns transfer ID and array of transaction IDs
-func (s *MongoDBService) SaveTransfer(ctx context.Context, lineID string, transfer *TransferData) (string, []string, error) {
-	today := time.Now().Format("2006-01-02")
-
-	// Calculate total amount from "from" entries
-	var totalAmount float64
-	for _, entry := range transfer.From {
-		totalAmount += entry.Amount
-	}
-
-	// Convert to DB format
-	fromEntries := make([]TransferEntryDB, len(transfer.From))
-	for i, e := range transfer.From {
-		fromEntries[i] = TransferEntryDB{
-			Amount:         e.Amount,
-			UseType:        e.UseType,
-			BankName:       e.BankName,
-			CreditCardName: e.CreditCardName,
-		}
-	}
-
-	toEntries := make([]TransferEntryDB, len(transfer.To))
-	for i, e := range transfer.To {
-		toEntries[i] = TransferEntryDB{
-			Amount:         e.Amount,
-			UseType:        e.UseType,
-			BankName:       e.BankName,
-			CreditCardName: e.CreditCardName,
-		}
-	}
-
-	// Create transfer record
-	transferRecord := TransferRecord{
-		ID:          primitive.NewObjectID(),
-		LineID:      lineID,
-		Date:        today,
-		Description: transfer.Description,
-		From:        fromEntries,
-		To:          toEntries,
-		TotalAmount: totalAmount,
-		CreatedAt:   time.Now(),
-	}
-
-	// Save transfer record
-	_, err := s.transferCollection.InsertOne(ctx, transferRecord)
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to save transfer: %w", err)
-	}
-
-	transferID := transferRecord.ID.Hex()
-	var txIDs []string
-
-	// Create expense transactions for "from" entries (money going out)
-	for _, entry := range transfer.From {
-		txData := &TransactionData{
-			Type:           "expense",
-			Amount:         entry.Amount,
-			Category:       "โอนเงิน",
-			Description:    transfer.Description,
-			UseType:        entry.UseType,
-			BankName:       entry.BankName,
-			CreditCardName: entry.CreditCardName,
-		}
-		txID, err := s.saveTransactionWithTransferID(ctx, lineID, txData, transferID)
-		if err != nil {
-			log.Printf("Failed to save from transaction: %v", err)
-			continue
-		}
-		txIDs = append(txIDs, txID)
-	}
-
-	// Create income transactions for "to" entries (money coming in)
-	for _, entry := range transfer.To {
-		txData := &TransactionData{
-			Type:           "income",
-			Amount:         entry.Amount,
-			Category:       "โอนเงิน",
-			Description:    transfer.Description,
-			UseType:        entry.UseType,
-			BankName:       entry.BankName,
-			CreditCardName: entry.CreditCardName,
-		}
-		txID, err := s.saveTransactionWithTransferID(ctx, lineID, txData, transferID)
-		if err != nil {
-			log.Printf("Failed to save to transaction: %v", err)
-			continue
-		}
-		txIDs = append(txIDs, txID)
-	}
-
-	return transferID, txIDs, nil
-}
-
-// saveTransactionWithTransferID saves a transaction with transfer_id
-func (s *MongoDBService) saveTransactionWithTransferID(ctx context.Context, lineID string, tx *TransactionData, transferID string) (string, error) {
-	today := time.Now().Format("2006-01-02")
-	currentTime := time.Now().Format("15:04")
-
-	txType := -1
-	if tx.Type == "income" {
-		txType = 1
-	}
-
-	newTx := Transaction{
-		ID:             primitive.NewObjectID(),
-		Type:           txType,
-		CustName:       tx.Merchant,
-		Amount:         tx.Amount,
-		Category:       tx.Category,
-		Description:    tx.Description,
-		UseType:        tx.UseType,
-		BankName:       tx.BankName,
-		CreditCardName: tx.CreditCardName,
-		TransferID:     transferID,
-		CreatedAt:      time.Now(),
-	}
-
-	filter := bson.M{
-		"lineid": lineID,
-		"date":   today,
-	}
-
-	var record DailyRecord
-	err := s.collection.FindOne(ctx, filter).Decode(&record)
-
-	if err == mongo.ErrNoDocuments {
-		record = DailyRecord{
-			LineID:    lineID,
-			Date:      today,
-			Time:      currentTime,
-			Incomes:   []Transaction{},
-			Expenses:  []Transaction{},
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		}
-
-		if txType == 1 {
-			record.Incomes = append(record.Incomes, newTx)
-			record.TotalIncome = tx.Amount
-		} else {
-			record.Expenses = append(record.Expenses, newTx)
-			record.TotalExpense = tx.Amount
-		}
-
-		_, err := s.collection.InsertOne(ctx, record)
-		if err != nil {
-			return "", fmt.Errorf("failed to insert daily record: %w", err)
-		}
-		return newTx.ID.Hex(), nil
-	} else if err != nil {
-		return "", fmt.Errorf("failed to find daily record: %w", err)
-	}
-
-	var update bson.M
-	if txType == 1 {
-		update = bson.M{
-			"$push": bson.M{"incomes": newTx},
-			"$inc":  bson.M{"totalIncome": tx.Amount},
-			"$set":  bson.M{"updatedAt": time.Now()},
-		}
-	} else {
-		update = bson.M{
-			"$push": bson.M{"expenses": newTx},
-			"$inc":  bson.M{"totalExpense": tx.Amount},
-			"$set":  bson.M{"updatedAt": time.Now()},
-		}
-	}
-
-	_, err = s.collection.UpdateOne(ctx, filter, update)
-	if err != nil {
-		return "", fmt.Errorf("failed to update daily record: %w", err)
-	}
-
-	return newTx.ID.Hex(), nil
-}
-
 // GetTransferByID returns a transfer by its ID
 func (s *MongoDBService) GetTransferByID(ctx context.Context, transferID string) (*TransferRecord, error) {
 	objectID, err := primitive.ObjectIDFromHex(transferID)
@@ -958,47 +990,58 @@ func (s *MongoDBService) GetTransferByID(ctx context.Context, transferID string)
 	return &transfer, nil
 }
 
-// DeleteTransfer deletes a transfer and its related transactions
+// DeleteTransfer deletes a transfer and its related transactions from the day it was saved
+// (today when its record is already gone, so leftover legs are still removed)
 func (s *MongoDBService) DeleteTransfer(ctx context.Context, lineID, transferID string) error {
-	today := time.Now().Format("2006-01-02")
-
-	// Delete from incomes where transfer_id matches
-	filterIncome := bson.M{
-		"lineid": lineID,
-		"date":   today,
-	}
-	updateIncome := bson.M{
-		"$pull": bson.M{"incomes": bson.M{"transfer_id": transferID}},
-		"$set":  bson.M{"updatedAt": time.Now()},
-	}
-	s.collection.UpdateOne(ctx, filterIncome, updateIncome)
-
-	// Delete from expenses where transfer_id matches
-	updateExpense := bson.M{
-		"$pull": bson.M{"expenses": bson.M{"transfer_id": transferID}},
-		"$set":  bson.M{"updatedAt": time.Now()},
+	date := time.Now().Format("2006-01-02")
+	if objectID, err := primitive.ObjectIDFromHex(transferID); err == nil {
+		var record TransferRecord
+		opts := options.FindOne().SetProjection(bson.M{"date": 1})
+		if s.transferCollection.FindOne(ctx, bson.M{"_id": objectID, "lineid": lineID}, opts).Decode(&record) == nil && record.Date != "" {
+			date = record.Date
+		}
 	}
-	s.collection.UpdateOne(ctx, filterIncome, updateExpense)
+	return s.DeleteTransferOnDate(ctx, lineID, transferID, date)
+}
 
-	// Delete transfer record
+// DeleteTransferOnDate removes both legs of a transfer from the record of date (YYYY-MM-DD) and its transfer record
+func (s *MongoDBService) DeleteTransferOnDate(ctx context.Context, lineID, transferID, date string) error {
 	objectID, err := primitive.ObjectIDFromHex(transferID)
 	if err != nil {
 		return fmt.Errorf("invalid transfer ID: %w", err)
 	}
-	s.transferCollection.DeleteOne(ctx, bson.M{"_id": objectID})
+	if err := s.checkPeriodOpen(ctx, lineID, date); err != nil {
+		return err
+	}
 
-	// Recalculate totals
-	return s.recalculateTotals(ctx, lineID, today)
+	// Keep the legs for hooks (sync deletes, hash chain) before they're gone
+	var record DailyRecord
+	_ = s.collection.FindOne(ctx, bson.M{"lineid": lineID, "date": date, "$or": []bson.M{
+		{"incomes.transfer_id": transferID}, {"expenses.transfer_id": transferID},
+	}}).Decode(&record)
+	legs := TransferLegsOf(record, transferID)
+
+	if err := s.pullTransferLegs(ctx, lineID, date, transferID); err != nil {
+		return err
+	}
+	for _, tx := range legs {
+		s.notifyTransaction(TransactionDeleted, lineID, date, tx)
+	}
+	s.markProjectionStale(lineID)
+	_, err = s.transferCollection.DeleteOne(ctx, bson.M{"_id": objectID, "lineid": lineID})
+	return err
 }
 
 // SearchResult represents a search result with full transaction details
 type SearchResult struct {
 	Transaction Transaction `json:"transaction"`
-	Date        string      `json:"date"`      // date from daily record
-	RecordID    string      `json:"record_id"` // ID of the daily record
+	Date        string      `json:"date"`               // date from daily record
+	RecordID    string      `json:"record_id"`          // ID of the daily record
+	Archived    bool        `json:"archived,omitempty"` // found in the archive (older than ARCHIVE_AFTER_YEARS)
 }
 
-// SearchTransactions searches transactions by keyword across description, category, custname
+// SearchTransactions searches transactions by keyword across description, category, custname and
+// normalized merchant ("เซเว่น" also finds receipts read as "7-ELEVEN สาขา 1234")
 // Returns matching transactions with their dates
 func (s *MongoDBService) SearchTransactions(ctx context.Context, lineID, keyword string, limit int) ([]SearchResult, error) {
 	if limit <= 0 {
@@ -1006,76 +1049,59 @@ func (s *MongoDBService) SearchTransactions(ctx context.Context, lineID, keyword
 	}
 
 	// Build regex pattern for case-insensitive search
-	filter := bson.M{
-		"lineid": lineID,
-		"$or": []bson.M{
-			{"incomes.description": bson.M{"$regex": keyword, "$options": "i"}},
-			{"incomes.category": bson.M{"$regex": keyword, "$options": "i"}},
-			{"incomes.custname": bson.M{"$regex": keyword, "$options": "i"}},
-			{"expenses.description": bson.M{"$regex": keyword, "$options": "i"}},
-			{"expenses.category": bson.M{"$regex": keyword, "$options": "i"}},
-			{"expenses.custname": bson.M{"$regex": keyword, "$options": "i"}},
-		},
-	}
-
-	// Sort by date descending (newest first)
+	or := []bson.M{
+		{"incomes.description": bson.M{"$regex": keyword, "$options": "i"}},
+		{"incomes.category": bson.M{"$regex": keyword, "$options": "i"}},
+		{"incomes.custname": bson.M{"$regex": keyword, "$options": "i"}},
+		{"incomes.merchant": bson.M{"$regex": keyword, "$options": "i"}},
+		{"expenses.description": bson.M{"$regex": keyword, "$options": "i"}},
+		{"expenses.category": bson.M{"$regex": keyword, "$options": "i"}},
+		{"expenses.custname": bson.M{"$regex": keyword, "$options": "i"}},
+		{"expenses.merchant": bson.M{"$regex": keyword, "$options": "i"}},
+	}
+	if chain := KnownMerchant(keyword); chain != "" {
+		or = append(or, bson.M{"incomes.merchant": chain}, bson.M{"expenses.merchant": chain})
+	}
+	filter := bson.M{"lineid": lineID, "$or": or}
+
+	// Sort by date descending (newest first); archived days follow the hot ones
 	opts := options.Find().SetSort(bson.D{{Key: "date", Value: -1}})
-	cursor, err := s.collection.Find(ctx, filter, opts)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
-
 	var results []SearchResult
-
-	for cursor.Next(ctx) {
-		var record DailyRecord
-		if err := cursor.Decode(&record); err != nil {
-			continue
-		}
-
-		// Search in incomes
-		for _, tx := range record.Incomes {
-			if matchesKeyword(tx, keyword) {
-				results = append(results, SearchResult{
-					Transaction: tx,
-					Date:        record.Date,
-					RecordID:    record.ID.Hex(),
-				})
-				if len(results) >= limit {
-					break
+	err := s.eachDailyRecord(ctx, lineID, "", filter, opts, false, func(record DailyRecord) error {
+		for _, list := range [][]Transaction{record.Incomes, record.Expenses} {
+			for _, tx := range list {
+				if !matchesKeyword(tx, keyword) {
+					continue
 				}
-			}
-		}
-
-		// Search in expenses
-		for _, tx := range record.Expenses {
-			if matchesKeyword(tx, keyword) {
 				results = append(results, SearchResult{
 					Transaction: tx,
 					Date:        record.Date,
 					RecordID:    record.ID.Hex(),
+					Archived:    record.Archived,
 				})
 				if len(results) >= limit {
-					break
+					return errEnoughRecords
 				}
 			}
 		}
-
-		if len(results) >= limit {
-			break
-		}
+		return nil
+	})
+	if err != nil && err != errEnoughRecords {
+		return nil, err
 	}
-
 	return results, nil
 }
 
 // matchesKeyword checks if a transaction matches the keyword
 func matchesKeyword(tx Transaction, keyword string) bool {
+	if chain := KnownMerchant(keyword); chain != "" && tx.Merchant == chain {
+		return true
+	}
 	keyword = strings.ToLower(keyword)
 	return strings.Contains(strings.ToLower(tx.Description), keyword) ||
 		strings.Contains(strings.ToLower(tx.Category), keyword) ||
-		strings.Contains(strings.ToLower(tx.CustName), keyword)
+		strings.Contains(strings.ToLower(tx.CustName), keyword) ||
+		strings.Contains(strings.ToLower(tx.Merchant), keyword)
 }
 
 // SearchByCategory searches transactions by category
@@ -1083,20 +1109,150 @@ func (s *MongoDBService) SearchByCategory(ctx context.Context, lineID, category
 	return s.SearchTransactions(ctx, lineID, category, limit)
 }
 
-// SearchByDateRange searches transactions within a date range
+// SearchByDateRange returns up to limit transactions between two dates, newest first
 func (s *MongoDBService) SearchByDateRange(ctx context.Context, lineID, startDate, endDate string, limit int) ([]SearchResult, error) {
+	results, _, err := s.SearchByDateRangePage(ctx, lineID, startDate, endDate, 0, limit)
+	return results, err
+}
+
+// SearchByDateRangePage returns one page of transactions between two dates (newest day, then newest entry first)
+// and the total number in the range. Unwinding, sorting and paging run in Mongo so only the page is fetched;
+// receipt images are left out. total counts the whole range, for "...และอีก N รายการ".
+func (s *MongoDBService) SearchByDateRangePage(ctx context.Context, lineID, startDate, endDate string, skip, limit int) ([]SearchResult, int, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if skip < 0 {
+		skip = 0
+	}
+
+	stages, err := s.transactionRowStages(ctx, lineID, startDate, endDate)
+	if err != nil {
+		return nil, 0, err
+	}
+	pipeline := append(mongo.Pipeline{}, stages...)
+	pipeline = append(pipeline,
+		bson.D{{Key: "$project", Value: bson.M{"tx.imagebase64": 0}}},
+		// _id breaks created_at ties so consecutive pages never repeat or drop an entry
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "date", Value: -1}, {Key: "tx.created_at", Value: -1}, {Key: "tx._id", Value: -1}}}},
+		bson.D{{Key: "$skip", Value: int64(skip)}},
+		bson.D{{Key: "$limit", Value: int64(limit)}},
+	)
+	results, err := s.aggregateTransactionRows(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// A short first page is the whole range, no count query needed
+	if skip == 0 && len(results) < limit {
+		return results, len(results), nil
+	}
+
+	countPipeline := append(mongo.Pipeline{}, stages...)
+	countPipeline = append(countPipeline, bson.D{{Key: "$count", Value: "total"}})
+	countCursor, err := s.collection.Aggregate(ctx, countPipeline)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count transactions: %w", err)
+	}
+	defer countCursor.Close(ctx)
+	var counts []struct {
+		Total int `bson:"total"`
+	}
+	if err := countCursor.All(ctx, &counts); err != nil {
+		return nil, 0, fmt.Errorf("failed to count transactions: %w", err)
+	}
+	if len(counts) == 0 {
+		return results, 0, nil // Page past the end of an empty range
+	}
+	return results, counts[0].Total, nil
+}
+
+// transactionRowStages are the pipeline stages giving one row (date, archived, tx) per transaction of the
+// user between two dates, archived days included
+func (s *MongoDBService) transactionRowStages(ctx context.Context, lineID, startDate, endDate string) ([]bson.D, error) {
+	settings, err := s.GetUserSettings(ctx, lineID)
+	if err != nil {
+		return nil, err
+	}
+	rangeFilter := bson.M{
+		"lineid": lineID,
+		"date":   bson.M{"$gte": startDate, "$lte": endDate},
+	}
+	stages := []bson.D{{{Key: "$match", Value: hotRecordFilter(rangeFilter, settings.ArchivedThrough)}}}
+	if settings.ArchivedThrough != "" && startDate <= settings.ArchivedThrough {
+		// Read-through: archived days join before sorting so paging spans both collections
+		stages = append(stages, bson.D{{Key: "$unionWith", Value: bson.M{
+			"coll":     s.archiveCollection.Name(),
+			"pipeline": bson.A{bson.M{"$match": rangeFilter}},
+		}}})
+	}
+	return append(stages,
+		bson.D{{Key: "$project", Value: bson.M{"date": 1, "archived": 1, "tx": bson.M{"$concatArrays": bson.A{
+			bson.M{"$ifNull": bson.A{"$incomes", bson.A{}}},
+			bson.M{"$ifNull": bson.A{"$expenses", bson.A{}}},
+		}}}}},
+		bson.D{{Key: "$unwind", Value: "$tx"}},
+	), nil
+}
+
+// aggregateTransactionRows runs a pipeline built on transactionRowStages
+func (s *MongoDBService) aggregateTransactionRows(ctx context.Context, pipeline mongo.Pipeline) ([]SearchResult, error) {
+	cursor, err := s.collection.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []SearchResult
+	for cursor.Next(ctx) {
+		var row struct {
+			ID       primitive.ObjectID `bson:"_id"`
+			Date     string             `bson:"date"`
+			Archived bool               `bson:"archived"`
+			Tx       Transaction        `bson:"tx"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			continue
+		}
+		results = append(results, SearchResult{Transaction: row.Tx, Date: row.Date, RecordID: row.ID.Hex(), Archived: row.Archived})
+	}
+	return results, cursor.Err()
+}
+
+// EachTransactionInRange streams transactions between two dates (newest day first, archived days included)
+// to fn without loading them all; receipt images are not fetched. Stops early when fn returns an error.
+func (s *MongoDBService) EachTransactionInRange(ctx context.Context, lineID, startDate, endDate string, fn func(SearchResult) error) error {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "date", Value: -1}}).
+		SetProjection(bson.M{"incomes.imagebase64": 0, "expenses.imagebase64": 0}).
+		SetBatchSize(200)
+	filter := bson.M{"lineid": lineID, "date": bson.M{"$gte": startDate, "$lte": endDate}}
+	return s.eachDailyRecord(ctx, lineID, startDate, filter, opts, false, func(record DailyRecord) error {
+		for _, list := range [][]Transaction{record.Incomes, record.Expenses} {
+			for _, tx := range list {
+				if err := fn(SearchResult{Transaction: tx, Date: record.Date, RecordID: record.ID.Hex(), Archived: record.Archived}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// SearchByCategoryRange returns transactions of one category within a date range (newest first)
+func (s *MongoDBService) SearchByCategoryRange(ctx context.Context, lineID, category, startDate, endDate string, limit int) ([]SearchResult, error) {
 	if limit <= 0 {
 		limit = 50
 	}
 
 	filter := bson.M{
 		"lineid": lineID,
-		"date": bson.M{
-			"$gte": startDate,
-			"$lte": endDate,
+		"date":   bson.M{"$gte": startDate, "$lte": endDate},
+		"$or": []bson.M{
+			{"incomes.category": category},
+			{"expenses.category": category},
 		},
 	}
-
 	opts := options.Find().SetSort(bson.D{{Key: "date", Value: -1}})
 	cursor, err := s.collection.Find(ctx, filter, opts)
 	if err != nil {
@@ -1105,36 +1261,19 @@ func (s *MongoDBService) SearchByDateRange(ctx context.Context, lineID, startDat
 	defer cursor.Close(ctx)
 
 	var results []SearchResult
-
-	for cursor.Next(ctx) {
+	for cursor.Next(ctx) && len(results) < limit {
 		var record DailyRecord
 		if err := cursor.Decode(&record); err != nil {
 			continue
 		}
-
-		// Add all incomes
-		for _, tx := range record.Incomes {
-			results = append(results, SearchResult{
-				Transaction: tx,
-				Date:        record.Date,
-				RecordID:    record.ID.Hex(),
-			})
-		}
-
-		// Add all expenses
-		for _, tx := range record.Expenses {
-			results = append(results, SearchResult{
-				Transaction: tx,
-				Date:        record.Date,
-				RecordID:    record.ID.Hex(),
-			})
-		}
-
-		if len(results) >= limit {
-			break
+		for _, list := range [][]Transaction{record.Expenses, record.Incomes} {
+			for _, tx := range list {
+				if tx.Category == category && len(results) < limit {
+					results = append(results, SearchResult{Transaction: tx, Date: record.Date, RecordID: record.ID.Hex()})
+				}
+			}
 		}
 	}
-
 	return results, nil
 }
 
@@ -1158,12 +1297,17 @@ func (s *MongoDBService) GetTransactionSummaryText(results []SearchResult) strin
 			typeStr = "รายรับ"
 		}
 
-		sb.WriteString(fmt.Sprintf("- %s: %s %.0f บาท (%s) วันที่ %s\n",
+		archived := ""
+		if r.Archived {
+			archived = " (เก็บถาวร)"
+		}
+		sb.WriteString(fmt.Sprintf("- %s: %s %.0f บาท (%s) วันที่ %s%s\n",
 			typeStr,
 			r.Transaction.Description,
 			r.Transaction.Amount,
 			r.Transaction.Category,
 			r.Date,
+			archived,
 		))
 	}
 
@@ -1303,6 +1447,11 @@ func (s *MongoDBService) SetBudget(ctx context.Context, lineID, category string,
 
 	opts := options.Update().SetUpsert(true)
 	_, err := s.budgetCollection.UpdateOne(ctx, filter, update, opts)
+	s.markProjectionStale(lineID)
+	if err == nil {
+		s.recordSyncChange(SyncChange{LineID: lineID, Entity: SyncEntityBudget, Op: SyncOpUpsert, ID: category,
+			Budget: &Budget{LineID: lineID, Category: category, Amount: amount, UpdatedAt: time.Now()}})
+	}
 	return err
 }
 
@@ -1347,6 +1496,10 @@ func (s *MongoDBService) DeleteBudget(ctx context.Context, lineID, category stri
 		"category": category,
 	}
 	_, err := s.budgetCollection.DeleteOne(ctx, filter)
+	s.markProjectionStale(lineID)
+	if err == nil {
+		s.recordSyncChange(SyncChange{LineID: lineID, Entity: SyncEntityBudget, Op: SyncOpDelete, ID: category})
+	}
 	return err
 }
 
@@ -1357,11 +1510,16 @@ func (s *MongoDBService) GetMonthlySpendingByCategory(ctx context.Context, lineI
 	firstDay := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
 	lastDay := firstDay.AddDate(0, 1, -1)
 
+	return s.GetSpendingByCategoryRange(ctx, lineID, firstDay.Format("2006-01-02"), lastDay.Format("2006-01-02"))
+}
+
+// GetSpendingByCategoryRange returns spending by category between two dates (inclusive)
+func (s *MongoDBService) GetSpendingByCategoryRange(ctx context.Context, lineID, startDate, endDate string) (map[string]float64, error) {
 	filter := bson.M{
 		"lineid": lineID,
 		"date": bson.M{
-			"$gte": firstDay.Format("2006-01-02"),
-			"$lte": lastDay.Format("2006-01-02"),
+			"$gte": startDate,
+			"$lte": endDate,
 		},
 	}
 
@@ -1379,14 +1537,14 @@ func (s *MongoDBService) GetMonthlySpendingByCategory(ctx context.Context, lineI
 			continue
 		}
 
-		// Sum expenses by category (exclude transfers)
+		// Sum expenses by category (exclude transfers and investments)
 		for _, tx := range record.Expenses {
 			category := tx.Category
 			if category == "" {
 				category = "อื่นๆ"
 			}
-			// Skip transfer transactions - they're not real expenses
-			if category == "โอนเงิน" {
+			// Skip transfer/investment transactions - they're not real expenses
+			if tx.IsAssetMove() {
 				continue
 			}
 			spendingByCategory[category] += tx.Amount
@@ -1436,6 +1594,24 @@ func (s *MongoDBService) GetBudgetStatus(ctx context.Context, lineID string) ([]
 	return statuses, nil
 }
 
+// FindBudgetStatus returns the status of category (nil when it has no budget)
+func FindBudgetStatus(statuses []BudgetStatus, category string) *BudgetStatus {
+	for i := range statuses {
+		if statuses[i].Category == category {
+			return &statuses[i]
+		}
+	}
+	return nil
+}
+
+// ProgressText returns the compact progress line shown with a saved expense ("งบอาหารใช้ไป 62%")
+func (b BudgetStatus) ProgressText() string {
+	if b.IsOverBudget {
+		return fmt.Sprintf("⚠️ งบ%sเกินแล้ว %.0f บาท (%.0f%%)", b.Category, -b.Remaining, b.Percentage)
+	}
+	return fmt.Sprintf("📊 งบ%sใช้ไป %.0f%%", b.Category, b.Percentage)
+}
+
 // CheckBudgetAlert checks if a category is over budget and returns alert message
 func (s *MongoDBService) CheckBudgetAlert(ctx context.Context, lineID, category string, newAmount float64) (bool, string) {
 	budget, err := s.GetBudget(ctx, lineID, category)
@@ -1501,6 +1677,8 @@ func (s *MongoDBService) SaveTempData(ctx context.Context, key, data string, ttl
 				"data":       data,
 				"expires_at": time.Now().Add(ttl),
 			},
+			// A re-saved state gets its own expiry reminder
+			"$unset": bson.M{"reminded": ""},
 		},
 		options.Update().SetUpsert(true),
 	)
@@ -1534,6 +1712,11 @@ func (s *MongoDBService) DeleteTempData(ctx context.Context, key string) error {
 	return err
 }
 
+// Database returns the underlying database (used by migrations)
+func (s *MongoDBService) Database() *mongo.Database {
+	return s.database
+}
+
 func (s *MongoDBService) Close() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -0,0 +1,71 @@
+package services
+
+import "strings"
+
+// AccountRef is a bank account or credit card referenced by a transaction
+type AccountRef struct {
+	UseType        int    `json:"usetype"` // 1=บัตรเครดิต, 2=ธนาคาร
+	BankName       string `json:"bankname,omitempty"`
+	CreditCardName string `json:"creditcardname,omitempty"`
+}
+
+// Name returns the bank or card name
+func (a AccountRef) Name() string {
+	if a.UseType == 1 {
+		return a.CreditCardName
+	}
+	return a.BankName
+}
+
+// Matches reports whether tx is paid with this account
+func (a AccountRef) Matches(tx TransactionData) bool {
+	if a.UseType == 1 {
+		return tx.UseType == 1 && strings.EqualFold(tx.CreditCardName, a.CreditCardName)
+	}
+	return tx.UseType == 2 && strings.EqualFold(tx.BankName, a.BankName)
+}
+
+// FindUnknownAccount returns the first bank/card in txs that the user has never used (nil if all known)
+func FindUnknownAccount(txs []TransactionData, banks, cards []string) *AccountRef {
+	for _, tx := range txs {
+		switch {
+		case tx.UseType == 1 && tx.CreditCardName != "" && !containsFold(cards, tx.CreditCardName):
+			return &AccountRef{UseType: 1, CreditCardName: tx.CreditCardName}
+		case tx.UseType == 2 && tx.BankName != "" && !containsFold(banks, tx.BankName):
+			return &AccountRef{UseType: 2, BankName: tx.BankName}
+		}
+	}
+	return nil
+}
+
+// containsFold reports whether list has name (case-insensitive)
+func containsFold(list []string, name string) bool {
+	for _, n := range list {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// yesAnswers and noAnswers are short replies to a yes/no question
+var (
+	yesAnswers = []string{"ใช่", "ใช่ค่ะ", "ใช่ครับ", "ใช่แล้ว", "ตกลง", "เพิ่ม", "เพิ่มเลย", "ok", "โอเค", "yes", "y"}
+	noAnswers  = []string{"ไม่", "ไม่ใช่", "ไม่ค่ะ", "ไม่ครับ", "ไม่ต้อง", "ไม่เพิ่ม", "no", "n"}
+)
+
+// ParseYesNo parses a short yes/no reply; ok is false for anything else
+func ParseYesNo(text string) (yes bool, ok bool) {
+	t := strings.ToLower(strings.TrimSpace(text))
+	for _, a := range yesAnswers {
+		if t == a {
+			return true, true
+		}
+	}
+	for _, a := range noAnswers {
+		if t == a {
+			return false, true
+		}
+	}
+	return false, false
+}
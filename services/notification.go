@@ -0,0 +1,355 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Notification types (users can turn each one off)
+const (
+	NotifyBudget   = "budget"   // budget nearly used / exceeded
+	NotifyRenewal  = "renewal"  // subscription renewing soon
+	NotifyCardDue  = "card_due" // credit card payment due soon
+	NotifyDigest   = "digest"   // weekly spending digest
+	NotifyAnomaly  = "anomaly"  // unusually high spending today
+	NotifyWarranty = "warranty" // product warranty expiring soon
+	NotifyRoundUp  = "roundup"  // weekly round-up savings total
+	NotifyReview   = "review"   // auto-captured entries waiting for review
+)
+
+// NotificationTypeNames are Thai names used in chat commands
+var NotificationTypeNames = map[string]string{
+	NotifyBudget:   "งบ",
+	NotifyRenewal:  "ต่ออายุ",
+	NotifyCardDue:  "บัตร",
+	NotifyDigest:   "สรุปสัปดาห์",
+	NotifyAnomaly:  "ใช้จ่ายผิดปกติ",
+	NotifyWarranty: "ประกันสินค้า",
+	NotifyRoundUp:  "ปัดเศษ",
+	NotifyReview:   "รอตรวจ",
+}
+
+// NotificationTypeOrder is display order of notification types
+var NotificationTypeOrder = []string{NotifyBudget, NotifyRenewal, NotifyCardDue, NotifyDigest, NotifyAnomaly, NotifyWarranty, NotifyRoundUp, NotifyReview}
+
+// Default notification preferences
+const (
+	defaultNotificationCap = 3
+	defaultQuietStart      = "22:00"
+	defaultQuietEnd        = "07:00"
+)
+
+// NotificationPrefs are per-user notification settings (stored in user_settings)
+type NotificationPrefs struct {
+	Disabled   []string `bson:"disabled,omitempty" json:"disabled,omitempty"` // turned-off types
+	QuietStart string   `bson:"quiet_start,omitempty" json:"quiet_start,omitempty"`
+	QuietEnd   string   `bson:"quiet_end,omitempty" json:"quiet_end,omitempty"`
+	QuietOff   bool     `bson:"quiet_off,omitempty" json:"quiet_off,omitempty"` // true = no quiet hours
+	DailyCap   int      `bson:"daily_cap,omitempty" json:"daily_cap,omitempty"` // 0 = default
+}
+
+// IsEnabled reports whether notification type is on
+func (p NotificationPrefs) IsEnabled(notifyType string) bool {
+	for _, t := range p.Disabled {
+		if t == notifyType {
+			return false
+		}
+	}
+	return true
+}
+
+// Cap returns daily notification limit
+func (p NotificationPrefs) Cap() int {
+	if p.DailyCap > 0 {
+		return p.DailyCap
+	}
+	return defaultNotificationCap
+}
+
+// QuietHours returns quiet start/end ("" if disabled)
+func (p NotificationPrefs) QuietHours() (string, string) {
+	if p.QuietOff {
+		return "", ""
+	}
+	if p.QuietStart == "" || p.QuietEnd == "" {
+		return defaultQuietStart, defaultQuietEnd
+	}
+	return p.QuietStart, p.QuietEnd
+}
+
+// InQuietHours checks if now (HH:MM) is within quiet hours (range may cross midnight)
+func (p NotificationPrefs) InQuietHours(now time.Time) bool {
+	start, end := p.QuietHours()
+	if start == "" {
+		return false
+	}
+	current := now.Format("15:04")
+	if start <= end {
+		return current >= start && current < end
+	}
+	return current >= start || current < end
+}
+
+// Notice is one notification ready to show
+type Notice struct {
+	Type    string         `json:"type"`
+	Key     string         `json:"key"` // dedupe key, e.g. "budget:อาหาร:2025-01:100"
+	Text    string         `json:"text"`
+	Actions []NoticeAction `json:"actions,omitempty"` // one-tap buttons under the text
+}
+
+// NoticeAction is a postback button of a notice
+type NoticeAction struct {
+	Label string `json:"label"`
+	Data  string `json:"data"` // postback data, e.g. "action=card_paid&card=KTC"
+}
+
+// NoticeProvider builds candidate notices for a user (checked against prefs later)
+type NoticeProvider func(ctx context.Context, s *MongoDBService, lineID string, now time.Time) []Notice
+
+// notificationLog records delivered notices for dedupe and daily cap
+type notificationLog struct {
+	LineID string    `bson:"lineid"`
+	Type   string    `bson:"type"`
+	Key    string    `bson:"key"`
+	SentAt time.Time `bson:"sent_at"`
+}
+
+// SetNotificationPrefs saves user's notification preferences
+func (s *MongoDBService) SetNotificationPrefs(ctx context.Context, lineID string, prefs NotificationPrefs) error {
+	_, err := s.settingsCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID},
+		bson.M{"$set": bson.M{"notifications": prefs, "updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// NotificationService decides which proactive notices reach the user
+// Notices ride along with replies (no push), limited by type, quiet hours and daily cap
+type NotificationService struct {
+	mongo     *MongoDBService
+	providers []NoticeProvider
+}
+
+// NewNotificationService creates notification service with built-in providers
+func NewNotificationService(mongo *MongoDBService) *NotificationService {
+	s := &NotificationService{mongo: mongo}
+	s.Register(budgetNotices)
+	s.Register(renewalNotices)
+	s.Register(cardDueNotices)
+	s.Register(weeklyDigestNotices)
+	s.Register(anomalyNotices)
+	s.Register(warrantyNotices)
+	s.Register(roundUpNotices)
+	s.Register(reviewNotices)
+	return s
+}
+
+// Register adds a notice provider (new notification types plug in here)
+func (s *NotificationService) Register(provider NoticeProvider) {
+	s.providers = append(s.providers, provider)
+}
+
+// Pending returns notices allowed right now and records them as sent
+func (s *NotificationService) Pending(ctx context.Context, lineID string) []Notice {
+	now := time.Now()
+	settings, err := s.mongo.GetUserSettings(ctx, lineID)
+	if err != nil {
+		return nil
+	}
+	prefs := settings.Notifications
+	if prefs.InQuietHours(now) {
+		return nil
+	}
+
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	sentToday, err := s.mongo.notificationCollection.CountDocuments(ctx, bson.M{"lineid": lineID, "sent_at": bson.M{"$gte": startOfDay}})
+	if err != nil {
+		return nil
+	}
+	remaining := prefs.Cap() - int(sentToday)
+	if remaining <= 0 {
+		return nil
+	}
+
+	var notices []Notice
+	for _, provider := range s.providers {
+		for _, notice := range provider(ctx, s.mongo, lineID, now) {
+			if len(notices) >= remaining {
+				return notices
+			}
+			if !prefs.IsEnabled(notice.Type) || s.wasSent(ctx, lineID, notice.Key) {
+				continue
+			}
+			if _, err := s.mongo.notificationCollection.InsertOne(ctx, notificationLog{
+				LineID: lineID, Type: notice.Type, Key: notice.Key, SentAt: now,
+			}); err != nil {
+				log.Printf("Failed to log notification: %v", err)
+				continue
+			}
+			notices = append(notices, notice)
+		}
+	}
+	return notices
+}
+
+// IsEnabled reports whether user has notification type on (for inline reminders)
+func (s *NotificationService) IsEnabled(ctx context.Context, lineID, notifyType string) bool {
+	settings, err := s.mongo.GetUserSettings(ctx, lineID)
+	if err != nil {
+		return true
+	}
+	return settings.Notifications.IsEnabled(notifyType)
+}
+
+// wasSent checks dedupe key
+func (s *NotificationService) wasSent(ctx context.Context, lineID, key string) bool {
+	count, err := s.mongo.notificationCollection.CountDocuments(ctx, bson.M{"lineid": lineID, "key": key})
+	return err != nil || count > 0
+}
+
+// budgetNotices warns once per month per level (80%, 100%)
+func budgetNotices(ctx context.Context, s *MongoDBService, lineID string, now time.Time) []Notice {
+	statuses, err := s.GetBudgetStatus(ctx, lineID)
+	if err != nil {
+		return nil
+	}
+	var notices []Notice
+	for _, b := range statuses {
+		level := 0
+		text := ""
+		switch {
+		case b.Percentage >= 100:
+			level = 100
+			text = fmt.Sprintf("🚨 งบ%s เกินแล้ว (ใช้ %.0f/%.0f บาท)", b.Category, b.Spent, b.Budget)
+		case b.Percentage >= 80:
+			level = 80
+			text = fmt.Sprintf("⚠️ งบ%s ใช้ไป %.0f%% เหลือ %.0f บาท", b.Category, b.Percentage, b.Remaining)
+		default:
+			continue
+		}
+		notices = append(notices, Notice{
+			Type: NotifyBudget,
+			Key:  fmt.Sprintf("budget:%s:%s:%d", b.Category, now.Format("2006-01"), level),
+			Text: text,
+		})
+	}
+	return notices
+}
+
+// renewalNotices reminds subscriptions renewing within 3 days
+func renewalNotices(ctx context.Context, s *MongoDBService, lineID string, now time.Time) []Notice {
+	renewals, err := s.GetUpcomingRenewals(ctx, lineID, 3)
+	if err != nil {
+		return nil
+	}
+	var notices []Notice
+	for _, sub := range renewals {
+		notices = append(notices, Notice{
+			Type: NotifyRenewal,
+			Key:  fmt.Sprintf("renewal:%s:%s", sub.ID.Hex(), sub.NextRenewal),
+			Text: fmt.Sprintf("🔔 %s จะตัดเงิน %.0f บาท วันที่ %s", sub.Name, sub.Amount, sub.NextRenewal),
+		})
+	}
+	return notices
+}
+
+// cardDueNotices reminds card payment due within 3 days; cards with a statement day get escalating
+// reminders of the unpaid statement instead, until a payment into the card is recorded
+func cardDueNotices(ctx context.Context, s *MongoDBService, lineID string, now time.Time) []Notice {
+	cards, err := s.GetCardAccounts(ctx, lineID)
+	if err != nil {
+		return nil
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var notices []Notice
+	for _, card := range cards {
+		if card.StatementDay > 0 {
+			if s.isCardReminderSnoozed(ctx, lineID, card.CardName) {
+				continue
+			}
+			st, err := s.cardStatement(ctx, lineID, card, now)
+			if err != nil {
+				log.Printf("Failed to build card statement: %v", err)
+				continue
+			}
+			if notice, ok := st.ReminderNotice(now); ok {
+				notices = append(notices, notice)
+			}
+			continue
+		}
+		due := NextMonthDay(card.DueDay, now)
+		if due.Sub(today) > 3*24*time.Hour {
+			continue
+		}
+		notices = append(notices, Notice{
+			Type: NotifyCardDue,
+			Key:  fmt.Sprintf("card_due:%s:%s", card.CardName, due.Format("2006-01-02")),
+			Text: fmt.Sprintf("💳 ครบกำหนดจ่ายบัตร %s วันที่ %s", card.CardName, due.Format("02/01")),
+		})
+	}
+	return notices
+}
+
+// weeklyDigestNotices summarizes last week once per week
+func weeklyDigestNotices(ctx context.Context, s *MongoDBService, lineID string, now time.Time) []Notice {
+	prevFrom, prevTo := LastWeekRange(now)
+	spending, err := s.GetSpendingByCategoryRange(ctx, lineID, prevFrom.Format("2006-01-02"), prevTo.Format("2006-01-02"))
+	if err != nil || len(spending) == 0 {
+		return nil
+	}
+	total := 0.0
+	topCategory, topAmount := "", 0.0
+	for cat, amount := range spending {
+		total += amount
+		if amount > topAmount {
+			topCategory, topAmount = cat, amount
+		}
+	}
+	year, week := prevFrom.ISOWeek()
+	return []Notice{{
+		Type: NotifyDigest,
+		Key:  fmt.Sprintf("digest:%d-W%02d", year, week),
+		Text: fmt.Sprintf("📊 สัปดาห์ที่แล้วจ่าย %.0f บาท จ่ายมากสุด: %s %.0f บาท", total, topCategory, topAmount),
+	}}
+}
+
+// anomalyNotices warns when today's spending is over 3x the 30-day daily average
+func anomalyNotices(ctx context.Context, s *MongoDBService, lineID string, now time.Time) []Notice {
+	today := now.Format("2006-01-02")
+	todaySpending, err := s.GetSpendingByCategoryRange(ctx, lineID, today, today)
+	if err != nil {
+		return nil
+	}
+	todayTotal := 0.0
+	for _, amount := range todaySpending {
+		todayTotal += amount
+	}
+	if todayTotal == 0 {
+		return nil
+	}
+
+	history, err := s.GetSpendingByCategoryRange(ctx, lineID, now.AddDate(0, 0, -30).Format("2006-01-02"), now.AddDate(0, 0, -1).Format("2006-01-02"))
+	if err != nil {
+		return nil
+	}
+	historyTotal := 0.0
+	for _, amount := range history {
+		historyTotal += amount
+	}
+	average := historyTotal / 30
+	if average <= 0 || todayTotal < average*3 {
+		return nil
+	}
+	return []Notice{{
+		Type: NotifyAnomaly,
+		Key:  "anomaly:" + today,
+		Text: fmt.Sprintf("👀 วันนี้จ่ายไป %.0f บาท สูงกว่าปกติ (เฉลี่ย %.0f บาท/วัน)", todayTotal, average),
+	}}
+}
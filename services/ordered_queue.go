@@ -0,0 +1,63 @@
+package services
+
+import "sync"
+
+// orderedQueue holds one user's background jobs in the order they were queued; one worker drains it
+type orderedQueue struct {
+	mu      sync.Mutex
+	pending []func()
+	running bool
+}
+
+// enqueueOrdered runs job in background after the jobs queued earlier under key
+// Hooks use it for writes whose order matters (sequence numbers) without blocking the caller
+func enqueueOrdered(queues *sync.Map, key, action string, job func()) {
+	q, _ := queues.LoadOrStore(key, &orderedQueue{})
+	queue := q.(*orderedQueue)
+	queue.mu.Lock()
+	queue.pending = append(queue.pending, job)
+	if queue.running {
+		queue.mu.Unlock()
+		return
+	}
+	queue.running = true
+	queue.mu.Unlock()
+	GoSafe(action, func() { queue.drain(action, key) })
+}
+
+// drain runs queued jobs one at a time until the queue is empty
+// A job that panics is reported and the jobs after it still run
+func (q *orderedQueue) drain(action, key string) {
+	for {
+		q.mu.Lock()
+		if len(q.pending) == 0 {
+			q.running = false
+			q.mu.Unlock()
+			return
+		}
+		job := q.pending[0]
+		q.pending = q.pending[1:]
+		q.mu.Unlock()
+		runRecovered(action, key, job)
+	}
+}
+
+// runRecovered runs job, reporting a panic instead of letting it stop the caller
+func runRecovered(action, lineID string, job func()) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			ReportPanic(action, lineID, rec)
+		}
+	}()
+	job()
+}
+
+// OrderedQueues runs background jobs one at a time per key (a user), in the order they were queued
+type OrderedQueues struct {
+	queues sync.Map
+}
+
+// Enqueue runs job in background after the jobs queued earlier under key
+func (q *OrderedQueues) Enqueue(key, action string, job func()) {
+	enqueueOrdered(&q.queues, key, action, job)
+}
@@ -0,0 +1,370 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Outbound webhook limits
+const (
+	maxWebhookEndpoints  = 3
+	webhookMaxAttempts   = 3
+	webhookDisableAfter  = 20 // consecutive failed deliveries before auto-disable
+	webhookDeliveryLimit = 10 * time.Second
+)
+
+// WebhookEndpoint is a user-registered URL that receives transaction events
+type WebhookEndpoint struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	LineID          string             `bson:"lineid" json:"lineid"`
+	URL             string             `bson:"url" json:"url"`
+	Secret          string             `bson:"secret" json:"-"` // HMAC key for X-Satisatang-Signature
+	Active          bool               `bson:"active" json:"active"`
+	FailCount       int                `bson:"fail_count" json:"fail_count"`
+	LastStatus      int                `bson:"last_status" json:"last_status"`
+	LastError       string             `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	LastDeliveredAt *time.Time         `bson:"last_delivered_at,omitempty" json:"last_delivered_at,omitempty"`
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// WebhookTransaction is the transaction JSON sent to endpoints (no image data)
+type WebhookTransaction struct {
+	ID             string    `json:"id"`
+	Date           string    `json:"date"`
+	Type           string    `json:"type"` // "income" or "expense"
+	Amount         float64   `json:"amount"`
+	Category       string    `json:"category"`
+	Description    string    `json:"description"`
+	CustName       string    `json:"custname,omitempty"`
+	Merchant       string    `json:"merchant,omitempty"` // normalized shop name
+	UseType        int       `json:"usetype"`            // 0=เงินสด, 1=บัตรเครดิต, 2=ธนาคาร
+	BankName       string    `json:"bankname,omitempty"`
+	CreditCardName string    `json:"creditcardname,omitempty"`
+	VATAmount      float64   `json:"vat,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// WebhookPayload is the JSON body of each delivery
+type WebhookPayload struct {
+	Event       string             `json:"event"` // "transaction.created", "transaction.updated", "ping"
+	Transaction WebhookTransaction `json:"transaction"`
+	SentAt      time.Time          `json:"sent_at"`
+}
+
+// newWebhookTransaction converts stored transaction to payload format
+func newWebhookTransaction(date string, tx Transaction) WebhookTransaction {
+	txType := "expense"
+	if tx.Type == 1 {
+		txType = "income"
+	}
+	return WebhookTransaction{
+		ID:             tx.ID.Hex(),
+		Date:           date,
+		Type:           txType,
+		Amount:         tx.Amount,
+		Category:       tx.Category,
+		Description:    tx.Description,
+		CustName:       tx.CustName,
+		Merchant:       tx.Merchant,
+		UseType:        tx.UseType,
+		BankName:       tx.BankName,
+		CreditCardName: tx.CreditCardName,
+		VATAmount:      tx.VATAmount,
+		CreatedAt:      tx.CreatedAt,
+	}
+}
+
+// ValidateWebhookURL checks endpoint is https and not a local/private address
+func ValidateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("URL ไม่ถูกต้อง")
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("ต้องเป็น https เท่านั้น")
+	}
+	host := u.Hostname()
+	if strings.EqualFold(host, "localhost") {
+		return fmt.Errorf("ไม่อนุญาต localhost")
+	}
+	if ip := net.ParseIP(host); ip != nil && isInternalIP(ip) {
+		return fmt.Errorf("ไม่อนุญาต IP ภายใน")
+	}
+	return nil
+}
+
+// cgnatRange is the carrier-grade NAT block (100.64.0.0/10), internal on most clouds
+var cgnatRange = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isInternalIP reports whether ip is loopback, private, link-local (cloud metadata) or otherwise not public
+func isInternalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || cgnatRange.Contains(ip)
+}
+
+// NewWebhookClient returns the HTTP client for deliveries: the address actually dialed is checked
+// (a public hostname may resolve to an internal IP, or be rebound after validation) and redirects
+// are refused so an endpoint can't bounce a delivery to an internal URL
+func NewWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isInternalIP(ip) {
+				return fmt.Errorf("webhook to internal address %s blocked", host)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: webhookDeliveryLimit,
+		// No proxy: the dialed address must be the endpoint's own
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return fmt.Errorf("webhook redirect to %s refused", req.URL.Redacted())
+		},
+	}
+}
+
+// SignWebhookPayload returns "sha256=<hex>" HMAC signature of body
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// generateWebhookSecret returns random 32-byte hex secret
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// AddWebhookEndpoint registers a new endpoint (max 3 per user) with generated secret
+func (s *MongoDBService) AddWebhookEndpoint(ctx context.Context, lineID, endpointURL string) (*WebhookEndpoint, error) {
+	if err := ValidateWebhookURL(endpointURL); err != nil {
+		return nil, err
+	}
+	count, err := s.webhookCollection.CountDocuments(ctx, bson.M{"lineid": lineID})
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxWebhookEndpoints {
+		return nil, fmt.Errorf("เพิ่มได้สูงสุด %d endpoint", maxWebhookEndpoints)
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	endpoint := &WebhookEndpoint{
+		ID:        primitive.NewObjectID(),
+		LineID:    lineID,
+		URL:       endpointURL,
+		Secret:    secret,
+		Active:    true,
+		CreatedAt: time.Now(),
+	}
+	if _, err := s.webhookCollection.InsertOne(ctx, endpoint); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// GetWebhookEndpoints returns user's endpoints (oldest first)
+func (s *MongoDBService) GetWebhookEndpoints(ctx context.Context, lineID string) ([]WebhookEndpoint, error) {
+	cursor, err := s.webhookCollection.Find(ctx, bson.M{"lineid": lineID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var endpoints []WebhookEndpoint
+	if err := cursor.All(ctx, &endpoints); err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+// DeleteWebhookEndpoint removes one endpoint by ID
+func (s *MongoDBService) DeleteWebhookEndpoint(ctx context.Context, lineID string, id primitive.ObjectID) error {
+	_, err := s.webhookCollection.DeleteOne(ctx, bson.M{"_id": id, "lineid": lineID})
+	return err
+}
+
+// SetWebhookEndpointActive enables or disables an endpoint (enabling resets fail count)
+func (s *MongoDBService) SetWebhookEndpointActive(ctx context.Context, lineID string, id primitive.ObjectID, active bool) error {
+	set := bson.M{"active": active}
+	if active {
+		set["fail_count"] = 0
+	}
+	_, err := s.webhookCollection.UpdateOne(ctx, bson.M{"_id": id, "lineid": lineID}, bson.M{"$set": set})
+	return err
+}
+
+// recordWebhookDelivery stores delivery result and auto-disables endpoints that keep failing
+func (s *MongoDBService) recordWebhookDelivery(ctx context.Context, id primitive.ObjectID, status int, deliveryErr error) {
+	var update bson.M
+	if deliveryErr == nil {
+		update = bson.M{
+			"$set":   bson.M{"last_status": status, "fail_count": 0, "last_delivered_at": time.Now()},
+			"$unset": bson.M{"last_error": ""},
+		}
+	} else {
+		update = bson.M{
+			"$set": bson.M{"last_status": status, "last_error": deliveryErr.Error()},
+			"$inc": bson.M{"fail_count": 1},
+		}
+	}
+	if _, err := s.webhookCollection.UpdateOne(ctx, bson.M{"_id": id}, update); err != nil {
+		log.Printf("Failed to record webhook delivery: %v", err)
+		return
+	}
+	if deliveryErr != nil {
+		s.webhookCollection.UpdateOne(ctx,
+			bson.M{"_id": id, "fail_count": bson.M{"$gte": webhookDisableAfter}},
+			bson.M{"$set": bson.M{"active": false}},
+		)
+	}
+}
+
+// WebhookService delivers transaction events to user endpoints
+type WebhookService struct {
+	mongo  *MongoDBService
+	client *http.Client
+	queues sync.Map // lineID -> *orderedQueue, so an endpoint gets a user's events in order
+}
+
+// NewWebhookService creates webhook service and subscribes to transaction changes
+func NewWebhookService(mongo *MongoDBService) *WebhookService {
+	s := &WebhookService{
+		mongo:  mongo,
+		client: NewWebhookClient(),
+	}
+	mongo.AddTransactionHook(s.onTransaction)
+	return s
+}
+
+// onTransaction fans out event to active endpoints in background, after the user's earlier events
+func (s *WebhookService) onTransaction(event, lineID, date string, tx Transaction) {
+	enqueueOrdered(&s.queues, lineID, "webhook.deliver", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+
+		endpoints, err := s.mongo.GetWebhookEndpoints(ctx, lineID)
+		if err != nil || len(endpoints) == 0 {
+			return
+		}
+		payload := WebhookPayload{
+			Event:       event,
+			Transaction: newWebhookTransaction(date, tx),
+			SentAt:      time.Now(),
+		}
+		for _, endpoint := range endpoints {
+			if endpoint.Active {
+				s.Deliver(ctx, endpoint, payload)
+			}
+		}
+	})
+}
+
+// Deliver posts payload with retries (1s, 2s backoff) and records the result
+func (s *WebhookService) Deliver(ctx context.Context, endpoint WebhookEndpoint, payload WebhookPayload) (int, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	var status int
+	var lastErr error
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		status, lastErr = s.post(ctx, endpoint, payload.Event, body)
+		if lastErr == nil {
+			break
+		}
+		// 4xx (except 429) won't succeed on retry
+		if status >= 400 && status < 500 && status != http.StatusTooManyRequests {
+			break
+		}
+		if attempt < webhookMaxAttempts {
+			select {
+			case <-ctx.Done():
+				attempt = webhookMaxAttempts
+			case <-time.After(time.Duration(attempt) * time.Second):
+			}
+		}
+	}
+
+	if lastErr != nil {
+		log.Printf("Webhook delivery to %s failed: %v", endpoint.URL, lastErr)
+	}
+	s.mongo.recordWebhookDelivery(ctx, endpoint.ID, status, lastErr)
+	return status, lastErr
+}
+
+// post sends one signed request
+func (s *WebhookService) post(ctx context.Context, endpoint WebhookEndpoint, event string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Satisatang-Webhook/1.0")
+	req.Header.Set("X-Satisatang-Event", event)
+	req.Header.Set("X-Satisatang-Signature", SignWebhookPayload(endpoint.Secret, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// SendPing delivers a sample "ping" event to test endpoint setup
+func (s *WebhookService) SendPing(ctx context.Context, endpoint WebhookEndpoint) (int, error) {
+	payload := WebhookPayload{
+		Event: "ping",
+		Transaction: WebhookTransaction{
+			Date:        time.Now().Format("2006-01-02"),
+			Type:        "expense",
+			Amount:      1,
+			Category:    "ทดสอบ",
+			Description: "ทดสอบ webhook",
+			CreatedAt:   time.Now(),
+		},
+		SentAt: time.Now(),
+	}
+	return s.Deliver(ctx, endpoint, payload)
+}
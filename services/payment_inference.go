@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// PaymentGuess is the likely payment method inferred from history
+type PaymentGuess struct {
+	UseType        int    `json:"usetype"`
+	BankName       string `json:"bankname"`
+	CreditCardName string `json:"creditcardname"`
+	Source         string `json:"source"` // "merchant" or "category"
+	Count          int    `json:"count"`  // matching past transactions
+}
+
+// paymentHintKeywords indicate the user already said how they paid
+var paymentHintKeywords = []string{
+	"เงินสด", "จ่ายสด", "บัตร", "เครดิต", "เดบิต", "โอน", "ธนาคาร", "ธ.", "แบงค์", "พร้อมเพย์", "สแกน",
+	"qr", "card", "cash", "bank", "visa", "master", "jcb", "truemoney", "ทรูมันนี่", "วอลเล็ท", "wallet",
+	"กสิกร", "ไทยพาณิชย์", "กรุงเทพ", "กรุงไทย", "กรุงศรี", "ทหารไทย", "ออมสิน", "kbank", "scb", "ktb", "bbl", "ttb", "ktc",
+}
+
+// HasPaymentHint checks if message mentions a payment method (keywords or user's own banks/cards)
+func HasPaymentHint(message string, banks, cards []string) bool {
+	lower := strings.ToLower(message)
+	for _, kw := range paymentHintKeywords {
+		if strings.Contains(lower, kw) {
+			return true
+		}
+	}
+	for _, name := range append(append([]string{}, banks...), cards...) {
+		if name != "" && strings.Contains(lower, strings.ToLower(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// paymentKey groups transactions by payment method
+type paymentKey struct {
+	useType        int
+	bankName       string
+	creditCardName string
+}
+
+// InferPaymentMethod guesses payment method from the last 90 days of history.
+// Same merchant/description weighs more than same category. Returns nil if no history.
+func (s *MongoDBService) InferPaymentMethod(ctx context.Context, lineID, txType, merchant, description, category string) (*PaymentGuess, error) {
+	from := time.Now().AddDate(0, 0, -90).Format("2006-01-02")
+	cursor, err := s.collection.Find(ctx, bson.M{
+		"lineid": lineID,
+		"date":   bson.M{"$gte": from},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	name := strings.ToLower(strings.TrimSpace(merchant))
+	if name == "" {
+		name = strings.ToLower(strings.TrimSpace(description))
+	}
+	chain := KnownMerchant(merchant) // another branch of the same chain counts as the same shop
+
+	merchantCounts := make(map[paymentKey]int)
+	categoryCounts := make(map[paymentKey]int)
+	for cursor.Next(ctx) {
+		var record DailyRecord
+		if err := cursor.Decode(&record); err != nil {
+			continue
+		}
+		txs := record.Expenses
+		if txType == "income" {
+			txs = record.Incomes
+		}
+		for _, tx := range txs {
+			if tx.IsTransfer {
+				continue
+			}
+			key := paymentKey{tx.UseType, tx.BankName, tx.CreditCardName}
+			past := strings.ToLower(tx.CustName + " " + tx.Description)
+			if (name != "" && strings.Contains(past, name)) || (chain != "" && tx.Merchant == chain) {
+				merchantCounts[key]++
+			} else if category != "" && tx.Category == category {
+				categoryCounts[key]++
+			}
+		}
+	}
+
+	// Merchant history wins; category needs at least 2 matches to be trusted
+	if guess := pickPaymentGuess(merchantCounts, "merchant", 1); guess != nil {
+		return guess, nil
+	}
+	return pickPaymentGuess(categoryCounts, "category", 2), nil
+}
+
+// pickPaymentGuess returns the most used payment method with at least minCount uses
+func pickPaymentGuess(counts map[paymentKey]int, source string, minCount int) *PaymentGuess {
+	var best *PaymentGuess
+	for key, count := range counts {
+		if count < minCount {
+			continue
+		}
+		if best == nil || count > best.Count ||
+			(count == best.Count && key.bankName+key.creditCardName < best.BankName+best.CreditCardName) {
+			best = &PaymentGuess{
+				UseType:        key.useType,
+				BankName:       key.bankName,
+				CreditCardName: key.creditCardName,
+				Source:         source,
+				Count:          count,
+			}
+		}
+	}
+	return best
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PaymentRule assigns a default payment method to expenses of a category ("ค่าเดินทางตัดกสิกรเสมอ")
+type PaymentRule struct {
+	LineID         string    `bson:"lineid" json:"lineid"`
+	Category       string    `bson:"category" json:"category"` // normalized like guardrails ("ค่าเดินทาง" -> "เดินทาง")
+	UseType        int       `bson:"usetype" json:"usetype"`
+	BankName       string    `bson:"bankname,omitempty" json:"bankname,omitempty"`
+	CreditCardName string    `bson:"creditcardname,omitempty" json:"creditcardname,omitempty"`
+	UpdatedAt      time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// SetPaymentRule creates or replaces the default payment method of a category
+func (s *MongoDBService) SetPaymentRule(ctx context.Context, lineID, category string, account AccountRef) error {
+	_, err := s.paymentRuleCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID, "category": normalizeGuardrailCategory(category)},
+		bson.M{"$set": bson.M{
+			"usetype":        account.UseType,
+			"bankname":       account.BankName,
+			"creditcardname": account.CreditCardName,
+			"updated_at":     time.Now(),
+		}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// GetPaymentRules returns all category payment rules of a user
+func (s *MongoDBService) GetPaymentRules(ctx context.Context, lineID string) ([]PaymentRule, error) {
+	cursor, err := s.paymentRuleCollection.Find(ctx, bson.M{"lineid": lineID}, options.Find().SetSort(bson.M{"category": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rules []PaymentRule
+	if err := cursor.All(ctx, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// DeletePaymentRule removes the rule of a category, false if there was none
+func (s *MongoDBService) DeletePaymentRule(ctx context.Context, lineID, category string) (bool, error) {
+	result, err := s.paymentRuleCollection.DeleteOne(ctx, bson.M{"lineid": lineID, "category": normalizeGuardrailCategory(category)})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// ApplyPaymentRules sets payment method of expenses without one from matching category rules
+// Returns indexes of transactions that were changed
+func ApplyPaymentRules(rules []PaymentRule, txs []TransactionData) []int {
+	var applied []int
+	for i := range txs {
+		tx := &txs[i]
+		if tx.Type != "expense" || tx.UseType != 0 || tx.BankName != "" || tx.CreditCardName != "" {
+			continue
+		}
+		category := normalizeGuardrailCategory(tx.Category)
+		for _, rule := range rules {
+			if rule.Category == category {
+				tx.UseType, tx.BankName, tx.CreditCardName = rule.UseType, rule.BankName, rule.CreditCardName
+				applied = append(applied, i)
+				break
+			}
+		}
+	}
+	return applied
+}
+
+// paymentTargetPrefixes are words before a bank/card name ("บัตร KTC", "ธนาคารกสิกร")
+var paymentTargetPrefixes = []struct {
+	prefix  string
+	useType int
+}{
+	{"บัตรเครดิต", 1}, {"บัตร", 1}, {"ธนาคาร", 2}, {"บัญชี", 2}, {"ธ.", 2},
+}
+
+// ParsePaymentTarget resolves "กสิกร", "บัตร KTC", "เงินสด" or a nickname ("บัญชีเงินเดือน") to an account,
+// preferring the user's known names
+func ParsePaymentTarget(text string, banks, cards []string, aliases []AccountAlias) (AccountRef, bool) {
+	name := strings.TrimSpace(text)
+	if a, ok := FindAccountAlias(aliases, name); ok {
+		return a.Account(), true
+	}
+	if name == "เงินสด" {
+		return AccountRef{UseType: 0}, true
+	}
+	useType := 2
+	for _, p := range paymentTargetPrefixes {
+		if strings.HasPrefix(name, p.prefix) {
+			name, useType = strings.TrimSpace(strings.TrimPrefix(name, p.prefix)), p.useType
+			break
+		}
+	}
+	if name == "" {
+		return AccountRef{}, false
+	}
+	for _, c := range cards {
+		if strings.EqualFold(c, name) {
+			return AccountRef{UseType: 1, CreditCardName: c}, true
+		}
+	}
+	for _, b := range banks {
+		if strings.EqualFold(b, name) {
+			return AccountRef{UseType: 2, BankName: b}, true
+		}
+	}
+	if useType == 1 {
+		return AccountRef{UseType: 1, CreditCardName: name}, true
+	}
+	return AccountRef{UseType: 2, BankName: name}, true
+}
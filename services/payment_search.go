@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// paymentQuestionMarkers mark a question about spending (not a new entry like "กาแฟ 65 บัตร KTC")
+var paymentQuestionMarkers = []string{"เท่าไหร่", "เท่าไร", "กี่บาท", "ยอดรวม", "ดูรายการ", "ใช้อะไรไป", "รายการบัตร", "รายการธนาคาร"}
+
+// PaymentQuery is a statement request for one payment method over a date range
+type PaymentQuery struct {
+	Account AccountRef // UseType 0 = เงินสด
+	From    string     // YYYY-MM-DD
+	To      string     // YYYY-MM-DD
+	Period  string     // "เดือนนี้", "เมื่อวาน", ... for display
+}
+
+// IsPaymentQuestion reports whether text asks about spending (cheap check before loading names)
+func IsPaymentQuestion(text string) bool {
+	// Balance questions ("เงินสดเหลือเท่าไหร่") are answered by the balance flow
+	if strings.Contains(text, "เหลือ") || strings.Contains(text, "ค้าง") {
+		return false
+	}
+	for _, m := range paymentQuestionMarkers {
+		if strings.Contains(text, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePaymentQuery parses "เดือนนี้รูดบัตร KTC ไปเท่าไหร่" using the user's known banks/cards.
+// Date range defaults to this month.
+func ParsePaymentQuery(text string, banks, cards []string, now time.Time) (*PaymentQuery, bool) {
+	if !IsPaymentQuestion(text) {
+		return nil, false
+	}
+	lower := strings.ToLower(text)
+
+	var account *AccountRef
+	// Longest name first so "KTC Platinum" wins over "KTC"
+	if name := longestContained(lower, cards); name != "" {
+		account = &AccountRef{UseType: 1, CreditCardName: name}
+	} else if name := longestContained(lower, banks); name != "" {
+		account = &AccountRef{UseType: 2, BankName: name}
+	} else if strings.Contains(text, "เงินสด") {
+		account = &AccountRef{UseType: 0}
+	}
+	if account == nil {
+		return nil, false
+	}
+
+	q := &PaymentQuery{Account: *account}
+	if r, ok := ParseThaiDate(text, now); ok {
+		q.From, q.To, q.Period = r.FromString(), r.ToString(), r.Expression
+	} else {
+		q.From = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).Format("2006-01-02")
+		q.To, q.Period = now.Format("2006-01-02"), "เดือนนี้"
+	}
+	return q, true
+}
+
+// longestContained returns the longest name found in lower-cased text
+func longestContained(lower string, names []string) string {
+	best := ""
+	for _, n := range names {
+		if n != "" && len(n) > len(best) && strings.Contains(lower, strings.ToLower(n)) {
+			best = n
+		}
+	}
+	return best
+}
+
+// SearchByPaymentRange returns transactions paid with account between start and end (newest first)
+func (s *MongoDBService) SearchByPaymentRange(ctx context.Context, lineID string, account AccountRef, startDate, endDate string, limit int) ([]SearchResult, error) {
+	if limit <= 0 {
+		limit = 200
+	}
+
+	match := bson.M{"usetype": account.UseType}
+	switch account.UseType {
+	case 1:
+		match["creditcardname"] = account.CreditCardName
+	case 2:
+		match["bankname"] = account.BankName
+	}
+	filter := bson.M{
+		"lineid": lineID,
+		"date":   bson.M{"$gte": startDate, "$lte": endDate},
+		"$or": []bson.M{
+			{"expenses": bson.M{"$elemMatch": match}},
+			{"incomes": bson.M{"$elemMatch": match}},
+		},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: -1}})
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []SearchResult
+	for cursor.Next(ctx) && len(results) < limit {
+		var record DailyRecord
+		if err := cursor.Decode(&record); err != nil {
+			continue
+		}
+		for _, list := range [][]Transaction{record.Expenses, record.Incomes} {
+			for _, tx := range list {
+				if tx.UseType != account.UseType || len(results) >= limit {
+					continue
+				}
+				// Cash query skips other assets (usetype 0 with a bank name)
+				if (account.UseType == 1 && tx.CreditCardName != account.CreditCardName) ||
+					(account.UseType != 1 && tx.BankName != account.BankName) {
+					continue
+				}
+				results = append(results, SearchResult{Transaction: tx, Date: record.Date, RecordID: record.ID.Hex()})
+			}
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PeerBenchmarkMinUsers is the k-anonymity threshold: a band or category average
+// is only published when at least this many consenting users are behind it
+const PeerBenchmarkMinUsers = 10
+
+// peerBenchmarkTTL is how long computed aggregates are reused before recomputing
+const peerBenchmarkTTL = 24 * time.Hour
+
+// incomeBand is a monthly income range users are compared within (Max 0 = no upper limit)
+type incomeBand struct {
+	Max   float64
+	Label string
+}
+
+// peerIncomeBands are income bands from lowest to highest
+var peerIncomeBands = []incomeBand{
+	{15000, "ต่ำกว่า 15,000"},
+	{30000, "15,000-30,000"},
+	{50000, "30,000-50,000"},
+	{100000, "50,000-100,000"},
+	{0, "100,000 ขึ้นไป"},
+}
+
+// IncomeBand returns the income band label of a monthly income ("" when no income recorded)
+func IncomeBand(income float64) string {
+	if income <= 0 {
+		return ""
+	}
+	for _, b := range peerIncomeBands {
+		if b.Max == 0 || income < b.Max {
+			return b.Label
+		}
+	}
+	return ""
+}
+
+// PeerSample is one consenting user's month, used only in memory while aggregating
+type PeerSample struct {
+	Income   float64
+	Spending map[string]float64 // category -> amount
+}
+
+// PeerBenchmark is the published aggregate of one income band for one month (no user IDs)
+type PeerBenchmark struct {
+	Month      string             `bson:"month" json:"month"` // YYYY-MM
+	Band       string             `bson:"band" json:"band"`
+	Users      int                `bson:"users" json:"users"`
+	Categories map[string]float64 `bson:"categories" json:"categories"` // category -> average per user in band
+	ComputedAt time.Time          `bson:"computed_at" json:"computed_at"`
+}
+
+// AggregatePeerSpending averages spending per category across samples of one band
+// Returns nil when fewer than k samples; categories spent by fewer than k users are dropped
+func AggregatePeerSpending(samples []PeerSample, k int) map[string]float64 {
+	if len(samples) < k {
+		return nil
+	}
+	totals := make(map[string]float64)
+	spenders := make(map[string]int)
+	for _, sample := range samples {
+		for category, amount := range sample.Spending {
+			if amount <= 0 {
+				continue
+			}
+			totals[category] += amount
+			spenders[category]++
+		}
+	}
+	averages := make(map[string]float64)
+	for category, total := range totals {
+		if spenders[category] < k {
+			continue
+		}
+		averages[category] = total / float64(len(samples))
+	}
+	return averages
+}
+
+// SetPeerBenchmark opts user in or out of anonymized peer benchmarking (default out)
+func (s *MongoDBService) SetPeerBenchmark(ctx context.Context, lineID string, enabled bool) error {
+	_, err := s.settingsCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID},
+		bson.M{"$set": bson.M{"peer_benchmark": enabled, "updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// getPeerSample sums one user's income and spending by category between two dates
+func (s *MongoDBService) getPeerSample(ctx context.Context, lineID, from, to string) (PeerSample, error) {
+	sample := PeerSample{Spending: make(map[string]float64)}
+	cursor, err := s.collection.Find(ctx, bson.M{"lineid": lineID, "date": bson.M{"$gte": from, "$lte": to}})
+	if err != nil {
+		return sample, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var record DailyRecord
+		if err := cursor.Decode(&record); err != nil {
+			continue
+		}
+		for _, tx := range record.Incomes {
+			if !tx.IsAssetMove() {
+				sample.Income += tx.Amount
+			}
+		}
+		for _, tx := range record.Expenses {
+			if tx.IsAssetMove() {
+				continue
+			}
+			category := tx.Category
+			if category == "" {
+				category = "อื่นๆ"
+			}
+			sample.Spending[category] += tx.Amount
+		}
+	}
+	return sample, nil
+}
+
+// peerMonthRange returns last full month (YYYY-MM and first/last day) benchmarks are computed on
+func peerMonthRange(now time.Time) (month, from, to string) {
+	first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).AddDate(0, -1, 0)
+	last := first.AddDate(0, 1, -1)
+	return first.Format("2006-01"), first.Format("2006-01-02"), last.Format("2006-01-02")
+}
+
+// RefreshPeerBenchmarks recomputes last month's aggregates of every income band from consenting users
+// Only band/category averages are stored; per-user samples never leave memory
+func (s *MongoDBService) RefreshPeerBenchmarks(ctx context.Context, now time.Time) error {
+	month, from, to := peerMonthRange(now)
+	values, err := s.settingsCollection.Distinct(ctx, "lineid", bson.M{"peer_benchmark": true})
+	if err != nil {
+		return err
+	}
+
+	bands := make(map[string][]PeerSample)
+	for _, v := range values {
+		lineID, ok := v.(string)
+		if !ok {
+			continue
+		}
+		sample, err := s.getPeerSample(ctx, lineID, from, to)
+		if err != nil {
+			continue
+		}
+		if band := IncomeBand(sample.Income); band != "" {
+			bands[band] = append(bands[band], sample)
+		}
+	}
+
+	for _, b := range peerIncomeBands {
+		samples := bands[b.Label]
+		benchmark := PeerBenchmark{
+			Month:      month,
+			Band:       b.Label,
+			Users:      len(samples),
+			Categories: AggregatePeerSpending(samples, PeerBenchmarkMinUsers),
+			ComputedAt: time.Now(),
+		}
+		if _, err := s.benchmarkCollection.ReplaceOne(ctx,
+			bson.M{"month": month, "band": b.Label},
+			benchmark,
+			options.Replace().SetUpsert(true),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPeerBenchmark returns last month's benchmark of user's income band (recomputed when stale)
+// along with user's own sample of that month; benchmark is nil when user had no income recorded
+func (s *MongoDBService) GetPeerBenchmark(ctx context.Context, lineID string, now time.Time) (*PeerBenchmark, PeerSample, error) {
+	month, from, to := peerMonthRange(now)
+	own, err := s.getPeerSample(ctx, lineID, from, to)
+	if err != nil {
+		return nil, own, err
+	}
+	band := IncomeBand(own.Income)
+	if band == "" {
+		return nil, own, nil
+	}
+
+	var benchmark PeerBenchmark
+	err = s.benchmarkCollection.FindOne(ctx, bson.M{"month": month, "band": band}).Decode(&benchmark)
+	if err == mongo.ErrNoDocuments || (err == nil && time.Since(benchmark.ComputedAt) > peerBenchmarkTTL) {
+		if err := s.RefreshPeerBenchmarks(ctx, now); err != nil {
+			return nil, own, err
+		}
+		err = s.benchmarkCollection.FindOne(ctx, bson.M{"month": month, "band": band}).Decode(&benchmark)
+	}
+	if err != nil {
+		return nil, own, err
+	}
+	return &benchmark, own, nil
+}
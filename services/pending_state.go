@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// pendingStates maps temp data key prefixes of conversation states (waiting for the user's next
+// message or tap) to Thai names shown when they are cancelled or about to expire.
+// Keys are prefix + lineID, or prefix + lineID + "_" + unix time for per-message confirmations.
+var pendingStates = []struct {
+	Prefix string
+	Label  string
+}{
+	{"slip_pending_", "สลิปที่รอเลือกหมวด"},
+	{"slip_", "สลิปที่รอยืนยัน"},
+	{"edit_target_", "การแก้ไขรายการ"},
+	{"liff_edit_", "การแก้ไขรายการ"},
+	{"budget_edit_", "การแก้งบประมาณ"},
+	{"new_account_", "การเพิ่มบัญชีใหม่"},
+	{"amount_only_", "ยอดเงินที่รอคำอธิบาย"},
+	{"amount_", "รายการที่รอยืนยันยอด"},
+	{"guardrail_", "รายการที่รอยืนยันเกินงบ"},
+	{"receipt_images_", "รูปใบเสร็จที่รอรวม"},
+	{"statement_", "statement ที่รอนำเข้า"},
+}
+
+// pendingStateKeyRe matches keys that end with a LINE user ID and optional unix time
+var pendingStateKeyRe = regexp.MustCompile(`^([a-z_]+_)(U[0-9a-f]+)(_\d+)?$`)
+
+// PendingStateLabel returns the Thai name and owner of a conversation state temp key
+func PendingStateLabel(key string) (lineID, label string, ok bool) {
+	m := pendingStateKeyRe.FindStringSubmatch(key)
+	if m == nil {
+		return "", "", false
+	}
+	for _, st := range pendingStates {
+		if m[1] == st.Prefix {
+			return m[2], st.Label, true
+		}
+	}
+	return "", "", false
+}
+
+// PendingReminder is a conversation state about to expire
+type PendingReminder struct {
+	LineID    string
+	Label     string
+	ExpiresAt time.Time
+}
+
+// ClearPendingStates deletes every unexpired conversation state of the user ("ยกเลิก")
+// and returns the names of what was abandoned, without duplicates
+func (s *MongoDBService) ClearPendingStates(ctx context.Context, lineID string) ([]string, error) {
+	cursor, err := s.tempCollection.Find(ctx, bson.M{
+		"key":        primitive.Regex{Pattern: "_" + regexp.QuoteMeta(lineID) + `(_\d+)?$`},
+		"expires_at": bson.M{"$gt": time.Now()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var docs []struct {
+		Key string `bson:"key"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	var keys, labels []string
+	for _, d := range docs {
+		owner, label, ok := PendingStateLabel(d.Key)
+		if !ok || owner != lineID {
+			continue
+		}
+		keys = append(keys, d.Key)
+		if !slices.Contains(labels, label) {
+			labels = append(labels, label)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	if _, err := s.tempCollection.DeleteMany(ctx, bson.M{"key": bson.M{"$in": keys}}); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// DuePendingReminders returns conversation states expiring within the window that were not reminded yet,
+// and marks them reminded so each state gets one reminder
+func (s *MongoDBService) DuePendingReminders(ctx context.Context, within time.Duration) ([]PendingReminder, error) {
+	now := time.Now()
+	cursor, err := s.tempCollection.Find(ctx, bson.M{
+		"expires_at": bson.M{"$gt": now, "$lte": now.Add(within)},
+		"reminded":   bson.M{"$ne": true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var docs []struct {
+		Key       string    `bson:"key"`
+		ExpiresAt time.Time `bson:"expires_at"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	var reminders []PendingReminder
+	var keys []string
+	for _, d := range docs {
+		lineID, label, ok := PendingStateLabel(d.Key)
+		if !ok {
+			continue
+		}
+		keys = append(keys, d.Key)
+		reminders = append(reminders, PendingReminder{LineID: lineID, Label: label, ExpiresAt: d.ExpiresAt})
+	}
+	if len(keys) > 0 {
+		if _, err := s.tempCollection.UpdateMany(ctx, bson.M{"key": bson.M{"$in": keys}}, bson.M{"$set": bson.M{"reminded": true}}); err != nil {
+			return nil, err
+		}
+	}
+	return reminders, nil
+}
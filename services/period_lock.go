@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrPeriodLocked is returned when saving, editing or deleting inside a closed period
+var ErrPeriodLocked = errors.New("period is closed")
+
+// ErrDateArchived is the ErrPeriodLocked of days moved to the archive (reopening the period doesn't unlock them)
+var ErrDateArchived = fmt.Errorf("%w: archived", ErrPeriodLocked)
+
+// ClosePeriod locks all transactions dated on or before through (YYYY-MM-DD)
+// Only past days can be closed so today's entries stay editable
+func (s *MongoDBService) ClosePeriod(ctx context.Context, lineID, through string) error {
+	if _, err := time.Parse("2006-01-02", through); err != nil {
+		return fmt.Errorf("invalid date: %s", through)
+	}
+	if through >= time.Now().Format("2006-01-02") {
+		return fmt.Errorf("ปิดงวดได้เฉพาะวันที่ผ่านมาแล้ว")
+	}
+	_, err := s.settingsCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID},
+		bson.M{"$set": bson.M{"locked_until": through, "updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// ReopenPeriod removes the lock so past transactions can be edited again
+func (s *MongoDBService) ReopenPeriod(ctx context.Context, lineID string) error {
+	_, err := s.settingsCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID},
+		bson.M{"$unset": bson.M{"locked_until": ""}, "$set": bson.M{"updated_at": time.Now()}},
+	)
+	return err
+}
+
+// IsDateLocked reports whether date falls inside the closed period or the archived days
+func (u *UserSettings) IsDateLocked(date string) bool {
+	through := u.LockedThrough()
+	return through != "" && date != "" && date <= through
+}
+
+// LockedThrough returns the last read-only date: the closed period or archived days, whichever is later
+func (u *UserSettings) LockedThrough() string {
+	if u == nil {
+		return ""
+	}
+	if u.ArchivedThrough > u.LockedUntil {
+		return u.ArchivedThrough
+	}
+	return u.LockedUntil
+}
+
+// checkPeriodOpen returns ErrPeriodLocked when date is inside the user's closed period (ErrDateArchived for archived days)
+func (s *MongoDBService) checkPeriodOpen(ctx context.Context, lineID, date string) error {
+	settings, err := s.GetUserSettings(ctx, lineID)
+	if err != nil {
+		return err
+	}
+	return periodOpen(settings, date)
+}
+
+// periodOpen is checkPeriodOpen for settings already loaded
+func periodOpen(settings *UserSettings, date string) error {
+	if date != "" && date <= settings.ArchivedThrough {
+		return fmt.Errorf("%w through %s", ErrDateArchived, settings.ArchivedThrough)
+	}
+	if settings.IsDateLocked(date) {
+		return fmt.Errorf("%w through %s", ErrPeriodLocked, settings.LockedUntil)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// excelSheetName trims sheet name to Excel's 31 character limit and removes invalid characters
+func excelSheetName(name string) string {
+	invalid := map[rune]bool{':': true, '\\': true, '/': true, '?': true, '*': true, '[': true, ']': true}
+	var runes []rune
+	for _, r := range name {
+		if invalid[r] {
+			continue
+		}
+		runes = append(runes, r)
+		if len(runes) >= 31 {
+			break
+		}
+	}
+	if len(runes) == 0 {
+		return "โปรเจกต์"
+	}
+	return string(runes)
+}
+
+// UniqueExcelSheetName returns a valid sheet name not in used ("name (2)", "name (3)", ...) and marks it used
+// The base is trimmed before the suffix is added, so long names sharing 31 characters still differ
+func UniqueExcelSheetName(name string, used map[string]bool) string {
+	sheet := excelSheetName(name)
+	for i := 2; used[sheet]; i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		base := []rune(excelSheetName(name))
+		if limit := 31 - len([]rune(suffix)); len(base) > limit {
+			base = base[:limit]
+		}
+		sheet = string(base) + suffix
+	}
+	used[sheet] = true
+	return sheet
+}
+
+// ExportProjects generates Excel with P&L summary sheet and one sheet per project (all time)
+func (s *ExportService) ExportProjects(ctx context.Context, lineID string) ([]byte, string, error) {
+	summaries, err := s.mongo.GetProjectSummaries(ctx, lineID, "", "")
+	if err != nil {
+		return nil, "", fmt.Errorf("ไม่สามารถดึงข้อมูลได้: %w", err)
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheetName := "สรุปโปรเจกต์"
+	f.SetSheetName("Sheet1", sheetName)
+
+	titleStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 16, Color: "#FFFFFF"},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{colorPrimary}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
+	})
+	headerStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF"},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{colorSecondary}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
+	})
+	numberStyle, _ := f.NewStyle(&excelize.Style{
+		Alignment: &excelize.Alignment{Horizontal: "right"},
+		NumFmt:    4, // #,##0.00
+	})
+	totalStyle, _ := f.NewStyle(&excelize.Style{
+		Font:   &excelize.Font{Bold: true, Color: "#FFFFFF"},
+		Fill:   excelize.Fill{Type: "pattern", Color: []string{colorPrimary}, Pattern: 1},
+		NumFmt: 4,
+	})
+
+	// Summary sheet
+	f.MergeCell(sheetName, "A1", "E1")
+	f.SetCellValue(sheetName, "A1", "💼 กำไร/ขาดทุนรายโปรเจกต์")
+	f.SetCellStyle(sheetName, "A1", "E1", titleStyle)
+	f.SetRowHeight(sheetName, 1, 30)
+
+	headers := []string{"โปรเจกต์/ลูกค้า", "รายรับ", "รายจ่าย", "กำไร", "จำนวนรายการ"}
+	for i, header := range headers {
+		f.SetCellValue(sheetName, fmt.Sprintf("%c2", 'A'+i), header)
+	}
+	f.SetCellStyle(sheetName, "A2", "E2", headerStyle)
+
+	var totalIncome, totalExpense float64
+	row := 3
+	for _, p := range summaries {
+		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), p.Project)
+		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), p.Income)
+		f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), p.Expense)
+		f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), p.Profit)
+		f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), p.TransactionCount)
+		f.SetCellStyle(sheetName, fmt.Sprintf("B%d", row), fmt.Sprintf("D%d", row), numberStyle)
+		totalIncome += p.Income
+		totalExpense += p.Expense
+		row++
+	}
+	if len(summaries) == 0 {
+		f.MergeCell(sheetName, "A3", "E3")
+		f.SetCellValue(sheetName, "A3", "ยังไม่มีรายการที่ระบุโปรเจกต์")
+		row++
+	}
+	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "รวม")
+	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), totalIncome)
+	f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), totalExpense)
+	f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), totalIncome-totalExpense)
+	f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("E%d", row), totalStyle)
+	f.SetColWidth(sheetName, "A", "A", 28)
+	f.SetColWidth(sheetName, "B", "E", 15)
+
+	// One sheet per project
+	used := map[string]bool{sheetName: true}
+	for _, p := range summaries {
+		results, err := s.mongo.GetProjectTransactions(ctx, lineID, p.Project)
+		if err != nil {
+			continue
+		}
+
+		name := UniqueExcelSheetName(p.Project, used)
+		f.NewSheet(name)
+
+		f.MergeCell(name, "A1", "E1")
+		f.SetCellValue(name, "A1", fmt.Sprintf("💼 %s", p.Project))
+		f.SetCellStyle(name, "A1", "E1", titleStyle)
+		f.SetRowHeight(name, 1, 30)
+
+		projectHeaders := []string{"วันที่", "หมวด", "รายละเอียด", "รายรับ", "รายจ่าย"}
+		for i, header := range projectHeaders {
+			f.SetCellValue(name, fmt.Sprintf("%c2", 'A'+i), header)
+		}
+		f.SetCellStyle(name, "A2", "E2", headerStyle)
+
+		r := 3
+		for _, res := range results {
+			tx := res.Transaction
+			f.SetCellValue(name, fmt.Sprintf("A%d", r), res.Date)
+			f.SetCellValue(name, fmt.Sprintf("B%d", r), tx.Category)
+			f.SetCellValue(name, fmt.Sprintf("C%d", r), tx.Description)
+			if tx.Type == 1 {
+				f.SetCellValue(name, fmt.Sprintf("D%d", r), tx.Amount)
+			} else {
+				f.SetCellValue(name, fmt.Sprintf("E%d", r), tx.Amount)
+			}
+			f.SetCellStyle(name, fmt.Sprintf("D%d", r), fmt.Sprintf("E%d", r), numberStyle)
+			r++
+		}
+		f.SetCellValue(name, fmt.Sprintf("A%d", r), fmt.Sprintf("กำไร %.2f", p.Profit))
+		f.SetCellValue(name, fmt.Sprintf("D%d", r), p.Income)
+		f.SetCellValue(name, fmt.Sprintf("E%d", r), p.Expense)
+		f.SetCellStyle(name, fmt.Sprintf("A%d", r), fmt.Sprintf("E%d", r), totalStyle)
+		f.SetColWidth(name, "A", "B", 14)
+		f.SetColWidth(name, "C", "C", 30)
+		f.SetColWidth(name, "D", "E", 15)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		return nil, "", fmt.Errorf("cannot create Excel: %w", err)
+	}
+
+	randomNum := fmt.Sprintf("%d%d", time.Now().UnixNano(), time.Now().UnixMicro()%10000)
+	return buf.Bytes(), fmt.Sprintf("%s.xlsx", randomNum), nil
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// projectionDebounce is how long a rebuild waits so a burst of writes is folded into one pass
+const projectionDebounce = 2 * time.Second
+
+// projectionLease is how long one instance may hold a user's rebuild before another takes over
+const projectionLease = time.Minute
+
+// UserProjection is a user's precomputed read model (user_projections) so balance, summary and
+// budget replies are one document fetch
+// Every write bumps WriteSeq right away; the projection worker rebuilds it and stamps BuiltSeq
+// BuildLease and BuildingUntil let one instance at a time rebuild it, Builds counts stored rebuilds
+type UserProjection struct {
+	LineID        string             `bson:"lineid"`
+	WriteSeq      int64              `bson:"write_seq"`
+	BuiltSeq      int64              `bson:"built_seq"`
+	Builds        int64              `bson:"builds"`
+	BuildLease    primitive.ObjectID `bson:"build_lease,omitempty"`
+	BuildingUntil time.Time          `bson:"building_until,omitempty"`
+	Day           string             `bson:"day"` // YYYY-MM-DD the today/month parts belong to
+	Balances      []PaymentBalance   `bson:"balances"`
+	Summary       BalanceSummary     `bson:"summary"`
+	Today         *PeriodSummary     `bson:"today"`
+	Month         *PeriodSummary     `bson:"month"`
+	Budgets       []BudgetStatus     `bson:"budgets"`
+	BuiltAt       time.Time          `bson:"built_at"`
+}
+
+// Fresh reports whether the projection reflects every write and is still for today
+func (p *UserProjection) Fresh(today string) bool {
+	return p != nil && p.BuiltSeq == p.WriteSeq && p.Day == today && !p.BuiltAt.IsZero()
+}
+
+// freshProjection returns the user's projection when it is up to date; otherwise nil and a
+// rebuild is scheduled so the next read is fast (callers fall back to live queries)
+func (s *MongoDBService) freshProjection(ctx context.Context, lineID string) *UserProjection {
+	var p UserProjection
+	err := s.projectionCollection.FindOne(ctx, bson.M{"lineid": lineID}).Decode(&p)
+	if err == nil && p.Fresh(time.Now().Format("2006-01-02")) {
+		return &p
+	}
+	s.scheduleProjection(lineID)
+	return nil
+}
+
+// ProjectedBalances returns balances by payment type from the read model (live when stale)
+func (s *MongoDBService) ProjectedBalances(ctx context.Context, lineID string) ([]PaymentBalance, error) {
+	if p := s.freshProjection(ctx, lineID); p != nil {
+		return p.Balances, nil
+	}
+	return s.GetBalanceByPaymentType(ctx, lineID)
+}
+
+// ProjectedBalanceSummary returns the balance summary from the read model (live when stale)
+func (s *MongoDBService) ProjectedBalanceSummary(ctx context.Context, lineID string) (*BalanceSummary, error) {
+	if p := s.freshProjection(ctx, lineID); p != nil {
+		return &p.Summary, nil
+	}
+	return s.GetBalanceSummary(ctx, lineID)
+}
+
+// ProjectedPeriodSummary returns today's ("day") or this month's ("month") summary from the read
+// model (live when stale)
+func (s *MongoDBService) ProjectedPeriodSummary(ctx context.Context, lineID, period string) (*PeriodSummary, error) {
+	if p := s.freshProjection(ctx, lineID); p != nil {
+		if period == "month" && p.Month != nil {
+			return p.Month, nil
+		}
+		if period == "day" && p.Today != nil {
+			return p.Today, nil
+		}
+	}
+	if period == "month" {
+		return s.GetMonthlySummary(ctx, lineID)
+	}
+	return s.GetDailySummary(ctx, lineID)
+}
+
+// ProjectedBudgetStatus returns this month's budget status from the read model (live when stale)
+func (s *MongoDBService) ProjectedBudgetStatus(ctx context.Context, lineID string) ([]BudgetStatus, error) {
+	if p := s.freshProjection(ctx, lineID); p != nil {
+		return p.Budgets, nil
+	}
+	return s.GetBudgetStatus(ctx, lineID)
+}
+
+// markProjectionStale bumps the user's write sequence so readers stop trusting the projection,
+// then schedules a rebuild
+// Runs inside the write (one indexed update, no upsert: a user without a projection has nothing to
+// mark) so the reply right after a save never reads old totals
+func (s *MongoDBService) markProjectionStale(lineID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	result, err := s.projectionCollection.UpdateOne(ctx, bson.M{"lineid": lineID}, bson.M{"$inc": bson.M{"write_seq": 1}})
+	if err != nil {
+		log.Printf("Failed to mark projection stale: %v", err)
+	}
+	if err == nil && result.MatchedCount == 0 {
+		return // built on the first read
+	}
+	s.scheduleProjection(lineID)
+}
+
+// onProjectionTransaction is the transaction hook keeping projections current
+func (s *MongoDBService) onProjectionTransaction(event, lineID, date string, tx Transaction) {
+	s.markProjectionStale(lineID)
+}
+
+// scheduleProjection rebuilds a user's projection in background, at most one pass per
+// projectionDebounce across every instance
+// Writes during a rebuild only bump WriteSeq; the instance holding the lease in user_projections
+// rebuilds until BuiltSeq catches up, so a burst of writes costs one or two rebuilds instead of one each
+// (a write landing while this instance's worker exits only leaves the projection stale, and the
+// next read schedules it again)
+func (s *MongoDBService) scheduleProjection(lineID string) {
+	if _, busy := s.projecting.LoadOrStore(lineID, struct{}{}); busy {
+		return
+	}
+	GoSafe("projection.build", func() {
+		defer s.projecting.Delete(lineID)
+		ctx, cancel := context.WithTimeout(context.Background(), projectionLease)
+		defer cancel()
+		if err := s.runProjectionBuilds(ctx, lineID); err != nil {
+			log.Printf("Failed to build projection: %v", err)
+		}
+	})
+}
+
+// runProjectionBuilds takes the user's rebuild lease and rebuilds until the projection is current
+// Another instance holding the lease will see the new WriteSeq itself; after releasing, the
+// sequence is checked once more so a write that found the lease taken isn't left unbuilt
+func (s *MongoDBService) runProjectionBuilds(ctx context.Context, lineID string) error {
+	for {
+		lease, err := s.acquireProjectionLease(ctx, lineID)
+		if err != nil || lease.IsZero() {
+			return err
+		}
+		for {
+			time.Sleep(projectionDebounce)
+			current, err := s.buildProjection(ctx, lineID)
+			if err != nil {
+				s.releaseProjectionLease(lineID, lease)
+				return err
+			}
+			if current {
+				break
+			}
+		}
+		s.releaseProjectionLease(lineID, lease)
+
+		var p UserProjection
+		opts := options.FindOne().SetProjection(bson.M{"write_seq": 1, "built_seq": 1})
+		if err := s.projectionCollection.FindOne(ctx, bson.M{"lineid": lineID}, opts).Decode(&p); err != nil || p.BuiltSeq == p.WriteSeq {
+			return err
+		}
+	}
+}
+
+// acquireProjectionLease claims the user's rebuild; a zero lease means another instance holds it
+func (s *MongoDBService) acquireProjectionLease(ctx context.Context, lineID string) (primitive.ObjectID, error) {
+	// First build of a user: create the document so the conditional updates have something to match
+	_, err := s.projectionCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID},
+		bson.M{"$setOnInsert": bson.M{"write_seq": 0}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	lease := primitive.NewObjectID()
+	now := time.Now()
+	result, err := s.projectionCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID, "$or": bson.A{
+			bson.M{"building_until": bson.M{"$exists": false}},
+			bson.M{"building_until": bson.M{"$lt": now}},
+		}},
+		bson.M{"$set": bson.M{"build_lease": lease, "building_until": now.Add(projectionLease)}},
+	)
+	if err != nil || result.ModifiedCount == 0 {
+		return primitive.NilObjectID, err
+	}
+	return lease, nil
+}
+
+// releaseProjectionLease gives up the rebuild lease if it's still ours
+func (s *MongoDBService) releaseProjectionLease(lineID string, lease primitive.ObjectID) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := s.projectionCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID, "build_lease": lease},
+		bson.M{"$unset": bson.M{"build_lease": "", "building_until": ""}},
+	)
+	if err != nil {
+		log.Printf("Failed to release projection lease: %v", err)
+	}
+}
+
+// buildProjection recomputes a user's read model; it's stored only if no write landed meanwhile
+// Returns whether the stored projection is current (false = a write landed, build again)
+func (s *MongoDBService) buildProjection(ctx context.Context, lineID string) (bool, error) {
+	var current UserProjection
+	opts := options.FindOne().SetProjection(bson.M{"write_seq": 1})
+	if err := s.projectionCollection.FindOne(ctx, bson.M{"lineid": lineID}, opts).Decode(&current); err != nil {
+		return false, err
+	}
+
+	today := time.Now().Format("2006-01-02")
+	balances, err := s.GetBalanceByPaymentType(ctx, lineID)
+	if err != nil {
+		return false, err
+	}
+	summary, err := s.GetBalanceSummary(ctx, lineID)
+	if err != nil {
+		return false, err
+	}
+	daily, err := s.GetDailySummary(ctx, lineID)
+	if err != nil {
+		return false, err
+	}
+	monthly, err := s.GetMonthlySummary(ctx, lineID)
+	if err != nil {
+		return false, err
+	}
+	budgets, err := s.GetBudgetStatus(ctx, lineID)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := s.projectionCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID, "write_seq": current.WriteSeq},
+		bson.M{
+			"$set": bson.M{
+				"built_seq": current.WriteSeq,
+				"day":       today,
+				"balances":  balances,
+				"summary":   summary,
+				"today":     daily,
+				"month":     monthly,
+				"budgets":   budgets,
+				"built_at":  time.Now(),
+			},
+			"$inc": bson.M{"builds": 1},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// promptFiles are the files of one prompt set, in the order they are hashed into its version
+var promptFiles = []string{"system.md", "examples.md", "receipt.md", "intent.md", "extract.md", "receipt_lang.md"}
+
+// ErrPromptsMissing is returned by ReloadPrompts when system.md can't be read; the running prompts stay
+var ErrPromptsMissing = errors.New("system.md not found")
+
+// PromptSet is one version of the prompt files; requests keep the set they started with during a reload
+type PromptSet struct {
+	Dir      string
+	System   string
+	Examples string
+	Receipt  string
+	Intent   string
+	Extract  map[string]string // extract.md sections by action
+	// receipt_lang.md sections by receipt language (en, ja, zh) plus "common"
+	ReceiptLang map[string]string
+	Version     string // hash of the file contents, logged with every AI call
+	LoadedAt    time.Time
+	Missing     []string // files that couldn't be read (system/receipt fall back to built-in defaults)
+}
+
+// LoadPromptSet reads the prompt files of dir
+func LoadPromptSet(dir string) *PromptSet {
+	contents := make(map[string]string, len(promptFiles))
+	var missing []string
+	for _, name := range promptFiles {
+		content := loadPromptFile(filepath.Join(dir, name))
+		if content == "" {
+			missing = append(missing, name)
+		}
+		contents[name] = content
+	}
+
+	p := &PromptSet{
+		Dir:         dir,
+		System:      contents["system.md"],
+		Examples:    contents["examples.md"],
+		Receipt:     contents["receipt.md"],
+		Intent:      contents["intent.md"],
+		Extract:     ParsePromptSections(contents["extract.md"]),
+		ReceiptLang: ParsePromptSections(contents["receipt_lang.md"]),
+		LoadedAt:    time.Now(),
+		Missing:     missing,
+	}
+	if p.System == "" {
+		p.System = getDefaultSystemPrompt()
+	}
+	if p.Receipt == "" {
+		p.Receipt = getDefaultReceiptPrompt()
+	}
+	parts := make([]string, len(promptFiles))
+	for i, name := range promptFiles {
+		parts[i] = contents[name]
+	}
+	p.Version = PromptVersion(parts...)
+	return p
+}
+
+// PromptVersion returns a short hash identifying prompt contents ("3f9a1c2e")
+func PromptVersion(contents ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(contents, "\x00")))
+	return hex.EncodeToString(sum[:4])
+}
+
+// HasPipeline reports whether the two-stage prompts (intent.md, extract.md) are loaded
+func (p *PromptSet) HasPipeline() bool {
+	return p.Intent != "" && len(p.Extract) > 0
+}
+
+// currentPrompts returns the prompt set new requests use
+func (s *AIService) currentPrompts() *PromptSet {
+	s.promptsMu.RLock()
+	defer s.promptsMu.RUnlock()
+	return s.prompts
+}
+
+// PromptVersion returns the version of the prompts new requests use
+func (s *AIService) PromptVersion() string {
+	return s.currentPrompts().Version
+}
+
+// ReloadPrompts rereads the prompt files and swaps them in without a restart
+// Returns the previous version; a set without system.md is rejected so a half-synced directory can't take over
+func (s *AIService) ReloadPrompts() (next *PromptSet, previous string, err error) {
+	current := s.currentPrompts()
+	dir := current.Dir
+	if _, statErr := os.Stat(dir); statErr != nil {
+		dir = findPromptsDir()
+	}
+	next = LoadPromptSet(dir)
+	for _, name := range next.Missing {
+		if name == "system.md" {
+			return current, current.Version, ErrPromptsMissing
+		}
+	}
+
+	s.promptsMu.Lock()
+	s.prompts = next
+	s.promptsMu.Unlock()
+	if next.Version != current.Version {
+		log.Printf("Reloaded prompts from %s: version %s -> %s", dir, current.Version, next.Version)
+	}
+	return next, current.Version, nil
+}
+
+// promptFilesStamp summarizes size and modification time of the prompt files (cheap change check)
+func promptFilesStamp(dir string) string {
+	var b strings.Builder
+	for _, name := range promptFiles {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			b.WriteString(info.ModTime().UTC().Format(time.RFC3339Nano))
+			b.WriteString(strconv.FormatInt(info.Size(), 10))
+		}
+		b.WriteByte('|')
+	}
+	return b.String()
+}
+
+// WatchPrompts reloads the prompts whenever the files change, checked every interval until ctx ends
+func (s *AIService) WatchPrompts(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	stamp := promptFilesStamp(s.currentPrompts().Dir)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			next := promptFilesStamp(s.currentPrompts().Dir)
+			if next == stamp {
+				continue
+			}
+			stamp = next
+			if _, _, err := s.ReloadPrompts(); err != nil {
+				log.Printf("Prompt watcher: %v", err)
+			}
+		}
+	}
+}
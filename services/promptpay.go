@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NormalizePromptPayID validates PromptPay ID: mobile (10 digits), national/tax ID (13) or e-wallet (15)
+func NormalizePromptPayID(id string) (string, error) {
+	id = strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(id))
+	id = thaiDigitReplacer.Replace(id)
+	for _, r := range id {
+		if r < '0' || r > '9' {
+			return "", fmt.Errorf("พร้อมเพย์ต้องเป็นตัวเลขเท่านั้น")
+		}
+	}
+	switch {
+	case len(id) == 10 && id[0] == '0', len(id) == 13, len(id) == 15:
+		return id, nil
+	}
+	return "", fmt.Errorf("ใช้เบอร์มือถือ 10 หลัก, เลขบัตรประชาชน 13 หลัก หรือ e-Wallet 15 หลัก")
+}
+
+// SetPromptPayID saves user's PromptPay ID for QR generation
+func (s *MongoDBService) SetPromptPayID(ctx context.Context, lineID, promptPayID string) error {
+	id, err := NormalizePromptPayID(promptPayID)
+	if err != nil {
+		return err
+	}
+	_, err = s.settingsCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID},
+		bson.M{"$set": bson.M{"promptpay_id": id, "updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// emvField formats one EMVCo TLV field (2-digit tag, 2-digit length, value)
+func emvField(tag, value string) string {
+	return fmt.Sprintf("%s%02d%s", tag, len(value), value)
+}
+
+// crc16CCITT computes CRC-16/CCITT-FALSE used by Thai QR payment
+func crc16CCITT(data string) uint16 {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(data); i++ {
+		crc ^= uint16(data[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// PromptPayPayload builds Thai QR (EMVCo) payload for PromptPay ID, amount 0 lets payer type it
+func PromptPayPayload(promptPayID string, amount float64) (string, error) {
+	id, err := NormalizePromptPayID(promptPayID)
+	if err != nil {
+		return "", err
+	}
+
+	// Account sub-tag: 01 mobile ("0066" + number without leading 0), 02 national/tax ID, 03 e-wallet
+	var account string
+	switch len(id) {
+	case 10:
+		account = emvField("01", "0066"+id[1:])
+	case 13:
+		account = emvField("02", id)
+	default:
+		account = emvField("03", id)
+	}
+
+	initiation := "11" // static, reusable
+	if amount > 0 {
+		initiation = "12" // dynamic, fixed amount
+	}
+	payload := emvField("00", "01") +
+		emvField("01", initiation) +
+		emvField("29", emvField("00", "A000000677010111")+account) +
+		emvField("58", "TH") +
+		emvField("53", "764")
+	if amount > 0 {
+		payload += emvField("54", fmt.Sprintf("%.2f", amount))
+	}
+	payload += "6304"
+	return payload + fmt.Sprintf("%04X", crc16CCITT(payload)), nil
+}
+
+// PromptPayQRPNG renders PromptPay QR image as PNG
+func PromptPayQRPNG(promptPayID string, amount float64) ([]byte, error) {
+	payload, err := PromptPayPayload(promptPayID, amount)
+	if err != nil {
+		return nil, err
+	}
+	modules, err := EncodeQR([]byte(payload))
+	if err != nil {
+		return nil, err
+	}
+	return QRCodePNG(modules, 10)
+}
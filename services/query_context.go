@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LastQueryTTL is how long a search/analyze answer can be refined by a follow-up question
+const LastQueryTTL = 30 * time.Minute
+
+// FollowUpScanLimit is how many rows a narrowed query reads before filtering in Go
+const FollowUpScanLimit = 200
+
+// LastQuery is the user's latest search/analyze, refined by follow-ups ("แล้วเดือนก่อนล่ะ", "เอาเฉพาะบัตรเครดิต")
+type LastQuery struct {
+	Action   string      `json:"action"` // "search" or "analyze"
+	Query    QueryFilter `json:"query"`
+	Account  *AccountRef `json:"account,omitempty"`   // payment filter; empty name = any account of UseType
+	OnlyType string      `json:"only_type,omitempty"` // "income"/"expense" when a follow-up asked for one side
+}
+
+// Narrowed reports whether results need filtering in Go: a payment or side was chosen, or a keyword/category
+// search (which ignores dates in Mongo) has an explicit date range
+func (q LastQuery) Narrowed() bool {
+	byName := q.Query.Keyword != "" || len(q.Query.Categories) > 0
+	return q.Account != nil || q.OnlyType != "" || (byName && q.Query.DateFrom != "")
+}
+
+// Filter keeps results inside the query's explicit date range, of OnlyType and paid with Account
+func (q LastQuery) Filter(results []SearchResult) []SearchResult {
+	from, to := q.Query.DateFrom, q.Query.DateTo
+	if from != "" && to == "" {
+		to = from
+	}
+	filtered := results[:0:0]
+	for _, r := range results {
+		tx := r.Transaction
+		if from != "" && (r.Date < from || r.Date > to) {
+			continue
+		}
+		if (q.OnlyType == "income" && tx.Type != 1) || (q.OnlyType == "expense" && tx.Type == 1) {
+			continue
+		}
+		if q.Account != nil && !accountPays(*q.Account, tx) {
+			continue
+		}
+		filtered = append(filtered, r)
+	}
+	return filtered
+}
+
+// Refine returns q with a follow-up's changes applied
+func (q LastQuery) Refine(f *FollowUp) LastQuery {
+	q.Query = MergeQueryFilter(q.Query, &f.Query)
+	if f.Account != nil {
+		q.Account = f.Account
+	}
+	if f.Query.Type != "" {
+		q.OnlyType = f.Query.Type
+	}
+	return q
+}
+
+// followUpPrefixes/followUpSuffixes mark a question that refines the previous answer
+var (
+	followUpPrefixes = []string{"แล้ว", "เอาเฉพาะ", "เฉพาะ", "ขอเฉพาะ", "ถ้าเป็น"}
+	followUpSuffixes = []string{"ล่ะ", "หล่ะ", "ล่ะคะ", "ล่ะครับ"}
+)
+
+// IsFollowUpQuery reports whether text refines the previous query rather than asking a new one
+func IsFollowUpQuery(text string) bool {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "แล้วก็") { // "แล้วก็กาแฟ 50" adds an entry
+		return false
+	}
+	for _, p := range followUpPrefixes {
+		if strings.HasPrefix(text, p) {
+			return true
+		}
+	}
+	for _, s := range followUpSuffixes {
+		if strings.HasSuffix(text, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// MergeQueryFilter applies the fields the AI filled for a follow-up onto the previous filter
+// A new date range replaces the old one whichever way it is given (date_from/date_to or days)
+func MergeQueryFilter(prev QueryFilter, delta *QueryFilter) QueryFilter {
+	if delta == nil {
+		return prev
+	}
+	merged := prev
+	switch {
+	case delta.DateFrom != "":
+		merged.DateFrom, merged.DateTo, merged.Days = delta.DateFrom, delta.DateTo, 0
+	case delta.Days > 0:
+		merged.DateFrom, merged.DateTo, merged.Days = "", "", delta.Days
+	}
+	if delta.Keyword != "" {
+		merged.Keyword = delta.Keyword
+	}
+	if len(delta.Categories) > 0 {
+		merged.Categories = delta.Categories
+	}
+	if delta.Type != "" && delta.Type != "all" {
+		merged.Type = delta.Type
+	}
+	if delta.GroupBy != "" {
+		merged.GroupBy = delta.GroupBy
+	}
+	if delta.Limit > 0 {
+		merged.Limit = delta.Limit
+	}
+	return merged
+}
+
+// FollowUpAccount finds the payment a follow-up narrows to: a known bank/card/nickname, or "บัตรเครดิต"/"ธนาคาร"
+// for every account of that kind
+func FollowUpAccount(text string, banks, cards []string, aliases []AccountAlias) (*AccountRef, bool) {
+	lower := strings.ToLower(text)
+	for _, a := range aliases {
+		if strings.Contains(lower, strings.ToLower(a.Alias)) {
+			account := a.Account()
+			return &account, true
+		}
+	}
+	if name := longestContained(lower, cards); name != "" {
+		return &AccountRef{UseType: 1, CreditCardName: name}, true
+	}
+	if name := longestContained(lower, banks); name != "" {
+		return &AccountRef{UseType: 2, BankName: name}, true
+	}
+	switch {
+	case strings.Contains(text, "บัตรเครดิต") || strings.Contains(text, "รูดบัตร"):
+		return &AccountRef{UseType: 1}, true
+	case strings.Contains(text, "ธนาคาร") || strings.Contains(text, "เงินโอน"):
+		return &AccountRef{UseType: 2}, true
+	case strings.Contains(text, "เงินสด"):
+		return &AccountRef{UseType: 0}, true
+	}
+	return nil, false
+}
+
+// FollowUpType returns "income"/"expense" when a follow-up narrows to one side ("" otherwise)
+func FollowUpType(text string) string {
+	switch {
+	case strings.Contains(text, "รายรับ") || strings.Contains(text, "รายได้"):
+		return "income"
+	case strings.Contains(text, "รายจ่าย"):
+		return "expense"
+	}
+	return ""
+}
+
+// accountPays reports whether tx was paid with account (empty name = any account of its UseType)
+func accountPays(a AccountRef, tx Transaction) bool {
+	if tx.UseType != a.UseType {
+		return false
+	}
+	switch a.UseType {
+	case 1:
+		return a.CreditCardName == "" || strings.EqualFold(tx.CreditCardName, a.CreditCardName)
+	case 2:
+		return a.BankName == "" || strings.EqualFold(tx.BankName, a.BankName)
+	}
+	// Cash skips other assets (usetype 0 with a bank name)
+	return tx.BankName == ""
+}
+
+// FollowUp is what a follow-up changes, resolved in Go
+type FollowUp struct {
+	Query   QueryFilter // DateFrom/DateTo and Type when given
+	Account *AccountRef
+	Label   string // "เดือนที่แล้ว · บัตรเครดิต" for the reply
+}
+
+// ParseFollowUp resolves a follow-up's date range, payment and income/expense without AI
+// Returns false when nothing is recognized or a number is left over (an entry amount, not a refinement)
+func ParseFollowUp(text string, banks, cards []string, aliases []AccountAlias, now time.Time) (*FollowUp, bool) {
+	if !IsFollowUpQuery(text) {
+		return nil, false
+	}
+	f := &FollowUp{}
+	var labels []string
+	rest := thaiDigitReplacer.Replace(text)
+	if r, ok := ParseThaiDate(text, now); ok {
+		f.Query.DateFrom, f.Query.DateTo = r.FromString(), r.ToString()
+		labels = append(labels, r.Expression)
+		rest = strings.Replace(rest, r.Expression, "", 1)
+	}
+	if account, ok := FollowUpAccount(text, banks, cards, aliases); ok {
+		f.Account = account
+		name := account.Name()
+		switch {
+		case account.UseType == 0:
+			name = "เงินสด"
+		case name == "" && account.UseType == 1:
+			name = "บัตรเครดิต"
+		case name == "":
+			name = "ธนาคาร"
+		}
+		labels = append(labels, name)
+	}
+	if t := FollowUpType(text); t != "" {
+		f.Query.Type = t
+		labels = append(labels, map[string]string{"income": "รายรับ", "expense": "รายจ่าย"}[t])
+	}
+	// Account names may carry digits ("บัตร 1234")
+	rest = strings.ToLower(rest)
+	for _, names := range [][]string{banks, cards} {
+		for _, n := range names {
+			if n != "" {
+				rest = strings.ReplaceAll(rest, strings.ToLower(n), "")
+			}
+		}
+	}
+	for _, a := range aliases {
+		rest = strings.ReplaceAll(rest, strings.ToLower(a.Alias), "")
+	}
+	if len(labels) == 0 || strings.ContainsAny(rest, "0123456789") {
+		return nil, false
+	}
+	f.Label = strings.Join(labels, " · ")
+	return f, true
+}
+
+// AIText describes the previous query for the AI so a follow-up can answer with only what changes
+func (q LastQuery) AIText() string {
+	data, _ := json.Marshal(q.Query)
+	return fmt.Sprintf("คำถามก่อนหน้า (%s): %s ถ้าผู้ใช้ถามต่อ ให้ตอบ action เดิมและใส่เฉพาะ query ที่เปลี่ยน", q.Action, data)
+}
+
+// lastQueryKey is the temp data key of a user's last search/analyze
+func lastQueryKey(lineID string) string {
+	return "lastquery_" + lineID
+}
+
+// RememberLastQuery keeps q for follow-up questions for LastQueryTTL
+func (s *MongoDBService) RememberLastQuery(ctx context.Context, lineID string, q LastQuery) error {
+	data, err := json.Marshal(q)
+	if err != nil {
+		return err
+	}
+	return s.SaveTempData(ctx, lastQueryKey(lineID), string(data), LastQueryTTL)
+}
+
+// GetLastQuery returns the user's last search/analyze (false when expired or never asked)
+func (s *MongoDBService) GetLastQuery(ctx context.Context, lineID string) (*LastQuery, bool) {
+	data, err := s.GetTempData(ctx, lastQueryKey(lineID))
+	if err != nil {
+		return nil, false
+	}
+	var q LastQuery
+	if err := json.Unmarshal([]byte(data), &q); err != nil {
+		return nil, false
+	}
+	return &q, true
+}
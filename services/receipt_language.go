@@ -0,0 +1,146 @@
+package services
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// receiptLanguageAliases map what the OCR pass may answer to receipt_lang.md sections
+var receiptLanguageAliases = map[string]string{
+	"th": "th", "thai": "th",
+	"en": "en", "english": "en",
+	"ja": "ja", "jp": "ja", "japanese": "ja",
+	"zh": "zh", "cn": "zh", "zh-cn": "zh", "zh-tw": "zh", "chinese": "zh",
+}
+
+// NormalizeReceiptLanguage returns th, en, ja or zh ("" when unknown)
+func NormalizeReceiptLanguage(lang string) string {
+	return receiptLanguageAliases[strings.ToLower(strings.TrimSpace(lang))]
+}
+
+// ReceiptPrompt returns the extraction prompt for a foreign receipt (common rules + the language's
+// section of receipt_lang.md), "" when the language has no section
+func (p *PromptSet) ReceiptPrompt(lang string) string {
+	section := p.ReceiptLang[lang]
+	if section == "" {
+		return ""
+	}
+	if common := p.ReceiptLang["common"]; common != "" {
+		return common + "\n\n" + section
+	}
+	return section
+}
+
+// Printed receipt date formats
+var (
+	eraDatePattern     = regexp.MustCompile(`(令和|平成|民國|民国)\s*(\d{1,3}|元)\s*[年./]\s*(\d{1,2})\s*[月./]\s*(\d{1,2})`)
+	cjkDatePattern     = regexp.MustCompile(`(\d{4}|\d{2})\s*年\s*(\d{1,2})\s*月\s*(\d{1,2})\s*日`)
+	ymdDatePattern     = regexp.MustCompile(`(\d{4})[./-](\d{1,2})[./-](\d{1,2})`)
+	monthDayPattern    = regexp.MustCompile(`(?i)\b(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?[\s-]+(\d{1,2})(?:st|nd|rd|th)?,?[\s-]+(\d{4})`)
+	dayMonthPattern    = regexp.MustCompile(`(?i)\b(\d{1,2})(?:st|nd|rd|th)?[\s-]+(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?,?[\s-]+(\d{4})`)
+	numericDatePattern = regexp.MustCompile(`\b(\d{1,2})[./-](\d{1,2})[./-](\d{4}|\d{2})\b`)
+)
+
+// englishMonths are month name prefixes of English receipts
+var englishMonths = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+	"may": time.May, "jun": time.June, "jul": time.July, "aug": time.August,
+	"sep": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+// eraOffsets convert era years to Gregorian (令和元年 = 2019, 平成元年 = 1989, 民國元年 = 1912)
+var eraOffsets = map[string]int{"令和": 2018, "平成": 1988, "民國": 1911, "民国": 1911}
+
+// ParseReceiptDate converts the date printed on a foreign receipt to YYYY-MM-DD
+// Japanese/Taiwanese era years and 年月日 dates are converted; an English day/month order that
+// can't be told apart (03/04/2026) resolves to the reading closest to now that isn't in the future.
+// Thai receipts are left to the AI (Buddhist years, Thai month names)
+func ParseReceiptDate(text, lang string, now time.Time) (string, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" || lang == "th" {
+		return "", false
+	}
+
+	if m := eraDatePattern.FindStringSubmatch(text); m != nil {
+		year := 1
+		if m[2] != "元" {
+			year, _ = strconv.Atoi(m[2])
+		}
+		return receiptDate(eraOffsets[m[1]]+year, atoi(m[3]), atoi(m[4]), now)
+	}
+	if m := cjkDatePattern.FindStringSubmatch(text); m != nil {
+		return receiptDate(fullYear(m[1]), atoi(m[2]), atoi(m[3]), now)
+	}
+	if m := ymdDatePattern.FindStringSubmatch(text); m != nil {
+		return receiptDate(atoi(m[1]), atoi(m[2]), atoi(m[3]), now)
+	}
+	if m := monthDayPattern.FindStringSubmatch(text); m != nil {
+		return receiptDate(atoi(m[3]), int(englishMonths[strings.ToLower(m[1])]), atoi(m[2]), now)
+	}
+	if m := dayMonthPattern.FindStringSubmatch(text); m != nil {
+		return receiptDate(atoi(m[3]), int(englishMonths[strings.ToLower(m[2])]), atoi(m[1]), now)
+	}
+	if m := numericDatePattern.FindStringSubmatch(text); m != nil {
+		a, b, year := atoi(m[1]), atoi(m[2]), fullYear(m[3])
+		dayFirst, okDayFirst := receiptDate(year, b, a, now)
+		monthFirst, okMonthFirst := receiptDate(year, a, b, now)
+		switch {
+		case okDayFirst && okMonthFirst:
+			return closestPastDate(dayFirst, monthFirst, now), true
+		case okDayFirst:
+			return dayFirst, true
+		case okMonthFirst:
+			return monthFirst, true
+		}
+	}
+	return "", false
+}
+
+// receiptDate validates a printed date (real calendar day, year 2000 to next year)
+func receiptDate(year, month, day int, now time.Time) (string, bool) {
+	if year < 2000 || year > now.Year()+1 || month < 1 || month > 12 || day < 1 {
+		return "", false
+	}
+	d := time.Date(year, time.Month(month), day, 0, 0, 0, 0, now.Location())
+	if d.Day() != day {
+		return "", false
+	}
+	return d.Format("2006-01-02"), true
+}
+
+// closestPastDate picks whichever of two dates is nearest to now without being after today
+func closestPastDate(a, b string, now time.Time) string {
+	today := now.Format("2006-01-02")
+	switch {
+	case a > today && b <= today:
+		return b
+	case b > today && a <= today:
+		return a
+	case a > today && b > today:
+		if a < b {
+			return a
+		}
+		return b
+	}
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// fullYear expands a two-digit year ("26" -> 2026)
+func fullYear(s string) int {
+	year := atoi(s)
+	if len(s) == 2 {
+		year += 2000
+	}
+	return year
+}
+
+// atoi converts digits, 0 when invalid
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
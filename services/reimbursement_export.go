@@ -0,0 +1,239 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ReimbursableTag marks expenses the company pays back ("แท็กซี่ 250 #เบิกได้")
+const ReimbursableTag = "#เบิกได้"
+
+// reimbursementImageHeight is row height (points) of rows with an embedded receipt
+const reimbursementImageHeight = 120
+
+// HasReimbursableTag reports whether text carries the reimbursable tag
+func HasReimbursableTag(text string) bool {
+	return strings.Contains(text, ReimbursableTag)
+}
+
+// TagReimbursable appends the reimbursable tag to a description once
+func TagReimbursable(description string) string {
+	if HasReimbursableTag(description) {
+		return description
+	}
+	return strings.TrimSpace(description + " " + ReimbursableTag)
+}
+
+// GetReimbursableTransactions returns expenses tagged #เบิกได้ between dates (oldest first, as claims are listed)
+func (s *MongoDBService) GetReimbursableTransactions(ctx context.Context, lineID, startDate, endDate string) ([]SearchResult, error) {
+	filter := bson.M{
+		"lineid":               lineID,
+		"date":                 bson.M{"$gte": startDate, "$lte": endDate},
+		"expenses.description": bson.M{"$regex": regexp.QuoteMeta(ReimbursableTag)},
+	}
+	cursor, err := s.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "date", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []SearchResult
+	for cursor.Next(ctx) {
+		var record DailyRecord
+		if err := cursor.Decode(&record); err != nil {
+			continue
+		}
+		for _, tx := range record.Expenses {
+			if HasReimbursableTag(tx.Description) {
+				results = append(results, SearchResult{Transaction: tx, Date: record.Date, RecordID: record.ID.Hex()})
+			}
+		}
+	}
+	return results, nil
+}
+
+// receiptImage decodes a stored receipt and returns it with an excelize extension ("" if unusable)
+func receiptImage(imageBase64 string) ([]byte, string) {
+	if imageBase64 == "" {
+		return nil, ""
+	}
+	data, err := base64.StdEncoding.DecodeString(imageBase64)
+	if err != nil {
+		return nil, ""
+	}
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		return data, ".jpg"
+	case "image/png":
+		return data, ".png"
+	case "image/gif":
+		return data, ".gif"
+	}
+	return nil, ""
+}
+
+// ExportReimbursement generates a claim workbook: cover summary plus item sheet with receipt images
+func (s *ExportService) ExportReimbursement(ctx context.Context, lineID, startDate, endDate string) ([]byte, string, int, error) {
+	results, err := s.mongo.GetReimbursableTransactions(ctx, lineID, startDate, endDate)
+	if err != nil {
+		return nil, "", 0, fmt.Errorf("ไม่สามารถดึงข้อมูลได้: %w", err)
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	titleStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 16, Color: "#FFFFFF"},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{colorPrimary}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
+	})
+	headerStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF"},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{colorSecondary}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
+	})
+	numberStyle, _ := f.NewStyle(&excelize.Style{
+		Alignment: &excelize.Alignment{Horizontal: "right", Vertical: "top"},
+		NumFmt:    4, // #,##0.00
+	})
+	textStyle, _ := f.NewStyle(&excelize.Style{
+		Alignment: &excelize.Alignment{Vertical: "top", WrapText: true},
+	})
+	totalStyle, _ := f.NewStyle(&excelize.Style{
+		Font:   &excelize.Font{Bold: true, Color: "#FFFFFF"},
+		Fill:   excelize.Fill{Type: "pattern", Color: []string{colorPrimary}, Pattern: 1},
+		NumFmt: 4,
+	})
+
+	var total float64
+	withReceipt := 0
+	byCategory := map[string]float64{}
+	for _, r := range results {
+		total += r.Transaction.Amount
+		category := r.Transaction.Category
+		if category == "" {
+			category = "อื่นๆ"
+		}
+		byCategory[category] += r.Transaction.Amount
+		if r.Transaction.ImageBase64 != "" {
+			withReceipt++
+		}
+	}
+
+	// ===== Cover: สรุปการเบิก =====
+	cover := "สรุปการเบิก"
+	f.SetSheetName("Sheet1", cover)
+	f.MergeCell(cover, "A1", "C1")
+	f.SetCellValue(cover, "A1", "🧾 ใบสรุปขอเบิกค่าใช้จ่าย")
+	f.SetCellStyle(cover, "A1", "C1", titleStyle)
+	f.SetRowHeight(cover, 1, 30)
+
+	claimant := s.mongo.GetDisplayName(ctx, lineID)
+	if claimant == "" {
+		claimant = "-"
+	}
+	info := [][2]interface{}{
+		{"ผู้ขอเบิก", claimant},
+		{"ช่วงวันที่", fmt.Sprintf("%s ถึง %s", startDate, endDate)},
+		{"จำนวนรายการ", len(results)},
+		{"มีใบเสร็จแนบ", fmt.Sprintf("%d/%d รายการ", withReceipt, len(results))},
+		{"ยอดขอเบิกรวม", total},
+		{"วันที่จัดทำ", time.Now().Format("2006-01-02")},
+	}
+	row := 3
+	for _, kv := range info {
+		f.SetCellValue(cover, fmt.Sprintf("A%d", row), kv[0])
+		f.SetCellValue(cover, fmt.Sprintf("B%d", row), kv[1])
+		row++
+	}
+	f.SetCellStyle(cover, "B7", "B7", numberStyle)
+
+	row++
+	f.SetCellValue(cover, fmt.Sprintf("A%d", row), "หมวด")
+	f.SetCellValue(cover, fmt.Sprintf("B%d", row), "ยอดเงิน")
+	f.SetCellStyle(cover, fmt.Sprintf("A%d", row), fmt.Sprintf("B%d", row), headerStyle)
+	row++
+	categories := make([]string, 0, len(byCategory))
+	for cat := range byCategory {
+		categories = append(categories, cat)
+	}
+	sort.Slice(categories, func(i, j int) bool { return byCategory[categories[i]] > byCategory[categories[j]] })
+	for _, cat := range categories {
+		f.SetCellValue(cover, fmt.Sprintf("A%d", row), cat)
+		f.SetCellValue(cover, fmt.Sprintf("B%d", row), byCategory[cat])
+		f.SetCellStyle(cover, fmt.Sprintf("B%d", row), fmt.Sprintf("B%d", row), numberStyle)
+		row++
+	}
+	f.SetCellValue(cover, fmt.Sprintf("A%d", row), "รวม")
+	f.SetCellValue(cover, fmt.Sprintf("B%d", row), total)
+	f.SetCellStyle(cover, fmt.Sprintf("A%d", row), fmt.Sprintf("B%d", row), totalStyle)
+	row += 3
+	f.SetCellValue(cover, fmt.Sprintf("A%d", row), "ลงชื่อผู้ขอเบิก ____________________")
+	f.SetCellValue(cover, fmt.Sprintf("B%d", row), "ลงชื่อผู้อนุมัติ ____________________")
+	f.SetColWidth(cover, "A", "A", 30)
+	f.SetColWidth(cover, "B", "B", 34)
+
+	// ===== Items with receipt images =====
+	items := "รายการ"
+	f.NewSheet(items)
+	headers := []string{"ลำดับ", "วันที่", "หมวด", "รายละเอียด", "ช่องทางจ่าย", "ยอดเงิน", "ใบเสร็จ"}
+	for i, header := range headers {
+		f.SetCellValue(items, fmt.Sprintf("%c1", 'A'+i), header)
+	}
+	f.SetCellStyle(items, "A1", "G1", headerStyle)
+
+	r := 2
+	for i, res := range results {
+		tx := res.Transaction
+		f.SetCellValue(items, fmt.Sprintf("A%d", r), i+1)
+		f.SetCellValue(items, fmt.Sprintf("B%d", r), res.Date)
+		f.SetCellValue(items, fmt.Sprintf("C%d", r), tx.Category)
+		f.SetCellValue(items, fmt.Sprintf("D%d", r), strings.TrimSpace(strings.ReplaceAll(tx.Description, ReimbursableTag, "")))
+		f.SetCellValue(items, fmt.Sprintf("E%d", r), getPaymentInfo(tx.UseType, tx.BankName, tx.CreditCardName))
+		f.SetCellValue(items, fmt.Sprintf("F%d", r), tx.Amount)
+		f.SetCellStyle(items, fmt.Sprintf("A%d", r), fmt.Sprintf("E%d", r), textStyle)
+		f.SetCellStyle(items, fmt.Sprintf("F%d", r), fmt.Sprintf("F%d", r), numberStyle)
+
+		if data, ext := receiptImage(tx.ImageBase64); data != nil {
+			f.SetRowHeight(items, r, reimbursementImageHeight)
+			err := f.AddPictureFromBytes(items, fmt.Sprintf("G%d", r), &excelize.Picture{
+				Extension: ext,
+				File:      data,
+				Format:    &excelize.GraphicOptions{AltText: "ใบเสร็จ " + tx.Description, AutoFit: true, LockAspectRatio: true},
+			})
+			if err != nil {
+				f.SetCellValue(items, fmt.Sprintf("G%d", r), "แนบรูปไม่ได้")
+			}
+		} else {
+			f.SetCellValue(items, fmt.Sprintf("G%d", r), "-")
+		}
+		r++
+	}
+	f.SetCellValue(items, fmt.Sprintf("A%d", r), "รวม")
+	f.SetCellValue(items, fmt.Sprintf("F%d", r), total)
+	f.SetCellStyle(items, fmt.Sprintf("A%d", r), fmt.Sprintf("G%d", r), totalStyle)
+	f.SetColWidth(items, "A", "A", 7)
+	f.SetColWidth(items, "B", "C", 13)
+	f.SetColWidth(items, "D", "D", 30)
+	f.SetColWidth(items, "E", "F", 15)
+	f.SetColWidth(items, "G", "G", 28)
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		return nil, "", 0, fmt.Errorf("cannot create Excel: %w", err)
+	}
+
+	randomNum := fmt.Sprintf("%d%d", time.Now().UnixNano(), time.Now().UnixMicro()%10000)
+	return buf.Bytes(), fmt.Sprintf("%s.xlsx", randomNum), len(results), nil
+}
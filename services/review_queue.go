@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Sources of auto-captured transactions (saved without user typing them)
+const (
+	TransactionSourceImage = "image"
+	TransactionSourceSMS   = "sms"
+)
+
+// reviewQueueDays is how far back unreviewed entries are looked up
+const reviewQueueDays = 90
+
+// GetUnreviewedTransactions returns auto-captured transactions user hasn't confirmed, newest first
+// Total counts every unreviewed entry even when more than limit
+func (s *MongoDBService) GetUnreviewedTransactions(ctx context.Context, lineID string, limit int) ([]SearchResult, int, error) {
+	since := time.Now().AddDate(0, 0, -reviewQueueDays).Format("2006-01-02")
+	cursor, err := s.collection.Find(ctx,
+		bson.M{
+			"lineid": lineID,
+			"date":   bson.M{"$gte": since},
+			"$or":    []bson.M{{"expenses.needs_review": true}, {"incomes.needs_review": true}},
+		},
+		options.Find().SetSort(bson.D{{Key: "date", Value: -1}}),
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []SearchResult
+	for cursor.Next(ctx) {
+		var record DailyRecord
+		if err := cursor.Decode(&record); err != nil {
+			continue
+		}
+		var day []SearchResult
+		for _, list := range [][]Transaction{record.Incomes, record.Expenses} {
+			for _, tx := range list {
+				if tx.NeedsReview {
+					day = append(day, SearchResult{Transaction: tx, Date: record.Date, RecordID: record.ID.Hex()})
+				}
+			}
+		}
+		sort.SliceStable(day, func(i, j int) bool {
+			return day[i].Transaction.CreatedAt.After(day[j].Transaction.CreatedAt)
+		})
+		results = append(results, day...)
+	}
+
+	total := len(results)
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, total, nil
+}
+
+// MarkTransactionsReviewed confirms auto-captured entries (all of them when txID is empty)
+// Returns number of daily records changed
+func (s *MongoDBService) MarkTransactionsReviewed(ctx context.Context, lineID, txID, date string) (int64, error) {
+	filter := bson.M{"lineid": lineID, "$or": []bson.M{{"expenses.needs_review": true}, {"incomes.needs_review": true}}}
+	match := bson.M{"t.needs_review": true}
+	if txID != "" {
+		objectID, err := primitive.ObjectIDFromHex(txID)
+		if err != nil {
+			return 0, fmt.Errorf("invalid transaction ID: %w", err)
+		}
+		filter["date"] = date
+		match["t._id"] = objectID
+	}
+
+	result, err := s.collection.UpdateMany(ctx, filter,
+		bson.M{"$unset": bson.M{"expenses.$[t].needs_review": "", "incomes.$[t].needs_review": ""}},
+		options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{match}}),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// markReviewedOnEdit treats editing an auto-captured entry as reviewing it
+func (s *MongoDBService) markReviewedOnEdit(event, lineID, date string, tx Transaction) {
+	if event != TransactionUpdated || !tx.NeedsReview {
+		return
+	}
+	GoSafe("mark reviewed", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if _, err := s.MarkTransactionsReviewed(ctx, lineID, tx.ID.Hex(), date); err != nil {
+			log.Printf("Failed to mark transaction reviewed: %v", err)
+		}
+	})
+}
+
+// reviewNotices reminds unreviewed auto-captured entries once per day
+func reviewNotices(ctx context.Context, s *MongoDBService, lineID string, now time.Time) []Notice {
+	_, total, err := s.GetUnreviewedTransactions(ctx, lineID, 0)
+	if err != nil || total == 0 {
+		return nil
+	}
+	return []Notice{{
+		Type: NotifyReview,
+		Key:  "review:" + now.Format("2006-01-02"),
+		Text: fmt.Sprintf("🔍 ยังไม่ได้ตรวจ %d รายการที่บันทึกจากรูป/SMS พิมพ์ \"รอตรวจ\" เพื่อดูและยืนยัน", total),
+	}}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultRoundUpBank is the savings account round-ups move to when user didn't name one
+const DefaultRoundUpBank = "เงินออม"
+
+// RoundUpAmount returns how much rounding amount up to the next step adds (0 when already even)
+func RoundUpAmount(amount float64, step int) float64 {
+	if step <= 0 || amount <= 0 {
+		return 0
+	}
+	s := float64(step)
+	diff := math.Ceil(amount/s)*s - amount
+	// Float noise like 0.0000001 is not a real round-up
+	return math.Round(diff*100) / 100
+}
+
+// SetRoundUp turns the round-up savings rule on (step 10 or 100) or off (step 0)
+// Turning on starts counting from today; money already moved stays recorded
+func (s *MongoDBService) SetRoundUp(ctx context.Context, lineID string, step int, bank string) error {
+	update := bson.M{"round_up_step": step, "updated_at": time.Now()}
+	if step > 0 {
+		if bank == "" {
+			bank = DefaultRoundUpBank
+		}
+		update["round_up_bank"] = bank
+		update["round_up_since"] = time.Now().Format("2006-01-02")
+		update["round_up_saved"] = 0.0
+	}
+	_, err := s.settingsCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID},
+		bson.M{"$set": update},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// GetRoundUpTotal sums round-ups of expenses between two dates (transfers and investments excluded)
+func (s *MongoDBService) GetRoundUpTotal(ctx context.Context, lineID, from, to string, step int) (float64, error) {
+	cursor, err := s.collection.Find(ctx,
+		bson.M{"lineid": lineID, "date": bson.M{"$gte": from, "$lte": to}},
+		options.Find().SetProjection(bson.M{"expenses.amount": 1, "expenses.category": 1}),
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	total := 0.0
+	for cursor.Next(ctx) {
+		var record DailyRecord
+		if err := cursor.Decode(&record); err != nil {
+			continue
+		}
+		for _, tx := range record.Expenses {
+			if tx.IsAssetMove() {
+				continue
+			}
+			total += RoundUpAmount(tx.Amount, step)
+		}
+	}
+	return total, nil
+}
+
+// GetRoundUpPot returns round-ups collected since the rule was turned on minus what was already moved
+func (s *MongoDBService) GetRoundUpPot(ctx context.Context, settings *UserSettings) (float64, error) {
+	if settings == nil || settings.RoundUpStep <= 0 {
+		return 0, nil
+	}
+	total, err := s.GetRoundUpTotal(ctx, settings.LineID, settings.RoundUpSince, time.Now().Format("2006-01-02"), settings.RoundUpStep)
+	if err != nil {
+		return 0, err
+	}
+	return math.Max(total-settings.RoundUpSaved, 0), nil
+}
+
+// MoveRoundUpPot records a real transfer of the pot into the savings account and empties the pot
+func (s *MongoDBService) MoveRoundUpPot(ctx context.Context, lineID string, amount float64, from TransferEntry, bank string) error {
+	from.Amount = amount
+	transfer := &TransferData{
+		From:        []TransferEntry{from},
+		To:          []TransferEntry{{Amount: amount, UseType: 2, BankName: bank}},
+		Description: "ออมเงินจากการปัดเศษ",
+	}
+	if _, _, err := s.SaveTransfer(ctx, lineID, transfer); err != nil {
+		return err
+	}
+	_, err := s.settingsCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID},
+		bson.M{"$inc": bson.M{"round_up_saved": amount}, "$set": bson.M{"updated_at": time.Now()}},
+	)
+	return err
+}
+
+// roundUpNotices sums last week's round-ups once per week
+func roundUpNotices(ctx context.Context, s *MongoDBService, lineID string, now time.Time) []Notice {
+	settings, err := s.GetUserSettings(ctx, lineID)
+	if err != nil || settings.RoundUpStep <= 0 {
+		return nil
+	}
+	pot, err := s.GetRoundUpPot(ctx, settings)
+	if err != nil || pot < 1 {
+		return nil
+	}
+	prevFrom, prevTo := LastWeekRange(now)
+	from := prevFrom.Format("2006-01-02")
+	if from < settings.RoundUpSince {
+		from = settings.RoundUpSince
+	}
+	week, _ := s.GetRoundUpTotal(ctx, lineID, from, prevTo.Format("2006-01-02"), settings.RoundUpStep)
+
+	year, weekNo := prevFrom.ISOWeek()
+	return []Notice{{
+		Type: NotifyRoundUp,
+		Key:  fmt.Sprintf("roundup:%d-W%02d", year, weekNo),
+		Text: fmt.Sprintf("🐷 สะสมเศษไปแล้ว %.0f บาท (สัปดาห์ที่แล้ว +%.0f) พิมพ์ \"เศษสะสม\" เพื่อโอนเข้า%s", pot, week, settings.RoundUpBank),
+	}}
+}
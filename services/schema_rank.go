@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// profileRankInterval is how often a profile's names are re-ranked from history
+const profileRankInterval = 7 * 24 * time.Hour
+
+// nameRankHalfLife is the age in days at which a name's uses count half
+const nameRankHalfLife = 30.0
+
+// NameUsage is how often and how recently a bank/card/category was used
+type NameUsage struct {
+	Count int    `bson:"count"`
+	Last  string `bson:"last"` // YYYY-MM-DD
+}
+
+// RankNames orders names by recency-weighted frequency (most used first); unused names keep
+// their order at the end
+func RankNames(names []string, usage map[string]NameUsage, now time.Time) []string {
+	score := func(name string) float64 {
+		u, ok := usage[name]
+		if !ok || u.Count == 0 {
+			return 0
+		}
+		days := 0.0
+		if last, err := time.Parse("2006-01-02", u.Last); err == nil {
+			days = now.Sub(last).Hours() / 24
+			if days < 0 {
+				days = 0
+			}
+		}
+		return float64(u.Count) / (1 + days/nameRankHalfLife)
+	}
+	ranked := append([]string(nil), names...)
+	sort.SliceStable(ranked, func(i, j int) bool { return score(ranked[i]) > score(ranked[j]) })
+	return ranked
+}
+
+// nameUsage is use counts per name of each profile list
+type nameUsage struct {
+	Banks             map[string]NameUsage
+	CreditCards       map[string]NameUsage
+	IncomeCategories  map[string]NameUsage
+	ExpenseCategories map[string]NameUsage
+}
+
+// loadNameUsage counts uses and last date of every bank, card and category in one aggregation
+func (s *MongoDBService) loadNameUsage(ctx context.Context, lineID string) (*nameUsage, error) {
+	entries := func(field string, income bool) bson.M {
+		return bson.M{"$map": bson.M{
+			"input": bson.M{"$ifNull": bson.A{"$" + field, bson.A{}}},
+			"as":    "t",
+			"in": bson.M{
+				"bank": "$$t.bankname", "card": "$$t.creditcardname", "category": "$$t.category",
+				"is_transfer": "$$t.is_transfer", "income": income,
+			},
+		}}
+	}
+	group := func(field string) bson.M {
+		return bson.M{"$group": bson.M{"_id": "$tx." + field, "count": bson.M{"$sum": 1}, "last": bson.M{"$max": "$date"}}}
+	}
+	categories := func(income bool) bson.A {
+		return bson.A{bson.M{"$match": bson.M{"tx.income": income, "tx.is_transfer": bson.M{"$ne": true}}}, group("category")}
+	}
+	pipeline := []bson.M{
+		{"$match": bson.M{"lineid": lineID}},
+		{"$project": bson.M{"date": 1, "tx": bson.M{"$concatArrays": bson.A{entries("incomes", true), entries("expenses", false)}}}},
+		{"$unwind": "$tx"},
+		{"$facet": bson.M{
+			"banks":    bson.A{group("bank")},
+			"cards":    bson.A{group("card")},
+			"incomes":  categories(true),
+			"expenses": categories(false),
+		}},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	type row struct {
+		Name      string `bson:"_id"`
+		NameUsage `bson:",inline"`
+	}
+	var out []struct {
+		Banks    []row `bson:"banks"`
+		Cards    []row `bson:"cards"`
+		Incomes  []row `bson:"incomes"`
+		Expenses []row `bson:"expenses"`
+	}
+	if err := cursor.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	toMap := func(rows []row) map[string]NameUsage {
+		m := make(map[string]NameUsage, len(rows))
+		for _, r := range rows {
+			if r.Name != "" {
+				m[r.Name] = r.NameUsage
+			}
+		}
+		return m
+	}
+	usage := &nameUsage{}
+	if len(out) > 0 {
+		usage.Banks = toMap(out[0].Banks)
+		usage.CreditCards = toMap(out[0].Cards)
+		usage.IncomeCategories = toMap(out[0].Incomes)
+		usage.ExpenseCategories = toMap(out[0].Expenses)
+	}
+	return usage, nil
+}
+
+// rankProfileAsync re-ranks a profile's names in background once its ranking is a week old
+func (s *MongoDBService) rankProfileAsync(profile *UserProfile) {
+	if time.Since(profile.RankedAt) < profileRankInterval {
+		return
+	}
+	lineID := profile.LineID
+	if _, running := s.rankingProfiles.LoadOrStore(lineID, struct{}{}); running {
+		return
+	}
+	GoSafe("profile.rank", func() {
+		defer s.rankingProfiles.Delete(lineID)
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if _, err := s.rebuildUserProfile(ctx, lineID); err != nil {
+			log.Printf("Failed to rank user profile names: %v", err)
+		}
+	})
+}
+
+// moveNameToFront is a pipeline expression putting name first in a profile list (most recently used first)
+func moveNameToFront(field, name string) bson.M {
+	return bson.M{"$concatArrays": bson.A{
+		bson.A{bson.M{"$literal": name}},
+		bson.M{"$filter": bson.M{
+			"input": bson.M{"$ifNull": bson.A{"$" + field, bson.A{}}},
+			"cond":  bson.M{"$ne": bson.A{"$$this", bson.M{"$literal": name}}},
+		}},
+	}}
+}
+
+// ActionListNames is the AI's request for every known bank/card/category when the schema was trimmed
+const ActionListNames = "list_names"
+
+// IsListNamesResponse reports whether the AI answered with ActionListNames instead of a result
+func IsListNamesResponse(response string) bool {
+	var resp struct {
+		Action string `json:"action"`
+	}
+	return json.Unmarshal([]byte(cleanJSONResponse(response)), &resp) == nil && resp.Action == ActionListNames
+}
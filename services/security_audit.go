@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Security events on financial data (token issuance, exports, deletions, rejected access, account links, fake slips,
+// reopened closed periods)
+const (
+	SecurityTokenIssued    = "token.issued"
+	SecurityDataExport     = "data.export"
+	SecurityDataDeleted    = "data.deleted"
+	SecurityAccessDenied   = "access.denied"
+	SecurityAccountLink    = "account.link"
+	SecuritySlipSuspicious = "slip.suspicious"
+	SecurityPeriodReopened = "period.reopened"
+)
+
+// securityBurstLimits is max events of a type per user (or IP) within securityBurstWindow before alerting
+var securityBurstLimits = map[string]int64{
+	SecurityTokenIssued:    5,
+	SecurityDataExport:     10,
+	SecurityDataDeleted:    3,
+	SecurityAccessDenied:   20,
+	SecuritySlipSuspicious: 3,
+	SecurityPeriodReopened: 3,
+}
+
+const securityBurstWindow = time.Hour
+
+// SecurityEvent is one audited action, stored in security_events
+type SecurityEvent struct {
+	LineID    string    `bson:"lineid,omitempty" json:"lineid,omitempty"`
+	Event     string    `bson:"event" json:"event"`
+	Detail    string    `bson:"detail,omitempty" json:"detail,omitempty"`
+	ClientIP  string    `bson:"client_ip,omitempty" json:"client_ip,omitempty"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// SecurityAlertHook is called when events look suspicious (e.g. burst of exports)
+// Hooks must not block (run slow work in a goroutine)
+type SecurityAlertHook func(event SecurityEvent, reason string)
+
+// AddSecurityAlertHook registers a hook for suspicious security events
+func (s *MongoDBService) AddSecurityAlertHook(hook SecurityAlertHook) {
+	s.securityHooks = append(s.securityHooks, hook)
+}
+
+// LogSecurityEvent stores event and raises an alert when its type bursts past the limit
+// Errors are only logged: auditing must never break the user's action
+func (s *MongoDBService) LogSecurityEvent(ctx context.Context, event SecurityEvent) {
+	event.CreatedAt = time.Now()
+	if _, err := s.securityCollection.InsertOne(ctx, event); err != nil {
+		log.Printf("Failed to log security event %s: %v", event.Event, err)
+		return
+	}
+
+	if _, ok := securityBurstLimits[event.Event]; !ok {
+		return
+	}
+	// Burst is counted per user, or per IP for anonymous requests
+	filter := bson.M{"event": event.Event, "created_at": bson.M{"$gte": event.CreatedAt.Add(-securityBurstWindow)}}
+	switch {
+	case event.LineID != "":
+		filter["lineid"] = event.LineID
+	case event.ClientIP != "":
+		filter["client_ip"] = event.ClientIP
+	default:
+		return
+	}
+	count, err := s.securityCollection.CountDocuments(ctx, filter)
+	if err == nil && SecurityBurstCrossed(event.Event, count) {
+		s.alertSecurity(event, "burst")
+	}
+}
+
+// SecurityBurstCrossed reports whether count events of a type within the window just crossed its burst limit
+// Only the event that crosses the limit alerts, not every one after it
+func SecurityBurstCrossed(event string, count int64) bool {
+	limit, ok := securityBurstLimits[event]
+	return ok && count == limit+1
+}
+
+// alertSecurity logs the alert and calls all registered hooks
+func (s *MongoDBService) alertSecurity(event SecurityEvent, reason string) {
+	log.Printf("SECURITY ALERT (%s): %s user=%s ip=%s %s", reason, event.Event, event.LineID, event.ClientIP, event.Detail)
+	for _, hook := range s.securityHooks {
+		hook(event, reason)
+	}
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Share link lifetime (days) when user doesn't say, and the longest allowed
+const (
+	DefaultShareLinkDays = 7
+	MaxShareLinkDays     = 30
+)
+
+// Share link errors
+var (
+	ErrShareNotFound = errors.New("share link not found")
+	ErrShareExpired  = errors.New("share link expired or revoked")
+)
+
+// ShareLink is a time-limited read-only web view of one report period
+type ShareLink struct {
+	Token     string     `bson:"token" json:"token"`
+	LineID    string     `bson:"lineid" json:"lineid"`
+	Label     string     `bson:"label" json:"label"` // "เดือนนี้", "มกราคม"
+	From      string     `bson:"from" json:"from"`   // YYYY-MM-DD
+	To        string     `bson:"to" json:"to"`       // YYYY-MM-DD
+	Views     int        `bson:"views" json:"views"`
+	RevokedAt *time.Time `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	ExpiresAt time.Time  `bson:"expires_at" json:"expires_at"`
+	CreatedAt time.Time  `bson:"created_at" json:"created_at"`
+}
+
+// ShareCommand is a parsed share link command
+type ShareCommand struct {
+	Action string // "create", "list", "revoke"
+	Label  string
+	From   string
+	To     string
+	Days   int
+}
+
+// shareCommandPrefixes start a share link command ("แชร์สรุปเดือนนี้ให้แฟนดู")
+var shareCommandPrefixes = []string{"แชร์สรุป", "แชร์รายงาน"}
+
+// shareDaysPattern matches link lifetime in the command ("3 วัน")
+var shareDaysPattern = regexp.MustCompile(`(\d+)\s*วัน`)
+
+// ParseShareCommand parses share link commands (no AI), period defaults to this month
+func ParseShareCommand(text string, now time.Time) (ShareCommand, bool) {
+	text = strings.TrimSpace(text)
+	switch text {
+	case "ลิงก์แชร์", "ดูลิงก์แชร์":
+		return ShareCommand{Action: "list"}, true
+	case "ยกเลิกแชร์", "ยกเลิกลิงก์แชร์":
+		return ShareCommand{Action: "revoke"}, true
+	}
+
+	for _, prefix := range shareCommandPrefixes {
+		if !strings.HasPrefix(text, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(text, prefix)
+		cmd := ShareCommand{Action: "create", Days: DefaultShareLinkDays}
+		if m := shareDaysPattern.FindStringSubmatch(rest); m != nil {
+			cmd.Days, _ = strconv.Atoi(m[1])
+			rest = strings.Replace(rest, m[0], "", 1)
+		}
+		if r, found := ParseThaiDate(rest, now); found {
+			cmd.Label, cmd.From, cmd.To = r.Expression, r.FromString(), r.ToString()
+		} else {
+			cmd.Label = "เดือนนี้"
+			cmd.From = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).Format("2006-01-02")
+			cmd.To = now.Format("2006-01-02")
+		}
+		return cmd, true
+	}
+	return ShareCommand{}, false
+}
+
+// ShareLinkExpiry returns when a link created at now for days expires (default 7, at most 30 days)
+func ShareLinkExpiry(days int, now time.Time) time.Time {
+	if days <= 0 {
+		days = DefaultShareLinkDays
+	}
+	if days > MaxShareLinkDays {
+		days = MaxShareLinkDays
+	}
+	return now.AddDate(0, 0, days)
+}
+
+// CreateShareLink stores a new share link for the period valid for days
+func (s *MongoDBService) CreateShareLink(ctx context.Context, lineID, label, from, to string, days int) (*ShareLink, error) {
+	token, err := generateDownloadToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	link := &ShareLink{
+		Token:     token,
+		LineID:    lineID,
+		Label:     label,
+		From:      from,
+		To:        to,
+		ExpiresAt: ShareLinkExpiry(days, now),
+		CreatedAt: now,
+	}
+	if _, err := s.shareCollection.InsertOne(ctx, link); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// OpenShareLink returns a valid share link and counts the view
+func (s *MongoDBService) OpenShareLink(ctx context.Context, token string) (*ShareLink, error) {
+	var link ShareLink
+	err := s.shareCollection.FindOneAndUpdate(ctx,
+		bson.M{"token": token, "revoked_at": bson.M{"$exists": false}, "expires_at": bson.M{"$gt": time.Now()}},
+		bson.M{"$inc": bson.M{"views": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&link)
+	if err == nil {
+		return &link, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	if s.shareCollection.FindOne(ctx, bson.M{"token": token}).Decode(&link) != nil {
+		return nil, ErrShareNotFound
+	}
+	return &link, ErrShareExpired
+}
+
+// GetActiveShareLinks returns user's links that still open, newest first
+func (s *MongoDBService) GetActiveShareLinks(ctx context.Context, lineID string) ([]ShareLink, error) {
+	cursor, err := s.shareCollection.Find(ctx,
+		bson.M{"lineid": lineID, "revoked_at": bson.M{"$exists": false}, "expires_at": bson.M{"$gt": time.Now()}},
+		options.Find().SetSort(bson.M{"created_at": -1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var links []ShareLink
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// RevokeShareLinks stops user's links from opening (all of them when token is empty)
+func (s *MongoDBService) RevokeShareLinks(ctx context.Context, lineID, token string) (int64, error) {
+	filter := bson.M{"lineid": lineID, "revoked_at": bson.M{"$exists": false}, "expires_at": bson.M{"$gt": time.Now()}}
+	if token != "" {
+		filter["token"] = token
+	}
+	result, err := s.shareCollection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revoked_at": time.Now()}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// GetRangeSummary returns summary between two dates (YYYY-MM-DD) under a custom label
+func (s *MongoDBService) GetRangeSummary(ctx context.Context, lineID, label, from, to string) (*PeriodSummary, error) {
+	return s.getPeriodSummary(ctx, lineID, "range", label, from, to)
+}
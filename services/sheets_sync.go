@@ -0,0 +1,358 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+// sheetsHeader is the first row of synced sheet (column A = transaction ID for updates)
+var sheetsHeader = []interface{}{"ID", "วันที่", "ประเภท", "หมวด", "รายละเอียด", "ร้าน/ลูกค้า", "จำนวนเงิน", "วิธีจ่าย", "บันทึกเมื่อ"}
+
+// spreadsheetIDPattern extracts ID from "https://docs.google.com/spreadsheets/d/<id>/edit"
+var spreadsheetIDPattern = regexp.MustCompile(`/spreadsheets/d/([a-zA-Z0-9_-]+)`)
+
+// rawSpreadsheetIDPattern matches a bare spreadsheet ID
+var rawSpreadsheetIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{20,}$`)
+
+// SheetsConnection stores user's Google Sheets sync settings
+type SheetsConnection struct {
+	LineID        string     `bson:"lineid" json:"lineid"`
+	RefreshToken  string     `bson:"refresh_token" json:"-"`
+	SpreadsheetID string     `bson:"spreadsheet_id" json:"spreadsheet_id"`
+	SheetName     string     `bson:"sheet_name" json:"sheet_name"`
+	Active        bool       `bson:"active" json:"active"`
+	LastError     string     `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	LastSyncedAt  *time.Time `bson:"last_synced_at,omitempty" json:"last_synced_at,omitempty"`
+	ConnectedAt   time.Time  `bson:"connected_at" json:"connected_at"`
+}
+
+// SpreadsheetURL returns link to the synced spreadsheet
+func (c *SheetsConnection) SpreadsheetURL() string {
+	return "https://docs.google.com/spreadsheets/d/" + c.SpreadsheetID
+}
+
+// ParseSpreadsheetID returns spreadsheet ID from URL or raw ID
+func ParseSpreadsheetID(text string) string {
+	if m := spreadsheetIDPattern.FindStringSubmatch(text); m != nil {
+		return m[1]
+	}
+	if rawSpreadsheetIDPattern.MatchString(text) {
+		return text
+	}
+	return ""
+}
+
+// GetSheetsConnection returns user's connection (nil if not connected)
+func (s *MongoDBService) GetSheetsConnection(ctx context.Context, lineID string) (*SheetsConnection, error) {
+	var conn SheetsConnection
+	err := s.sheetsCollection.FindOne(ctx, bson.M{"lineid": lineID}).Decode(&conn)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &conn, nil
+}
+
+// updateSheetsConnection sets fields of user's connection (upsert)
+func (s *MongoDBService) updateSheetsConnection(ctx context.Context, lineID string, set bson.M) error {
+	_, err := s.sheetsCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID},
+		bson.M{"$set": set},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// DeleteSheetsConnection disconnects Google Sheets
+func (s *MongoDBService) DeleteSheetsConnection(ctx context.Context, lineID string) error {
+	_, err := s.sheetsCollection.DeleteOne(ctx, bson.M{"lineid": lineID})
+	return err
+}
+
+// SheetsService syncs transactions to user's Google Sheet
+type SheetsService struct {
+	mongo *MongoDBService
+	oauth *oauth2.Config
+	slots chan struct{} // limits concurrent background syncs
+	queue OrderedQueues // per user, so an update never reaches the sheet before its append
+}
+
+// NewSheetsService creates Google Sheets sync service and subscribes to transaction changes
+// Returns nil when OAuth client is not configured
+func NewSheetsService(mongo *MongoDBService, clientID, clientSecret, redirectURL string) *SheetsService {
+	if clientID == "" || clientSecret == "" || redirectURL == "" {
+		return nil
+	}
+	s := &SheetsService{
+		mongo: mongo,
+		oauth: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       []string{sheets.SpreadsheetsScope},
+			Endpoint:     google.Endpoint,
+		},
+		slots: make(chan struct{}, 5),
+	}
+	mongo.AddTransactionHook(s.onTransaction)
+	return s
+}
+
+// CreateConnectState stores one-time OAuth state for user (15 minutes)
+func (s *SheetsService) CreateConnectState(ctx context.Context, lineID string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	state := hex.EncodeToString(b)
+	if err := s.mongo.SaveTempData(ctx, "sheets_state_"+state, lineID, 15*time.Minute); err != nil {
+		return "", err
+	}
+	return state, nil
+}
+
+// AuthURL returns Google consent URL (offline access for refresh token)
+func (s *SheetsService) AuthURL(state string) string {
+	return s.oauth.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.SetAuthURLParam("prompt", "consent"))
+}
+
+// CompleteConnect exchanges OAuth code, creates spreadsheet if user has none and rebuilds it
+func (s *SheetsService) CompleteConnect(ctx context.Context, state, code string) (*SheetsConnection, error) {
+	key := "sheets_state_" + state
+	lineID, err := s.mongo.GetTempData(ctx, key)
+	if err != nil || lineID == "" {
+		return nil, fmt.Errorf("ลิงก์เชื่อมต่อหมดอายุ กรุณาขอลิงก์ใหม่ใน LINE")
+	}
+	s.mongo.DeleteTempData(ctx, key)
+
+	token, err := s.oauth.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange oauth code: %w", err)
+	}
+	if token.RefreshToken == "" {
+		return nil, fmt.Errorf("google did not return refresh token")
+	}
+	s.mongo.LogSecurityEvent(ctx, SecurityEvent{LineID: lineID, Event: SecurityTokenIssued, Detail: "google sheets oauth connected"})
+
+	existing, _ := s.mongo.GetSheetsConnection(ctx, lineID)
+	conn := &SheetsConnection{
+		LineID:       lineID,
+		RefreshToken: token.RefreshToken,
+		SheetName:    "รายการ",
+		Active:       true,
+		ConnectedAt:  time.Now(),
+	}
+	if existing != nil && existing.SpreadsheetID != "" {
+		conn.SpreadsheetID = existing.SpreadsheetID
+		conn.SheetName = existing.SheetName
+	}
+
+	srv, err := s.client(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	if conn.SpreadsheetID == "" {
+		created, err := srv.Spreadsheets.Create(&sheets.Spreadsheet{
+			Properties: &sheets.SpreadsheetProperties{Title: "สติสตางค์ - รายรับรายจ่าย"},
+			Sheets:     []*sheets.Sheet{{Properties: &sheets.SheetProperties{Title: conn.SheetName}}},
+		}).Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create spreadsheet: %w", err)
+		}
+		conn.SpreadsheetID = created.SpreadsheetId
+	}
+
+	if err := s.mongo.updateSheetsConnection(ctx, lineID, bson.M{
+		"refresh_token":  conn.RefreshToken,
+		"spreadsheet_id": conn.SpreadsheetID,
+		"sheet_name":     conn.SheetName,
+		"active":         true,
+		"connected_at":   conn.ConnectedAt,
+	}); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.Rebuild(ctx, lineID); err != nil {
+		log.Printf("Failed to rebuild sheet after connect: %v", err)
+	}
+	return conn, nil
+}
+
+// SelectSpreadsheet switches sync target to user's own spreadsheet and rebuilds it
+func (s *SheetsService) SelectSpreadsheet(ctx context.Context, lineID, spreadsheetID string) (int, error) {
+	conn, err := s.mongo.GetSheetsConnection(ctx, lineID)
+	if err != nil || conn == nil || conn.RefreshToken == "" {
+		return 0, fmt.Errorf("ยังไม่ได้เชื่อมต่อ Google Sheets")
+	}
+	conn.SpreadsheetID = spreadsheetID
+
+	srv, err := s.client(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.ensureSheet(ctx, srv, conn); err != nil {
+		return 0, fmt.Errorf("เปิดชีตไม่ได้ (ตรวจสอบว่าบัญชีที่เชื่อมมีสิทธิ์แก้ไข): %w", err)
+	}
+	if err := s.mongo.updateSheetsConnection(ctx, lineID, bson.M{"spreadsheet_id": spreadsheetID}); err != nil {
+		return 0, err
+	}
+	return s.Rebuild(ctx, lineID)
+}
+
+// client returns Sheets API client authorized with user's refresh token
+func (s *SheetsService) client(ctx context.Context, conn *SheetsConnection) (*sheets.Service, error) {
+	tokenSource := s.oauth.TokenSource(context.Background(), &oauth2.Token{RefreshToken: conn.RefreshToken})
+	return sheets.NewService(ctx, option.WithTokenSource(tokenSource))
+}
+
+// ensureSheet creates the sync tab in spreadsheet if missing
+func (s *SheetsService) ensureSheet(ctx context.Context, srv *sheets.Service, conn *SheetsConnection) error {
+	spreadsheet, err := srv.Spreadsheets.Get(conn.SpreadsheetID).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties != nil && sheet.Properties.Title == conn.SheetName {
+			return nil
+		}
+	}
+	_, err = srv.Spreadsheets.BatchUpdate(conn.SpreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{{AddSheet: &sheets.AddSheetRequest{Properties: &sheets.SheetProperties{Title: conn.SheetName}}}},
+	}).Context(ctx).Do()
+	return err
+}
+
+// sheetsRow converts transaction to sheet row
+func sheetsRow(date string, tx Transaction) []interface{} {
+	txType := "รายจ่าย"
+	if tx.Type == 1 {
+		txType = "รายรับ"
+	}
+	return []interface{}{
+		tx.ID.Hex(), date, txType, tx.Category, tx.Description, tx.CustName,
+		tx.Amount, getPaymentInfo(tx.UseType, tx.BankName, tx.CreditCardName),
+		tx.CreatedAt.Local().Format("2006-01-02 15:04"),
+	}
+}
+
+// Rebuild clears the sheet and writes full history (oldest first), returns row count
+func (s *SheetsService) Rebuild(ctx context.Context, lineID string) (int, error) {
+	conn, err := s.mongo.GetSheetsConnection(ctx, lineID)
+	if err != nil || conn == nil || !conn.Active {
+		return 0, fmt.Errorf("ยังไม่ได้เชื่อมต่อ Google Sheets")
+	}
+	srv, err := s.client(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.ensureSheet(ctx, srv, conn); err != nil {
+		return 0, s.recordError(ctx, lineID, err)
+	}
+
+	results, err := s.mongo.SearchByDateRange(ctx, lineID, "0000-01-01", "9999-12-31", 100000)
+	if err != nil {
+		return 0, err
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Date != results[j].Date {
+			return results[i].Date < results[j].Date
+		}
+		return results[i].Transaction.CreatedAt.Before(results[j].Transaction.CreatedAt)
+	})
+
+	rows := [][]interface{}{sheetsHeader}
+	for _, r := range results {
+		rows = append(rows, sheetsRow(r.Date, r.Transaction))
+	}
+
+	sheetRange := fmt.Sprintf("'%s'", conn.SheetName)
+	if _, err := srv.Spreadsheets.Values.Clear(conn.SpreadsheetID, sheetRange, &sheets.ClearValuesRequest{}).Context(ctx).Do(); err != nil {
+		return 0, s.recordError(ctx, lineID, err)
+	}
+	if _, err := srv.Spreadsheets.Values.Update(conn.SpreadsheetID, sheetRange+"!A1", &sheets.ValueRange{Values: rows}).
+		ValueInputOption("USER_ENTERED").Context(ctx).Do(); err != nil {
+		return 0, s.recordError(ctx, lineID, err)
+	}
+	s.recordSynced(ctx, lineID)
+	return len(results), nil
+}
+
+// onTransaction appends new transactions and updates changed rows in background, in the order they happened
+func (s *SheetsService) onTransaction(event, lineID, date string, tx Transaction) {
+	s.queue.Enqueue(lineID, "sheets.sync", func() {
+		s.slots <- struct{}{}
+		defer func() { <-s.slots }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		conn, err := s.mongo.GetSheetsConnection(ctx, lineID)
+		if err != nil || conn == nil || !conn.Active {
+			return
+		}
+		if err := s.syncTransaction(ctx, conn, event, date, tx); err != nil {
+			log.Printf("Failed to sync transaction to sheets: %v", err)
+			s.recordError(ctx, lineID, err)
+			return
+		}
+		s.recordSynced(ctx, lineID)
+	})
+}
+
+// syncTransaction writes one transaction row (update finds existing row by ID in column A)
+func (s *SheetsService) syncTransaction(ctx context.Context, conn *SheetsConnection, event, date string, tx Transaction) error {
+	if event == TransactionDeleted {
+		return nil // Sheet is an append log, deleted rows stay for the user to review
+	}
+	srv, err := s.client(ctx, conn)
+	if err != nil {
+		return err
+	}
+	sheetRange := fmt.Sprintf("'%s'", conn.SheetName)
+	row := &sheets.ValueRange{Values: [][]interface{}{sheetsRow(date, tx)}}
+
+	if event == TransactionUpdated {
+		ids, err := srv.Spreadsheets.Values.Get(conn.SpreadsheetID, sheetRange+"!A:A").Context(ctx).Do()
+		if err != nil {
+			return err
+		}
+		for i, values := range ids.Values {
+			if len(values) > 0 && values[0] == tx.ID.Hex() {
+				_, err := srv.Spreadsheets.Values.Update(conn.SpreadsheetID, fmt.Sprintf("%s!A%d", sheetRange, i+1), row).
+					ValueInputOption("USER_ENTERED").Context(ctx).Do()
+				return err
+			}
+		}
+	}
+
+	_, err = srv.Spreadsheets.Values.Append(conn.SpreadsheetID, sheetRange+"!A1", row).
+		ValueInputOption("USER_ENTERED").InsertDataOption("INSERT_ROWS").Context(ctx).Do()
+	return err
+}
+
+// recordSynced stores last successful sync time
+func (s *SheetsService) recordSynced(ctx context.Context, lineID string) {
+	s.mongo.updateSheetsConnection(ctx, lineID, bson.M{"last_synced_at": time.Now(), "last_error": ""})
+}
+
+// recordError stores last sync error and returns it
+func (s *SheetsService) recordError(ctx context.Context, lineID string, err error) error {
+	s.mongo.updateSheetsConnection(ctx, lineID, bson.M{"last_error": err.Error()})
+	return err
+}
@@ -0,0 +1,32 @@
+package services
+
+import "fmt"
+
+// SlipFeeCategory is the category of the separate fee entry recorded with a transfer slip
+const SlipFeeCategory = "ค่าธรรมเนียม"
+
+// SplitSlipFee returns the slip transaction followed by its fee as its own expense against the sending bank.
+// Only outgoing slips get a fee entry (the sender pays it); a fee that isn't smaller than the amount is a misread and dropped.
+func SplitSlipFee(slip TransactionData) []TransactionData {
+	fee := slip.Fee
+	slip.Fee = 0
+	if slip.Type != "expense" || fee <= 0 || fee >= slip.Amount {
+		return []TransactionData{slip}
+	}
+
+	description := "ค่าธรรมเนียมโอน"
+	if slip.RefNo != "" {
+		description = fmt.Sprintf("ค่าธรรมเนียมโอน (อ้างอิง %s)", slip.RefNo)
+	}
+	return []TransactionData{slip, {
+		ImageType:   slip.ImageType,
+		Date:        slip.Date,
+		Amount:      fee,
+		Category:    SlipFeeCategory,
+		Type:        "expense",
+		Description: description,
+		UseType:     slip.UseType,
+		BankName:    slip.BankName,
+		Source:      slip.Source,
+	}}
+}
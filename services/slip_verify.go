@@ -0,0 +1,100 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+)
+
+// Slip verification results stored on slip transactions
+const (
+	SlipVerified   = "verified"   // bank confirmed reference and amount
+	SlipSuspicious = "suspicious" // reference unknown or amount differs: edited or fake slip
+	SlipUnchecked  = "unchecked"  // provider unavailable or slip has no reference
+)
+
+// SlipCheck is the verdict on one slip; Reason is Thai text shown to the user
+type SlipCheck struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// SlipVerifier checks slips against a slip-verify provider (e.g. OpenSlipVerify) before they are recorded
+type SlipVerifier struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+// NewSlipVerifier returns nil when endpoint is empty (verification disabled)
+func NewSlipVerifier(endpoint, apiKey string) *SlipVerifier {
+	if endpoint == "" {
+		return nil
+	}
+	return &SlipVerifier{endpoint: endpoint, apiKey: apiKey, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// slipVerifyRequest is POSTed to the provider as JSON
+type slipVerifyRequest struct {
+	RefNo    string  `json:"ref_no"`
+	Amount   float64 `json:"amount"`
+	Date     string  `json:"date,omitempty"`
+	FromBank string  `json:"from_bank,omitempty"`
+	ToBank   string  `json:"to_bank,omitempty"`
+}
+
+// slipVerifyResponse is the provider's answer: whether the bank knows the reference and its real amount
+type slipVerifyResponse struct {
+	Found  bool    `json:"found"`
+	Amount float64 `json:"amount"`
+}
+
+// Verify asks the provider about the slip's reference; errors give SlipUnchecked so recording is never blocked
+func (v *SlipVerifier) Verify(ctx context.Context, slip TransactionData) SlipCheck {
+	if slip.RefNo == "" {
+		return SlipCheck{Status: SlipUnchecked, Reason: "อ่านเลขอ้างอิงจากสลิปไม่ได้"}
+	}
+	body, _ := json.Marshal(slipVerifyRequest{RefNo: slip.RefNo, Amount: slip.Amount, Date: slip.Date, FromBank: slip.FromBank, ToBank: slip.ToBank})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return SlipCheck{Status: SlipUnchecked}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if v.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+v.apiKey)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return SlipCheck{Status: SlipUnchecked, Reason: "ตรวจสอบสลิปไม่ได้ในตอนนี้"}
+	}
+	defer resp.Body.Close()
+	// 404 means the provider has no transaction with this reference
+	if resp.StatusCode == http.StatusNotFound {
+		return EvaluateSlipVerification(slip, false, 0)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return SlipCheck{Status: SlipUnchecked, Reason: "ตรวจสอบสลิปไม่ได้ในตอนนี้"}
+	}
+	var result slipVerifyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return SlipCheck{Status: SlipUnchecked, Reason: "ตรวจสอบสลิปไม่ได้ในตอนนี้"}
+	}
+	return EvaluateSlipVerification(slip, result.Found, result.Amount)
+}
+
+// EvaluateSlipVerification compares the slip with what the bank reported for its reference;
+// the amount must match to the satang
+func EvaluateSlipVerification(slip TransactionData, found bool, bankAmount float64) SlipCheck {
+	if !found {
+		return SlipCheck{Status: SlipSuspicious, Reason: "ไม่พบเลขอ้างอิงนี้ในระบบธนาคาร"}
+	}
+	if math.Abs(bankAmount-slip.Amount) >= 0.005 {
+		return SlipCheck{Status: SlipSuspicious, Reason: fmt.Sprintf("ยอดในสลิปไม่ตรงกับธนาคาร (ธนาคาร %.2f บาท)", bankAmount)}
+	}
+	return SlipCheck{Status: SlipVerified}
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Spending approval statuses
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+)
+
+// childLinkTTL is how long a parent's link code can be used
+const childLinkTTL = 30 * time.Minute
+
+// SpendingApproval is a child's batch above the cap, saved only after the parent approves
+type SpendingApproval struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ChildLineID  string             `bson:"child_lineid" json:"child_lineid"`
+	ParentLineID string             `bson:"parent_lineid" json:"parent_lineid"`
+	ChildName    string             `bson:"child_name,omitempty" json:"child_name,omitempty"`
+	Transactions []TransactionData  `bson:"transactions" json:"transactions"`
+	Status       string             `bson:"status" json:"status"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	ResolvedAt   time.Time          `bson:"resolved_at,omitempty" json:"resolved_at,omitempty"`
+}
+
+// Total returns sum of amounts in the batch
+func (a *SpendingApproval) Total() float64 {
+	var total float64
+	for _, tx := range a.Transactions {
+		total += tx.Amount
+	}
+	return total
+}
+
+// IsRestricted reports whether the user is a child account with a spending cap
+func (u *UserSettings) IsRestricted() bool {
+	return u != nil && u.ParentLineID != "" && u.SpendingCap > 0
+}
+
+// ExceedsSpendingCap returns indexes of expenses above cap (single transaction, not a running total)
+func ExceedsSpendingCap(spendingCap float64, txs []TransactionData) []int {
+	var over []int
+	if spendingCap <= 0 {
+		return nil
+	}
+	for i, tx := range txs {
+		if tx.Type == "expense" && tx.Amount > spendingCap {
+			over = append(over, i)
+		}
+	}
+	return over
+}
+
+// NeedsParentApproval reports whether a child's batch must wait for the parent before it is saved
+func NeedsParentApproval(settings *UserSettings, txs []TransactionData) bool {
+	return settings.IsRestricted() && len(ExceedsSpendingCap(settings.SpendingCap, txs)) > 0
+}
+
+// ErrSpendingCap is returned when a child account saves or raises an expense above its cap
+var ErrSpendingCap = errors.New("spending cap exceeded")
+
+// SpendingHeldError reports that a save became a request for the parent's approval
+type SpendingHeldError struct {
+	Approval *SpendingApproval
+	Cap      float64
+}
+
+func (e *SpendingHeldError) Error() string {
+	return fmt.Sprintf("held for parent approval: %.2f above cap %.2f", e.Approval.Total(), e.Cap)
+}
+
+func (e *SpendingHeldError) Unwrap() error { return ErrSpendingCap }
+
+// HoldForParentApproval stores a child's batch as a pending request instead of saving it
+func (s *MongoDBService) HoldForParentApproval(ctx context.Context, childLineID string, settings *UserSettings, txs []TransactionData) (*SpendingApproval, error) {
+	approval := &SpendingApproval{
+		ChildLineID:  childLineID,
+		ParentLineID: settings.ParentLineID,
+		ChildName:    s.GetDisplayName(ctx, childLineID),
+		Transactions: txs,
+	}
+	if err := s.CreateSpendingApproval(ctx, approval); err != nil {
+		return nil, err
+	}
+	return approval, nil
+}
+
+// holdOverCap turns a child's expense above the cap into an approval request (*SpendingHeldError)
+// Every save goes through it, so slips, receipts, statements and confirm buttons can't skip the parent
+func (s *MongoDBService) holdOverCap(ctx context.Context, lineID string, settings *UserSettings, tx *TransactionData, date string) error {
+	held := *tx
+	held.Date = date
+	txs := []TransactionData{held}
+	if !NeedsParentApproval(settings, txs) {
+		return nil
+	}
+	approval, err := s.HoldForParentApproval(ctx, lineID, settings, txs)
+	if err != nil {
+		return fmt.Errorf("failed to hold for approval: %w", err)
+	}
+	return &SpendingHeldError{Approval: approval, Cap: settings.SpendingCap}
+}
+
+// SaveApprovedTransactions saves a batch the parent approved into the child's ledger (cap not checked again)
+// Returns number saved
+func (s *MongoDBService) SaveApprovedTransactions(ctx context.Context, approval *SpendingApproval) int {
+	saved := 0
+	for i := range approval.Transactions {
+		tx := approval.Transactions[i]
+		if tx.Amount <= 0 {
+			continue
+		}
+		if err := s.checkPeriodOpen(ctx, approval.ChildLineID, tx.Date); err != nil {
+			log.Printf("Failed to save approved transaction: %v", err)
+			continue
+		}
+		if _, err := s.saveTransactionOnDate(ctx, approval.ChildLineID, &tx, tx.Date); err != nil {
+			log.Printf("Failed to save approved transaction: %v", err)
+			continue
+		}
+		saved++
+	}
+	return saved
+}
+
+// CreateChildLinkCode issues a one-time code the child types to join the parent with cap
+func (s *MongoDBService) CreateChildLinkCode(ctx context.Context, parentLineID string, spendingCap float64) (string, error) {
+	if spendingCap <= 0 {
+		return "", fmt.Errorf("วงเงินต้องมากกว่า 0")
+	}
+	code := GenerateExportPassword()[:6]
+	data := fmt.Sprintf("%s|%.2f", parentLineID, spendingCap)
+	if err := s.SaveTempData(ctx, "child_link_"+code, data, childLinkTTL); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// LinkChildAccount makes childLineID a restricted account of the code's parent
+func (s *MongoDBService) LinkChildAccount(ctx context.Context, childLineID, code string) (parentLineID string, spendingCap float64, err error) {
+	data, err := s.GetTempData(ctx, "child_link_"+code)
+	if err != nil || data == "" {
+		return "", 0, fmt.Errorf("รหัสไม่ถูกต้องหรือหมดอายุ")
+	}
+	parentLineID, capText, ok := strings.Cut(data, "|")
+	if !ok {
+		return "", 0, fmt.Errorf("รหัสไม่ถูกต้อง")
+	}
+	if spendingCap, err = strconv.ParseFloat(capText, 64); err != nil {
+		return "", 0, fmt.Errorf("รหัสไม่ถูกต้อง")
+	}
+	if parentLineID == childLineID {
+		return "", 0, fmt.Errorf("ใช้รหัสของตัวเองไม่ได้")
+	}
+	// A linked child can't move to another "parent" (e.g. a second account of their own)
+	settings, err := s.GetUserSettings(ctx, childLineID)
+	if err != nil {
+		return "", 0, err
+	}
+	if settings.ParentLineID != "" {
+		return "", 0, fmt.Errorf("บัญชีนี้เชื่อมกับผู้ปกครองอยู่แล้ว ให้ผู้ปกครองพิมพ์ \"ยกเลิกบัญชีเด็ก\" ก่อนค่ะ")
+	}
+	s.DeleteTempData(ctx, "child_link_"+code)
+
+	_, err = s.settingsCollection.UpdateOne(ctx,
+		bson.M{"lineid": childLineID},
+		bson.M{"$set": bson.M{"parent_lineid": parentLineID, "spending_cap": spendingCap, "updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return parentLineID, spendingCap, err
+}
+
+// SetChildrenSpendingCap changes the cap of all children of a parent (returns number updated)
+func (s *MongoDBService) SetChildrenSpendingCap(ctx context.Context, parentLineID string, spendingCap float64) (int64, error) {
+	result, err := s.settingsCollection.UpdateMany(ctx,
+		bson.M{"parent_lineid": parentLineID},
+		bson.M{"$set": bson.M{"spending_cap": spendingCap, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// UnlinkChildren removes restricted mode from all children of a parent
+func (s *MongoDBService) UnlinkChildren(ctx context.Context, parentLineID string) (int64, error) {
+	result, err := s.settingsCollection.UpdateMany(ctx,
+		bson.M{"parent_lineid": parentLineID},
+		bson.M{"$unset": bson.M{"parent_lineid": "", "spending_cap": ""}, "$set": bson.M{"updated_at": time.Now()}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// CreateSpendingApproval stores a held batch for the parent to approve
+func (s *MongoDBService) CreateSpendingApproval(ctx context.Context, approval *SpendingApproval) error {
+	approval.Status = ApprovalPending
+	approval.CreatedAt = time.Now()
+	result, err := s.approvalCollection.InsertOne(ctx, approval)
+	if err != nil {
+		return err
+	}
+	approval.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// GetPendingApprovals returns a parent's pending requests (oldest first)
+func (s *MongoDBService) GetPendingApprovals(ctx context.Context, parentLineID string) ([]SpendingApproval, error) {
+	cursor, err := s.approvalCollection.Find(ctx,
+		bson.M{"parent_lineid": parentLineID, "status": ApprovalPending},
+		options.Find().SetSort(bson.M{"created_at": 1}).SetLimit(10),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var approvals []SpendingApproval
+	if err := cursor.All(ctx, &approvals); err != nil {
+		return nil, err
+	}
+	return approvals, nil
+}
+
+// ResolveSpendingApproval approves or rejects a pending request of this parent (once only)
+func (s *MongoDBService) ResolveSpendingApproval(ctx context.Context, parentLineID, approvalID string, approve bool) (*SpendingApproval, error) {
+	objectID, err := primitive.ObjectIDFromHex(approvalID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid approval ID: %w", err)
+	}
+	status := ApprovalRejected
+	if approve {
+		status = ApprovalApproved
+	}
+	var approval SpendingApproval
+	err = s.approvalCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": objectID, "parent_lineid": parentLineID, "status": ApprovalPending},
+		bson.M{"$set": bson.M{"status": status, "resolved_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&approval)
+	if err == mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("คำขอนี้ถูกจัดการไปแล้ว")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &approval, nil
+}
@@ -0,0 +1,319 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// statementColumns maps header keywords to column roles (checked in this order)
+var statementColumns = []struct {
+	role     string
+	keywords []string
+}{
+	{"date", []string{"วันที่", "date"}},
+	{"withdraw", []string{"ถอน", "withdraw", "debit", "เดบิต", "จ่าย"}},
+	{"deposit", []string{"ฝาก", "deposit", "credit", "เครดิต", "รับ"}},
+	{"amount", []string{"จำนวนเงิน", "amount"}},
+	{"description", []string{"รายการ", "รายละเอียด", "description", "detail", "หมายเหตุ"}},
+}
+
+// ParseStatementCSV reads a bank statement CSV (UTF-8 or Windows-874) into transactions
+// Supports separate withdraw/deposit columns or one signed amount column (negative = expense)
+func ParseStatementCSV(data []byte, now time.Time) ([]TransactionData, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		if decoded, err := charmap.Windows874.NewDecoder().Bytes(data); err == nil {
+			data = decoded // Thai Excel exports are usually TIS-620
+		}
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	// Statements often start with account info, so find the header in the first rows
+	header, columns := -1, map[string]int{}
+	for i := 0; i < len(rows) && i < 15 && header < 0; i++ {
+		found := map[string]int{}
+		for col, cell := range rows[i] {
+			cell = strings.ToLower(strings.TrimSpace(cell))
+			for _, c := range statementColumns {
+				if _, taken := found[c.role]; taken {
+					continue
+				}
+				if containsAny(cell, c.keywords) {
+					found[c.role] = col
+					break
+				}
+			}
+		}
+		_, hasDate := found["date"]
+		_, hasAmount := found["amount"]
+		_, hasWithdraw := found["withdraw"]
+		_, hasDeposit := found["deposit"]
+		if hasDate && (hasAmount || hasWithdraw || hasDeposit) {
+			header, columns = i, found
+		}
+	}
+	if header < 0 {
+		return nil, errors.New("statement header not found")
+	}
+
+	cell := func(row []string, role string) string {
+		col, ok := columns[role]
+		if !ok || col >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[col])
+	}
+
+	var txs []TransactionData
+	for _, row := range rows[header+1:] {
+		date, ok := parseStatementDate(cell(row, "date"), now)
+		if !ok {
+			continue // Summary/footer rows
+		}
+
+		tx := TransactionData{
+			Date:        date,
+			Category:    "อื่นๆ",
+			Description: cell(row, "description"),
+		}
+		if amount := parseStatementAmount(cell(row, "withdraw")); amount > 0 {
+			tx.Type, tx.Amount = "expense", amount
+		} else if amount := parseStatementAmount(cell(row, "deposit")); amount > 0 {
+			tx.Type, tx.Amount = "income", amount
+		} else if raw := cell(row, "amount"); raw != "" {
+			amount := parseStatementAmount(raw)
+			tx.Type, tx.Amount = "income", math.Abs(amount)
+			if amount < 0 || strings.HasSuffix(raw, "-") {
+				tx.Type = "expense"
+			}
+		}
+		if tx.Amount == 0 {
+			continue
+		}
+		txs = append(txs, tx)
+	}
+	return txs, nil
+}
+
+// ParseStatementSMS splits pasted SMS history into transactions (one SMS may span several lines)
+func ParseStatementSMS(text string, now time.Time) []TransactionData {
+	var txs []TransactionData
+	var buf []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			buf = nil
+			continue
+		}
+		buf = append(buf, line)
+		if sms, ok := ParseBankSMS(strings.Join(buf, " "), now); ok {
+			txs = append(txs, sms.ToTransaction(nil, nil))
+			buf = nil
+		}
+	}
+	return txs
+}
+
+// parseStatementDate accepts dd/mm/yyyy, dd-mm-yy (BE or CE) and yyyy-mm-dd, ignoring time
+func parseStatementDate(value string, now time.Time) (string, bool) {
+	value = thaiDigitReplacer.Replace(strings.TrimSpace(value))
+	if fields := strings.Fields(value); len(fields) > 0 {
+		value = fields[0]
+	}
+	parts := strings.FieldsFunc(value, func(r rune) bool { return r == '/' || r == '-' || r == '.' })
+	if len(parts) != 3 {
+		return "", false
+	}
+	nums := make([]int, 3)
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return "", false
+		}
+		nums[i] = n
+	}
+	day, month, year := nums[0], nums[1], nums[2]
+	if len(parts[0]) == 4 {
+		year, month, day = nums[0], nums[1], nums[2]
+	}
+	d, ok := makeDate(year, month, day, now)
+	if !ok {
+		return "", false
+	}
+	return d.Format("2006-01-02"), true
+}
+
+// parseStatementAmount parses "1,234.50", "(1,234.50)" or "-1234.50"; returns 0 when empty
+func parseStatementAmount(value string) float64 {
+	value = strings.TrimSpace(value)
+	negative := strings.HasPrefix(value, "(") || strings.HasPrefix(value, "-") || strings.HasSuffix(value, "-")
+	value = strings.Trim(value, "()-+ ")
+	value = strings.ReplaceAll(value, ",", "")
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	if negative {
+		return -amount
+	}
+	return amount
+}
+
+// DetectBankName returns the bank/card issuer mentioned in text (e.g. statement file name)
+func DetectBankName(text string) (name string, useType int, ok bool) {
+	sender := detectBankSender(strings.ToLower(text))
+	if sender == nil {
+		return "", 0, false
+	}
+	return sender.name, sender.useType, true
+}
+
+// samePaymentAccount checks whether a recorded transaction belongs to the statement's account
+// Statement rows without account info match any recorded transaction
+func samePaymentAccount(statement, recorded TransactionData) bool {
+	want := statement.BankName
+	if statement.UseType == 1 {
+		want = statement.CreditCardName
+	}
+	if want == "" {
+		return true
+	}
+	if recorded.UseType != statement.UseType {
+		return false
+	}
+	got := recorded.BankName
+	if recorded.UseType == 1 {
+		got = recorded.CreditCardName
+	}
+	if got == "" || strings.EqualFold(got, want) {
+		return true
+	}
+	sender := detectBankSender(strings.ToLower(want))
+	return sender != nil && sender == detectBankSender(strings.ToLower(got))
+}
+
+// FindMissingTransactions returns statement rows with no recorded transaction of the same
+// type, account and amount on the same day (or one day apart for posting delays)
+// Each recorded transaction matches at most one statement row
+func FindMissingTransactions(statement, recorded []TransactionData) []TransactionData {
+	used := make([]bool, len(recorded))
+	matched := make([]bool, len(statement))
+
+	// Exact date first, then ±1 day so a nearby duplicate can't steal an exact match
+	for _, tolerance := range []float64{0, 1} {
+		for i, st := range statement {
+			if matched[i] {
+				continue
+			}
+			stDate, err := time.Parse("2006-01-02", st.Date)
+			if err != nil {
+				continue
+			}
+			for j, rec := range recorded {
+				if used[j] || rec.Type != st.Type || math.Abs(rec.Amount-st.Amount) >= 0.005 || !samePaymentAccount(st, rec) {
+					continue
+				}
+				recDate, err := time.Parse("2006-01-02", rec.Date)
+				if err != nil || math.Abs(recDate.Sub(stDate).Hours()/24) > tolerance {
+					continue
+				}
+				used[j], matched[i] = true, true
+				break
+			}
+		}
+	}
+
+	var missing []TransactionData
+	for i, st := range statement {
+		if !matched[i] {
+			missing = append(missing, st)
+		}
+	}
+	sort.SliceStable(missing, func(i, j int) bool { return missing[i].Date < missing[j].Date })
+	return missing
+}
+
+// FindUnrecordedTransactions compares statement rows with recorded transactions in the same date range
+func (s *MongoDBService) FindUnrecordedTransactions(ctx context.Context, lineID string, statement []TransactionData) ([]TransactionData, error) {
+	if len(statement) == 0 {
+		return nil, nil
+	}
+	from, to := statement[0].Date, statement[0].Date
+	for _, tx := range statement {
+		if tx.Date < from {
+			from = tx.Date
+		}
+		if tx.Date > to {
+			to = tx.Date
+		}
+	}
+	// Widen by a day for the ±1 day match
+	if d, err := time.Parse("2006-01-02", from); err == nil {
+		from = d.AddDate(0, 0, -1).Format("2006-01-02")
+	}
+	if d, err := time.Parse("2006-01-02", to); err == nil {
+		to = d.AddDate(0, 0, 1).Format("2006-01-02")
+	}
+
+	cursor, err := s.collection.Find(ctx, bson.M{
+		"lineid": lineID,
+		"date":   bson.M{"$gte": from, "$lte": to},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var recorded []TransactionData
+	for cursor.Next(ctx) {
+		var record DailyRecord
+		if err := cursor.Decode(&record); err != nil {
+			continue
+		}
+		for _, list := range [][]Transaction{record.Incomes, record.Expenses} {
+			for _, tx := range list {
+				txType := "expense"
+				if tx.Type == 1 {
+					txType = "income"
+				}
+				recorded = append(recorded, TransactionData{
+					Date:           record.Date,
+					Amount:         tx.Amount,
+					Type:           txType,
+					UseType:        tx.UseType,
+					BankName:       tx.BankName,
+					CreditCardName: tx.CreditCardName,
+				})
+			}
+		}
+	}
+
+	return FindMissingTransactions(statement, recorded), nil
+}
+
+// containsAny reports whether text contains any of the keywords
+func containsAny(text string, keywords []string) bool {
+	for _, kw := range keywords {
+		if strings.Contains(text, kw) {
+			return true
+		}
+	}
+	return false
+}
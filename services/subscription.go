@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Subscription represents a recurring same-amount charge detected from expenses
+type Subscription struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	LineID         string             `bson:"lineid" json:"lineid"`
+	Key            string             `bson:"key" json:"key"` // normalized name|amount
+	Name           string             `bson:"name" json:"name"`
+	Amount         float64            `bson:"amount" json:"amount"`
+	Category       string             `bson:"category" json:"category"`
+	UseType        int                `bson:"usetype" json:"usetype"`
+	BankName       string             `bson:"bankname" json:"bankname"`
+	CreditCardName string             `bson:"creditcardname" json:"creditcardname"`
+	LastCharged    string             `bson:"last_charged" json:"last_charged"` // YYYY-MM-DD
+	NextRenewal    string             `bson:"next_renewal" json:"next_renewal"` // YYYY-MM-DD
+	Occurrences    int                `bson:"occurrences" json:"occurrences"`
+	Status         string             `bson:"status" json:"status"` // "active" or "cancelled"
+	CancelledAt    *time.Time         `bson:"cancelled_at,omitempty" json:"cancelled_at,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// knownSubscriptions are merchant keywords that are almost always subscriptions
+var knownSubscriptions = []string{
+	"netflix", "spotify", "icloud", "youtube", "disney", "apple music", "apple one",
+	"google one", "chatgpt", "viu", "wetv", "iqiyi", "hbo", "prime video", "canva", "line music",
+}
+
+// isKnownSubscription checks if name matches a known subscription service
+func isKnownSubscription(name string) bool {
+	lower := strings.ToLower(name)
+	for _, kw := range knownSubscriptions {
+		if strings.Contains(lower, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// subscriptionCandidate collects charges with the same name and amount
+type subscriptionCandidate struct {
+	tx    Transaction
+	name  string
+	dates []string
+}
+
+// DetectSubscriptions scans last 6 months of expenses for monthly same-amount charges
+// and upserts them. Cancelled subscriptions stay cancelled.
+func (s *MongoDBService) DetectSubscriptions(ctx context.Context, lineID string) error {
+	now := time.Now()
+	from := now.AddDate(0, -6, 0).Format("2006-01-02")
+
+	cursor, err := s.collection.Find(ctx, bson.M{
+		"lineid": lineID,
+		"date":   bson.M{"$gte": from},
+	})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	candidates := make(map[string]*subscriptionCandidate)
+	for cursor.Next(ctx) {
+		var record DailyRecord
+		if err := cursor.Decode(&record); err != nil {
+			continue
+		}
+		for _, tx := range record.Expenses {
+			if tx.IsAssetMove() || tx.Amount <= 0 {
+				continue
+			}
+			name := tx.MerchantName() // branch numbers and spellings of a chain group as one merchant
+			if name == "" {
+				name = strings.TrimSpace(tx.Description)
+			}
+			if name == "" {
+				continue
+			}
+			key := fmt.Sprintf("%s|%.2f", strings.ToLower(name), tx.Amount)
+			c, ok := candidates[key]
+			if !ok {
+				c = &subscriptionCandidate{name: name}
+				candidates[key] = c
+			}
+			c.dates = append(c.dates, record.Date)
+			if record.Date >= maxDate(c.dates) {
+				c.tx = tx // Keep latest payment method
+			}
+		}
+	}
+
+	for key, c := range candidates {
+		sort.Strings(c.dates)
+		if !isMonthlyPattern(c.dates) && !(isKnownSubscription(c.name) && withinDays(c.dates[len(c.dates)-1], now, 35)) {
+			continue
+		}
+
+		last := c.dates[len(c.dates)-1]
+		lastDate, err := time.Parse("2006-01-02", last)
+		if err != nil {
+			continue
+		}
+
+		_, err = s.subscriptionCollection.UpdateOne(ctx,
+			bson.M{"lineid": lineID, "key": key},
+			bson.M{
+				"$set": bson.M{
+					"name":           c.name,
+					"amount":         c.tx.Amount,
+					"category":       c.tx.Category,
+					"usetype":        c.tx.UseType,
+					"bankname":       c.tx.BankName,
+					"creditcardname": c.tx.CreditCardName,
+					"last_charged":   last,
+					"next_renewal":   lastDate.AddDate(0, 1, 0).Format("2006-01-02"),
+					"occurrences":    len(c.dates),
+					"updated_at":     now,
+				},
+				"$setOnInsert": bson.M{
+					"status":     "active",
+					"created_at": now,
+				},
+			},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maxDate returns the latest YYYY-MM-DD date in list
+func maxDate(dates []string) string {
+	latest := ""
+	for _, d := range dates {
+		if d > latest {
+			latest = d
+		}
+	}
+	return latest
+}
+
+// isMonthlyPattern checks if sorted dates contain charges roughly one month apart
+func isMonthlyPattern(dates []string) bool {
+	for i := 1; i < len(dates); i++ {
+		prev, err1 := time.Parse("2006-01-02", dates[i-1])
+		cur, err2 := time.Parse("2006-01-02", dates[i])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		days := cur.Sub(prev).Hours() / 24
+		if days >= 25 && days <= 35 {
+			return true
+		}
+	}
+	return false
+}
+
+// withinDays checks if date (YYYY-MM-DD) is within n days before now
+func withinDays(date string, now time.Time, n int) bool {
+	d, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return false
+	}
+	return now.Sub(d) <= time.Duration(n)*24*time.Hour
+}
+
+// GetSubscriptions returns subscriptions sorted by next renewal (active first)
+func (s *MongoDBService) GetSubscriptions(ctx context.Context, lineID string) ([]Subscription, error) {
+	cursor, err := s.subscriptionCollection.Find(ctx, bson.M{"lineid": lineID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var subs []Subscription
+	if err := cursor.All(ctx, &subs); err != nil {
+		return nil, err
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		if subs[i].Status != subs[j].Status {
+			return subs[i].Status == "active"
+		}
+		return subs[i].NextRenewal < subs[j].NextRenewal
+	})
+	return subs, nil
+}
+
+// GetUpcomingRenewals returns active subscriptions renewing within the next n days
+func (s *MongoDBService) GetUpcomingRenewals(ctx context.Context, lineID string, days int) ([]Subscription, error) {
+	now := time.Now()
+	cursor, err := s.subscriptionCollection.Find(ctx, bson.M{
+		"lineid": lineID,
+		"status": "active",
+		"next_renewal": bson.M{
+			"$gte": now.Format("2006-01-02"),
+			"$lte": now.AddDate(0, 0, days).Format("2006-01-02"),
+		},
+	}, options.Find().SetSort(bson.M{"next_renewal": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var subs []Subscription
+	if err := cursor.All(ctx, &subs); err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// CancelSubscription marks a subscription as cancelled so it is no longer expected
+func (s *MongoDBService) CancelSubscription(ctx context.Context, lineID, subID string) (*Subscription, error) {
+	objID, err := primitive.ObjectIDFromHex(subID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subscription ID: %w", err)
+	}
+
+	now := time.Now()
+	var sub Subscription
+	err = s.subscriptionCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": objID, "lineid": lineID},
+		bson.M{"$set": bson.M{"status": "cancelled", "cancelled_at": now, "updated_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&sub)
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// GetSubscriptionMonthlyTotal sums amounts of active subscriptions
+func GetSubscriptionMonthlyTotal(subs []Subscription) float64 {
+	var total float64
+	for _, sub := range subs {
+		if sub.Status == "active" {
+			total += sub.Amount
+		}
+	}
+	return total
+}
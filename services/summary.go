@@ -0,0 +1,356 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// CategoryComparison represents spending of one category in two periods
+type CategoryComparison struct {
+	Category      string  `json:"category"`
+	Current       float64 `json:"current"`
+	Previous      float64 `json:"previous"`
+	Diff          float64 `json:"diff"`           // current - previous
+	ChangePercent float64 `json:"change_percent"` // diff/previous * 100 (0 if previous is 0)
+}
+
+// PeriodComparison represents spending comparison between current and previous period
+type PeriodComparison struct {
+	Period        string               `json:"period"` // "month" or "week"
+	CurrentLabel  string               `json:"current_label"`
+	PreviousLabel string               `json:"previous_label"`
+	CurrentFrom   string               `json:"current_from"`
+	CurrentTo     string               `json:"current_to"`
+	PreviousFrom  string               `json:"previous_from"`
+	PreviousTo    string               `json:"previous_to"`
+	CurrentTotal  float64              `json:"current_total"`
+	PreviousTotal float64              `json:"previous_total"`
+	Categories    []CategoryComparison `json:"categories"` // sorted by current spending (highest first)
+}
+
+// PeriodRanges returns date ranges for current period (to date) and the same days of the previous period
+// period: "month" (calendar month; the 3rd is compared with 1-3 of last month, not a whole month) or
+// "week" (Monday - Sunday; Wednesday is compared with Monday - Wednesday of last week)
+func PeriodRanges(period string, now time.Time) (curFrom, curTo, prevFrom, prevTo time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	if period == "week" {
+		// Monday as first day of week
+		offset := (int(today.Weekday()) + 6) % 7
+		curFrom = today.AddDate(0, 0, -offset)
+		prevFrom = curFrom.AddDate(0, 0, -7)
+		prevTo = prevFrom.AddDate(0, 0, offset)
+		return curFrom, today, prevFrom, prevTo
+	}
+
+	curFrom = time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+	prevFrom = curFrom.AddDate(0, -1, 0)
+	prevTo = prevFrom.AddDate(0, 0, today.Day()-1)
+	if monthEnd := curFrom.AddDate(0, 0, -1); prevTo.After(monthEnd) {
+		prevTo = monthEnd // 31 Mar vs 1-28 Feb
+	}
+	return curFrom, today, prevFrom, prevTo
+}
+
+// LastWeekRange returns the whole previous week (Monday - Sunday) for weekly digests
+func LastWeekRange(now time.Time) (from, to time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	return monday.AddDate(0, 0, -7), monday.AddDate(0, 0, -1)
+}
+
+// GetPeriodComparison compares spending by category between current period and previous period
+func (s *MongoDBService) GetPeriodComparison(ctx context.Context, lineID, period string) (*PeriodComparison, error) {
+	if period != "week" {
+		period = "month"
+	}
+
+	curFrom, curTo, prevFrom, prevTo := PeriodRanges(period, time.Now())
+
+	current, err := s.GetSpendingByCategoryRange(ctx, lineID, curFrom.Format("2006-01-02"), curTo.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.GetSpendingByCategoryRange(ctx, lineID, prevFrom.Format("2006-01-02"), prevTo.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+
+	comparison := &PeriodComparison{
+		Period:        period,
+		CurrentLabel:  "เดือนนี้",
+		PreviousLabel: "เดือนที่แล้ว",
+		CurrentFrom:   curFrom.Format("2006-01-02"),
+		CurrentTo:     curTo.Format("2006-01-02"),
+		PreviousFrom:  prevFrom.Format("2006-01-02"),
+		PreviousTo:    prevTo.Format("2006-01-02"),
+	}
+	if period == "week" {
+		comparison.CurrentLabel = "สัปดาห์นี้"
+		comparison.PreviousLabel = "สัปดาห์ที่แล้ว"
+	}
+
+	// Merge category keys from both periods
+	categories := make(map[string]bool)
+	for cat, amount := range current {
+		categories[cat] = true
+		comparison.CurrentTotal += amount
+	}
+	for cat, amount := range previous {
+		categories[cat] = true
+		comparison.PreviousTotal += amount
+	}
+
+	for cat := range categories {
+		cc := CategoryComparison{
+			Category: cat,
+			Current:  current[cat],
+			Previous: previous[cat],
+		}
+		cc.Diff = cc.Current - cc.Previous
+		if cc.Previous > 0 {
+			cc.ChangePercent = (cc.Diff / cc.Previous) * 100
+		}
+		comparison.Categories = append(comparison.Categories, cc)
+	}
+
+	sort.Slice(comparison.Categories, func(i, j int) bool {
+		if comparison.Categories[i].Current != comparison.Categories[j].Current {
+			return comparison.Categories[i].Current > comparison.Categories[j].Current
+		}
+		return comparison.Categories[i].Previous > comparison.Categories[j].Previous
+	})
+
+	return comparison, nil
+}
+
+// GetComparisonContextText returns compact month/week comparison text for AI context
+// Format: "เทียบเดือนนี้/เดือนที่แล้ว|รวม:1200/900|อาหาร:800/500|..."
+func (s *MongoDBService) GetComparisonContextText(ctx context.Context, lineID string) string {
+	var lines []string
+
+	for _, period := range []string{"month", "week"} {
+		comparison, err := s.GetPeriodComparison(ctx, lineID, period)
+		if err != nil || (comparison.CurrentTotal == 0 && comparison.PreviousTotal == 0) {
+			continue
+		}
+
+		label := fmt.Sprintf("เทียบ%s/%s", comparison.CurrentLabel, comparison.PreviousLabel)
+		if period == "month" {
+			label += fmt.Sprintf("(ช่วงวันที่ 1-%s)", comparison.CurrentTo[8:])
+		}
+		parts := []string{
+			label,
+			fmt.Sprintf("รวม:%.0f/%.0f", comparison.CurrentTotal, comparison.PreviousTotal),
+		}
+		for i, cc := range comparison.Categories {
+			if i >= 8 { // Limit categories to keep context small
+				break
+			}
+			parts = append(parts, fmt.Sprintf("%s:%.0f/%.0f", cc.Category, cc.Current, cc.Previous))
+		}
+		lines = append(lines, strings.Join(parts, "|"))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// CategoryAmount represents total amount of one category
+type CategoryAmount struct {
+	Category string  `json:"category"`
+	Amount   float64 `json:"amount"`
+}
+
+// PeriodSummary represents deterministic income/expense summary for a date range
+type PeriodSummary struct {
+	Period            string           `json:"period"` // "day", "week", "month"
+	Label             string           `json:"label"`  // "วันนี้", "สัปดาห์นี้", "เดือนนี้"
+	From              string           `json:"from"`
+	To                string           `json:"to"`
+	TotalIncome       float64          `json:"total_income"`
+	TotalExpense      float64          `json:"total_expense"`
+	TotalInvested     float64          `json:"total_invested,omitempty"` // investment buys, kept out of expense
+	Balance           float64          `json:"balance"`                  // income - expense
+	TransactionCount  int              `json:"transaction_count"`
+	DailyAverage      float64          `json:"daily_average"`       // average expense per day (to date)
+	ExpenseByCategory []CategoryAmount `json:"expense_by_category"` // sorted highest first
+	Pace              *BudgetPace      `json:"pace,omitempty"`      // monthly budget pace (nil when no budget)
+}
+
+// BudgetPace compares share of monthly budget used with share of month elapsed
+type BudgetPace struct {
+	Budget         float64 `json:"budget"`
+	Spent          float64 `json:"spent"`
+	UsedPercent    float64 `json:"used_percent"`
+	ElapsedPercent float64 `json:"elapsed_percent"`
+	Status         string  `json:"status"` // "over", "ahead" (spending faster than time), "on_track"
+}
+
+// paceTolerance is how many percent points spending may run ahead of the month before warning
+const paceTolerance = 10.0
+
+// CalculateBudgetPace computes budget pace for the month containing now
+func CalculateBudgetPace(spent, budget float64, now time.Time) *BudgetPace {
+	if budget <= 0 {
+		return nil
+	}
+	daysInMonth := time.Date(now.Year(), now.Month()+1, 0, 0, 0, 0, 0, now.Location()).Day()
+	pace := &BudgetPace{
+		Budget:         budget,
+		Spent:          spent,
+		UsedPercent:    (spent / budget) * 100,
+		ElapsedPercent: float64(now.Day()) / float64(daysInMonth) * 100,
+		Status:         "on_track",
+	}
+	switch {
+	case spent > budget:
+		pace.Status = "over"
+	case pace.UsedPercent > pace.ElapsedPercent+paceTolerance:
+		pace.Status = "ahead"
+	}
+	return pace
+}
+
+// Text returns pace sentence, e.g. "ใช้ไป 60% ของงบ แต่เพิ่งผ่านไป 48% ของเดือน"
+func (p *BudgetPace) Text() string {
+	switch p.Status {
+	case "over":
+		return fmt.Sprintf("ใช้เกินงบแล้ว %.0f%% ทั้งที่ผ่านไป %.0f%% ของเดือน", p.UsedPercent, p.ElapsedPercent)
+	case "ahead":
+		return fmt.Sprintf("ใช้ไป %.0f%% ของงบ แต่เพิ่งผ่านไป %.0f%% ของเดือน", p.UsedPercent, p.ElapsedPercent)
+	}
+	return fmt.Sprintf("ใช้ไป %.0f%% ของงบ ผ่านไป %.0f%% ของเดือน ตามแผน", p.UsedPercent, p.ElapsedPercent)
+}
+
+// GetBudgetPace returns this month's pace over all budgeted categories (nil when no budget)
+func (s *MongoDBService) GetBudgetPace(ctx context.Context, lineID string, now time.Time) (*BudgetPace, error) {
+	statuses, err := s.ProjectedBudgetStatus(ctx, lineID)
+	if err != nil {
+		return nil, err
+	}
+	var budget, spent float64
+	for _, st := range statuses {
+		budget += st.Budget
+		spent += st.Spent
+	}
+	return CalculateBudgetPace(spent, budget, now), nil
+}
+
+// GetDailySummary returns today's summary
+func (s *MongoDBService) GetDailySummary(ctx context.Context, lineID string) (*PeriodSummary, error) {
+	today := time.Now().Format("2006-01-02")
+	return s.getPeriodSummary(ctx, lineID, "day", "วันนี้", today, today)
+}
+
+// GetWeeklySummary returns this week's summary (Monday to today)
+func (s *MongoDBService) GetWeeklySummary(ctx context.Context, lineID string) (*PeriodSummary, error) {
+	from, to, _, _ := PeriodRanges("week", time.Now())
+	return s.getPeriodSummary(ctx, lineID, "week", "สัปดาห์นี้", from.Format("2006-01-02"), to.Format("2006-01-02"))
+}
+
+// GetMonthlySummary returns this month's summary (1st to today)
+func (s *MongoDBService) GetMonthlySummary(ctx context.Context, lineID string) (*PeriodSummary, error) {
+	from, to, _, _ := PeriodRanges("month", time.Now())
+	return s.getPeriodSummary(ctx, lineID, "month", "เดือนนี้", from.Format("2006-01-02"), to.Format("2006-01-02"))
+}
+
+// getPeriodSummary calculates summary between two dates, excluding transfers
+func (s *MongoDBService) getPeriodSummary(ctx context.Context, lineID, period, label, from, to string) (*PeriodSummary, error) {
+	filter := bson.M{
+		"lineid": lineID,
+		"date":   bson.M{"$gte": from, "$lte": to},
+	}
+	cursor, err := s.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	summary := &PeriodSummary{
+		Period: period,
+		Label:  label,
+		From:   from,
+		To:     to,
+	}
+
+	byCategory := make(map[string]float64)
+	for cursor.Next(ctx) {
+		var record DailyRecord
+		if err := cursor.Decode(&record); err != nil {
+			continue
+		}
+
+		for _, tx := range record.Incomes {
+			if tx.IsAssetMove() {
+				continue // Transfers and investments don't affect income/expense
+			}
+			summary.TransactionCount++
+			summary.TotalIncome += tx.Amount
+		}
+		for _, tx := range record.Expenses {
+			if tx.Category == InvestmentCategory {
+				summary.TotalInvested += tx.Amount
+				continue
+			}
+			if tx.IsTransfer {
+				continue
+			}
+			summary.TransactionCount++
+			summary.TotalExpense += tx.Amount
+			category := tx.Category
+			if category == "" {
+				category = "อื่นๆ"
+			}
+			byCategory[category] += tx.Amount
+		}
+	}
+	summary.Balance = summary.TotalIncome - summary.TotalExpense
+
+	for cat, amount := range byCategory {
+		summary.ExpenseByCategory = append(summary.ExpenseByCategory, CategoryAmount{Category: cat, Amount: amount})
+	}
+	sort.Slice(summary.ExpenseByCategory, func(i, j int) bool {
+		return summary.ExpenseByCategory[i].Amount > summary.ExpenseByCategory[j].Amount
+	})
+
+	// Daily average over elapsed days
+	if fromDate, err := time.Parse("2006-01-02", from); err == nil {
+		if toDate, err := time.Parse("2006-01-02", to); err == nil {
+			days := int(toDate.Sub(fromDate).Hours()/24) + 1
+			if days > 0 {
+				summary.DailyAverage = summary.TotalExpense / float64(days)
+			}
+		}
+	}
+
+	return summary, nil
+}
+
+// ToAIText returns compact summary text for AI advice prompt
+func (p *PeriodSummary) ToAIText() string {
+	parts := []string{
+		fmt.Sprintf("สรุป%s (%s ถึง %s)", p.Label, p.From, p.To),
+		fmt.Sprintf("รายรับ:%.0f", p.TotalIncome),
+		fmt.Sprintf("รายจ่าย:%.0f", p.TotalExpense),
+		fmt.Sprintf("คงเหลือ:%.0f", p.Balance),
+		fmt.Sprintf("เฉลี่ยจ่าย/วัน:%.0f", p.DailyAverage),
+	}
+	if p.TotalInvested > 0 {
+		parts = append(parts, fmt.Sprintf("ลงทุน(ไม่นับเป็นรายจ่าย):%.0f", p.TotalInvested))
+	}
+	for i, ca := range p.ExpenseByCategory {
+		if i >= 5 {
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s:%.0f", ca.Category, ca.Amount))
+	}
+	if p.Pace != nil {
+		parts = append(parts, fmt.Sprintf("งบเดือน:ใช้%.0f%%/ผ่าน%.0f%%", p.Pace.UsedPercent, p.Pace.ElapsedPercent))
+	}
+	return strings.Join(parts, "|")
+}
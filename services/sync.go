@@ -0,0 +1,349 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Sync entities and operations of a SyncChange
+const (
+	SyncEntityTransaction = "transaction"
+	SyncEntityBudget      = "budget"
+	SyncEntityCard        = "card"
+
+	SyncOpUpsert = "upsert"
+	SyncOpDelete = "delete"
+	SyncOpReset  = "reset" // all data replaced (backup restore): clients download everything again
+)
+
+// SyncRetention is how long changes are kept (sync_changes TTL); older cursors start over with a full download
+const SyncRetention = 90 * 24 * time.Hour
+
+// syncSettle is how long a missing sequence number is waited for: a change numbered earlier may still be
+// inserting on another instance, so the page stops before the gap instead of skipping it
+const syncSettle = 5 * time.Second
+
+// Sync page sizes
+const (
+	DefaultSyncLimit = 200
+	MaxSyncLimit     = 1000
+)
+
+// ErrInvalidSyncCursor is returned for a cursor the server didn't issue
+var ErrInvalidSyncCursor = errors.New("invalid sync cursor")
+
+// SyncChange is one change in a user's change feed (sync_changes), numbered by a per-user Seq
+type SyncChange struct {
+	LineID      string       `bson:"lineid" json:"-"`
+	Seq         int64        `bson:"seq" json:"seq"`
+	Entity      string       `bson:"entity" json:"entity"`
+	Op          string       `bson:"op" json:"op"`
+	ID          string       `bson:"id" json:"id"`                         // transaction ID, budget category or card name
+	Date        string       `bson:"date,omitempty" json:"date,omitempty"` // day of a transaction
+	Transaction *Transaction `bson:"transaction,omitempty" json:"transaction,omitempty"`
+	Budget      *Budget      `bson:"budget,omitempty" json:"budget,omitempty"`
+	Card        *CardAccount `bson:"card,omitempty" json:"card,omitempty"`
+	CreatedAt   time.Time    `bson:"created_at" json:"created_at"`
+}
+
+// SyncCursor is where a client is in its change feed
+// Seq is the last change applied; a bootstrap cursor is a full download in progress (After = last
+// transaction sent) that continues from Seq once done
+type SyncCursor struct {
+	Seq       int64
+	Issued    time.Time
+	Bootstrap bool
+	After     SyncKey
+}
+
+// SyncKey is a transaction's place in the full download order (date, created_at, _id, all descending)
+// Paging continues after the key instead of skipping a count, so entries added or deleted while a
+// client downloads don't shift rows past it
+type SyncKey struct {
+	Date      string
+	CreatedAt time.Time
+	ID        primitive.ObjectID
+}
+
+// ParseSyncCursor reads a cursor string ("" starts a full download)
+// Formats: "<seq>.<issued unix>", "b<seq>" and "b<seq>.<date>.<created unix ms>.<id>"
+func ParseSyncCursor(s string) (SyncCursor, error) {
+	if s == "" {
+		return SyncCursor{Bootstrap: true}, nil
+	}
+	if strings.HasPrefix(s, "b") {
+		parts := strings.Split(s[1:], ".")
+		seq, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil || seq < 0 {
+			return SyncCursor{}, ErrInvalidSyncCursor
+		}
+		cursor := SyncCursor{Seq: seq, Bootstrap: true}
+		if len(parts) == 1 {
+			return cursor, nil
+		}
+		if len(parts) != 4 {
+			return SyncCursor{}, ErrInvalidSyncCursor
+		}
+		created, err1 := strconv.ParseInt(parts[2], 10, 64)
+		id, err2 := primitive.ObjectIDFromHex(parts[3])
+		if _, err3 := time.Parse("2006-01-02", parts[1]); err1 != nil || err2 != nil || err3 != nil {
+			return SyncCursor{}, ErrInvalidSyncCursor
+		}
+		cursor.After = SyncKey{Date: parts[1], CreatedAt: time.UnixMilli(created), ID: id}
+		return cursor, nil
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) != 2 {
+		return SyncCursor{}, ErrInvalidSyncCursor
+	}
+	seq, err1 := strconv.ParseInt(parts[0], 10, 64)
+	issued, err2 := strconv.ParseInt(parts[1], 10, 64)
+	if err1 != nil || err2 != nil || seq < 0 || issued < 0 {
+		return SyncCursor{}, ErrInvalidSyncCursor
+	}
+	return SyncCursor{Seq: seq, Issued: time.Unix(issued, 0)}, nil
+}
+
+// String formats the cursor for the client
+func (c SyncCursor) String() string {
+	if c.Bootstrap && c.After.ID.IsZero() {
+		return fmt.Sprintf("b%d", c.Seq)
+	}
+	if c.Bootstrap {
+		return fmt.Sprintf("b%d.%s.%d.%s", c.Seq, c.After.Date, c.After.CreatedAt.UnixMilli(), c.After.ID.Hex())
+	}
+	return fmt.Sprintf("%d.%d", c.Seq, c.Issued.Unix())
+}
+
+// Expired reports whether changes after the cursor may already be gone (TTL), so the client must start over
+func (c SyncCursor) Expired(now time.Time) bool {
+	return !c.Bootstrap && now.Sub(c.Issued) > SyncRetention-24*time.Hour
+}
+
+// SyncPage is one response of the sync API
+// Reset tells the client to drop local data first (first page of a full download)
+type SyncPage struct {
+	Cursor   string           `json:"cursor"`
+	HasMore  bool             `json:"has_more"`
+	Reset    bool             `json:"reset,omitempty"`
+	Changes  []SyncChange     `json:"changes"`
+	Accounts []PaymentBalance `json:"accounts,omitempty"` // current balances, sent when transactions changed
+}
+
+// recordSyncChange queues a change for the user's feed; it's numbered and written in background, in the
+// order changes were recorded (failures are logged; clients only miss it until their next full download)
+func (s *MongoDBService) recordSyncChange(change SyncChange) {
+	enqueueOrdered(&s.syncQueues, change.LineID, "sync.record", func() { s.writeSyncChange(change) })
+}
+
+// writeSyncChange numbers a change and appends it to the user's feed
+func (s *MongoDBService) writeSyncChange(change SyncChange) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	err := s.syncCounterCollection.FindOneAndUpdate(ctx,
+		bson.M{"lineid": change.LineID},
+		bson.M{"$inc": bson.M{"seq": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		log.Printf("Failed to number sync change: %v", err)
+		return
+	}
+	change.Seq = counter.Seq
+	change.CreatedAt = time.Now()
+	if _, err := s.syncCollection.InsertOne(ctx, change); err != nil {
+		log.Printf("Failed to record sync change: %v", err)
+	}
+}
+
+// currentSyncSeq returns the user's latest change number
+func (s *MongoDBService) currentSyncSeq(ctx context.Context, lineID string) (int64, error) {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	err := s.syncCounterCollection.FindOne(ctx, bson.M{"lineid": lineID}).Decode(&counter)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	return counter.Seq, err
+}
+
+// onSyncTransaction is the transaction hook feeding the change feed
+func (s *MongoDBService) onSyncTransaction(event, lineID, date string, tx Transaction) {
+	change := SyncChange{LineID: lineID, Entity: SyncEntityTransaction, Op: SyncOpUpsert, ID: tx.ID.Hex(), Date: date}
+	if event == TransactionDeleted {
+		change.Op = SyncOpDelete
+	} else {
+		tx.ImageBase64 = "" // receipts stay on the server
+		change.Transaction = &tx
+	}
+	s.recordSyncChange(change)
+}
+
+// SyncChangesSince returns the next page of changes after cursor ("" starts a full download)
+func (s *MongoDBService) SyncChangesSince(ctx context.Context, lineID, cursorText string, limit int) (*SyncPage, error) {
+	if limit <= 0 {
+		limit = DefaultSyncLimit
+	}
+	if limit > MaxSyncLimit {
+		limit = MaxSyncLimit
+	}
+	cursor, err := ParseSyncCursor(cursorText)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if cursor.Expired(now) {
+		cursor = SyncCursor{Bootstrap: true}
+	}
+	if cursor.Bootstrap {
+		return s.syncBootstrapPage(ctx, lineID, cursor, limit)
+	}
+
+	found, err := s.syncCollection.Find(ctx,
+		bson.M{"lineid": lineID, "seq": bson.M{"$gt": cursor.Seq}},
+		options.Find().SetSort(bson.M{"seq": 1}).SetLimit(int64(limit)+1),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var changes []SyncChange
+	if err := found.All(ctx, &changes); err != nil {
+		return nil, err
+	}
+
+	page := &SyncPage{Changes: []SyncChange{}}
+	last := cursor.Seq
+	for i, change := range changes {
+		if i == limit {
+			page.HasMore = true
+			break
+		}
+		if change.Op == SyncOpReset {
+			return s.syncBootstrapPage(ctx, lineID, SyncCursor{Bootstrap: true}, limit)
+		}
+		if change.Seq != last+1 && now.Sub(change.CreatedAt) < syncSettle {
+			page.HasMore = true // an earlier number is still being written
+			break
+		}
+		page.Changes = append(page.Changes, change)
+		last = change.Seq
+	}
+	page.Cursor = SyncCursor{Seq: last, Issued: now}.String()
+
+	for _, change := range page.Changes {
+		if change.Entity == SyncEntityTransaction {
+			page.Accounts, err = s.ProjectedBalances(ctx, lineID)
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	return page, nil
+}
+
+// syncBootstrapPage sends the full data set: budgets, cards and accounts on the first page, then
+// transactions newest first; the feed continues from the change number taken when the download started
+func (s *MongoDBService) syncBootstrapPage(ctx context.Context, lineID string, cursor SyncCursor, limit int) (*SyncPage, error) {
+	first := cursor.After.ID.IsZero()
+	page := &SyncPage{Changes: []SyncChange{}, Reset: first}
+	if first {
+		seq, err := s.currentSyncSeq(ctx, lineID)
+		if err != nil {
+			return nil, err
+		}
+		cursor = SyncCursor{Seq: seq, Bootstrap: true}
+
+		budgets, err := s.GetAllBudgets(ctx, lineID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range budgets {
+			page.Changes = append(page.Changes, SyncChange{Entity: SyncEntityBudget, Op: SyncOpUpsert, ID: budgets[i].Category, Budget: &budgets[i]})
+		}
+		cards, err := s.GetCardAccounts(ctx, lineID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range cards {
+			page.Changes = append(page.Changes, SyncChange{Entity: SyncEntityCard, Op: SyncOpUpsert, ID: cards[i].CardName, Card: &cards[i]})
+		}
+		if page.Accounts, err = s.ProjectedBalances(ctx, lineID); err != nil {
+			return nil, err
+		}
+	}
+
+	// One extra row tells whether another page follows
+	results, err := s.transactionsAfter(ctx, lineID, cursor.After, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	hasMore := len(results) > limit
+	if hasMore {
+		results = results[:limit]
+	}
+	for i := range results {
+		tx := results[i].Transaction
+		page.Changes = append(page.Changes, SyncChange{Entity: SyncEntityTransaction, Op: SyncOpUpsert, ID: tx.ID.Hex(), Date: results[i].Date, Transaction: &tx})
+	}
+
+	if hasMore {
+		last := results[len(results)-1]
+		created := last.Transaction.CreatedAt
+		if created.IsZero() {
+			created = syncKeyEpoch
+		}
+		cursor.After = SyncKey{Date: last.Date, CreatedAt: created, ID: last.Transaction.ID}
+		page.HasMore = true
+		page.Cursor = cursor.String()
+	} else {
+		// Changes made during the download replay on top; upserts/deletes are idempotent
+		page.Cursor = SyncCursor{Seq: cursor.Seq, Issued: time.Now()}.String()
+	}
+	return page, nil
+}
+
+// syncKeyEpoch stands in for a missing created_at in the download order
+var syncKeyEpoch = time.UnixMilli(0).UTC()
+
+// transactionsAfter returns up to limit of the user's transactions that come after key in the full
+// download order (all of them from the start when key is zero); receipt images are left out
+func (s *MongoDBService) transactionsAfter(ctx context.Context, lineID string, key SyncKey, limit int) ([]SearchResult, error) {
+	stages, err := s.transactionRowStages(ctx, lineID, "0000-01-01", "9999-12-31")
+	if err != nil {
+		return nil, err
+	}
+	// Entries saved before created_at existed sort as the oldest of their day
+	pipeline := append(mongo.Pipeline(stages), bson.D{{Key: "$addFields", Value: bson.M{
+		"sort_created": bson.M{"$ifNull": bson.A{"$tx.created_at", syncKeyEpoch}},
+	}}})
+	if !key.ID.IsZero() {
+		created := key.CreatedAt
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{"$or": bson.A{
+			bson.M{"date": bson.M{"$lt": key.Date}},
+			bson.M{"date": key.Date, "sort_created": bson.M{"$lt": created}},
+			bson.M{"date": key.Date, "sort_created": created, "tx._id": bson.M{"$lt": key.ID}},
+		}}}})
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$project", Value: bson.M{"tx.imagebase64": 0}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "date", Value: -1}, {Key: "sort_created", Value: -1}, {Key: "tx._id", Value: -1}}}},
+		bson.D{{Key: "$limit", Value: int64(limit)}},
+	)
+	return s.aggregateTransactionRows(ctx, pipeline)
+}
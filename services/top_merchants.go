@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TopMerchantLimit is how many merchants the ranking shows
+const TopMerchantLimit = 10
+
+// MerchantSpend is the spending at one merchant over a period
+type MerchantSpend struct {
+	Merchant string  `json:"merchant" bson:"_id"`
+	Amount   float64 `json:"amount" bson:"amount"`
+	Visits   int     `json:"visits" bson:"visits"`
+}
+
+// topMerchantMarkers mark a question about where the money goes ("ร้านไหนเปลืองสุด", "ร้านที่จ่ายเยอะสุด")
+var topMerchantMarkers = []string{"เปลือง", "เยอะสุด", "มากสุด", "อันดับ"}
+
+// MerchantQuery is a top-merchant ranking request over a date range
+type MerchantQuery struct {
+	From   string // YYYY-MM-DD
+	To     string // YYYY-MM-DD
+	Period string // "เดือนนี้", "สัปดาห์ที่แล้ว", ... for display
+}
+
+// ParseTopMerchantQuery parses "ร้านไหนเปลืองสุด" with an optional period ("ปีนี้", "เดือนที่แล้ว"),
+// this month by default
+func ParseTopMerchantQuery(text string, now time.Time) (*MerchantQuery, bool) {
+	if !strings.Contains(text, "ร้าน") {
+		return nil, false
+	}
+	found := false
+	for _, m := range topMerchantMarkers {
+		if strings.Contains(text, m) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, false
+	}
+
+	if r, ok := ParseThaiDate(text, now); ok {
+		return &MerchantQuery{From: r.FromString(), To: r.ToString(), Period: r.Expression}, true
+	}
+	from, to, label := StatsPeriodRange("month", now)
+	return &MerchantQuery{From: from.Format("2006-01-02"), To: to.Format("2006-01-02"), Period: label}, true
+}
+
+// RankMerchants merges rows of the same normalized merchant (entries saved before normalization are grouped
+// by their raw name) and returns the top limit by amount, most visits first on ties
+func RankMerchants(rows []MerchantSpend, limit int) []MerchantSpend {
+	merged := make(map[string]*MerchantSpend)
+	var order []string
+	for _, r := range rows {
+		name := NormalizeMerchant(r.Merchant)
+		if name == "" {
+			continue
+		}
+		m, ok := merged[name]
+		if !ok {
+			m = &MerchantSpend{Merchant: name}
+			merged[name] = m
+			order = append(order, name)
+		}
+		m.Amount += r.Amount
+		m.Visits += r.Visits
+	}
+
+	ranked := make([]MerchantSpend, 0, len(order))
+	for _, name := range order {
+		ranked = append(ranked, *merged[name])
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Amount != ranked[j].Amount {
+			return ranked[i].Amount > ranked[j].Amount
+		}
+		return ranked[i].Visits > ranked[j].Visits
+	})
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
+
+// GetTopMerchants ranks the merchants the user spent most at between two dates (inclusive)
+// Transfers and investments are not shopping and are left out; archived days are read through
+func (s *MongoDBService) GetTopMerchants(ctx context.Context, lineID, startDate, endDate string, limit int) ([]MerchantSpend, error) {
+	settings, err := s.GetUserSettings(ctx, lineID)
+	if err != nil {
+		return nil, err
+	}
+	rangeFilter := bson.M{
+		"lineid": lineID,
+		"date":   bson.M{"$gte": startDate, "$lte": endDate},
+	}
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: hotRecordFilter(rangeFilter, settings.ArchivedThrough)}}}
+	if settings.ArchivedThrough != "" && startDate <= settings.ArchivedThrough {
+		pipeline = append(pipeline, bson.D{{Key: "$unionWith", Value: bson.M{
+			"coll":     s.archiveCollection.Name(),
+			"pipeline": bson.A{bson.M{"$match": rangeFilter}},
+		}}})
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$unwind", Value: "$expenses"}},
+		bson.D{{Key: "$match", Value: bson.M{
+			"expenses.is_transfer": bson.M{"$ne": true},
+			"expenses.category":    bson.M{"$ne": InvestmentCategory},
+		}}},
+		// Merchant is the normalized name; entries saved before it fall back to the raw OCR name
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":    bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$expenses.merchant", ""}}, "$expenses.merchant", "$expenses.custname"}},
+			"amount": bson.M{"$sum": "$expenses.amount"},
+			"visits": bson.M{"$sum": 1},
+		}}},
+		bson.D{{Key: "$match", Value: bson.M{"_id": bson.M{"$nin": bson.A{nil, ""}}}}},
+	)
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []MerchantSpend
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	return RankMerchants(rows, limit), nil
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RecordTotals sums the embedded incomes/expenses of a daily record (what totalIncome/totalExpense should be)
+func RecordTotals(record DailyRecord) (income, expense float64) {
+	for _, tx := range record.Incomes {
+		income += tx.Amount
+	}
+	for _, tx := range record.Expenses {
+		expense += tx.Amount
+	}
+	return income, expense
+}
+
+// HasTotalsDrift reports whether stored totals no longer match the embedded transactions
+// (e.g. a $push succeeded but a later $inc or recalculation failed)
+func HasTotalsDrift(record DailyRecord) bool {
+	income, expense := RecordTotals(record)
+	return math.Abs(income-record.TotalIncome) > 0.005 || math.Abs(expense-record.TotalExpense) > 0.005
+}
+
+// RecalculateAllTotals rewrites totalIncome/totalExpense of every daily record of lineID (all users when empty)
+// from the embedded transactions; only drifted records are written. Returns records checked and fixed.
+func (s *MongoDBService) RecalculateAllTotals(ctx context.Context, lineID string) (checked, fixed int, err error) {
+	filter := bson.M{}
+	if lineID != "" {
+		filter["lineid"] = lineID
+	}
+	opts := options.Find().
+		SetProjection(bson.M{"incomes.imagebase64": 0, "expenses.imagebase64": 0}).
+		SetBatchSize(200)
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var record DailyRecord
+		if err := cursor.Decode(&record); err != nil {
+			continue
+		}
+		checked++
+		if !HasTotalsDrift(record) {
+			continue
+		}
+		if _, err := s.collection.UpdateOne(ctx, bson.M{"_id": record.ID}, recordTotalsUpdate()); err != nil {
+			return checked, fixed, err
+		}
+		fixed++
+	}
+	return checked, fixed, cursor.Err()
+}
+
+// recordTotalsUpdate is a pipeline update that sums the embedded arrays inside Mongo
+// (atomic per record, unlike reading the record and writing totals back)
+func recordTotalsUpdate() mongo.Pipeline {
+	return mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"totalIncome":  bson.M{"$sum": "$incomes.amount"},
+		"totalExpense": bson.M{"$sum": "$expenses.amount"},
+		"updatedAt":    time.Now(),
+	}}}}
+}
+
+// repairTotalsAsync fixes drifted records found while reading, without delaying the read
+func (s *MongoDBService) repairTotalsAsync(lineID string, dates []string) {
+	if len(dates) == 0 {
+		return
+	}
+	GoSafe("totals.repair", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		for _, date := range dates {
+			// Recalculate from the latest copy: the record may have changed since it was read
+			if err := s.recalculateTotals(ctx, lineID, date); err != nil {
+				log.Printf("Failed to repair totals %s %s: %v", lineID, date, err)
+				continue
+			}
+			log.Printf("Repaired totals drift %s %s", lineID, date)
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"log"
+)
+
+// Transaction change events passed to hooks
+const (
+	TransactionCreated = "transaction.created"
+	TransactionUpdated = "transaction.updated"
+	TransactionDeleted = "transaction.deleted"
+)
+
+// TransactionHook is called after a transaction is saved, updated or deleted
+// Hooks must not block (run slow work in a goroutine)
+type TransactionHook func(event, lineID, date string, tx Transaction)
+
+// AddTransactionHook registers a hook for transaction changes (outbound webhooks, sync)
+func (s *MongoDBService) AddTransactionHook(hook TransactionHook) {
+	s.txHooks = append(s.txHooks, hook)
+}
+
+// notifyTransaction calls all registered hooks
+func (s *MongoDBService) notifyTransaction(event, lineID, date string, tx Transaction) {
+	for _, hook := range s.txHooks {
+		hook(event, lineID, date, tx)
+	}
+}
+
+// notifyTransactionUpdated loads the updated transaction and notifies hooks
+func (s *MongoDBService) notifyTransactionUpdated(ctx context.Context, lineID, txID, date string) {
+	if len(s.txHooks) == 0 {
+		return
+	}
+	tx, err := s.GetTransactionOnDate(ctx, lineID, txID, date)
+	if err != nil {
+		log.Printf("Failed to load updated transaction for hooks: %v", err)
+		return
+	}
+	s.notifyTransaction(TransactionUpdated, lineID, date, *tx)
+}
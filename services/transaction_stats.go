@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TransactionStats is counts and amounts of income/expense in a period (transfers and investments excluded)
+type TransactionStats struct {
+	Period        string  `json:"period"` // "day", "week", "month", "year"
+	Label         string  `json:"label"`
+	From          string  `json:"from"`
+	To            string  `json:"to"`
+	Count         int     `json:"count"`
+	IncomeCount   int     `json:"income_count"`
+	ExpenseCount  int     `json:"expense_count"`
+	TotalIncome   float64 `json:"total_income"`
+	TotalExpense  float64 `json:"total_expense"`
+	AvgExpense    float64 `json:"avg_expense"` // per expense transaction
+	MinExpense    float64 `json:"min_expense"`
+	MaxExpense    float64 `json:"max_expense"`
+	ActiveDays    int     `json:"active_days"`           // days with at least one transaction
+	BusiestDay    string  `json:"busiest_day,omitempty"` // date with most transactions (higher expense breaks ties)
+	BusiestCount  int     `json:"busiest_count"`         // transactions on BusiestDay
+	Users         int     `json:"users,omitempty"`       // distinct users (all-user stats only)
+	DailyAvgSpent float64 `json:"daily_avg_spent"`       // expense per calendar day of the period to date
+}
+
+// StatsPeriodRange returns dates and Thai label of a stats period ending today
+// period: "day", "week" (Monday - today), "year" (1 Jan - today) or "month" (default, 1st - today)
+func StatsPeriodRange(period string, now time.Time) (from, to time.Time, label string) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch period {
+	case "day":
+		return today, today, "วันนี้"
+	case "week":
+		from, _, _, _ = PeriodRanges("week", now)
+		return from, today, "สัปดาห์นี้"
+	case "year":
+		return time.Date(today.Year(), 1, 1, 0, 0, 0, 0, today.Location()), today, "ปีนี้"
+	}
+	from, _, _, _ = PeriodRanges("month", now)
+	return from, today, "เดือนนี้"
+}
+
+// GetTransactionStats computes TransactionStats of one user in a single aggregation
+// Empty lineID gives stats over all users (admin usage report)
+func (s *MongoDBService) GetTransactionStats(ctx context.Context, lineID, period string) (*TransactionStats, error) {
+	switch period {
+	case "day", "week", "year":
+	default:
+		period = "month"
+	}
+	from, to, label := StatsPeriodRange(period, time.Now())
+	stats := &TransactionStats{
+		Period: period,
+		Label:  label,
+		From:   from.Format("2006-01-02"),
+		To:     to.Format("2006-01-02"),
+	}
+
+	match := bson.M{"date": bson.M{"$gte": stats.From, "$lte": stats.To}}
+	if lineID != "" {
+		match["lineid"] = lineID
+	}
+	// Only amount/category/kind of each entry leave the daily record (no receipt images)
+	entries := func(field string, income bool) bson.M {
+		return bson.M{"$map": bson.M{
+			"input": bson.M{"$ifNull": bson.A{"$" + field, bson.A{}}},
+			"as":    "t",
+			"in":    bson.M{"amount": "$$t.amount", "category": "$$t.category", "is_transfer": "$$t.is_transfer", "income": income},
+		}}
+	}
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$project": bson.M{"lineid": 1, "date": 1, "tx": bson.M{"$concatArrays": bson.A{entries("incomes", true), entries("expenses", false)}}}},
+		{"$unwind": "$tx"},
+		{"$match": bson.M{"tx.is_transfer": bson.M{"$ne": true}, "tx.category": bson.M{"$ne": InvestmentCategory}}},
+		{"$facet": bson.M{
+			"totals": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":   "$tx.income",
+					"count": bson.M{"$sum": 1},
+					"sum":   bson.M{"$sum": "$tx.amount"},
+					"avg":   bson.M{"$avg": "$tx.amount"},
+					"min":   bson.M{"$min": "$tx.amount"},
+					"max":   bson.M{"$max": "$tx.amount"},
+				}},
+			},
+			"days": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":     "$date",
+					"count":   bson.M{"$sum": 1},
+					"expense": bson.M{"$sum": bson.M{"$cond": bson.A{"$tx.income", 0, "$tx.amount"}}},
+				}},
+				bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "expense", Value: -1}, {Key: "_id", Value: -1}}},
+				bson.M{"$group": bson.M{"_id": nil, "active": bson.M{"$sum": 1}, "busiest": bson.M{"$first": "$$ROOT"}}},
+			},
+			"users": bson.A{
+				bson.M{"$group": bson.M{"_id": "$lineid"}},
+				bson.M{"$count": "users"},
+			},
+		}},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var out []struct {
+		Totals []struct {
+			Income bool    `bson:"_id"`
+			Count  int     `bson:"count"`
+			Sum    float64 `bson:"sum"`
+			Avg    float64 `bson:"avg"`
+			Min    float64 `bson:"min"`
+			Max    float64 `bson:"max"`
+		} `bson:"totals"`
+		Days []struct {
+			Active  int `bson:"active"`
+			Busiest struct {
+				Date  string `bson:"_id"`
+				Count int    `bson:"count"`
+			} `bson:"busiest"`
+		} `bson:"days"`
+		Users []struct {
+			Users int `bson:"users"`
+		} `bson:"users"`
+	}
+	if err := cursor.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return stats, nil
+	}
+
+	for _, t := range out[0].Totals {
+		if t.Income {
+			stats.IncomeCount = t.Count
+			stats.TotalIncome = t.Sum
+			continue
+		}
+		stats.ExpenseCount = t.Count
+		stats.TotalExpense = t.Sum
+		stats.AvgExpense = t.Avg
+		stats.MinExpense = t.Min
+		stats.MaxExpense = t.Max
+	}
+	stats.Count = stats.IncomeCount + stats.ExpenseCount
+	if len(out[0].Days) > 0 {
+		stats.ActiveDays = out[0].Days[0].Active
+		stats.BusiestDay = out[0].Days[0].Busiest.Date
+		stats.BusiestCount = out[0].Days[0].Busiest.Count
+	}
+	if len(out[0].Users) > 0 {
+		stats.Users = out[0].Users[0].Users
+	}
+	stats.DailyAvgSpent = stats.TotalExpense / float64(int(to.Sub(from).Hours()/24)+1)
+	return stats, nil
+}
+
+// ToAIText returns compact stats for AI context
+// Format: "สถิติเดือนนี้|รายการ:42(รับ3/จ่าย39)|จ่ายเฉลี่ย:180|ต่ำสุด:15|สูงสุด:2500|ต่อวัน:450|วันที่ใช้บ่อย:2025-01-10(8)"
+func (st *TransactionStats) ToAIText() string {
+	if st == nil || st.Count == 0 {
+		return ""
+	}
+	text := fmt.Sprintf("สถิติ%s|รายการ:%d(รับ%d/จ่าย%d)", st.Label, st.Count, st.IncomeCount, st.ExpenseCount)
+	if st.ExpenseCount > 0 {
+		text += fmt.Sprintf("|จ่ายเฉลี่ย:%.0f|ต่ำสุด:%.0f|สูงสุด:%.0f|ต่อวัน:%.0f", st.AvgExpense, st.MinExpense, st.MaxExpense, st.DailyAvgSpent)
+	}
+	if st.BusiestDay != "" {
+		text += fmt.Sprintf("|วันที่ใช้บ่อย:%s(%d)", st.BusiestDay, st.BusiestCount)
+	}
+	return text
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrNotTransferPair means the two transactions no longer look like one transfer (edited or deleted)
+var ErrNotTransferPair = errors.New("not a transfer pair")
+
+// isTransferPair reports whether expense and income look like the two sides of one transfer:
+// same amount, different accounts, at least one a bank account, neither already a transfer
+func isTransferPair(expense, income Transaction) bool {
+	if expense.Type != -1 || income.Type != 1 {
+		return false
+	}
+	if expense.TransferID != "" || income.TransferID != "" || expense.IsAssetMove() || income.IsAssetMove() {
+		return false
+	}
+	if expense.Amount <= 0 || math.Abs(expense.Amount-income.Amount) > 0.005 {
+		return false
+	}
+	if expense.UseType != 2 && income.UseType != 2 {
+		return false // cash/card spending that happens to match an income is not a transfer
+	}
+	return expense.UseType != income.UseType || expense.BankName != income.BankName || expense.CreditCardName != income.CreditCardName
+}
+
+// FindTransferPair returns the most recent opposite-side transaction in candidates that pairs with tx
+// as one account-to-account transfer ("โอนออกกสิกร 5000" + "เงินเข้า SCB 5000"), or nil
+func FindTransferPair(tx Transaction, candidates []Transaction) *Transaction {
+	var best *Transaction
+	for i := range candidates {
+		c := candidates[i]
+		paired := isTransferPair(tx, c)
+		if tx.Type == 1 {
+			paired = isTransferPair(c, tx)
+		}
+		if paired && (best == nil || c.CreatedAt.After(best.CreatedAt)) {
+			best = &candidates[i]
+		}
+	}
+	return best
+}
+
+// FindTransferPairOnDate looks for the other side of a likely transfer recorded on the same day as txID
+func (s *MongoDBService) FindTransferPairOnDate(ctx context.Context, lineID, txID, date string) (*Transaction, error) {
+	objectID, err := primitive.ObjectIDFromHex(txID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction ID: %w", err)
+	}
+	var record DailyRecord
+	opts := options.FindOne().SetProjection(bson.M{"incomes.imagebase64": 0, "expenses.imagebase64": 0})
+	if err := s.collection.FindOne(ctx, bson.M{"lineid": lineID, "date": date}, opts).Decode(&record); err != nil {
+		return nil, err
+	}
+	for _, tx := range record.Expenses {
+		if tx.ID == objectID {
+			return FindTransferPair(tx, record.Incomes), nil
+		}
+	}
+	for _, tx := range record.Incomes {
+		if tx.ID == objectID {
+			return FindTransferPair(tx, record.Expenses), nil
+		}
+	}
+	return nil, nil
+}
+
+// MergeTransferPair turns an expense and an income of the same day into one transfer record:
+// both keep their amounts and accounts but become category "โอนเงิน", flagged and linked by transfer_id,
+// so balances stay right and neither counts as income/expense any more
+func (s *MongoDBService) MergeTransferPair(ctx context.Context, lineID, date, txID, pairID string) (*TransferRecord, error) {
+	if err := s.checkPeriodOpen(ctx, lineID, date); err != nil {
+		return nil, err
+	}
+	var record DailyRecord
+	opts := options.FindOne().SetProjection(bson.M{"incomes.imagebase64": 0, "expenses.imagebase64": 0})
+	if err := s.collection.FindOne(ctx, bson.M{"lineid": lineID, "date": date}, opts).Decode(&record); err != nil {
+		return nil, err
+	}
+
+	var expense, income *Transaction
+	for i := range record.Expenses {
+		if id := record.Expenses[i].ID.Hex(); id == txID || id == pairID {
+			expense = &record.Expenses[i]
+		}
+	}
+	for i := range record.Incomes {
+		if id := record.Incomes[i].ID.Hex(); id == txID || id == pairID {
+			income = &record.Incomes[i]
+		}
+	}
+	if expense == nil || income == nil || !isTransferPair(*expense, *income) {
+		return nil, ErrNotTransferPair
+	}
+
+	description := expense.Description
+	if description == "" {
+		description = "โอนเงิน"
+	}
+	transfer := TransferRecord{
+		ID:          primitive.NewObjectID(),
+		LineID:      lineID,
+		Date:        date,
+		Description: description,
+		From:        []TransferEntryDB{{Amount: expense.Amount, UseType: expense.UseType, BankName: expense.BankName, CreditCardName: expense.CreditCardName}},
+		To:          []TransferEntryDB{{Amount: income.Amount, UseType: income.UseType, BankName: income.BankName, CreditCardName: income.CreditCardName}},
+		TotalAmount: expense.Amount,
+		CreatedAt:   time.Now(),
+	}
+	if _, err := s.transferCollection.InsertOne(ctx, transfer); err != nil {
+		return nil, fmt.Errorf("failed to save transfer: %w", err)
+	}
+
+	transferID := transfer.ID.Hex()
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"lineid": lineID, "date": date},
+		bson.M{"$set": bson.M{
+			"expenses.$[e].category":    "โอนเงิน",
+			"expenses.$[e].transfer_id": transferID,
+			"expenses.$[e].is_transfer": true,
+			"incomes.$[i].category":     "โอนเงิน",
+			"incomes.$[i].transfer_id":  transferID,
+			"incomes.$[i].is_transfer":  true,
+			"updatedAt":                 time.Now(),
+		}},
+		options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+			bson.M{"e._id": expense.ID},
+			bson.M{"i._id": income.ID},
+		}}),
+	)
+	if err != nil {
+		s.transferCollection.DeleteOne(ctx, bson.M{"_id": transfer.ID})
+		return nil, fmt.Errorf("failed to link transfer: %w", err)
+	}
+
+	s.notifyTransactionUpdated(ctx, lineID, expense.ID.Hex(), date)
+	s.notifyTransactionUpdated(ctx, lineID, income.ID.Hex(), date)
+	return &transfer, nil
+}
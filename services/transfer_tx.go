@@ -0,0 +1,383 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SaveTransfer saves a transfer and its transactions all-or-nothing
+// Returns transfer ID and array of transaction IDs
+//
+// Every leg lands in today's daily record with one update (atomic on its own); the transfer record and
+// that update run in one multi-document transaction. Standalone servers without transactions write the
+// legs first and the record second, undoing the legs if the record fails, so a crash in between leaves
+// legs without a record, which CheckTransferIntegrity reports and RepairTransfers rebuilds.
+func (s *MongoDBService) SaveTransfer(ctx context.Context, lineID string, transfer *TransferData) (string, []string, error) {
+	now := time.Now()
+	today := now.Format("2006-01-02")
+
+	// Calculate total amount from "from" entries
+	var totalAmount float64
+	for _, entry := range transfer.From {
+		totalAmount += entry.Amount
+	}
+
+	// Convert to DB format
+	toDB := func(entries []TransferEntry) []TransferEntryDB {
+		out := make([]TransferEntryDB, len(entries))
+		for i, e := range entries {
+			out[i] = TransferEntryDB{Amount: e.Amount, UseType: e.UseType, BankName: e.BankName, CreditCardName: e.CreditCardName}
+		}
+		return out
+	}
+	record := TransferRecord{
+		ID:          primitive.NewObjectID(),
+		LineID:      lineID,
+		Date:        today,
+		Description: transfer.Description,
+		From:        toDB(transfer.From),
+		To:          toDB(transfer.To),
+		TotalAmount: totalAmount,
+		CreatedAt:   now,
+	}
+	expenses, incomes := transferLegs(record)
+
+	if s.noTransactions.Load() {
+		err := s.saveTransferCompensating(ctx, record, expenses, incomes)
+		if err != nil {
+			return "", nil, err
+		}
+	} else if err := s.saveTransferInTransaction(ctx, record, expenses, incomes); err != nil {
+		if !isTransactionUnsupported(err) {
+			return "", nil, err
+		}
+		log.Println("MongoDB transactions not supported (standalone server), transfers use compensating writes")
+		s.noTransactions.Store(true)
+		if err := s.saveTransferCompensating(ctx, record, expenses, incomes); err != nil {
+			return "", nil, err
+		}
+	}
+
+	txIDs := make([]string, 0, len(expenses)+len(incomes))
+	for _, tx := range append(expenses, incomes...) {
+		txIDs = append(txIDs, tx.ID.Hex())
+		s.notifyTransaction(TransactionCreated, lineID, today, tx)
+	}
+	return record.ID.Hex(), txIDs, nil
+}
+
+// transferLegs builds the expense (money out) and income (money in) transactions of a transfer record
+func transferLegs(record TransferRecord) (expenses, incomes []Transaction) {
+	leg := func(e TransferEntryDB, txType int) Transaction {
+		return Transaction{
+			ID:             primitive.NewObjectID(),
+			Type:           txType,
+			Amount:         e.Amount,
+			Category:       "โอนเงิน",
+			Description:    record.Description,
+			UseType:        e.UseType,
+			BankName:       e.BankName,
+			CreditCardName: e.CreditCardName,
+			TransferID:     record.ID.Hex(),
+			IsTransfer:     true,
+			CreatedAt:      record.CreatedAt,
+		}
+	}
+	for _, e := range record.From {
+		expenses = append(expenses, leg(e, -1))
+	}
+	for _, e := range record.To {
+		incomes = append(incomes, leg(e, 1))
+	}
+	return expenses, incomes
+}
+
+// pushTransferLegs adds all legs to the daily record in one upsert
+func (s *MongoDBService) pushTransferLegs(ctx context.Context, record TransferRecord, expenses, incomes []Transaction) error {
+	var totalIncome, totalExpense float64
+	for _, tx := range incomes {
+		totalIncome += tx.Amount
+	}
+	for _, tx := range expenses {
+		totalExpense += tx.Amount
+	}
+	now := time.Now()
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"lineid": record.LineID, "date": record.Date},
+		bson.M{
+			"$push": bson.M{
+				"incomes":  bson.M{"$each": append([]Transaction{}, incomes...)},
+				"expenses": bson.M{"$each": append([]Transaction{}, expenses...)},
+			},
+			"$inc":         bson.M{"totalIncome": totalIncome, "totalExpense": totalExpense},
+			"$set":         bson.M{"updatedAt": now},
+			"$setOnInsert": bson.M{"time": now.Format("15:04"), "createdAt": now},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save transfer transactions: %w", err)
+	}
+	return nil
+}
+
+// saveTransferInTransaction writes the transfer record and its legs in one multi-document transaction
+func (s *MongoDBService) saveTransferInTransaction(ctx context.Context, record TransferRecord, expenses, incomes []Transaction) error {
+	session, err := s.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		if _, err := s.transferCollection.InsertOne(sc, record); err != nil {
+			return nil, fmt.Errorf("failed to save transfer: %w", err)
+		}
+		return nil, s.pushTransferLegs(sc, record, expenses, incomes)
+	})
+	return err
+}
+
+// saveTransferCompensating writes legs then the record, pulling the legs back if the record fails
+func (s *MongoDBService) saveTransferCompensating(ctx context.Context, record TransferRecord, expenses, incomes []Transaction) error {
+	if err := s.pushTransferLegs(ctx, record, expenses, incomes); err != nil {
+		return err
+	}
+	if _, err := s.transferCollection.InsertOne(ctx, record); err != nil {
+		if undoErr := s.pullTransferLegs(ctx, record.LineID, record.Date, record.ID.Hex()); undoErr != nil {
+			log.Printf("Failed to undo transfer legs %s: %v", record.ID.Hex(), undoErr)
+		}
+		return fmt.Errorf("failed to save transfer: %w", err)
+	}
+	return nil
+}
+
+// TransferLegsOf returns the transactions of a daily record that belong to transferID
+func TransferLegsOf(record DailyRecord, transferID string) []Transaction {
+	var legs []Transaction
+	for _, tx := range append(append([]Transaction{}, record.Expenses...), record.Incomes...) {
+		if tx.TransferID == transferID {
+			legs = append(legs, tx)
+		}
+	}
+	return legs
+}
+
+// pullTransferLegs removes every transaction of a transfer from a daily record and fixes its totals
+func (s *MongoDBService) pullTransferLegs(ctx context.Context, lineID, date, transferID string) error {
+	filter := bson.M{"lineid": lineID, "date": date}
+	_, err := s.collection.UpdateOne(ctx, filter, bson.M{"$pull": bson.M{
+		"incomes":  bson.M{"transfer_id": transferID},
+		"expenses": bson.M{"transfer_id": transferID},
+	}})
+	if err != nil {
+		return err
+	}
+	_, err = s.collection.UpdateOne(ctx, filter, recordTotalsUpdate())
+	return err
+}
+
+// isTransactionUnsupported reports whether the server can't run multi-document transactions
+// (IllegalOperation: "Transaction numbers are only allowed on a replica set member or mongos")
+func isTransactionUnsupported(err error) bool {
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == 20 {
+		return true
+	}
+	return err != nil && strings.Contains(err.Error(), "Transaction numbers are only allowed")
+}
+
+// TransferIssue is a transfer whose record and linked transactions don't agree
+type TransferIssue struct {
+	TransferID string `json:"transfer_id"`
+	LineID     string `json:"lineid"`
+	Date       string `json:"date"`
+	Problem    string `json:"problem"`
+}
+
+// Transfer integrity problems
+const (
+	TransferMissingRecord = "missing transfer record" // legs without a record (crash between writes)
+	TransferMissingLegs   = "missing transactions"    // record without all of its legs
+	TransferLegMismatch   = "transactions don't match record"
+)
+
+// VerifyTransferLinks checks that exactly the expected transactions carry the transfer's transfer_id:
+// one expense per "from" entry and one income per "to" entry with the same amounts, all flagged as transfers
+// Returns "" when they match, otherwise one of the Transfer* problems
+func VerifyTransferLinks(record TransferRecord, linked []Transaction) string {
+	var outs, ins []float64
+	for _, tx := range linked {
+		if !tx.IsTransfer {
+			return TransferLegMismatch
+		}
+		if tx.Type == 1 {
+			ins = append(ins, tx.Amount)
+		} else {
+			outs = append(outs, tx.Amount)
+		}
+	}
+	if len(linked) < len(record.From)+len(record.To) {
+		return TransferMissingLegs
+	}
+	amounts := func(entries []TransferEntryDB) []float64 {
+		out := make([]float64, len(entries))
+		for i, e := range entries {
+			out[i] = e.Amount
+		}
+		return out
+	}
+	if !sameAmounts(outs, amounts(record.From)) || !sameAmounts(ins, amounts(record.To)) {
+		return TransferLegMismatch
+	}
+	return ""
+}
+
+// sameAmounts compares two lists of amounts ignoring order (to the satang)
+func sameAmounts(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = append([]float64(nil), a...), append([]float64(nil), b...)
+	sort.Float64s(a)
+	sort.Float64s(b)
+	for i := range a {
+		if math.Abs(a[i]-b[i]) >= 0.005 {
+			return false
+		}
+	}
+	return true
+}
+
+// linkedTransfers loads transactions that carry a transfer_id, grouped by it (empty lineID = all users)
+func (s *MongoDBService) linkedTransfers(ctx context.Context, lineID string) (map[string][]Transaction, map[string]string, error) {
+	match := bson.M{}
+	if lineID != "" {
+		match["lineid"] = lineID
+	}
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$project": bson.M{"lineid": 1, "date": 1, "tx": bson.M{"$concatArrays": bson.A{
+			bson.M{"$ifNull": bson.A{"$incomes", bson.A{}}},
+			bson.M{"$ifNull": bson.A{"$expenses", bson.A{}}},
+		}}}},
+		{"$unwind": "$tx"},
+		{"$match": bson.M{"tx.transfer_id": bson.M{"$nin": bson.A{nil, ""}}}},
+		{"$project": bson.M{"lineid": 1, "date": 1, "tx._id": 1, "tx.type": 1, "tx.amount": 1, "tx.transfer_id": 1, "tx.is_transfer": 1,
+			"tx.usetype": 1, "tx.bankname": 1, "tx.creditcardname": 1, "tx.description": 1, "tx.created_at": 1}},
+	}
+	cursor, err := s.collection.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	linked := map[string][]Transaction{}
+	where := map[string]string{} // transfer_id -> "lineid|date" of its legs
+	for cursor.Next(ctx) {
+		var row struct {
+			LineID string      `bson:"lineid"`
+			Date   string      `bson:"date"`
+			Tx     Transaction `bson:"tx"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			continue
+		}
+		linked[row.Tx.TransferID] = append(linked[row.Tx.TransferID], row.Tx)
+		where[row.Tx.TransferID] = row.LineID + "|" + row.Date
+	}
+	return linked, where, cursor.Err()
+}
+
+// CheckTransferIntegrity compares every transfer record with the transactions linked to it by transfer_id
+// Empty lineID checks all users
+func (s *MongoDBService) CheckTransferIntegrity(ctx context.Context, lineID string) ([]TransferIssue, error) {
+	linked, where, err := s.linkedTransfers(ctx, lineID)
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{}
+	if lineID != "" {
+		filter["lineid"] = lineID
+	}
+	cursor, err := s.transferCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var issues []TransferIssue
+	seen := map[string]bool{}
+	for cursor.Next(ctx) {
+		var record TransferRecord
+		if err := cursor.Decode(&record); err != nil {
+			continue
+		}
+		id := record.ID.Hex()
+		seen[id] = true
+		if problem := VerifyTransferLinks(record, linked[id]); problem != "" {
+			issues = append(issues, TransferIssue{TransferID: id, LineID: record.LineID, Date: record.Date, Problem: problem})
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	for id := range linked {
+		if !seen[id] {
+			owner, date, _ := strings.Cut(where[id], "|")
+			issues = append(issues, TransferIssue{TransferID: id, LineID: owner, Date: date, Problem: TransferMissingRecord})
+		}
+	}
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Date < issues[j].Date })
+	return issues, nil
+}
+
+// RepairTransfers rebuilds the missing records of transfers whose legs were saved (the compensating
+// fallback's crash window); other problems are only reported
+func (s *MongoDBService) RepairTransfers(ctx context.Context, issues []TransferIssue) (int, error) {
+	repaired := 0
+	legsByUser := map[string]map[string][]Transaction{}
+	for _, issue := range issues {
+		if issue.Problem != TransferMissingRecord {
+			continue
+		}
+		id, err := primitive.ObjectIDFromHex(issue.TransferID)
+		if err != nil {
+			continue
+		}
+		linked, ok := legsByUser[issue.LineID]
+		if !ok {
+			if linked, _, err = s.linkedTransfers(ctx, issue.LineID); err != nil {
+				return repaired, err
+			}
+			legsByUser[issue.LineID] = linked
+		}
+		record := TransferRecord{ID: id, LineID: issue.LineID, Date: issue.Date}
+		for _, tx := range linked[issue.TransferID] {
+			entry := TransferEntryDB{Amount: tx.Amount, UseType: tx.UseType, BankName: tx.BankName, CreditCardName: tx.CreditCardName}
+			if tx.Type == 1 {
+				record.To = append(record.To, entry)
+				continue
+			}
+			record.From = append(record.From, entry)
+			record.TotalAmount += tx.Amount
+			record.Description, record.CreatedAt = tx.Description, tx.CreatedAt
+		}
+		if _, err := s.transferCollection.InsertOne(ctx, record); err != nil && !mongo.IsDuplicateKeyError(err) {
+			return repaired, fmt.Errorf("failed to rebuild transfer %s: %w", issue.TransferID, err)
+		}
+		repaired++
+	}
+	return repaired, nil
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Trip is the travel mode of a user: expenses typed during the trip are in Currency and tagged to it
+type Trip struct {
+	Destination string  `bson:"destination" json:"destination"`
+	Currency    string  `bson:"currency" json:"currency"`
+	Rate        float64 `bson:"rate" json:"rate"` // THB per 1 unit, 0 = not known yet (no conversion)
+	StartDate   string  `bson:"start_date" json:"start_date"`
+	EndDate     string  `bson:"end_date" json:"end_date"`
+}
+
+// Tag identifies the trip on its transactions ("ญี่ปุ่น 2026-10-16"), unique per trip to the same place
+func (t *Trip) Tag() string {
+	return t.Destination + " " + t.StartDate
+}
+
+// Covers reports whether date (YYYY-MM-DD) is within the trip
+func (t *Trip) Covers(date string) bool {
+	return t != nil && date >= t.StartDate && date <= t.EndDate
+}
+
+// NewTrip starts a trip of days days today
+func NewTrip(destination, currency string, days int, rate float64, now time.Time) *Trip {
+	return &Trip{
+		Destination: destination,
+		Currency:    currency,
+		Rate:        rate,
+		StartDate:   now.Format("2006-01-02"),
+		EndDate:     now.AddDate(0, 0, days-1).Format("2006-01-02"),
+	}
+}
+
+// travelDestinations map places users type to a display name and currency
+var travelDestinations = []struct {
+	names       []string
+	destination string
+	currency    string
+}{
+	{[]string{"ญี่ปุ่น", "japan", "โตเกียว", "โอซาก้า", "เกียวโต", "ฮอกไกโด", "ซัปโปโร", "ฟุกุโอกะ"}, "ญี่ปุ่น", "JPY"},
+	{[]string{"เกาหลี", "korea", "โซล", "ปูซาน", "เชจู"}, "เกาหลี", "KRW"},
+	{[]string{"จีน", "china", "ปักกิ่ง", "เซี่ยงไฮ้", "เฉิงตู", "คุนหมิง"}, "จีน", "CNY"},
+	{[]string{"ไต้หวัน", "taiwan", "ไทเป"}, "ไต้หวัน", "TWD"},
+	{[]string{"ฮ่องกง", "hongkong", "hong kong"}, "ฮ่องกง", "HKD"},
+	{[]string{"สิงคโปร์", "singapore"}, "สิงคโปร์", "SGD"},
+	{[]string{"มาเลเซีย", "malaysia", "กัวลาลัมเปอร์"}, "มาเลเซีย", "MYR"},
+	{[]string{"เวียดนาม", "vietnam", "ฮานอย", "โฮจิมินห์", "ดานัง"}, "เวียดนาม", "VND"},
+	{[]string{"ลาว", "laos", "หลวงพระบาง", "เวียงจันทน์"}, "ลาว", "LAK"},
+	{[]string{"บาหลี", "bali", "อินโดนีเซีย", "indonesia"}, "อินโดนีเซีย", "IDR"},
+	{[]string{"ฟิลิปปินส์", "philippines"}, "ฟิลิปปินส์", "PHP"},
+	{[]string{"อินเดีย", "india"}, "อินเดีย", "INR"},
+	{[]string{"อเมริกา", "usa", "us", "สหรัฐ", "นิวยอร์ก"}, "อเมริกา", "USD"},
+	{[]string{"อังกฤษ", "uk", "ลอนดอน", "london"}, "อังกฤษ", "GBP"},
+	{[]string{"ยุโรป", "europe", "ฝรั่งเศส", "ปารีส", "เยอรมัน", "อิตาลี", "สเปน", "เนเธอร์แลนด์"}, "ยุโรป", "EUR"},
+	{[]string{"สวิส", "switzerland"}, "สวิตเซอร์แลนด์", "CHF"},
+	{[]string{"ออสเตรเลีย", "australia"}, "ออสเตรเลีย", "AUD"},
+	{[]string{"นิวซีแลนด์", "newzealand", "new zealand"}, "นิวซีแลนด์", "NZD"},
+	{[]string{"ดูไบ", "dubai"}, "ดูไบ", "AED"},
+}
+
+// currencySymbols are shown before foreign amounts ("¥1,200"); other currencies show their code
+var currencySymbols = map[string]string{
+	"JPY": "¥", "CNY": "¥", "KRW": "₩", "USD": "$", "EUR": "€", "GBP": "£", "VND": "₫", "PHP": "₱", "INR": "₹",
+}
+
+// CurrencySymbol returns the symbol of a currency code, or the code followed by a space
+func CurrencySymbol(currency string) string {
+	if s, ok := currencySymbols[currency]; ok {
+		return s
+	}
+	return currency + " "
+}
+
+// Travel mode commands
+var (
+	travelStartPattern = regexp.MustCompile(`(?i)^(?:ไป|เที่ยว|บินไป|ทริป|โหมดเที่ยว)\s*(.+?)\s*(\d{1,3})\s*วัน$`)
+	travelRatePattern  = regexp.MustCompile(`^เรท\s*(?:1\s*\S+\s*=\s*)?(\d+(?:\.\d+)?)\s*(?:บาท)?$`)
+)
+
+// TravelCommand is a parsed travel mode command
+type TravelCommand struct {
+	Action      string // "start", "stop", "rate", "summary"
+	Destination string
+	Currency    string
+	Days        int
+	Rate        float64
+}
+
+// ParseTravelCommand parses "ไปญี่ปุ่น 10 วัน", "เรท 0.23", "สรุปทริป" and "จบทริป" (no AI)
+func ParseTravelCommand(text string) (TravelCommand, bool) {
+	text = strings.TrimSpace(text)
+	switch text {
+	case "จบทริป", "ปิดโหมดเที่ยว", "กลับไทยแล้ว", "กลับถึงไทยแล้ว":
+		return TravelCommand{Action: "stop"}, true
+	case "สรุปทริป", "ดูทริป", "ทริป":
+		return TravelCommand{Action: "summary"}, true
+	}
+	if m := travelRatePattern.FindStringSubmatch(text); m != nil {
+		rate, err := strconv.ParseFloat(m[1], 64)
+		if err == nil && rate > 0 {
+			return TravelCommand{Action: "rate", Rate: rate}, true
+		}
+	}
+	if m := travelStartPattern.FindStringSubmatch(text); m != nil {
+		days, _ := strconv.Atoi(m[2])
+		place := strings.ToLower(strings.TrimSpace(m[1]))
+		for _, d := range travelDestinations {
+			for _, name := range d.names {
+				if place == name && days > 0 && days <= 365 {
+					return TravelCommand{Action: "start", Destination: d.destination, Currency: d.currency, Days: days}, true
+				}
+			}
+		}
+	}
+	return TravelCommand{}, false
+}
+
+// MentionsBaht reports whether the user said the amount is in baht ("ข้าว 200 บาท")
+func MentionsBaht(text string) bool {
+	lower := strings.ToLower(text)
+	return strings.Contains(lower, "บาท") || strings.Contains(lower, "thb") || strings.Contains(lower, "฿")
+}
+
+// ApplyTripCurrency tags expenses dated within the trip and, unless they were paid in baht, treats their
+// amounts as the trip currency: the foreign amount is kept and Amount (VAT, service charge) becomes THB
+// Returns how many entries were converted
+func ApplyTripCurrency(trip *Trip, txs []TransactionData, paidInBaht bool) int {
+	if trip == nil {
+		return 0
+	}
+	converted := 0
+	for i := range txs {
+		tx := &txs[i]
+		if tx.Type == "income" || tx.Type == "investment" || !trip.Covers(tx.Date) {
+			continue
+		}
+		tx.Trip = trip.Tag()
+		if paidInBaht || trip.Rate <= 0 || tx.ForeignAmount > 0 {
+			continue
+		}
+		tx.Currency, tx.FXRate, tx.ForeignAmount = trip.Currency, trip.Rate, tx.Amount
+		tx.Amount = roundBaht(tx.Amount * trip.Rate)
+		tx.VATAmount = roundBaht(tx.VATAmount * trip.Rate)
+		tx.ServiceCharge = roundBaht(tx.ServiceCharge * trip.Rate)
+		converted++
+	}
+	return converted
+}
+
+// roundBaht rounds to satang
+func roundBaht(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// TripDay is one day of trip spending in both currencies
+type TripDay struct {
+	Date    string  `json:"date"`
+	Foreign float64 `json:"foreign"` // entries recorded in the trip currency
+	THB     float64 `json:"thb"`     // all trip entries in baht (converted + paid in baht)
+	Count   int     `json:"count"`
+}
+
+// TripSummary totals a trip's expenses per day
+type TripSummary struct {
+	Trip    Trip      `json:"trip"`
+	Days    []TripDay `json:"days"` // oldest first
+	Foreign float64   `json:"foreign"`
+	THB     float64   `json:"thb"`
+}
+
+// SummarizeTrip totals expenses tagged to trip in records per day
+func SummarizeTrip(trip *Trip, records []DailyRecord) *TripSummary {
+	summary := &TripSummary{Trip: *trip}
+	tag := trip.Tag()
+	for _, record := range records {
+		day := TripDay{Date: record.Date}
+		for _, tx := range record.Expenses {
+			if tx.Trip != tag || tx.IsAssetMove() {
+				continue
+			}
+			if tx.Currency == trip.Currency {
+				day.Foreign += tx.ForeignAmount
+			}
+			day.THB += tx.Amount
+			day.Count++
+		}
+		if day.Count == 0 {
+			continue
+		}
+		summary.Days = append(summary.Days, day)
+		summary.Foreign += day.Foreign
+		summary.THB += day.THB
+	}
+	sort.Slice(summary.Days, func(i, j int) bool { return summary.Days[i].Date < summary.Days[j].Date })
+	return summary
+}
+
+// GetTripSummary loads the trip's days and totals them
+func (s *MongoDBService) GetTripSummary(ctx context.Context, lineID string, trip *Trip) (*TripSummary, error) {
+	cursor, err := s.collection.Find(ctx,
+		bson.M{"lineid": lineID, "date": bson.M{"$gte": trip.StartDate, "$lte": trip.EndDate}, "expenses.trip": trip.Tag()},
+		options.Find().SetProjection(bson.M{"date": 1, "expenses.amount": 1, "expenses.category": 1, "expenses.is_transfer": 1,
+			"expenses.trip": 1, "expenses.currency": 1, "expenses.foreign_amount": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var records []DailyRecord
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return SummarizeTrip(trip, records), nil
+}
+
+// StartTrip turns travel mode on (replacing a trip in progress)
+func (s *MongoDBService) StartTrip(ctx context.Context, lineID string, trip *Trip) error {
+	_, err := s.settingsCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID},
+		bson.M{"$set": bson.M{"trip": trip, "updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// SetTripRate sets the THB rate used for the rest of the trip
+func (s *MongoDBService) SetTripRate(ctx context.Context, lineID string, rate float64) error {
+	_, err := s.settingsCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID, "trip": bson.M{"$exists": true}},
+		bson.M{"$set": bson.M{"trip.rate": rate, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+// StopTrip turns travel mode off; recorded entries keep their trip tag
+func (s *MongoDBService) StopTrip(ctx context.Context, lineID string) error {
+	_, err := s.settingsCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID},
+		bson.M{"$unset": bson.M{"trip": ""}, "$set": bson.M{"updated_at": time.Now()}},
+	)
+	return err
+}
@@ -0,0 +1,32 @@
+package services
+
+// Transaction kinds carried in postbacks ("kind=") so a handler goes straight to the right array
+const (
+	TransactionKindIncome  = "income"
+	TransactionKindExpense = "expense"
+)
+
+// TransactionKind returns the kind of a saved transaction type (1 income, otherwise expense)
+func TransactionKind(txType int) string {
+	if txType == 1 {
+		return TransactionKindIncome
+	}
+	return TransactionKindExpense
+}
+
+// TransactionDataKind returns the kind a TransactionData is saved as (investments are expenses)
+func TransactionDataKind(tx *TransactionData) string {
+	if tx.Type == "income" {
+		return TransactionKindIncome
+	}
+	return TransactionKindExpense
+}
+
+// TransactionFields returns the daily record arrays to search for a transaction of kind,
+// hinted one first (the other still follows in case the type was edited since the button was sent)
+func TransactionFields(kind string) []string {
+	if kind == TransactionKindExpense {
+		return []string{"expenses", "incomes"}
+	}
+	return []string{"incomes", "expenses"}
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UndoWindow is how long the "↩️ ไม่ใช่อันนี้" button of an AI update/transfer or a delete works
+const UndoWindow = 5 * time.Minute
+
+// Undoable AI actions
+const (
+	UndoKindUpdate   = "update"
+	UndoKindTransfer = "transfer"
+	UndoKindDelete   = "delete"
+)
+
+// Undo errors
+var (
+	ErrUndoExpired  = errors.New("undo expired")
+	ErrUndoConflict = errors.New("transaction changed since")
+)
+
+// UndoState is the part of a transaction an AI update can change
+type UndoState struct {
+	Amount         float64 `json:"amount"`
+	UseType        int     `json:"usetype"`
+	BankName       string  `json:"bankname,omitempty"`
+	CreditCardName string  `json:"creditcardname,omitempty"`
+}
+
+// UndoStateOf returns the undoable state of a transaction
+func UndoStateOf(tx Transaction) UndoState {
+	return UndoState{Amount: tx.Amount, UseType: tx.UseType, BankName: tx.BankName, CreditCardName: tx.CreditCardName}
+}
+
+// UndoAction is the before/after record of the user's last AI update or transfer, kept for UndoWindow
+// Only the newest one can be undone; Token ties the button to it so an older button can't undo a newer action
+type UndoAction struct {
+	Token      string       `json:"token"`
+	Kind       string       `json:"kind"`
+	TxID       string       `json:"txid,omitempty"`
+	TransferID string       `json:"transfer_id,omitempty"`
+	Date       string       `json:"date"`
+	Before     UndoState    `json:"before"`
+	After      UndoState    `json:"after"`
+	Deleted    *Transaction `json:"deleted,omitempty"` // the removed entry, put back as it was
+}
+
+// CanUndo reports whether current is still what the update left, so undoing reverses exactly that operation
+func (a UndoAction) CanUndo(current Transaction) bool {
+	return UndoStateOf(current) == a.After
+}
+
+// undoKey is the temp data key of a user's undoable action
+func undoKey(lineID string) string {
+	return "undo_" + lineID
+}
+
+// RememberUndo keeps action as the user's undoable action and returns its token
+func (s *MongoDBService) RememberUndo(ctx context.Context, lineID string, action UndoAction) (string, error) {
+	action.Token = primitive.NewObjectID().Hex()
+	data, err := json.Marshal(action)
+	if err != nil {
+		return "", err
+	}
+	if err := s.SaveTempData(ctx, undoKey(lineID), string(data), UndoWindow); err != nil {
+		return "", err
+	}
+	return action.Token, nil
+}
+
+// Undo reverses the action the token belongs to (ErrUndoExpired after UndoWindow or once a newer action
+// replaced it, ErrUndoConflict when the transaction was changed again since)
+func (s *MongoDBService) Undo(ctx context.Context, lineID, token string) (*UndoAction, error) {
+	data, err := s.GetTempData(ctx, undoKey(lineID))
+	if err != nil {
+		return nil, ErrUndoExpired
+	}
+	var action UndoAction
+	if err := json.Unmarshal([]byte(data), &action); err != nil || action.Token != token {
+		return nil, ErrUndoExpired
+	}
+
+	switch action.Kind {
+	case UndoKindTransfer:
+		if _, err := s.GetTransferByID(ctx, action.TransferID); err != nil {
+			return nil, ErrUndoConflict // already cancelled with 🗑️
+		}
+		if err := s.DeleteTransferOnDate(ctx, lineID, action.TransferID, action.Date); err != nil {
+			return nil, err
+		}
+	case UndoKindDelete:
+		if action.Deleted == nil {
+			return nil, ErrUndoExpired
+		}
+		if _, err := s.GetTransactionOnDate(ctx, lineID, action.TxID, action.Date); err == nil {
+			return nil, ErrUndoConflict // already back
+		}
+		if err := s.RestoreTransactionOnDate(ctx, lineID, action.Date, *action.Deleted); err != nil {
+			return nil, err
+		}
+	case UndoKindUpdate:
+		current, err := s.GetTransactionOnDate(ctx, lineID, action.TxID, action.Date)
+		if err != nil {
+			return nil, err
+		}
+		if !action.CanUndo(*current) {
+			return nil, ErrUndoConflict
+		}
+		before := action.Before
+		// The amount before the edit was already accepted, so a child's cap isn't checked again
+		if before.Amount != action.After.Amount {
+			if err := s.checkPeriodOpen(ctx, lineID, action.Date); err != nil {
+				return nil, err
+			}
+			if err := s.updateTransactionAmount(ctx, lineID, current.ID, action.Date, before.Amount); err != nil {
+				return nil, err
+			}
+		}
+		if before.UseType != action.After.UseType || before.BankName != action.After.BankName || before.CreditCardName != action.After.CreditCardName {
+			if err := s.UpdateTransactionPaymentOnDate(ctx, lineID, action.TxID, action.Date, before.UseType, before.BankName, before.CreditCardName); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, ErrUndoExpired
+	}
+	s.DeleteTempData(ctx, undoKey(lineID))
+	return &action, nil
+}
+
+// RestoreTransactionOnDate puts a deleted transaction back into the record of date with its original ID
+func (s *MongoDBService) RestoreTransactionOnDate(ctx context.Context, lineID, date string, tx Transaction) error {
+	if err := s.checkPeriodOpen(ctx, lineID, date); err != nil {
+		return err
+	}
+	field := TransactionFields(TransactionKind(tx.Type))[0]
+	now := time.Now()
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"lineid": lineID, "date": date},
+		bson.M{
+			"$push":        bson.M{field: tx},
+			"$set":         bson.M{"updatedAt": now},
+			"$setOnInsert": bson.M{"time": now.Format("15:04"), "createdAt": now},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return err
+	}
+	if err := s.recalculateTotals(ctx, lineID, date); err != nil {
+		return err
+	}
+	s.notifyTransaction(TransactionCreated, lineID, date, tx)
+	return nil
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Profile defaults for users created before the profile existed
+const (
+	defaultTimezone = "Asia/Bangkok"
+	defaultLanguage = "th"
+)
+
+// lineProfileTTL is how long a fetched LINE display name/picture is trusted
+const lineProfileTTL = 7 * 24 * time.Hour
+
+// UserProfile holds derived per-user metadata kept up to date incrementally
+// so the webhook can build AI context with a single read
+type UserProfile struct {
+	LineID            string         `bson:"lineid" json:"lineid"`
+	DisplayName       string         `bson:"display_name,omitempty" json:"display_name,omitempty"`
+	PictureURL        string         `bson:"picture_url,omitempty" json:"picture_url,omitempty"`
+	ProfileFetchedAt  time.Time      `bson:"profile_fetched_at,omitempty" json:"-"`
+	Timezone          string         `bson:"timezone" json:"timezone"`
+	Language          string         `bson:"language" json:"language"`
+	OnboardingState   string         `bson:"onboarding_state,omitempty" json:"onboarding_state,omitempty"`
+	Banks             []string       `bson:"banks" json:"banks"`
+	CreditCards       []string       `bson:"credit_cards" json:"credit_cards"`
+	IncomeCategories  []string       `bson:"income_categories" json:"income_categories"`
+	ExpenseCategories []string       `bson:"expense_categories" json:"expense_categories"`
+	AccountAliases    []AccountAlias `bson:"account_aliases,omitempty" json:"account_aliases,omitempty"` // set by the user, kept on rebuild
+	BalanceVersion    int64          `bson:"balance_version" json:"balance_version"`                     // bumped on every transaction change (cache key)
+	RankedAt          time.Time      `bson:"ranked_at,omitempty" json:"-"`                               // names ordered by use, most used first
+	UpdatedAt         time.Time      `bson:"updated_at" json:"updated_at"`
+}
+
+// GetUserProfile returns profile, building it from history on first use
+func (s *MongoDBService) GetUserProfile(ctx context.Context, lineID string) (*UserProfile, error) {
+	var profile UserProfile
+	err := s.profileCollection.FindOne(ctx, bson.M{"lineid": lineID}).Decode(&profile)
+	if err == nil {
+		s.rankProfileAsync(&profile)
+		return &profile, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	return s.rebuildUserProfile(ctx, lineID)
+}
+
+// rebuildUserProfile recomputes known names from history, most used first (keeps display name/settings)
+func (s *MongoDBService) rebuildUserProfile(ctx context.Context, lineID string) (*UserProfile, error) {
+	names, err := s.loadDistinctNames(ctx, lineID)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := s.loadNameUsage(ctx, lineID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"banks":              RankNames(names.Banks, usage.Banks, now),
+			"credit_cards":       RankNames(names.CreditCards, usage.CreditCards, now),
+			"income_categories":  RankNames(names.IncomeCategories, usage.IncomeCategories, now),
+			"expense_categories": RankNames(names.ExpenseCategories, usage.ExpenseCategories, now),
+			"ranked_at":          now,
+			"updated_at":         now,
+		},
+		"$setOnInsert": bson.M{"timezone": defaultTimezone, "language": defaultLanguage},
+		"$inc":         bson.M{"balance_version": 1},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var profile UserProfile
+	if err := s.profileCollection.FindOneAndUpdate(ctx, bson.M{"lineid": lineID}, update, opts).Decode(&profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// NeedsLineProfile reports whether the LINE display name should be (re)fetched
+func (p *UserProfile) NeedsLineProfile(now time.Time) bool {
+	return p == nil || p.ProfileFetchedAt.IsZero() || now.Sub(p.ProfileFetchedAt) > lineProfileTTL
+}
+
+// SetLineProfile caches LINE display name and picture on the profile
+func (s *MongoDBService) SetLineProfile(ctx context.Context, lineID, displayName, pictureURL string) error {
+	return s.updateUserProfile(ctx, lineID, bson.M{
+		"display_name":       displayName,
+		"picture_url":        pictureURL,
+		"profile_fetched_at": time.Now(),
+	})
+}
+
+// GetDisplayName returns cached LINE display name ("" when unknown)
+func (s *MongoDBService) GetDisplayName(ctx context.Context, lineID string) string {
+	var profile UserProfile
+	opts := options.FindOne().SetProjection(bson.M{"display_name": 1})
+	if err := s.profileCollection.FindOne(ctx, bson.M{"lineid": lineID}, opts).Decode(&profile); err != nil {
+		return ""
+	}
+	return profile.DisplayName
+}
+
+// ReportOwnerTitle returns export header "รายงานของคุณ <name>" (generic title when name unknown)
+func ReportOwnerTitle(displayName string) string {
+	if name := strings.TrimSpace(displayName); name != "" {
+		return "รายงานของคุณ " + name
+	}
+	return "รายงานสรุปการเงินส่วนตัว"
+}
+
+// nameTitles are honorifics printed on bank slips before the account name
+var nameTitles = []string{"นางสาว", "น.ส.", "นาย", "นาง", "ด.ช.", "ด.ญ.", "mrs.", "mrs ", "miss ", "mr.", "mr ", "ms.", "ms "}
+
+// firstNameToken returns lowercase first name without honorific ("นาย สมชาย ใ" -> "สมชาย")
+func firstNameToken(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, title := range nameTitles {
+		if strings.HasPrefix(name, title) {
+			name = strings.TrimSpace(strings.TrimPrefix(name, title))
+			break
+		}
+	}
+	if fields := strings.Fields(name); len(fields) > 0 {
+		return strings.Trim(fields[0], ".")
+	}
+	return ""
+}
+
+// MatchSlipName reports whether a slip's sender/receiver name looks like the user's display name
+// (slips truncate surnames, so only first names are compared)
+func MatchSlipName(displayName, slipName string) bool {
+	a, b := firstNameToken(displayName), firstNameToken(slipName)
+	if len([]rune(a)) < 2 || len([]rune(b)) < 2 {
+		return false
+	}
+	return a == b
+}
+
+// updateUserProfile sets profile fields (creates profile with defaults if missing)
+func (s *MongoDBService) updateUserProfile(ctx context.Context, lineID string, fields bson.M) error {
+	fields["updated_at"] = time.Now()
+	_, err := s.profileCollection.UpdateOne(ctx,
+		bson.M{"lineid": lineID},
+		bson.M{"$set": fields, "$setOnInsert": bson.M{"timezone": defaultTimezone, "language": defaultLanguage}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// updateProfileFromTransaction adds new names and bumps balance version in background
+func (s *MongoDBService) updateProfileFromTransaction(event, lineID, date string, tx Transaction) {
+	GoSafe("profile.update", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		set := bson.M{
+			"balance_version": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$balance_version", 0}}, 1}},
+			"updated_at":      time.Now(),
+		}
+		// Names just used move to the front of their list (AI schema keeps only the first few)
+		// Deleted names stay until the next rebuild (a superset is harmless for AI context)
+		if event != TransactionDeleted {
+			if tx.BankName != "" {
+				set["banks"] = moveNameToFront("banks", tx.BankName)
+			}
+			if tx.CreditCardName != "" {
+				set["credit_cards"] = moveNameToFront("credit_cards", tx.CreditCardName)
+			}
+			if tx.Category != "" && !tx.IsTransfer {
+				field := "expense_categories"
+				if tx.Type == 1 {
+					field = "income_categories"
+				}
+				set[field] = moveNameToFront(field, tx.Category)
+			}
+		}
+		// Only existing profiles are updated; missing ones are built from history on first read
+		if _, err := s.profileCollection.UpdateOne(ctx, bson.M{"lineid": lineID}, mongo.Pipeline{{{Key: "$set", Value: set}}}); err != nil {
+			log.Printf("Failed to update user profile: %v", err)
+		}
+	})
+}
+
+// AISchemaTopNames is how many banks/cards/categories each go into the AI schema; the rest become "อื่นๆ..."
+const AISchemaTopNames = 10
+
+// BuildAISchema returns compact names context with the most used names of each list:
+// "ชื่อ:Nok|ธนาคาร:SCB,KBank|บัตร:CITI|หมวด:อาหาร,เดินทาง,อื่นๆ..."
+func (p *UserProfile) BuildAISchema() string {
+	return p.buildAISchema(AISchemaTopNames)
+}
+
+// BuildFullAISchema returns names context with every known name (AI asked for the full list)
+func (p *UserProfile) BuildFullAISchema() string {
+	return p.buildAISchema(0)
+}
+
+// buildAISchema lists at most limit names per list (0 = all)
+func (p *UserProfile) buildAISchema(limit int) string {
+	var parts []string
+	if p.DisplayName != "" {
+		// Lets AI greet the user by name (few tokens)
+		parts = append(parts, "ชื่อ:"+p.DisplayName)
+	}
+	list := func(label string, names []string) {
+		if len(names) == 0 {
+			return
+		}
+		if limit > 0 && len(names) > limit {
+			names = append(names[:limit:limit], "อื่นๆ...")
+		}
+		parts = append(parts, label+":"+strings.Join(names, ","))
+	}
+	list("ธนาคาร", p.Banks)
+	list("บัตร", p.CreditCards)
+	if len(p.AccountAliases) > 0 {
+		// Nicknames are few and the user's own words, so all of them are always sent
+		aliases := make([]string, 0, len(p.AccountAliases))
+		for _, a := range p.AccountAliases {
+			aliases = append(aliases, a.Alias+"="+a.Target())
+		}
+		parts = append(parts, "ชื่อบัญชี:"+strings.Join(aliases, ","))
+	}
+	list("หมวด", p.ExpenseCategories)
+	return strings.Join(parts, "|")
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// vatReportPageSize is how many transactions each page query of the VAT report fetches
+const vatReportPageSize = 1000
+
+// ExportVATReport generates Excel listing receipts with VAT/tax ID (for freelancers/small businesses)
+// from/to are YYYY-MM-DD; empty means this month
+func (s *ExportService) ExportVATReport(ctx context.Context, lineID, from, to string) ([]byte, string, error) {
+	now := time.Now()
+	if from == "" {
+		from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).Format("2006-01-02")
+	}
+	if to == "" {
+		to = now.Format("2006-01-02")
+	}
+
+	// Read every page so a busy month isn't cut off
+	var results []SearchResult
+	for {
+		page, total, err := s.mongo.SearchByDateRangePage(ctx, lineID, from, to, len(results), vatReportPageSize)
+		if err != nil {
+			return nil, "", fmt.Errorf("ไม่สามารถดึงข้อมูลได้: %w", err)
+		}
+		results = append(results, page...)
+		if len(page) == 0 || len(results) >= total {
+			break
+		}
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheetName := "รายงาน VAT"
+	f.SetSheetName("Sheet1", sheetName)
+
+	titleStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 16, Color: "#FFFFFF"},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{colorPrimary}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
+	})
+	f.MergeCell(sheetName, "A1", "H1")
+	f.SetCellValue(sheetName, "A1", "🧾 รายงานภาษีซื้อ (VAT)")
+	f.SetCellStyle(sheetName, "A1", "H1", titleStyle)
+	f.SetRowHeight(sheetName, 1, 30)
+
+	f.MergeCell(sheetName, "A2", "H2")
+	f.SetCellValue(sheetName, "A2", fmt.Sprintf("วันที่ %s ถึง %s", from, to))
+
+	headers := []string{"วันที่", "ร้านค้า", "เลขที่ใบเสร็จ", "เลขผู้เสียภาษี", "มูลค่าก่อน VAT", "VAT", "Service Charge", "รวม"}
+	headerStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 11, Color: "#FFFFFF"},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{colorSecondary}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
+	})
+	for i, header := range headers {
+		f.SetCellValue(sheetName, fmt.Sprintf("%c3", 'A'+i), header)
+	}
+	f.SetCellStyle(sheetName, "A3", "H3", headerStyle)
+
+	numberStyle, _ := f.NewStyle(&excelize.Style{
+		Alignment: &excelize.Alignment{Horizontal: "right"},
+		NumFmt:    4, // #,##0.00
+	})
+
+	// Deductible receipts = expenses with VAT or tax ID
+	var totalBase, totalVAT, totalService, totalAmount float64
+	row := 4
+	for _, r := range results {
+		tx := r.Transaction
+		if tx.Type != -1 || tx.IsTransfer || (tx.VATAmount <= 0 && tx.TaxID == "") {
+			continue
+		}
+		base := tx.Amount - tx.VATAmount
+		merchant := tx.CustName
+		if merchant == "" {
+			merchant = tx.Description
+		}
+
+		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), r.Date)
+		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), merchant)
+		f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), tx.ReceiptNo)
+		f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), tx.TaxID)
+		f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), base)
+		f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), tx.VATAmount)
+		f.SetCellValue(sheetName, fmt.Sprintf("G%d", row), tx.ServiceCharge)
+		f.SetCellValue(sheetName, fmt.Sprintf("H%d", row), tx.Amount)
+		f.SetCellStyle(sheetName, fmt.Sprintf("E%d", row), fmt.Sprintf("H%d", row), numberStyle)
+
+		totalBase += base
+		totalVAT += tx.VATAmount
+		totalService += tx.ServiceCharge
+		totalAmount += tx.Amount
+		row++
+	}
+
+	if row == 4 {
+		f.MergeCell(sheetName, "A4", "H4")
+		f.SetCellValue(sheetName, "A4", "ไม่มีใบเสร็จที่มี VAT ในช่วงนี้")
+		row++
+	}
+
+	totalStyle, _ := f.NewStyle(&excelize.Style{
+		Font:   &excelize.Font{Bold: true, Color: "#FFFFFF"},
+		Fill:   excelize.Fill{Type: "pattern", Color: []string{colorPrimary}, Pattern: 1},
+		NumFmt: 4,
+	})
+	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "รวม")
+	f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), totalBase)
+	f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), totalVAT)
+	f.SetCellValue(sheetName, fmt.Sprintf("G%d", row), totalService)
+	f.SetCellValue(sheetName, fmt.Sprintf("H%d", row), totalAmount)
+	f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("H%d", row), totalStyle)
+
+	f.SetColWidth(sheetName, "A", "A", 12)
+	f.SetColWidth(sheetName, "B", "B", 28)
+	f.SetColWidth(sheetName, "C", "D", 18)
+	f.SetColWidth(sheetName, "E", "H", 15)
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		return nil, "", fmt.Errorf("cannot create Excel: %w", err)
+	}
+
+	randomNum := fmt.Sprintf("%d%d", time.Now().UnixNano(), time.Now().UnixMicro()%10000)
+	return buf.Bytes(), fmt.Sprintf("%s.xlsx", randomNum), nil
+}
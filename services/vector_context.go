@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Semantic retrieval for open questions ("ช่วงนี้ฟุ่มเฟือยไหม"), gated by FlagVectorSearch
+const (
+	vectorSearchDays     = 180 // history searched
+	vectorSearchMinScore = 0.3 // cosine similarity below this is noise
+)
+
+// openQuestionMarkers are particles of a question that needs judgement, not a lookup
+var openQuestionMarkers = []string{"ไหม", "มั้ย", "หรือเปล่า", "รึเปล่า", "ป่าว", "ยังไง", "อย่างไร", "ทำไม", "ควร", "?"}
+
+// semanticExpansions map abstract words to spending they usually mean (no embedding API needed)
+var semanticExpansions = map[string][]string{
+	"ฟุ่มเฟือย":  {"ช้อปปิ้ง", "บันเทิง", "กาแฟ", "ชานม", "ขนม", "เดลิเวอรี่", "grab food", "lineman", "shopee", "lazada", "เสื้อผ้า", "เกม"},
+	"สิ้นเปลือง": {"ช้อปปิ้ง", "บันเทิง", "กาแฟ", "ขนม", "เดลิเวอรี่"},
+	"ประหยัด":    {"ช้อปปิ้ง", "บันเทิง", "กาแฟ", "เดลิเวอรี่", "อาหาร"},
+	"กิน":        {"อาหาร", "ข้าว", "ร้านอาหาร", "กาแฟ", "ขนม", "เดลิเวอรี่"},
+	"เที่ยว":     {"ท่องเที่ยว", "โรงแรม", "ตั๋ว", "เดินทาง", "บันเทิง"},
+	"เดินทาง":    {"เดินทาง", "น้ำมัน", "แท็กซี่", "grab", "bts", "mrt", "ทางด่วน"},
+	"สุขภาพ":     {"โรงพยาบาล", "ยา", "คลินิก", "ฟิตเนส", "สุขภาพ"},
+	"บ้าน":       {"ค่าน้ำ", "ค่าไฟ", "ค่าเช่า", "อินเทอร์เน็ต", "ของใช้"},
+}
+
+// vectorStopWords are question words that would otherwise dominate the query vector
+var vectorStopWords = []string{"ช่วงนี้", "เดือนนี้", "ตอนนี้", "หรือเปล่า", "รึเปล่า", "ไหม", "มั้ย", "ป่าว", "ยังไง", "อย่างไร", "ทำไม", "ควร", "ฉัน", "เรา", "บ้าง", "เยอะ", "มาก", "ใช้จ่าย", "ใช้เงิน", "จ่าย"}
+
+// IsOpenQuestion reports whether text asks for judgement over history rather than recording an entry
+func IsOpenQuestion(text string) bool {
+	for _, r := range text {
+		if unicode.IsDigit(r) {
+			return false
+		}
+	}
+	lower := strings.ToLower(text)
+	for _, marker := range openQuestionMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandQuery strips question words and adds spending terms related to abstract words
+// Returns one term per entry so a long question doesn't dilute each term's similarity
+func expandQuery(query string) []string {
+	lower := strings.ToLower(query)
+	var extra []string
+	for word, terms := range semanticExpansions {
+		if strings.Contains(lower, word) {
+			extra = append(extra, terms...)
+		}
+	}
+	for _, stop := range vectorStopWords {
+		lower = strings.ReplaceAll(lower, stop, " ")
+	}
+	return append(strings.Fields(lower), extra...)
+}
+
+// TextVector builds a character-trigram vector per word (Thai has no spaces, so n-grams beat words)
+func TextVector(text string) map[string]float64 {
+	vec := make(map[string]float64)
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		runes := []rune(word)
+		if len(runes) < 3 {
+			vec[word]++
+			continue
+		}
+		for i := 0; i+3 <= len(runes); i++ {
+			vec[string(runes[i:i+3])]++
+		}
+	}
+	return vec
+}
+
+// CosineSimilarity returns cosine similarity of two sparse vectors (0 if either is empty)
+func CosineSimilarity(a, b map[string]float64) float64 {
+	var dot, normA, normB float64
+	for k, v := range a {
+		normA += v * v
+		if w, ok := b[k]; ok {
+			dot += v * w
+		}
+	}
+	for _, w := range b {
+		normB += w * w
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// vectorHit is a past transaction scored against the query
+type vectorHit struct {
+	date  string
+	tx    Transaction
+	score float64
+}
+
+// GetVectorSearchResultText returns past transactions most similar to query as AI context ("" if none match)
+func (s *MongoDBService) GetVectorSearchResultText(ctx context.Context, lineID, query string, limit int) string {
+	if limit <= 0 {
+		limit = 15
+	}
+	var termVecs []map[string]float64
+	for _, term := range expandQuery(query) {
+		termVecs = append(termVecs, TextVector(term))
+	}
+	if len(termVecs) == 0 {
+		return ""
+	}
+
+	filter := bson.M{
+		"lineid": lineID,
+		"date":   bson.M{"$gte": time.Now().AddDate(0, 0, -vectorSearchDays).Format("2006-01-02")},
+	}
+	// Skip receipt images, they are the bulk of each record
+	opts := options.Find().SetProjection(bson.M{"expenses.imagebase64": 0, "incomes.imagebase64": 0})
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return ""
+	}
+	defer cursor.Close(ctx)
+
+	var hits []vectorHit
+	for cursor.Next(ctx) {
+		var record DailyRecord
+		if err := cursor.Decode(&record); err != nil {
+			continue
+		}
+		for _, tx := range record.Expenses {
+			txVec := TextVector(strings.Join([]string{tx.Category, tx.Description, tx.CustName}, " "))
+			score := 0.0
+			for _, termVec := range termVecs {
+				score = math.Max(score, CosineSimilarity(termVec, txVec))
+			}
+			if score >= vectorSearchMinScore {
+				hits = append(hits, vectorHit{date: record.Date, tx: tx, score: score})
+			}
+		}
+	}
+	if len(hits) == 0 {
+		return ""
+	}
+
+	// Totals cover every match, the list only the closest ones
+	total := 0.0
+	byCategory := make(map[string]float64)
+	for _, hit := range hits {
+		total += hit.tx.Amount
+		byCategory[hit.tx.Category] += hit.tx.Amount
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return hits[i].date > hits[j].date
+	})
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("รายการที่เกี่ยวข้องกับคำถาม (%d วัน):\n", vectorSearchDays))
+	for i, hit := range hits {
+		if i >= limit {
+			break
+		}
+		desc := hit.tx.Description
+		if desc == "" {
+			desc = hit.tx.Category
+		}
+		sb.WriteString(fmt.Sprintf("- %s: %s %.0f บาท (%s)\n", hit.date, hit.tx.Category, hit.tx.Amount, desc))
+	}
+
+	categories := make([]string, 0, len(byCategory))
+	for category := range byCategory {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool { return byCategory[categories[i]] > byCategory[categories[j]] })
+	var parts []string
+	for i, category := range categories {
+		if i >= 5 {
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s %.0f", category, byCategory[category]))
+	}
+	sb.WriteString(fmt.Sprintf("รวมที่เกี่ยวข้อง %d รายการ %.0f บาท (%s)", len(hits), total, strings.Join(parts, ", ")))
+	return sb.String()
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// WarmUpStep is one dependency primed by /warmup and how long it took
+type WarmUpStep struct {
+	Name  string `json:"name"`
+	Ms    int64  `json:"ms"`
+	Error string `json:"error,omitempty"`
+}
+
+// RunWarmUpStep times fn as a named warm-up step
+func RunWarmUpStep(name string, fn func() error) WarmUpStep {
+	start := time.Now()
+	step := WarmUpStep{Name: name}
+	if err := fn(); err != nil {
+		step.Error = err.Error()
+	}
+	step.Ms = time.Since(start).Milliseconds()
+	return step
+}
+
+// Ping checks the Mongo connection, opening pooled connections on a cold instance
+func (s *MongoDBService) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx, readpref.Primary())
+}
+
+// WarmUpFonts parses the Thai PDF fonts ahead of the first export
+func WarmUpFonts() error {
+	_, err := loadThaiFonts()
+	return err
+}
+
+// WarmUpExcel builds and discards a tiny workbook so the first real export doesn't pay excelize setup
+// (Go can't defer loading the package itself; this primes its templates and buffers)
+func WarmUpExcel() error {
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := f.SetCellValue("Sheet1", "A1", "warmup"); err != nil {
+		return err
+	}
+	_, err := f.WriteToBuffer()
+	return err
+}
+
+// PromptsReady reports whether prompt files were loaded (false means built-in defaults are used)
+func (s *AIService) PromptsReady() bool {
+	p := s.currentPrompts()
+	return p.System != getDefaultSystemPrompt() && p.Receipt != getDefaultReceiptPrompt()
+}
+
+// Prime loads rollout percentages into the cache so the first message doesn't query them
+func (f *FeatureFlagService) Prime(ctx context.Context) {
+	f.Percent(ctx, "")
+}
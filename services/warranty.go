@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// warrantyReminderDays are days before expiry when a reminder is shown
+var warrantyReminderDays = []int{30, 7}
+
+// Warranty keeps the receipt of a big-ticket purchase until its warranty ends
+type Warranty struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	LineID        string             `bson:"lineid" json:"lineid"`
+	TransactionID string             `bson:"transaction_id" json:"transaction_id"`
+	PurchaseDate  string             `bson:"purchase_date" json:"purchase_date"` // YYYY-MM-DD (date of the daily record)
+	Description   string             `bson:"description" json:"description"`
+	Amount        float64            `bson:"amount" json:"amount"`
+	Months        int                `bson:"months" json:"months"`
+	ExpiresAt     string             `bson:"expires_at" json:"expires_at"` // YYYY-MM-DD
+	HasReceipt    bool               `bson:"has_receipt" json:"has_receipt"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// warrantyPattern matches "ประกัน 2 ปี", "รับประกัน 18 เดือน"
+var warrantyPattern = regexp.MustCompile(`(?:รับ)?ประกัน\s*(\d+)\s*(ปี|เดือน)`)
+
+// ParseWarrantyPeriod finds a warranty length in text ("เก็บใบเสร็จประกัน 2 ปี") and returns it in months
+func ParseWarrantyPeriod(text string) (int, bool) {
+	m := warrantyPattern.FindStringSubmatch(text)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	if m[2] == "ปี" {
+		n *= 12
+	}
+	if n > 120 {
+		return 0, false
+	}
+	return n, true
+}
+
+// WarrantyExpiry returns the expiry date (YYYY-MM-DD) of a warranty starting on purchase date
+func WarrantyExpiry(purchaseDate string, months int) (string, error) {
+	start, err := time.Parse("2006-01-02", purchaseDate)
+	if err != nil {
+		return "", err
+	}
+	return start.AddDate(0, months, 0).Format("2006-01-02"), nil
+}
+
+// SaveWarranty records (or replaces) the warranty of a saved transaction
+func (s *MongoDBService) SaveWarranty(ctx context.Context, lineID, txID, date string, months int) (*Warranty, error) {
+	tx, err := s.GetTransactionOnDate(ctx, lineID, txID, date)
+	if err != nil {
+		return nil, fmt.Errorf("transaction not found: %w", err)
+	}
+	expiresAt, err := WarrantyExpiry(date, months)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Warranty{
+		LineID:        lineID,
+		TransactionID: txID,
+		PurchaseDate:  date,
+		Description:   tx.Description,
+		Amount:        tx.Amount,
+		Months:        months,
+		ExpiresAt:     expiresAt,
+		HasReceipt:    tx.ImageBase64 != "",
+		CreatedAt:     time.Now(),
+	}
+	if w.Description == "" {
+		w.Description = tx.Category
+	}
+	_, err = s.warrantyCollection.ReplaceOne(ctx,
+		bson.M{"lineid": lineID, "transaction_id": txID},
+		w,
+		options.Replace().SetUpsert(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// GetWarranties returns warranties not yet expired, soonest expiry first
+func (s *MongoDBService) GetWarranties(ctx context.Context, lineID string) ([]Warranty, error) {
+	cursor, err := s.warrantyCollection.Find(ctx,
+		bson.M{"lineid": lineID, "expires_at": bson.M{"$gte": time.Now().Format("2006-01-02")}},
+		options.Find().SetSort(bson.M{"expires_at": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var warranties []Warranty
+	if err := cursor.All(ctx, &warranties); err != nil {
+		return nil, err
+	}
+	return warranties, nil
+}
+
+// GetWarrantyReceipt returns the receipt image of a warranty's purchase (nil data if none)
+func (s *MongoDBService) GetWarrantyReceipt(ctx context.Context, lineID string, w Warranty) ([]byte, string, error) {
+	tx, err := s.GetTransactionOnDate(ctx, lineID, w.TransactionID, w.PurchaseDate)
+	if err != nil {
+		return nil, "", err
+	}
+	data, mimeType := receiptImage(tx.ImageBase64)
+	return data, mimeType, nil
+}
+
+// warrantyNotices reminds warranties expiring within 30 and 7 days
+func warrantyNotices(ctx context.Context, s *MongoDBService, lineID string, now time.Time) []Notice {
+	warranties, err := s.GetWarranties(ctx, lineID)
+	if err != nil {
+		return nil
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var notices []Notice
+	for _, w := range warranties {
+		expiry, err := time.ParseInLocation("2006-01-02", w.ExpiresAt, now.Location())
+		if err != nil {
+			continue
+		}
+		daysLeft := int(expiry.Sub(today).Hours() / 24)
+		// Only the closest threshold, so a late first look doesn't show both
+		threshold := 0
+		for _, days := range warrantyReminderDays {
+			if daysLeft <= days {
+				threshold = days
+			}
+		}
+		if threshold == 0 {
+			continue
+		}
+		notices = append(notices, Notice{
+			Type: NotifyWarranty,
+			Key:  fmt.Sprintf("warranty:%s:%d", w.ID.Hex(), threshold),
+			Text: fmt.Sprintf("🛡️ ประกัน %s (%.0f บาท) หมดวันที่ %s อีก %d วัน พิมพ์ \"ประกันสินค้า\" เพื่อดูใบเสร็จ", w.Description, w.Amount, expiry.Format("02/01/2006"), daysLeft),
+		})
+	}
+	return notices
+}
@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+var testAliases = []services.AccountAlias{
+	{Alias: "บัญชีเงินเดือน", UseType: 2, BankName: "กสิกร xxx-1234"},
+	{Alias: "บัญชีออม", UseType: 2, BankName: "ออมสิน"},
+	{Alias: "กระเป๋า", UseType: 0},
+}
+
+func TestFindAccountAlias(t *testing.T) {
+	for _, name := range []string{"บัญชีเงินเดือน", "บัญชี เงินเดือน", "เงินเดือน"} {
+		a, ok := services.FindAccountAlias(testAliases, name)
+		if !ok || a.BankName != "กสิกร xxx-1234" {
+			t.Errorf("FindAccountAlias(%q) = %+v, %v", name, a, ok)
+		}
+	}
+	if _, ok := services.FindAccountAlias(testAliases, "บัญชี"); ok {
+		t.Error("bare บัญชี must not match an alias")
+	}
+}
+
+func TestParseAccountTransfer(t *testing.T) {
+	banks := []string{"SCB"}
+	tests := []struct {
+		text   string
+		amount float64
+		from   string
+		to     string
+	}{
+		{"โอน 5000 จากบัญชีเงินเดือนไปบัญชีออม", 5000, "กสิกร xxx-1234", "ออมสิน"},
+		{"โอนจากบัญชีเงินเดือนไปบัญชีออม 5,000 บาท", 5000, "กสิกร xxx-1234", "ออมสิน"},
+		{"โอนเงินจาก SCB เข้าบัญชีเงินเดือน 2k", 2000, "SCB", "กสิกร xxx-1234"},
+	}
+	for _, tt := range tests {
+		got, ok := services.ParseAccountTransfer(tt.text, banks, nil, testAliases)
+		if !ok {
+			t.Errorf("ParseAccountTransfer(%q) not parsed", tt.text)
+			continue
+		}
+		if got.From[0].Amount != tt.amount || got.To[0].Amount != tt.amount || got.From[0].BankName != tt.from || got.To[0].BankName != tt.to {
+			t.Errorf("ParseAccountTransfer(%q) = from %+v to %+v", tt.text, got.From[0], got.To[0])
+		}
+	}
+
+	// Unknown side (a person), missing amount or same account are left to the AI
+	for _, text := range []string{"โอน 500 จากแม่ไปบัญชีออม", "โอนจากบัญชีเงินเดือนไปบัญชีออม", "โอน 100 จากบัญชีออมไปบัญชีออม"} {
+		if _, ok := services.ParseAccountTransfer(text, banks, nil, testAliases); ok {
+			t.Errorf("ParseAccountTransfer(%q) should not parse", text)
+		}
+	}
+}
+
+func TestResolveAliasesInAIResult(t *testing.T) {
+	txs := []services.TransactionData{{Type: "expense", UseType: 2, BankName: "บัญชีเงินเดือน"}, {Type: "expense", UseType: 2, BankName: "SCB"}}
+	services.ResolveTransactionAliases(testAliases, txs)
+	if txs[0].BankName != "กสิกร xxx-1234" || txs[1].BankName != "SCB" {
+		t.Errorf("ResolveTransactionAliases = %+v", txs)
+	}
+
+	transfer := &services.TransferData{
+		From: []services.TransferEntry{{Amount: 100, UseType: 2, BankName: "กระเป๋า"}},
+		To:   []services.TransferEntry{{Amount: 100, UseType: 2, BankName: "บัญชีออม"}},
+	}
+	services.ResolveTransferAliases(testAliases, transfer)
+	if transfer.From[0].UseType != 0 || transfer.From[0].BankName != "" || transfer.To[0].BankName != "ออมสิน" {
+		t.Errorf("ResolveTransferAliases = from %+v to %+v", transfer.From[0], transfer.To[0])
+	}
+}
+
+func TestBuildAISchemaIncludesAliases(t *testing.T) {
+	p := &services.UserProfile{Banks: []string{"กสิกร xxx-1234"}, AccountAliases: testAliases[:1]}
+	if got, want := p.BuildAISchema(), "ธนาคาร:กสิกร xxx-1234|ชื่อบัญชี:บัญชีเงินเดือน=กสิกร xxx-1234"; got != want {
+		t.Errorf("BuildAISchema() = %q, want %q", got, want)
+	}
+}
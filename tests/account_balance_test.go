@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestParseAccountBalanceQuery(t *testing.T) {
+	banks := []string{"กสิกร", "ไทยพาณิชย์"}
+	cards := []string{"KTC"}
+	aliases := []services.AccountAlias{{Alias: "บัญชีเงินเดือน", UseType: 2, BankName: "กสิกร"}}
+	tests := []struct {
+		text string
+		want services.AccountRef
+		ok   bool
+	}{
+		{"ยอด กสิกร", services.AccountRef{UseType: 2, BankName: "กสิกร"}, true},
+		{"ยอดktc", services.AccountRef{UseType: 1, CreditCardName: "KTC"}, true},
+		{"ยอด บัญชีเงินเดือน", services.AccountRef{UseType: 2, BankName: "กสิกร"}, true},
+		{"ยอด เงินสด", services.AccountRef{UseType: 0}, true},
+		{"ยอดคงเหลือ", services.AccountRef{}, false},
+		{"ยอด", services.AccountRef{}, false},
+		{"กสิกร", services.AccountRef{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := services.ParseAccountBalanceQuery(tt.text, banks, cards, aliases)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseAccountBalanceQuery(%q) = %+v %v, want %+v %v", tt.text, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFindPaymentBalance(t *testing.T) {
+	balances := []services.PaymentBalance{
+		{UseType: 0, Balance: 500},
+		{UseType: 0, BankName: "ทอง", Balance: 20000},
+		{UseType: 2, BankName: "กสิกร", Balance: 1200},
+	}
+	if got := services.FindPaymentBalance(balances, services.AccountRef{UseType: 0}); got.Balance != 500 {
+		t.Errorf("cash balance = %v, want 500", got.Balance)
+	}
+	if got := services.FindPaymentBalance(balances, services.AccountRef{UseType: 2, BankName: "กสิกร"}); got.Balance != 1200 {
+		t.Errorf("bank balance = %v, want 1200", got.Balance)
+	}
+	if got := services.FindPaymentBalance(balances, services.AccountRef{UseType: 1, CreditCardName: "KTC"}); got.Balance != 0 || got.CreditCardName != "KTC" {
+		t.Errorf("unused card = %+v, want zero KTC balance", got)
+	}
+}
@@ -0,0 +1,35 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestParseIntent(t *testing.T) {
+	tests := map[string]string{
+		"new":            "new",
+		" Transfer\n":    "transfer",
+		"\"analyze\"":    "analyze",
+		"action: budget": "budget",
+		"chart":          "chart",
+		"```\nchat\n```": "chat",
+		"ไม่แน่ใจ":       "",
+		"newest":         "",
+	}
+	for input, want := range tests {
+		if got := services.ParseIntent(input); got != want {
+			t.Errorf("ParseIntent(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestParsePromptSections(t *testing.T) {
+	sections := services.ParsePromptSections("### common\nตอบ JSON\n\n### new\n{\"action\":\"new\"}\nline2\n")
+	if sections["common"] != "ตอบ JSON" {
+		t.Errorf("common = %q", sections["common"])
+	}
+	if sections["new"] != "{\"action\":\"new\"}\nline2" {
+		t.Errorf("new = %q", sections["new"])
+	}
+}
@@ -53,6 +53,9 @@ func TestAIResponses(t *testing.T) {
 	// Create AI service
 	ai := services.NewAIService()
 	defer ai.Close()
+	if os.Getenv("AI_TWO_STAGE") == "true" {
+		ai.EnableTwoStage()
+	}
 
 	results := make([]TestResult, 0)
 	passed := 0
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestParseThaiAmount(t *testing.T) {
+	cases := []struct {
+		input string
+		want  float64
+		ok    bool
+	}{
+		{"1,500", 1500, true},
+		{"2.5k", 2500, true},
+		{"3K", 3000, true},
+		{"1.2m", 1200000, true},
+		{"3 หมื่น", 30000, true},
+		{"2พัน", 2000, true},
+		{"๑๕๐", 150, true},
+		{"150 บาท", 150, true},
+		{"สองร้อยห้าสิบ", 250, true},
+		{"หนึ่งพันสองร้อย", 1200, true},
+		{"ยี่สิบเอ็ด", 21, true},
+		{"ร้อยห้าสิบ", 150, true},
+		{"พันครึ่ง", 1500, true},
+		{"หนึ่งล้านห้าแสน", 1500000, true},
+		{"ห้าง", 0, false},
+		{"abc", 0, false},
+	}
+
+	for _, c := range cases {
+		got, ok := services.ParseThaiAmount(c.input)
+		if ok != c.ok || got != c.want {
+			t.Errorf("ParseThaiAmount(%q) = %v, %v; want %v, %v", c.input, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestCheckAmount(t *testing.T) {
+	cases := []struct {
+		message   string
+		aiAmount  float64
+		mismatch  bool
+		suggested float64
+	}{
+		{"ข้าว 50 บาท", 50, false, 0},
+		{"ซื้อของ 2.5k", 2.5, true, 2500},
+		{"ค่าเสื้อ สองร้อยห้าสิบบาท", 250, false, 0},
+		{"ค่าเสื้อ สองร้อยห้าสิบบาท", 200, true, 250},
+		{"ข้าว 50 น้ำ 10", 60, false, 0},
+		{"ไปห้างซื้อของ", 300, false, 0},
+		{"นัด 15/10 12:30 ค่าหมอ 800", 800, false, 0},
+	}
+
+	for _, c := range cases {
+		check := services.CheckAmount(c.message, c.aiAmount)
+		if check.Mismatch != c.mismatch || check.Suggested != c.suggested {
+			t.Errorf("CheckAmount(%q, %v) = %+v; want mismatch=%v suggested=%v", c.message, c.aiAmount, check, c.mismatch, c.suggested)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestAnonymizeUserID(t *testing.T) {
+	a := services.AnonymizeUserID("U1234567890abcdef")
+	if a != services.AnonymizeUserID("U1234567890abcdef") {
+		t.Error("hash must be stable for the same user")
+	}
+	if a == services.AnonymizeUserID("U0000000000000000") {
+		t.Error("different users must not share a hash")
+	}
+	if len(a) != 16 || a == "U1234567890abcdef" {
+		t.Errorf("unexpected hash %q", a)
+	}
+}
@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestArchiveCutoff(t *testing.T) {
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local)
+	if got := services.ArchiveCutoff(now, 3); got != "2023-10-15" {
+		t.Errorf("ArchiveCutoff = %q, want 2023-10-15", got)
+	}
+}
+
+func TestBuildArchiveCarry(t *testing.T) {
+	records := []services.DailyRecord{
+		{Date: "2022-01-01",
+			Incomes:  []services.Transaction{{Type: 1, Amount: 1000, Category: "เงินเดือน", UseType: 2, BankName: "กสิกร"}},
+			Expenses: []services.Transaction{{Type: -1, Amount: 200, Category: "อาหาร"}},
+		},
+		{Date: "2022-01-02",
+			Expenses: []services.Transaction{
+				{Type: -1, Amount: 300, Category: services.InvestmentCategory, UseType: 2, BankName: "กสิกร"},
+				{Type: -1, Amount: 100, IsTransfer: true, UseType: 2, BankName: "กสิกร"},
+			},
+			Incomes: []services.Transaction{{Type: 1, Amount: 100, IsTransfer: true}},
+		},
+	}
+	carry := services.BuildArchiveCarry(records)
+	if carry.TotalIncome != 1000 || carry.TotalExpense != 200 || carry.TotalInvested != 300 {
+		t.Errorf("totals = %+v", carry)
+	}
+	if len(carry.Balances) != 2 || carry.Balances[0].Balance != -100 || carry.Balances[1].Balance != 600 {
+		t.Errorf("balances = %+v", carry.Balances)
+	}
+}
+
+func TestArchivedDatesLocked(t *testing.T) {
+	settings := &services.UserSettings{LockedUntil: "2025-01-31", ArchivedThrough: "2023-10-15"}
+	if got := settings.LockedThrough(); got != "2025-01-31" {
+		t.Errorf("LockedThrough = %q", got)
+	}
+	settings = &services.UserSettings{ArchivedThrough: "2023-10-15"}
+	if !settings.IsDateLocked("2023-10-15") || settings.IsDateLocked("2023-10-16") {
+		t.Errorf("archived days should be locked through 2023-10-15")
+	}
+	if !errors.Is(services.ErrDateArchived, services.ErrPeriodLocked) {
+		t.Errorf("ErrDateArchived should be an ErrPeriodLocked")
+	}
+}
@@ -0,0 +1,35 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestMatchDescriptionPrefix(t *testing.T) {
+	list := []services.FrequentDescription{
+		{Description: "กาแฟ", Count: 12, LastAmount: 65},
+		{Description: "ค่ารถไฟฟ้า", Count: 8, LastAmount: 42},
+		{Description: "ข้าวมันไก่", Count: 5, LastAmount: 50},
+		{Description: "กาแฟเย็น", Count: 3, LastAmount: 55},
+		{Description: "กางเกง", Count: 1, LastAmount: 590},
+	}
+
+	got := services.MatchDescriptionPrefix(list, "กา", 5)
+	if len(got) != 2 || got[0].Description != "กาแฟ" || got[1].Description != "กาแฟเย็น" {
+		t.Errorf("prefix กา = %+v", got)
+	}
+
+	// Contains matches come after prefix matches
+	got = services.MatchDescriptionPrefix(list, "รถ", 5)
+	if len(got) != 1 || got[0].Description != "ค่ารถไฟฟ้า" {
+		t.Errorf("contains รถ = %+v", got)
+	}
+
+	if got := services.MatchDescriptionPrefix(list, "กา", 1); len(got) != 1 {
+		t.Errorf("limit not applied: %+v", got)
+	}
+	if got := services.MatchDescriptionPrefix(list, "", 5); got != nil {
+		t.Errorf("empty prefix = %+v", got)
+	}
+}
@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func newBalanceContext() *services.BalanceContext {
+	return &services.BalanceContext{
+		Balances: []services.PaymentBalance{
+			{UseType: 0, Balance: 2000, TotalIncome: 2000},
+			{UseType: 2, BankName: "กสิกร", Balance: 50000, TotalIncome: 58000, TotalExpense: 8000},
+		},
+		Summary: services.BalanceSummary{TotalIncome: 60000, TotalExpense: 8000, Balance: 52000},
+		Day:     "2025-03-01",
+	}
+}
+
+func TestBalanceContextReflectsJustSavedTransaction(t *testing.T) {
+	c := newBalanceContext()
+	coffee := services.Transaction{Type: -1, Amount: 65, Category: "เครื่องดื่ม", UseType: 2, BankName: "กสิกร"}
+	if !c.Apply(services.TransactionCreated, "2025-03-01", coffee, c.Day) {
+		t.Fatal("created transaction should patch the context")
+	}
+
+	text := c.ToAIText()
+	for _, want := range []string{"ยอดรวม:51935", "กสิกร:49935", "รายจ่ายรวม:8065", "วันนี้รับ:0,จ่าย:65"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("follow-up context %q missing %q", text, want)
+		}
+	}
+
+	c.Apply(services.TransactionDeleted, "2025-03-01", coffee, c.Day)
+	if text := c.ToAIText(); !strings.Contains(text, "ยอดรวม:52000") || !strings.Contains(text, "รายจ่ายรวม:8000") || strings.Contains(text, "วันนี้") {
+		t.Errorf("context after delete = %q, want original totals", text)
+	}
+}
+
+func TestBalanceContextNewAccountAndTransfer(t *testing.T) {
+	c := newBalanceContext()
+	c.Apply(services.TransactionCreated, "2025-02-28", services.Transaction{Type: 1, Amount: 1000, Category: "เงินเดือน", UseType: 1, CreditCardName: "KTC"}, c.Day)
+	if text := c.ToAIText(); !strings.Contains(text, "KTC:1000") || strings.Contains(text, "วันนี้") {
+		t.Errorf("backdated entry on new card = %q", text)
+	}
+
+	// Transfer moves money between accounts without counting as income/expense
+	c = newBalanceContext()
+	c.Apply(services.TransactionCreated, c.Day, services.Transaction{Type: -1, Amount: 500, UseType: 2, BankName: "กสิกร", IsTransfer: true}, c.Day)
+	c.Apply(services.TransactionCreated, c.Day, services.Transaction{Type: 1, Amount: 500, UseType: 0, IsTransfer: true}, c.Day)
+	if text := c.ToAIText(); !strings.Contains(text, "เงินสด:2500") || !strings.Contains(text, "รายได้รวม:60000") || !strings.Contains(text, "รายจ่ายรวม:8000") {
+		t.Errorf("context after transfer = %q", text)
+	}
+
+	if c.Apply(services.TransactionUpdated, c.Day, services.Transaction{Type: -1, Amount: 1}, c.Day) {
+		t.Error("updates can't be patched and should force a reload")
+	}
+}
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestBalanceSnapshotOutdated(t *testing.T) {
+	state := services.BalanceSnapshotState{Version: 3, Invalidations: []services.SnapshotInvalidation{
+		{Version: 2, From: "2026-09-10"},
+		{Version: 3, From: "2026-09-20"},
+	}}
+	cases := []struct {
+		date     string
+		version  int64
+		outdated bool
+	}{
+		{"2026-09-05", 0, false}, // before every change
+		{"2026-09-10", 1, true},  // computed before the 09-10 change
+		{"2026-09-15", 2, false}, // computed after it, before the 09-20 one
+		{"2026-09-25", 2, true},
+		{"2026-09-25", 3, false},
+		{"2026-09-25", 4, false}, // state read before a newer snapshot was written
+	}
+	for _, c := range cases {
+		snapshot := services.BalanceSnapshot{Date: c.date, Version: c.version}
+		if got := state.Outdated(&snapshot); got != c.outdated {
+			t.Errorf("snapshot %s v%d: outdated = %v, want %v", c.date, c.version, got, c.outdated)
+		}
+	}
+
+	// Once the history is trimmed, snapshots older than it can't be checked
+	full := services.BalanceSnapshotState{Version: 100}
+	for v := int64(51); v <= 100; v++ {
+		full.Invalidations = append(full.Invalidations, services.SnapshotInvalidation{Version: v, From: "2026-12-31"})
+	}
+	if !full.Outdated(&services.BalanceSnapshot{Date: "2026-01-01", Version: 10}) {
+		t.Error("snapshot older than the kept history counted as current")
+	}
+	if full.Outdated(&services.BalanceSnapshot{Date: "2026-01-01", Version: 50}) {
+		t.Error("snapshot right before the kept history counted as outdated")
+	}
+}
+
+func TestBalanceSnapshotOutdatedAcrossInstances(t *testing.T) {
+	reader := testMongoService(t)
+	writer := testMongoService(t) // another instance: nothing is shared in memory
+	ctx := context.Background()
+	userID := testUserID("snapshot")
+	day := time.Now().AddDate(0, 0, -10).Format("2006-01-02")
+	earlier := time.Now().AddDate(0, 0, -12).Format("2006-01-02")
+
+	if _, err := reader.SaveTransactionOnDate(ctx, userID, &services.TransactionData{Amount: 100, Type: "income", Category: "เงินเดือน"}, day); err != nil {
+		t.Fatal(err)
+	}
+	balances, err := reader.GetBalanceAsOf(ctx, userID, day)
+	if err != nil || len(balances) != 1 || balances[0].Balance != 100 {
+		t.Fatalf("before = %+v, %v", balances, err)
+	}
+
+	// Backdated expense saved through the other instance outdates the snapshot of day
+	if _, err := writer.SaveTransactionOnDate(ctx, userID, &services.TransactionData{Amount: 30, Type: "expense", Category: "อาหาร"}, earlier); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		balances, err = reader.GetBalanceAsOf(ctx, userID, day)
+		if err == nil && len(balances) == 1 && balances[0].Balance == 70 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("snapshot still read after a backdated change: %+v, %v", balances, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,47 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestParseBankSMS(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name    string
+		text    string
+		ok      bool
+		bank    string
+		txType  string
+		amount  float64
+		balance float64
+		date    string
+	}{
+		{"kbank withdraw", "15/10/67 13:45 บช X123456X ถอน/โอน 1,500.00 บ. คงเหลือ 10,250.50 บ. KBank", true, "กสิกร", "expense", 1500, 10250.5, "2026-10-15"},
+		{"kbank receive", "KBank: 15/10/67 09:12 บช X123456X รับโอนจาก X987654X 2,000.00 บ. คงเหลือ 12,250.50 บ.", true, "กสิกร", "income", 2000, 12250.5, "2026-10-15"},
+		{"scb deposit", "SCB: เงินเข้า 5,000.00บ. บ/ช x123456 15/10@13:45 ใช้ได้ 20,000.00บ", true, "ไทยพาณิชย์", "income", 5000, 20000, "2026-10-15"},
+		{"ktc card", "KTC: ใช้จ่าย 1,234.50 บาท บัตร xx1234 ที่ STARBUCKS 16/10 14:20", true, "KTC", "expense", 1234.5, 0, "2026-10-16"},
+		{"no bank", "ถอนเงิน 500.00", false, "", "", 0, 0, ""},
+		{"plain chat", "กินข้าว 50", false, "", "", 0, 0, ""},
+		{"typed note", "จ่ายค่าไฟ 1,250.50 บาท ผ่าน scb", false, "", "", 0, 0, ""},
+		{"unmasked account", "โอนเงิน 1,250.50 บาท บัญชี 123456 scb คงเหลือ 5,000.00", false, "", "", 0, 0, ""},
+		{"no balance or time", "SCB ชำระ 1,250.50 บาท บ/ช x1234", false, "", "", 0, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sms, ok := services.ParseBankSMS(tt.text, now)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if sms.Bank != tt.bank || sms.Type != tt.txType || sms.Amount != tt.amount || sms.Balance != tt.balance || sms.Date != tt.date {
+				t.Errorf("got %+v", sms)
+			}
+		})
+	}
+}
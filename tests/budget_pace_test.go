@@ -0,0 +1,40 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestCalculateBudgetPace(t *testing.T) {
+	// 15 of 30 days in June = 50% elapsed
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name   string
+		spent  float64
+		budget float64
+		status string
+	}{
+		{"on track", 5000, 10000, "on_track"},
+		{"ahead", 6500, 10000, "ahead"},
+		{"over", 10500, 10000, "over"},
+	}
+
+	for _, tt := range tests {
+		pace := services.CalculateBudgetPace(tt.spent, tt.budget, now)
+		if pace == nil {
+			t.Fatalf("%s: expected pace", tt.name)
+		}
+		if pace.Status != tt.status {
+			t.Errorf("%s: status = %s, want %s", tt.name, pace.Status, tt.status)
+		}
+		if pace.ElapsedPercent != 50 {
+			t.Errorf("%s: elapsed = %.1f, want 50", tt.name, pace.ElapsedPercent)
+		}
+	}
+
+	if services.CalculateBudgetPace(100, 0, now) != nil {
+		t.Error("expected nil pace without budget")
+	}
+}
@@ -0,0 +1,32 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestFindBudgetStatus(t *testing.T) {
+	statuses := []services.BudgetStatus{{Category: "อาหาร", Percentage: 62}, {Category: "เดินทาง"}}
+	if st := services.FindBudgetStatus(statuses, "อาหาร"); st == nil || st.Percentage != 62 {
+		t.Errorf("FindBudgetStatus(อาหาร) = %+v", st)
+	}
+	if st := services.FindBudgetStatus(statuses, "ช้อปปิ้ง"); st != nil {
+		t.Errorf("unbudgeted category should return nil, got %+v", st)
+	}
+}
+
+func TestBudgetProgressText(t *testing.T) {
+	tests := []struct {
+		status services.BudgetStatus
+		want   string
+	}{
+		{services.BudgetStatus{Category: "อาหาร", Budget: 5000, Spent: 3100, Remaining: 1900, Percentage: 62}, "📊 งบอาหารใช้ไป 62%"},
+		{services.BudgetStatus{Category: "อาหาร", Budget: 5000, Spent: 5600, Remaining: -600, Percentage: 112, IsOverBudget: true}, "⚠️ งบอาหารเกินแล้ว 600 บาท (112%)"},
+	}
+	for _, tt := range tests {
+		if got := tt.status.ProgressText(); got != tt.want {
+			t.Errorf("ProgressText() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestParseArithmetic(t *testing.T) {
+	cases := []struct {
+		text  string
+		expr  string
+		value float64
+		label string
+		out   string
+	}{
+		{"150*3", "150×3", 450, "", "450"},
+		{"ค่าอาหาร 120+80+65", "120+80+65", 265, "ค่าอาหาร", "ค่าอาหาร 265"},
+		{"(100+50)x2 ค่ารถ", "(100+50)×2", 300, "ค่ารถ", "300 ค่ารถ"},
+		{"หาร 4 คน 1,200 ÷ 4", "1,200÷4", 300, "หาร 4 คน", "หาร 4 คน 300"},
+		{"ส่วนลด 500 - 50", "500-50", 450, "ส่วนลด", "ส่วนลด 450"},
+		{"15/10 ข้าว 50+20", "50+20", 70, "15/10 ข้าว", "15/10 ข้าว 70"},
+		{"๑๐๐+๕๐", "100+50", 150, "", "150"},
+		{"100 / 3", "100÷3", 33.33, "", "33.33"},
+	}
+	for _, c := range cases {
+		calc, ok := services.ParseArithmetic(c.text)
+		if !ok || len(calc.Parts) != 1 {
+			t.Errorf("ParseArithmetic(%q) = %+v, %v", c.text, calc, ok)
+			continue
+		}
+		if calc.Parts[0].Expr != c.expr || calc.Parts[0].Value != c.value || calc.Label != c.label || calc.Text != c.out {
+			t.Errorf("ParseArithmetic(%q) = %+v", c.text, calc)
+		}
+	}
+
+	// Dates, phone numbers, ranges and sizes aren't math
+	for _, text := range []string{"ข้าว 50", "15/10", "10/3", "2026-09-01", "โทร 081-234-5678", "1,500-2,000 บาท", "เสื้อ 2xl 350", "10 ÷ 0", "(100+50",
+		"ซื้อชั้นวาง 60x120 ซม. 3,500", "ไม้อัด 4x8 ฟุต 890 บาท", "กรอบรูป 20x30cm", "ค่าอาหาร 120+80 ทิป 20"} {
+		if calc, ok := services.ParseArithmetic(text); ok {
+			t.Errorf("ParseArithmetic(%q) = %+v", text, calc)
+		}
+	}
+}
+
+func TestApplyArithmetic(t *testing.T) {
+	calc, _ := services.ParseArithmetic("ค่าอาหาร 120+80+65")
+	txs := []services.TransactionData{{Description: "ค่าอาหาร", Amount: 265}}
+	services.ApplyArithmetic(calc, txs)
+	if txs[0].Amount != 265 || txs[0].Description != "ค่าอาหาร (120+80+65)" {
+		t.Errorf("single = %+v", txs[0])
+	}
+
+	// An amount that isn't the result is the AI's own reading and stays as is
+	txs = []services.TransactionData{{Description: "ค่าอาหาร", Amount: 256}}
+	services.ApplyArithmetic(calc, txs)
+	if txs[0].Amount != 256 || txs[0].Description != "ค่าอาหาร" {
+		t.Errorf("mismatch = %+v", txs[0])
+	}
+
+	calc, _ = services.ParseArithmetic("ข้าว 50+20 น้ำ 10*2")
+	txs = []services.TransactionData{{Description: "ข้าว", Amount: 70}, {Description: "น้ำ", Amount: 20}}
+	services.ApplyArithmetic(calc, txs)
+	if txs[0].Description != "ข้าว (50+20)" || txs[1].Description != "น้ำ (10×2)" {
+		t.Errorf("multiple = %+v", txs)
+	}
+}
@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"encoding/hex"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNextMonthDay(t *testing.T) {
+	cases := []struct {
+		day       int
+		now, want string
+	}{
+		{15, "2026-10-16", "2026-11-15"},
+		{16, "2026-10-16", "2026-10-16"}, // today counts
+		{31, "2026-04-10", "2026-04-30"},
+		{30, "2026-02-01", "2026-02-28"},
+		{30, "2028-02-01", "2028-02-29"}, // leap year
+		{31, "2026-12-31", "2026-12-31"},
+		{29, "2026-03-01", "2026-03-29"},
+	}
+	for _, c := range cases {
+		now, _ := time.Parse("2006-01-02", c.now)
+		if got := services.NextMonthDay(c.day, now).Format("2006-01-02"); got != c.want {
+			t.Errorf("NextMonthDay(%d, %s) = %s, want %s", c.day, c.now, got, c.want)
+		}
+	}
+}
+
+// icalEvents returns the DTSTART and RRULE of every event in a feed, keyed by UID
+func icalEvents(feed string) map[string][2]string {
+	events := map[string][2]string{}
+	uid, start := "", ""
+	for _, line := range strings.Split(strings.ReplaceAll(feed, "\r\n ", ""), "\r\n") {
+		switch {
+		case strings.HasPrefix(line, "UID:"):
+			uid = strings.TrimPrefix(line, "UID:")
+		case strings.HasPrefix(line, "DTSTART;VALUE=DATE:"):
+			start = strings.TrimPrefix(line, "DTSTART;VALUE=DATE:")
+		case strings.HasPrefix(line, "RRULE:"):
+			events[uid] = [2]string{start, strings.TrimPrefix(line, "RRULE:")}
+		}
+	}
+	return events
+}
+
+var ruleDaysPattern = regexp.MustCompile(`BYMONTHDAY=([-\d,]+)`)
+
+// ruleDay expands a BYMONTHDAY (with BYSETPOS=-1) monthly rule for one month
+func ruleDay(t *testing.T, rule string, year int, month time.Month) int {
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	m := ruleDaysPattern.FindStringSubmatch(rule)
+	if m == nil {
+		t.Fatalf("rule without BYMONTHDAY: %s", rule)
+	}
+	var days []int
+	for _, s := range strings.Split(m[1], ",") {
+		d, _ := strconv.Atoi(s)
+		if d < 0 {
+			d = lastDay + 1 + d
+		}
+		if d <= lastDay {
+			days = append(days, d)
+		}
+	}
+	if len(days) == 0 {
+		return 0
+	}
+	if strings.Contains(rule, "BYSETPOS=-1") {
+		return days[len(days)-1]
+	}
+	if len(days) != 1 {
+		t.Fatalf("rule %s yields %v in %d-%02d", rule, days, year, month)
+	}
+	return days[0]
+}
+
+func TestBuildICalFeed(t *testing.T) {
+	now := time.Date(2026, 1, 31, 9, 0, 0, 0, time.UTC)
+	cards := []services.CardAccount{
+		{CardName: "KTC", StatementDay: 30, DueDay: 15},
+		{CardName: "UOB", StatementDay: 29, DueDay: 31},
+	}
+	subs := []services.Subscription{
+		{ID: primitive.NewObjectID(), Name: "Netflix", Amount: 419, Status: "active", NextRenewal: "2026-02-05", UseType: 1, CreditCardName: "KTC"},
+		{ID: primitive.NewObjectID(), Name: "Spotify", Amount: 149, Status: "cancelled", NextRenewal: "2026-02-07"},
+	}
+	feed := services.BuildICalFeed(subs, cards, now)
+	if strings.Contains(feed, "Spotify") {
+		t.Error("cancelled subscription in feed")
+	}
+	for _, line := range strings.Split(feed, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line not folded: %q", line)
+		}
+	}
+
+	events := icalEvents(feed)
+	if len(events) != 5 {
+		t.Fatalf("events = %v", events)
+	}
+	wantRules := map[int]string{
+		15: "FREQ=MONTHLY;BYMONTHDAY=15",
+		29: "FREQ=MONTHLY;BYMONTHDAY=28,29;BYSETPOS=-1",
+		30: "FREQ=MONTHLY;BYMONTHDAY=28,29,30;BYSETPOS=-1",
+		31: "FREQ=MONTHLY;BYMONTHDAY=-1",
+	}
+	days := map[string]int{}
+	for _, card := range cards {
+		name := hex.EncodeToString([]byte(card.CardName))
+		days["card-due-"+name+"@satisatang"] = card.DueDay
+		days["card-statement-"+name+"@satisatang"] = card.StatementDay
+	}
+	for uid, day := range days {
+		start, rule := events[uid][0], events[uid][1]
+		if rule != wantRules[day] {
+			t.Errorf("%s: rule %s, want %s", uid, rule, wantRules[day])
+		}
+		// DTSTART is the first occurrence, and every later one lands where NextMonthDay puts it
+		if want := services.NextMonthDay(day, now).Format("20060102"); start != want {
+			t.Errorf("%s: DTSTART %s, want %s", uid, start, want)
+		}
+		for i := 0; i < 24; i++ {
+			first := time.Date(2026, time.February+time.Month(i), 1, 0, 0, 0, 0, time.UTC)
+			want := services.NextMonthDay(day, first)
+			if got := ruleDay(t, rule, first.Year(), first.Month()); got != want.Day() {
+				t.Errorf("%s in %s: rule gives day %d, want %d", uid, first.Format("2006-01"), got, want.Day())
+			}
+		}
+	}
+	for uid, e := range events {
+		if strings.HasPrefix(uid, "sub-") && (e[0] != "20260205" || e[1] != "FREQ=MONTHLY;BYMONTHDAY=5") {
+			t.Errorf("subscription event = %v", e)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestCardCycle(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	cases := []struct {
+		statement, due  int
+		now             string
+		from, close, dd string
+	}{
+		{20, 5, "2026-10-25", "2026-09-21", "2026-10-20", "2026-11-05"},
+		{20, 5, "2026-10-20", "2026-08-21", "2026-09-20", "2026-10-05"}, // statement day itself is still open
+		{25, 28, "2026-10-26", "2026-09-26", "2026-10-25", "2026-10-28"},
+		{31, 15, "2026-03-05", "2026-02-01", "2026-02-28", "2026-03-15"},
+	}
+	for _, c := range cases {
+		from, closeDate, due := services.CardCycle(c.statement, c.due, day(c.now))
+		got := from.Format("2006-01-02") + " " + closeDate.Format("2006-01-02") + " " + due.Format("2006-01-02")
+		if want := c.from + " " + c.close + " " + c.dd; got != want {
+			t.Errorf("cycle %d/%d on %s: got %s, want %s", c.statement, c.due, c.now, got, want)
+		}
+	}
+}
+
+func TestCardStatementReminders(t *testing.T) {
+	card := services.CardAccount{CardName: "KTC", StatementDay: 20, DueDay: 5}
+	results := []services.SearchResult{
+		{Date: "2026-09-10", Transaction: services.Transaction{Type: -1, Amount: 999}}, // previous cycle
+		{Date: "2026-09-25", Transaction: services.Transaction{Type: -1, Amount: 3000}},
+		{Date: "2026-10-01", Transaction: services.Transaction{Type: 1, Amount: 200}}, // refund
+		{Date: "2026-10-18", Transaction: services.Transaction{Type: -1, Amount: 1500}},
+		{Date: "2026-10-22", Transaction: services.Transaction{Type: -1, Amount: 700}}, // next cycle
+	}
+
+	now := time.Date(2026, 10, 25, 9, 0, 0, 0, time.UTC)
+	st := services.BuildCardStatement(card, results, now)
+	if st.Outstanding != 4300 || st.DueDate != "2026-11-05" {
+		t.Fatalf("statement: got %+v", st)
+	}
+	notice, ok := st.ReminderNotice(now)
+	if !ok || !strings.HasSuffix(notice.Key, ":closed") || len(notice.Actions) != 2 ||
+		!strings.Contains(notice.Actions[0].Data, "action=card_paid&card=KTC") {
+		t.Errorf("closed notice: got %+v", notice)
+	}
+
+	levels := map[string]string{"2026-11-03": ":soon", "2026-11-05": ":due", "2026-11-07": ":overdue:2026-11-07"}
+	for date, suffix := range levels {
+		now, _ := time.Parse("2006-01-02", date)
+		notice, ok := services.BuildCardStatement(card, results, now).ReminderNotice(now)
+		if !ok || !strings.HasSuffix(notice.Key, suffix) {
+			t.Errorf("%s: got %+v, want key ending %s", date, notice, suffix)
+		}
+	}
+
+	// A partial payment lowers the amount, paying the rest stops the reminders
+	now = time.Date(2026, 11, 3, 9, 0, 0, 0, time.UTC)
+	paid := append(results, services.SearchResult{Date: "2026-10-28", Transaction: services.Transaction{Type: 1, Amount: 1000, IsTransfer: true}})
+	if st := services.BuildCardStatement(card, paid, now); st.Outstanding != 3300 {
+		t.Errorf("partial payment: got %+v", st)
+	}
+	paid = append(paid, services.SearchResult{Date: "2026-11-02", Transaction: services.Transaction{Type: 1, Amount: 3300, IsTransfer: true}})
+	if _, ok := services.BuildCardStatement(card, paid, now).ReminderNotice(now); ok {
+		t.Error("paid statement should not be reminded")
+	}
+
+	// Last month's bill paid mid-cycle settles the previous statement, not this one
+	lastBill := append(results, services.SearchResult{Date: "2026-10-05", Transaction: services.Transaction{Type: 1, Amount: 2500, IsTransfer: true}})
+	if st := services.BuildCardStatement(card, lastBill, time.Date(2026, 10, 25, 9, 0, 0, 0, time.UTC)); st.Outstanding != 4300 || st.Credited != 200 {
+		t.Errorf("previous bill paid in cycle: got %+v", st)
+	}
+
+	if services.BuildCardStatement(services.CardAccount{CardName: "UOB", DueDay: 5}, results, now) != nil {
+		t.Error("card without statement day should have no statement")
+	}
+}
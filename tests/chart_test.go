@@ -0,0 +1,30 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestChartRange(t *testing.T) {
+	now := time.Date(2025, 3, 13, 15, 30, 0, 0, time.UTC) // Thursday
+	tests := []struct {
+		name            string
+		query           *services.QueryFilter
+		from, to, label string
+	}{
+		{"nil", nil, "2025-03-01", "2025-03-13", "เดือนนี้"},
+		{"week", &services.QueryFilter{Period: "week"}, "2025-03-10", "2025-03-13", "สัปดาห์นี้"},
+		{"days", &services.QueryFilter{Days: 7, Period: "year"}, "2025-03-07", "2025-03-13", "7 วันล่าสุด"},
+		{"dates", &services.QueryFilter{DateFrom: "2025-02-01", DateTo: "2025-02-28", Days: 7}, "2025-02-01", "2025-02-28", "1/2 - 28/2/2025"},
+		{"open end", &services.QueryFilter{DateFrom: "2025-03-05"}, "2025-03-05", "2025-03-13", "5/3 - 13/3/2025"},
+		{"one day", &services.QueryFilter{DateFrom: "2025-03-05", DateTo: "2025-03-05"}, "2025-03-05", "2025-03-05", "5/3/2025"},
+	}
+	for _, tt := range tests {
+		from, to, label := services.ChartRange(tt.query, now)
+		if got := from.Format("2006-01-02") + " " + to.Format("2006-01-02"); got != tt.from+" "+tt.to || label != tt.label {
+			t.Errorf("%s: ChartRange = %s %q, want %s %s %q", tt.name, got, label, tt.from, tt.to, tt.label)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestBuildCloseReport(t *testing.T) {
+	records := []services.DailyRecord{
+		{Date: "2026-09-01", Incomes: []services.Transaction{{Amount: 30000, Category: "เงินเดือน", UseType: 2, BankName: "กสิกร"}}},
+		{Date: "2026-09-05", Expenses: []services.Transaction{
+			{Amount: 600, Category: "อาหาร", UseType: 1, CreditCardName: "KTC"},
+			{Amount: 400, Category: "อาหาร"},
+			{Amount: 1000, Category: "เดินทาง", UseType: 2, BankName: "กสิกร"},
+			{Amount: 5000, Category: services.InvestmentCategory, UseType: 2, BankName: "กสิกร"},
+			{Amount: 2000, IsTransfer: true, UseType: 2, BankName: "กสิกร"},
+		}},
+		{Date: "2026-09-20", Incomes: []services.Transaction{{Amount: 1500, Category: services.InvestmentCategory, UseType: 2, BankName: "กสิกร"}}},
+		{Date: "2026-10-01", Expenses: []services.Transaction{{Amount: 999, Category: "อาหาร"}}},
+	}
+	budgets := []services.Budget{{Category: "อาหาร", Amount: 800}, {Category: "เดินทาง", Amount: 2000}}
+	r, err := services.BuildCloseReport("2026-09", records, budgets, "2026-09-30", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.SchemaVersion != services.CloseReportSchemaVersion || r.From != "2026-09-01" || r.To != "2026-09-30" || !r.Closed {
+		t.Errorf("header = %+v", r)
+	}
+	if r.Totals.Income != 30000 || r.Totals.Expense != 2000 || r.Totals.Net != 28000 || r.Totals.Invested != 3500 || r.Totals.InvestmentBought != 5000 || r.Totals.InvestmentSold != 1500 || r.Totals.TransactionCount != 4 {
+		t.Errorf("totals = %+v", r.Totals)
+	}
+	if len(r.Categories) != 3 || r.Categories[0].Category != "อาหาร" || r.Categories[0].Amount != 1000 || r.Categories[0].Percent != 50 || r.Categories[2].Type != "income" {
+		t.Errorf("categories = %+v", r.Categories)
+	}
+	if len(r.Accounts) != 3 {
+		t.Fatalf("accounts = %+v", r.Accounts)
+	}
+	for _, a := range r.Accounts {
+		if a.Type == "bank" && (a.In != 31500 || a.Out != 8000 || a.Net != 23500) {
+			t.Errorf("bank = %+v", a)
+		}
+	}
+	if r.Budgets[0].Category != "อาหาร" || !r.Budgets[0].Over || r.Budgets[0].Percent != 125 || r.Budgets[1].Over {
+		t.Errorf("budgets = %+v", r.Budgets)
+	}
+
+	if _, err := services.BuildCloseReport("09/2026", nil, nil, "", time.Now()); err == nil {
+		t.Error("bad month accepted")
+	}
+}
+
+func TestCloseReportEmptyArrays(t *testing.T) {
+	r, _ := services.BuildCloseReport("2026-02", nil, nil, "", time.Now())
+	b, _ := json.Marshal(r)
+	for _, field := range []string{`"categories":[]`, `"accounts":[]`, `"budgets":[]`, `"to":"2026-02-28"`, `"closed":false`} {
+		if !strings.Contains(string(b), field) {
+			t.Errorf("json missing %s: %s", field, b)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestParseDailyLimitCommand(t *testing.T) {
+	cases := []struct {
+		text   string
+		ok     bool
+		action string
+		limit  float64
+	}{
+		{"จำกัดวันละ 500", true, "set", 500},
+		{"จำกัดรายวัน 1,200 บาท", true, "set", 1200},
+		{"งบวันละ ๓๐๐", true, "set", 300},
+		{"จำกัดวันละ", true, "show", 0},
+		{"จำกัดวันละ 0", true, "off", 0},
+		{"ยกเลิกจำกัดวันละ", true, "off", 0},
+		{"จำกัดวันละ เยอะๆ", false, "", 0},
+		{"ข้าวมันไก่ 50", false, "", 0},
+	}
+	for _, c := range cases {
+		cmd, ok := services.ParseDailyLimitCommand(c.text)
+		if ok != c.ok || cmd.Action != c.action || cmd.Limit != c.limit {
+			t.Errorf("%q: got %+v ok=%v, want %s %.0f ok=%v", c.text, cmd, ok, c.action, c.limit, c.ok)
+		}
+	}
+}
+
+func TestDailyLimitStatus(t *testing.T) {
+	if services.NewDailyLimitStatus(100, 0) != nil {
+		t.Error("no limit should give nil status")
+	}
+
+	st := services.NewDailyLimitStatus(320, 500)
+	if st.Over || st.Percent != 64 {
+		t.Errorf("320/500: got %+v", st)
+	}
+	if got := st.ProgressText(); got != "📆 วันนี้ใช้ไป 320/500 บาท (64%)" {
+		t.Errorf("progress text: %q", got)
+	}
+
+	st = services.NewDailyLimitStatus(620.004, 500)
+	if !st.Over || st.Spent != 620 {
+		t.Errorf("620/500: got %+v", st)
+	}
+	if got := st.ProgressText(); got != "⚠️ วันนี้ใช้ไป 620/500 บาท เกินวงเงินรายวัน 120 บาท" {
+		t.Errorf("over text: %q", got)
+	}
+}
+
+func TestDailySpendingSkipsTransfersAndInvestments(t *testing.T) {
+	record := services.DailyRecord{Expenses: []services.Transaction{
+		{Amount: 120, Category: "อาหาร"},
+		{Amount: 10000, Category: "โอนเงิน", IsTransfer: true},
+		{Amount: 5000, Category: services.InvestmentCategory},
+		{Amount: 80, Category: "เดินทาง"},
+	}}
+	if got := services.DailySpending(record); got != 200 {
+		t.Errorf("daily spending = %.2f, want 200", got)
+	}
+	if st := services.NewDailyLimitStatus(services.DailySpending(record), 500); st.Over {
+		t.Errorf("a transfer to savings shouldn't exceed the cap: %+v", st)
+	}
+}
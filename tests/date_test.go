@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestParseThaiDate(t *testing.T) {
+	// Wednesday 15 Oct 2025
+	now := time.Date(2025, 10, 15, 14, 30, 0, 0, time.Local)
+
+	cases := []struct {
+		input    string
+		from, to string
+	}{
+		{"ข้าวเมื่อวาน 50", "2025-10-14", "2025-10-14"},
+		{"เมื่อวานซืนเติมน้ำมัน", "2025-10-13", "2025-10-13"},
+		{"3 วันก่อน ค่าหมอ", "2025-10-12", "2025-10-12"},
+		{"จันทร์ที่แล้วกินข้าว", "2025-10-06", "2025-10-06"},
+		{"วันจันทร์ซื้อของ", "2025-10-13", "2025-10-13"},
+		{"วันอาทิตย์ที่แล้ว", "2025-10-12", "2025-10-12"},
+		{"อาทิตย์ที่แล้วใช้เท่าไหร่", "2025-10-06", "2025-10-12"},
+		{"ค่าเช่าต้นเดือน", "2025-10-01", "2025-10-10"},
+		{"เดือนที่แล้วใช้อะไรบ้าง", "2025-09-01", "2025-09-30"},
+		{"15 ต.ค. 67", "2024-10-15", "2024-10-15"},
+		{"1/9/2568 ค่าไฟ", "2025-09-01", "2025-09-01"},
+		{"2568-09-01", "2025-09-01", "2025-09-01"},
+		{"20 ธันวาคม", "2024-12-20", "2024-12-20"},
+	}
+
+	for _, c := range cases {
+		r, ok := services.ParseThaiDate(c.input, now)
+		if !ok || r.FromString() != c.from || r.ToString() != c.to {
+			t.Errorf("ParseThaiDate(%q) = %s..%s (%v); want %s..%s", c.input, r.FromString(), r.ToString(), ok, c.from, c.to)
+		}
+	}
+
+	if _, ok := services.ParseThaiDate("ข้าวมันไก่ 50", now); ok {
+		t.Errorf("ParseThaiDate should not find a date in plain entry")
+	}
+}
+
+func TestResolveTransactionDate(t *testing.T) {
+	now := time.Date(2025, 10, 15, 14, 30, 0, 0, time.Local)
+
+	if date, mismatch := services.ResolveTransactionDate("ข้าว 50", "", now); date != "2025-10-15" || mismatch {
+		t.Errorf("plain entry = %s, %v; want today", date, mismatch)
+	}
+	if date, mismatch := services.ResolveTransactionDate("เมื่อวานกาแฟ 60", "2025-10-15", now); date != "2025-10-14" || !mismatch {
+		t.Errorf("yesterday entry = %s, %v; want 2025-10-14 with mismatch", date, mismatch)
+	}
+	if date, _ := services.ResolveTransactionDate("เดือนที่แล้วใช้อะไรบ้าง", "", now); date != "2025-10-15" {
+		t.Errorf("month range must not backdate entry, got %s", date)
+	}
+}
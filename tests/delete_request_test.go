@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseDeleteRequest(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		text    string
+		ok      bool
+		keyword string
+		amount  float64
+		from    string
+	}{
+		{"ลบค่ากาแฟเมื่อวาน", true, "ค่ากาแฟ", 0, "2026-10-15"},
+		{"ลบ กาแฟ 65 บาท วันนี้", true, "กาแฟ", 65, "2026-10-16"},
+		{"ลบรายการแท็กซี่ 14/10", true, "แท็กซี่", 0, "2026-10-14"},
+		{"ลบข้าวมันไก่", true, "ข้าวมันไก่", 0, ""},
+		{"ลบรายการล่าสุด", false, "", 0, ""},
+		{"ลบงบอาหาร", false, "", 0, ""},
+		{"ลบเมื่อวาน", false, "", 0, ""},
+		{"กาแฟ 65", false, "", 0, ""},
+	}
+	for _, c := range cases {
+		req, ok := services.ParseDeleteRequest(c.text, now)
+		if ok != c.ok {
+			t.Errorf("%q: ok=%v, want %v", c.text, ok, c.ok)
+			continue
+		}
+		if ok && (req.Keyword != c.keyword || req.Amount != c.amount || req.From != c.from) {
+			t.Errorf("%q: got %+v", c.text, req)
+		}
+	}
+}
+
+func TestFilterDeleteCandidates(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	req, _ := services.ParseDeleteRequest("ลบค่ากาแฟเมื่อวาน", now)
+	tx := func(desc string, amount float64) services.Transaction {
+		return services.Transaction{ID: primitive.NewObjectID(), Type: -1, Description: desc, Amount: amount}
+	}
+	results := []services.SearchResult{
+		{Date: "2026-10-15", Transaction: tx("กาแฟอเมซอน", 65)},
+		{Date: "2026-10-15", Transaction: tx("ข้าวผัด", 60)},
+		{Date: "2026-10-14", Transaction: tx("กาแฟ", 55)},
+		{Date: "2026-10-15", Transaction: services.Transaction{Type: -1, Description: "กาแฟ", Amount: 100, IsTransfer: true}},
+		{Date: "2026-10-15", Transaction: tx("กาแฟ", 45), Archived: true},
+	}
+	got := services.FilterDeleteCandidates(req, results)
+	if len(got) != 1 || got[0].Transaction.Description != "กาแฟอเมซอน" {
+		t.Errorf("candidates: got %+v", got)
+	}
+
+	req, _ = services.ParseDeleteRequest("ลบกาแฟ 55", now)
+	if got := services.FilterDeleteCandidates(req, results); len(got) != 1 || got[0].Date != "2026-10-14" {
+		t.Errorf("amount filter: got %+v", got)
+	}
+}
@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestClaimDownloadLink(t *testing.T) {
+	mongoService := testMongoService(t)
+	ctx := context.Background()
+	userID := testUserID("download")
+
+	link, err := mongoService.CreateDownloadLink(ctx, userID, "exports/test.xlsx", "test.xlsx", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl := time.Until(link.ExpiresAt); ttl < services.DownloadLinkTTL-time.Minute || ttl > services.DownloadLinkTTL {
+		t.Errorf("expires in %v, want %v", ttl, services.DownloadLinkTTL)
+	}
+
+	claimed, err := mongoService.ClaimDownloadLink(ctx, link.Token)
+	if err != nil || !claimed.Downloaded || claimed.DownloadedAt == nil || claimed.ObjectPath != link.ObjectPath {
+		t.Fatalf("first claim = %+v, %v", claimed, err)
+	}
+	// One download only
+	if _, err := mongoService.ClaimDownloadLink(ctx, link.Token); !errors.Is(err, services.ErrDownloadUsed) {
+		t.Errorf("second claim = %v, want ErrDownloadUsed", err)
+	}
+	// A download that failed to stream can be retried
+	if err := mongoService.ReleaseDownloadLink(ctx, link.Token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mongoService.ClaimDownloadLink(ctx, link.Token); err != nil {
+		t.Errorf("claim after release = %v", err)
+	}
+	if _, err := mongoService.ClaimDownloadLink(ctx, "no-such-token"); !errors.Is(err, services.ErrDownloadNotFound) {
+		t.Errorf("unknown token = %v", err)
+	}
+
+	// Unused but past its lifetime
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(os.Getenv("TEST_MONGODB_URI")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(ctx)
+	expired := services.DownloadLink{Token: "expired-" + userID, LineID: userID, ObjectPath: "exports/old.pdf", Filename: "old.pdf",
+		ExpiresAt: time.Now().Add(-time.Minute), CreatedAt: time.Now().Add(-services.DownloadLinkTTL)}
+	if _, err := client.Database("satistang_test").Collection("download_links").InsertOne(ctx, expired); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := mongoService.ClaimDownloadLink(ctx, expired.Token); !errors.Is(err, services.ErrDownloadExpired) || got.Downloaded {
+		t.Errorf("expired claim = %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,31 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestParseSentryDSN(t *testing.T) {
+	tests := []struct {
+		dsn      string
+		endpoint string
+		key      string
+		ok       bool
+	}{
+		{"https://abc123@o42.ingest.sentry.io/4501", "https://o42.ingest.sentry.io/api/4501/store/", "abc123", true},
+		{"https://key@sentry.example.com/tools/7", "https://sentry.example.com/tools/api/7/store/", "key", true},
+		{"https://sentry.io/4501", "", "", false},
+		{"https://key@sentry.io/", "", "", false},
+	}
+	for _, tt := range tests {
+		endpoint, key, err := services.ParseSentryDSN(tt.dsn)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: err = %v", tt.dsn, err)
+			continue
+		}
+		if endpoint != tt.endpoint || key != tt.key {
+			t.Errorf("%s: got %s %s", tt.dsn, endpoint, key)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestParseExportPassword(t *testing.T) {
+	cases := []struct {
+		text      string
+		password  string
+		requested bool
+	}{
+		{"export excel ใส่รหัส 1234", "1234", true},
+		{"ขอไฟล์ pdf รหัสผ่าน: abc123", "abc123", true},
+		{"export password excel", "", true},
+		{"export excel", "", false},
+	}
+	for _, c := range cases {
+		password, requested := services.ParseExportPassword(c.text)
+		if password != c.password || requested != c.requested {
+			t.Errorf("ParseExportPassword(%q) = %q, %v", c.text, password, requested)
+		}
+	}
+}
+
+func TestRedactExportPassword(t *testing.T) {
+	cases := map[string]string{
+		"export excel ใส่รหัส 1234":   "export excel ใส่รหัส ****",
+		"ขอไฟล์ pdf รหัสผ่าน: abc123": "ขอไฟล์ pdf รหัสผ่าน: ****",
+		"export password excel":       "export password excel",
+		"export excel":                "export excel",
+	}
+	for text, want := range cases {
+		got := services.RedactExportPassword(text)
+		if got != want {
+			t.Errorf("RedactExportPassword(%q) = %q, want %q", text, got, want)
+		}
+		// Redacted text still asks for protection, and the password is gone
+		password, requested := services.ParseExportPassword(text)
+		if _, stillRequested := services.ParseExportPassword(got); stillRequested != requested {
+			t.Errorf("redacted %q changed the protection request", got)
+		}
+		if password != "" && strings.Contains(got, password) {
+			t.Errorf("redacted %q still has password %q", got, password)
+		}
+	}
+}
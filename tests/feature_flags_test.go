@@ -0,0 +1,41 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestParseFeatureFlags(t *testing.T) {
+	flags := services.ParseFeatureFlags(" two_stage_ai:20, draft_mode ,vector_search:150,bad:x,")
+	want := map[string]int{"two_stage_ai": 20, "draft_mode": 100, "vector_search": 100}
+	if len(flags) != len(want) {
+		t.Fatalf("flags = %v", flags)
+	}
+	for name, p := range want {
+		if flags[name] != p {
+			t.Errorf("%s = %d, want %d", name, flags[name], p)
+		}
+	}
+}
+
+func TestRolloutBucket(t *testing.T) {
+	if services.RolloutBucket("two_stage_ai", "U1") != services.RolloutBucket("two_stage_ai", "U1") {
+		t.Error("bucket must be stable")
+	}
+	// Roughly 20% of users fall below 20
+	in := 0
+	for i := 0; i < 1000; i++ {
+		b := services.RolloutBucket("two_stage_ai", fmt.Sprintf("U%d", i))
+		if b < 0 || b > 99 {
+			t.Fatalf("bucket out of range: %d", b)
+		}
+		if b < 20 {
+			in++
+		}
+	}
+	if in < 150 || in > 250 {
+		t.Errorf("20%% rollout picked %d of 1000 users", in)
+	}
+}
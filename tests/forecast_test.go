@@ -0,0 +1,43 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestIsForecastQuery(t *testing.T) {
+	for _, text := range []string{"คาดการณ์", "สิ้นเดือนจะเหลือเท่าไหร่", "forecast"} {
+		if !services.IsForecastQuery(text) {
+			t.Errorf("IsForecastQuery(%q) = false", text)
+		}
+	}
+	if services.IsForecastQuery("กาแฟ 65") {
+		t.Error("IsForecastQuery(กาแฟ 65) = true")
+	}
+}
+
+func TestBuildCashFlowForecast(t *testing.T) {
+	now := time.Date(2026, 10, 10, 9, 0, 0, 0, time.Local)
+	subs := []services.Subscription{
+		{Name: "Netflix", Amount: 419, Status: "active", LastCharged: "2026-10-05", NextRenewal: "2026-11-05"},
+		{Name: "Spotify", Amount: 149, Status: "active", LastCharged: "2026-09-20", NextRenewal: "2026-10-20"},
+		{Name: "iCloud", Amount: 35, Status: "active", LastCharged: "2026-09-10", NextRenewal: "2026-10-10"},
+		{Name: "YouTube", Amount: 179, Status: "cancelled", NextRenewal: "2026-10-15"},
+	}
+	// 5,419 spent so far, 419 of it Netflix -> 500/day discretionary
+	f := services.BuildCashFlowForecast(20000, 5419, subs, now)
+	if f.DaysLeft != 21 {
+		t.Errorf("DaysLeft = %d, want 21", f.DaysLeft)
+	}
+	if f.Committed != 184 || len(f.Commitments) != 2 || f.Commitments[0].Name != "iCloud" {
+		t.Errorf("committed = %v %+v, want iCloud and Spotify (184)", f.Committed, f.Commitments)
+	}
+	if f.DailyDiscretionary != 500 || f.Discretionary != 10500 {
+		t.Errorf("discretionary = %v/day %v, want 500/day 10500", f.DailyDiscretionary, f.Discretionary)
+	}
+	if f.ExpectedBalance != 20000-184-10500 {
+		t.Errorf("ExpectedBalance = %v", f.ExpectedBalance)
+	}
+}
@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestIsFXFeeQuery(t *testing.T) {
+	for _, text := range []string{"บัตรไหนชาร์จ FX แพง", "ค่าธรรมเนียม FX", "fx fee"} {
+		if !services.IsFXFeeQuery(text) {
+			t.Errorf("IsFXFeeQuery(%q) = false", text)
+		}
+	}
+	if services.IsFXFeeQuery("ไปญี่ปุ่น 10 วัน") {
+		t.Error("IsFXFeeQuery matched a travel command")
+	}
+}
+
+func TestForeignCharge(t *testing.T) {
+	// Still the travel-mode estimate: nothing to compare
+	estimate := services.Transaction{Amount: 276, Currency: "JPY", ForeignAmount: 1200, FXRate: 0.23, UseType: 1, CreditCardName: "KTC"}
+	if _, ok := services.ForeignCharge("2026-10-17", estimate); ok {
+		t.Error("estimate counted as a charge")
+	}
+	cash := services.Transaction{Amount: 280, Currency: "JPY", ForeignAmount: 1200, FXRate: 0.23}
+	if _, ok := services.ForeignCharge("2026-10-17", cash); ok {
+		t.Error("cash counted as a charge")
+	}
+	edited := estimate
+	edited.Amount = 283.2
+	if c, ok := services.ForeignCharge("2026-10-17", edited); !ok || c.Card != "KTC" || c.Amount != 283.2 {
+		t.Errorf("edited = %+v, %v", c, ok)
+	}
+}
+
+func TestAnalyzeFXFees(t *testing.T) {
+	charges := []services.FXCharge{
+		{Date: "2026-10-17", Card: "KTC", Currency: "JPY", ForeignAmount: 1000, Amount: 236.9},
+		{Date: "2026-10-18", Card: "KTC", Currency: "JPY", ForeignAmount: 1000, Amount: 236.9},
+		{Date: "2026-10-17", Card: "YouTrip", Currency: "JPY", ForeignAmount: 1000, Amount: 231},
+		{Date: "2026-10-19", Card: "KTC", Currency: "USD", ForeignAmount: 10, Amount: 400},
+	}
+	rate := func(currency, date string) (float64, error) {
+		if currency == "USD" {
+			return 0, errors.New("no rate")
+		}
+		return 0.231, nil
+	}
+	fees := services.AnalyzeFXFees(charges, rate)
+	if len(fees) != 2 || fees[0].Card != "KTC" {
+		t.Fatalf("fees = %+v", fees)
+	}
+	if fees[0].Count != 2 || fees[0].Fee != 11.8 || fees[0].Percent != 2.6 {
+		t.Errorf("KTC = %+v", fees[0])
+	}
+	if fees[1].Fee != 0 || fees[1].Percent != 0 {
+		t.Errorf("YouTrip = %+v", fees[1])
+	}
+}
@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestFindGuardrailBreaches(t *testing.T) {
+	rules := []services.Guardrail{
+		{Category: "บันเทิง", MaxAmount: 1000},
+		{Category: "ช้อปปิ้ง", MaxAmount: 2000},
+	}
+	txs := []services.TransactionData{
+		{Type: "expense", Category: "ค่าบันเทิง", Amount: 1500}, // "ค่า" prefix still matches
+		{Type: "expense", Category: "บันเทิง", Amount: 1000},    // equal to limit is allowed
+		{Type: "income", Category: "ช้อปปิ้ง", Amount: 5000},    // income is never checked
+		{Type: "expense", Category: "ช้อปปิ้ง", Amount: 2500},
+		{Type: "expense", Category: "อาหาร", Amount: 9000},
+	}
+
+	breaches := services.FindGuardrailBreaches(rules, txs)
+	if len(breaches) != 2 {
+		t.Fatalf("got %d breaches, want 2: %+v", len(breaches), breaches)
+	}
+	if breaches[0].Index != 0 || breaches[1].Index != 3 {
+		t.Errorf("breach indexes = %d, %d; want 0, 3", breaches[0].Index, breaches[1].Index)
+	}
+}
+
+// TestGuardrailConfirmSavesLikeTextEntry taps "บันทึกเลย" and checks the batch is saved the way a typed
+// entry is: the unknown card becomes cash (asked about after) and the warranty is stored
+func TestGuardrailConfirmSavesLikeTextEntry(t *testing.T) {
+	mongoService := testMongoService(t)
+	ctx := context.Background()
+
+	user := testUserID("guardrail")
+	key := "guardrail_" + user
+	pending, _ := json.Marshal(map[string]interface{}{
+		"transactions": []services.TransactionData{{
+			Type: "expense", Category: "ช้อปปิ้ง", Description: "ทีวี", Amount: 25000,
+			UseType: 1, CreditCardName: "บัตรใหม่", Date: time.Now().Format("2006-01-02"),
+		}},
+		"warranty_months": 24,
+		"new_account":     services.AccountRef{UseType: 1, CreditCardName: "บัตรใหม่"},
+	})
+	if err := mongoService.SaveTempData(ctx, key, string(pending), 10*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	postbackWebhook(t, testWebhookHandler(t, mongoService), user, "action=guardrail_confirm&key="+key)
+
+	recent, err := mongoService.GetRecentTransactions(ctx, user, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 1 || recent[0].Transaction.UseType != 0 || recent[0].Transaction.CreditCardName != "" {
+		t.Fatalf("want one cash entry, got %+v", recent)
+	}
+	warranties, err := mongoService.GetWarranties(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warranties) != 1 || warranties[0].Months != 24 || warranties[0].TransactionID != recent[0].Transaction.ID.Hex() {
+		t.Errorf("want a 24-month warranty on the saved entry, got %+v", warranties)
+	}
+	if data, err := mongoService.GetTempData(ctx, "new_account_"+user); err != nil || data == "" {
+		t.Errorf("new card question should be pending, got %q, %v", data, err)
+	}
+}
@@ -0,0 +1,168 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// chainKey is the server secret the test chains are keyed with
+var chainKey = []byte("test-hash-chain-secret")
+
+// chainOf builds a valid chain of events the way recordHashChain appends them
+func chainOf(lineID, month string, events []services.HashChainEntry) []services.HashChainEntry {
+	return chainWithKey(chainKey, lineID, month, events)
+}
+
+// chainWithKey builds a chain keyed with key
+func chainWithKey(key []byte, lineID, month string, events []services.HashChainEntry) []services.HashChainEntry {
+	prev := services.HashChainGenesis(lineID, month)
+	for i := range events {
+		e := &events[i]
+		e.Seq, e.Month, e.Prev = i+1, month, prev
+		e.Hash = services.ChainHash(key, e.Prev, e.Event, e.TxID, e.Date, e.TxHash)
+		prev = e.Hash
+	}
+	return events
+}
+
+func TestVerifyHashChain(t *testing.T) {
+	since := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	food := services.Transaction{ID: primitive.NewObjectID(), Type: -1, Amount: 120, Category: "อาหาร", Description: "ข้าว", CreatedAt: since.Add(time.Hour)}
+	taxi := services.Transaction{ID: primitive.NewObjectID(), Type: -1, Amount: 300, Category: "เดินทาง", CreatedAt: since.Add(2 * time.Hour)}
+	old := services.Transaction{ID: primitive.NewObjectID(), Type: -1, Amount: 50, CreatedAt: since.Add(-time.Hour)}
+
+	edited := food
+	edited.Amount = 150
+	entries := chainOf("U1", "2026-09", []services.HashChainEntry{
+		{Event: services.TransactionCreated, TxID: food.ID.Hex(), Date: "2026-09-02", TxHash: services.TransactionHash("2026-09-02", food)},
+		{Event: services.TransactionCreated, TxID: taxi.ID.Hex(), Date: "2026-09-02", TxHash: services.TransactionHash("2026-09-02", taxi)},
+		{Event: services.TransactionUpdated, TxID: food.ID.Hex(), Date: "2026-09-02", TxHash: services.TransactionHash("2026-09-02", edited)},
+	})
+	records := []services.DailyRecord{{Date: "2026-09-02", Expenses: []services.Transaction{edited, taxi, old}}}
+
+	r := services.VerifyHashChain(chainKey, "U1", "2026-09", entries, nil, records, since)
+	if !r.OK() || r.Verified != 2 || r.Unsealed != 1 || r.Head != entries[2].Hash {
+		t.Errorf("intact = %+v", r)
+	}
+
+	// Amount changed directly in the database
+	tampered := edited
+	tampered.Amount = 15
+	r = services.VerifyHashChain(chainKey, "U1", "2026-09", entries, nil, []services.DailyRecord{{Date: "2026-09-02", Expenses: []services.Transaction{tampered}}}, since)
+	if r.OK() || len(r.Mismatches) != 2 || r.Verified != 0 {
+		t.Fatalf("tampered = %+v", r)
+	}
+	reasons := map[string]string{}
+	for _, m := range r.Mismatches {
+		reasons[m.TxID] = m.Reason
+	}
+	if reasons[food.ID.Hex()] != "modified" || reasons[taxi.ID.Hex()] != "missing" {
+		t.Errorf("reasons = %v", reasons)
+	}
+
+	// Chain entry rewritten to hide the edit
+	forged := append([]services.HashChainEntry{}, entries...)
+	forged[1].TxHash = services.TransactionHash("2026-09-02", old)
+	if r := services.VerifyHashChain(chainKey, "U1", "2026-09", forged, nil, records, since); r.BrokenAt != 2 || r.Head != "" {
+		t.Errorf("forged = %+v", r)
+	}
+
+	// Chain rebuilt from the database alone, without the server secret
+	rebuilt := chainWithKey(nil, "U1", "2026-09", append([]services.HashChainEntry{}, entries...))
+	if r := services.VerifyHashChain(chainKey, "U1", "2026-09", rebuilt, nil, records, since); r.BrokenAt != 1 {
+		t.Errorf("rebuilt without key = %+v", r)
+	}
+}
+
+func TestVerifyHashChainPending(t *testing.T) {
+	since := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	food := services.Transaction{ID: primitive.NewObjectID(), Type: -1, Amount: 120, Category: "อาหาร", CreatedAt: since.Add(time.Hour)}
+	taxi := services.Transaction{ID: primitive.NewObjectID(), Type: -1, Amount: 300, Category: "เดินทาง", CreatedAt: since.Add(2 * time.Hour)}
+	entries := chainOf("U1", "2026-09", []services.HashChainEntry{
+		{Event: services.TransactionCreated, TxID: food.ID.Hex(), Date: "2026-09-02", TxHash: services.TransactionHash("2026-09-02", food)},
+	})
+	// taxi's append failed and waits to be retried; food was deleted and that append failed too
+	pendingOf := func(key []byte, events ...services.HashChainEntry) []services.HashChainEntry {
+		for i := range events {
+			e := &events[i]
+			e.Hash = services.ChainHash(key, "", e.Event, e.TxID, e.Date, e.TxHash)
+		}
+		return events
+	}
+	events := []services.HashChainEntry{
+		{Event: services.TransactionCreated, TxID: taxi.ID.Hex(), Date: "2026-09-02", TxHash: services.TransactionHash("2026-09-02", taxi)},
+		{Event: services.TransactionDeleted, TxID: food.ID.Hex(), Date: "2026-09-02"},
+	}
+	records := []services.DailyRecord{{Date: "2026-09-02", Expenses: []services.Transaction{taxi}}}
+
+	if r := services.VerifyHashChain(chainKey, "U1", "2026-09", entries, nil, records, since); r.OK() {
+		t.Fatalf("without pending entries = %+v", r)
+	}
+	r := services.VerifyHashChain(chainKey, "U1", "2026-09", entries, pendingOf(chainKey, events...), records, since)
+	if !r.OK() || r.Pending != 2 || r.Verified != 0 {
+		t.Errorf("pending = %+v", r)
+	}
+
+	// A pending entry written without the key doesn't hide a change
+	if r := services.VerifyHashChain(chainKey, "U1", "2026-09", entries, pendingOf(nil, events...), records, since); r.OK() || r.Pending != 0 {
+		t.Errorf("forged pending = %+v", r)
+	}
+
+	// Edited after the failed append: the pending state no longer matches
+	edited := taxi
+	edited.Amount = 30
+	r = services.VerifyHashChain(chainKey, "U1", "2026-09", entries, pendingOf(chainKey, events...), []services.DailyRecord{{Date: "2026-09-02", Expenses: []services.Transaction{edited}}}, since)
+	if r.OK() || len(r.Mismatches) != 1 || r.Mismatches[0].Reason != "modified" {
+		t.Errorf("edited pending = %+v", r)
+	}
+}
+
+func TestTransactionHashIgnoresReviewFlags(t *testing.T) {
+	tx := services.Transaction{ID: primitive.NewObjectID(), Amount: 99, Category: "อาหาร"}
+	reviewed := tx
+	reviewed.NeedsReview, reviewed.SlipStatus = true, "verified"
+	if services.TransactionHash("2026-09-01", tx) != services.TransactionHash("2026-09-01", reviewed) {
+		t.Error("review flags changed the hash")
+	}
+	if services.TransactionHash("2026-09-01", tx) == services.TransactionHash("2026-09-02", tx) {
+		t.Error("date not covered by the hash")
+	}
+}
+
+func TestHashChainCancelledTransfer(t *testing.T) {
+	since := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	transferID := primitive.NewObjectID().Hex()
+	out := services.Transaction{ID: primitive.NewObjectID(), Type: -1, Amount: 5000, Category: "โอนเงิน", TransferID: transferID, IsTransfer: true, CreatedAt: since.Add(time.Hour)}
+	in := services.Transaction{ID: primitive.NewObjectID(), Type: 1, Amount: 5000, Category: "โอนเงิน", TransferID: transferID, IsTransfer: true, CreatedAt: since.Add(time.Hour)}
+	food := services.Transaction{ID: primitive.NewObjectID(), Type: -1, Amount: 80, Category: "อาหาร", CreatedAt: since.Add(2 * time.Hour)}
+	before := services.DailyRecord{Date: "2026-09-03", Expenses: []services.Transaction{out, food}, Incomes: []services.Transaction{in}}
+
+	// SaveTransfer notifies each leg as created
+	events := []services.HashChainEntry{
+		{Event: services.TransactionCreated, TxID: out.ID.Hex(), Date: "2026-09-03", TxHash: services.TransactionHash("2026-09-03", out)},
+		{Event: services.TransactionCreated, TxID: in.ID.Hex(), Date: "2026-09-03", TxHash: services.TransactionHash("2026-09-03", in)},
+		{Event: services.TransactionCreated, TxID: food.ID.Hex(), Date: "2026-09-03", TxHash: services.TransactionHash("2026-09-03", food)},
+	}
+	after := []services.DailyRecord{{Date: "2026-09-03", Expenses: []services.Transaction{food}}}
+
+	// Without delete entries the cancelled legs look deleted outside the app
+	if r := services.VerifyHashChain(chainKey, "U1", "2026-09", chainOf("U1", "2026-09", append([]services.HashChainEntry{}, events...)), nil, after, since); r.OK() {
+		t.Fatalf("unrecorded cancel should be reported, got %+v", r)
+	}
+
+	// DeleteTransferOnDate notifies every leg of the transfer as deleted
+	legs := services.TransferLegsOf(before, transferID)
+	if len(legs) != 2 {
+		t.Fatalf("legs = %+v", legs)
+	}
+	for _, tx := range legs {
+		events = append(events, services.HashChainEntry{Event: services.TransactionDeleted, TxID: tx.ID.Hex(), Date: "2026-09-03"})
+	}
+	r := services.VerifyHashChain(chainKey, "U1", "2026-09", chainOf("U1", "2026-09", events), nil, after, since)
+	if !r.OK() || r.Verified != 1 {
+		t.Errorf("cancelled transfer = %+v", r)
+	}
+}
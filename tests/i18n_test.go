@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestParseExportLanguage(t *testing.T) {
+	cases := []struct {
+		message, profile, want string
+	}{
+		{"export excel", "", services.LangThai},
+		{"export excel", "en", services.LangEnglish},
+		{"export excel english", "th", services.LangEnglish},
+		{"ขอไฟล์ excel ภาษาอังกฤษ", "", services.LangEnglish},
+		{"export pdf สองภาษา", "en", services.LangBilingual},
+		{"export excel", "fr", services.LangThai},
+	}
+	for _, c := range cases {
+		if got := services.ParseExportLanguage(c.message, c.profile); got != c.want {
+			t.Errorf("ParseExportLanguage(%q, %q) = %q, want %q", c.message, c.profile, got, c.want)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	cases := []struct {
+		lang, text, want string
+	}{
+		{services.LangThai, "อาหาร", "อาหาร"},
+		{services.LangEnglish, "อาหาร", "Food"},
+		{services.LangEnglish, services.InvestmentCategory, "Investment"},
+		{services.LangBilingual, "อาหาร", "อาหาร / Food"},
+		{services.LangEnglish, "ค่าขนมแมว", "ค่าขนมแมว"},
+		{services.LangEnglish, "จำนวน (บาท)", "Amount (THB)"},
+	}
+	for _, c := range cases {
+		if got := services.Translate(c.lang, c.text); got != c.want {
+			t.Errorf("Translate(%q, %q) = %q, want %q", c.lang, c.text, got, c.want)
+		}
+	}
+	if got := services.SheetName(services.LangBilingual, "ร้านค้า"); got != "ร้านค้า" {
+		t.Errorf("bilingual sheet name = %q, want Thai", got)
+	}
+	if got := services.PaymentLabel(services.LangEnglish, 1, "", "KTC"); got != "Card KTC" {
+		t.Errorf("PaymentLabel = %q, want Card KTC", got)
+	}
+}
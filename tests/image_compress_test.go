@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestCompressImage(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 3000, 2000))
+	for y := 0; y < 2000; y++ {
+		for x := 0; x < 3000; x++ {
+			src.Set(x, y, color.RGBA{uint8(x * y), uint8(x + y), uint8(x ^ y), 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := services.ImageCompressOptions{MaxDimension: 1600, MaxBytes: 500 << 10, Quality: 80}
+	out, mime := services.CompressImage(buf.Bytes(), "image/png", opts)
+	if mime != "image/jpeg" {
+		t.Fatalf("mime = %s, want image/jpeg", mime)
+	}
+	if len(out) > opts.MaxBytes {
+		t.Errorf("size = %d, want <= %d", len(out), opts.MaxBytes)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width > 1600 || cfg.Height > 1600 || cfg.Width != 1600 || cfg.Height < 1060 {
+		t.Errorf("dimensions = %dx%d", cfg.Width, cfg.Height)
+	}
+
+	// Small images pass through unchanged
+	small, smallMime := services.CompressImage(out, mime, opts)
+	if !bytes.Equal(small, out) || smallMime != mime {
+		t.Error("small image was re-encoded")
+	}
+}
+
+func TestCompressImageLowQualityFinishes(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 1200, 900))
+	for y := 0; y < 900; y++ {
+		for x := 0; x < 1200; x++ {
+			src.Set(x, y, color.RGBA{uint8(x * y), uint8(x + y), uint8(x ^ y), 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+
+	// Quality below the minimum and an unreachable size must still return (used to loop forever)
+	done := make(chan []byte, 1)
+	go func() {
+		out, _ := services.CompressImage(buf.Bytes(), "image/png", services.ImageCompressOptions{MaxDimension: 1000, MaxBytes: 1, Quality: 30})
+		done <- out
+	}()
+	select {
+	case out := <-done:
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(out))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Width < 400 || cfg.Width > 1000 {
+			t.Errorf("width = %d, want within 400-1000", cfg.Width)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("CompressImage with quality 30 did not return")
+	}
+}
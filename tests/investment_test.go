@@ -0,0 +1,33 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestApplyInvestmentType(t *testing.T) {
+	txs := []services.TransactionData{{Type: "expense", Category: "อื่นๆ", Description: "SCBSET", Amount: 5000}}
+	if !services.ApplyInvestmentType(txs, "DCA กองทุน SCBSET 5000") {
+		t.Fatal("investment message not detected")
+	}
+	if txs[0].Type != "investment" || txs[0].Category != services.InvestmentCategory || txs[0].Merchant != "SCBSET" {
+		t.Errorf("got %+v", txs[0])
+	}
+
+	food := []services.TransactionData{{Type: "expense", Category: "อาหาร", Amount: 60}}
+	if services.ApplyInvestmentType(food, "ข้าวมันไก่ 60") || food[0].Type != "expense" {
+		t.Errorf("expense changed: %+v", food[0])
+	}
+}
+
+func TestIsAssetMove(t *testing.T) {
+	transfer := services.Transaction{Category: "โอนเงิน", IsTransfer: true}
+	synonym := services.Transaction{Category: "โอนระหว่างบัญชี", IsTransfer: true}
+	invest := services.Transaction{Category: services.InvestmentCategory}
+	unflagged := services.Transaction{Category: "โอนเงิน"}
+	food := services.Transaction{Category: "อาหาร"}
+	if !transfer.IsAssetMove() || !synonym.IsAssetMove() || !invest.IsAssetMove() || unflagged.IsAssetMove() || food.IsAssetMove() {
+		t.Error("IsAssetMove mismatch")
+	}
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+// loadTestService connects to the dataset seeded by "go run ./cmd/loadtest seed"; benchmarks are
+// skipped unless LOADTEST_MONGODB_URI is set
+func loadTestService(b *testing.B) *services.MongoDBService {
+	uri := os.Getenv("LOADTEST_MONGODB_URI")
+	if uri == "" {
+		b.Skip("LOADTEST_MONGODB_URI not set")
+	}
+	dbName := os.Getenv("LOADTEST_MONGODB_DBNAME")
+	if dbName == "" {
+		dbName = "satistang_loadtest"
+	}
+	mongoService, err := services.NewMongoDBService(uri, dbName)
+	if err != nil {
+		b.Fatalf("connect: %v", err)
+	}
+	b.Cleanup(func() { mongoService.Close() })
+	return mongoService
+}
+
+const loadTestUser = "Uloadtest0001"
+
+func BenchmarkGetBalanceSummary(b *testing.B) {
+	s := loadTestService(b)
+	for i := 0; i < b.N; i++ {
+		if _, err := s.GetBalanceSummary(context.Background(), loadTestUser); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetBalanceByPaymentType(b *testing.B) {
+	s := loadTestService(b)
+	for i := 0; i < b.N; i++ {
+		if _, err := s.GetBalanceByPaymentType(context.Background(), loadTestUser); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSearchTransactions(b *testing.B) {
+	s := loadTestService(b)
+	for i := 0; i < b.N; i++ {
+		if _, err := s.SearchTransactions(context.Background(), loadTestUser, "Starbucks", 20); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSearchByDateRangePage(b *testing.B) {
+	s := loadTestService(b)
+	from := time.Now().AddDate(0, -1, 0).Format("2006-01-02")
+	to := time.Now().Format("2006-01-02")
+	for i := 0; i < b.N; i++ {
+		if _, _, err := s.SearchByDateRangePage(context.Background(), loadTestUser, from, to, 0, 20); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSearchByPaymentRange(b *testing.B) {
+	s := loadTestService(b)
+	account := services.AccountRef{UseType: 1, CreditCardName: "KTC"}
+	to := time.Now().Format("2006-01-02")
+	for i := 0; i < b.N; i++ {
+		if _, err := s.SearchByPaymentRange(context.Background(), loadTestUser, account, "", to, 5); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetTopMerchants(b *testing.B) {
+	s := loadTestService(b)
+	from := time.Now().AddDate(-1, 0, 0).Format("2006-01-02")
+	to := time.Now().Format("2006-01-02")
+	for i := 0; i < b.N; i++ {
+		if _, err := s.GetTopMerchants(context.Background(), loadTestUser, from, to, services.TopMerchantLimit); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestNormalizeMerchant(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"7-ELEVEN สาขา 12345", "7-Eleven"},
+		{"เซเว่น อีเลฟเว่น (สาขาที่ 00321)", "7-Eleven"},
+		{"7-11", "7-Eleven"},
+		{"ＳＴＡＲＢＵＣＫＳ #123", "Starbucks"},
+		{"Lotus's Rama 4", "Lotus's"},
+		{"บิ๊กซี สาขา 45", "Big C"},
+		{"CAFE AMAZON", "Café Amazon"},
+		{"SOMTAM NUA (สาขา 2)", "Somtam Nua"},
+		{"ร้านป้าแดง  สำนักงานใหญ่", "ร้านป้าแดง"},
+		{"  ร้านกาแฟ   ลุงชัย ", "ร้านกาแฟ ลุงชัย"},
+		{"Shellfish House", "Shellfish House"},
+		{"Bike Workshop 2", "Bike Workshop 2"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := services.NormalizeMerchant(tt.raw); got != tt.want {
+			t.Errorf("NormalizeMerchant(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestKnownMerchant(t *testing.T) {
+	if got := services.KnownMerchant("เซเว่น"); got != "7-Eleven" {
+		t.Errorf("KnownMerchant(เซเว่น) = %q", got)
+	}
+	if got := services.KnownMerchant("ข้าวมันไก่"); got != "" {
+		t.Errorf("KnownMerchant(ข้าวมันไก่) = %q, want empty", got)
+	}
+}
+
+func TestTransactionMerchantName(t *testing.T) {
+	if got := (services.Transaction{CustName: "7-ELEVEN", Merchant: "7-Eleven"}).MerchantName(); got != "7-Eleven" {
+		t.Errorf("MerchantName = %q", got)
+	}
+	if got := (services.Transaction{CustName: " ร้านเก่า "}).MerchantName(); got != "ร้านเก่า" {
+		t.Errorf("MerchantName fallback = %q", got)
+	}
+}
@@ -0,0 +1,37 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/satisatang/backend/migrations"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestRegisteredMigrationsAreValid(t *testing.T) {
+	if err := migrations.Validate(migrations.All); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPendingMigrations(t *testing.T) {
+	noop := func(ctx context.Context, db *mongo.Database) error { return nil }
+	all := []migrations.Migration{
+		{Version: 3, Name: "c", Up: noop, Down: noop},
+		{Version: 1, Name: "a", Up: noop, Down: noop},
+		{Version: 2, Name: "b", Up: noop, Down: noop},
+	}
+
+	pending := migrations.Pending(all, map[int]bool{2: true})
+	if len(pending) != 2 || pending[0].Version != 1 || pending[1].Version != 3 {
+		t.Errorf("pending = %+v", pending)
+	}
+
+	dup := append(all, migrations.Migration{Version: 2, Name: "dup", Up: noop, Down: noop})
+	if migrations.Validate(dup) == nil {
+		t.Error("expected duplicate version error")
+	}
+	if migrations.Validate([]migrations.Migration{{Version: 4, Name: "no down", Up: noop}}) == nil {
+		t.Error("expected missing down error")
+	}
+}
@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestFindUnknownAccount(t *testing.T) {
+	banks := []string{"กสิกร"}
+	cards := []string{"KTC"}
+
+	txs := []services.TransactionData{
+		{Amount: 50, UseType: 0},
+		{Amount: 65, UseType: 1, CreditCardName: "ktc"},
+		{Amount: 100, UseType: 2, BankName: "ออมสิน"},
+	}
+	got := services.FindUnknownAccount(txs, banks, cards)
+	if got == nil || got.UseType != 2 || got.Name() != "ออมสิน" {
+		t.Fatalf("FindUnknownAccount = %+v, want ออมสิน bank", got)
+	}
+	if !got.Matches(txs[2]) || got.Matches(txs[1]) {
+		t.Errorf("Matches mismatch for %+v", got)
+	}
+
+	if got := services.FindUnknownAccount(txs[:2], banks, cards); got != nil {
+		t.Errorf("known accounts should return nil, got %+v", got)
+	}
+	if got := services.FindUnknownAccount([]services.TransactionData{{UseType: 1, CreditCardName: "CITI"}}, nil, nil); got == nil || got.Name() != "CITI" {
+		t.Errorf("new user card = %+v, want CITI", got)
+	}
+}
+
+func TestParseYesNo(t *testing.T) {
+	tests := []struct {
+		text    string
+		yes, ok bool
+	}{
+		{"ใช่", true, true},
+		{" ใช่ครับ ", true, true},
+		{"OK", true, true},
+		{"ไม่ใช่", false, true},
+		{"ไม่ต้อง", false, true},
+		{"กาแฟ 50", false, false},
+		{"ใช่ไหม", false, false},
+	}
+	for _, tt := range tests {
+		yes, ok := services.ParseYesNo(tt.text)
+		if yes != tt.yes || ok != tt.ok {
+			t.Errorf("ParseYesNo(%q) = (%v, %v), want (%v, %v)", tt.text, yes, ok, tt.yes, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestOrderedQueuesKeepOrderPerKey(t *testing.T) {
+	var queues services.OrderedQueues
+	var mu sync.Mutex
+	got := map[string][]int{}
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		for _, key := range []string{"U1", "U2"} {
+			i, key := i, key
+			wg.Add(1)
+			queues.Enqueue(key, "test.ordered", func() {
+				defer wg.Done()
+				if i%7 == 0 {
+					time.Sleep(time.Millisecond) // a slow job must not let later ones pass it
+				}
+				mu.Lock()
+				got[key] = append(got[key], i)
+				mu.Unlock()
+			})
+		}
+	}
+	wg.Wait()
+
+	for _, key := range []string{"U1", "U2"} {
+		if len(got[key]) != 200 {
+			t.Fatalf("%s ran %d jobs, want 200", key, len(got[key]))
+		}
+		for i, v := range got[key] {
+			if v != i {
+				t.Fatalf("%s job %d ran at position %d", key, v, i)
+			}
+		}
+	}
+}
+
+func TestOrderedQueuesContinueAfterPanic(t *testing.T) {
+	var queues services.OrderedQueues
+	done := make(chan struct{})
+	queues.Enqueue("U1", "test.panic", func() { panic("boom") })
+	queues.Enqueue("U1", "test.panic", func() { close(done) })
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job queued after a panic never ran")
+	}
+}
@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestValidateWebhookURL(t *testing.T) {
+	for _, raw := range []string{"http://example.com/hook", "https://localhost/hook", "https://127.0.0.1/hook", "https://10.0.0.5/hook",
+		"https://169.254.169.254/latest/meta-data", "https://[::1]/hook", "https://100.64.0.1/hook", "https://0.0.0.0/hook"} {
+		if err := services.ValidateWebhookURL(raw); err == nil {
+			t.Errorf("ValidateWebhookURL(%q) should be rejected", raw)
+		}
+	}
+	if err := services.ValidateWebhookURL("https://example.com/hook"); err != nil {
+		t.Errorf("public https URL rejected: %v", err)
+	}
+}
+
+func TestWebhookClientBlocksInternalAddress(t *testing.T) {
+	hit := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hit = true }))
+	defer server.Close()
+
+	// "localhost" passes no IP check by name; the dialed 127.0.0.1 must still be refused
+	client := services.NewWebhookClient()
+	for _, target := range []string{server.URL, strings.Replace(server.URL, "127.0.0.1", "localhost", 1)} {
+		resp, err := client.Post(target, "application/json", strings.NewReader("{}"))
+		if err == nil {
+			resp.Body.Close()
+			t.Errorf("POST %s should be blocked", target)
+		} else if !strings.Contains(err.Error(), "blocked") {
+			t.Errorf("POST %s: %v", target, err)
+		}
+	}
+	if hit {
+		t.Error("internal server received a delivery")
+	}
+}
+
+func TestWebhookClientRefusesRedirect(t *testing.T) {
+	client := services.NewWebhookClient()
+	req := httptest.NewRequest(http.MethodPost, "http://169.254.169.254/latest/meta-data", nil)
+	if err := client.CheckRedirect(req, []*http.Request{httptest.NewRequest(http.MethodPost, "https://example.com/hook", nil)}); err == nil {
+		t.Error("redirect should be refused")
+	}
+}
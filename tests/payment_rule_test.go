@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestParsePaymentTarget(t *testing.T) {
+	banks := []string{"กสิกร"}
+	cards := []string{"KTC"}
+	tests := []struct {
+		text string
+		want services.AccountRef
+	}{
+		{"กสิกร", services.AccountRef{UseType: 2, BankName: "กสิกร"}},
+		{"ktc", services.AccountRef{UseType: 1, CreditCardName: "KTC"}},
+		{"บัตร KTC", services.AccountRef{UseType: 1, CreditCardName: "KTC"}},
+		{"บัตรCITI", services.AccountRef{UseType: 1, CreditCardName: "CITI"}},
+		{"ธนาคารออมสิน", services.AccountRef{UseType: 2, BankName: "ออมสิน"}},
+		{"เงินสด", services.AccountRef{UseType: 0}},
+	}
+	for _, tt := range tests {
+		got, ok := services.ParsePaymentTarget(tt.text, banks, cards, nil)
+		if !ok || got != tt.want {
+			t.Errorf("ParsePaymentTarget(%q) = %+v, %v; want %+v", tt.text, got, ok, tt.want)
+		}
+	}
+	if _, ok := services.ParsePaymentTarget("บัตร", banks, cards, nil); ok {
+		t.Error("prefix only should not parse")
+	}
+}
+
+func TestApplyPaymentRules(t *testing.T) {
+	rules := []services.PaymentRule{{Category: "เดินทาง", UseType: 2, BankName: "กสิกร"}}
+	txs := []services.TransactionData{
+		{Type: "expense", Category: "ค่าเดินทาง", Amount: 40},
+		{Type: "expense", Category: "เดินทาง", UseType: 1, CreditCardName: "KTC"},
+		{Type: "income", Category: "เดินทาง"},
+		{Type: "expense", Category: "อาหาร"},
+	}
+	applied := services.ApplyPaymentRules(rules, txs)
+	if len(applied) != 1 || applied[0] != 0 {
+		t.Fatalf("applied = %v, want [0]", applied)
+	}
+	if txs[0].UseType != 2 || txs[0].BankName != "กสิกร" {
+		t.Errorf("rule not applied: %+v", txs[0])
+	}
+	if txs[1].CreditCardName != "KTC" {
+		t.Errorf("explicit payment overwritten: %+v", txs[1])
+	}
+}
@@ -0,0 +1,42 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestParsePaymentQuery(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	banks := []string{"กสิกร"}
+	cards := []string{"KTC", "KTC Platinum"}
+
+	q, ok := services.ParsePaymentQuery("เดือนนี้รูดบัตร ktc ไปเท่าไหร่", banks, cards, now)
+	if !ok || q.Account.UseType != 1 || q.Account.CreditCardName != "KTC" {
+		t.Fatalf("card query = %+v, %v", q, ok)
+	}
+	if q.From != "2026-10-01" || q.To != "2026-10-16" {
+		t.Errorf("range = %s..%s, want 2026-10-01..2026-10-16", q.From, q.To)
+	}
+
+	q, ok = services.ParsePaymentQuery("KTC Platinum เดือนที่แล้วใช้ไปเท่าไร", banks, cards, now)
+	if !ok || q.Account.CreditCardName != "KTC Platinum" || q.From != "2026-09-01" || q.To != "2026-09-30" {
+		t.Errorf("longest card/last month = %+v, %v", q, ok)
+	}
+
+	q, ok = services.ParsePaymentQuery("กสิกรใช้ไปกี่บาท", banks, cards, now)
+	if !ok || q.Account.UseType != 2 || q.Account.BankName != "กสิกร" || q.Period != "เดือนนี้" {
+		t.Errorf("bank default month = %+v, %v", q, ok)
+	}
+
+	if q, ok = services.ParsePaymentQuery("เงินสดเมื่อวานใช้ไปเท่าไหร่", banks, cards, now); !ok || q.Account.UseType != 0 || q.From != "2026-10-15" {
+		t.Errorf("cash yesterday = %+v, %v", q, ok)
+	}
+
+	for _, text := range []string{"กาแฟ 65 บัตร KTC", "ใช้ไปเท่าไหร่", "บัตร CITI ใช้ไปเท่าไหร่", "เงินสดเหลือเท่าไหร่"} {
+		if q, ok := services.ParsePaymentQuery(text, banks, cards, now); ok {
+			t.Errorf("ParsePaymentQuery(%q) = %+v, want no match", text, q)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/satisatang/backend/services"
+	"github.com/signintech/gopdf"
+)
+
+// renderThaiPDF writes a one-page PDF using fonts (or parses them per export when nil)
+func renderThaiPDF(b *testing.B, fonts *gopdf.FontContainer) {
+	pdf := gopdf.GoPdf{}
+	pdf.Start(gopdf.Config{PageSize: *gopdf.PageSizeA4})
+	if fonts == nil {
+		var err error
+		if fonts, err = services.NewThaiFonts(false); err != nil {
+			b.Skipf("fonts unavailable: %v", err)
+		}
+	}
+	if err := services.AddThaiFonts(&pdf, fonts); err != nil {
+		b.Fatal(err)
+	}
+	pdf.AddPage()
+	pdf.SetFont("SarabunBold", "", 16)
+	pdf.Cell(nil, "สรุปรายรับรายจ่าย")
+	pdf.SetFont("Sarabun", "", 12)
+	pdf.Cell(nil, "ค่าอาหาร 1,250.00 บาท")
+	var buf bytes.Buffer
+	if _, err := pdf.WriteTo(&buf); err != nil {
+		b.Fatal(err)
+	}
+}
+
+// BenchmarkPDFFontsPerExport parses the TTF data on every export (old behavior)
+func BenchmarkPDFFontsPerExport(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		renderThaiPDF(b, nil)
+	}
+}
+
+// BenchmarkPDFFontsCached reuses fonts parsed once
+func BenchmarkPDFFontsCached(b *testing.B) {
+	fonts, err := services.NewThaiFonts(false)
+	if err != nil {
+		b.Skipf("fonts unavailable: %v", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		renderThaiPDF(b, fonts)
+	}
+}
+
+// BenchmarkPDFFontsLite parses only the regular face once
+func BenchmarkPDFFontsLite(b *testing.B) {
+	fonts, err := services.NewThaiFonts(true)
+	if err != nil {
+		b.Skipf("fonts unavailable: %v", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		renderThaiPDF(b, fonts)
+	}
+}
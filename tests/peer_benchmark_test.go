@@ -0,0 +1,41 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestIncomeBand(t *testing.T) {
+	cases := map[float64]string{
+		0:      "",
+		9000:   "ต่ำกว่า 15,000",
+		15000:  "15,000-30,000",
+		45000:  "30,000-50,000",
+		250000: "100,000 ขึ้นไป",
+	}
+	for income, want := range cases {
+		if got := services.IncomeBand(income); got != want {
+			t.Errorf("IncomeBand(%v) = %q, want %q", income, got, want)
+		}
+	}
+}
+
+func TestAggregatePeerSpendingKAnonymity(t *testing.T) {
+	samples := []services.PeerSample{
+		{Spending: map[string]float64{"อาหาร": 3000, "ท่องเที่ยว": 9000}},
+		{Spending: map[string]float64{"อาหาร": 5000}},
+		{Spending: map[string]float64{"อาหาร": 4000}},
+	}
+	if got := services.AggregatePeerSpending(samples, 4); got != nil {
+		t.Fatalf("expected nil below k users, got %v", got)
+	}
+
+	got := services.AggregatePeerSpending(samples, 3)
+	if got["อาหาร"] != 4000 {
+		t.Errorf("อาหาร average = %v, want 4000", got["อาหาร"])
+	}
+	if _, ok := got["ท่องเที่ยว"]; ok {
+		t.Error("category spent by fewer than k users must not be published")
+	}
+}
@@ -0,0 +1,33 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestPendingStateLabel(t *testing.T) {
+	const user = "U4af4980629ac0bd8f4a1e6d1c6f5a0b2"
+	cases := []struct {
+		key   string
+		label string
+		ok    bool
+	}{
+		{"slip_pending_" + user, "สลิปที่รอเลือกหมวด", true},
+		{"slip_" + user + "_1735689600", "สลิปที่รอยืนยัน", true},
+		{"amount_only_" + user, "ยอดเงินที่รอคำอธิบาย", true},
+		{"amount_" + user + "_1735689600", "รายการที่รอยืนยันยอด", true},
+		{"edit_target_" + user, "การแก้ไขรายการ", true},
+		{"rate_limit_" + user, "", false},
+		{"slip_pending_", "", false},
+	}
+	for _, c := range cases {
+		lineID, label, ok := services.PendingStateLabel(c.key)
+		if ok != c.ok || label != c.label {
+			t.Errorf("PendingStateLabel(%q) = %q, %v; want %q, %v", c.key, label, ok, c.label, c.ok)
+		}
+		if ok && lineID != user {
+			t.Errorf("PendingStateLabel(%q) owner = %q, want %q", c.key, lineID, user)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package tests
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestUniqueExcelSheetName(t *testing.T) {
+	used := map[string]bool{"สรุป P&L": true}
+	long := "ปรับปรุงบ้านคุณสมชายซอยสุขุมวิท 101 ระยะที่"
+	names := []string{
+		services.UniqueExcelSheetName(long+" 1", used),
+		services.UniqueExcelSheetName(long+" 2", used),
+		services.UniqueExcelSheetName(long+" 3", used),
+		services.UniqueExcelSheetName("ร้าน A/B", used),
+	}
+
+	seen := map[string]bool{}
+	for _, name := range names {
+		if n := utf8.RuneCountInString(name); n > 31 {
+			t.Errorf("%q has %d characters, Excel allows 31", name, n)
+		}
+		if seen[name] {
+			t.Errorf("duplicate sheet name %q", name)
+		}
+		seen[name] = true
+	}
+	if names[3] != "ร้าน AB" {
+		t.Errorf("invalid characters kept: %q", names[3])
+	}
+	if want := string([]rune(long)[:27]) + " (3)"; names[2] != want {
+		t.Errorf("third name = %q, want %q", names[2], want)
+	}
+}
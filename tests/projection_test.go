@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestUserProjectionFresh(t *testing.T) {
+	built := time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local)
+	tests := []struct {
+		name string
+		p    *services.UserProjection
+		want bool
+	}{
+		{"up to date", &services.UserProjection{WriteSeq: 3, BuiltSeq: 3, Day: "2026-10-16", BuiltAt: built}, true},
+		{"write since build", &services.UserProjection{WriteSeq: 4, BuiltSeq: 3, Day: "2026-10-16", BuiltAt: built}, false},
+		{"built yesterday", &services.UserProjection{WriteSeq: 3, BuiltSeq: 3, Day: "2026-10-15", BuiltAt: built}, false},
+		{"never built", &services.UserProjection{Day: "2026-10-16"}, false},
+		{"missing", nil, false},
+	}
+	for _, tt := range tests {
+		if got := tt.p.Fresh("2026-10-16"); got != tt.want {
+			t.Errorf("%s: Fresh() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestProjectionRebuildsCoalesceAcrossInstances(t *testing.T) {
+	first := testMongoService(t)
+	second := testMongoService(t) // another instance: the rebuild guard has to live in Mongo
+	ctx := context.Background()
+	userID := testUserID("projection")
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(os.Getenv("TEST_MONGODB_URI")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(ctx)
+	projections := client.Database("satistang_test").Collection("user_projections")
+	waitFresh := func() services.UserProjection {
+		deadline := time.Now().Add(20 * time.Second)
+		for {
+			var p services.UserProjection
+			err := projections.FindOne(ctx, bson.M{"lineid": userID}).Decode(&p)
+			if err == nil && p.Fresh(time.Now().Format("2006-01-02")) {
+				return p
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("projection never caught up: %+v, %v", p, err)
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	// First read builds the projection
+	if _, err := first.ProjectedBalances(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+	before := waitFresh()
+
+	const writes = 10
+	for i := 0; i < writes; i++ {
+		svc := first
+		if i%2 == 1 {
+			svc = second
+		}
+		if _, err := svc.SaveTransaction(ctx, userID, &services.TransactionData{Amount: 10, Type: "expense", Category: "อาหาร"}); err != nil {
+			t.Fatal(err)
+		}
+		// Right after a save the reply must not read the old projection
+		balances, err := svc.ProjectedBalances(ctx, userID)
+		if err != nil || len(balances) != 1 || balances[0].Balance != float64(-10*(i+1)) {
+			t.Fatalf("after write %d: %+v, %v", i+1, balances, err)
+		}
+	}
+
+	after := waitFresh()
+	if after.WriteSeq-before.WriteSeq != writes {
+		t.Errorf("write_seq moved by %d, want %d", after.WriteSeq-before.WriteSeq, writes)
+	}
+	if builds := after.Builds - before.Builds; builds >= writes {
+		t.Errorf("%d rebuilds for %d writes, want them coalesced", builds, writes)
+	}
+	if len(after.Balances) != 1 || after.Balances[0].Balance != -10*writes {
+		t.Errorf("projected balances = %+v", after.Balances)
+	}
+	if !after.BuildingUntil.IsZero() {
+		t.Errorf("rebuild lease not released: %+v", after)
+	}
+}
@@ -0,0 +1,36 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestLoadPromptSet(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("system.md", "ระบบ v1")
+	write("extract.md", "### common\nตอบ JSON\n### new\n{}")
+
+	first := services.LoadPromptSet(dir)
+	if first.System != "ระบบ v1" || first.Extract["new"] != "{}" || first.HasPipeline() {
+		t.Errorf("LoadPromptSet = %+v", first)
+	}
+	if len(first.Missing) != 4 || first.Receipt == "" {
+		t.Errorf("missing = %v, receipt default %q", first.Missing, first.Receipt)
+	}
+	if again := services.LoadPromptSet(dir); again.Version != first.Version {
+		t.Errorf("same files gave versions %s and %s", first.Version, again.Version)
+	}
+
+	write("system.md", "ระบบ v2")
+	if next := services.LoadPromptSet(dir); next.Version == first.Version || len(next.Version) != 8 {
+		t.Errorf("edited prompt version = %s (was %s)", next.Version, first.Version)
+	}
+}
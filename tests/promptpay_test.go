@@ -0,0 +1,28 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestPromptPayPayload(t *testing.T) {
+	cases := []struct {
+		id     string
+		amount float64
+		want   string
+	}{
+		{"000-000-0000", 4.22, "00020101021229370016A000000677010111011300660000000005802TH530376454044.226304E469"},
+		{"0812345678", 0, "00020101021129370016A000000677010111011300668123456785802TH530376463045D82"},
+	}
+	for _, c := range cases {
+		got, err := services.PromptPayPayload(c.id, c.amount)
+		if err != nil || got != c.want {
+			t.Errorf("PromptPayPayload(%q, %v) = %q, %v; want %q", c.id, c.amount, got, err, c.want)
+		}
+	}
+
+	if _, err := services.PromptPayPayload("12345", 100); err == nil {
+		t.Error("PromptPayPayload accepted invalid ID")
+	}
+}
@@ -0,0 +1,300 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+// QR reference values from ISO/IEC 18004 (format/version tables, level M block layout)
+var (
+	// 15-bit format information for level M, index = mask
+	qrFormatM = []int{0x5412, 0x5125, 0x5E7C, 0x5B4B, 0x45F9, 0x40CE, 0x4F97, 0x4AA0}
+	// 18-bit version information, index = version
+	qrVersionInfo = map[int]int{7: 0x07C94, 8: 0x085BC, 9: 0x09A99}
+	// level M: EC codewords per block, then (blocks, data codewords) per group
+	qrBlocksM = map[int][5]int{
+		1: {10, 1, 16, 0, 0}, 2: {16, 1, 28, 0, 0}, 3: {26, 1, 44, 0, 0},
+		4: {18, 2, 32, 0, 0}, 5: {24, 2, 43, 0, 0}, 6: {16, 4, 27, 0, 0},
+		7: {18, 4, 31, 0, 0}, 8: {22, 2, 38, 2, 39}, 9: {22, 3, 36, 2, 37},
+	}
+	qrAlignCenters = map[int][]int{
+		2: {6, 18}, 3: {6, 22}, 4: {6, 26}, 5: {6, 30}, 6: {6, 34},
+		7: {6, 22, 38}, 8: {6, 24, 42}, 9: {6, 26, 46},
+	}
+)
+
+// gfExp and gfLog are GF(256) tables for polynomial 0x11D, used to check EC by syndromes
+var gfExp, gfLog = func() ([512]byte, [256]int) {
+	var exp [512]byte
+	var log [256]int
+	x := 1
+	for i := 0; i < 255; i++ {
+		exp[i], exp[i+255] = byte(x), byte(x)
+		log[x] = i
+		if x <<= 1; x&0x100 != 0 {
+			x ^= 0x11D
+		}
+	}
+	return exp, log
+}()
+
+// qrSyndromesZero reports whether codewords (data then EC) form a valid Reed-Solomon codeword
+func qrSyndromesZero(codewords []byte, ecCount int) bool {
+	for i := 0; i < ecCount; i++ {
+		var s byte
+		for _, c := range codewords {
+			if s != 0 {
+				s = gfExp[gfLog[s]+i]
+			}
+			s ^= c
+		}
+		if s != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// qrIsFunction reports whether (x, y) belongs to a function pattern of version
+func qrIsFunction(version, x, y int) bool {
+	size := version*4 + 17
+	if x == 6 || y == 6 {
+		return true // timing
+	}
+	if (x < 9 && y < 9) || (x >= size-8 && y < 9) || (x < 9 && y >= size-8) {
+		return true // finders, separators, format
+	}
+	if version >= 7 && ((x >= size-11 && x < size-8 && y < 6) || (y >= size-11 && y < size-8 && x < 6)) {
+		return true // version information
+	}
+	centers := qrAlignCenters[version]
+	for i, cx := range centers {
+		for j, cy := range centers {
+			last := len(centers) - 1
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			if x >= cx-2 && x <= cx+2 && y >= cy-2 && y <= cy+2 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// qrMaskInverts is the ISO mask condition for row i, column j
+func qrMaskInverts(mask, i, j int) bool {
+	switch mask {
+	case 0:
+		return (i+j)%2 == 0
+	case 1:
+		return i%2 == 0
+	case 2:
+		return j%3 == 0
+	case 3:
+		return (i+j)%3 == 0
+	case 4:
+		return (i/2+j/3)%2 == 0
+	case 5:
+		return (i*j)%2+(i*j)%3 == 0
+	case 6:
+		return ((i*j)%2+(i*j)%3)%2 == 0
+	default:
+		return ((i+j)%2+(i*j)%3)%2 == 0
+	}
+}
+
+// decodeQR reads a level M byte-mode matrix back to its data, checking every structural part
+func decodeQR(t *testing.T, m [][]bool) []byte {
+	t.Helper()
+	size := len(m)
+	version := (size - 17) / 4
+	layout, ok := qrBlocksM[version]
+	if !ok || size != version*4+17 {
+		t.Fatalf("unexpected size %d", size)
+	}
+	bit := func(x, y int) int {
+		if m[y][x] {
+			return 1
+		}
+		return 0
+	}
+
+	// Finder pattern rows (top-left corner) and the dark module
+	for y, row := range []string{"1111111", "1000001", "1011101", "1011101", "1011101", "1000001", "1111111"} {
+		for x, c := range row {
+			if (c == '1') != m[y][x] {
+				t.Fatalf("finder module (%d,%d) wrong", x, y)
+			}
+		}
+	}
+	if !m[size-8][8] {
+		t.Fatal("dark module missing")
+	}
+	for i := 8; i < size-8; i++ {
+		if m[6][i] != (i%2 == 0) || m[i][6] != (i%2 == 0) {
+			t.Fatalf("timing module %d wrong", i)
+		}
+	}
+
+	// Format information: both copies must equal a level M table value
+	format, format2 := 0, 0
+	for _, p := range [][2]int{{8, 0}, {8, 1}, {8, 2}, {8, 3}, {8, 4}, {8, 5}, {8, 7}, {8, 8}, {7, 8}, {5, 8}, {4, 8}, {3, 8}, {2, 8}, {1, 8}, {0, 8}} {
+		format = format>>1 | bit(p[0], p[1])<<14
+	}
+	for i := 0; i < 8; i++ {
+		format2 = format2>>1 | bit(size-1-i, 8)<<14
+	}
+	for i := 8; i < 15; i++ {
+		format2 = format2>>1 | bit(8, size-15+i)<<14
+	}
+	mask := -1
+	for i, f := range qrFormatM {
+		if f == format {
+			mask = i
+		}
+	}
+	if mask < 0 || format2 != format {
+		t.Fatalf("format bits %015b / %015b are not level M", format, format2)
+	}
+
+	if want, ok := qrVersionInfo[version]; ok {
+		v1, v2 := 0, 0
+		for i := 0; i < 18; i++ {
+			v1 |= bit(size-11+i%3, i/3) << i
+			v2 |= bit(i/3, size-11+i%3) << i
+		}
+		if v1 != want || v2 != want {
+			t.Fatalf("version bits %018b / %018b, want %018b", v1, v2, want)
+		}
+	}
+
+	// Zigzag read from the bottom-right corner, two columns at a time
+	var stream []int
+	upward := true
+	for right := size - 1; right > 0; right -= 2 {
+		if right == 6 {
+			right--
+		}
+		for k := 0; k < size; k++ {
+			y := k
+			if upward {
+				y = size - 1 - k
+			}
+			for x := right; x > right-2; x-- {
+				if qrIsFunction(version, x, y) {
+					continue
+				}
+				b := bit(x, y)
+				if qrMaskInverts(mask, y, x) {
+					b ^= 1
+				}
+				stream = append(stream, b)
+			}
+		}
+		upward = !upward
+	}
+	total := layout[1]*(layout[2]+layout[0]) + layout[3]*(layout[4]+layout[0])
+	if len(stream) < total*8 {
+		t.Fatalf("only %d data bits for %d codewords", len(stream), total)
+	}
+	interleaved := make([]byte, total)
+	for i := range interleaved {
+		for _, b := range stream[i*8 : i*8+8] {
+			interleaved[i] = interleaved[i]<<1 | byte(b)
+		}
+	}
+
+	// De-interleave blocks and check each with Reed-Solomon syndromes
+	blocks := make([][]byte, layout[1]+layout[3])
+	dataLen := func(i int) int {
+		if i < layout[1] {
+			return layout[2]
+		}
+		return layout[4]
+	}
+	pos := 0
+	for i := 0; i < layout[4] || i < layout[2]; i++ {
+		for b := range blocks {
+			if i < dataLen(b) {
+				blocks[b] = append(blocks[b], interleaved[pos])
+				pos++
+			}
+		}
+	}
+	for i := 0; i < layout[0]; i++ {
+		for b := range blocks {
+			blocks[b] = append(blocks[b], interleaved[pos])
+			pos++
+		}
+	}
+	var data []byte
+	for b, block := range blocks {
+		if !qrSyndromesZero(block, layout[0]) {
+			t.Fatalf("block %d fails Reed-Solomon check", b)
+		}
+		data = append(data, block[:dataLen(b)]...)
+	}
+
+	// Byte mode header, then count bytes spanning codeword boundaries
+	if data[0]>>4 != 0x4 {
+		t.Fatalf("mode %04b, want byte mode", data[0]>>4)
+	}
+	count := int(data[0]&0x0F)<<4 | int(data[1]>>4)
+	out := make([]byte, count)
+	for i := range out {
+		out[i] = data[i+1]<<4 | data[i+2]>>4
+	}
+	return out
+}
+
+func TestQRSyndromeReference(t *testing.T) {
+	// "HELLO WORLD" version 1-M codewords from the ISO worked example
+	codewords := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17,
+		196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if !qrSyndromesZero(codewords, 10) {
+		t.Fatal("reference codewords fail the syndrome check")
+	}
+	codewords[3] ^= 1
+	if qrSyndromesZero(codewords, 10) {
+		t.Fatal("corrupted codewords pass the syndrome check")
+	}
+}
+
+func TestEncodeQR(t *testing.T) {
+	payload, err := services.PromptPayPayload("0812345678", 150)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name    string
+		data    string
+		version int
+	}{
+		{"short", "HELLO WORLD", 1},
+		{"promptpay", payload, 5},
+		{"version info", strings.Repeat("x", 110), 7},
+		{"two block groups", strings.Repeat("ก", 55), 9},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m, err := services.EncodeQR([]byte(c.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(m) != c.version*4+17 {
+				t.Fatalf("size %d, want version %d", len(m), c.version)
+			}
+			if got := decodeQR(t, m); !bytes.Equal(got, []byte(c.data)) {
+				t.Errorf("decoded %q, want %q", got, c.data)
+			}
+		})
+	}
+
+	if _, err := services.EncodeQR(make([]byte, 181)); err == nil {
+		t.Error("EncodeQR accepted data beyond version 9")
+	}
+}
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestIsFollowUpQuery(t *testing.T) {
+	tests := map[string]bool{
+		"แล้วเดือนก่อนล่ะ":    true,
+		"เอาเฉพาะบัตรเครดิต":  true,
+		"ปีที่แล้วล่ะ":        true,
+		"แล้วก็กาแฟ 50":       false,
+		"สรุปรายจ่ายเดือนนี้": false,
+	}
+	for text, want := range tests {
+		if got := services.IsFollowUpQuery(text); got != want {
+			t.Errorf("IsFollowUpQuery(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestMergeQueryFilter(t *testing.T) {
+	prev := services.QueryFilter{Type: "expense", Days: 30, GroupBy: "category", Keyword: "กาแฟ"}
+	got := services.MergeQueryFilter(prev, &services.QueryFilter{Type: "all", DateFrom: "2025-02-01", DateTo: "2025-02-28"})
+	if got.Days != 0 || got.DateFrom != "2025-02-01" || got.Type != "expense" || got.GroupBy != "category" || got.Keyword != "กาแฟ" {
+		t.Errorf("MergeQueryFilter(date) = %+v", got)
+	}
+	got = services.MergeQueryFilter(got, &services.QueryFilter{Days: 7})
+	if got.Days != 7 || got.DateFrom != "" || got.DateTo != "" {
+		t.Errorf("MergeQueryFilter(days) = %+v", got)
+	}
+}
+
+func TestParseFollowUp(t *testing.T) {
+	now := time.Date(2025, 3, 13, 15, 30, 0, 0, time.UTC)
+	cards := []string{"KTC 1234"}
+	f, ok := services.ParseFollowUp("แล้วเดือนก่อนล่ะ", nil, cards, nil, now)
+	if !ok || f.Query.DateFrom != "2025-02-01" || f.Query.DateTo != "2025-02-28" || f.Account != nil {
+		t.Errorf("ParseFollowUp(last month) = %+v, %v", f, ok)
+	}
+	f, ok = services.ParseFollowUp("เอาเฉพาะบัตรเครดิต", nil, cards, nil, now)
+	if !ok || f.Account == nil || f.Account.UseType != 1 || f.Account.CreditCardName != "" || f.Label != "บัตรเครดิต" {
+		t.Errorf("ParseFollowUp(credit) = %+v, %v", f, ok)
+	}
+	f, ok = services.ParseFollowUp("เฉพาะ ktc 1234 ล่ะ", nil, cards, nil, now)
+	if !ok || f.Account == nil || f.Account.CreditCardName != "KTC 1234" {
+		t.Errorf("ParseFollowUp(card with digits) = %+v, %v", f, ok)
+	}
+	if _, ok := services.ParseFollowUp("แล้วกาแฟ 50 บัตรเครดิต", nil, cards, nil, now); ok {
+		t.Errorf("ParseFollowUp should leave an entry with an amount to the AI")
+	}
+	if _, ok := services.ParseFollowUp("แล้วทำไมล่ะ", nil, cards, nil, now); ok {
+		t.Errorf("ParseFollowUp should need a recognized change")
+	}
+}
+
+func TestLastQueryFilter(t *testing.T) {
+	results := []services.SearchResult{
+		{Date: "2025-02-10", Transaction: services.Transaction{Type: -1, Amount: 60, UseType: 1, CreditCardName: "KTC"}},
+		{Date: "2025-02-11", Transaction: services.Transaction{Type: -1, Amount: 40, UseType: 0}},
+		{Date: "2025-02-12", Transaction: services.Transaction{Type: 1, Amount: 500, UseType: 1, CreditCardName: "KTC"}},
+		{Date: "2025-03-01", Transaction: services.Transaction{Type: -1, Amount: 80, UseType: 1, CreditCardName: "UOB"}},
+	}
+	q := services.LastQuery{Query: services.QueryFilter{Keyword: "x"}}
+	q = q.Refine(&services.FollowUp{Query: services.QueryFilter{DateFrom: "2025-02-01", DateTo: "2025-02-28", Type: "expense"}, Account: &services.AccountRef{UseType: 1}})
+	if !q.Narrowed() {
+		t.Fatalf("refined query should be narrowed")
+	}
+	got := q.Filter(results)
+	if len(got) != 1 || got[0].Transaction.Amount != 60 {
+		t.Errorf("Filter = %+v, want only the February KTC expense", got)
+	}
+	if (services.LastQuery{Query: services.QueryFilter{Days: 30}}).Narrowed() {
+		t.Errorf("plain date query should not be narrowed")
+	}
+}
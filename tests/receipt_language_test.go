@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestParseReceiptDate(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	cases := []struct {
+		text, lang, want string
+	}{
+		{"2026年10月14日(水) 12:30", "ja", "2026-10-14"},
+		{"令和8年10月3日", "ja", "2026-10-03"},
+		{"令和元年5月1日", "ja", "2019-05-01"},
+		{"民國115年10月2日", "zh", "2026-10-02"},
+		{"2026/10/15 18:02", "zh", "2026-10-15"},
+		{"Oct 12, 2026 7:45 PM", "en", "2026-10-12"},
+		{"12 October 2026", "en", "2026-10-12"},
+		{"10/13/2026", "en", "2026-10-13"}, // month first (13 can't be a month)
+		{"13/10/26", "en", "2026-10-13"},   // day first, two-digit year
+		{"10/11/2026", "en", "2026-10-11"}, // ambiguous: 11 Oct is nearer than 10 Nov (future)
+		{"03/04/2026", "en", "2026-04-03"}, // ambiguous, both past: the later one
+		{"16/10/2569", "th", ""},           // Thai receipts stay with the AI
+		{"", "en", ""},
+		{"31/02/2026", "en", ""},
+	}
+	for _, c := range cases {
+		got, ok := services.ParseReceiptDate(c.text, c.lang, now)
+		if got != c.want || ok != (c.want != "") {
+			t.Errorf("ParseReceiptDate(%q, %q) = %q, %v, want %q", c.text, c.lang, got, ok, c.want)
+		}
+	}
+}
+
+func TestReceiptPrompt(t *testing.T) {
+	p := &services.PromptSet{ReceiptLang: services.ParsePromptSections("### common\nกฏ\n### ja\n合計")}
+	if got := p.ReceiptPrompt(services.NormalizeReceiptLanguage("Japanese")); got != "กฏ\n\n合計" {
+		t.Errorf("ReceiptPrompt(ja) = %q", got)
+	}
+	if got := p.ReceiptPrompt(services.NormalizeReceiptLanguage("zh")); got != "" {
+		t.Errorf("ReceiptPrompt without zh section = %q, want empty", got)
+	}
+}
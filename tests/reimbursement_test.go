@@ -0,0 +1,19 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestTagReimbursable(t *testing.T) {
+	if got := services.TagReimbursable("แท็กซี่"); got != "แท็กซี่ #เบิกได้" {
+		t.Errorf("TagReimbursable = %q", got)
+	}
+	if got := services.TagReimbursable("แท็กซี่ #เบิกได้"); got != "แท็กซี่ #เบิกได้" {
+		t.Errorf("tag added twice: %q", got)
+	}
+	if got := services.TagReimbursable(""); got != "#เบิกได้" {
+		t.Errorf("empty description = %q", got)
+	}
+}
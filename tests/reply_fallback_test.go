@@ -0,0 +1,32 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/handlers"
+)
+
+func TestReplyTokenExpired(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	invalid := errors.New("unexpected status code: 400, {\"message\":\"Invalid reply token\"}")
+	cases := []struct {
+		name     string
+		err      error
+		age      time.Duration
+		used     bool
+		expected bool
+	}{
+		{"expired after slow AI", invalid, 70 * time.Second, false, true},
+		{"already used, long ago", invalid, 70 * time.Second, true, false},
+		{"rejected right away", invalid, 5 * time.Second, false, false},
+		{"other error", errors.New("connection reset"), 70 * time.Second, false, false},
+		{"reply went through", nil, 70 * time.Second, false, false},
+	}
+	for _, c := range cases {
+		if got := handlers.ReplyTokenExpired(c.err, now.Add(-c.age), c.used, now); got != c.expected {
+			t.Errorf("%s: ReplyTokenExpired = %v, want %v", c.name, got, c.expected)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestRoundUpAmount(t *testing.T) {
+	tests := []struct {
+		amount float64
+		step   int
+		want   float64
+	}{
+		{47, 10, 3},
+		{47, 100, 53},
+		{50, 10, 0},
+		{12.5, 10, 7.5},
+		{47, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := services.RoundUpAmount(tt.amount, tt.step); got != tt.want {
+			t.Errorf("RoundUpAmount(%v, %d) = %v, want %v", tt.amount, tt.step, got, tt.want)
+		}
+	}
+}
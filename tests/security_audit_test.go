@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestSecurityBurstCrossed(t *testing.T) {
+	cases := []struct {
+		event string
+		count int64
+		want  bool
+	}{
+		{services.SecurityDataExport, 10, false},
+		{services.SecurityDataExport, 11, true},
+		{services.SecurityDataExport, 12, false}, // already alerted on the 11th
+		{services.SecurityPeriodReopened, 4, true},
+		{services.SecurityAccountLink, 100, false}, // no burst limit
+	}
+	for _, c := range cases {
+		if got := services.SecurityBurstCrossed(c.event, c.count); got != c.want {
+			t.Errorf("SecurityBurstCrossed(%s, %d) = %v, want %v", c.event, c.count, got, c.want)
+		}
+	}
+}
+
+func TestSecurityAlertHookFiresOncePerBurst(t *testing.T) {
+	mongoService := testMongoService(t)
+	userID := testUserID("burst")
+	ctx := context.Background()
+
+	var alerts []services.SecurityEvent
+	mongoService.AddSecurityAlertHook(func(event services.SecurityEvent, reason string) {
+		if event.LineID == userID && reason == "burst" {
+			alerts = append(alerts, event)
+		}
+	})
+	// Limit for reopened periods is 3: the 4th alerts, the 5th and 6th don't
+	for i := 0; i < 6; i++ {
+		mongoService.LogSecurityEvent(ctx, services.SecurityEvent{LineID: userID, Event: services.SecurityPeriodReopened})
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("hook fired %d times, want 1", len(alerts))
+	}
+	if alerts[0].Event != services.SecurityPeriodReopened {
+		t.Errorf("alert event = %s", alerts[0].Event)
+	}
+}
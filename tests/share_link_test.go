@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestParseShareCommand(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	cases := []struct {
+		text     string
+		ok       bool
+		action   string
+		from, to string
+		days     int
+	}{
+		{"แชร์สรุปเดือนนี้ให้แฟนดู", true, "create", "2026-10-01", "2026-10-16", 7},
+		{"แชร์สรุป", true, "create", "2026-10-01", "2026-10-16", 7},
+		{"แชร์รายงานเดือนที่แล้ว 3 วัน", true, "create", "2026-09-01", "2026-09-30", 3},
+		{"ลิงก์แชร์", true, "list", "", "", 0},
+		{" ยกเลิกแชร์ ", true, "revoke", "", "", 0},
+		{"แชร์ร้านอร่อย", false, "", "", "", 0},
+		{"สรุปเดือนนี้", false, "", "", "", 0},
+	}
+	for _, c := range cases {
+		cmd, ok := services.ParseShareCommand(c.text, now)
+		if ok != c.ok || cmd.Action != c.action || cmd.From != c.from || cmd.To != c.to || cmd.Days != c.days {
+			t.Errorf("ParseShareCommand(%q) = %+v, %v", c.text, cmd, ok)
+		}
+	}
+}
+
+func TestShareLinkExpiry(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	cases := map[int]string{0: "2026-10-23", -5: "2026-10-23", 3: "2026-10-19", 30: "2026-11-15", 90: "2026-11-15"}
+	for days, want := range cases {
+		if got := services.ShareLinkExpiry(days, now).Format("2006-01-02"); got != want {
+			t.Errorf("ShareLinkExpiry(%d) = %s, want %s", days, got, want)
+		}
+	}
+}
+
+func TestShareLinkRevokeAndExpiry(t *testing.T) {
+	mongoService := testMongoService(t)
+	h := testWebhookHandler(t, mongoService)
+	ctx := context.Background()
+	userID := testUserID("share")
+
+	first, err := mongoService.CreateShareLink(ctx, userID, "เดือนนี้", "2026-10-01", "2026-10-16", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := mongoService.CreateShareLink(ctx, userID, "เดือนนี้", "2026-10-01", "2026-10-16", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link, err := mongoService.OpenShareLink(ctx, first.Token); err != nil || link.Views != 1 {
+		t.Fatalf("open = %+v, %v", link, err)
+	}
+
+	// A revoke button without a token must not revoke every link
+	postbackWebhook(t, h, userID, "action=share_revoke&token=")
+	if links, _ := mongoService.GetActiveShareLinks(ctx, userID); len(links) != 2 {
+		t.Fatalf("empty token revoked links, %d left", len(links))
+	}
+
+	// Another user can't revoke the link
+	if count, err := mongoService.RevokeShareLinks(ctx, testUserID("other"), first.Token); err != nil || count != 0 {
+		t.Fatalf("other user revoked %d, %v", count, err)
+	}
+	postbackWebhook(t, h, userID, "action=share_revoke&token="+first.Token)
+	if _, err := mongoService.OpenShareLink(ctx, first.Token); !errors.Is(err, services.ErrShareExpired) {
+		t.Errorf("revoked link opened: %v", err)
+	}
+	if _, err := mongoService.OpenShareLink(ctx, second.Token); err != nil {
+		t.Errorf("other link stopped opening: %v", err)
+	}
+	if _, err := mongoService.OpenShareLink(ctx, "no-such-token"); !errors.Is(err, services.ErrShareNotFound) {
+		t.Errorf("unknown token = %v", err)
+	}
+
+	// A link past its expiry no longer opens
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(os.Getenv("TEST_MONGODB_URI")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(ctx)
+	expired := services.ShareLink{Token: "expired-" + userID, LineID: userID, Label: "เดือนที่แล้ว", From: "2026-09-01", To: "2026-09-30",
+		ExpiresAt: time.Now().Add(-time.Minute), CreatedAt: time.Now().AddDate(0, 0, -7)}
+	if _, err := client.Database("satistang_test").Collection("share_links").InsertOne(ctx, expired); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mongoService.OpenShareLink(ctx, expired.Token); !errors.Is(err, services.ErrShareExpired) {
+		t.Errorf("expired link opened: %v", err)
+	}
+	if links, _ := mongoService.GetActiveShareLinks(ctx, userID); len(links) != 1 || links[0].Token != second.Token {
+		t.Errorf("active links = %+v", links)
+	}
+}
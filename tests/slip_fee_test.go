@@ -0,0 +1,34 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestSplitSlipFee(t *testing.T) {
+	slip := services.TransactionData{ImageType: "slip", Type: "expense", Amount: 5000, Fee: 25, UseType: 2, BankName: "กสิกร", RefNo: "ABC123", Date: "2025-03-01"}
+
+	txs := services.SplitSlipFee(slip)
+	if len(txs) != 2 {
+		t.Fatalf("expected transfer and fee entries, got %d", len(txs))
+	}
+	if txs[0].Amount != 5000 || txs[0].Fee != 0 {
+		t.Errorf("transfer entry = %+v, want amount 5000 without fee", txs[0])
+	}
+	fee := txs[1]
+	if fee.Amount != 25 || fee.Category != services.SlipFeeCategory || fee.Type != "expense" || fee.BankName != "กสิกร" || fee.Date != "2025-03-01" {
+		t.Errorf("fee entry = %+v", fee)
+	}
+
+	income := slip
+	income.Type = "income"
+	if got := services.SplitSlipFee(income); len(got) != 1 {
+		t.Errorf("incoming slip fee is paid by the sender, got %d entries", len(got))
+	}
+	misread := slip
+	misread.Fee = 5000
+	if got := services.SplitSlipFee(misread); len(got) != 1 {
+		t.Errorf("fee not smaller than amount should be dropped, got %d entries", len(got))
+	}
+}
@@ -0,0 +1,32 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestEvaluateSlipVerification(t *testing.T) {
+	slip := services.TransactionData{ImageType: "slip", Amount: 1250.50, RefNo: "REF001"}
+
+	if got := services.EvaluateSlipVerification(slip, true, 1250.50); got.Status != services.SlipVerified {
+		t.Errorf("matching amount = %+v, want verified", got)
+	}
+	if got := services.EvaluateSlipVerification(slip, true, 1250.00); got.Status != services.SlipSuspicious || got.Reason == "" {
+		t.Errorf("amount off by 50 satang = %+v, want suspicious with reason", got)
+	}
+	if got := services.EvaluateSlipVerification(slip, false, 0); got.Status != services.SlipSuspicious {
+		t.Errorf("unknown reference = %+v, want suspicious", got)
+	}
+}
+
+func TestSlipVerifierDisabledAndNoRef(t *testing.T) {
+	if services.NewSlipVerifier("", "key") != nil {
+		t.Error("empty endpoint should disable verification")
+	}
+	v := services.NewSlipVerifier("http://127.0.0.1:1/verify", "")
+	if got := v.Verify(context.Background(), services.TransactionData{Amount: 100}); got.Status != services.SlipUnchecked {
+		t.Errorf("slip without reference = %+v, want unchecked", got)
+	}
+}
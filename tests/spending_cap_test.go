@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestExceedsSpendingCap(t *testing.T) {
+	txs := []services.TransactionData{
+		{Type: "expense", Amount: 120},
+		{Type: "expense", Amount: 600},
+		{Type: "income", Amount: 1000},
+		{Type: "expense", Amount: 500},
+	}
+	if got := services.ExceedsSpendingCap(500, txs); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("ExceedsSpendingCap(500) = %v, want [1]", got)
+	}
+	if got := services.ExceedsSpendingCap(0, txs); got != nil {
+		t.Errorf("zero cap should not restrict, got %v", got)
+	}
+}
+
+func TestUserSettingsIsRestricted(t *testing.T) {
+	var nilSettings *services.UserSettings
+	if nilSettings.IsRestricted() {
+		t.Error("nil settings should not be restricted")
+	}
+	if (&services.UserSettings{ParentLineID: "U1"}).IsRestricted() {
+		t.Error("no cap should not be restricted")
+	}
+	if !(&services.UserSettings{ParentLineID: "U1", SpendingCap: 300}).IsRestricted() {
+		t.Error("parent with cap should be restricted")
+	}
+}
+
+// TestGuardrailConfirmHoldsForParent taps "บันทึกเลย" on a child's guardrail warning through the webhook
+func TestGuardrailConfirmHoldsForParent(t *testing.T) {
+	mongoService := testMongoService(t)
+	ctx := context.Background()
+
+	parent, child := testUserID("parent"), testUserID("child")
+	code, err := mongoService.CreateChildLinkCode(ctx, parent, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := mongoService.LinkChildAccount(ctx, child, code); err != nil {
+		t.Fatal(err)
+	}
+
+	key := "guardrail_" + child
+	pending, _ := json.Marshal(map[string]interface{}{
+		"transactions": []services.TransactionData{{Type: "expense", Category: "บันเทิง", Amount: 800, Date: time.Now().Format("2006-01-02")}},
+	})
+	if err := mongoService.SaveTempData(ctx, key, string(pending), 10*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	postbackWebhook(t, testWebhookHandler(t, mongoService), child, "action=guardrail_confirm&key="+key)
+
+	approvals, err := mongoService.GetPendingApprovals(ctx, parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(approvals) != 1 || approvals[0].ChildLineID != child || approvals[0].Total() != 800 {
+		t.Fatalf("want one pending approval of 800 from %s, got %+v", child, approvals)
+	}
+	recent, err := mongoService.GetRecentTransactions(ctx, child, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 0 {
+		t.Errorf("held entry should not be saved, got %d transactions", len(recent))
+	}
+}
+
+func TestNeedsParentApproval(t *testing.T) {
+	txs := []services.TransactionData{{Type: "expense", Category: "บันเทิง", Amount: 800}}
+	if !services.NeedsParentApproval(&services.UserSettings{ParentLineID: "U1", SpendingCap: 500}, txs) {
+		t.Error("over-cap entry should be held for the parent")
+	}
+	if services.NeedsParentApproval(&services.UserSettings{}, txs) {
+		t.Error("unrestricted user should save")
+	}
+}
@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestParseStatementCSV(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	csv := "บัญชี,123-4-56789-0\n" +
+		"วันที่,เวลา,รายการ,ถอนเงิน,ฝากเงิน,ยอดคงเหลือ\n" +
+		"14/10/2569,09:00,ค่าไฟ,\"1,200.00\",,\"8,800.00\"\n" +
+		"15/10/2569,12:30,เงินเดือน,,\"30,000.00\",\"38,800.00\"\n" +
+		"รวม,,,\"1,200.00\",\"30,000.00\",\n"
+
+	txs, err := services.ParseStatementCSV([]byte(csv), now)
+	if err != nil {
+		t.Fatalf("ParseStatementCSV: %v", err)
+	}
+	if len(txs) != 2 {
+		t.Fatalf("got %d rows, want 2: %+v", len(txs), txs)
+	}
+	if txs[0].Date != "2026-10-14" || txs[0].Type != "expense" || txs[0].Amount != 1200 || txs[0].Description != "ค่าไฟ" {
+		t.Errorf("row 0 = %+v", txs[0])
+	}
+	if txs[1].Date != "2026-10-15" || txs[1].Type != "income" || txs[1].Amount != 30000 {
+		t.Errorf("row 1 = %+v", txs[1])
+	}
+}
+
+func TestFindMissingTransactions(t *testing.T) {
+	statement := []services.TransactionData{
+		{Date: "2026-10-14", Type: "expense", Amount: 1200, UseType: 2, BankName: "กสิกร"},
+		{Date: "2026-10-15", Type: "expense", Amount: 50, UseType: 2, BankName: "กสิกร"},
+		{Date: "2026-10-15", Type: "expense", Amount: 50, UseType: 2, BankName: "กสิกร"},
+		{Date: "2026-10-15", Type: "income", Amount: 30000, UseType: 2, BankName: "กสิกร"},
+	}
+	recorded := []services.TransactionData{
+		{Date: "2026-10-13", Type: "expense", Amount: 1200, UseType: 2, BankName: "KBank"}, // posted a day later
+		{Date: "2026-10-15", Type: "expense", Amount: 50, UseType: 2, BankName: "KBank"},
+		{Date: "2026-10-15", Type: "income", Amount: 30000, UseType: 2, BankName: "SCB"}, // other account
+	}
+
+	missing := services.FindMissingTransactions(statement, recorded)
+	if len(missing) != 2 {
+		t.Fatalf("got %d missing, want 2: %+v", len(missing), missing)
+	}
+	if missing[0].Amount != 50 || missing[1].Amount != 30000 {
+		t.Errorf("missing = %+v", missing)
+	}
+}
@@ -0,0 +1,45 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestPeriodRanges(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.ParseInLocation("2006-01-02", s, time.Local)
+		return d.Add(14 * time.Hour)
+	}
+	cases := []struct {
+		period, now                      string
+		curFrom, curTo, prevFrom, prevTo string
+	}{
+		// Monday: one day against last Monday, not a whole week
+		{"week", "2025-10-13", "2025-10-13", "2025-10-13", "2025-10-06", "2025-10-06"},
+		{"week", "2025-10-15", "2025-10-13", "2025-10-15", "2025-10-06", "2025-10-08"},
+		{"week", "2025-10-19", "2025-10-13", "2025-10-19", "2025-10-06", "2025-10-12"},
+		{"month", "2025-10-03", "2025-10-01", "2025-10-03", "2025-09-01", "2025-09-03"},
+		{"month", "2025-03-31", "2025-03-01", "2025-03-31", "2025-02-01", "2025-02-28"},
+	}
+	for _, c := range cases {
+		curFrom, curTo, prevFrom, prevTo := services.PeriodRanges(c.period, day(c.now))
+		got := []string{curFrom.Format("2006-01-02"), curTo.Format("2006-01-02"), prevFrom.Format("2006-01-02"), prevTo.Format("2006-01-02")}
+		want := []string{c.curFrom, c.curTo, c.prevFrom, c.prevTo}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("PeriodRanges(%s, %s) = %v; want %v", c.period, c.now, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestLastWeekRange(t *testing.T) {
+	// Wednesday 15 Oct 2025: the digest covers the whole week before
+	from, to := services.LastWeekRange(time.Date(2025, 10, 15, 9, 0, 0, 0, time.Local))
+	if from.Format("2006-01-02") != "2025-10-06" || to.Format("2006-01-02") != "2025-10-12" {
+		t.Errorf("LastWeekRange = %s..%s; want 2025-10-06..2025-10-12", from.Format("2006-01-02"), to.Format("2006-01-02"))
+	}
+}
@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseSyncCursor(t *testing.T) {
+	issued := time.Unix(1760600000, 0)
+	after, _ := primitive.ObjectIDFromHex("6710f0a2c3b4d5e6f7a8b9c0")
+	tests := []struct {
+		in   string
+		want services.SyncCursor
+	}{
+		{"", services.SyncCursor{Bootstrap: true}},
+		{"42.1760600000", services.SyncCursor{Seq: 42, Issued: issued}},
+		{"b7", services.SyncCursor{Seq: 7, Bootstrap: true}},
+		{"b7.2026-10-15.1760500000123.6710f0a2c3b4d5e6f7a8b9c0", services.SyncCursor{Seq: 7, Bootstrap: true, After: services.SyncKey{
+			Date: "2026-10-15", CreatedAt: time.UnixMilli(1760500000123), ID: after,
+		}}},
+	}
+	for _, tt := range tests {
+		got, err := services.ParseSyncCursor(tt.in)
+		if err != nil || got.Seq != tt.want.Seq || got.Bootstrap != tt.want.Bootstrap || got.After.Date != tt.want.After.Date || got.After.ID != tt.want.After.ID || !got.After.CreatedAt.Equal(tt.want.After.CreatedAt) || !got.Issued.Equal(tt.want.Issued) {
+			t.Errorf("ParseSyncCursor(%q) = %+v, %v", tt.in, got, err)
+		}
+		if tt.in != "" && got.String() != tt.in {
+			t.Errorf("String() = %q, want %q", got.String(), tt.in)
+		}
+	}
+	for _, bad := range []string{"abc", "42", "-1.5", "b1.x", "b1.200", "b1.2026-10-15.x.6710f0a2c3b4d5e6f7a8b9c0", "b1.15/10.1.6710f0a2c3b4d5e6f7a8b9c0", "b1.2026-10-15.1.nothex"} {
+		if _, err := services.ParseSyncCursor(bad); !errors.Is(err, services.ErrInvalidSyncCursor) {
+			t.Errorf("ParseSyncCursor(%q) error = %v", bad, err)
+		}
+	}
+}
+
+func TestSyncCursorExpired(t *testing.T) {
+	now := time.Now()
+	if (services.SyncCursor{Seq: 1, Issued: now.Add(-24 * time.Hour)}).Expired(now) {
+		t.Errorf("day-old cursor should not expire")
+	}
+	if !(services.SyncCursor{Seq: 1, Issued: now.Add(-services.SyncRetention)}).Expired(now) {
+		t.Errorf("cursor older than retention should expire")
+	}
+}
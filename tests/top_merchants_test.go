@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestParseTopMerchantQuery(t *testing.T) {
+	now := time.Date(2025, 3, 13, 15, 30, 0, 0, time.UTC)
+	tests := []struct {
+		text, from, to, period string
+		ok                     bool
+	}{
+		{"ร้านไหนเปลืองสุด", "2025-03-01", "2025-03-13", "เดือนนี้", true},
+		{"ร้านไหนเปลืองสุดปีนี้", "2025-01-01", "2025-03-13", "ปีนี้", true},
+		{"เดือนที่แล้วจ่ายร้านไหนเยอะสุด", "2025-02-01", "2025-02-28", "เดือนที่แล้ว", true},
+		{"ข้าวร้านป้า 50", "", "", "", false},
+		{"หมวดไหนเปลืองสุด", "", "", "", false},
+	}
+	for _, tt := range tests {
+		q, ok := services.ParseTopMerchantQuery(tt.text, now)
+		if ok != tt.ok {
+			t.Errorf("ParseTopMerchantQuery(%q) ok = %v, want %v", tt.text, ok, tt.ok)
+			continue
+		}
+		if ok && (q.From != tt.from || q.To != tt.to || q.Period != tt.period) {
+			t.Errorf("ParseTopMerchantQuery(%q) = %+v, want %s %s %s", tt.text, q, tt.from, tt.to, tt.period)
+		}
+	}
+}
+
+func TestRankMerchants(t *testing.T) {
+	rows := []services.MerchantSpend{
+		{Merchant: "7-Eleven", Amount: 300, Visits: 4},
+		{Merchant: "เซเว่น สาขา 1234", Amount: 100, Visits: 1}, // saved before normalization
+		{Merchant: "Starbucks", Amount: 400, Visits: 2},
+		{Merchant: "SOMTAM NUA", Amount: 50, Visits: 1},
+		{Merchant: "  ", Amount: 999, Visits: 9},
+	}
+	got := services.RankMerchants(rows, 2)
+	if len(got) != 2 {
+		t.Fatalf("RankMerchants returned %d rows, want 2", len(got))
+	}
+	if got[0].Merchant != "7-Eleven" || got[0].Amount != 400 || got[0].Visits != 5 {
+		t.Errorf("first = %+v, want merged 7-Eleven 400 x5", got[0])
+	}
+	if got[1].Merchant != "Starbucks" {
+		t.Errorf("second = %+v, want Starbucks (fewer visits on tie)", got[1])
+	}
+}
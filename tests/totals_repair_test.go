@@ -0,0 +1,27 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestHasTotalsDrift(t *testing.T) {
+	record := services.DailyRecord{
+		Incomes:      []services.Transaction{{Amount: 1000}},
+		Expenses:     []services.Transaction{{Amount: 120.5}, {Amount: 79.5}},
+		TotalIncome:  1000,
+		TotalExpense: 200,
+	}
+	if services.HasTotalsDrift(record) {
+		t.Error("matching totals reported as drift")
+	}
+
+	record.TotalExpense = 120.5 // second expense pushed but total never incremented
+	if !services.HasTotalsDrift(record) {
+		t.Error("expected drift when stored expense total misses a transaction")
+	}
+	if income, expense := services.RecordTotals(record); income != 1000 || expense != 200 {
+		t.Errorf("RecordTotals = %v, %v, want 1000, 200", income, expense)
+	}
+}
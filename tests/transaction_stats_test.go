@@ -0,0 +1,43 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestStatsPeriodRange(t *testing.T) {
+	now := time.Date(2025, 3, 13, 15, 30, 0, 0, time.UTC) // Thursday
+	cases := map[string]struct{ from, label string }{
+		"day":   {"2025-03-13", "วันนี้"},
+		"week":  {"2025-03-10", "สัปดาห์นี้"},
+		"month": {"2025-03-01", "เดือนนี้"},
+		"year":  {"2025-01-01", "ปีนี้"},
+		"":      {"2025-03-01", "เดือนนี้"},
+	}
+	for period, want := range cases {
+		from, to, label := services.StatsPeriodRange(period, now)
+		if got := from.Format("2006-01-02"); got != want.from || label != want.label {
+			t.Errorf("StatsPeriodRange(%q) = %s %s, want %s %s", period, got, label, want.from, want.label)
+		}
+		if got := to.Format("2006-01-02"); got != "2025-03-13" {
+			t.Errorf("StatsPeriodRange(%q) to = %s, want today", period, got)
+		}
+	}
+}
+
+func TestTransactionStatsAIText(t *testing.T) {
+	if got := (&services.TransactionStats{Label: "เดือนนี้"}).ToAIText(); got != "" {
+		t.Errorf("empty stats should add no context, got %q", got)
+	}
+	stats := &services.TransactionStats{
+		Label: "เดือนนี้", Count: 3, IncomeCount: 1, ExpenseCount: 2,
+		AvgExpense: 150, MinExpense: 100, MaxExpense: 200, DailyAvgSpent: 23,
+		BusiestDay: "2025-03-10", BusiestCount: 2,
+	}
+	want := "สถิติเดือนนี้|รายการ:3(รับ1/จ่าย2)|จ่ายเฉลี่ย:150|ต่ำสุด:100|สูงสุด:200|ต่อวัน:23|วันที่ใช้บ่อย:2025-03-10(2)"
+	if got := stats.ToAIText(); got != want {
+		t.Errorf("ToAIText() = %q, want %q", got, want)
+	}
+}
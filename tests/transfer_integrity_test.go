@@ -0,0 +1,35 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestVerifyTransferLinks(t *testing.T) {
+	record := services.TransferRecord{
+		From: []services.TransferEntryDB{{Amount: 1000, UseType: 2, BankName: "กสิกร"}},
+		To:   []services.TransferEntryDB{{Amount: 600, UseType: 0}, {Amount: 400, UseType: 2, BankName: "ไทยพาณิชย์"}},
+	}
+	out := services.Transaction{Type: -1, Amount: 1000, IsTransfer: true}
+	in1 := services.Transaction{Type: 1, Amount: 400, IsTransfer: true}
+	in2 := services.Transaction{Type: 1, Amount: 600, IsTransfer: true}
+
+	cases := []struct {
+		name   string
+		linked []services.Transaction
+		want   string
+	}{
+		{"all legs in any order", []services.Transaction{in1, out, in2}, ""},
+		{"no legs", nil, services.TransferMissingLegs},
+		{"one leg lost", []services.Transaction{out, in2}, services.TransferMissingLegs},
+		{"extra leg", []services.Transaction{out, in1, in2, in2}, services.TransferLegMismatch},
+		{"edited amount", []services.Transaction{out, in1, {Type: 1, Amount: 650, IsTransfer: true}}, services.TransferLegMismatch},
+		{"leg not flagged", []services.Transaction{out, in1, {Type: 1, Amount: 600}}, services.TransferLegMismatch},
+	}
+	for _, c := range cases {
+		if got := services.VerifyTransferLinks(record, c.linked); got != c.want {
+			t.Errorf("%s: VerifyTransferLinks = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestFindTransferPair(t *testing.T) {
+	now := time.Now()
+	out := services.Transaction{Type: -1, Amount: 5000, Category: "อื่นๆ", UseType: 2, BankName: "กสิกร", CreatedAt: now}
+	in := services.Transaction{Type: 1, Amount: 5000, Category: "อื่นๆ", UseType: 2, BankName: "SCB", CreatedAt: now}
+
+	if got := services.FindTransferPair(out, []services.Transaction{in}); got == nil || got.BankName != "SCB" {
+		t.Fatalf("expense should pair with same-amount income on another bank, got %v", got)
+	}
+	if got := services.FindTransferPair(in, []services.Transaction{out}); got == nil || got.BankName != "กสิกร" {
+		t.Fatalf("income should pair with same-amount expense on another bank, got %v", got)
+	}
+
+	sameAccount := in
+	sameAccount.BankName = "กสิกร"
+	otherAmount := in
+	otherAmount.Amount = 4999
+	linked := in
+	linked.TransferID = "t1"
+	cashSpend := out
+	cashSpend.UseType, cashSpend.BankName = 0, ""
+	cashIncome := in
+	cashIncome.UseType, cashIncome.BankName = 0, ""
+	for name, candidate := range map[string]services.Transaction{"same account": sameAccount, "other amount": otherAmount, "already a transfer": linked} {
+		if got := services.FindTransferPair(out, []services.Transaction{candidate}); got != nil {
+			t.Errorf("%s: expected no pair, got %v", name, got)
+		}
+	}
+	if got := services.FindTransferPair(cashSpend, []services.Transaction{cashIncome}); got != nil {
+		t.Errorf("cash spending with matching cash income is not a transfer, got %v", got)
+	}
+}
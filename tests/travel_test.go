@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestParseTravelCommand(t *testing.T) {
+	cmd, ok := services.ParseTravelCommand("ไปญี่ปุ่น 10 วัน")
+	if !ok || cmd.Action != "start" || cmd.Destination != "ญี่ปุ่น" || cmd.Currency != "JPY" || cmd.Days != 10 {
+		t.Errorf("start = %+v, %v", cmd, ok)
+	}
+	if cmd, ok := services.ParseTravelCommand("เที่ยว โซล 5 วัน"); !ok || cmd.Currency != "KRW" {
+		t.Errorf("seoul = %+v, %v", cmd, ok)
+	}
+	if cmd, ok := services.ParseTravelCommand("เรท 0.23"); !ok || cmd.Action != "rate" || cmd.Rate != 0.23 {
+		t.Errorf("rate = %+v, %v", cmd, ok)
+	}
+	if cmd, _ := services.ParseTravelCommand("จบทริป"); cmd.Action != "stop" {
+		t.Errorf("stop = %+v", cmd)
+	}
+	if cmd, _ := services.ParseTravelCommand("สรุปทริป"); cmd.Action != "summary" {
+		t.Errorf("summary = %+v", cmd)
+	}
+	for _, text := range []string{"ไปตลาด 2 วัน", "ราเมง 1200", "เรท"} {
+		if _, ok := services.ParseTravelCommand(text); ok {
+			t.Errorf("ParseTravelCommand(%q) matched", text)
+		}
+	}
+}
+
+func TestApplyTripCurrency(t *testing.T) {
+	trip := services.NewTrip("ญี่ปุ่น", "JPY", 10, 0.23, time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local))
+	txs := []services.TransactionData{
+		{Type: "expense", Amount: 1200, Date: "2026-10-17", Description: "ราเมง"},
+		{Type: "expense", Amount: 300, Date: "2026-10-30", Description: "หลังทริป"},
+		{Type: "income", Amount: 5000, Date: "2026-10-17"},
+	}
+	if n := services.ApplyTripCurrency(trip, txs, false); n != 1 {
+		t.Fatalf("converted = %d, want 1", n)
+	}
+	if tx := txs[0]; tx.Amount != 276 || tx.ForeignAmount != 1200 || tx.Currency != "JPY" || tx.Trip != "ญี่ปุ่น 2026-10-16" {
+		t.Errorf("ramen = %+v", tx)
+	}
+	if txs[1].Trip != "" || txs[1].Amount != 300 || txs[2].Trip != "" {
+		t.Errorf("outside trip/income touched: %+v %+v", txs[1], txs[2])
+	}
+
+	// Paid in baht: tagged, not converted
+	baht := []services.TransactionData{{Type: "expense", Amount: 200, Date: "2026-10-17"}}
+	if n := services.ApplyTripCurrency(trip, baht, services.MentionsBaht("ซิม 200 บาท")); n != 0 || baht[0].Amount != 200 || baht[0].Trip == "" {
+		t.Errorf("baht = %+v, n=%d", baht[0], n)
+	}
+}
+
+func TestSummarizeTrip(t *testing.T) {
+	trip := &services.Trip{Destination: "ญี่ปุ่น", Currency: "JPY", StartDate: "2026-10-16", EndDate: "2026-10-25"}
+	tag := trip.Tag()
+	records := []services.DailyRecord{
+		{Date: "2026-10-17", Expenses: []services.Transaction{
+			{Amount: 276, Currency: "JPY", ForeignAmount: 1200, Trip: tag},
+			{Amount: 200, Trip: tag},
+			{Amount: 50, Trip: "เกาหลี 2026-01-01"},
+		}},
+		{Date: "2026-10-16", Expenses: []services.Transaction{{Amount: 460, Currency: "JPY", ForeignAmount: 2000, Trip: tag}}},
+	}
+	s := services.SummarizeTrip(trip, records)
+	if len(s.Days) != 2 || s.Days[0].Date != "2026-10-16" {
+		t.Fatalf("days = %+v", s.Days)
+	}
+	if s.Foreign != 3200 || s.THB != 936 || s.Days[1].Count != 2 {
+		t.Errorf("summary = %+v", s)
+	}
+}
@@ -0,0 +1,36 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestTransactionKind(t *testing.T) {
+	if got := services.TransactionKind(1); got != services.TransactionKindIncome {
+		t.Errorf("TransactionKind(1) = %q", got)
+	}
+	if got := services.TransactionKind(-1); got != services.TransactionKindExpense {
+		t.Errorf("TransactionKind(-1) = %q", got)
+	}
+	if got := services.TransactionDataKind(&services.TransactionData{Type: "investment"}); got != services.TransactionKindExpense {
+		t.Errorf("investment kind = %q, want expense", got)
+	}
+}
+
+func TestTransactionFields(t *testing.T) {
+	tests := []struct {
+		kind string
+		want []string
+	}{
+		{services.TransactionKindIncome, []string{"incomes", "expenses"}},
+		{services.TransactionKindExpense, []string{"expenses", "incomes"}},
+		{"", []string{"incomes", "expenses"}},
+	}
+	for _, tt := range tests {
+		if got := services.TransactionFields(tt.kind); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("TransactionFields(%q) = %v, want %v", tt.kind, got, tt.want)
+		}
+	}
+}
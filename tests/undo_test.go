@@ -0,0 +1,28 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestUndoActionCanUndo(t *testing.T) {
+	before := services.Transaction{Amount: 120, UseType: 0}
+	after := services.Transaction{Amount: 120, UseType: 2, BankName: "กสิกร"}
+	action := services.UndoAction{
+		Kind:   services.UndoKindUpdate,
+		Before: services.UndoStateOf(before),
+		After:  services.UndoStateOf(after),
+	}
+	if !action.CanUndo(after) {
+		t.Errorf("CanUndo should accept the state the edit left")
+	}
+	edited := after
+	edited.Amount = 150
+	if action.CanUndo(edited) {
+		t.Errorf("CanUndo should refuse a transaction edited again since")
+	}
+	if action.Before == action.After {
+		t.Errorf("payment change should differ in undo state")
+	}
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestUserProfileBuildAISchema(t *testing.T) {
+	p := &services.UserProfile{
+		Banks:             []string{"SCB", "KBank"},
+		ExpenseCategories: []string{"อาหาร"},
+	}
+	if got, want := p.BuildAISchema(), "ธนาคาร:SCB,KBank|หมวด:อาหาร"; got != want {
+		t.Errorf("BuildAISchema() = %q, want %q", got, want)
+	}
+	if got := (&services.UserProfile{}).BuildAISchema(); got != "" {
+		t.Errorf("empty profile schema = %q, want empty", got)
+	}
+}
+
+func TestMatchSlipName(t *testing.T) {
+	tests := []struct {
+		display, slip string
+		want          bool
+	}{
+		{"สมชาย", "นาย สมชาย ใ", true},
+		{"สมชาย ใจดี", "นายสมชาย ใจดี", true},
+		{"Somchai J.", "MR. SOMCHAI J", true},
+		{"สมชาย", "น.ส. สมหญิง ร", false},
+		{"", "นาย สมชาย", false},
+		{"ก", "ก", false},
+	}
+	for _, tt := range tests {
+		if got := services.MatchSlipName(tt.display, tt.slip); got != tt.want {
+			t.Errorf("MatchSlipName(%q, %q) = %v, want %v", tt.display, tt.slip, got, tt.want)
+		}
+	}
+}
+
+func TestReportOwnerTitle(t *testing.T) {
+	if got := services.ReportOwnerTitle(" Nok "); got != "รายงานของคุณ Nok" {
+		t.Errorf("ReportOwnerTitle = %q", got)
+	}
+	if got := services.ReportOwnerTitle(""); got != "รายงานสรุปการเงินส่วนตัว" {
+		t.Errorf("ReportOwnerTitle(empty) = %q", got)
+	}
+}
+
+func TestBuildAISchemaTrimsToTopNames(t *testing.T) {
+	cats := make([]string, services.AISchemaTopNames+3)
+	for i := range cats {
+		cats[i] = fmt.Sprintf("หมวด%d", i)
+	}
+	p := &services.UserProfile{ExpenseCategories: cats}
+
+	got := p.BuildAISchema()
+	if !strings.HasSuffix(got, ",หมวด9,อื่นๆ...") || strings.Contains(got, "หมวด10") {
+		t.Errorf("BuildAISchema() = %q, want top %d names and อื่นๆ...", got, services.AISchemaTopNames)
+	}
+	if full := p.BuildFullAISchema(); !strings.Contains(full, "หมวด12") || strings.Contains(full, "อื่นๆ") {
+		t.Errorf("BuildFullAISchema() = %q, want every name", full)
+	}
+	if len(p.ExpenseCategories) != len(cats) {
+		t.Error("BuildAISchema must not modify the profile lists")
+	}
+}
+
+func TestRankNames(t *testing.T) {
+	now := time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)
+	usage := map[string]services.NameUsage{
+		"อาหาร":   {Count: 40, Last: "2025-06-29"},
+		"ของขวัญ": {Count: 60, Last: "2024-06-01"}, // used a lot, but a year ago
+		"เดินทาง": {Count: 10, Last: "2025-06-30"},
+	}
+	got := services.RankNames([]string{"ของขวัญ", "ช้อปปิ้ง", "เดินทาง", "อาหาร"}, usage, now)
+	want := []string{"อาหาร", "เดินทาง", "ของขวัญ", "ช้อปปิ้ง"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("RankNames() = %v, want %v", got, want)
+	}
+}
+
+func TestIsListNamesResponse(t *testing.T) {
+	if !services.IsListNamesResponse("```json\n{\"action\":\"list_names\"}\n```") {
+		t.Error("list_names response not detected")
+	}
+	if services.IsListNamesResponse(`{"action":"chat","message":"list_names"}`) {
+		t.Error("chat response detected as list_names")
+	}
+}
@@ -0,0 +1,30 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestIsOpenQuestion(t *testing.T) {
+	for text, want := range map[string]bool{
+		"ช่วงนี้ฟุ่มเฟือยไหม":   true,
+		"ควรลดค่ากาแฟหรือเปล่า": true,
+		"กาแฟ 60":      false,
+		"สรุปเดือนนี้": false,
+	} {
+		if got := services.IsOpenQuestion(text); got != want {
+			t.Errorf("IsOpenQuestion(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	coffee := services.TextVector("กาแฟ")
+	if got := services.CosineSimilarity(coffee, services.TextVector("อาหาร กาแฟ ลาเต้")); got < 0.3 {
+		t.Errorf("related text similarity = %.2f", got)
+	}
+	if got := services.CosineSimilarity(coffee, services.TextVector("ค่าไฟ")); got != 0 {
+		t.Errorf("unrelated text similarity = %.2f", got)
+	}
+}
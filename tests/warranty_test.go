@@ -0,0 +1,33 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/satisatang/backend/services"
+)
+
+func TestParseWarrantyPeriod(t *testing.T) {
+	tests := []struct {
+		text   string
+		months int
+		ok     bool
+	}{
+		{"เก็บใบเสร็จประกัน 2 ปี", 24, true},
+		{"ซื้อทีวี 25000 รับประกัน 18 เดือน", 18, true},
+		{"ประกัน 0 ปี", 0, false},
+		{"ซื้อทีวี 25000", 0, false},
+	}
+	for _, tt := range tests {
+		months, ok := services.ParseWarrantyPeriod(tt.text)
+		if months != tt.months || ok != tt.ok {
+			t.Errorf("ParseWarrantyPeriod(%q) = %d, %v; want %d, %v", tt.text, months, ok, tt.months, tt.ok)
+		}
+	}
+}
+
+func TestWarrantyExpiry(t *testing.T) {
+	got, err := services.WarrantyExpiry("2025-03-15", 24)
+	if err != nil || got != "2027-03-15" {
+		t.Errorf("WarrantyExpiry = %q, %v", got, err)
+	}
+}